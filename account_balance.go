@@ -0,0 +1,173 @@
+package cnlib
+
+import (
+	"errors"
+	"sort"
+)
+
+/// Type Definitions
+
+// AccountBalance is the balance of the utxos belonging to one account, identified by the purpose, coin and account of
+// their derivation paths. All amounts are in satoshis.
+type AccountBalance struct {
+	BaseCoin    *BaseCoin // nil for utxos without a derivation path, such as imported keys or descriptors
+	Confirmed   int
+	Unconfirmed int
+	UtxoCount   int
+}
+
+// AggregateBalance sums the balance of a wallet's utxos across every active account and purpose. Add all utxos one at
+// a time using `AddUTXO`, as gomobile does not support custom arrays/slices.
+type AggregateBalance struct {
+	Confirmed   int
+	Unconfirmed int
+	accounts    []*AccountBalance
+}
+
+/// Constructors
+
+// NewAggregateBalance returns a pointer to an empty AggregateBalance.
+func NewAggregateBalance() *AggregateBalance {
+	return &AggregateBalance{accounts: []*AccountBalance{}}
+}
+
+/// Receiver functions
+
+// AddUTXO adds a utxo to the balance of its account, and to the totals.
+func (b *AggregateBalance) AddUTXO(utxo *UTXO) {
+	account := b.accountForBaseCoin(accountOf(utxo))
+	if account == nil {
+		account = &AccountBalance{BaseCoin: accountOf(utxo)}
+		b.accounts = append(b.accounts, account)
+	}
+	account.add(utxo)
+	if utxo.IsConfirmed {
+		b.Confirmed += int(utxo.Amount)
+	} else {
+		b.Unconfirmed += int(utxo.Amount)
+	}
+}
+
+// Total returns the confirmed and unconfirmed balance of every account.
+func (b *AggregateBalance) Total() int {
+	return b.Confirmed + b.Unconfirmed
+}
+
+// AccountCount returns the number of accounts holding utxos.
+func (b *AggregateBalance) AccountCount() int {
+	return len(b.accounts)
+}
+
+// AccountAtIndex returns the balance of an account, in the order first added, or error if out of bounds.
+func (b *AggregateBalance) AccountAtIndex(index int) (*AccountBalance, error) {
+	if index < 0 || index > len(b.accounts)-1 {
+		return nil, errors.New("index must be within range of accounts")
+	}
+	return b.accounts[index], nil
+}
+
+// BalanceForBaseCoin returns the balance of the account matching the purpose, coin and account of a BaseCoin, which
+// is empty if no utxos were added for it.
+func (b *AggregateBalance) BalanceForBaseCoin(bc *BaseCoin) *AccountBalance {
+	if account := b.accountForBaseCoin(bc); account != nil {
+		return account
+	}
+	return &AccountBalance{BaseCoin: bc}
+}
+
+// Total returns the confirmed and unconfirmed balance of the account.
+func (a *AccountBalance) Total() int {
+	return a.Confirmed + a.Unconfirmed
+}
+
+// SetAccountMixingAllowed sets whether `Generate` may select utxos from more than one account, which is allowed by
+// default. Spending utxos of several accounts together links them on chain, so forbid it for privacy. When forbidden,
+// utxos are only selected from the account of `ChangePath`, so change also stays in that account, or, without a change
+// path, from the single account that funds the transaction with the largest balance.
+func (td *TransactionData) SetAccountMixingAllowed(allowed bool) {
+	td.forbidAccountMixing = !allowed
+}
+
+/// Unexported functions
+
+func (a *AccountBalance) add(utxo *UTXO) {
+	if utxo.IsConfirmed {
+		a.Confirmed += int(utxo.Amount)
+	} else {
+		a.Unconfirmed += int(utxo.Amount)
+	}
+	a.UtxoCount++
+}
+
+func (b *AggregateBalance) accountForBaseCoin(bc *BaseCoin) *AccountBalance {
+	for _, account := range b.accounts {
+		if sameAccount(account.BaseCoin, bc) {
+			return account
+		}
+	}
+	return nil
+}
+
+// accountOf returns the BaseCoin identifying the account of a utxo, or nil if it has no derivation path.
+func accountOf(utxo *UTXO) *BaseCoin {
+	if utxo.Path == nil || utxo.descriptor != nil {
+		return nil
+	}
+	return utxo.Path.BaseCoin
+}
+
+// sameAccount returns true if both are nil, or have the same purpose, coin and account.
+func sameAccount(a *BaseCoin, b *BaseCoin) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Purpose == b.Purpose && a.Coin == b.Coin && a.Account == b.Account
+}
+
+// generateWithinAccount calls generate with the available utxos of one account at a time when account mixing is
+// forbidden, keeping the first to succeed, and otherwise calls it once with every utxo. Accounts are tried largest
+// balance first, moving on only when one cannot fund the transaction, and the error of the first is returned if none
+// can.
+func (td *TransactionData) generateWithinAccount(generate func() error) error {
+	if !td.forbidAccountMixing {
+		return generate()
+	}
+
+	all := td.availableUtxos
+	defer func() { td.availableUtxos = all }()
+
+	balance := NewAggregateBalance()
+	for _, utxo := range all {
+		balance.AddUTXO(utxo)
+	}
+	accounts := append([]*AccountBalance{}, balance.accounts...)
+	if td.ChangePath != nil {
+		accounts = []*AccountBalance{balance.BalanceForBaseCoin(td.ChangePath.BaseCoin)}
+	}
+	sort.SliceStable(accounts, func(i, j int) bool { return accounts[i].Total() > accounts[j].Total() })
+
+	var firstErr error
+	for _, account := range accounts {
+		td.availableUtxos = []*UTXO{}
+		for _, utxo := range all {
+			if sameAccount(accountOf(utxo), account.BaseCoin) {
+				td.availableUtxos = append(td.availableUtxos, utxo)
+			}
+		}
+		td.ChangeAmount = 0
+		err := generate()
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrInsufficientFunds) && err != errTransactionTooSmall {
+			return err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return td.insufficientFundsError(int64(td.Amount), 0)
+	}
+	return firstErr
+}
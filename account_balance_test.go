@@ -0,0 +1,82 @@
+package cnlib
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newAccountTestUTXO(txid string, basecoin *BaseCoin, amount int64, isConfirmed bool) *UTXO {
+	return NewUTXO(txid, 0, amount, NewDerivationPath(basecoin, 0, 1), nil, isConfirmed)
+}
+
+func TestAggregateBalance_SumsAcrossAccounts(t *testing.T) {
+	secondAccount := NewBaseCoin(84, 0, 1)
+	balance := NewAggregateBalance()
+	balance.AddUTXO(newAccountTestUTXO("1111111111111111111111111111111111111111111111111111111111111111", BaseCoinBip84MainNet, 30000, true))
+	balance.AddUTXO(newAccountTestUTXO("2222222222222222222222222222222222222222222222222222222222222222", BaseCoinBip49MainNet, 20000, false))
+	balance.AddUTXO(newAccountTestUTXO("3333333333333333333333333333333333333333333333333333333333333333", NewBaseCoin(84, 0, 0), 5000, false))
+	balance.AddUTXO(newAccountTestUTXO("4444444444444444444444444444444444444444444444444444444444444444", secondAccount, 7000, true))
+
+	assert.Equal(t, 37000, balance.Confirmed)
+	assert.Equal(t, 25000, balance.Unconfirmed)
+	assert.Equal(t, 62000, balance.Total())
+	assert.Equal(t, 3, balance.AccountCount())
+
+	bip84 := balance.BalanceForBaseCoin(BaseCoinBip84MainNet)
+	assert.Equal(t, 30000, bip84.Confirmed)
+	assert.Equal(t, 5000, bip84.Unconfirmed)
+	assert.Equal(t, 2, bip84.UtxoCount)
+
+	account, err := balance.AccountAtIndex(2)
+	assert.Nil(t, err)
+	assert.Equal(t, secondAccount, account.BaseCoin)
+	assert.Equal(t, 7000, account.Total())
+
+	_, err = balance.AccountAtIndex(3)
+	assert.EqualError(t, err, "index must be within range of accounts")
+	assert.Equal(t, 0, balance.BalanceForBaseCoin(BaseCoinBip49TestNet).Total())
+}
+
+func TestGenerate_AccountMixingAllowed_SelectsAcrossAccounts(t *testing.T) {
+	bip49 := newAccountTestUTXO("1111111111111111111111111111111111111111111111111111111111111111", BaseCoinBip49MainNet, 40000, true)
+	bip84 := newAccountTestUTXO("2222222222222222222222222222222222222222222222222222222222222222", BaseCoinBip84MainNet, 30000, true)
+
+	data := newPresetTestData(50000, 10, bip49, bip84)
+	assert.Nil(t, data.Generate())
+	assert.Equal(t, 2, data.TransactionData.UtxoCount())
+}
+
+func TestGenerate_AccountMixingForbidden_StaysInChangeAccount(t *testing.T) {
+	bip49 := newAccountTestUTXO("1111111111111111111111111111111111111111111111111111111111111111", BaseCoinBip49MainNet, 90000, true)
+	bip84 := newAccountTestUTXO("2222222222222222222222222222222222222222222222222222222222222222", BaseCoinBip84MainNet, 60000, true)
+	other := newAccountTestUTXO("3333333333333333333333333333333333333333333333333333333333333333", BaseCoinBip84MainNet, 20000, true)
+
+	data := newPresetTestData(50000, 10, bip49, bip84, other)
+	data.TransactionData.SetAccountMixingAllowed(false)
+	assert.Nil(t, data.Generate())
+	assert.Equal(t, 1, data.TransactionData.UtxoCount())
+	selected, err := data.TransactionData.RequiredUTXOAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, bip84, selected)
+
+	// the bip49 utxo alone could fund the payment, but would send change to another account
+	data = newPresetTestData(70000, 10, bip49, bip84)
+	data.TransactionData.SetAccountMixingAllowed(false)
+	err = data.Generate()
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+	assert.Equal(t, 60000, InsufficientFundsDetails(err).SpendableBalance)
+}
+
+func TestGenerate_SendMaxAccountMixingForbidden_SpendsLargestAccount(t *testing.T) {
+	data := NewTransactionDataSendingMax(presetTestAddress, BaseCoinBip84MainNet, 10, 590582)
+	data.AddUTXO(newAccountTestUTXO("1111111111111111111111111111111111111111111111111111111111111111", BaseCoinBip84MainNet, 30000, true))
+	data.AddUTXO(newAccountTestUTXO("2222222222222222222222222222222222222222222222222222222222222222", BaseCoinBip49MainNet, 40000, true))
+	data.AddUTXO(newAccountTestUTXO("3333333333333333333333333333333333333333333333333333333333333333", BaseCoinBip84MainNet, 20000, true))
+	data.TransactionData.SetAccountMixingAllowed(false)
+
+	assert.Nil(t, data.Generate())
+	assert.Equal(t, 2, data.TransactionData.UtxoCount())
+	assert.Equal(t, 50000, data.TransactionData.Amount+data.TransactionData.FeeAmount)
+}
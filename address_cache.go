@@ -0,0 +1,310 @@
+package cnlib
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// addressCacheStateVersion 2 added the MAC. Version 1 states are rejected, and the cache rebuilt as addresses are
+// derived.
+const (
+	addressCacheStateVersion = 2
+	addressCacheMACLabel     = "cnlib address cache"
+)
+
+/// Type Definition
+
+// AddressCache holds addresses derived by a wallet, so `ReceiveAddressForIndex`, `ChangeAddressForIndex` and
+// `CheckForAddress` return them without deriving again. Caching is off until the app calls `AddressCache` or
+// `RestoreAddressCache`. Serialized state carries a MAC keyed from the account extended public key, so a cache edited
+// in storage by anyone without that key is rejected on restore. Safe for concurrent use.
+type AddressCache struct {
+	mu        sync.Mutex
+	wallet    *HDWallet
+	entries   map[addressCacheKey]*addressCacheEntry
+	byAddress map[string]*addressCacheEntry
+}
+
+type addressCacheKey struct {
+	purpose     int
+	coin        int
+	account     int
+	testNetwork int
+	change      int
+	index       int
+}
+
+// addressCacheEntry is a cached address, and the serialized form of one.
+type addressCacheEntry struct {
+	Purpose     int    `json:"purpose"`
+	Coin        int    `json:"coin"`
+	Account     int    `json:"account"`
+	TestNetwork int    `json:"test_network,omitempty"`
+	Change      int    `json:"change"`
+	Index       int    `json:"index"`
+	Address     string `json:"address"`
+	PublicKey   string `json:"pubkey,omitempty"`
+}
+
+// addressCacheState is the serialized form of an AddressCache. Account is the fingerprint of the account public key
+// the addresses were derived from, and MAC the HMAC-SHA256 of the account and entries.
+type addressCacheState struct {
+	Version int                  `json:"version"`
+	Account string               `json:"account"`
+	Entries []*addressCacheEntry `json:"entries"`
+	MAC     string               `json:"mac"`
+}
+
+/// Receiver functions
+
+// AddressCache returns the wallet's cache of derived addresses, creating an empty one, and enabling caching, if needed.
+func (wallet *HDWallet) AddressCache() *AddressCache {
+	if wallet.addressCache == nil {
+		wallet.addressCache = newAddressCache(wallet)
+	}
+	return wallet.addressCache
+}
+
+// RestoreAddressCache replaces the wallet's address cache with one restored from a string returned by
+// `SerializedState`, enabling caching. Returns error if the state was serialized by a wallet with a different account,
+// or was modified since.
+func (wallet *HDWallet) RestoreAddressCache(serializedState string) error {
+	var state addressCacheState
+	if err := json.Unmarshal([]byte(serializedState), &state); err != nil {
+		return err
+	}
+	if state.Version != addressCacheStateVersion {
+		return errors.New("unsupported address cache state version")
+	}
+	fingerprint, err := wallet.accountKeyFingerprint()
+	if err != nil {
+		return err
+	}
+	if state.Account != fingerprint {
+		return errors.New("address cache state does not match wallet account")
+	}
+	mac, err := wallet.addressCacheMAC(&state)
+	if err != nil {
+		return err
+	}
+	expected, err := hex.DecodeString(state.MAC)
+	if err != nil || !hmac.Equal(mac, expected) {
+		return errors.New("address cache state failed authentication")
+	}
+
+	cache := newAddressCache(wallet)
+	for _, entry := range state.Entries {
+		if entry == nil || entry.Address == "" || entry.Index < 0 || (entry.Change != 0 && entry.Change != 1) {
+			return errors.New("address cache state contains an invalid entry")
+		}
+		cache.add(entry)
+	}
+	wallet.addressCache = cache
+	return nil
+}
+
+// Count returns the number of cached addresses.
+func (c *AddressCache) Count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// ContainsAddress returns true if an address is cached, for any BaseCoin the wallet has used.
+func (c *AddressCache) ContainsAddress(address string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.byAddress[address]
+	return ok
+}
+
+// Clear removes every cached address. Caching stays enabled.
+func (c *AddressCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[addressCacheKey]*addressCacheEntry)
+	c.byAddress = make(map[string]*addressCacheEntry)
+}
+
+// SerializedState returns the cache as a JSON string, suitable for persisting and passing to `RestoreAddressCache`.
+func (c *AddressCache) SerializedState() (string, error) {
+	fingerprint, err := c.wallet.accountKeyFingerprint()
+	if err != nil {
+		return "", err
+	}
+	state := addressCacheState{Version: addressCacheStateVersion, Account: fingerprint, Entries: []*addressCacheEntry{}}
+	c.mu.Lock()
+	for _, entry := range c.entries {
+		state.Entries = append(state.Entries, entry)
+	}
+	c.mu.Unlock()
+	sort.Slice(state.Entries, func(i, j int) bool {
+		a, b := state.Entries[i], state.Entries[j]
+		if a.Purpose != b.Purpose {
+			return a.Purpose < b.Purpose
+		}
+		if a.Coin != b.Coin {
+			return a.Coin < b.Coin
+		}
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		if a.TestNetwork != b.TestNetwork {
+			return a.TestNetwork < b.TestNetwork
+		}
+		if a.Change != b.Change {
+			return a.Change < b.Change
+		}
+		return a.Index < b.Index
+	})
+	mac, err := c.wallet.addressCacheMAC(&state)
+	if err != nil {
+		return "", err
+	}
+	state.MAC = hex.EncodeToString(mac)
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+/// Unexported functions
+
+func newAddressCache(wallet *HDWallet) *AddressCache {
+	return &AddressCache{
+		wallet:    wallet,
+		entries:   make(map[addressCacheKey]*addressCacheEntry),
+		byAddress: make(map[string]*addressCacheEntry),
+	}
+}
+
+func (c *AddressCache) add(entry *addressCacheEntry) {
+	key := addressCacheKey{purpose: entry.Purpose, coin: entry.Coin, account: entry.Account, testNetwork: entry.TestNetwork,
+		change: entry.Change, index: entry.Index}
+	if existing, ok := c.entries[key]; ok {
+		delete(c.byAddress, existing.Address)
+	}
+	c.entries[key] = entry
+	c.byAddress[entry.Address] = entry
+}
+
+// metaAddress returns the cached address at a path as a new MetaAddress, or nil if not cached.
+func (c *AddressCache) metaAddress(path *DerivationPath) *MetaAddress {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := addressCacheKey{purpose: path.Purpose, coin: path.Coin, account: path.Account, testNetwork: path.TestNetwork,
+		change: path.Change, index: path.Index}
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	return NewMetaAddress(entry.Address, path, entry.PublicKey)
+}
+
+// cachedAddress returns the cached address of the wallet's BaseCoin as a new MetaAddress, or nil if not cached or
+// caching is off.
+func (wallet *HDWallet) cachedAddress(address string) *MetaAddress {
+	c := wallet.addressCache
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.byAddress[address]
+	bc := wallet.BaseCoin
+	if !ok || entry.Purpose != bc.Purpose || entry.Coin != bc.Coin || entry.Account != bc.Account || entry.TestNetwork != bc.TestNetwork {
+		return nil
+	}
+	return NewMetaAddress(entry.Address, NewDerivationPath(bc, entry.Change, entry.Index), entry.PublicKey)
+}
+
+// store caches a derived address.
+func (c *AddressCache) store(meta *MetaAddress) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := meta.DerivationPath
+	c.add(&addressCacheEntry{
+		Purpose:     path.Purpose,
+		Coin:        path.Coin,
+		Account:     path.Account,
+		TestNetwork: path.TestNetwork,
+		Change:      path.Change,
+		Index:       path.Index,
+		Address:     meta.Address,
+		PublicKey:   meta.UncompressedPublicKey,
+	})
+}
+
+// addressForIndex returns the address at an index of a chain, from the address cache if enabled.
+func (wallet *HDWallet) addressForIndex(change int, index int) (*MetaAddress, error) {
+	cache := wallet.addressCache
+	if cache != nil && index >= 0 {
+		if meta := cache.metaAddress(NewDerivationPath(wallet.BaseCoin, change, index)); meta != nil {
+			return meta, nil
+		}
+	}
+	meta, err := wallet.deriveAddressForIndex(change, index)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		cache.store(meta)
+	}
+	return meta, nil
+}
+
+// accountKeyFingerprint returns the first 4 bytes of the hash160 of the wallet's account public key, as hex.
+func (wallet *HDWallet) accountKeyFingerprint() (string, error) {
+	accountKey, err := wallet.accountExtendedKey()
+	if err != nil {
+		return "", err
+	}
+	if accountKey == nil {
+		return "", errors.New("no account extended public key found")
+	}
+	pubkey, err := accountKey.ECPubKey()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(btcutil.Hash160(pubkey.SerializeCompressed())[:4]), nil
+}
+
+// addressCacheMAC returns the HMAC-SHA256 of an address cache state's version, account and entries, keyed from the
+// chain code and public key of the wallet's account extended key, which watch-only wallets of the same account share
+// whatever their version bytes.
+func (wallet *HDWallet) addressCacheMAC(state *addressCacheState) ([]byte, error) {
+	accountKey, err := wallet.accountExtendedKey()
+	if err != nil {
+		return nil, err
+	}
+	if accountKey == nil {
+		return nil, errors.New("no account extended public key found")
+	}
+	// version, depth, parent fingerprint and child number precede the chain code and key
+	serialized := base58.Decode(accountKey.String())
+	if len(serialized) < 78 {
+		return nil, errors.New("invalid account extended key")
+	}
+	key, err := hkdfBytes(serialized[13:78], addressCacheMACLabel, nil, sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := json.Marshal(state.Entries)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(fmt.Sprintf("%d\n%s\n", state.Version, state.Account)))
+	mac.Write(entries)
+	return mac.Sum(nil), nil
+}
@@ -0,0 +1,109 @@
+package cnlib
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressCache_CachesDerivedAddresses(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	uncached, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+
+	cache := wallet.AddressCache()
+	assert.Equal(t, 0, cache.Count())
+	first, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, uncached, first)
+	assert.Equal(t, 1, cache.Count())
+	assert.True(t, cache.ContainsAddress(first.Address))
+
+	second, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+	assert.False(t, first.DerivationPath == second.DerivationPath)
+
+	_, err = wallet.ChangeAddressForIndex(3)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, cache.Count())
+
+	cache.Clear()
+	assert.Equal(t, 0, cache.Count())
+}
+
+func TestAddressCache_RestoresSerializedState(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	wallet.AddressCache()
+	for i := 0; i < 5; i++ {
+		_, err := wallet.ReceiveAddressForIndex(i)
+		assert.Nil(t, err)
+	}
+	change, err := wallet.ChangeAddressForIndex(2)
+	assert.Nil(t, err)
+	state, err := wallet.AddressCache().SerializedState()
+	assert.Nil(t, err)
+
+	restored := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	assert.Nil(t, restored.RestoreAddressCache(state))
+	assert.Equal(t, 6, restored.AddressCache().Count())
+
+	found, err := restored.CheckForAddress(change.Address, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, found.DerivationPath.Change)
+	assert.Equal(t, 2, found.DerivationPath.Index)
+	_, err = restored.CheckForAddress(change.Address, 2)
+	assert.NotNil(t, err)
+
+	watchOnly, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	assert.Nil(t, watchOnly.RestoreAddressCache(state))
+}
+
+func TestAddressCache_RejectsMismatchedState(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	wallet.AddressCache()
+	state, err := wallet.AddressCache().SerializedState()
+	assert.Nil(t, err)
+
+	other := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	assert.NotNil(t, other.RestoreAddressCache(state))
+	assert.NotNil(t, wallet.RestoreAddressCache("{}"))
+
+	var decoded addressCacheState
+	assert.Nil(t, json.Unmarshal([]byte(state), &decoded))
+	decoded.Entries = []*addressCacheEntry{{Change: 2, Address: "bc1q"}}
+	invalid, _ := json.Marshal(decoded)
+	assert.NotNil(t, wallet.RestoreAddressCache(string(invalid)))
+}
+
+func TestAddressCache_RejectsTamperedState(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	wallet.AddressCache()
+	first, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	state, err := wallet.AddressCache().SerializedState()
+	assert.Nil(t, err)
+
+	// an attacker's address swapped in at a wallet path
+	var decoded addressCacheState
+	assert.Nil(t, json.Unmarshal([]byte(state), &decoded))
+	decoded.Entries[0].Address = "bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6"
+	tampered, _ := json.Marshal(decoded)
+	assert.EqualError(t, wallet.RestoreAddressCache(string(tampered)), "address cache state failed authentication")
+
+	decoded.Entries[0].Address = first.Address
+	decoded.MAC = ""
+	unauthenticated, _ := json.Marshal(decoded)
+	assert.EqualError(t, wallet.RestoreAddressCache(string(unauthenticated)), "address cache state failed authentication")
+
+	decoded.Version = 1
+	legacy, _ := json.Marshal(decoded)
+	assert.EqualError(t, wallet.RestoreAddressCache(string(legacy)), "unsupported address cache state version")
+
+	assert.Nil(t, wallet.RestoreAddressCache(state))
+	cached, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Address, cached.Address)
+}
@@ -0,0 +1,112 @@
+package cnlib
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// Following constants are used for AddressDeprecationWarning.Severity.
+const (
+	DeprecationSeverityNotice  int = 0 // a dated but safe format, such as one costing more to spend
+	DeprecationSeverityWarning int = 1 // a format the payment is unlikely to be intended for
+)
+
+/// Type Definitions
+
+// AddressDeprecationWarning describes a destination address of a deprecated type, for the UI to surface before sending.
+type AddressDeprecationWarning struct {
+	DestinationType int // one of the `DestinationType` constants
+	Severity        int // `DeprecationSeverityNotice` (0) or `DeprecationSeverityWarning` (1)
+	Network         string
+	Message         string
+}
+
+// AddressDeprecationPolicy holds the address types deprecated on each network. Starts with the library's defaults,
+// which each deployment may change with `SetDeprecation` and `ClearDeprecation`.
+type AddressDeprecationPolicy struct {
+	deprecations map[int]map[int]*AddressDeprecationWarning // keyed by coin, then destination type
+}
+
+/// Constructors
+
+// NewAddressDeprecationPolicy returns a policy warning, on every network, against paying bare public keys (P2PK), an
+// obsolete format seen in early coinbase outputs, and noting that legacy P2PKH addresses cost more to spend.
+func NewAddressDeprecationPolicy() *AddressDeprecationPolicy {
+	p := &AddressDeprecationPolicy{deprecations: make(map[int]map[int]*AddressDeprecationWarning)}
+	for coin := range networkRegistry {
+		p.SetDeprecation(coin, DestinationTypeP2PK, DeprecationSeverityWarning, "paying to a bare public key is obsolete; ask the recipient for a current address")
+		p.SetDeprecation(coin, DestinationTypeP2PKH, DeprecationSeverityNotice, "legacy addresses cost the recipient more in fees to spend")
+	}
+	return p
+}
+
+/// Receiver functions
+
+// SetDeprecation deprecates a destination type on the network of a coin, replacing any existing deprecation.
+func (p *AddressDeprecationPolicy) SetDeprecation(coin int, destinationType int, severity int, message string) error {
+	network, ok := networkRegistry[coin]
+	if !ok {
+		return ErrInvalidCoinValue
+	}
+	if _, err := bytesPerDestinationType(destinationType); err != nil {
+		return err
+	}
+	if severity != DeprecationSeverityNotice && severity != DeprecationSeverityWarning {
+		return errors.New("invalid deprecation severity")
+	}
+	if p.deprecations[coin] == nil {
+		p.deprecations[coin] = make(map[int]*AddressDeprecationWarning)
+	}
+	p.deprecations[coin][destinationType] = &AddressDeprecationWarning{
+		DestinationType: destinationType,
+		Severity:        severity,
+		Network:         network.name,
+		Message:         message,
+	}
+	return nil
+}
+
+// ClearDeprecation removes any deprecation of a destination type on the network of a coin.
+func (p *AddressDeprecationPolicy) ClearDeprecation(coin int, destinationType int) {
+	delete(p.deprecations[coin], destinationType)
+}
+
+// Check returns a warning if an address, on the network of a BaseCoin, is of a deprecated type, or nil if not. A hex
+// encoded public key is treated as a P2PK destination. Returns error if the address cannot be decoded.
+func (p *AddressDeprecationPolicy) Check(address string, bc *BaseCoin) (*AddressDeprecationWarning, error) {
+	if bc == nil {
+		return nil, errors.New("no basecoin provided")
+	}
+	network := bc.network()
+	decoded, err := btcutil.DecodeAddress(address, network.chainParams)
+	if err != nil {
+		return nil, err
+	}
+	if !decoded.IsForNet(network.chainParams) {
+		return nil, errors.New("address does not belong to wallet network")
+	}
+
+	var destinationType int
+	switch decoded.(type) {
+	case *btcutil.AddressPubKey:
+		destinationType = DestinationTypeP2PK
+	case *btcutil.AddressPubKeyHash:
+		destinationType = DestinationTypeP2PKH
+	case *btcutil.AddressScriptHash:
+		destinationType = DestinationTypeP2SH
+	case *btcutil.AddressWitnessPubKeyHash:
+		destinationType = DestinationTypeP2WPKH
+	case *btcutil.AddressWitnessScriptHash:
+		destinationType = DestinationTypeP2WSH
+	default:
+		return nil, errors.New("address not supported")
+	}
+
+	warning, ok := p.deprecations[network.coin][destinationType]
+	if !ok {
+		return nil, nil
+	}
+	copied := *warning
+	return &copied, nil
+}
@@ -0,0 +1,62 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressDeprecationPolicy_Defaults(t *testing.T) {
+	policy := NewAddressDeprecationPolicy()
+
+	warning, err := policy.Check("02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9", BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	assert.Equal(t, DestinationTypeP2PK, warning.DestinationType)
+	assert.Equal(t, DeprecationSeverityWarning, warning.Severity)
+	assert.Equal(t, "mainnet", warning.Network)
+
+	warning, err = policy.Check("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	assert.Equal(t, DestinationTypeP2PKH, warning.DestinationType)
+	assert.Equal(t, DeprecationSeverityNotice, warning.Severity)
+
+	warning, err = policy.Check("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	assert.Nil(t, warning)
+
+	warning, err = policy.Check("3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	assert.Nil(t, warning)
+}
+
+func TestAddressDeprecationPolicy_ConfiguredPerNetwork(t *testing.T) {
+	policy := NewAddressDeprecationPolicy()
+	policy.ClearDeprecation(0, DestinationTypeP2PKH)
+	assert.Nil(t, policy.SetDeprecation(0, DestinationTypeP2SH, DeprecationSeverityNotice, "prefer native segwit"))
+
+	warning, err := policy.Check("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	assert.Nil(t, warning)
+
+	warning, err = policy.Check("3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	assert.Equal(t, "prefer native segwit", warning.Message)
+
+	// the test network keeps its defaults
+	warning, err = policy.Check("mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", BaseCoinBip84TestNet)
+	assert.Nil(t, err)
+	assert.Equal(t, DestinationTypeP2PKH, warning.DestinationType)
+	assert.Equal(t, "regtest", warning.Network)
+
+	assert.Equal(t, ErrInvalidCoinValue, policy.SetDeprecation(3, DestinationTypeP2SH, DeprecationSeverityNotice, ""))
+	assert.EqualError(t, policy.SetDeprecation(0, 9, DeprecationSeverityNotice, ""), "invalid destination type")
+	assert.EqualError(t, policy.SetDeprecation(0, DestinationTypeP2SH, 2, ""), "invalid deprecation severity")
+}
+
+func TestAddressDeprecationPolicy_InvalidAddress_ReturnsError(t *testing.T) {
+	policy := NewAddressDeprecationPolicy()
+	_, err := policy.Check("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", BaseCoinBip84TestNet)
+	assert.NotNil(t, err)
+	_, err = policy.Check("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", nil)
+	assert.EqualError(t, err, "no basecoin provided")
+}
@@ -0,0 +1,185 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	addressDerivationReceiptVersion = 1
+	addressDerivationReceiptLabel   = "address derivation receipt"
+)
+
+// ErrReceiptAddressMismatch is returned when a receipt's address is not derived from its address public key.
+var ErrReceiptAddressMismatch = errors.New("receipt address does not match its public key and path")
+
+/// Type Definitions
+
+// AddressDerivationReceipt records that the wallet handed out Address, derived at a path of the wallet with
+// MasterFingerprint, at Timestamp. It is signed by the wallet's m/42 identity key, the CoinNinja verification key, and
+// by the address's own key, so a later dispute over whether the wallet gave out the address can be settled by
+// verifying the receipt and comparing IdentityPublicKey with the key known for the wallet, such as from an
+// `IdentityAttestation`. Signatures are deterministic, so the same address and timestamp always produce the same
+// receipt.
+type AddressDerivationReceipt struct {
+	Version           int    `json:"version"`
+	Address           string `json:"address"`
+	Purpose           int    `json:"purpose"`
+	Coin              int    `json:"coin"`
+	Account           int    `json:"account"`
+	Change            int    `json:"change"`
+	Index             int    `json:"index"`
+	MasterFingerprint string `json:"master_fingerprint"`
+	Timestamp         int64  `json:"timestamp"`       // seconds since unix epoch
+	AddressPublicKey  string `json:"address_pubkey"`  // hex-encoded compressed public key of the address
+	IdentityPublicKey string `json:"identity_pubkey"` // hex-encoded compressed public key at m/42
+	IdentitySignature string `json:"identity_signature"`
+	AddressSignature  string `json:"address_signature"`
+}
+
+/// Receiver functions
+
+// AddressDerivationReceipt returns a receipt, signed now, for the wallet's receive (0) or change (1) address at an
+// index, to be stored when the address is handed out. Returns error if the wallet is watch-only.
+func (wallet *HDWallet) AddressDerivationReceipt(change int, index int) (*AddressDerivationReceipt, error) {
+	return wallet.addressDerivationReceiptAt(change, index, time.Now().Unix())
+}
+
+// Encode returns the receipt as a JSON string, suitable for storing and passing to `DecodeAddressDerivationReceipt`.
+func (r *AddressDerivationReceipt) Encode() (string, error) {
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// Verify returns error unless both signatures are valid and the address is the one the address public key pays to
+// under the receipt's purpose. Callers should also check that IdentityPublicKey and MasterFingerprint are those of
+// the wallet in question.
+func (r *AddressDerivationReceipt) Verify() error {
+	if r.Version != addressDerivationReceiptVersion {
+		return errors.New("unsupported address derivation receipt version")
+	}
+
+	addressPubkey, err := parseHexPubKey(r.AddressPublicKey)
+	if err != nil {
+		return err
+	}
+	network, err := networkForAddress(r.Address)
+	if err != nil {
+		return err
+	}
+	if network.coin != r.Coin {
+		return ErrReceiptAddressMismatch
+	}
+	bc := &BaseCoin{Purpose: r.Purpose, Coin: r.Coin, Account: r.Account, TestNetwork: network.testNetwork}
+	path := NewDerivationPath(bc, r.Change, r.Index)
+	if err := path.Validate(); err != nil {
+		return err
+	}
+	address, err := generateAddress(path, addressPubkey)
+	if err != nil {
+		return err
+	}
+	if address != r.Address {
+		return ErrReceiptAddressMismatch
+	}
+
+	identityPubkey, err := parseHexPubKey(r.IdentityPublicKey)
+	if err != nil {
+		return err
+	}
+	identitySignature, err := hex.DecodeString(r.IdentitySignature)
+	if err != nil {
+		return err
+	}
+	addressSignature, err := hex.DecodeString(r.AddressSignature)
+	if err != nil {
+		return err
+	}
+	digest := r.digest()
+	if err := verifyDERSignature(identitySignature, digest, identityPubkey); err != nil {
+		return fmt.Errorf("identity signature: %w", err)
+	}
+	if err := verifyDERSignature(addressSignature, digest, addressPubkey); err != nil {
+		return fmt.Errorf("address signature: %w", err)
+	}
+	return nil
+}
+
+/// Exported functions
+
+// DecodeAddressDerivationReceipt parses and verifies a receipt from a string returned by `Encode`.
+func DecodeAddressDerivationReceipt(encoded string) (*AddressDerivationReceipt, error) {
+	var receipt AddressDerivationReceipt
+	if err := json.Unmarshal([]byte(encoded), &receipt); err != nil {
+		return nil, err
+	}
+	if err := receipt.Verify(); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+/// Unexported functions
+
+func (wallet *HDWallet) addressDerivationReceiptAt(change int, index int, timestamp int64) (*AddressDerivationReceipt, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+	if change != 0 && change != 1 {
+		return nil, errors.New("change must be 0 or 1")
+	}
+
+	path := NewDerivationPath(wallet.BaseCoin, change, index)
+	ua, err := newUsableAddressWithDerivationPath(wallet, path)
+	if err != nil {
+		return nil, err
+	}
+	address, err := generateAddress(path, ua.derivedPrivateKey.PubKey())
+	if err != nil {
+		return nil, err
+	}
+	identityKey, err := wallet.signingPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := wallet.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &AddressDerivationReceipt{
+		Version:           addressDerivationReceiptVersion,
+		Address:           address,
+		Purpose:           path.Purpose,
+		Coin:              path.Coin,
+		Account:           path.Account,
+		Change:            change,
+		Index:             index,
+		MasterFingerprint: fingerprint,
+		Timestamp:         timestamp,
+		AddressPublicKey:  hex.EncodeToString(ua.derivedPrivateKey.PubKey().SerializeCompressed()),
+		IdentityPublicKey: hex.EncodeToString(identityKey.PubKey().SerializeCompressed()),
+	}
+
+	digest := receipt.digest()
+	if receipt.IdentitySignature, err = signDigestHex(identityKey, digest); err != nil {
+		return nil, err
+	}
+	if receipt.AddressSignature, err = signDigestHex(ua.derivedPrivateKey, digest); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// digest returns the signed record digest of the receipt's fields.
+func (r *AddressDerivationReceipt) digest() []byte {
+	path := fmt.Sprintf("m/%d'/%d'/%d'/%d/%d", r.Purpose, r.Coin, r.Account, r.Change, r.Index)
+	return signedRecordDigest(addressDerivationReceiptLabel, r.Version, r.Address, path, r.MasterFingerprint, r.Timestamp,
+		r.AddressPublicKey, r.IdentityPublicKey)
+}
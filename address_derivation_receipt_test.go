@@ -0,0 +1,74 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressDerivationReceipt_RoundTrip(t *testing.T) {
+	for _, basecoin := range []*BaseCoin{BaseCoinBip49MainNet, BaseCoinBip84MainNet, BaseCoinBip86MainNet, BaseCoinBip84TestNet} {
+		wallet := NewHDWalletFromWords(w, basecoin)
+		receipt, err := wallet.addressDerivationReceiptAt(0, 4, 1577836800)
+		assert.Nil(t, err)
+
+		meta, err := wallet.ReceiveAddressForIndex(4)
+		assert.Nil(t, err)
+		assert.Equal(t, meta.Address, receipt.Address)
+		assert.Equal(t, "73c5da0a", receipt.MasterFingerprint)
+		verificationKey, err := wallet.CoinNinjaVerificationKeyHexString()
+		assert.Nil(t, err)
+		assert.Equal(t, verificationKey, receipt.IdentityPublicKey)
+		assert.Nil(t, receipt.Verify())
+
+		encoded, err := receipt.Encode()
+		assert.Nil(t, err)
+		decoded, err := DecodeAddressDerivationReceipt(encoded)
+		assert.Nil(t, err)
+		assert.Equal(t, receipt, decoded)
+
+		again, err := wallet.addressDerivationReceiptAt(0, 4, 1577836800)
+		assert.Nil(t, err)
+		assert.Equal(t, receipt, again)
+	}
+}
+
+func TestAddressDerivationReceipt_Tampered(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	receipt, err := wallet.addressDerivationReceiptAt(1, 2, 1577836800)
+	assert.Nil(t, err)
+	other, err := wallet.ChangeAddressForIndex(3)
+	assert.Nil(t, err)
+
+	tampered := *receipt
+	tampered.Timestamp++
+	assert.EqualError(t, tampered.Verify(), "identity signature: "+ErrSignatureInvalid.Error())
+
+	tampered = *receipt
+	tampered.Index = 3
+	assert.EqualError(t, tampered.Verify(), "identity signature: "+ErrSignatureInvalid.Error())
+
+	tampered = *receipt
+	tampered.Address = other.Address
+	assert.Equal(t, ErrReceiptAddressMismatch, tampered.Verify())
+
+	tampered = *receipt
+	tampered.Purpose = 49
+	assert.Equal(t, ErrReceiptAddressMismatch, tampered.Verify())
+
+	otherReceipt, err := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet).addressDerivationReceiptAt(1, 2, 1577836800)
+	assert.Nil(t, err)
+	tampered = *receipt
+	tampered.IdentitySignature = otherReceipt.IdentitySignature
+	assert.EqualError(t, tampered.Verify(), "identity signature: "+ErrSignatureInvalid.Error())
+
+	_, err = DecodeAddressDerivationReceipt("{}")
+	assert.NotNil(t, err)
+}
+
+func TestAddressDerivationReceipt_WatchOnly(t *testing.T) {
+	wallet, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	_, err = wallet.AddressDerivationReceipt(0, 0)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,105 @@
+package cnlib
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+/// Type Definitions
+
+// Following constants are used for VerifiedAddressOwnership.ProofType.
+const (
+	ProofTypeLegacy int = 0
+	ProofTypeBIP322 int = 1
+)
+
+// AddressOwnershipProof is a claim, supplied by a payee, that they control Address.
+//
+// Without a validity window, the proof is a standard BIP137 or BIP322 signature of Message, verifiable by any wallet.
+// When ValidFrom or ValidUntil are non-zero, the signed message is instead the Message followed by the validity
+// window, "\nValid from: <ValidFrom>\nValid until: <ValidUntil>", so that the window cannot be altered without
+// invalidating the signature. This suffix is a cnlib convention, not part of either BIP: the payee must sign the exact
+// text returned by `SignedMessage`, and other verifiers see only a signature of that text, with no window enforced.
+type AddressOwnershipProof struct {
+	Address    string
+	Message    string
+	Signature  string // base64 encoded; a BIP322 simple signature, or a legacy signmessage compact signature
+	ValidFrom  int64  // seconds since unix epoch, 0 if unbounded
+	ValidUntil int64  // seconds since unix epoch, 0 if unbounded
+}
+
+// VerifiedAddressOwnership is the result of successfully verifying an AddressOwnershipProof.
+type VerifiedAddressOwnership struct {
+	Address    string
+	ProofType  int // `ProofTypeLegacy` (0) or `ProofTypeBIP322` (1)
+	ValidFrom  int64
+	ValidUntil int64
+	IsExpired  bool // true if the current time falls outside of the validity window
+}
+
+/// Constructors
+
+// NewAddressOwnershipProof instantiates a new AddressOwnershipProof and returns a ref to it.
+func NewAddressOwnershipProof(address string, message string, signature string, validFrom int64, validUntil int64) *AddressOwnershipProof {
+	return &AddressOwnershipProof{
+		Address:    address,
+		Message:    message,
+		Signature:  signature,
+		ValidFrom:  validFrom,
+		ValidUntil: validUntil,
+	}
+}
+
+/// Receiver functions
+
+// SignedMessage returns the exact message text covered by the proof's signature, including the cnlib-specific
+// validity window suffix if the proof has a window, for the payee to sign.
+func (p *AddressOwnershipProof) SignedMessage() string {
+	if p.ValidFrom == 0 && p.ValidUntil == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("%s\nValid from: %d\nValid until: %d", p.Message, p.ValidFrom, p.ValidUntil)
+}
+
+// VerifyAddressOwnershipProof verifies a BIP322 or legacy signmessage proof on the BaseCoin's network. Returns error
+// if the signature is invalid; an expired but otherwise valid proof is returned with `IsExpired` set.
+func (bc *BaseCoin) VerifyAddressOwnershipProof(proof *AddressOwnershipProof) (*VerifiedAddressOwnership, error) {
+	if proof == nil {
+		return nil, errors.New("proof cannot be nil")
+	}
+	if proof.ValidFrom != 0 && proof.ValidUntil != 0 && proof.ValidUntil < proof.ValidFrom {
+		return nil, errors.New("proof validity window ends before it begins")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(proof.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	params := bc.defaultNetParams()
+	message := proof.SignedMessage()
+
+	proofType := ProofTypeBIP322
+	if len(signature) == 65 {
+		proofType = ProofTypeLegacy
+		err = verifyLegacySignedMessage(proof.Address, message, signature, params)
+	} else {
+		err = verifyBIP322SimpleSignedMessage(proof.Address, message, signature, params)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	isExpired := (proof.ValidFrom != 0 && now < proof.ValidFrom) || (proof.ValidUntil != 0 && now > proof.ValidUntil)
+
+	return &VerifiedAddressOwnership{
+		Address:    proof.Address,
+		ProofType:  proofType,
+		ValidFrom:  proof.ValidFrom,
+		ValidUntil: proof.ValidUntil,
+		IsExpired:  isExpired,
+	}, nil
+}
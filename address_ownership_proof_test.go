@@ -0,0 +1,101 @@
+package cnlib
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyAddressOwnershipProof_BIP322Vectors(t *testing.T) {
+	address := "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l"
+	vectors := map[string]string{
+		"":            "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+		"Hello World": "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+	}
+
+	for message, signature := range vectors {
+		proof := NewAddressOwnershipProof(address, message, signature, 0, 0)
+		verified, err := BaseCoinBip84MainNet.VerifyAddressOwnershipProof(proof)
+		assert.Nil(t, err)
+		assert.Equal(t, address, verified.Address)
+		assert.Equal(t, ProofTypeBIP322, verified.ProofType)
+		assert.False(t, verified.IsExpired)
+	}
+
+	wrong := NewAddressOwnershipProof(address, "Hello World", vectors[""], 0, 0)
+	verified, err := BaseCoinBip84MainNet.VerifyAddressOwnershipProof(wrong)
+	assert.NotNil(t, err)
+	assert.Nil(t, verified)
+}
+
+func TestVerifyAddressOwnershipProof_LegacyWithWindow(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	ua, err := newUsableAddressWithDerivationPath(wallet, path)
+	assert.Nil(t, err)
+	meta, err := ua.MetaAddress()
+	assert.Nil(t, err)
+
+	unbounded := NewAddressOwnershipProof(meta.Address, "I own this address", "", 0, 0)
+	assert.Equal(t, "I own this address", unbounded.SignedMessage())
+	windowed := NewAddressOwnershipProof(meta.Address, "I own this address", "", 1700000000, 1700003600)
+	assert.Equal(t, "I own this address\nValid from: 1700000000\nValid until: 1700003600", windowed.SignedMessage())
+
+	now := time.Now().Unix()
+	proof := NewAddressOwnershipProof(meta.Address, "I own this address", "", now-60, now+60)
+	sig, err := btcec.SignCompact(btcec.S256(), ua.derivedPrivateKey, legacyMessageHash(proof.SignedMessage()), true)
+	assert.Nil(t, err)
+	proof.Signature = base64.StdEncoding.EncodeToString(sig)
+
+	verified, err := BaseCoinBip84MainNet.VerifyAddressOwnershipProof(proof)
+	assert.Nil(t, err)
+	assert.Equal(t, ProofTypeLegacy, verified.ProofType)
+	assert.Equal(t, now+60, verified.ValidUntil)
+	assert.False(t, verified.IsExpired)
+
+	// altering the window invalidates the signature
+	proof.ValidUntil = now + 6000
+	_, err = BaseCoinBip84MainNet.VerifyAddressOwnershipProof(proof)
+	assert.NotNil(t, err)
+}
+
+func TestVerifyAddressOwnershipProof_LegacySegwitHeaders(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	ua, err := newUsableAddressWithDerivationPath(wallet, NewDerivationPath(BaseCoinBip84MainNet, 0, 0))
+	assert.Nil(t, err)
+	native, err := ua.MetaAddress()
+	assert.Nil(t, err)
+	nestedUA, err := newUsableAddressWithDerivationPath(wallet, NewDerivationPath(BaseCoinBip49MainNet, 0, 0))
+	assert.Nil(t, err)
+	nested, err := nestedUA.MetaAddress()
+	assert.Nil(t, err)
+
+	signWithHeader := func(ua *usableAddress, address string, header byte) *AddressOwnershipProof {
+		proof := NewAddressOwnershipProof(address, "I own this address", "", 0, 0)
+		sig, err := btcec.SignCompact(btcec.S256(), ua.derivedPrivateKey, legacyMessageHash(proof.SignedMessage()), true)
+		assert.Nil(t, err)
+		sig[0] = header + (sig[0]-bip137HeaderP2PKHUncompressed)%4
+		proof.Signature = base64.StdEncoding.EncodeToString(sig)
+		return proof
+	}
+
+	// BIP137 segwit headers, 35 through 42, match their own address type only
+	_, err = BaseCoinBip84MainNet.VerifyAddressOwnershipProof(signWithHeader(ua, native.Address, bip137HeaderP2WPKH))
+	assert.Nil(t, err)
+	_, err = BaseCoinBip84MainNet.VerifyAddressOwnershipProof(signWithHeader(ua, native.Address, bip137HeaderP2SHP2WPKH))
+	assert.Equal(t, ErrSignedMessageAddressMismatch, err)
+	_, err = BaseCoinBip49MainNet.VerifyAddressOwnershipProof(signWithHeader(nestedUA, nested.Address, bip137HeaderP2SHP2WPKH))
+	assert.Nil(t, err)
+	_, err = BaseCoinBip49MainNet.VerifyAddressOwnershipProof(signWithHeader(nestedUA, nested.Address, bip137HeaderP2WPKH))
+	assert.Equal(t, ErrSignedMessageAddressMismatch, err)
+
+	// compressed P2PKH headers match any single key address, as Electrum signs
+	_, err = BaseCoinBip84MainNet.VerifyAddressOwnershipProof(signWithHeader(ua, native.Address, bip137HeaderP2PKH))
+	assert.Nil(t, err)
+
+	_, err = BaseCoinBip84MainNet.VerifyAddressOwnershipProof(signWithHeader(ua, native.Address, bip137HeaderEnd))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,171 @@
+package cnlib
+
+import (
+	"errors"
+	"strings"
+)
+
+// Following constants describe where a lookalike address was found.
+const (
+	PoisoningSourceNone        int = 0
+	PoisoningSourceOwnAddress  int = 1
+	PoisoningSourceRecentPayee int = 2
+)
+
+// Poisoning addresses are ground to match the characters a user is likely to check, the start and end of the address.
+// Defaults flag 3 matching characters at both ends, roughly a one in a billion chance for unrelated bech32 addresses.
+const (
+	defaultPoisoningPrefixMatch = 3
+	defaultPoisoningSuffixMatch = 3
+)
+
+/// Type Definitions
+
+// AddressPoisoningChecker compares destination addresses against the wallet's own addresses and recent payees to
+// flag lookalikes, typically sent as dust to plant them in the user's transaction history.
+type AddressPoisoningChecker struct {
+	known          map[string]int
+	order          []string // known addresses in the order added, so ties resolve the same way on every check
+	MinPrefixMatch int      // characters after the address type prefix which must match to flag a lookalike
+	MinSuffixMatch int      // trailing characters which must match to flag a lookalike
+}
+
+// AddressPoisoningResult is the result of checking a destination address.
+type AddressPoisoningResult struct {
+	IsSuspicious   bool
+	SimilarAddress string // the known address the destination resembles, empty if not suspicious
+	Source         int    // PoisoningSourceNone, PoisoningSourceOwnAddress, or PoisoningSourceRecentPayee
+	PrefixMatch    int
+	SuffixMatch    int
+}
+
+/// Constructors
+
+// NewAddressPoisoningChecker returns a pointer to an AddressPoisoningChecker seeded with the wallet's receive and
+// change addresses up to a given index.
+func (wallet *HDWallet) NewAddressPoisoningChecker(upTo int) (*AddressPoisoningChecker, error) {
+	if upTo < 0 {
+		return nil, errors.New("index cannot be negative")
+	}
+
+	checker := &AddressPoisoningChecker{
+		known:          make(map[string]int),
+		MinPrefixMatch: defaultPoisoningPrefixMatch,
+		MinSuffixMatch: defaultPoisoningSuffixMatch,
+	}
+	for i := 0; i < upTo; i++ {
+		rma, err := wallet.ReceiveAddressForIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		checker.add(rma.Address, PoisoningSourceOwnAddress)
+
+		cma, err := wallet.ChangeAddressForIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		checker.add(cma.Address, PoisoningSourceOwnAddress)
+	}
+	return checker, nil
+}
+
+/// Receiver functions
+
+// AddRecentPayee records an address the user has previously paid.
+func (c *AddressPoisoningChecker) AddRecentPayee(address string) error {
+	if address == "" {
+		return errors.New("address cannot be empty")
+	}
+	c.add(address, PoisoningSourceRecentPayee)
+	return nil
+}
+
+// Check compares a destination address against all known addresses. An exact match is not suspicious. Otherwise the
+// destination is flagged if it shares both a prefix and a suffix with a known address, returning the closest match, or
+// of equally close ones, the first added.
+func (c *AddressPoisoningChecker) Check(destination string) (*AddressPoisoningResult, error) {
+	if destination == "" {
+		return nil, errors.New("address cannot be empty")
+	}
+
+	normalized := normalizedAddress(destination)
+	result := &AddressPoisoningResult{Source: PoisoningSourceNone}
+	if _, ok := c.known[normalized]; ok {
+		return result, nil
+	}
+
+	for _, address := range c.order {
+		source := c.known[address]
+		prefix, suffix := addressSimilarity(normalized, address)
+		if prefix < c.MinPrefixMatch || suffix < c.MinSuffixMatch {
+			continue
+		}
+		if result.IsSuspicious && prefix+suffix <= result.PrefixMatch+result.SuffixMatch {
+			continue
+		}
+		result.IsSuspicious = true
+		result.SimilarAddress = address
+		result.Source = source
+		result.PrefixMatch = prefix
+		result.SuffixMatch = suffix
+	}
+
+	return result, nil
+}
+
+/// Unexported functions
+
+// add records a known address from a source, unless already known.
+func (c *AddressPoisoningChecker) add(address string, source int) {
+	normalized := normalizedAddress(address)
+	if _, ok := c.known[normalized]; ok {
+		return
+	}
+	c.known[normalized] = source
+	c.order = append(c.order, normalized)
+}
+
+// normalizedAddress lowercases bech32 addresses, which are case insensitive. Base58 addresses are returned as-is.
+func normalizedAddress(address string) string {
+	if bech32NetworkForAddress(address) != nil {
+		return strings.ToLower(address)
+	}
+	return address
+}
+
+// addressBody strips the part of an address determined by its type, which every address of that type shares: the
+// human readable part, separator and witness version for bech32, or the version character for base58.
+func addressBody(address string) string {
+	if sep := strings.LastIndex(address, "1"); sep > 0 && bech32NetworkForAddress(address) != nil {
+		if len(address) > sep+2 {
+			return address[sep+2:]
+		}
+		return ""
+	}
+	if len(address) > 1 {
+		return address[1:]
+	}
+	return ""
+}
+
+// addressSimilarity returns the number of matching leading characters after the address type prefix, and the number
+// of matching trailing characters, of two normalized addresses. Addresses of different types never match.
+func addressSimilarity(a string, b string) (int, int) {
+	aBody, bBody := addressBody(a), addressBody(b)
+	if len(a)-len(aBody) != len(b)-len(bBody) || a[:len(a)-len(aBody)] != b[:len(b)-len(bBody)] {
+		return 0, 0
+	}
+
+	prefix := 0
+	for prefix < len(aBody) && prefix < len(bBody) && aBody[prefix] == bBody[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(aBody)-prefix && suffix < len(bBody)-prefix &&
+		aBody[len(aBody)-1-suffix] == bBody[len(bBody)-1-suffix] {
+		suffix++
+	}
+
+	return prefix, suffix
+}
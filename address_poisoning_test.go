@@ -0,0 +1,85 @@
+package cnlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressPoisoningChecker_FlagsLookalikeOfOwnAddress(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	checker, err := wallet.NewAddressPoisoningChecker(5)
+	assert.Nil(t, err)
+
+	// own address is bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu
+	result, err := checker.Check("bc1qcr8xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx6fyu")
+	assert.Nil(t, err)
+	assert.True(t, result.IsSuspicious)
+	assert.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", result.SimilarAddress)
+	assert.Equal(t, PoisoningSourceOwnAddress, result.Source)
+	assert.Equal(t, 3, result.PrefixMatch)
+	assert.Equal(t, 4, result.SuffixMatch)
+}
+
+func TestAddressPoisoningChecker_ExactMatchIsNotSuspicious(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	checker, err := wallet.NewAddressPoisoningChecker(5)
+	assert.Nil(t, err)
+
+	result, err := checker.Check(strings.ToUpper("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"))
+	assert.Nil(t, err)
+	assert.False(t, result.IsSuspicious)
+	assert.Equal(t, PoisoningSourceNone, result.Source)
+}
+
+func TestAddressPoisoningChecker_FlagsLookalikeOfRecentPayee(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	checker, err := wallet.NewAddressPoisoningChecker(0)
+	assert.Nil(t, err)
+	assert.Nil(t, checker.AddRecentPayee("3Cd4xEu2VvM352BVgd9cb1Ct5vxz318tVT"))
+
+	result, err := checker.Check("3Cd4yyyyyyyyyyyyyyyyyyyyyyyyyy8tVT")
+	assert.Nil(t, err)
+	assert.True(t, result.IsSuspicious)
+	assert.Equal(t, PoisoningSourceRecentPayee, result.Source)
+
+	// type prefix alone does not count towards a match
+	result, err = checker.Check("3xd4yyyyyyyyyyyyyyyyyyyyyyyyyy8tVT")
+	assert.Nil(t, err)
+	assert.False(t, result.IsSuspicious)
+
+	// different address types never match
+	result, err = checker.Check("1Cd4yyyyyyyyyyyyyyyyyyyyyyyyyy8tVT")
+	assert.Nil(t, err)
+	assert.False(t, result.IsSuspicious)
+}
+
+func TestAddressPoisoningChecker_TiesResolveToFirstAdded(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	checker, err := wallet.NewAddressPoisoningChecker(0)
+	assert.Nil(t, err)
+	assert.Nil(t, checker.AddRecentPayee("3Cd4xEu2VvM352BVgd9cb1Ct5vxz318tVT"))
+	assert.Nil(t, checker.AddRecentPayee("3Cd4aaaaaaaaaaaaaaaaaaaaaaaaaa8tVT"))
+	assert.Nil(t, checker.AddRecentPayee("3Cd4bbbbbbbbbbbbbbbbbbbbbbbbbb8tVT"))
+
+	for i := 0; i < 20; i++ {
+		result, err := checker.Check("3Cd4yyyyyyyyyyyyyyyyyyyyyyyyyy8tVT")
+		assert.Nil(t, err)
+		assert.Equal(t, "3Cd4xEu2VvM352BVgd9cb1Ct5vxz318tVT", result.SimilarAddress)
+	}
+}
+
+func TestAddressPoisoningChecker_UnrelatedAddressIsNotSuspicious(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	checker, err := wallet.NewAddressPoisoningChecker(5)
+	assert.Nil(t, err)
+
+	result, err := checker.Check("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6")
+	assert.Nil(t, err)
+	assert.False(t, result.IsSuspicious)
+	assert.Equal(t, "", result.SimilarAddress)
+
+	_, err = checker.Check("")
+	assert.NotNil(t, err)
+}
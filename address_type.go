@@ -0,0 +1,91 @@
+package cnlib
+
+import (
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// Following constants are used for AddressClassification.Type.
+const (
+	AddressTypeInvalid          int = 0
+	AddressTypeP2PKH            int = 1
+	AddressTypeP2SHOrP2SHP2WPKH int = 2 // P2SH, possibly P2SH-P2WPKH: the address hashes the redeem script, hiding which
+	AddressTypeP2WPKH           int = 3
+	AddressTypeP2WSH            int = 4
+	AddressTypeP2TR             int = 5
+	AddressTypeWitnessUnknown   int = 6 // valid segwit address of a witness version or program size not yet defined
+)
+
+/// Type Definitions
+
+// AddressHelper classifies addresses of any supported network.
+type AddressHelper struct{}
+
+// AddressClassification is the type and network of an address.
+type AddressClassification struct {
+	Type    int          // one of the `AddressType` constants
+	Network *NetworkInfo // nil if `Type` is `AddressTypeInvalid`
+}
+
+/// Constructors
+
+// NewAddressHelper returns a pointer to an AddressHelper.
+func NewAddressHelper() *AddressHelper {
+	return &AddressHelper{}
+}
+
+/// Receiver functions
+
+// TypeOfAddress fully validates an address, and returns its type and the network it belongs to, with `Type` of
+// `AddressTypeInvalid` if it is not a valid address of a supported network. As with `AddressIsValidSegwitAddress`,
+// segwit addresses must use the checksum BIP350 requires of their witness version. P2SH addresses are always
+// `AddressTypeP2SHOrP2SHP2WPKH`, as nothing in the address tells plain P2SH from nested segwit.
+func (h *AddressHelper) TypeOfAddress(addr string) *AddressClassification {
+	invalid := &AddressClassification{Type: AddressTypeInvalid}
+	network, err := networkForAddress(addr)
+	if err != nil {
+		return invalid
+	}
+
+	addressType := base58AddressType(addr, network)
+	if bech32NetworkForAddress(addr) != nil {
+		addressType = segwitAddressType(addr, network)
+	}
+	if addressType == AddressTypeInvalid {
+		return invalid
+	}
+	return &AddressClassification{Type: addressType, Network: network.info()}
+}
+
+/// Unexported functions
+
+func segwitAddressType(addr string, network *networkParams) int {
+	version, program, err := decodeSegwitAddress(addr, network.chainParams.Bech32HRPSegwit)
+	if err != nil {
+		return AddressTypeInvalid
+	}
+	switch {
+	case version == 0 && len(program) == 20:
+		return AddressTypeP2WPKH
+	case version == 0 && len(program) == 32:
+		return AddressTypeP2WSH
+	case version == taprootWitnessVersion && len(program) == taprootOutputKeySize:
+		return AddressTypeP2TR
+	default:
+		return AddressTypeWitnessUnknown
+	}
+}
+
+func base58AddressType(addr string, network *networkParams) int {
+	hash, version, err := base58.CheckDecode(addr)
+	if err != nil || len(hash) != 20 {
+		return AddressTypeInvalid
+	}
+	switch version {
+	case network.chainParams.PubKeyHashAddrID:
+		return AddressTypeP2PKH
+	case network.chainParams.ScriptHashAddrID:
+		return AddressTypeP2SHOrP2SHP2WPKH
+	default:
+		return AddressTypeInvalid
+	}
+}
@@ -0,0 +1,69 @@
+package cnlib
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressHelper_TypeOfAddress(t *testing.T) {
+	unknownWitness, err := encodeSegwitAddress("bc", 2, bytes.Repeat([]byte{0x01}, 16))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		address     string
+		addressType int
+		network     string
+	}{
+		{"1B3kirKp5kmVnHJv6YyqaK8gbYkNCVo9WN", AddressTypeP2PKH, "mainnet"},
+		{"37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf", AddressTypeP2SHOrP2SHP2WPKH, "mainnet"},
+		{"2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2", AddressTypeP2SHOrP2SHP2WPKH, "regtest"},
+		{"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", AddressTypeP2WPKH, "mainnet"},
+		{"BC1QCR8TE4KR609GCAWUTMRZA0J4XV80JY8Z306FYU", AddressTypeP2WPKH, "mainnet"},
+		{"bcrt1q6rz28mcfaxtmd6v789l9rrlrusdprr9pz3cppk", AddressTypeP2WPKH, "regtest"},
+		{"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", AddressTypeP2WPKH, "testnet"},
+		{"ltc1qw508d6qejxtdg4y5r3zarvary0c5xw7kgmn4n9", AddressTypeP2WPKH, "litecoin"},
+		{"bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3", AddressTypeP2WSH, "mainnet"},
+		{"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr", AddressTypeP2TR, "mainnet"},
+		{"bcrt1p8wpt9v4frpf3tkn0srd97pksgsxc5hs52lafxwru9kgeephvs7rqjeprhg", AddressTypeP2TR, "regtest"},
+		{unknownWitness, AddressTypeWitnessUnknown, "mainnet"},
+	}
+
+	helper := NewAddressHelper()
+	for _, test := range tests {
+		result := helper.TypeOfAddress(test.address)
+		assert.Equal(t, test.addressType, result.Type, test.address)
+		if assert.NotNil(t, result.Network, test.address) {
+			assert.Equal(t, test.network, result.Network.Name)
+		}
+	}
+}
+
+func TestAddressHelper_TypeOfAddress_Invalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"not an address",
+		"1B3kirKp5kmVnHJv6YyqaK8gbYkNCVo9WM", // bad checksum
+		"BC1QW508D6QEJXTDG4Y5R3ZARVAYR0C5XW7KV8F3T4",    // transposed characters
+		"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyv",    // bad checksum
+		"xyz1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",   // unsupported network
+		"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac", // truncated
+	}
+
+	helper := NewAddressHelper()
+	for _, address := range invalid {
+		result := helper.TypeOfAddress(address)
+		assert.Equal(t, AddressTypeInvalid, result.Type, address)
+		assert.Nil(t, result.Network, address)
+	}
+}
+
+func TestAddressHelper_TypeOfAddress_NestedSegwitIsAmbiguous(t *testing.T) {
+	nested, err := NewHDWalletFromWords(w, BaseCoinBip49MainNet).ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+
+	helper := NewAddressHelper()
+	assert.Equal(t, AddressTypeP2SHOrP2SHP2WPKH, helper.TypeOfAddress(nested.Address).Type)
+	assert.Equal(t, helper.TypeOfAddress("37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf").Type, helper.TypeOfAddress(nested.Address).Type)
+}
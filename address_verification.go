@@ -0,0 +1,133 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+// ErrAddressDescriptorMismatch is returned when an address differs from the one computed from a descriptor.
+var ErrAddressDescriptorMismatch = errors.New("address does not match descriptor")
+
+/// Receiver functions
+
+// AccountDescriptor returns the BIP380 output descriptor, with checksum, of the wallet's receive (0) or change (1)
+// chain, such as "wpkh([73c5da0a/84h/0h/0h]xpub.../0/*)#...", for import into other wallets or for verifying
+// addresses with `VerifyAddressWithDescriptor`. The key origin is omitted for watch-only wallets without a known
+// master fingerprint.
+func (wallet *HDWallet) AccountDescriptor(change int) (string, error) {
+	if change != 0 && change != 1 {
+		return "", errors.New("change must be 0 or 1")
+	}
+	network, ok := networkRegistry[wallet.BaseCoin.Coin]
+	if !ok {
+		return "", ErrInvalidCoinValue
+	}
+
+	kf := wallet.keyFactory()
+	accountKey, _, err := kf.accountExtendedPublicKey(wallet.BaseCoin)
+	if err != nil {
+		return "", err
+	}
+	encodedKey, err := encodeExtendedPubkeyWithType(accountKey, network.extendedPubkeyTypes[bip44purpose])
+	if err != nil {
+		return "", err
+	}
+
+	origin := ""
+	fingerprint, err := wallet.masterFingerprintBytes()
+	if err == nil {
+		bc := wallet.BaseCoin
+		origin = fmt.Sprintf("[%s/%dh/%dh/%dh]", hex.EncodeToString(fingerprint), bc.Purpose, bc.Coin, bc.Account)
+	} else if err != ErrMasterFingerprintUnknown {
+		return "", err
+	}
+
+	key := fmt.Sprintf("%s%s/%d/*", origin, encodedKey, change)
+	var descriptor string
+	switch wallet.BaseCoin.Purpose {
+	case bip84purpose:
+		descriptor = "wpkh(" + key + ")"
+	case bip49purpose:
+		descriptor = "sh(wpkh(" + key + "))"
+	case bip86purpose:
+		descriptor = "tr(" + key + ")"
+	default:
+		return "", ErrInvalidPurposeValue
+	}
+
+	checksum, err := descriptorChecksum(descriptor)
+	if err != nil {
+		return "", err
+	}
+	return descriptor + "#" + checksum, nil
+}
+
+// VerifyReceiveAddress recomputes the receive address at a given index from the wallet's exported descriptor,
+// independently of the code deriving addresses for display, and returns `ErrAddressDescriptorMismatch` if it differs
+// from the address given. Call before showing or sharing a receive address.
+func (wallet *HDWallet) VerifyReceiveAddress(address string, index int) error {
+	descriptor, err := wallet.AccountDescriptor(0)
+	if err != nil {
+		return err
+	}
+	return VerifyAddressWithDescriptor(descriptor, index, address)
+}
+
+/// Exported functions
+
+// VerifyAddressWithDescriptor computes the output script of a ranged descriptor, such as one exported by a hardware
+// wallet, at a given index, and returns `ErrAddressDescriptorMismatch` if the address pays to a different script.
+// Supports the descriptors accepted by `UTXO.SetDescriptor`, with the wildcard "*" standing for the index. Taproot
+// addresses cannot be decoded, and so return error.
+func VerifyAddressWithDescriptor(descriptor string, index int, address string) error {
+	if err := validateDerivationIndex(index); err != nil {
+		return err
+	}
+	stripped, err := stripDescriptorChecksum(descriptor)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(stripped, "/*") {
+		return errors.New("descriptor must be ranged")
+	}
+	parsed, err := parseOutputDescriptor(strings.ReplaceAll(stripped, "/*", fmt.Sprintf("/%d", index)))
+	if err != nil {
+		return err
+	}
+	expected, err := parsed.pkScript()
+	if err != nil {
+		return err
+	}
+
+	pkScript, err := pkScriptForAnyNetwork(address)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(pkScript, expected) {
+		return ErrAddressDescriptorMismatch
+	}
+	return nil
+}
+
+/// Unexported functions
+
+// pkScriptForAnyNetwork returns the output script paying to an address of any supported network.
+func pkScriptForAnyNetwork(address string) ([]byte, error) {
+	for _, network := range supportedNetworks {
+		if outputKey, err := decodeTaprootAddress(address, network.chainParams); err == nil {
+			return P2TRScript(outputKey)
+		}
+		decoded, err := btcutil.DecodeAddress(address, network.chainParams)
+		if err != nil || !decoded.IsForNet(network.chainParams) {
+			continue
+		}
+		return txscript.PayToAddrScript(decoded)
+	}
+	return nil, errors.New("unrecognized address")
+}
@@ -0,0 +1,71 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const bip84ReceiveDescriptor = "wpkh([73c5da0a/84h/0h/0h]xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/0/*)#afwvtk2s"
+
+func TestAccountDescriptor(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	descriptor, err := wallet.AccountDescriptor(0)
+	assert.Nil(t, err)
+	assert.Equal(t, bip84ReceiveDescriptor, descriptor)
+
+	bip49, err := NewHDWalletFromWords(w, BaseCoinBip49MainNet).AccountDescriptor(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "sh(wpkh([73c5da0a/49h/0h/0h]xpub6C6nQwHaWbSrzs5tZ1q7m5R9cPK9eYpNMFesiXsYrgc1P8bvLLAet9JfHjYXKjToD8cBRswJXXbbFpXgwsswVPAZzKMa1jUp2kVkGVUaJa7/1/*))#ea5vzgxl", bip49)
+
+	_, err = wallet.AccountDescriptor(2)
+	assert.EqualError(t, err, "change must be 0 or 1")
+}
+
+func TestAccountDescriptor_WatchOnlyWallet_OmitsUnknownOrigin(t *testing.T) {
+	zpub := "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	wallet, err := NewHDWalletFromAccountExtendedPublicKey(zpub)
+	assert.Nil(t, err)
+
+	descriptor, err := wallet.AccountDescriptor(0)
+	assert.Nil(t, err)
+	checksum, err := DescriptorChecksum("wpkh(xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/0/*)")
+	assert.Nil(t, err)
+	assert.Equal(t, "wpkh(xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/0/*)#"+checksum, descriptor)
+
+	assert.Nil(t, wallet.SetMasterFingerprint("73c5da0a"))
+	descriptor, err = wallet.AccountDescriptor(0)
+	assert.Nil(t, err)
+	assert.Equal(t, bip84ReceiveDescriptor, descriptor)
+}
+
+func TestVerifyAddressWithDescriptor(t *testing.T) {
+	// BIP84 test vector receive address at index 0
+	assert.Nil(t, VerifyAddressWithDescriptor(bip84ReceiveDescriptor, 0, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"))
+	assert.Equal(t, ErrAddressDescriptorMismatch, VerifyAddressWithDescriptor(bip84ReceiveDescriptor, 1, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"))
+
+	bip49 := NewHDWalletFromWords(w, BaseCoinBip49MainNet)
+	descriptor, err := bip49.AccountDescriptor(1)
+	assert.Nil(t, err)
+	change, err := bip49.ChangeAddressForIndex(3)
+	assert.Nil(t, err)
+	assert.Nil(t, VerifyAddressWithDescriptor(descriptor, 3, change.Address))
+
+	assert.EqualError(t, VerifyAddressWithDescriptor(bip84ReceiveDescriptor[:len(bip84ReceiveDescriptor)-1]+"x", 0, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"), "invalid descriptor checksum")
+	assert.EqualError(t, VerifyAddressWithDescriptor("wpkh(02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9)", 0, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"), "descriptor must be ranged")
+	assert.EqualError(t, VerifyAddressWithDescriptor(bip84ReceiveDescriptor, 0, "notanaddress"), "unrecognized address")
+	assert.Equal(t, ErrDerivationIndexOverflow, VerifyAddressWithDescriptor(bip84ReceiveDescriptor, 1<<31, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"))
+}
+
+func TestVerifyReceiveAddress(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	for i := 0; i < 3; i++ {
+		ma, err := wallet.ReceiveAddressForIndex(i)
+		assert.Nil(t, err)
+		assert.Nil(t, wallet.VerifyReceiveAddress(ma.Address, i))
+	}
+
+	swapped, err := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet).ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrAddressDescriptorMismatch, wallet.VerifyReceiveAddress(swapped.Address, 0))
+}
@@ -0,0 +1,165 @@
+package cnlib
+
+import (
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+const (
+	btcDecimals     = 8
+	maxFiatDecimals = 8
+)
+
+// fiatRatePattern matches a plain decimal number, such as "34123.45", without sign, exponent or fraction notation.
+var fiatRatePattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// Following errors are returned for fiat rates which cannot price an amount.
+var (
+	ErrFiatRateInvalid     = errors.New("fiat rate must be a positive decimal number, such as 34123.45")
+	ErrFiatCurrencyInvalid = errors.New("fiat currency code must be 3 letters")
+	ErrFiatDecimalsInvalid = errors.New("fiat decimals must be between 0 and 8")
+)
+
+/// Type Definitions
+
+// Amount is an amount of bitcoin in satoshis, for display.
+type Amount struct {
+	Satoshis int64
+}
+
+// FiatRate is the price of one bitcoin in a fiat currency, held exactly as the decimal string given, so that every
+// platform converting with it gets the same result, which floating point arithmetic does not guarantee. Its fields
+// are validated again on each conversion, so a receipt always shows the rate and decimals it was converted with.
+type FiatRate struct {
+	CurrencyCode string // ISO 4217 code, such as "USD"
+	Rate         string // price of 1 BTC, as a decimal string
+	Decimals     int    // digits of the currency's minor unit, such as 2 for cents
+	Timestamp    int64  // seconds since unix epoch the rate was quoted, 0 if unknown
+}
+
+// RatedAmount is an amount displayed in both BTC and a fiat currency, along with the rate used to convert it, for
+// receipts. The fiat value is rounded half away from zero to the currency's minor unit.
+type RatedAmount struct {
+	Satoshis       int64
+	BTC            string // BTC with 8 decimals, such as "0.00120000"
+	Fiat           string // fiat with the currency's decimals, such as "40.95"
+	FiatMinorUnits int64  // fiat in minor units, such as cents
+	CurrencyCode   string
+	Rate           string
+	RateTimestamp  int64
+}
+
+/// Constructors
+
+// NewAmount returns a pointer to an Amount of satoshis.
+func NewAmount(satoshis int64) *Amount {
+	return &Amount{Satoshis: satoshis}
+}
+
+// NewFiatRate returns a pointer to a FiatRate of a currency, the price of 1 BTC as a decimal string such as
+// "34123.45", the number of decimals of the currency's minor unit, and when the rate was quoted. Returns
+// ErrFiatCurrencyInvalid, ErrFiatRateInvalid or ErrFiatDecimalsInvalid if any is malformed.
+func NewFiatRate(currencyCode string, rate string, decimals int, timestamp int64) (*FiatRate, error) {
+	fiatRate := &FiatRate{CurrencyCode: strings.ToUpper(currencyCode), Rate: rate, Decimals: decimals, Timestamp: timestamp}
+	if _, err := fiatRate.parse(); err != nil {
+		return nil, err
+	}
+	return fiatRate, nil
+}
+
+/// Receiver functions
+
+// BTC returns the amount in BTC with 8 decimals, such as "0.00120000".
+func (a *Amount) BTC() string {
+	return formatScaledInteger(big.NewInt(a.Satoshis), btcDecimals)
+}
+
+// WithRate returns the amount in both BTC and the currency of a fiat rate, with the rate used. Returns
+// ErrFiatCurrencyInvalid, ErrFiatRateInvalid or ErrFiatDecimalsInvalid if the rate's fields were set to malformed
+// values.
+func (a *Amount) WithRate(fiatRate *FiatRate) (*RatedAmount, error) {
+	if fiatRate == nil {
+		return nil, errors.New("fiat rate cannot be nil")
+	}
+	rate, err := fiatRate.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	// satoshis * rate * 10^decimals / 10^8, in minor units
+	minor := new(big.Rat).SetInt64(a.Satoshis)
+	minor.Mul(minor, rate)
+	minor.Mul(minor, new(big.Rat).SetInt(pow10(fiatRate.Decimals)))
+	minor.Quo(minor, new(big.Rat).SetInt(pow10(btcDecimals)))
+	rounded := roundHalfAwayFromZero(minor)
+	if !rounded.IsInt64() {
+		return nil, errors.New("fiat amount out of range")
+	}
+
+	return &RatedAmount{
+		Satoshis:       a.Satoshis,
+		BTC:            a.BTC(),
+		Fiat:           formatScaledInteger(rounded, fiatRate.Decimals),
+		FiatMinorUnits: rounded.Int64(),
+		CurrencyCode:   fiatRate.CurrencyCode,
+		Rate:           fiatRate.Rate,
+		RateTimestamp:  fiatRate.Timestamp,
+	}, nil
+}
+
+/// Unexported functions
+
+// parse returns the rate of a FiatRate, after validating each of its fields.
+func (r *FiatRate) parse() (*big.Rat, error) {
+	if len(r.CurrencyCode) != 3 || strings.Trim(r.CurrencyCode, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return nil, ErrFiatCurrencyInvalid
+	}
+	if !fiatRatePattern.MatchString(r.Rate) {
+		return nil, ErrFiatRateInvalid
+	}
+	rate, ok := new(big.Rat).SetString(r.Rate)
+	if !ok || rate.Sign() <= 0 {
+		return nil, ErrFiatRateInvalid
+	}
+	if r.Decimals < 0 || r.Decimals > maxFiatDecimals {
+		return nil, ErrFiatDecimalsInvalid
+	}
+	return rate, nil
+}
+
+// roundHalfAwayFromZero returns a rational rounded to the nearest integer, with halves rounded away from zero.
+func roundHalfAwayFromZero(value *big.Rat) *big.Int {
+	numerator := new(big.Int).Abs(value.Num())
+	denominator := value.Denom()
+	// floor((2n + d) / 2d)
+	rounded := new(big.Int).Mul(numerator, big.NewInt(2))
+	rounded.Add(rounded, denominator)
+	rounded.Quo(rounded, new(big.Int).Mul(denominator, big.NewInt(2)))
+	if value.Sign() < 0 {
+		rounded.Neg(rounded)
+	}
+	return rounded
+}
+
+// formatScaledInteger formats an integer count of 10^-decimals units as a decimal string with exactly that many
+// decimals.
+func formatScaledInteger(value *big.Int, decimals int) string {
+	digits := new(big.Int).Abs(value).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+	}
+	if decimals == 0 {
+		return sign + digits
+	}
+	return sign + digits[:len(digits)-decimals] + "." + digits[len(digits)-decimals:]
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
@@ -0,0 +1,85 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmount_BTC(t *testing.T) {
+	assert.Equal(t, "0.00120000", NewAmount(120000).BTC())
+	assert.Equal(t, "0.00000001", NewAmount(1).BTC())
+	assert.Equal(t, "0.00000000", NewAmount(0).BTC())
+	assert.Equal(t, "21000000.00000000", NewAmount(2100000000000000).BTC())
+	assert.Equal(t, "-1.50000000", NewAmount(-150000000).BTC())
+}
+
+func TestAmount_WithRate(t *testing.T) {
+	rate, err := NewFiatRate("usd", "34123.45", 2, 1700000000)
+	assert.Nil(t, err)
+	assert.Equal(t, "USD", rate.CurrencyCode)
+
+	rated, err := NewAmount(120000).WithRate(rate)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(120000), rated.Satoshis)
+	assert.Equal(t, "0.00120000", rated.BTC)
+	// 40.94814 rounds to the nearest cent
+	assert.Equal(t, "40.95", rated.Fiat)
+	assert.Equal(t, int64(4095), rated.FiatMinorUnits)
+	assert.Equal(t, "USD", rated.CurrencyCode)
+	assert.Equal(t, "34123.45", rated.Rate)
+	assert.Equal(t, int64(1700000000), rated.RateTimestamp)
+
+	// halves round away from zero, where floating point would give 0.01 or 0.02 depending on representation
+	rate, _ = NewFiatRate("USD", "30000", 2, 0)
+	rated, _ = NewAmount(50).WithRate(rate)
+	assert.Equal(t, "0.02", rated.Fiat)
+	rated, _ = NewAmount(-50).WithRate(rate)
+	assert.Equal(t, "-0.02", rated.Fiat)
+	rated, _ = NewAmount(49).WithRate(rate)
+	assert.Equal(t, "0.01", rated.Fiat)
+
+	// currencies without minor units
+	rate, _ = NewFiatRate("JPY", "5012345.678", 0, 0)
+	rated, _ = NewAmount(100000000).WithRate(rate)
+	assert.Equal(t, "5012346", rated.Fiat)
+	assert.Equal(t, int64(5012346), rated.FiatMinorUnits)
+}
+
+func TestNewFiatRate_Invalid(t *testing.T) {
+	for _, rate := range []string{"", "0", "0.00", "-1", "1e3", "1/3", " 1", "1.", ".5", "NaN"} {
+		_, err := NewFiatRate("USD", rate, 2, 0)
+		assert.Equal(t, ErrFiatRateInvalid, err, rate)
+	}
+	_, err := NewFiatRate("US", "1", 2, 0)
+	assert.Equal(t, ErrFiatCurrencyInvalid, err)
+	_, err = NewFiatRate("US1", "1", 2, 0)
+	assert.Equal(t, ErrFiatCurrencyInvalid, err)
+	_, err = NewFiatRate("USD", "1", 9, 0)
+	assert.Equal(t, ErrFiatDecimalsInvalid, err)
+
+	_, err = NewAmount(1).WithRate(nil)
+	assert.NotNil(t, err)
+	_, err = NewAmount(1).WithRate(&FiatRate{CurrencyCode: "USD"})
+	assert.Equal(t, ErrFiatRateInvalid, err)
+}
+
+func TestAmount_WithRate_RevalidatesChangedRate(t *testing.T) {
+	rate, err := NewFiatRate("USD", "34123.45", 2, 0)
+	assert.Nil(t, err)
+
+	// the receipt shows the rate it was converted with
+	rate.Rate = "1.00"
+	rated, err := NewAmount(100000000).WithRate(rate)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.00", rated.Rate)
+	assert.Equal(t, "1.00", rated.Fiat)
+
+	rate.Decimals = -1
+	_, err = NewAmount(100000000).WithRate(rate)
+	assert.Equal(t, ErrFiatDecimalsInvalid, err)
+	rate.Decimals = 2
+	rate.Rate = "-1"
+	_, err = NewAmount(100000000).WithRate(rate)
+	assert.Equal(t, ErrFiatRateInvalid, err)
+}
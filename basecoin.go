@@ -10,15 +10,20 @@ import (
 )
 
 var (
-	BaseCoinBip49MainNet = &BaseCoin{Purpose: 49, Coin: 0, Account: 0}
-	BaseCoinBip49TestNet = &BaseCoin{Purpose: 49, Coin: 1, Account: 0}
-	BaseCoinBip84MainNet = &BaseCoin{Purpose: 84, Coin: 0, Account: 0}
-	BaseCoinBip84TestNet = &BaseCoin{Purpose: 84, Coin: 1, Account: 0}
+	BaseCoinBip49MainNet  = &BaseCoin{Purpose: 49, Coin: 0, Account: 0}
+	BaseCoinBip49TestNet  = &BaseCoin{Purpose: 49, Coin: 1, Account: 0}
+	BaseCoinBip84MainNet  = &BaseCoin{Purpose: 84, Coin: 0, Account: 0}
+	BaseCoinBip84TestNet  = &BaseCoin{Purpose: 84, Coin: 1, Account: 0}
+	BaseCoinBip86MainNet  = &BaseCoin{Purpose: 86, Coin: 0, Account: 0}
+	BaseCoinBip86TestNet  = &BaseCoin{Purpose: 86, Coin: 1, Account: 0}
+	BaseCoinBip49Litecoin = &BaseCoin{Purpose: 49, Coin: 2, Account: 0}
+	BaseCoinBip84Litecoin = &BaseCoin{Purpose: 84, Coin: 2, Account: 0}
 )
 
 const (
-	mainnet = 0
-	testnet = 1
+	mainnet  = 0
+	testnet  = 1
+	litecoin = 2
 
 	xpub = "xpub"
 	ypub = "ypub"
@@ -26,6 +31,8 @@ const (
 	tpub = "tpub"
 	upub = "upub"
 	vpub = "vpub"
+	ltub = "Ltub"
+	mtub = "Mtub"
 )
 
 var (
@@ -40,9 +47,10 @@ var (
 
 // BaseCoin is used to provide information about the current user's wallet.
 type BaseCoin struct {
-	Purpose int
-	Coin    int
-	Account int
+	Purpose     int
+	Coin        int
+	Account     int
+	TestNetwork int // `TestNetwork` constant selecting the network if Coin is 1, regtest by default; ignored on mainnet
 }
 
 // NewBaseCoin instantiates a new object and sets values
@@ -50,36 +58,43 @@ func NewBaseCoin(purpose int, coin int, account int) *BaseCoin {
 	return &BaseCoin{Purpose: purpose, Coin: coin, Account: account}
 }
 
+// NewTestNetworkBaseCoin returns a BaseCoin of coin 1 on a test network, one of the `TestNetwork` constants, which
+// selects its address HRP, and base58 version bytes of its addresses and WIF private keys.
+func NewTestNetworkBaseCoin(purpose int, account int, testNetwork int) (*BaseCoin, error) {
+	if _, ok := testNetworkRegistry[testNetwork]; !ok {
+		return nil, errors.New("unknown test network")
+	}
+	return &BaseCoin{Purpose: purpose, Coin: testnet, Account: account, TestNetwork: testNetwork}, nil
+}
+
 // NewBaseCoinFromAccountPubKey returns a new BaseCoin pointer based on prefix, or error if unrecognized.
 func NewBaseCoinFromAccountPubKey(key string) (*BaseCoin, error) {
 	// prefix := key[:4]
 	var bc *BaseCoin
 
-	dec, _, err := base58.CheckDecode(key)
+	dec, version, err := base58.CheckDecode(key)
 	if err != nil {
 		return nil, err
 	}
+	if len(dec) < 12 {
+		return nil, errors.New("unrecognized account key prefix")
+	}
 
-	decoded := []byte{0x04}
+	decoded := []byte{version}
 	decoded = append(decoded, dec...)
 
-	acctBytes := decoded[9:13]                                   // bytes 9 through 12 are the 4 bytes of the account "child number", but first byte 0x04 is dropped in CheckDecode
+	acctBytes := decoded[9:13]                                   // bytes 9 through 12 are the 4 bytes of the account "child number", but the first version byte is split off by CheckDecode
 	acctIndex := binary.BigEndian.Uint32(acctBytes) & 0x0FFFFFFF // if hardened, remove hardened offset
 	acct := int(acctIndex)
 
 	prefix := decoded[:4]
-	if bytes.Equal(prefix, pubkeyIDs[xpub]) {
-		bc = &BaseCoin{Purpose: bip44purpose, Coin: mainnet, Account: acct}
-	} else if bytes.Equal(prefix, pubkeyIDs[ypub]) {
-		bc = &BaseCoin{Purpose: bip49purpose, Coin: mainnet, Account: acct}
-	} else if bytes.Equal(prefix, pubkeyIDs[zpub]) {
-		bc = &BaseCoin{Purpose: bip84purpose, Coin: mainnet, Account: acct}
-	} else if bytes.Equal(prefix, pubkeyIDs[tpub]) {
-		bc = &BaseCoin{Purpose: bip44purpose, Coin: testnet, Account: acct}
-	} else if bytes.Equal(prefix, pubkeyIDs[upub]) {
-		bc = &BaseCoin{Purpose: bip49purpose, Coin: testnet, Account: acct}
-	} else if bytes.Equal(prefix, pubkeyIDs[vpub]) {
-		bc = &BaseCoin{Purpose: bip84purpose, Coin: testnet, Account: acct}
+	for idType, id := range pubkeyIDs {
+		if !bytes.Equal(prefix, id) {
+			continue
+		}
+		if purpose, coin, ok := extendedPubkeyBaseCoin(idType); ok {
+			bc = &BaseCoin{Purpose: purpose, Coin: coin, Account: acct}
+		}
 	}
 
 	if bc != nil {
@@ -99,6 +114,11 @@ func (bc *BaseCoin) UpdateCoin(coin int) {
 	bc.Coin = coin
 }
 
+// UpdateTestNetwork updates the test network on the BaseCoin receiver.
+func (bc *BaseCoin) UpdateTestNetwork(testNetwork int) {
+	bc.TestNetwork = testNetwork
+}
+
 // UpdateAccount updates the coin account on the BaseCoin receiver.
 func (bc *BaseCoin) UpdateAccount(account int) {
 	bc.Account = account
@@ -110,53 +130,49 @@ func (bc *BaseCoin) GetBech32HRP() (string, error) {
 		return "", errors.New("no basecoin provided")
 	}
 
-	if bc.Purpose != 84 {
+	if bc.Purpose != bip84purpose && bc.Purpose != bip86purpose {
 		return "", errors.New("basecoin purpose is not a segwit purpose")
 	}
-	if bc.Coin == 0 {
-		return "bc", nil
-	}
-	return "bcrt", nil
+	return bc.network().chainParams.Bech32HRPSegwit, nil
 }
 
 func (bc *BaseCoin) isTestNet() bool {
-	return bc.Coin != 0
+	return bc.Coin != mainnet && bc.Coin != litecoin
 }
 
 func (bc *BaseCoin) defaultExtendedPubkeyType() (string, error) {
-	if bc.Purpose == bip44purpose {
-		if bc.Coin == mainnet {
-			return xpub, nil
-		}
-		if bc.Coin == testnet {
-			return tpub, nil
-		}
-		return "", ErrInvalidCoinValue
+	if bc.Purpose != bip44purpose && bc.Purpose != bip49purpose && bc.Purpose != bip84purpose && bc.Purpose != bip86purpose {
+		return "", ErrInvalidPurposeValue
 	}
-	if bc.Purpose == bip49purpose {
-		if bc.Coin == mainnet {
-			return ypub, nil
-		}
-		if bc.Coin == testnet {
-			return upub, nil
-		}
+	network, ok := networkRegistry[bc.Coin]
+	if !ok {
 		return "", ErrInvalidCoinValue
 	}
-	if bc.Purpose == bip84purpose {
-		if bc.Coin == mainnet {
-			return zpub, nil
-		}
-		if bc.Coin == testnet {
-			return vpub, nil
+	idType, ok := network.extendedPubkeyTypes[bc.Purpose]
+	if !ok {
+		// SLIP-132 defines no version bytes for taproot, or for Litecoin native segwit; use those of BIP44
+		return network.extendedPubkeyTypes[bip44purpose], nil
+	}
+	return idType, nil
+}
+
+// validateIndices returns error if the purpose, coin or account cannot be derived as a hardened index.
+func (bc *BaseCoin) validateIndices() error {
+	for _, index := range []int{bc.Purpose, bc.Coin, bc.Account} {
+		if err := validateDerivationIndex(index); err != nil {
+			return err
 		}
-		return "", ErrInvalidCoinValue
 	}
-	return "", ErrInvalidPurposeValue
+	return nil
 }
 
 func (bc *BaseCoin) defaultNetParams() *chaincfg.Params {
-	if bc.isTestNet() {
-		return &chaincfg.RegressionNetParams
-	}
-	return &chaincfg.MainNetParams
+	return bc.network().chainParams
+}
+
+// withPurpose returns a copy of the BaseCoin, on the same network, with a different purpose.
+func (bc *BaseCoin) withPurpose(purpose int) *BaseCoin {
+	copied := *bc
+	copied.Purpose = purpose
+	return &copied
 }
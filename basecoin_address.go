@@ -2,9 +2,7 @@ package cnlib
 
 import (
 	"errors"
-	"strings"
 
-	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/base58"
 )
@@ -13,6 +11,7 @@ const (
 	bip44purpose = 44
 	bip49purpose = 49
 	bip84purpose = 84
+	bip86purpose = 86
 )
 
 // constants for size in bytes of pieces of a transaction
@@ -21,9 +20,12 @@ const (
 	p2shOutputSize        = 32
 	p2wpkhOutputSize      = 31
 	p2DefaultOutputSize   = 32
+	p2pkOutputSize        = 44
 	p2pkhInputSize        = 147
 	p2shSegwitInputSize   = 91
 	p2wpkhSegwitInputSize = 68
+	p2trOutputSize        = 43
+	p2trKeyPathInputSize  = 58
 	baseSize              = 11
 )
 
@@ -42,35 +44,25 @@ func AddressIsBase58CheckEncoded(addr string) error {
 	return errors.New("address is not base58check encoded")
 }
 
-// AddressIsValidSegwitAddress decodes the address, returns true if is a witness type.
+// AddressIsValidSegwitAddress decodes the address, returns nil if it is a valid segwit address of a supported network.
+// Per BIP350, witness version 0 addresses must be bech32 encoded with a 20 or 32 byte program, and later versions,
+// such as taproot, bech32m encoded with a 2 to 40 byte program.
 func AddressIsValidSegwitAddress(addr string) error {
-	params := &chaincfg.MainNetParams
-	if strings.HasPrefix(strings.ToLower(addr), "bcrt") {
-		params = &chaincfg.RegressionNetParams
-	}
-
-	if !strings.HasPrefix(strings.ToLower(addr), "bc") {
+	network := bech32NetworkForAddress(addr)
+	if network == nil {
 		return errors.New("address is not a bech32 encoded segwit address")
 	}
 
-	address, err := btcutil.DecodeAddress(addr, params)
-
-	if err != nil {
-		return err
-	}
-
-	_, okWpkh := address.(*btcutil.AddressWitnessPubKeyHash)
-	_, okWsh := address.(*btcutil.AddressWitnessScriptHash)
-
-	if okWpkh || okWsh {
-		return nil
-	}
-
-	return errors.New("address is not a bech32 encoded segwit address")
+	_, _, err := decodeSegwitAddress(addr, network.chainParams.Bech32HRPSegwit)
+	return err
 }
 
 // HRPFromAddress decodes the given address, and if a SegWit address, returns the HRP.
 func (bc *BaseCoin) HRPFromAddress(addr string) (string, error) {
+	if isTaprootAddress(addr, bc.defaultNetParams()) {
+		return bc.defaultNetParams().Bech32HRPSegwit, nil
+	}
+
 	address, addrErr := btcutil.DecodeAddress(addr, bc.defaultNetParams())
 
 	if addrErr != nil {
@@ -95,9 +87,19 @@ func (bc *BaseCoin) bytesPerInput(utxo *UTXO) (int, error) {
 		if bc.Purpose == bip84purpose {
 			return p2wpkhSegwitInputSize, nil
 		}
+		if bc.Purpose == bip86purpose {
+			return p2trKeyPathInputSize, nil
+		}
+		if bc.Purpose == bip44purpose {
+			return p2pkhInputSize, nil
+		}
 		return p2shSegwitInputSize, nil
 	}
 
+	if utxo.descriptor != nil {
+		return utxo.descriptor.inputBytes()
+	}
+
 	if utxo.ImportedPrivateKey != nil {
 		addr, err := btcutil.DecodeAddress(utxo.ImportedPrivateKey.SelectedAddress, bc.defaultNetParams())
 		if err != nil {
@@ -119,6 +121,12 @@ func (bc *BaseCoin) bytesPerInput(utxo *UTXO) (int, error) {
 		if utxo.Path.Purpose == bip84purpose {
 			return p2wpkhSegwitInputSize, nil
 		}
+		if utxo.Path.Purpose == bip86purpose {
+			return p2trKeyPathInputSize, nil
+		}
+		if utxo.Path.Purpose == bip44purpose {
+			return p2pkhInputSize, nil
+		}
 		return p2shSegwitInputSize, nil
 	}
 
@@ -129,6 +137,12 @@ func (bc *BaseCoin) bytesPerChangeOuptut() int {
 	if bc.Purpose == bip84purpose {
 		return p2wpkhOutputSize
 	}
+	if bc.Purpose == bip86purpose {
+		return p2trOutputSize
+	}
+	if bc.Purpose == bip44purpose {
+		return p2pkhOutputSize
+	}
 	return p2shOutputSize
 }
 
@@ -148,12 +162,7 @@ func (bc *BaseCoin) totalBytes(utxos []*UTXO, address string, includeChange bool
 		total = total + bc.bytesPerChangeOuptut()
 	}
 
-	addressForSizeEstimation := address
-	if address == PlaceholderDestination {
-		addressForSizeEstimation = "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"
-	}
-
-	outBytes, err := bc.bytesPerOutputAddress(addressForSizeEstimation)
+	outBytes, err := bc.bytesPerDestination(address)
 	if err != nil {
 		return 0, err
 	}
@@ -162,7 +171,20 @@ func (bc *BaseCoin) totalBytes(utxos []*UTXO, address string, includeChange bool
 	return total, nil
 }
 
+// bytesPerDestination returns the size of the output paying a destination address, which may be
+// PlaceholderDestination.
+func (bc *BaseCoin) bytesPerDestination(address string) (int, error) {
+	if address == PlaceholderDestination {
+		return bc.bytesPerOutputAddress("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu")
+	}
+	return bc.bytesPerOutputAddress(address)
+}
+
 func (bc *BaseCoin) bytesPerOutputAddress(addr string) (int, error) {
+	if isTaprootAddress(addr, bc.defaultNetParams()) {
+		return p2trOutputSize, nil
+	}
+
 	dec, decErr := btcutil.DecodeAddress(addr, bc.defaultNetParams())
 	if decErr != nil {
 		return 0, decErr
@@ -124,7 +124,7 @@ func TestBytesPerInputP2PKHInput(t *testing.T) {
 
 	info := NewPreviousOutputInfo(address, "txid string", 0, 11413)
 	key := ImportedPrivateKey{wif: wif, PossibleAddresses: address, PrivateKeyAsWIF: pkString, PreviousOutputInfo: info}
-	utxo := NewUTXO(info.Txid, info.Index, info.Amount, nil, &key, true)
+	utxo := NewUTXO(info.Txid, info.Index, int64(info.Amount), nil, &key, true)
 	bpi, err := BaseCoinBip84MainNet.bytesPerInput(utxo)
 	assert.Nil(t, err)
 	assert.Equal(t, p2pkhInputSize, bpi)
@@ -138,12 +138,29 @@ func TestBytesPerInputP2PKHInput_Copy(t *testing.T) {
 
 	info := NewPreviousOutputInfo(address, "txid string", 0, 5782)
 	key := ImportedPrivateKey{wif: wif, PossibleAddresses: address, PrivateKeyAsWIF: pkString, PreviousOutputInfo: info}
-	utxo := NewUTXO(info.Txid, info.Index, info.Amount, nil, &key, true)
+	utxo := NewUTXO(info.Txid, info.Index, int64(info.Amount), nil, &key, true)
 	bpi, err := BaseCoinBip84MainNet.bytesPerInput(utxo)
 	assert.Nil(t, err)
 	assert.Equal(t, p2pkhInputSize, bpi)
 }
 
+func TestBytesPerInputBIP44Input(t *testing.T) {
+	bc := NewBaseCoin(44, 0, 0)
+	path := NewDerivationPath(bc, 0, 0)
+	utxo := NewUTXO("previous txid", 0, 1, path, nil, true)
+	bpi, err := BaseCoinBip84MainNet.bytesPerInput(utxo)
+	assert.Nil(t, err)
+	assert.Equal(t, p2pkhInputSize, bpi)
+	bpi, err = bc.bytesPerInput(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, p2pkhInputSize, bpi)
+}
+
+func TestBytesPerChangeOuptutBIP44(t *testing.T) {
+	bpco := NewBaseCoin(44, 0, 0).bytesPerChangeOuptut()
+	assert.Equal(t, p2pkhOutputSize, bpco)
+}
+
 func TestBytesPerChangeOuptutBIP84(t *testing.T) {
 	bpco := BaseCoinBip84MainNet.bytesPerChangeOuptut()
 	assert.Equal(t, p2wpkhOutputSize, bpco)
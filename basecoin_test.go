@@ -57,8 +57,22 @@ func TestAccountExtendedKeyPrefix_m_45_0(t *testing.T) {
 	assert.Equal(t, "", key)
 }
 
-func TestAccountExtendedKeyPrefix_m_44_2(t *testing.T) {
-	bc := NewBaseCoin(44, 2, 0)
+func TestAccountExtendedKeyPrefix_m_84_2(t *testing.T) {
+	bc := NewBaseCoin(84, 2, 0)
+	key, err := bc.defaultExtendedPubkeyType()
+	assert.Nil(t, err)
+	assert.Equal(t, "Ltub", key)
+}
+
+func TestAccountExtendedKeyPrefix_m_49_2(t *testing.T) {
+	bc := NewBaseCoin(49, 2, 0)
+	key, err := bc.defaultExtendedPubkeyType()
+	assert.Nil(t, err)
+	assert.Equal(t, "Mtub", key)
+}
+
+func TestAccountExtendedKeyPrefix_m_44_3(t *testing.T) {
+	bc := NewBaseCoin(44, 3, 0)
 	key, err := bc.defaultExtendedPubkeyType()
 	assert.NotNil(t, err)
 	assert.EqualError(t, errors.New("invalid basecoin coin value"), err.Error())
@@ -0,0 +1,266 @@
+package cnlib
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// Bitcoin Core relay policy limits. MAX_STANDARD_TX_WEIGHT is 400000, expressed here in virtual bytes.
+const (
+	maxStandardTxVsize      = 100000
+	maxStandardTxSigOpsCost = 16000
+	legacySigOpsCost        = 4 // legacy sigops are scaled by the witness scale factor
+	witnessSigOpsCost       = 1
+)
+
+/// Type Definitions
+
+// BatchPaymentPlanner splits a large payout batch across as few transactions as possible while keeping each within
+// standardness limits on size and signature operations. Add utxos and recipients one at a time using `AddUTXO` and
+// `AddRecipient`, as gomobile does not support custom arrays/slices.
+type BatchPaymentPlanner struct {
+	basecoin      *BaseCoin
+	utxos         []*UTXO
+	recipients    []*BatchRecipient
+	MaxVsize      int // largest planned transaction, in virtual bytes
+	MaxSigOpsCost int // largest planned signature operation cost per transaction
+}
+
+// BatchRecipient is a single payment in a batch.
+type BatchRecipient struct {
+	Address string
+	Amount  int
+}
+
+// BatchPaymentPlan is the set of transactions planned for a batch. Recipients keep the order they were added in,
+// across and within transactions.
+type BatchPaymentPlan struct {
+	transactions []*PlannedBatchTransaction
+	TotalFee     int
+}
+
+// PlannedBatchTransaction is a single transaction of a BatchPaymentPlan: the recipients it pays and the utxos it
+// spends. ChangeAmount is 0 if change would be dust, in which case it is added to the fee.
+type PlannedBatchTransaction struct {
+	recipients   []*BatchRecipient
+	utxos        []*UTXO
+	FeeAmount    int
+	ChangeAmount int
+	Vsize        int
+	SigOpsCost   int
+}
+
+/// Constructors
+
+// NewBatchPaymentPlanner returns a pointer to an empty BatchPaymentPlanner for the given BaseCoin, with Bitcoin Core's
+// standardness limits.
+func NewBatchPaymentPlanner(basecoin *BaseCoin) *BatchPaymentPlanner {
+	return &BatchPaymentPlanner{
+		basecoin:      basecoin,
+		utxos:         []*UTXO{},
+		recipients:    []*BatchRecipient{},
+		MaxVsize:      maxStandardTxVsize,
+		MaxSigOpsCost: maxStandardTxSigOpsCost,
+	}
+}
+
+/// Receiver functions
+
+// AddUTXO adds a utxo available to fund the batch.
+func (p *BatchPaymentPlanner) AddUTXO(utxo *UTXO) {
+	p.utxos = append(p.utxos, utxo)
+}
+
+// AddRecipient adds a payment to the batch. Returns error if the amount is dust or the address is not supported.
+func (p *BatchPaymentPlanner) AddRecipient(address string, amount int) error {
+	if amount < dustThreshold {
+		return errors.New("amount is below dust threshold")
+	}
+	if _, err := p.basecoin.bytesPerOutputAddress(address); err != nil {
+		return err
+	}
+	p.recipients = append(p.recipients, &BatchRecipient{Address: address, Amount: amount})
+	return nil
+}
+
+// RecipientCount returns the number of recipients added.
+func (p *BatchPaymentPlanner) RecipientCount() int {
+	return len(p.recipients)
+}
+
+// Plan splits the recipients into transactions at feeRate. Each transaction takes as many of the remaining
+// recipients as fit within the limits, funded by the largest remaining utxos, to amortize the fixed size of each
+// transaction and its change output. Returns ErrInsufficientFunds if the utxos cannot cover every recipient, or error
+// if a single recipient cannot be paid within the limits.
+func (p *BatchPaymentPlanner) Plan(feeRate int) (*BatchPaymentPlan, error) {
+	if feeRate < 0 {
+		return nil, errors.New("fee rate cannot be negative")
+	}
+	if len(p.recipients) == 0 {
+		return nil, errors.New("no recipients to plan")
+	}
+
+	pool := make([]*UTXO, len(p.utxos))
+	copy(pool, p.utxos)
+	sort.SliceStable(pool, func(i, j int) bool { return pool[i].Amount > pool[j].Amount })
+
+	plan := &BatchPaymentPlan{}
+	var current *PlannedBatchTransaction
+	group := []*BatchRecipient{}
+
+	for _, recipient := range p.recipients {
+		candidate, err := p.planTransaction(append(group, recipient), pool, int64(feeRate))
+		if err != nil {
+			return nil, err
+		}
+		if p.withinLimits(candidate) {
+			current = candidate
+			group = candidate.recipients
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("recipient %s cannot be paid within standardness limits", recipient.Address)
+		}
+
+		plan.add(current)
+		pool = pool[len(current.utxos):]
+		current, err = p.planTransaction([]*BatchRecipient{recipient}, pool, int64(feeRate))
+		if err != nil {
+			return nil, err
+		}
+		if !p.withinLimits(current) {
+			return nil, fmt.Errorf("recipient %s cannot be paid within standardness limits", recipient.Address)
+		}
+		group = current.recipients
+	}
+	plan.add(current)
+
+	return plan, nil
+}
+
+// TransactionCount returns the number of planned transactions.
+func (bp *BatchPaymentPlan) TransactionCount() int {
+	return len(bp.transactions)
+}
+
+// TransactionAtIndex returns the planned transaction at a given index, or error if out of bounds.
+func (bp *BatchPaymentPlan) TransactionAtIndex(index int) (*PlannedBatchTransaction, error) {
+	if index < 0 || index > len(bp.transactions)-1 {
+		return nil, errors.New("index must be within range of transactions")
+	}
+	return bp.transactions[index], nil
+}
+
+// RecipientCount returns the number of recipients paid by the transaction.
+func (pt *PlannedBatchTransaction) RecipientCount() int {
+	return len(pt.recipients)
+}
+
+// RecipientAtIndex returns the recipient at a given index, or error if out of bounds.
+func (pt *PlannedBatchTransaction) RecipientAtIndex(index int) (*BatchRecipient, error) {
+	if index < 0 || index > len(pt.recipients)-1 {
+		return nil, errors.New("index must be within range of recipients")
+	}
+	return pt.recipients[index], nil
+}
+
+// UtxoCount returns the number of utxos spent by the transaction.
+func (pt *PlannedBatchTransaction) UtxoCount() int {
+	return len(pt.utxos)
+}
+
+// UTXOAtIndex returns the utxo at a given index, or error if out of bounds.
+func (pt *PlannedBatchTransaction) UTXOAtIndex(index int) (*UTXO, error) {
+	if index < 0 || index > len(pt.utxos)-1 {
+		return nil, errors.New("index must be within range of utxos")
+	}
+	return pt.utxos[index], nil
+}
+
+/// Unexported functions
+
+func (bp *BatchPaymentPlan) add(tx *PlannedBatchTransaction) {
+	bp.transactions = append(bp.transactions, tx)
+	bp.TotalFee += tx.FeeAmount
+}
+
+func (p *BatchPaymentPlanner) withinLimits(tx *PlannedBatchTransaction) bool {
+	return tx.Vsize <= p.MaxVsize && tx.SigOpsCost <= p.MaxSigOpsCost
+}
+
+// planTransaction funds a transaction paying recipients with utxos taken in order from the front of pool.
+func (p *BatchPaymentPlanner) planTransaction(recipients []*BatchRecipient, pool []*UTXO, feeRate int64) (*PlannedBatchTransaction, error) {
+	tx := &PlannedBatchTransaction{recipients: recipients}
+	outputs := int64(0)
+	size := baseSize
+	for _, recipient := range recipients {
+		outputBytes, err := p.basecoin.bytesPerOutputAddress(recipient.Address)
+		if err != nil {
+			return nil, err
+		}
+		sigOps, err := p.outputSigOpsCost(recipient.Address)
+		if err != nil {
+			return nil, err
+		}
+		outputs += int64(recipient.Amount)
+		size += outputBytes
+		tx.SigOpsCost += sigOps
+	}
+
+	inputs := int64(0)
+	for _, utxo := range pool {
+		inputBytes, err := p.basecoin.bytesPerInput(utxo)
+		if err != nil {
+			return nil, err
+		}
+		tx.utxos = append(tx.utxos, utxo)
+		inputs += utxo.Amount
+		size += inputBytes
+		tx.SigOpsCost += inputSigOpsCost(inputBytes)
+
+		if inputs < outputs+feeRate*int64(size) {
+			continue
+		}
+		changeSize := size + p.basecoin.bytesPerChangeOuptut()
+		change := inputs - outputs - feeRate*int64(changeSize)
+		if change >= dustThreshold {
+			size = changeSize
+			tx.ChangeAmount = int(change)
+		}
+		tx.FeeAmount = int(inputs - outputs - int64(tx.ChangeAmount))
+		tx.Vsize = size
+		return tx, nil
+	}
+
+	return nil, ErrInsufficientFunds
+}
+
+// outputSigOpsCost counts the signature operations in a recipient's output script. Only bare public key and P2PKH
+// outputs contain any; P2SH and segwit outputs are counted when spent.
+func (p *BatchPaymentPlanner) outputSigOpsCost(address string) (int, error) {
+	if isTaprootAddress(address, p.basecoin.defaultNetParams()) {
+		return 0, nil
+	}
+	decoded, err := btcutil.DecodeAddress(address, p.basecoin.defaultNetParams())
+	if err != nil {
+		return 0, err
+	}
+	switch decoded.(type) {
+	case *btcutil.AddressPubKey, *btcutil.AddressPubKeyHash:
+		return legacySigOpsCost, nil
+	}
+	return 0, nil
+}
+
+// inputSigOpsCost counts the signature operations of an input by its size, as estimated by `bytesPerInput`. A P2PKH
+// signature script only pushes data, its spent script's sigop was counted in the funding transaction. All other
+// inputs spend a single key P2WPKH witness program.
+func inputSigOpsCost(inputBytes int) int {
+	if inputBytes == p2pkhInputSize {
+		return 0
+	}
+	return witnessSigOpsCost
+}
@@ -0,0 +1,106 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBatchPaymentPlanner(utxoCount int) *BatchPaymentPlanner {
+	planner := NewBatchPaymentPlanner(BaseCoinBip84MainNet)
+	for i := 0; i < utxoCount; i++ {
+		path := NewDerivationPath(BaseCoinBip84MainNet, 0, i)
+		planner.AddUTXO(NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", i, 100000, path, nil, true))
+	}
+	return planner
+}
+
+func TestBatchPaymentPlanner_FitsInOneTransaction(t *testing.T) {
+	planner := newTestBatchPaymentPlanner(2)
+	assert.Nil(t, planner.AddRecipient("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", 10000))
+	assert.Nil(t, planner.AddRecipient("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", 20000))
+	assert.Nil(t, planner.AddRecipient("37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf", 30000))
+
+	plan, err := planner.Plan(5)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, plan.TransactionCount())
+
+	tx, err := plan.TransactionAtIndex(0)
+	assert.Nil(t, err)
+	expectedVsize := baseSize + p2wpkhSegwitInputSize + 2*p2wpkhOutputSize + p2shOutputSize + p2wpkhOutputSize
+	assert.Equal(t, 3, tx.RecipientCount())
+	assert.Equal(t, 1, tx.UtxoCount())
+	assert.Equal(t, expectedVsize, tx.Vsize)
+	assert.Equal(t, expectedVsize*5, tx.FeeAmount)
+	assert.Equal(t, 100000-60000-tx.FeeAmount, tx.ChangeAmount)
+	assert.Equal(t, 1, tx.SigOpsCost)
+	assert.Equal(t, tx.FeeAmount, plan.TotalFee)
+}
+
+func TestBatchPaymentPlanner_SplitsAtVsizeLimit(t *testing.T) {
+	planner := newTestBatchPaymentPlanner(2)
+	planner.MaxVsize = baseSize + p2wpkhSegwitInputSize + 3*p2wpkhOutputSize
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, planner.AddRecipient("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", 10000+i))
+	}
+
+	plan, err := planner.Plan(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, plan.TransactionCount())
+
+	first, _ := plan.TransactionAtIndex(0)
+	second, _ := plan.TransactionAtIndex(1)
+	assert.Equal(t, 2, first.RecipientCount())
+	assert.Equal(t, 1, second.RecipientCount())
+	recipient, err := second.RecipientAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 10002, recipient.Amount)
+
+	firstUTXO, _ := first.UTXOAtIndex(0)
+	secondUTXO, _ := second.UTXOAtIndex(0)
+	assert.NotEqual(t, firstUTXO.Index, secondUTXO.Index)
+	assert.Equal(t, first.FeeAmount+second.FeeAmount, plan.TotalFee)
+
+	_, err = plan.TransactionAtIndex(2)
+	assert.NotNil(t, err)
+}
+
+func TestBatchPaymentPlanner_SplitsAtSigOpsLimit(t *testing.T) {
+	planner := newTestBatchPaymentPlanner(2)
+	planner.MaxSigOpsCost = 2*legacySigOpsCost + witnessSigOpsCost
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, planner.AddRecipient("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", 10000))
+	}
+
+	plan, err := planner.Plan(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, plan.TransactionCount())
+
+	first, _ := plan.TransactionAtIndex(0)
+	assert.Equal(t, 2, first.RecipientCount())
+	assert.Equal(t, planner.MaxSigOpsCost, first.SigOpsCost)
+}
+
+func TestBatchPaymentPlanner_InsufficientFunds_ReturnsError(t *testing.T) {
+	planner := newTestBatchPaymentPlanner(1)
+	assert.Nil(t, planner.AddRecipient("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", 100000))
+
+	plan, err := planner.Plan(1)
+	assert.Nil(t, plan)
+	assert.Equal(t, ErrInsufficientFunds, err)
+}
+
+func TestBatchPaymentPlanner_RecipientExceedsLimits_ReturnsError(t *testing.T) {
+	planner := newTestBatchPaymentPlanner(1)
+	planner.MaxVsize = 100
+	assert.Nil(t, planner.AddRecipient("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", 10000))
+
+	_, err := planner.Plan(1)
+	assert.EqualError(t, err, "recipient bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu cannot be paid within standardness limits")
+}
+
+func TestBatchPaymentPlanner_AddRecipient_RejectsDust(t *testing.T) {
+	planner := newTestBatchPaymentPlanner(1)
+	assert.EqualError(t, planner.AddRecipient("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", 999), "amount is below dust threshold")
+	assert.Equal(t, 0, planner.RecipientCount())
+}
@@ -0,0 +1,95 @@
+package cnlib
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// ErrBIP322AddressType describes an error in which the caller signed a BIP322 message for an address without a simple
+// signature, any but native segwit (BIP84) and taproot (BIP86) single key addresses.
+var ErrBIP322AddressType = errors.New("BIP322 simple signatures are only supported for P2WPKH and P2TR addresses")
+
+/// Receiver functions
+
+// SignBIP322Message signs a message with the key of the address at a BIP84 or BIP86 derivation path, and returns the
+// base64 encoded BIP322 "simple" signature, the witness spending the message's virtual transaction, which covers the
+// segwit and taproot addresses signmessage (BIP137) cannot. Taproot signatures are not deterministic
+// unless the wallet is set to `SetDeterministicSchnorrSignatures`. Returns
+// ErrBIP322AddressType for other paths, or ErrKeyRoleMismatch if the path is not of a spending key.
+func (wallet *HDWallet) SignBIP322Message(message string, path *DerivationPath) (string, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return "", err
+	}
+	if path == nil {
+		return "", errors.New("derivation path cannot be nil")
+	}
+	if err := requireKeyRole(path, KeyRoleSpending); err != nil {
+		return "", err
+	}
+	purpose := path.bip32Path()[0]
+	if purpose != hardened(bip84purpose) && purpose != hardened(bip86purpose) {
+		return "", ErrBIP322AddressType
+	}
+
+	key, err := wallet.keyFactory().indexPrivateKey(path)
+	if err != nil {
+		return "", err
+	}
+	privateKey, err := key.ECPrivKey()
+	if err != nil {
+		return "", err
+	}
+
+	var pkScript []byte
+	if purpose == hardened(bip86purpose) {
+		pkScript, err = P2TRScript(taprootOutputKey(privateKey.PubKey()))
+	} else {
+		pkScript, err = P2WPKHScript(btcutil.Hash160(privateKey.PubKey().SerializeCompressed()))
+	}
+	if err != nil {
+		return "", err
+	}
+	toSpend, err := bip322ToSpend(message, pkScript)
+	if err != nil {
+		return "", err
+	}
+	toSign := bip322ToSign(toSpend)
+
+	if purpose == hardened(bip86purpose) {
+		var aux []byte
+		if aux, err = wallet.schnorrAuxRandomness(); err == nil {
+			err = signTaprootKeyPathInput(toSign, 0, []*wire.TxOut{toSpend.TxOut[0]}, privateKey, aux)
+		}
+	} else {
+		err = signInputWithHashType(toSign, 0, pkScript, 0, txscript.SigHashAll, privateKey)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := serializeWitness(toSign.TxIn[0].Witness)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+/// Exported functions
+
+// VerifyBIP322Message verifies a base64 encoded BIP322 "simple" signature of a message by a segwit or taproot address
+// on any supported network. Returns nil if valid, or error otherwise.
+func VerifyBIP322Message(address string, message string, signature string) error {
+	network, err := networkForAddress(address)
+	if err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	return verifyBIP322SimpleSignedMessage(address, message, decoded, network.chainParams)
+}
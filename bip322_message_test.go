@@ -0,0 +1,88 @@
+package cnlib
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyBIP322Message_Vectors(t *testing.T) {
+	// from BIP322, the taproot vector signed with SIGHASH_ALL
+	vectors := []struct {
+		address   string
+		message   string
+		signature string
+	}{
+		{"bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", "Hello World", "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI="},
+		{"bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg4q75mr5sxq8lt3", "Hello World", "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1vMU5EfMtjc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ=="},
+	}
+	for _, vector := range vectors {
+		assert.Nil(t, VerifyBIP322Message(vector.address, vector.message, vector.signature), vector.address)
+		assert.NotNil(t, VerifyBIP322Message(vector.address, "Hello World!", vector.signature), vector.address)
+	}
+
+	// the taproot vector's key, by its WIF
+	wif, err := btcutil.DecodeWIF("L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k")
+	assert.Nil(t, err)
+	script, _ := AddressScript(vectors[1].address)
+	assert.Equal(t, taprootOutputKey(wif.PrivKey.PubKey()), script[2:])
+}
+
+func TestSignBIP322Message_RoundTrip(t *testing.T) {
+	for _, basecoin := range []*BaseCoin{BaseCoinBip84MainNet, BaseCoinBip86MainNet, BaseCoinBip86TestNet} {
+		wallet := NewHDWalletFromWords(w, basecoin)
+		meta, err := wallet.ReceiveAddressForIndex(1)
+		assert.Nil(t, err)
+
+		signature, err := wallet.SignBIP322Message("proof of reserves", NewDerivationPath(basecoin, 0, 1))
+		assert.Nil(t, err)
+		assert.Nil(t, VerifyBIP322Message(meta.Address, "proof of reserves", signature))
+		assert.NotNil(t, VerifyBIP322Message(meta.Address, "proof of reserve", signature))
+		other, _ := wallet.ReceiveAddressForIndex(2)
+		assert.NotNil(t, VerifyBIP322Message(other.Address, "proof of reserves", signature))
+
+		// ownership proofs of the address verify too
+		proof := NewAddressOwnershipProof(meta.Address, "proof of reserves", signature, 0, 0)
+		verified, err := basecoin.VerifyAddressOwnershipProof(proof)
+		assert.Nil(t, err)
+		assert.Equal(t, ProofTypeBIP322, verified.ProofType)
+	}
+
+	// a taproot witness is a single 64-byte SIGHASH_DEFAULT signature
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	signature, _ := wallet.SignBIP322Message("", NewDerivationPath(BaseCoinBip86MainNet, 0, 0))
+	decoded, _ := base64.StdEncoding.DecodeString(signature)
+	witness, err := deserializeWitness(decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(witness))
+	assert.Equal(t, schnorrSignatureSize, len(witness[0]))
+
+	// an explicit SIGHASH_DEFAULT byte is invalid
+	meta, _ := wallet.ReceiveAddressForIndex(0)
+	explicit, _ := serializeWitness([][]byte{append(witness[0], 0x00)})
+	assert.NotNil(t, VerifyBIP322Message(meta.Address, "", base64.StdEncoding.EncodeToString(explicit)))
+	assert.True(t, strings.HasPrefix(meta.Address, "bc1p"))
+}
+
+func TestSignBIP322Message_Invalid(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	_, err := wallet.SignBIP322Message("hello", NewDerivationPath(BaseCoinBip49MainNet, 0, 0))
+	assert.Equal(t, ErrBIP322AddressType, err)
+	identity, _ := ParseCustomDerivationPath("m/42")
+	_, err = wallet.SignBIP322Message("hello", identity)
+	assert.Equal(t, ErrKeyRoleMismatch, err)
+	_, err = wallet.SignBIP322Message("hello", nil)
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, VerifyBIP322Message("not an address", "hello", ""))
+	assert.NotNil(t, VerifyBIP322Message("bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", "hello", "not base64"))
+	// legacy addresses have no simple signatures
+	assert.NotNil(t, VerifyBIP322Message("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "hello", "AA=="))
+
+	assert.Nil(t, wallet.SetRole(WalletRoleHot))
+	_, err = wallet.SignBIP322Message("hello", NewDerivationPath(BaseCoinBip84MainNet, 0, 0))
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
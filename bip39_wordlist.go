@@ -0,0 +1,322 @@
+package cnlib
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39/wordlists"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Following constants are used for BIP39 wordlist languages.
+const (
+	WordListLanguageEnglish            int = 0
+	WordListLanguageJapanese           int = 1
+	WordListLanguageSpanish            int = 2
+	WordListLanguageFrench             int = 3
+	WordListLanguageItalian            int = 4
+	WordListLanguageKorean             int = 5
+	WordListLanguageChineseSimplified  int = 6
+	WordListLanguageChineseTraditional int = 7
+)
+
+const (
+	bip39BitsPerWord      = 11
+	bip39SeedIterations   = 2048
+	bip39SeedLength       = 64
+	japaneseWordSeparator = "\u3000" // ideographic space, as in the BIP39 Japanese test vectors
+
+	// mixedEntropySalt is the HKDF salt with which caller-provided entropy is mixed with the system's random source.
+	mixedEntropySalt = "cnlib mnemonic entropy"
+	// mixedEntropySystemBytes is the number of bytes read from the system's random source for mixing.
+	mixedEntropySystemBytes = 32
+)
+
+var (
+	ErrWordListLanguageUnknown = errors.New("unrecognized wordlist language")
+	ErrMnemonicWordCount       = errors.New("mnemonic must be 12 to 24 words, in 3 word increments")
+	ErrMnemonicUnknownWord     = errors.New("mnemonic contains a word not in the wordlist")
+	ErrMnemonicChecksum        = errors.New("mnemonic checksum is invalid")
+	ErrMnemonicStrength        = errors.New("strength must be 128 to 256 bits, in 32 bit increments")
+	ErrMnemonicCallerEntropy   = errors.New("caller-provided entropy cannot be empty")
+)
+
+var bip39WordLists = map[int][]string{
+	WordListLanguageEnglish:            wordlists.English,
+	WordListLanguageJapanese:           wordlists.Japanese,
+	WordListLanguageSpanish:            wordlists.Spanish,
+	WordListLanguageFrench:             wordlists.French,
+	WordListLanguageItalian:            wordlists.Italian,
+	WordListLanguageKorean:             wordlists.Korean,
+	WordListLanguageChineseSimplified:  wordlists.ChineseSimplified,
+	WordListLanguageChineseTraditional: wordlists.ChineseTraditional,
+}
+
+var bip39WordIndexes = func() map[int]map[string]int {
+	indexes := make(map[int]map[string]int)
+	for language, list := range bip39WordLists {
+		indexes[language] = make(map[string]int)
+		for i, word := range list {
+			indexes[language][word] = i
+		}
+	}
+	return indexes
+}()
+
+// mnemonicDecompositions maps the precomposed characters found in the wordlists, which are stored decomposed, to their
+// NFKD form. Hangul syllables are decomposed algorithmically.
+var mnemonicDecompositions = map[rune]string{
+	'\u00e1': "\u0061\u0301", // á
+	'\u00e8': "\u0065\u0300", // è
+	'\u00e9': "\u0065\u0301", // é
+	'\u00ed': "\u0069\u0301", // í
+	'\u00f1': "\u006e\u0303", // ñ
+	'\u00f3': "\u006f\u0301", // ó
+	'\u00fa': "\u0075\u0301", // ú
+	'\u304c': "\u304b\u3099", // が
+	'\u304e': "\u304d\u3099", // ぎ
+	'\u3050': "\u304f\u3099", // ぐ
+	'\u3052': "\u3051\u3099", // げ
+	'\u3054': "\u3053\u3099", // ご
+	'\u3056': "\u3055\u3099", // ざ
+	'\u3058': "\u3057\u3099", // じ
+	'\u305a': "\u3059\u3099", // ず
+	'\u305c': "\u305b\u3099", // ぜ
+	'\u305e': "\u305d\u3099", // ぞ
+	'\u3060': "\u305f\u3099", // だ
+	'\u3065': "\u3064\u3099", // づ
+	'\u3067': "\u3066\u3099", // で
+	'\u3069': "\u3068\u3099", // ど
+	'\u3070': "\u306f\u3099", // ば
+	'\u3071': "\u306f\u309a", // ぱ
+	'\u3073': "\u3072\u3099", // び
+	'\u3074': "\u3072\u309a", // ぴ
+	'\u3076': "\u3075\u3099", // ぶ
+	'\u3077': "\u3075\u309a", // ぷ
+	'\u3079': "\u3078\u3099", // べ
+	'\u307a': "\u3078\u309a", // ぺ
+	'\u307c': "\u307b\u3099", // ぼ
+	'\u307d': "\u307b\u309a", // ぽ
+	'\u3000': " ",            // ideographic space
+}
+
+/// Exported functions
+
+// GetFullBIP39WordListStringForLanguage returns all 2,048 BIP39 mnemonic words of a language, one of the
+// `WordListLanguage` constants, as a space-separated string.
+func GetFullBIP39WordListStringForLanguage(language int) (string, error) {
+	list, ok := bip39WordLists[language]
+	if !ok {
+		return "", ErrWordListLanguageUnknown
+	}
+	return strings.Join(list, " "), nil
+}
+
+// NewWordListFromEntropyForLanguage returns a list of mnemonic words of a language from entropy. Words are separated
+// by spaces, or by ideographic spaces for Japanese.
+func NewWordListFromEntropyForLanguage(entropy []byte, language int) (string, error) {
+	list, ok := bip39WordLists[language]
+	if !ok {
+		return "", ErrWordListLanguageUnknown
+	}
+	if err := validateEntropyLength(entropy); err != nil {
+		return "", err
+	}
+
+	checksumBits := uint(len(entropy) / 4)
+	value := new(big.Int).SetBytes(entropy)
+	value.Lsh(value, checksumBits)
+	value.Or(value, big.NewInt(int64(sha256.Sum256(entropy)[0]>>(8-checksumBits))))
+
+	count := (len(entropy)*8 + int(checksumBits)) / bip39BitsPerWord
+	words := make([]string, count)
+	mask := big.NewInt(1<<bip39BitsPerWord - 1)
+	for i := count - 1; i >= 0; i-- {
+		words[i] = list[new(big.Int).And(value, mask).Int64()]
+		value.Rsh(value, bip39BitsPerWord)
+	}
+
+	separator := " "
+	if language == WordListLanguageJapanese {
+		separator = japaneseWordSeparator
+	}
+	return strings.Join(words, separator), nil
+}
+
+// NewWordListWithStrength returns a space-separated list of English mnemonic words encoding bits of entropy read from
+// crypto/rand: 128, 160, 192, 224 or 256 bits, for 12, 15, 18, 21 or 24 words. Returns ErrMnemonicStrength for any
+// other strength, or error if the system's random source fails.
+func NewWordListWithStrength(bits int) (string, error) {
+	return NewWordListWithStrengthForLanguage(bits, WordListLanguageEnglish)
+}
+
+// NewWordListWithStrengthForLanguage is `NewWordListWithStrength` for the wordlist of a language, one of the
+// `WordListLanguage` constants.
+func NewWordListWithStrengthForLanguage(bits int, language int) (string, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", ErrMnemonicStrength
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+	return NewWordListFromEntropyForLanguage(entropy, language)
+}
+
+// NewWordListWithMixedEntropy returns a space-separated list of English mnemonic words of a strength, as
+// `NewWordListWithStrength`, whose entropy mixes caller-provided entropy, such as from dice rolls or an external
+// device, with 256 bits read from crypto/rand using HKDF-SHA256. The mnemonic remains unpredictable as long as either
+// source is, so a compromised system random source alone cannot reveal it. Returns ErrMnemonicCallerEntropy if the
+// caller's entropy is empty.
+func NewWordListWithMixedEntropy(bits int, callerEntropy []byte) (string, error) {
+	return NewWordListWithMixedEntropyForLanguage(bits, callerEntropy, WordListLanguageEnglish)
+}
+
+// NewWordListWithMixedEntropyForLanguage is `NewWordListWithMixedEntropy` for the wordlist of a language, one of the
+// `WordListLanguage` constants.
+func NewWordListWithMixedEntropyForLanguage(bits int, callerEntropy []byte, language int) (string, error) {
+	entropy, err := mixedMnemonicEntropy(bits, callerEntropy, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return NewWordListFromEntropyForLanguage(entropy, language)
+}
+
+// ValidateWordStringForLanguage returns nil if a mnemonic consists of words of a language's wordlist with a valid
+// checksum, or the reason it does not. Words may be separated by any whitespace, and composed or decomposed accents
+// are both accepted.
+func ValidateWordStringForLanguage(wordString string, language int) error {
+	if _, ok := bip39WordLists[language]; !ok {
+		return ErrWordListLanguageUnknown
+	}
+	_, err := mnemonicEntropy(mnemonicWords(wordString), language)
+	return err
+}
+
+// DetectWordListLanguage returns the language, one of the `WordListLanguage` constants, whose wordlist contains every
+// word of a mnemonic. Some words appear in more than one wordlist, notably between the Chinese wordlists, so the first
+// language in which the checksum is also valid is preferred, then the first containing every word. Returns error if no
+// wordlist contains every word.
+func DetectWordListLanguage(wordString string) (int, error) {
+	words := mnemonicWords(wordString)
+	if len(words) == 0 {
+		return 0, ErrWordListLanguageUnknown
+	}
+
+	candidate := -1
+	for language := WordListLanguageEnglish; language <= WordListLanguageChineseTraditional; language++ {
+		_, err := mnemonicEntropy(words, language)
+		switch err {
+		case nil:
+			return language, nil
+		case ErrMnemonicChecksum, ErrMnemonicWordCount:
+			if candidate < 0 {
+				candidate = language
+			}
+		}
+	}
+	if candidate < 0 {
+		return 0, ErrWordListLanguageUnknown
+	}
+	return candidate, nil
+}
+
+/// Unexported functions
+
+// mixedMnemonicEntropy returns bits of entropy derived by HKDF-SHA256 from bytes read from a random source followed
+// by the caller's entropy.
+func mixedMnemonicEntropy(bits int, callerEntropy []byte, random io.Reader) ([]byte, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return nil, ErrMnemonicStrength
+	}
+	if len(callerEntropy) == 0 {
+		return nil, ErrMnemonicCallerEntropy
+	}
+	secret := make([]byte, mixedEntropySystemBytes, mixedEntropySystemBytes+len(callerEntropy))
+	if _, err := io.ReadFull(random, secret); err != nil {
+		return nil, err
+	}
+	secret = append(secret, callerEntropy...)
+
+	entropy := make([]byte, bits/8)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, []byte(mixedEntropySalt), nil), entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+// mnemonicWords returns the NFKD normalized words of a mnemonic.
+func mnemonicWords(wordString string) []string {
+	return strings.Fields(normalizeMnemonic(wordString))
+}
+
+// mnemonicEntropy returns the entropy encoded by the words of a mnemonic in a language's wordlist, verifying the
+// checksum.
+func mnemonicEntropy(words []string, language int) ([]byte, error) {
+	indexes := bip39WordIndexes[language]
+	value := new(big.Int)
+	for _, word := range words {
+		index, ok := indexes[word]
+		if !ok {
+			return nil, ErrMnemonicUnknownWord
+		}
+		value.Lsh(value, bip39BitsPerWord)
+		value.Or(value, big.NewInt(int64(index)))
+	}
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, ErrMnemonicWordCount
+	}
+
+	checksumBits := uint(len(words) / 3)
+	checksum := new(big.Int).And(value, big.NewInt(1<<checksumBits-1)).Int64()
+	value.Rsh(value, checksumBits)
+	entropy := make([]byte, len(words)*4/3)
+	encoded := value.Bytes()
+	copy(entropy[len(entropy)-len(encoded):], encoded)
+	if int64(sha256.Sum256(entropy)[0]>>(8-checksumBits)) != checksum {
+		return nil, ErrMnemonicChecksum
+	}
+	return entropy, nil
+}
+
+// normalizeMnemonic returns the NFKD form of a mnemonic as required by BIP39, for the characters of the wordlists.
+func normalizeMnemonic(wordString string) string {
+	const (
+		hangulBase      = 0xAC00
+		hangulCount     = 11172
+		hangulLeadBase  = 0x1100
+		hangulVowelBase = 0x1161
+		hangulTailBase  = 0x11A7
+		hangulTailCount = 28
+		hangulVowelTail = 21 * hangulTailCount
+	)
+
+	var normalized strings.Builder
+	for _, r := range wordString {
+		if decomposed, ok := mnemonicDecompositions[r]; ok {
+			normalized.WriteString(decomposed)
+			continue
+		}
+		if s := r - hangulBase; s >= 0 && s < hangulCount {
+			normalized.WriteRune(hangulLeadBase + s/hangulVowelTail)
+			normalized.WriteRune(hangulVowelBase + s%hangulVowelTail/hangulTailCount)
+			if tail := s % hangulTailCount; tail != 0 {
+				normalized.WriteRune(hangulTailBase + tail)
+			}
+			continue
+		}
+		normalized.WriteRune(r)
+	}
+	return normalized.String()
+}
+
+// mnemonicSeed returns the BIP39 seed of a mnemonic in any language, without a passphrase.
+func mnemonicSeed(wordString string) []byte {
+	return pbkdf2.Key([]byte(normalizeMnemonic(wordString)), []byte("mnemonic"), bip39SeedIterations, bip39SeedLength, sha512.New)
+}
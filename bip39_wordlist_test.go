@@ -0,0 +1,147 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tyler-smith/go-bip39"
+)
+
+func TestGetFullBIP39WordListStringForLanguage(t *testing.T) {
+	wl, err := GetFullBIP39WordListStringForLanguage(WordListLanguageSpanish)
+	assert.Nil(t, err)
+	words := strings.Split(wl, " ")
+	assert.Equal(t, 2048, len(words))
+	assert.Equal(t, normalizeMnemonic("\u00e1baco"), words[0])
+
+	_, err = GetFullBIP39WordListStringForLanguage(42)
+	assert.Equal(t, ErrWordListLanguageUnknown, err)
+}
+
+func TestNewWordListFromEntropyForLanguage_MatchesEnglishVectors(t *testing.T) {
+	wordString, err := NewWordListFromEntropyForLanguage(make([]byte, 16), WordListLanguageEnglish)
+	assert.Nil(t, err)
+	assert.Equal(t, w, wordString)
+
+	entropy := bytes.Repeat([]byte{0x7f}, 32)
+	wordString, err = NewWordListFromEntropyForLanguage(entropy, WordListLanguageEnglish)
+	assert.Nil(t, err)
+	expected, _ := bip39.NewMnemonic(entropy)
+	assert.Equal(t, expected, wordString)
+
+	_, err = NewWordListFromEntropyForLanguage(make([]byte, 15), WordListLanguageEnglish)
+	assert.NotNil(t, err)
+}
+
+func TestNewWordListFromEntropyForLanguage_Japanese(t *testing.T) {
+	wordString, err := NewWordListFromEntropyForLanguage(make([]byte, 16), WordListLanguageJapanese)
+	assert.Nil(t, err)
+	words := strings.Split(wordString, japaneseWordSeparator)
+	assert.Equal(t, 12, len(words))
+	assert.Equal(t, "あいこくしん", words[0])
+	assert.Equal(t, normalizeMnemonic("あお\u305eら"), words[11])
+
+	assert.Nil(t, ValidateWordStringForLanguage(wordString, WordListLanguageJapanese))
+	language, err := DetectWordListLanguage(wordString)
+	assert.Nil(t, err)
+	assert.Equal(t, WordListLanguageJapanese, language)
+}
+
+func TestNewWordListWithStrength(t *testing.T) {
+	for bits, count := range map[int]int{128: 12, 160: 15, 192: 18, 224: 21, 256: 24} {
+		wordString, err := NewWordListWithStrength(bits)
+		assert.Nil(t, err)
+		assert.Equal(t, count, len(strings.Split(wordString, " ")))
+		assert.Nil(t, ValidateWordStringForLanguage(wordString, WordListLanguageEnglish))
+	}
+
+	first, _ := NewWordListWithStrength(256)
+	second, _ := NewWordListWithStrength(256)
+	assert.NotEqual(t, first, second)
+
+	for _, bits := range []int{0, 96, 129, 288} {
+		_, err := NewWordListWithStrength(bits)
+		assert.Equal(t, ErrMnemonicStrength, err)
+	}
+
+	_, err := NewWordListWithStrengthForLanguage(128, 99)
+	assert.Equal(t, ErrWordListLanguageUnknown, err)
+}
+
+func TestNewWordListWithMixedEntropy(t *testing.T) {
+	dice := []byte("3 1 4 1 5 9 2 6 5 3 5 8 9 7 9 3 2 3 8 4 6 2 6 4 3 3 8 3 2 7 9 5")
+	for bits, count := range map[int]int{128: 12, 256: 24} {
+		wordString, err := NewWordListWithMixedEntropy(bits, dice)
+		assert.Nil(t, err)
+		assert.Equal(t, count, len(strings.Split(wordString, " ")))
+		assert.Nil(t, ValidateWordStringForLanguage(wordString, WordListLanguageEnglish))
+	}
+
+	first, _ := NewWordListWithMixedEntropy(256, dice)
+	second, _ := NewWordListWithMixedEntropy(256, dice)
+	assert.NotEqual(t, first, second)
+
+	wordString, err := NewWordListWithMixedEntropyForLanguage(128, dice, WordListLanguageJapanese)
+	assert.Nil(t, err)
+	assert.Nil(t, ValidateWordStringForLanguage(wordString, WordListLanguageJapanese))
+
+	_, err = NewWordListWithMixedEntropy(128, nil)
+	assert.Equal(t, ErrMnemonicCallerEntropy, err)
+	_, err = NewWordListWithMixedEntropy(96, dice)
+	assert.Equal(t, ErrMnemonicStrength, err)
+}
+
+func TestMixedMnemonicEntropy(t *testing.T) {
+	system := bytes.Repeat([]byte{0xaa}, mixedEntropySystemBytes)
+	dice := []byte("6 6 6 6")
+
+	entropy, err := mixedMnemonicEntropy(128, dice, bytes.NewReader(system))
+	assert.Nil(t, err)
+	assert.Equal(t, 16, len(entropy))
+
+	// mixing is deterministic in both sources, and depends on each
+	again, _ := mixedMnemonicEntropy(128, dice, bytes.NewReader(system))
+	assert.Equal(t, entropy, again)
+	otherCaller, _ := mixedMnemonicEntropy(128, []byte("6 6 6 5"), bytes.NewReader(system))
+	assert.NotEqual(t, entropy, otherCaller)
+	otherSystem, _ := mixedMnemonicEntropy(128, dice, bytes.NewReader(bytes.Repeat([]byte{0xab}, mixedEntropySystemBytes)))
+	assert.NotEqual(t, entropy, otherSystem)
+
+	// neither source is used directly
+	assert.NotEqual(t, system[:16], entropy)
+
+	_, err = mixedMnemonicEntropy(128, dice, bytes.NewReader(system[:8]))
+	assert.NotNil(t, err)
+}
+
+func TestValidateWordStringForLanguage(t *testing.T) {
+	assert.Nil(t, ValidateWordStringForLanguage(w, WordListLanguageEnglish))
+	assert.Equal(t, ErrMnemonicChecksum, ValidateWordStringForLanguage(strings.Replace(w, "about", "abandon", 1), WordListLanguageEnglish))
+	assert.Equal(t, ErrMnemonicUnknownWord, ValidateWordStringForLanguage(w+" bitcoin", WordListLanguageEnglish))
+	assert.Equal(t, ErrMnemonicWordCount, ValidateWordStringForLanguage("abandon abandon", WordListLanguageEnglish))
+	assert.Equal(t, ErrMnemonicUnknownWord, ValidateWordStringForLanguage(w, WordListLanguageFrench))
+	assert.Equal(t, ErrWordListLanguageUnknown, ValidateWordStringForLanguage(w, -1))
+}
+
+func TestDetectWordListLanguage(t *testing.T) {
+	for _, language := range []int{WordListLanguageSpanish, WordListLanguageFrench, WordListLanguageItalian, WordListLanguageKorean} {
+		wordString, err := NewWordListFromEntropyForLanguage(bytes.Repeat([]byte{0x5a}, 16), language)
+		assert.Nil(t, err)
+		detected, err := DetectWordListLanguage(wordString)
+		assert.Nil(t, err)
+		assert.Equal(t, language, detected)
+	}
+
+	_, err := DetectWordListLanguage("not a mnemonic")
+	assert.Equal(t, ErrWordListLanguageUnknown, err)
+	_, err = DetectWordListLanguage("")
+	assert.Equal(t, ErrWordListLanguageUnknown, err)
+}
+
+func TestMnemonicSeed_MatchesEnglishSeed(t *testing.T) {
+	expected := "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+	assert.Equal(t, expected, hex.EncodeToString(mnemonicSeed(w)))
+}
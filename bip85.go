@@ -0,0 +1,86 @@
+package cnlib
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+)
+
+const (
+	bip85Purpose          = 83696968
+	bip85ApplicationBIP39 = 39
+	bip85ApplicationXPRV  = 32
+	bip85ApplicationHex   = 128169
+	bip85EntropyKey       = "bip-entropy-from-k"
+)
+
+// bip85LanguageCodes maps the `WordListLanguage` constants to BIP85's language codes.
+var bip85LanguageCodes = map[int]int{
+	WordListLanguageEnglish:            0,
+	WordListLanguageJapanese:           1,
+	WordListLanguageKorean:             2,
+	WordListLanguageSpanish:            3,
+	WordListLanguageChineseSimplified:  4,
+	WordListLanguageChineseTraditional: 5,
+	WordListLanguageFrench:             6,
+	WordListLanguageItalian:            7,
+}
+
+// ErrBIP85WordCount is returned for BIP85 mnemonics of an unsupported length.
+var ErrBIP85WordCount = errors.New("BIP85 mnemonic must be 12, 18 or 24 words")
+
+/// Receiver functions
+
+// DeriveBIP85Mnemonic returns the English BIP39 mnemonic of wordCount words, 12, 18 or 24, at a given index of the
+// wallet's BIP85 child seeds, m/83696968'/39'/0'/words'/index'. Each child is an independent wallet, such as one for
+// another app, that is recovered from this wallet's backup, while revealing nothing about this wallet or its other
+// children. Returns error if the wallet is watch-only.
+func (wallet *HDWallet) DeriveBIP85Mnemonic(index int, wordCount int) (string, error) {
+	return wallet.DeriveBIP85MnemonicForLanguage(index, wordCount, WordListLanguageEnglish)
+}
+
+// DeriveBIP85MnemonicForLanguage returns the BIP85 child mnemonic as `DeriveBIP85Mnemonic`, in one of the
+// `WordListLanguage` languages. Children of different languages are different seeds, not translations of each other.
+func (wallet *HDWallet) DeriveBIP85MnemonicForLanguage(index int, wordCount int, language int) (string, error) {
+	code, ok := bip85LanguageCodes[language]
+	if !ok {
+		return "", ErrWordListLanguageUnknown
+	}
+	if wordCount != 12 && wordCount != 18 && wordCount != 24 {
+		return "", ErrBIP85WordCount
+	}
+	if err := validateDerivationIndex(index); err != nil {
+		return "", err
+	}
+	entropy, err := wallet.bip85Entropy(bip85ApplicationBIP39, code, wordCount, index)
+	if err != nil {
+		return "", err
+	}
+	return NewWordListFromEntropyForLanguage(entropy[:wordCount*4/3], language)
+}
+
+/// Unexported functions
+
+// bip85Entropy returns the 64 bytes of BIP85 entropy at m/83696968'/path', the HMAC-SHA512 of the derived private key.
+func (wallet *HDWallet) bip85Entropy(path ...int) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+	key, err := wallet.masterPrivateKey.Child(hardened(bip85Purpose))
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range path {
+		key, err = key.Child(hardened(i))
+		if err != nil {
+			return nil, err
+		}
+	}
+	privateKey, err := key.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha512.New, []byte(bip85EntropyKey))
+	mac.Write(privateKey.Serialize())
+	return mac.Sum(nil), nil
+}
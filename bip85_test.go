@@ -0,0 +1,70 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/stretchr/testify/assert"
+)
+
+func bip85TestWallet(t *testing.T) *HDWallet {
+	master, err := hdkeychain.NewKeyFromString("xprv9s21ZrQH143K2LBWUUQRFXhucrQqBpKdRRxNVq2zBqsx8HVqFk2uYo8kmbaLLHRdqtQpUm98uKfu3vca1LqdGhUtyoFnCNkfmXRyPXLjbKb")
+	assert.Nil(t, err)
+	return &HDWallet{BaseCoin: BaseCoinBip84MainNet, masterPrivateKey: master}
+}
+
+func TestDeriveBIP85Mnemonic_Vectors(t *testing.T) {
+	wallet := bip85TestWallet(t)
+
+	words, err := wallet.DeriveBIP85Mnemonic(0, 12)
+	assert.Nil(t, err)
+	assert.Equal(t, "girl mad pet galaxy egg matter matrix prison refuse sense ordinary nose", words)
+
+	words, err = wallet.DeriveBIP85Mnemonic(0, 18)
+	assert.Nil(t, err)
+	assert.Equal(t, "near account window bike charge season chef number sketch tomorrow excuse sniff circle vital hockey outdoor supply token", words)
+
+	words, err = wallet.DeriveBIP85Mnemonic(0, 24)
+	assert.Nil(t, err)
+	assert.Equal(t, "puppy ocean match cereal symbol another shed magic wrap hammer bulb intact gadget divorce twin tonight reason outdoor destroy simple truth cigar social volcano", words)
+}
+
+func TestDeriveBIP85Mnemonic_ChildWallets(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	first, err := wallet.DeriveBIP85Mnemonic(0, 12)
+	assert.Nil(t, err)
+	second, err := wallet.DeriveBIP85Mnemonic(1, 12)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, second)
+	assert.Nil(t, ValidateWordStringForLanguage(first, WordListLanguageEnglish))
+
+	spanish, err := wallet.DeriveBIP85MnemonicForLanguage(0, 12, WordListLanguageSpanish)
+	assert.Nil(t, err)
+	assert.Nil(t, ValidateWordStringForLanguage(spanish, WordListLanguageSpanish))
+
+	child := NewHDWalletFromWords(first, BaseCoinBip84MainNet)
+	assert.NotNil(t, child)
+}
+
+func TestDeriveBIP85Mnemonic_Invalid(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	_, err := wallet.DeriveBIP85Mnemonic(0, 15)
+	assert.Equal(t, ErrBIP85WordCount, err)
+	_, err = wallet.DeriveBIP85Mnemonic(-1, 12)
+	assert.Equal(t, ErrDerivationIndexNegative, err)
+	_, err = wallet.DeriveBIP85MnemonicForLanguage(0, 12, 99)
+	assert.Equal(t, ErrWordListLanguageUnknown, err)
+
+	watchOnly, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	_, err = watchOnly.DeriveBIP85Mnemonic(0, 12)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
+
+func TestBIP85Entropy_HexVector(t *testing.T) {
+	wallet := bip85TestWallet(t)
+	entropy, err := wallet.bip85Entropy(bip85ApplicationHex, 64, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "492db4698cf3b73a5a24998aa3e9d7fa96275d85724a91e71aa2d645442f878555d078fd1f1f67e368976f04137b1f7a0d19232136ca50c44614af72b5582a5c", hex.EncodeToString(entropy))
+}
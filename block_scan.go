@@ -0,0 +1,202 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// Following errors are returned for blocks whose transactions are not those committed to by the block.
+var (
+	ErrBlockMerkleRoot        = errors.New("block transactions do not match its merkle root")
+	ErrBlockWitnessCommitment = errors.New("block witnesses do not match its coinbase witness commitment")
+	ErrBlockDuplicateTx       = errors.New("block contains a transaction more than once")
+)
+
+/// Type Definitions
+
+// WatchSet holds the output scripts and outpoints a wallet watches for in blocks, such as those fetched after a BIP158
+// filter match. Scripts are watched rather than addresses, so any script type matches, including taproot. Add each
+// using `AddAddress`, `AddScript` or `AddOutpoint`, as gomobile does not support custom arrays/slices.
+type WatchSet struct {
+	scripts   map[string]bool
+	outpoints map[wire.OutPoint]bool
+}
+
+// BlockScan is the result of scanning a block for transactions touching a WatchSet.
+type BlockScan struct {
+	BlockHash        string
+	PrevBlockHash    string
+	Timestamp        int64 // seconds since unix epoch, from the block header
+	TransactionCount int   // number of transactions in the block, matched or not
+	transactions     []*BlockTransaction
+}
+
+// BlockTransaction is a transaction of a scanned block which pays to a watched script or spends a watched outpoint.
+// Pass EncodedTx to `AnalyzeTransaction` for details.
+type BlockTransaction struct {
+	Txid           string
+	EncodedTx      string
+	Position       int   // index of the transaction in the block
+	ReceivedAmount int64 // total of the outputs paying to watched scripts
+	ReceivedCount  int   // number of outputs paying to watched scripts
+	SpentCount     int   // number of inputs spending watched outpoints
+}
+
+/// Constructors
+
+// NewWatchSet returns a pointer to an empty WatchSet.
+func NewWatchSet() *WatchSet {
+	return &WatchSet{scripts: map[string]bool{}, outpoints: map[wire.OutPoint]bool{}}
+}
+
+// NewWatchSet returns a pointer to a WatchSet of the wallet's receive and change addresses up to a given index.
+func (wallet *HDWallet) NewWatchSet(upTo int) (*WatchSet, error) {
+	known, err := wallet.derivedAddressMap(upTo)
+	if err != nil {
+		return nil, err
+	}
+	set := NewWatchSet()
+	for address := range known {
+		if err := set.AddAddress(address); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+/// Receiver functions
+
+// AddAddress watches for outputs paying to an address.
+func (s *WatchSet) AddAddress(address string) error {
+	script, err := AddressScript(address)
+	if err != nil {
+		return err
+	}
+	return s.AddScript(script)
+}
+
+// AddScript watches for outputs paying to an output script.
+func (s *WatchSet) AddScript(pkScript []byte) error {
+	if len(pkScript) == 0 {
+		return errors.New("output script cannot be empty")
+	}
+	s.scripts[string(pkScript)] = true
+	return nil
+}
+
+// AddOutpoint watches for inputs spending an outpoint, such as a utxo of the wallet.
+func (s *WatchSet) AddOutpoint(txid string, index int) error {
+	outPoint, err := outPointFromInfo(txid, index)
+	if err != nil {
+		return err
+	}
+	s.outpoints[*outPoint] = true
+	return nil
+}
+
+// ScriptCount returns the number of watched output scripts.
+func (s *WatchSet) ScriptCount() int {
+	return len(s.scripts)
+}
+
+// OutpointCount returns the number of watched outpoints.
+func (s *WatchSet) OutpointCount() int {
+	return len(s.outpoints)
+}
+
+// MatchedCount returns the number of matched transactions.
+func (b *BlockScan) MatchedCount() int {
+	return len(b.transactions)
+}
+
+// MatchedAtIndex returns the matched transaction at a given index, in block order, or error if out of bounds.
+func (b *BlockScan) MatchedAtIndex(index int) (*BlockTransaction, error) {
+	if index < 0 || index > len(b.transactions)-1 {
+		return nil, errors.New("index must be within range of matched transactions")
+	}
+	return b.transactions[index], nil
+}
+
+/// Exported functions
+
+// ParseBlock parses a hex-encoded raw block and returns only the transactions paying to a watched script or spending
+// a watched outpoint. Outputs paying to watched scripts are added to the watch set as they are found, so spends of them
+// later in the block, or in blocks scanned later with the same set, are matched too. Returns ErrBlockMerkleRoot if
+// the block's transactions do not match its header, ErrBlockDuplicateTx if a transaction repeats, which leaves the
+// merkle root unchanged, ErrBlockWitnessCommitment if the witnesses do not match the coinbase's commitment, or error
+// if the block is malformed.
+func ParseBlock(rawBlock string, watchSet *WatchSet) (*BlockScan, error) {
+	if watchSet == nil {
+		return nil, errors.New("watch set cannot be nil")
+	}
+	blockBytes, err := hex.DecodeString(rawBlock)
+	if err != nil {
+		return nil, err
+	}
+	var block wire.MsgBlock
+	if err := block.Deserialize(bytes.NewReader(blockBytes)); err != nil {
+		return nil, err
+	}
+	if len(block.Transactions) == 0 {
+		return nil, errors.New("block has no transactions")
+	}
+
+	// transactions are hashed without witnesses, so the merkle root commits to every txid, and the coinbase commits to
+	// the witnesses
+	txs := make([]*btcutil.Tx, len(block.Transactions))
+	txids := make(map[chainhash.Hash]bool, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		txs[i] = btcutil.NewTx(tx)
+		if txids[*txs[i].Hash()] {
+			return nil, ErrBlockDuplicateTx
+		}
+		txids[*txs[i].Hash()] = true
+	}
+	merkles := blockchain.BuildMerkleTreeStore(txs, false)
+	if !merkles[len(merkles)-1].IsEqual(&block.Header.MerkleRoot) {
+		return nil, ErrBlockMerkleRoot
+	}
+	if err := blockchain.ValidateWitnessCommitment(btcutil.NewBlock(&block)); err != nil {
+		return nil, ErrBlockWitnessCommitment
+	}
+
+	scan := &BlockScan{
+		BlockHash:        block.BlockHash().String(),
+		PrevBlockHash:    block.Header.PrevBlock.String(),
+		Timestamp:        block.Header.Timestamp.Unix(),
+		TransactionCount: len(block.Transactions),
+		transactions:     []*BlockTransaction{},
+	}
+	for position, tx := range block.Transactions {
+		txid := tx.TxHash()
+		matched := &BlockTransaction{Txid: txid.String(), Position: position}
+		if position > 0 {
+			for _, txIn := range tx.TxIn {
+				if watchSet.outpoints[txIn.PreviousOutPoint] {
+					matched.SpentCount++
+				}
+			}
+		}
+		for i, txOut := range tx.TxOut {
+			if watchSet.scripts[string(txOut.PkScript)] {
+				matched.ReceivedCount++
+				matched.ReceivedAmount += txOut.Value
+				watchSet.outpoints[*wire.NewOutPoint(&txid, uint32(i))] = true
+			}
+		}
+		if matched.ReceivedCount == 0 && matched.SpentCount == 0 {
+			continue
+		}
+		if matched.EncodedTx, err = encodeMsgTx(tx); err != nil {
+			return nil, err
+		}
+		scan.transactions = append(scan.transactions, matched)
+	}
+	return scan, nil
+}
@@ -0,0 +1,156 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockScanTestBlock returns a hex-encoded block of the given transactions after a coinbase, with a valid merkle root
+// and witness commitment.
+func blockScanTestBlock(t *testing.T, txs ...*wire.MsgTx) string {
+	block := blockScanTestMsgBlock(t, txs...)
+	var buf bytes.Buffer
+	assert.Nil(t, block.Serialize(&buf))
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func blockScanTestMsgBlock(t *testing.T, txs ...*wire.MsgTx) *wire.MsgBlock {
+	nonce := make([]byte, blockchain.CoinbaseWitnessDataLen)
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), []byte{0x01, 0x01}, wire.TxWitness{nonce}))
+	coinbase.AddTxOut(wire.NewTxOut(625000000, []byte{0x51}))
+	block := wire.NewMsgBlock(wire.NewBlockHeader(0x20000000, &chainhash.Hash{0x01}, &chainhash.Hash{}, 0x1d00ffff, 0))
+	block.Header.Timestamp = time.Unix(1700000000, 0)
+	assert.Nil(t, block.AddTransaction(coinbase))
+	for _, tx := range txs {
+		assert.Nil(t, block.AddTransaction(tx))
+	}
+
+	// the coinbase's witness hash counts as zero, so its commitment output does not change the witness root
+	utilTxs := make([]*btcutil.Tx, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		utilTxs[i] = btcutil.NewTx(tx)
+	}
+	witnessMerkles := blockchain.BuildMerkleTreeStore(utilTxs, true)
+	commitment := chainhash.DoubleHashB(append(witnessMerkles[len(witnessMerkles)-1][:], nonce...))
+	coinbase.AddTxOut(wire.NewTxOut(0, append(append([]byte{}, blockchain.WitnessMagicBytes...), commitment...)))
+
+	utilTxs[0] = btcutil.NewTx(coinbase)
+	merkles := blockchain.BuildMerkleTreeStore(utilTxs, false)
+	block.Header.MerkleRoot = *merkles[len(merkles)-1]
+	return block
+}
+
+func blockScanTestTx(prevout *wire.OutPoint, amount int64, pkScript []byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(prevout, nil, wire.TxWitness{{0x01}}))
+	tx.AddTxOut(wire.NewTxOut(amount, pkScript))
+	return tx
+}
+
+func TestParseBlock_MatchesWatchedTransactions(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	watchSet, err := wallet.NewWatchSet(5)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, watchSet.ScriptCount())
+	taproot := "bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg4q75mr5sxq8lt3"
+	assert.Nil(t, watchSet.AddAddress(taproot))
+	assert.Nil(t, watchSet.AddOutpoint(descriptorTestTxid, 3))
+
+	receive, _ := wallet.ReceiveAddressForIndex(2)
+	receiveScript, _ := AddressScript(receive.Address)
+	taprootScript, _ := AddressScript(taproot)
+	externalScript, _ := AddressScript("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6")
+	watchedHash, _ := chainhash.NewHashFromStr(descriptorTestTxid)
+
+	unrelated := blockScanTestTx(wire.NewOutPoint(&chainhash.Hash{0x02}, 0), 5000, externalScript)
+	received := blockScanTestTx(wire.NewOutPoint(&chainhash.Hash{0x03}, 0), 40000, receiveScript)
+	received.AddTxOut(wire.NewTxOut(20000, taprootScript))
+	receivedHash := received.TxHash()
+	// spends an output received earlier in the block
+	spentInBlock := blockScanTestTx(wire.NewOutPoint(&receivedHash, 1), 19000, externalScript)
+	spentWatched := blockScanTestTx(wire.NewOutPoint(watchedHash, 3), 9000, externalScript)
+
+	scan, err := ParseBlock(blockScanTestBlock(t, unrelated, received, spentInBlock, spentWatched), watchSet)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, scan.TransactionCount)
+	assert.Equal(t, (&chainhash.Hash{0x01}).String(), scan.PrevBlockHash)
+	assert.Equal(t, int64(1700000000), scan.Timestamp)
+	assert.Equal(t, 3, scan.MatchedCount())
+
+	matched, err := scan.MatchedAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, receivedHash.String(), matched.Txid)
+	assert.Equal(t, 2, matched.Position)
+	assert.Equal(t, 2, matched.ReceivedCount)
+	assert.Equal(t, int64(60000), matched.ReceivedAmount)
+	assert.Equal(t, 0, matched.SpentCount)
+	encoded, _ := encodeMsgTx(received)
+	assert.Equal(t, encoded, matched.EncodedTx)
+
+	matched, _ = scan.MatchedAtIndex(1)
+	assert.Equal(t, 3, matched.Position)
+	assert.Equal(t, 1, matched.SpentCount)
+	assert.Equal(t, 0, matched.ReceivedCount)
+	matched, _ = scan.MatchedAtIndex(2)
+	assert.Equal(t, spentWatched.TxHash().String(), matched.Txid)
+	assert.Equal(t, 1, matched.SpentCount)
+
+	// received outputs are now watched
+	assert.Equal(t, 3, watchSet.OutpointCount())
+	_, err = scan.MatchedAtIndex(3)
+	assert.NotNil(t, err)
+}
+
+func TestParseBlock_Invalid(t *testing.T) {
+	watchSet := NewWatchSet()
+	externalScript, _ := AddressScript("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6")
+	tx := blockScanTestTx(wire.NewOutPoint(&chainhash.Hash{0x02}, 0), 5000, externalScript)
+	rawBlock := blockScanTestBlock(t, tx)
+
+	scan, err := ParseBlock(rawBlock, watchSet)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, scan.MatchedCount())
+
+	// altering an output amount, the 8 bytes before the script and its length, breaks the merkle root
+	blockBytes, _ := hex.DecodeString(rawBlock)
+	tampered := append([]byte{}, blockBytes...)
+	index := bytes.LastIndex(tampered, externalScript) - 9
+	tampered[index]++
+	_, err = ParseBlock(hex.EncodeToString(tampered), watchSet)
+	assert.Equal(t, ErrBlockMerkleRoot, err)
+
+	// altering a witness leaves the merkle root unchanged, but breaks the witness commitment
+	block := blockScanTestMsgBlock(t, tx)
+	block.Transactions[1].TxIn[0].Witness = wire.TxWitness{{0x02}}
+	var buf bytes.Buffer
+	assert.Nil(t, block.Serialize(&buf))
+	_, err = ParseBlock(hex.EncodeToString(buf.Bytes()), watchSet)
+	assert.Equal(t, ErrBlockWitnessCommitment, err)
+
+	// repeating the last of an odd number of transactions leaves the merkle root unchanged
+	block = blockScanTestMsgBlock(t, tx, blockScanTestTx(wire.NewOutPoint(&chainhash.Hash{0x03}, 0), 5000, externalScript))
+	assert.Nil(t, block.AddTransaction(block.Transactions[2]))
+	buf.Reset()
+	assert.Nil(t, block.Serialize(&buf))
+	_, err = ParseBlock(hex.EncodeToString(buf.Bytes()), watchSet)
+	assert.Equal(t, ErrBlockDuplicateTx, err)
+
+	_, err = ParseBlock(rawBlock, nil)
+	assert.NotNil(t, err)
+	_, err = ParseBlock("not hex", watchSet)
+	assert.NotNil(t, err)
+	_, err = ParseBlock(rawBlock[:100], watchSet)
+	assert.NotNil(t, err)
+	assert.NotNil(t, watchSet.AddScript(nil))
+	assert.NotNil(t, watchSet.AddAddress("not an address"))
+	assert.NotNil(t, watchSet.AddOutpoint("not a txid", 0))
+}
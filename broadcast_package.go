@@ -0,0 +1,153 @@
+package cnlib
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+/// Type Definitions
+
+// BroadcastPackage bundles a signed transaction with unconfirmed parent transactions it depends on, such as the
+// parent of a CPFP child, so they can be submitted together. Transactions are accessed in broadcast order, parents
+// before children, one at a time as gomobile does not support custom arrays/slices.
+type BroadcastPackage struct {
+	child   *wire.MsgTx
+	parents []*wire.MsgTx
+}
+
+// BroadcastPackageTransaction is a single transaction of a BroadcastPackage.
+type BroadcastPackageTransaction struct {
+	Txid      string
+	EncodedTx string
+	Order     int  // position in broadcast order, starting at 0
+	IsChild   bool // true for the package's final transaction, which the package was built for
+}
+
+/// Constructors
+
+// NewBroadcastPackage returns a pointer to a BroadcastPackage for a signed child transaction, such as
+// `TransactionMetadata.EncodedTx`.
+func NewBroadcastPackage(encodedChildTx string) (*BroadcastPackage, error) {
+	tx, err := decodeMsgTx(encodedChildTx)
+	if err != nil {
+		return nil, err
+	}
+	return &BroadcastPackage{child: tx}, nil
+}
+
+/// Receiver functions
+
+// AddParent adds an unconfirmed transaction the child depends on, directly or through another parent. Parents may be
+// added in any order. Returns error if the transaction is already in the package.
+func (p *BroadcastPackage) AddParent(encodedTx string) error {
+	tx, err := decodeMsgTx(encodedTx)
+	if err != nil {
+		return err
+	}
+	hash := tx.TxHash()
+	for _, existing := range p.allTransactions() {
+		if existing.TxHash() == hash {
+			return errors.New("transaction already in package")
+		}
+	}
+	p.parents = append(p.parents, tx)
+	return nil
+}
+
+// IsPackage returns true if the child has parents which must be submitted with it, rather than broadcast alone.
+func (p *BroadcastPackage) IsPackage() bool {
+	return len(p.parents) > 0
+}
+
+// TransactionCount returns the number of transactions in the package, including the child.
+func (p *BroadcastPackage) TransactionCount() int {
+	return len(p.parents) + 1
+}
+
+// TransactionAtIndex returns the transaction at a given position in broadcast order, or error if the index is out of
+// bounds or a parent is not an ancestor of the child.
+func (p *BroadcastPackage) TransactionAtIndex(index int) (*BroadcastPackageTransaction, error) {
+	ordered, err := p.orderedTransactions()
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(ordered) {
+		return nil, errors.New("index out of bounds")
+	}
+	return newBroadcastPackageTransaction(ordered[index], index, index == len(ordered)-1)
+}
+
+// SubmitPackageJSON returns the package's encoded transactions, in broadcast order, as a JSON array of hex strings,
+// the format accepted by Bitcoin Core's `submitpackage`.
+func (p *BroadcastPackage) SubmitPackageJSON() (string, error) {
+	ordered, err := p.orderedTransactions()
+	if err != nil {
+		return "", err
+	}
+
+	encoded := make([]string, 0, len(ordered))
+	for _, tx := range ordered {
+		raw, err := encodeMsgTx(tx)
+		if err != nil {
+			return "", err
+		}
+		encoded = append(encoded, raw)
+	}
+
+	result, err := json.Marshal(encoded)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+/// Unexported functions
+
+func newBroadcastPackageTransaction(tx *wire.MsgTx, order int, isChild bool) (*BroadcastPackageTransaction, error) {
+	encoded, err := encodeMsgTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &BroadcastPackageTransaction{Txid: tx.TxHash().String(), EncodedTx: encoded, Order: order, IsChild: isChild}, nil
+}
+
+func (p *BroadcastPackage) allTransactions() []*wire.MsgTx {
+	return append([]*wire.MsgTx{p.child}, p.parents...)
+}
+
+// orderedTransactions returns the package's transactions sorted so each transaction follows every package
+// transaction it spends, ending with the child. Returns error if a parent is not an ancestor of the child.
+func (p *BroadcastPackage) orderedTransactions() ([]*wire.MsgTx, error) {
+	byHash := make(map[chainhash.Hash]*wire.MsgTx)
+	for _, tx := range p.parents {
+		byHash[tx.TxHash()] = tx
+	}
+
+	ordered := make([]*wire.MsgTx, 0, len(p.parents)+1)
+	visited := make(map[chainhash.Hash]bool)
+
+	// depth-first post-order walk from the child through inputs spending package parents
+	var visit func(tx *wire.MsgTx)
+	visit = func(tx *wire.MsgTx) {
+		hash := tx.TxHash()
+		if visited[hash] {
+			return
+		}
+		visited[hash] = true
+		for _, txIn := range tx.TxIn {
+			if parent, ok := byHash[txIn.PreviousOutPoint.Hash]; ok {
+				visit(parent)
+			}
+		}
+		ordered = append(ordered, tx)
+	}
+	visit(p.child)
+
+	if len(ordered) != len(p.parents)+1 {
+		return nil, errors.New("package contains a transaction which is not an ancestor of the child")
+	}
+	return ordered, nil
+}
@@ -0,0 +1,69 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// packageTestTx returns an encoded transaction spending output 0 of a given txid, and its txid.
+func packageTestTx(t *testing.T, spendTxid string) (string, string) {
+	rt, err := NewRawTransaction(2, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, rt.AddInput(NewRawTransactionInput(spendTxid, 0, 0xfffffffd)))
+	pkScript, _ := hex.DecodeString("0014933c5165df610846d08f026d18332610c13eef7f")
+	assert.Nil(t, rt.AddOutput(NewRawTransactionOutput(5000, pkScript)))
+	encoded, err := rt.Encode()
+	assert.Nil(t, err)
+	return encoded, rt.Txid()
+}
+
+func TestBroadcastPackage_OrdersParentsBeforeChild(t *testing.T) {
+	grandparent, grandparentTxid := packageTestTx(t, "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69")
+	parent, parentTxid := packageTestTx(t, grandparentTxid)
+	child, childTxid := packageTestTx(t, parentTxid)
+
+	pkg, err := NewBroadcastPackage(child)
+	assert.Nil(t, err)
+	assert.False(t, pkg.IsPackage())
+
+	// added out of order
+	assert.Nil(t, pkg.AddParent(parent))
+	assert.Nil(t, pkg.AddParent(grandparent))
+	assert.NotNil(t, pkg.AddParent(parent))
+	assert.True(t, pkg.IsPackage())
+	assert.Equal(t, 3, pkg.TransactionCount())
+
+	expected := []string{grandparentTxid, parentTxid, childTxid}
+	for i, txid := range expected {
+		tx, err := pkg.TransactionAtIndex(i)
+		assert.Nil(t, err)
+		assert.Equal(t, txid, tx.Txid)
+		assert.Equal(t, i, tx.Order)
+		assert.Equal(t, i == 2, tx.IsChild)
+	}
+	_, err = pkg.TransactionAtIndex(3)
+	assert.NotNil(t, err)
+
+	encoded, err := pkg.SubmitPackageJSON()
+	assert.Nil(t, err)
+	var txs []string
+	assert.Nil(t, json.Unmarshal([]byte(encoded), &txs))
+	assert.Equal(t, []string{grandparent, parent, child}, txs)
+}
+
+func TestBroadcastPackage_RejectsUnrelatedParent(t *testing.T) {
+	child, _ := packageTestTx(t, "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69")
+	unrelated, _ := packageTestTx(t, "fe7f9a6de3203eb300cc66159e762251d675b5555dbd215c3574e75a762ca402")
+
+	pkg, err := NewBroadcastPackage(child)
+	assert.Nil(t, err)
+	assert.Nil(t, pkg.AddParent(unrelated))
+
+	_, err = pkg.TransactionAtIndex(0)
+	assert.NotNil(t, err)
+	_, err = pkg.SubmitPackageJSON()
+	assert.NotNil(t, err)
+}
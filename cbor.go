@@ -0,0 +1,231 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// A minimal CBOR (RFC 8949) codec covering the subset used by UR types: unsigned integers, byte and text strings,
+// arrays, maps with unsigned integer keys, tags and booleans. Values are represented as uint64, []byte, string,
+// []interface{}, map[uint64]interface{}, cborTag and bool. Maps are encoded with keys in ascending order, as
+// canonical CBOR requires.
+
+const (
+	cborMajorUnsigned = 0
+	cborMajorBytes    = 2
+	cborMajorText     = 3
+	cborMajorArray    = 4
+	cborMajorMap      = 5
+	cborMajorTag      = 6
+	cborMajorSimple   = 7
+
+	cborFalse = 20
+	cborTrue  = 21
+
+	cborMaxNesting = 32
+)
+
+/// Type Definitions
+
+type cborTag struct {
+	tag   uint64
+	value interface{}
+}
+
+/// Unexported functions
+
+func cborEncode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeCBOR(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCBOR(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case uint64:
+		writeCBORHead(buf, cborMajorUnsigned, v)
+	case uint32:
+		writeCBORHead(buf, cborMajorUnsigned, uint64(v))
+	case int:
+		if v < 0 {
+			return errors.New("cbor: negative integers are not supported")
+		}
+		writeCBORHead(buf, cborMajorUnsigned, uint64(v))
+	case []byte:
+		writeCBORHead(buf, cborMajorBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeCBORHead(buf, cborMajorText, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		writeCBORHead(buf, cborMajorArray, uint64(len(v)))
+		for _, item := range v {
+			if err := writeCBOR(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[uint64]interface{}:
+		keys := make([]uint64, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		writeCBORHead(buf, cborMajorMap, uint64(len(v)))
+		for _, key := range keys {
+			writeCBORHead(buf, cborMajorUnsigned, key)
+			if err := writeCBOR(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	case cborTag:
+		writeCBORHead(buf, cborMajorTag, v.tag)
+		return writeCBOR(buf, v.value)
+	case bool:
+		if v {
+			buf.WriteByte(cborMajorSimple<<5 | cborTrue)
+		} else {
+			buf.WriteByte(cborMajorSimple<<5 | cborFalse)
+		}
+	default:
+		return errors.New("cbor: unsupported type")
+	}
+	return nil
+}
+
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= 0xff:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(n))
+	case n <= 0xffff:
+		buf.WriteByte(major<<5 | 25)
+		b := make([]byte, 2)
+		binary.BigEndian.PutUint16(b, uint16(n))
+		buf.Write(b)
+	case n <= 0xffffffff:
+		buf.WriteByte(major<<5 | 26)
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(n))
+		buf.Write(b)
+	default:
+		buf.WriteByte(major<<5 | 27)
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, n)
+		buf.Write(b)
+	}
+}
+
+// cborDecode decodes a single CBOR value, returning error if any data follows it.
+func cborDecode(data []byte) (interface{}, error) {
+	value, rest, err := readCBOR(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("cbor: unexpected trailing data")
+	}
+	return value, nil
+}
+
+func readCBOR(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxNesting {
+		return nil, nil, errors.New("cbor: nesting too deep")
+	}
+	major, n, rest, err := readCBORHead(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborMajorUnsigned:
+		return n, rest, nil
+	case cborMajorBytes, cborMajorText:
+		if uint64(len(rest)) < n {
+			return nil, nil, errors.New("cbor: insufficient data")
+		}
+		if major == cborMajorText {
+			return string(rest[:n]), rest[n:], nil
+		}
+		return append([]byte{}, rest[:n]...), rest[n:], nil
+	case cborMajorArray:
+		if uint64(len(rest)) < n {
+			return nil, nil, errors.New("cbor: insufficient data")
+		}
+		items := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var item interface{}
+			item, rest, err = readCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, rest, nil
+	case cborMajorMap:
+		if uint64(len(rest)) < 2*n {
+			return nil, nil, errors.New("cbor: insufficient data")
+		}
+		entries := make(map[uint64]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			var key, value interface{}
+			key, rest, err = readCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			intKey, ok := key.(uint64)
+			if !ok {
+				return nil, nil, errors.New("cbor: map keys must be unsigned integers")
+			}
+			value, rest, err = readCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			entries[intKey] = value
+		}
+		return entries, rest, nil
+	case cborMajorTag:
+		value, rest, err := readCBOR(rest, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cborTag{tag: n, value: value}, rest, nil
+	case cborMajorSimple:
+		switch n {
+		case cborFalse:
+			return false, rest, nil
+		case cborTrue:
+			return true, rest, nil
+		}
+	}
+	return nil, nil, errors.New("cbor: unsupported type")
+}
+
+func readCBORHead(data []byte) (byte, uint64, []byte, error) {
+	if len(data) == 0 {
+		return 0, 0, nil, errors.New("cbor: insufficient data")
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	if info < 24 {
+		return major, uint64(info), data, nil
+	}
+	if info > 27 {
+		return 0, 0, nil, errors.New("cbor: indefinite lengths are not supported")
+	}
+	size := 1 << (info - 24)
+	if len(data) < size {
+		return 0, 0, nil, errors.New("cbor: insufficient data")
+	}
+	var n uint64
+	for _, b := range data[:size] {
+		n = n<<8 | uint64(b)
+	}
+	return major, n, data[size:], nil
+}
@@ -0,0 +1,55 @@
+package cnlib
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrChangeOutputNotFound is returned by `VerifyChangeOutput` when no output pays to the wallet's change chain.
+var ErrChangeOutputNotFound = errors.New("no output pays to the wallet's change chain")
+
+/// Receiver functions
+
+// VerifyChangeOutput proves, before signing, that a transaction pays change to an address derived from the wallet's
+// own change chain, guarding against malware swapping the change address held in memory. Change addresses are
+// derived afresh from the wallet's keys, starting at changeIndexHint, the index the app expects, and up to the gap
+// limit beyond it in case the app's index lags. Returns the change output, with its derivation path, or
+// `ErrChangeOutputNotFound`.
+func (wallet *HDWallet) VerifyChangeOutput(encodedTx string, changeIndexHint int) (*AnalyzedOutput, error) {
+	tx, err := decodeMsgTx(encodedTx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDerivationIndex(changeIndexHint); err != nil {
+		return nil, err
+	}
+
+	for i := changeIndexHint; i < changeIndexHint+defaultGapLimit; i++ {
+		path := NewDerivationPath(wallet.BaseCoin, 1, i)
+		if err := path.Validate(); err != nil {
+			break
+		}
+		pkScript, _, err := wallet.prevoutScriptsForPath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for index, txOut := range tx.TxOut {
+			if !bytes.Equal(txOut.PkScript, pkScript) {
+				continue
+			}
+			meta, err := wallet.ChangeAddressForIndex(i)
+			if err != nil {
+				return nil, err
+			}
+			return &AnalyzedOutput{
+				Index:          index,
+				Address:        meta.Address,
+				Amount:         int(txOut.Value),
+				Kind:           OutputKindChange,
+				DerivationPath: path,
+			}, nil
+		}
+	}
+	return nil, ErrChangeOutputNotFound
+}
@@ -0,0 +1,56 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyChangeOutput_FindsChangeFromHint(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+
+	change, err := wallet.VerifyChangeOutput(analysisEncodedTx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, change.Index)
+	assert.Equal(t, 85936, change.Amount)
+	assert.Equal(t, OutputKindChange, change.Kind)
+	assert.Equal(t, 1, change.DerivationPath.Change)
+	assert.Equal(t, 1, change.DerivationPath.Index)
+
+	expected, err := wallet.ChangeAddressForIndex(1)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Address, change.Address)
+
+	// a lagging hint still finds the change within the gap limit
+	change, err = wallet.VerifyChangeOutput(analysisEncodedTx, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, change.DerivationPath.Index)
+}
+
+func TestVerifyChangeOutput_WatchOnlyWallet_FindsChange(t *testing.T) {
+	zpub := "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	wallet, err := NewHDWalletFromAccountExtendedPublicKey(zpub)
+	assert.Nil(t, err)
+
+	change, err := wallet.VerifyChangeOutput(analysisEncodedTx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, change.Index)
+}
+
+func TestVerifyChangeOutput_SwappedChange_ReturnsError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+
+	// change derived before the hint is not accepted
+	_, err := wallet.VerifyChangeOutput(analysisEncodedTx, 2)
+	assert.Equal(t, ErrChangeOutputNotFound, err)
+
+	// nor is change paying to another wallet's change chain
+	other := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	_, err = other.VerifyChangeOutput(analysisEncodedTx, 0)
+	assert.Equal(t, ErrChangeOutputNotFound, err)
+
+	_, err = wallet.VerifyChangeOutput(analysisEncodedTx, -1)
+	assert.Equal(t, ErrDerivationIndexNegative, err)
+	_, err = wallet.VerifyChangeOutput("00", 0)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,171 @@
+package cnlib
+
+import (
+	"errors"
+	"sort"
+)
+
+// maxChangelessSearchTries bounds the branch and bound search for a changeless selection, as Bitcoin Core does.
+const maxChangelessSearchTries = 100000
+
+// ErrChangelessPaymentNotFound describes an error in which no selection of utxos pays an amount within the tolerance
+// without change.
+var ErrChangelessPaymentNotFound = errors.New("no changeless payment found within tolerance")
+
+/// Type Definitions
+
+// ChangelessPaymentOptimizer finds a payment amount, within a tolerance the caller approves, which utxos can pay
+// exactly, with no change output, avoiding the fee of creating change and the link it makes to a future transaction.
+// Add all of the wallet's spendable utxos one at a time using `AddUTXO`, as gomobile does not support custom
+// arrays/slices.
+type ChangelessPaymentOptimizer struct {
+	basecoin *BaseCoin
+	utxos    []*UTXO
+}
+
+// ChangelessPayment is a payment of Amount, adjusted from RequestedAmount by AmountDelta, which its utxos pay exactly
+// with FeeAmount at the fee rate. Show the adjusted amount to the user to confirm before building the transaction.
+type ChangelessPayment struct {
+	PaymentAddress  string
+	RequestedAmount int
+	Amount          int
+	AmountDelta     int // Amount - RequestedAmount; positive if paying more than requested
+	FeeAmount       int
+	utxos           []*UTXO
+}
+
+/// Constructors
+
+// NewChangelessPaymentOptimizer returns a pointer to an empty ChangelessPaymentOptimizer for the given BaseCoin.
+func NewChangelessPaymentOptimizer(basecoin *BaseCoin) *ChangelessPaymentOptimizer {
+	return &ChangelessPaymentOptimizer{basecoin: basecoin, utxos: []*UTXO{}}
+}
+
+/// Receiver functions
+
+// AddUTXO adds a spendable utxo.
+func (o *ChangelessPaymentOptimizer) AddUTXO(utxo *UTXO) {
+	o.utxos = append(o.utxos, utxo)
+}
+
+// UtxoCount returns the number of utxos added.
+func (o *ChangelessPaymentOptimizer) UtxoCount() int {
+	return len(o.utxos)
+}
+
+// Optimize returns the changeless payment to an address, at feeRate in satoshis per vbyte, whose amount is closest to
+// amount and differs by at most tolerance satoshis, preferring fewer inputs between equally close payments. Returns
+// ErrChangelessPaymentNotFound if there is none, or error if the arguments are invalid.
+func (o *ChangelessPaymentOptimizer) Optimize(paymentAddress string, amount int, feeRate int, tolerance int) (*ChangelessPayment, error) {
+	if amount < dustThreshold {
+		return nil, errors.New("amount is below dust threshold")
+	}
+	if feeRate < 0 || tolerance < 0 {
+		return nil, errors.New("fee rate and tolerance cannot be negative")
+	}
+	baseBytes, err := o.basecoin.totalBytes(nil, paymentAddress, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// a selection's payment is the sum of its inputs' effective values, less the fee of the rest of the transaction
+	type candidate struct {
+		utxo      *UTXO
+		effective int64
+	}
+	candidates := make([]candidate, 0, len(o.utxos))
+	for _, utxo := range o.utxos {
+		inputBytes, err := o.basecoin.bytesPerInput(utxo)
+		if err != nil {
+			return nil, err
+		}
+		if effective := utxo.Amount - int64(feeRate*inputBytes); effective > 0 {
+			candidates = append(candidates, candidate{utxo, effective})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].effective > candidates[j].effective })
+	remaining := make([]int64, len(candidates)+1)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + candidates[i].effective
+	}
+
+	target := int64(amount) + int64(feeRate*baseBytes)
+	low, high := target-int64(tolerance), target+int64(tolerance)
+	if low < int64(dustThreshold+feeRate*baseBytes) {
+		low = int64(dustThreshold + feeRate*baseBytes)
+	}
+
+	var best []int
+	bestDistance := int64(-1)
+	selected := make([]int, 0, len(candidates))
+	tries := 0
+	var search func(index int, sum int64)
+	search = func(index int, sum int64) {
+		tries++
+		if tries > maxChangelessSearchTries || sum > high || sum+remaining[index] < low {
+			return
+		}
+		if sum >= low {
+			distance := sum - target
+			if distance < 0 {
+				distance = -distance
+			}
+			if bestDistance < 0 || distance < bestDistance || (distance == bestDistance && len(selected) < len(best)) {
+				best = append([]int(nil), selected...)
+				bestDistance = distance
+			}
+			if distance == 0 {
+				return
+			}
+		}
+		if index == len(candidates) {
+			return
+		}
+		selected = append(selected, index)
+		search(index+1, sum+candidates[index].effective)
+		selected = selected[:len(selected)-1]
+		search(index+1, sum)
+	}
+	search(0, 0)
+	if best == nil {
+		return nil, ErrChangelessPaymentNotFound
+	}
+
+	payment := &ChangelessPayment{PaymentAddress: paymentAddress, RequestedAmount: amount}
+	total := int64(0)
+	for _, index := range best {
+		payment.utxos = append(payment.utxos, candidates[index].utxo)
+		total += candidates[index].utxo.Amount
+	}
+	totalBytes, err := o.basecoin.totalBytes(payment.utxos, paymentAddress, false)
+	if err != nil {
+		return nil, err
+	}
+	payment.FeeAmount = feeRate * totalBytes
+	payment.Amount = int(total) - payment.FeeAmount
+	payment.AmountDelta = payment.Amount - amount
+	return payment, nil
+}
+
+// UtxoCount returns the number of utxos the payment spends.
+func (p *ChangelessPayment) UtxoCount() int {
+	return len(p.utxos)
+}
+
+// UtxoAtIndex returns a utxo the payment spends.
+func (p *ChangelessPayment) UtxoAtIndex(index int) (*UTXO, error) {
+	if index < 0 || index >= len(p.utxos) {
+		return nil, errors.New("index out of range")
+	}
+	return p.utxos[index], nil
+}
+
+// TransactionData returns a flat fee transaction of the confirmed payment, spending only its utxos, with no change.
+// Call `Generate` before building it as any other.
+func (p *ChangelessPayment) TransactionData(basecoin *BaseCoin, changePath *DerivationPath, blockHeight int) *TransactionDataFlatFee {
+	data := NewTransactionDataFlatFee(p.PaymentAddress, basecoin, p.Amount, p.FeeAmount, changePath, blockHeight)
+	for _, utxo := range p.utxos {
+		data.AddUTXO(utxo)
+	}
+	return data
+}
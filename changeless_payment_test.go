@@ -0,0 +1,91 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const changelessPaymentTestAddress = "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"
+
+func changelessPaymentTestOptimizer(amounts ...int64) *ChangelessPaymentOptimizer {
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	optimizer := NewChangelessPaymentOptimizer(BaseCoinBip84MainNet)
+	for i, amount := range amounts {
+		optimizer.AddUTXO(NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", i, amount, path, nil, true))
+	}
+	return optimizer
+}
+
+func TestChangelessPaymentOptimizer_AdjustsAmountWithinTolerance(t *testing.T) {
+	optimizer := changelessPaymentTestOptimizer(100000, 52000, 30000)
+	assert.Equal(t, 3, optimizer.UtxoCount())
+	oneInputFee := 10 * (baseSize + p2wpkhSegwitInputSize + p2wpkhOutputSize)
+
+	payment, err := optimizer.Optimize(changelessPaymentTestAddress, 50000, 10, 2000)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, payment.UtxoCount())
+	utxo, _ := payment.UtxoAtIndex(0)
+	assert.Equal(t, int64(52000), utxo.Amount)
+	assert.Equal(t, oneInputFee, payment.FeeAmount)
+	assert.Equal(t, 52000-oneInputFee, payment.Amount)
+	assert.Equal(t, 50000, payment.RequestedAmount)
+	assert.Equal(t, payment.Amount-50000, payment.AmountDelta)
+
+	// paying less than requested is a candidate too
+	payment, err = optimizer.Optimize(changelessPaymentTestAddress, 29500, 10, 2000)
+	assert.Nil(t, err)
+	assert.Equal(t, 30000-oneInputFee, payment.Amount)
+	assert.True(t, payment.AmountDelta < 0)
+}
+
+func TestChangelessPaymentOptimizer_PrefersClosestThenFewestInputs(t *testing.T) {
+	optimizer := changelessPaymentTestOptimizer(60000, 30000, 30000, 25000)
+	inputFee := 10 * p2wpkhSegwitInputSize
+	baseFee := 10 * (baseSize + p2wpkhOutputSize)
+
+	// two 30,000 utxos hit the amount exactly, where 60,000 alone is further off
+	amount := 60000 - 2*inputFee - baseFee
+	payment, err := optimizer.Optimize(changelessPaymentTestAddress, amount, 10, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, payment.UtxoCount())
+	assert.Equal(t, 0, payment.AmountDelta)
+
+	// equally close, one input beats two
+	amount = 60000 - inputFee - baseFee
+	payment, err = optimizer.Optimize(changelessPaymentTestAddress, amount, 10, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, payment.UtxoCount())
+	assert.Equal(t, 0, payment.AmountDelta)
+}
+
+func TestChangelessPaymentOptimizer_BuildsTransactionWithoutChange(t *testing.T) {
+	optimizer := changelessPaymentTestOptimizer(100000, 52000, 30000)
+	payment, err := optimizer.Optimize(changelessPaymentTestAddress, 80000, 5, 3000)
+	assert.Nil(t, err)
+
+	data := payment.TransactionData(BaseCoinBip84MainNet, NewDerivationPath(BaseCoinBip84MainNet, 1, 0), 500000)
+	assert.Nil(t, data.Generate())
+	assert.Equal(t, payment.Amount, data.TransactionData.Amount)
+	assert.Equal(t, payment.FeeAmount, data.TransactionData.FeeAmount)
+	assert.Equal(t, 0, data.TransactionData.ChangeAmount)
+	assert.Equal(t, payment.UtxoCount(), len(data.TransactionData.requiredUtxos))
+}
+
+func TestChangelessPaymentOptimizer_NotFound(t *testing.T) {
+	optimizer := changelessPaymentTestOptimizer(100000, 52000)
+	_, err := optimizer.Optimize(changelessPaymentTestAddress, 75000, 10, 1000)
+	assert.Equal(t, ErrChangelessPaymentNotFound, err)
+
+	// uneconomical utxos are never selected
+	optimizer = changelessPaymentTestOptimizer(600)
+	_, err = optimizer.Optimize(changelessPaymentTestAddress, 1000, 10, 1000)
+	assert.Equal(t, ErrChangelessPaymentNotFound, err)
+
+	_, err = optimizer.Optimize(changelessPaymentTestAddress, 500, 10, 1000)
+	assert.NotNil(t, err)
+	_, err = optimizer.Optimize(changelessPaymentTestAddress, 50000, 10, -1)
+	assert.NotNil(t, err)
+	_, err = optimizer.Optimize("not an address", 50000, 10, 1000)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,97 @@
+package cnlib
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+const compactSignatureSize = 65
+
+// ErrCompactSignatureLength describes an error in which a compact signature is not 65 bytes.
+var ErrCompactSignatureLength = errors.New("compact signature must be 65 bytes")
+
+/// Receiver functions
+
+// SignCompactData signs a given message with the signing key (m/42), as `SignData` does, and returns a 65-byte compact
+// signature from which `RecoverCompactPublicKey` recovers the signing public key, so messages need not carry it.
+func (wallet *HDWallet) SignCompactData(message []byte) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := wallet.signingPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return signCompact(message, privateKey)
+}
+
+// SignCompactDataWithPath signs a given message as `SignCompactData` does, with the key derived from given derivation
+// path. Returns ErrKeyRoleMismatch for spending or encryption key paths, as `SignDataWithPath` does.
+func (wallet *HDWallet) SignCompactDataWithPath(message []byte, path *DerivationPath) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	if path == nil {
+		return nil, errors.New("derivation path cannot be nil")
+	}
+	if err := rejectKeyRoles(path, KeyRoleSpending, KeyRoleEncryption); err != nil {
+		return nil, err
+	}
+
+	key, err := wallet.keyFactory().indexPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := key.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return signCompact(message, privateKey)
+}
+
+/// Exported functions
+
+// RecoverCompactPublicKey returns the compressed public key which made a 65-byte compact signature of a message, as
+// returned by `SignCompactData`. Any signature recovers some key, so compare it with the sender's known key, such as
+// one from their key exchange payload, or use `VerifyCompactSignature`. Returns ErrCompactSignatureLength, or error
+// if no key can be recovered.
+func RecoverCompactPublicKey(message []byte, signature []byte) ([]byte, error) {
+	if len(signature) != compactSignatureSize {
+		return nil, ErrCompactSignatureLength
+	}
+	pubkey, _, err := btcec.RecoverCompact(btcec.S256(), signature, chainhash.DoubleHashB(message))
+	if err != nil {
+		return nil, err
+	}
+	return pubkey.SerializeCompressed(), nil
+}
+
+// VerifyCompactSignature verifies a compact signature of a message against a hex-encoded compressed or uncompressed
+// public key. Returns nil if valid, ErrSignatureInvalid if made by another key, ErrPublicKeyHex, ErrPublicKeyLength or
+// ErrPublicKeyPoint if the public key is malformed, or error if the signature is.
+func VerifyCompactSignature(message []byte, signature []byte, pubkeyHex string) error {
+	pubkey, err := parseHexPubKey(pubkeyHex)
+	if err != nil {
+		return err
+	}
+	recovered, err := RecoverCompactPublicKey(message, signature)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(recovered, pubkey.SerializeCompressed()) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+/// Unexported functions
+
+// signCompact returns the compact signature of the double-SHA256 hash of a message, recovering a compressed key.
+func signCompact(message []byte, privateKey *btcec.PrivateKey) ([]byte, error) {
+	return btcec.SignCompact(btcec.S256(), privateKey, chainhash.DoubleHashB(message), true)
+}
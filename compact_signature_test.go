@@ -0,0 +1,66 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignCompactData_RecoversSigningKey(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	message := []byte("Hello World")
+
+	signature, err := wallet.SignCompactData(message)
+	assert.Nil(t, err)
+	assert.Equal(t, compactSignatureSize, len(signature))
+	pubkey, err := RecoverCompactPublicKey(message, signature)
+	assert.Nil(t, err)
+	signingPubkey, _ := wallet.SigningPublicKey()
+	assert.Equal(t, signingPubkey, pubkey)
+	assert.Nil(t, VerifyCompactSignature(message, signature, hex.EncodeToString(signingPubkey)))
+
+	// another message recovers another key
+	pubkey, err = RecoverCompactPublicKey([]byte("Hello World!"), signature)
+	assert.Nil(t, err)
+	assert.NotEqual(t, signingPubkey, pubkey)
+	assert.Equal(t, ErrSignatureInvalid, VerifyCompactSignature([]byte("Hello World!"), signature, hex.EncodeToString(signingPubkey)))
+
+	// the uncompressed key verifies too
+	uncompressed, _ := wallet.UncompressedPubKeyForPath(&DerivationPath{components: []uint32{42}})
+	assert.Nil(t, VerifyCompactSignature(message, signature, hex.EncodeToString(uncompressed)))
+}
+
+func TestSignCompactDataWithPath(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	message := []byte("Hello World")
+	contact, _ := ParseCustomDerivationPath("m/1000'/7'")
+
+	signature, err := wallet.SignCompactDataWithPath(message, contact)
+	assert.Nil(t, err)
+	pubkey, err := RecoverCompactPublicKey(message, signature)
+	assert.Nil(t, err)
+	expected, _ := wallet.CompressedPubKeyForPath(contact)
+	assert.Equal(t, expected, pubkey)
+
+	_, err = wallet.SignCompactDataWithPath(message, NewDerivationPath(BaseCoinBip84MainNet, 0, 0))
+	assert.Equal(t, ErrKeyRoleMismatch, err)
+	_, err = wallet.SignCompactDataWithPath(message, nil)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, wallet.SetRole(WalletRoleHot))
+	_, err = wallet.SignCompactData(message)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
+
+func TestRecoverCompactPublicKey_Invalid(t *testing.T) {
+	message := []byte("Hello World")
+	_, err := RecoverCompactPublicKey(message, make([]byte, 64))
+	assert.Equal(t, ErrCompactSignatureLength, err)
+	_, err = RecoverCompactPublicKey(message, make([]byte, 65))
+	assert.NotNil(t, err)
+
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	signature, _ := wallet.SignCompactData(message)
+	assert.Equal(t, ErrPublicKeyHex, VerifyCompactSignature(message, signature, "not hex"))
+}
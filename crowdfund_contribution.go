@@ -0,0 +1,89 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// crowdfundSigHashType commits to every output while allowing other contributors to add their own inputs.
+const crowdfundSigHashType = txscript.SigHashAll | txscript.SigHashAnyOneCanPay
+
+/// Receiver functions
+
+/*
+BuildCrowdfundContribution creates a single-input, single-output assurance contract contribution.
+
+The one output pays the full pledge goal to the pledge address, and the one input, the contributor's utxo, is signed
+with SIGHASH_ALL|SIGHASH_ANYONECANPAY. The resulting transaction is not valid on its own; the campaign combines the
+inputs of all contributions into a single transaction once their sum satisfies the pledge amount.
+
+@param utxo The utxo being contributed. Its entire amount is pledged.
+@param pledgeAddress The address collecting the crowdfunded amount.
+@param pledgeAmount The fixed goal, in satoshis, of the pledge output.
+@return Returns the txid and encoded contribution, or error if the contribution cannot be signed.
+*/
+func (wallet *HDWallet) BuildCrowdfundContribution(utxo *UTXO, pledgeAddress string, pledgeAmount int) (*TransactionMetadata, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	if utxo == nil {
+		return nil, errors.New("utxo cannot be nil")
+	}
+	if pledgeAmount < dustThreshold {
+		return nil, errors.New("pledge amount too small")
+	}
+	if utxo.Amount <= 0 || utxo.Amount > int64(pledgeAmount) {
+		return nil, errors.New("contribution must be positive and not exceed pledge amount")
+	}
+
+	tb := transactionBuilder{wallet: wallet}
+
+	pledgeScript, err := tb.pkScriptForAddress(pledgeAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, sourceAddress, err := tb.usableAddressForUTXO(utxo)
+	if err != nil {
+		return nil, err
+	}
+	prevScript, err := tb.pkScriptForAddress(sourceAddress)
+	if err != nil {
+		return nil, err
+	}
+	if err := utxo.verifyPrevout(prevScript); err != nil {
+		return nil, err
+	}
+
+	outpoint, err := outPointFromInfo(utxo.Txid, utxo.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(outpoint, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(int64(pledgeAmount), pledgeScript))
+
+	err = signInputWithHashType(tx, 0, prevScript, utxo.Amount, crowdfundSigHashType, signer.derivedPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateMsgTx(tx, [][]byte{prevScript}, []btcutil.Amount{btcutil.Amount(utxo.Amount)})
+	if err != nil {
+		return nil, err
+	}
+
+	var encoded bytes.Buffer
+	if err := tx.Serialize(&encoded); err != nil {
+		return nil, err
+	}
+
+	return &TransactionMetadata{Txid: tx.TxHash().String(), EncodedTx: hex.EncodeToString(encoded.Bytes())}, nil
+}
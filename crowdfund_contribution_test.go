@@ -0,0 +1,46 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildCrowdfundContribution_SignsWithAnyoneCanPay(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+	pledgeAddress := "bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6"
+
+	meta, err := wallet.BuildCrowdfundContribution(utxo, pledgeAddress, 1000000)
+	assert.Nil(t, err)
+
+	txBytes, err := hex.DecodeString(meta.EncodedTx)
+	assert.Nil(t, err)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	assert.Nil(t, tx.Deserialize(bytes.NewReader(txBytes)))
+
+	assert.Equal(t, 1, len(tx.TxIn))
+	assert.Equal(t, 1, len(tx.TxOut))
+	assert.Equal(t, int64(1000000), tx.TxOut[0].Value)
+	assert.Nil(t, meta.TransactionChangeMetadata)
+
+	sig := tx.TxIn[0].Witness[0]
+	assert.Equal(t, byte(txscript.SigHashAll|txscript.SigHashAnyOneCanPay), sig[len(sig)-1])
+}
+
+func TestBuildCrowdfundContribution_ExceedsPledge_ReturnsError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+
+	meta, err := wallet.BuildCrowdfundContribution(utxo, "bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", 50000)
+	assert.NotNil(t, err)
+	assert.Nil(t, meta)
+}
@@ -0,0 +1,145 @@
+package cnlib
+
+import (
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+/// Type Definitions
+
+// derivationCache holds the hardened and chain-level extended keys of a wallet, so that each signature or address
+// derivation only derives the final, non-hardened index. Safe for concurrent use; a nil cache derives every key.
+type derivationCache struct {
+	mu         sync.Mutex
+	chainKeys  map[derivationCacheKey]*hdkeychain.ExtendedKey
+	signingKey *hdkeychain.ExtendedKey
+	accountKey *hdkeychain.ExtendedKey // account extended public key of a lite wallet, see `NewLiteHDWalletFromWords`
+}
+
+// derivationCacheKey identifies the extended key of a change chain, m/purpose'/coin'/account'/change.
+type derivationCacheKey struct {
+	purpose int
+	coin    int
+	account int
+	change  int
+	public  bool // derived from the account extended public key rather than the master private key
+}
+
+/// Constructors
+
+func newDerivationCache() *derivationCache {
+	return &derivationCache{chainKeys: make(map[derivationCacheKey]*hdkeychain.ExtendedKey)}
+}
+
+/// Exported functions
+
+// WarmUpKeyDerivation initializes the secp256k1 curve and its precomputed multiplication table, which otherwise
+// happens on the first signature or derivation. Apps may call this in the background at launch to keep the cost off
+// the first user-facing operation; calling it is optional and repeat calls return immediately.
+func WarmUpKeyDerivation() {
+	curve := btcec.S256()
+	curve.ScalarBaseMult([]byte{1})
+}
+
+/// Receiver functions
+
+// WarmUp initializes the secp256k1 curve, as `WarmUpKeyDerivation` does, and derives the wallet's receive and change
+// chain keys, and signing key if not watch-only, so subsequent addresses and signatures derive a single key each.
+func (wallet *HDWallet) WarmUp() error {
+	WarmUpKeyDerivation()
+	for change := 0; change <= 1; change++ {
+		path := NewDerivationPath(wallet.BaseCoin, change, 0)
+		if wallet.masterPrivateKey == nil {
+			if _, err := wallet.accountChildPublicKey(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := wallet.keyFactory().indexPrivateKey(path); err != nil {
+			return err
+		}
+	}
+	if wallet.masterPrivateKey == nil {
+		return nil
+	}
+	_, err := wallet.keyFactory().signingMasterKey()
+	return err
+}
+
+// keyFactory returns a keyFactory for the wallet's keys, sharing its derivation cache.
+func (wallet *HDWallet) keyFactory() keyFactory {
+	return keyFactory{masterPrivateKey: wallet.masterPrivateKey, acctExtPubKey: wallet.accountPublicKey, cache: wallet.keyCache}
+}
+
+// chainKey returns the cached key for a change chain, deriving and caching it on a miss.
+func (c *derivationCache) chainKey(key derivationCacheKey, derive func() (*hdkeychain.ExtendedKey, error)) (*hdkeychain.ExtendedKey, error) {
+	if c == nil {
+		return derive()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.chainKeys[key]; ok {
+		return cached, nil
+	}
+	derived, err := derive()
+	if err != nil {
+		return nil, err
+	}
+	c.chainKeys[key] = derived
+	return derived, nil
+}
+
+// signingMasterKey returns the cached m/42 key, deriving and caching it on a miss.
+func (c *derivationCache) signingMasterKey(derive func() (*hdkeychain.ExtendedKey, error)) (*hdkeychain.ExtendedKey, error) {
+	if c == nil {
+		return derive()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.signingKey != nil {
+		return c.signingKey, nil
+	}
+	derived, err := derive()
+	if err != nil {
+		return nil, err
+	}
+	c.signingKey = derived
+	return derived, nil
+}
+
+// accountExtendedKey returns the cached account extended public key, deriving and caching it on a miss.
+func (c *derivationCache) accountExtendedKey(derive func() (*hdkeychain.ExtendedKey, error)) (*hdkeychain.ExtendedKey, error) {
+	if c == nil {
+		return derive()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accountKey != nil {
+		return c.accountKey, nil
+	}
+	derived, err := derive()
+	if err != nil {
+		return nil, err
+	}
+	c.accountKey = derived
+	return derived, nil
+}
+
+// wipe zeroes and discards every cached key, so that none outlives the wallet's master key.
+func (c *derivationCache) wipe() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, extended := range c.chainKeys {
+		extended.Zero()
+		delete(c.chainKeys, key)
+	}
+	if c.signingKey != nil {
+		c.signingKey.Zero()
+		c.signingKey = nil
+	}
+}
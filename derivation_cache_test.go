@@ -0,0 +1,43 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHDWalletWarmUp_CachesChainAndSigningKeys(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	uncached := &HDWallet{BaseCoin: wallet.BaseCoin, masterPrivateKey: wallet.masterPrivateKey, accountPublicKey: wallet.accountPublicKey}
+
+	assert.Nil(t, wallet.WarmUp())
+	assert.Equal(t, 2, len(wallet.keyCache.chainKeys))
+	assert.NotNil(t, wallet.keyCache.signingKey)
+
+	for i := 0; i < 3; i++ {
+		cached, err := wallet.ReceiveAddressForIndex(i)
+		assert.Nil(t, err)
+		expected, err := uncached.ReceiveAddressForIndex(i)
+		assert.Nil(t, err)
+		assert.Equal(t, expected.Address, cached.Address)
+	}
+	assert.Equal(t, 2, len(wallet.keyCache.chainKeys))
+
+	cachedKey, err := wallet.SigningKey()
+	assert.Nil(t, err)
+	expectedKey, err := uncached.SigningKey()
+	assert.Nil(t, err)
+	assert.Equal(t, expectedKey, cachedKey)
+}
+
+func TestHDWalletWarmUp_WatchOnly(t *testing.T) {
+	wallet, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	assert.Nil(t, wallet.WarmUp())
+	assert.Equal(t, 2, len(wallet.keyCache.chainKeys))
+
+	address, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", address.Address)
+	assert.Equal(t, 2, len(wallet.keyCache.chainKeys))
+}
@@ -1,13 +1,49 @@
 package cnlib
 
-// DerivationPath is used to provide information about an address to be generated.
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+// Following errors describe a derivation path which cannot be derived, such as one restored from corrupted app state.
+var (
+	ErrDerivationIndexNegative       = errors.New("index cannot be negative")
+	ErrDerivationIndexOverflow       = errors.New("index must be less than 2^31")
+	ErrDerivationPathMissingBaseCoin = errors.New("derivation path is missing basecoin")
+	ErrDerivationPathCustom          = errors.New("custom derivation path has no basecoin, change or index")
+)
+
+// Following errors are returned by `ParseDerivationPath`, wrapped with the offending segment where there is one.
+var (
+	ErrDerivationPathMalformed = errors.New("derivation path must be of the form m/purpose'/coin'/account'/change/index")
+	ErrDerivationPathSegment   = errors.New("derivation path segment is not a valid index")
+	ErrDerivationPathHardening = errors.New("derivation path must harden purpose, coin and account, and only those")
+)
+
+const (
+	// derivationPathDepth is the number of levels below the master key in a standard `DerivationPath`.
+	derivationPathDepth = 5
+	// maxCustomDerivationPathDepth is the deepest custom path, as BIP32 serializes depth in a single byte.
+	maxCustomDerivationPathDepth = 255
+)
+
+// DerivationPath is used to provide information about an address to be generated. A custom path, returned by
+// `ParseCustomDerivationPath`, instead holds a path of any depth from the master key, and has no BaseCoin, change or
+// index.
 type DerivationPath struct {
 	*BaseCoin // Embedded
 	Change    int
 	Index     int
+
+	components []uint32 // full path of a custom path, with hardened offsets applied
 }
 
-// NewDerivationPath instantiates a new object and sets values.
+// NewDerivationPath instantiates a new object and sets values. The values are validated before any key is derived
+// from the path; call `Validate` to check them sooner.
 func NewDerivationPath(bc *BaseCoin, change int, index int) *DerivationPath {
 	return &DerivationPath{
 		BaseCoin: bc,
@@ -15,3 +51,131 @@ func NewDerivationPath(bc *BaseCoin, change int, index int) *DerivationPath {
 		Index:    index,
 	}
 }
+
+// ParseDerivationPath parses a full path such as "m/84'/0'/0'/0/12", accepting either ' or h as the hardened marker,
+// as used by output descriptors and PSBTs. Purpose, coin and account must be hardened, and change and index not.
+// Paths of test coins use the default test network; call `UpdateTestNetwork` on the path's BaseCoin to change it.
+func ParseDerivationPath(path string) (*DerivationPath, error) {
+	segments := strings.Split(path, "/")
+	if len(segments) != derivationPathDepth+1 || segments[0] != "m" {
+		return nil, ErrDerivationPathMalformed
+	}
+
+	indexes := make([]int, derivationPathDepth)
+	for i, segment := range segments[1:] {
+		index, isHardened, err := parseDerivationPathSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+		if isHardened != (i < 3) {
+			return nil, fmt.Errorf("%w: %q", ErrDerivationPathHardening, segment)
+		}
+		indexes[i] = index
+	}
+
+	bc := NewBaseCoin(indexes[0], indexes[1], indexes[2])
+	return NewDerivationPath(bc, indexes[3], indexes[4]), nil
+}
+
+// ParseCustomDerivationPath parses a path of any depth from the master key, such as "m/45'/0" or "m/48'/0'/0'/2'",
+// accepting either ' or h as the hardened marker, for keys of multisig, identity or other nonstandard schemes. The
+// path may be used to derive keys, such as with `DecryptWithKeyFromDerivationPath` or `ExtendedPublicKeyForPath`,
+// but not addresses, as it has no BaseCoin.
+func ParseCustomDerivationPath(path string) (*DerivationPath, error) {
+	segments := strings.Split(path, "/")
+	if len(segments) < 2 || len(segments) > maxCustomDerivationPathDepth+1 || segments[0] != "m" {
+		return nil, ErrDerivationPathMalformed
+	}
+
+	components := make([]uint32, 0, len(segments)-1)
+	for _, segment := range segments[1:] {
+		index, isHardened, err := parseDerivationPathSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+		if isHardened {
+			components = append(components, hardened(index))
+		} else {
+			components = append(components, uint32(index))
+		}
+	}
+	return &DerivationPath{components: components}, nil
+}
+
+// IsCustom returns true if the path was returned by `ParseCustomDerivationPath`.
+func (p *DerivationPath) IsCustom() bool {
+	return p.components != nil
+}
+
+// Depth returns the number of levels below the master key, which is 5 unless the path is custom.
+func (p *DerivationPath) Depth() int {
+	if p.IsCustom() {
+		return len(p.components)
+	}
+	return derivationPathDepth
+}
+
+// String returns the path from the master key, such as "m/84'/0'/0'/0/12", using ' as the hardened marker, or an
+// empty string if a standard path has no BaseCoin.
+func (p *DerivationPath) String() string {
+	if p.IsCustom() {
+		var b strings.Builder
+		b.WriteString("m")
+		for _, component := range p.components {
+			if component >= hdkeychain.HardenedKeyStart {
+				fmt.Fprintf(&b, "/%d'", component-hdkeychain.HardenedKeyStart)
+			} else {
+				fmt.Fprintf(&b, "/%d", component)
+			}
+		}
+		return b.String()
+	}
+	if p.BaseCoin == nil {
+		return ""
+	}
+	return fmt.Sprintf("m/%d'/%d'/%d'/%d/%d", p.Purpose, p.Coin, p.Account, p.Change, p.Index)
+}
+
+// Validate returns error if the path has no BaseCoin, or if its purpose, coin, account, change or index is negative
+// or not below 2^31, beyond which it would silently derive the key of another index. Returns ErrDerivationPathCustom
+// for a custom path, which can derive keys but not addresses.
+func (p *DerivationPath) Validate() error {
+	if p.IsCustom() {
+		return ErrDerivationPathCustom
+	}
+	if p.BaseCoin == nil {
+		return ErrDerivationPathMissingBaseCoin
+	}
+	if err := p.BaseCoin.validateIndices(); err != nil {
+		return err
+	}
+	if err := validateDerivationIndex(p.Change); err != nil {
+		return err
+	}
+	return validateDerivationIndex(p.Index)
+}
+
+// validateDerivationIndex returns error if an index is negative, or would overflow a BIP32 child index.
+func validateDerivationIndex(index int) error {
+	if index < 0 {
+		return ErrDerivationIndexNegative
+	}
+	if uint64(index) >= uint64(hdkeychain.HardenedKeyStart) {
+		return ErrDerivationIndexOverflow
+	}
+	return nil
+}
+
+// parseDerivationPathSegment returns the index of a path segment, and whether it is marked hardened by ' or h.
+func parseDerivationPathSegment(segment string) (int, bool, error) {
+	digits := strings.TrimRight(segment, "'h")
+	isHardened := len(digits) != len(segment)
+	if len(segment)-len(digits) > 1 || digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return 0, false, fmt.Errorf("%w: %q", ErrDerivationPathSegment, segment)
+	}
+	index, err := strconv.ParseUint(digits, 10, 32)
+	if err != nil || index >= hdkeychain.HardenedKeyStart {
+		return 0, false, fmt.Errorf("%w: %q", ErrDerivationPathSegment, segment)
+	}
+	return int(index), isHardened, nil
+}
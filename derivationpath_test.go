@@ -0,0 +1,159 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDerivationPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected *DerivationPath
+		formats  string
+	}{
+		{"m/84'/0'/0'/0/12", NewDerivationPath(BaseCoinBip84MainNet, 0, 12), "m/84'/0'/0'/0/12"},
+		{"m/49h/1h/0h/1/0", NewDerivationPath(BaseCoinBip49TestNet, 1, 0), "m/49'/1'/0'/1/0"},
+		{"m/86'/0h/5'/0/2147483647", NewDerivationPath(NewBaseCoin(86, 0, 5), 0, 2147483647), "m/86'/0'/5'/0/2147483647"},
+	}
+
+	for _, test := range tests {
+		path, err := ParseDerivationPath(test.path)
+		assert.Nil(t, err, test.path)
+		assert.Equal(t, test.expected.Purpose, path.Purpose)
+		assert.Equal(t, test.expected.Coin, path.Coin)
+		assert.Equal(t, test.expected.Account, path.Account)
+		assert.Equal(t, test.expected.Change, path.Change)
+		assert.Equal(t, test.expected.Index, path.Index)
+		assert.Nil(t, path.Validate())
+		assert.Equal(t, test.formats, path.String())
+	}
+}
+
+func TestParseDerivationPath_Invalid(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected error
+	}{
+		{"", ErrDerivationPathMalformed},
+		{"84'/0'/0'/0/12", ErrDerivationPathMalformed},
+		{"m/84'/0'/0'/0", ErrDerivationPathMalformed},
+		{"m/84'/0'/0'/0/12/1", ErrDerivationPathMalformed},
+		{"m/84'/0'/0'/0/12/", ErrDerivationPathMalformed},
+		{"m/84'//0'/0/12", ErrDerivationPathSegment},
+		{"m/84'/0'/0'/0/x", ErrDerivationPathSegment},
+		{"m/84'/0'/0'/0/-1", ErrDerivationPathSegment},
+		{"m/84'/0'/0'/0/+1", ErrDerivationPathSegment},
+		{"m/84''/0'/0'/0/12", ErrDerivationPathSegment},
+		{"m/84'h/0'/0'/0/12", ErrDerivationPathSegment},
+		{"m/84'/0'/0'/0/2147483648", ErrDerivationPathSegment},
+		{"m/2147483648'/0'/0'/0/1", ErrDerivationPathSegment},
+		{"m/84/0'/0'/0/12", ErrDerivationPathHardening},
+		{"m/84'/0'/0'/0'/12", ErrDerivationPathHardening},
+		{"m/84'/0'/0'/0/12h", ErrDerivationPathHardening},
+	}
+
+	for _, test := range tests {
+		path, err := ParseDerivationPath(test.path)
+		assert.Nil(t, path, test.path)
+		assert.True(t, errors.Is(err, test.expected), test.path)
+	}
+}
+
+func TestDerivationPath_String_MissingBaseCoin(t *testing.T) {
+	assert.Equal(t, "", NewDerivationPath(nil, 0, 1).String())
+}
+
+func TestParseCustomDerivationPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		formats string
+		depth   int
+	}{
+		{"m/45'/0", "m/45'/0", 2},
+		{"m/48h/0h/0h/2h", "m/48'/0'/0'/2'", 4},
+		{"m/0", "m/0", 1},
+		{"m/84'/0'/0'/0/12", "m/84'/0'/0'/0/12", 5},
+		{"m/1/2/3/4/5/6/7", "m/1/2/3/4/5/6/7", 7},
+	}
+
+	for _, test := range tests {
+		path, err := ParseCustomDerivationPath(test.path)
+		assert.Nil(t, err, test.path)
+		assert.True(t, path.IsCustom())
+		assert.Equal(t, test.depth, path.Depth())
+		assert.Equal(t, test.formats, path.String())
+		assert.Equal(t, ErrDerivationPathCustom, path.Validate())
+	}
+
+	standard := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	assert.False(t, standard.IsCustom())
+	assert.Equal(t, 5, standard.Depth())
+
+	for _, invalid := range []string{"", "m", "m/", "45'/0", "m/45'/x", "m/2147483648"} {
+		_, err := ParseCustomDerivationPath(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestHDWallet_ExtendedPublicKeyForPath(t *testing.T) {
+	// BIP32 test vector 1
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	wallet, err := NewHDWalletFromSeed(seed, BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+
+	path, _ := ParseCustomDerivationPath("m/0'")
+	xpub, err := wallet.ExtendedPublicKeyForPath(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw", xpub)
+
+	path, _ = ParseCustomDerivationPath("m/0h/1")
+	xpub, err = wallet.ExtendedPublicKeyForPath(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ", xpub)
+
+	accountKey, err := wallet.AccountExtendedPublicKey()
+	assert.Nil(t, err)
+	watchOnly, err := NewHDWalletFromAccountExtendedPublicKey(accountKey)
+	assert.Nil(t, err)
+	_, err = watchOnly.ExtendedPublicKeyForPath(path)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+	_, err = watchOnly.CompressedPubKeyForPath(path)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
+
+func TestHDWallet_CustomPathKeys(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+
+	// a custom path of the standard shape derives the same keys as the standard path
+	custom, _ := ParseCustomDerivationPath("m/84'/0'/0'/0/3")
+	standard := NewDerivationPath(BaseCoinBip84MainNet, 0, 3)
+	customKey, err := wallet.CompressedPubKeyForPath(custom)
+	assert.Nil(t, err)
+	standardKey, _ := wallet.CompressedPubKeyForPath(standard)
+	assert.Equal(t, standardKey, customKey)
+	assert.Equal(t, standard.bip32Path(), custom.bip32Path())
+
+	// encrypt to, and decrypt with, the key of a multisig path
+	multisig, _ := ParseCustomDerivationPath("m/48'/0'/0'/2'")
+	pubkey, err := wallet.UncompressedPubKeyForPath(multisig)
+	assert.Nil(t, err)
+	sender := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	encrypted, err := sender.EncryptMessage([]byte("cosigner note"), hex.EncodeToString(pubkey))
+	assert.Nil(t, err)
+	decrypted, err := wallet.DecryptWithKeyFromDerivationPath(multisig, encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "cosigner note", string(decrypted))
+
+	// only encryption key paths derive record keys
+	_, err = wallet.DeriveEncryptionKey(multisig, "notes")
+	assert.Equal(t, ErrKeyRoleMismatch, err)
+	first, _ := ParseCustomDerivationPath("m/42'/0'")
+	second, _ := ParseCustomDerivationPath("m/42'/1'")
+	firstKey, err := wallet.DeriveEncryptionKey(first, "notes")
+	assert.Nil(t, err)
+	secondKey, _ := wallet.DeriveEncryptionKey(second, "notes")
+	assert.NotEqual(t, firstKey, secondKey)
+}
@@ -0,0 +1,164 @@
+package cnlib
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+// accountKeyDepth is the depth of an account-level extended key, m/purpose'/coin'/account'.
+const accountKeyDepth = 3
+
+// ErrDescriptorUnsupported is returned by `NewWalletFromDescriptor` for descriptors other than a single account key.
+var ErrDescriptorUnsupported = errors.New("descriptor must be pkh, wpkh, sh(wpkh) or tr of an account extended public key with /0/*, /1/* or /<0;1>/*")
+
+// descriptorWalletScripts maps the script functions of a single-key descriptor, outermost first, to their purpose.
+var descriptorWalletScripts = []struct {
+	functions []string
+	purpose   int
+}{
+	{[]string{"pkh"}, bip44purpose},
+	{[]string{"sh", "wpkh"}, bip49purpose},
+	{[]string{"wpkh"}, bip84purpose},
+	{[]string{"tr"}, bip86purpose},
+}
+
+/// Constructors
+
+// NewWalletFromDescriptor returns a watch-only HDWallet deriving the receive and change addresses of a wallet created
+// elsewhere, from a BIP380 descriptor of its account extended public key, such as
+// "wpkh([73c5da0a/84h/0h/0h]xpub.../0/*)#..." as returned by `AccountDescriptor`. Supports pkh, wpkh, sh(wpkh) and
+// key path tr, with an xpub or tpub, an optional checksum, and an optional key origin, which must be the standard
+// account path for the script and sets the wallet's master fingerprint. Without an origin, the account is that of the
+// key's child number. Wallets of a tpub use the default test network; call `UpdateTestNetwork` on the wallet's
+// BaseCoin to change it.
+func NewWalletFromDescriptor(descriptor string) (*HDWallet, error) {
+	stripped, err := stripDescriptorChecksum(strings.TrimSpace(descriptor))
+	if err != nil {
+		return nil, err
+	}
+	purpose, keyExpression, ok := unwrapDescriptorWalletScript(stripped)
+	if !ok {
+		return nil, ErrDescriptorUnsupported
+	}
+
+	var fingerprint []byte
+	var origin []uint32
+	if strings.HasPrefix(keyExpression, "[") {
+		end := strings.IndexByte(keyExpression, ']')
+		if end < 0 {
+			return nil, errors.New("invalid key origin")
+		}
+		steps := strings.Split(keyExpression[1:end], "/")
+		if fingerprint, err = hex.DecodeString(steps[0]); err != nil || len(fingerprint) != 4 {
+			return nil, errors.New("invalid key origin fingerprint")
+		}
+		for _, step := range steps[1:] {
+			index, err := parseDescriptorPathStep(step, true)
+			if err != nil {
+				return nil, err
+			}
+			origin = append(origin, index)
+		}
+		keyExpression = keyExpression[end+1:]
+	}
+
+	slash := strings.IndexByte(keyExpression, '/')
+	if slash < 0 {
+		return nil, ErrDescriptorUnsupported
+	}
+	switch keyExpression[slash:] {
+	case "/0/*", "/1/*", "/<0;1>/*":
+	default:
+		return nil, ErrDescriptorUnsupported
+	}
+	encodedKey := keyExpression[:slash]
+	key, err := hdkeychain.NewKeyFromString(encodedKey)
+	if err != nil {
+		return nil, errors.New("invalid descriptor key")
+	}
+	if key.IsPrivate() {
+		return nil, errors.New("descriptor must contain only public keys")
+	}
+	if key.Depth() != accountKeyDepth {
+		return nil, ErrDescriptorUnsupported
+	}
+
+	basecoin, err := descriptorWalletBaseCoin(purpose, key, encodedKey, origin)
+	if err != nil {
+		return nil, err
+	}
+	wallet := HDWallet{
+		BaseCoin:          basecoin,
+		accountPublicKey:  key,
+		role:              WalletRoleHot,
+		masterFingerprint: fingerprint,
+		keyCache:          newDerivationCache(),
+	}
+	return &wallet, nil
+}
+
+/// Unexported functions
+
+// unwrapDescriptorWalletScript returns the purpose of a single-key descriptor's script, and its key expression.
+func unwrapDescriptorWalletScript(descriptor string) (int, string, bool) {
+	for _, script := range descriptorWalletScripts {
+		inner, ok := descriptor, true
+		for _, function := range script.functions {
+			if inner, ok = unwrapDescriptorFunction(inner, function); !ok {
+				break
+			}
+		}
+		if ok {
+			return script.purpose, inner, true
+		}
+	}
+	return 0, "", false
+}
+
+// descriptorWalletBaseCoin returns the BaseCoin of an account key of a purpose, on the network of the key's version,
+// with coin and account from the key's origin if given, or its child number if not.
+func descriptorWalletBaseCoin(purpose int, key *hdkeychain.ExtendedKey, encodedKey string, origin []uint32) (*BaseCoin, error) {
+	coin := mainnet
+	if !key.IsForNet(mainnetNetwork.chainParams) {
+		coin = testnet
+		if !key.IsForNet(regtestNetwork.chainParams) {
+			return nil, errors.New("descriptor key must be an xpub or tpub")
+		}
+	}
+
+	if origin == nil {
+		decoded, _, err := base58.CheckDecode(encodedKey)
+		if err != nil {
+			return nil, err
+		}
+		// child number follows the remaining version bytes, depth and parent fingerprint
+		account := binary.BigEndian.Uint32(decoded[8:12]) &^ hdkeychain.HardenedKeyStart
+		return &BaseCoin{Purpose: purpose, Coin: coin, Account: int(account)}, nil
+	}
+
+	if len(origin) != accountKeyDepth || origin[0] != hardened(purpose) {
+		return nil, errors.New("key origin must be the account path of the descriptor's script")
+	}
+	for _, index := range origin {
+		if index < hdkeychain.HardenedKeyStart {
+			return nil, errors.New("key origin must be the account path of the descriptor's script")
+		}
+	}
+	bc := &BaseCoin{
+		Purpose: purpose,
+		Coin:    int(origin[1] - hdkeychain.HardenedKeyStart),
+		Account: int(origin[2] - hdkeychain.HardenedKeyStart),
+	}
+	if _, ok := networkRegistry[bc.Coin]; !ok {
+		return nil, ErrInvalidCoinValue
+	}
+	if !key.IsForNet(bc.defaultNetParams()) {
+		return nil, errors.New("descriptor key is not of the network of its key origin")
+	}
+	return bc, nil
+}
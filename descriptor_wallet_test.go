@@ -0,0 +1,95 @@
+package cnlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWalletFromDescriptor_AccountDescriptorRoundTrip(t *testing.T) {
+	for _, basecoin := range []*BaseCoin{BaseCoinBip49MainNet, BaseCoinBip84MainNet, BaseCoinBip86MainNet, BaseCoinBip84TestNet} {
+		wallet := NewHDWalletFromWords(w, basecoin)
+		for _, change := range []int{0, 1} {
+			descriptor, err := wallet.AccountDescriptor(change)
+			assert.Nil(t, err)
+
+			watchOnly, err := NewWalletFromDescriptor(descriptor)
+			assert.Nil(t, err, descriptor)
+			assert.True(t, watchOnly.IsWatchOnly())
+			assert.Equal(t, basecoin.Purpose, watchOnly.BaseCoin.Purpose)
+			assert.Equal(t, basecoin.Coin, watchOnly.BaseCoin.Coin)
+			assert.Equal(t, basecoin.Account, watchOnly.BaseCoin.Account)
+
+			fingerprint, err := watchOnly.Fingerprint()
+			assert.Nil(t, err)
+			assert.Equal(t, "73c5da0a", fingerprint)
+
+			for _, index := range []int{0, 7} {
+				expected, _ := wallet.ReceiveAddressForIndex(index)
+				actual, err := watchOnly.ReceiveAddressForIndex(index)
+				assert.Nil(t, err)
+				assert.Equal(t, expected.Address, actual.Address)
+				expected, _ = wallet.ChangeAddressForIndex(index)
+				actual, err = watchOnly.ChangeAddressForIndex(index)
+				assert.Nil(t, err)
+				assert.Equal(t, expected.Address, actual.Address)
+			}
+		}
+	}
+}
+
+func TestNewWalletFromDescriptor_Scripts(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		address    string
+	}{
+		{"pkh([73c5da0a/44'/0'/0']xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/0/*)", "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"},
+		{"pkh(xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/<0;1>/*)", "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"},
+	}
+	for _, test := range tests {
+		wallet, err := NewWalletFromDescriptor(test.descriptor)
+		assert.Nil(t, err, test.descriptor)
+		assert.Equal(t, 44, wallet.BaseCoin.Purpose)
+		address, err := wallet.ReceiveAddressForIndex(0)
+		assert.Nil(t, err)
+		assert.Equal(t, test.address, address.Address)
+	}
+
+	// without an origin the fingerprint is unknown
+	wallet, _ := NewWalletFromDescriptor(tests[1].descriptor)
+	_, err := wallet.Fingerprint()
+	assert.Equal(t, ErrMasterFingerprintUnknown, err)
+}
+
+func TestNewWalletFromDescriptor_Invalid(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	descriptor, _ := wallet.AccountDescriptor(0)
+	stripped := descriptor[:strings.IndexByte(descriptor, '#')]
+	xpub := stripped[strings.IndexByte(stripped, ']')+1 : strings.LastIndex(stripped, "/0/*")]
+
+	tests := []struct {
+		descriptor string
+		expected   error
+	}{
+		{stripped[:len(stripped)-3] + "1/*)#" + descriptor[len(descriptor)-8:], nil}, // checksum no longer matches
+		{"wsh(" + stripped + ")", ErrDescriptorUnsupported},
+		{"wpkh(" + xpub + ")", ErrDescriptorUnsupported},
+		{"wpkh(" + xpub + "/0/5)", ErrDescriptorUnsupported},
+		{"wpkh(" + xpub + "/0/*/1)", ErrDescriptorUnsupported},
+		{"wpkh(" + xpub + "/0/*')", ErrDescriptorUnsupported},
+		{"wpkh([73c5da0a/49h/0h/0h]" + xpub + "/0/*)", nil},
+		{"wpkh([73c5da0a/84h/1h/0h]" + xpub + "/0/*)", nil},
+		{"wpkh([73c5/84h/0h/0h]" + xpub + "/0/*)", nil},
+		{"wpkh(xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8/0/*)", ErrDescriptorUnsupported},
+		{"combo(" + xpub + "/0/*)", ErrDescriptorUnsupported},
+	}
+	for _, test := range tests {
+		_, err := NewWalletFromDescriptor(test.descriptor)
+		if test.expected != nil {
+			assert.Equal(t, test.expected, err, test.descriptor)
+		} else {
+			assert.NotNil(t, err, test.descriptor)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Command fixturegen writes deterministic test fixtures, generated by cnlib from a fixed mnemonic, as JSON: wallets
+// and their addresses, utxo sets, and signed transactions for every supported script type on mainnet and regtest.
+// The iOS, Android and backend test suites share its output as test vectors.
+//
+//	go run ./devtools/fixturegen > fixtures.json
+//
+// Output is identical across runs and platforms. Transactions spending P2TR inputs are signed with all-zero BIP340
+// auxiliary randomness, so their witnesses are stable too.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"git.coinninja.net/engineering/cnlib"
+)
+
+const (
+	defaultMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	addressCount    = 5
+	feeRate         = 5
+	blockHeight     = 700000
+	paymentAmount   = 120000
+	paymentIndex    = 10 // receive index of the wallet's own address paid by the fixture transaction
+)
+
+// utxoAmounts are the amounts of each wallet's utxos, received at consecutive receive indexes from 0.
+var utxoAmounts = []int64{100000, 250000, 50000}
+
+type fixtures struct {
+	Mnemonic string           `json:"mnemonic"`
+	Wallets  []*walletFixture `json:"wallets"`
+}
+
+type walletFixture struct {
+	Purpose          int                 `json:"purpose"`
+	Coin             int                 `json:"coin"`
+	Account          int                 `json:"account"`
+	AccountPublicKey string              `json:"account_public_key"`
+	ReceiveAddresses []*addressFixture   `json:"receive_addresses"`
+	ChangeAddresses  []*addressFixture   `json:"change_addresses"`
+	UTXOs            []*utxoFixture      `json:"utxos"`
+	Transaction      *transactionFixture `json:"transaction"`
+}
+
+type addressFixture struct {
+	Path    string `json:"path"`
+	Address string `json:"address"`
+	Script  string `json:"script"`
+}
+
+type utxoFixture struct {
+	Txid   string `json:"txid"`
+	Index  int    `json:"index"`
+	Amount int64  `json:"amount"`
+	Path   string `json:"path"`
+	Script string `json:"script"`
+}
+
+type transactionFixture struct {
+	PaymentAddress        string `json:"payment_address"`
+	Amount                int    `json:"amount"`
+	FeeRate               int    `json:"fee_rate"`
+	Fee                   int    `json:"fee"`
+	ChangeAddress         string `json:"change_address,omitempty"`
+	ChangeAmount          int    `json:"change_amount"`
+	Locktime              int    `json:"locktime"`
+	Txid                  string `json:"txid"`
+	EncodedTx             string `json:"encoded_tx"`
+	DeterministicEncoding bool   `json:"deterministic_encoding"` // always true, kept for existing consumers
+}
+
+func main() {
+	mnemonic := flag.String("mnemonic", defaultMnemonic, "BIP39 mnemonic of the fixture wallets")
+	flag.Parse()
+
+	out, err := generate(*mnemonic)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fixturegen:", err)
+		os.Exit(1)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(out); err != nil {
+		fmt.Fprintln(os.Stderr, "fixturegen:", err)
+		os.Exit(1)
+	}
+}
+
+func generate(mnemonic string) (*fixtures, error) {
+	if err := cnlib.ValidateWordStringForLanguage(mnemonic, cnlib.WordListLanguageEnglish); err != nil {
+		return nil, err
+	}
+	basecoins := []*cnlib.BaseCoin{
+		cnlib.BaseCoinBip49MainNet,
+		cnlib.BaseCoinBip84MainNet,
+		cnlib.BaseCoinBip86MainNet,
+		cnlib.BaseCoinBip49TestNet,
+		cnlib.BaseCoinBip84TestNet,
+		cnlib.BaseCoinBip86TestNet,
+	}
+	out := &fixtures{Mnemonic: mnemonic}
+	for _, basecoin := range basecoins {
+		wallet, err := walletFixtureFor(mnemonic, basecoin)
+		if err != nil {
+			return nil, fmt.Errorf("purpose %d coin %d: %v", basecoin.Purpose, basecoin.Coin, err)
+		}
+		out.Wallets = append(out.Wallets, wallet)
+	}
+	return out, nil
+}
+
+func walletFixtureFor(mnemonic string, basecoin *cnlib.BaseCoin) (*walletFixture, error) {
+	wallet := cnlib.NewHDWalletFromWords(mnemonic, basecoin)
+	if wallet == nil {
+		return nil, fmt.Errorf("unable to create wallet")
+	}
+	wallet.SetDeterministicSchnorrSignatures(true)
+	accountKey, err := wallet.AccountExtendedPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	fixture := &walletFixture{
+		Purpose:          basecoin.Purpose,
+		Coin:             basecoin.Coin,
+		Account:          basecoin.Account,
+		AccountPublicKey: accountKey,
+	}
+
+	for i := 0; i < addressCount; i++ {
+		receive, err := wallet.ReceiveAddressForIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		change, err := wallet.ChangeAddressForIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		for _, meta := range []*cnlib.MetaAddress{receive, change} {
+			address, err := addressFixtureFor(meta)
+			if err != nil {
+				return nil, err
+			}
+			if meta.IsReceiveAddress() {
+				fixture.ReceiveAddresses = append(fixture.ReceiveAddresses, address)
+			} else {
+				fixture.ChangeAddresses = append(fixture.ChangeAddresses, address)
+			}
+		}
+	}
+
+	payment, err := wallet.ReceiveAddressForIndex(paymentIndex)
+	if err != nil {
+		return nil, err
+	}
+	changePath := cnlib.NewDerivationPath(basecoin, 1, 0)
+	data := cnlib.NewTransactionDataStandard(payment.Address, basecoin, paymentAmount, feeRate, changePath, blockHeight, cnlib.NewRBFOption(cnlib.AllowedToBeRBF))
+
+	for i, amount := range utxoAmounts {
+		path := cnlib.NewDerivationPath(basecoin, 0, i)
+		meta, err := wallet.ReceiveAddressForIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		script, err := cnlib.AddressScript(meta.Address)
+		if err != nil {
+			return nil, err
+		}
+		txid := fixtureTxid(basecoin, i)
+		utxo := cnlib.NewUTXO(txid, i, amount, path, nil, true)
+		utxo.SetPrevout(script, amount)
+		data.AddUTXO(utxo)
+		fixture.UTXOs = append(fixture.UTXOs, &utxoFixture{
+			Txid:   txid,
+			Index:  i,
+			Amount: amount,
+			Path:   pathString(path),
+			Script: hex.EncodeToString(script),
+		})
+	}
+
+	if err := data.Generate(); err != nil {
+		return nil, err
+	}
+	meta, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	if err != nil {
+		return nil, err
+	}
+	tx := &transactionFixture{
+		PaymentAddress:        payment.Address,
+		Amount:                data.TransactionData.Amount,
+		FeeRate:               feeRate,
+		Fee:                   data.TransactionData.FeeAmount,
+		ChangeAmount:          data.TransactionData.ChangeAmount,
+		Locktime:              data.TransactionData.Locktime,
+		Txid:                  meta.Txid,
+		EncodedTx:             meta.EncodedTx,
+		DeterministicEncoding: true,
+	}
+	if meta.TransactionChangeMetadata != nil {
+		tx.ChangeAddress = meta.TransactionChangeMetadata.Address
+	}
+	fixture.Transaction = tx
+	return fixture, nil
+}
+
+func addressFixtureFor(meta *cnlib.MetaAddress) (*addressFixture, error) {
+	script, err := cnlib.AddressScript(meta.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &addressFixture{Path: pathString(meta.DerivationPath), Address: meta.Address, Script: hex.EncodeToString(script)}, nil
+}
+
+// fixtureTxid returns a stable, made-up txid for a wallet's utxo.
+func fixtureTxid(basecoin *cnlib.BaseCoin, index int) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("cnlib fixture utxo %d/%d/%d/%d", basecoin.Purpose, basecoin.Coin, basecoin.Account, index)))
+	return hex.EncodeToString(hash[:])
+}
+
+func pathString(path *cnlib.DerivationPath) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'/%d/%d", path.Purpose, path.Coin, path.Account, path.Change, path.Index)
+}
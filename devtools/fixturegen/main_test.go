@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate_IsDeterministic(t *testing.T) {
+	first, err := generate(defaultMnemonic)
+	assert.Nil(t, err)
+	second, err := generate(defaultMnemonic)
+	assert.Nil(t, err)
+
+	firstJSON, err := json.Marshal(first)
+	assert.Nil(t, err)
+	secondJSON, err := json.Marshal(second)
+	assert.Nil(t, err)
+	assert.Equal(t, string(firstJSON), string(secondJSON))
+
+	taproot := 0
+	for _, wallet := range first.Wallets {
+		assert.True(t, wallet.Transaction.DeterministicEncoding)
+		if wallet.Purpose == 86 {
+			taproot++
+		}
+	}
+	assert.Equal(t, 2, taproot)
+}
@@ -0,0 +1,75 @@
+package cnlib
+
+import "errors"
+
+// defaultDustAttackThreshold is the amount below which an incoming utxo is treated as dust by `NewDefaultDustFilter`,
+// the same as the library's dust threshold for outputs it creates.
+const defaultDustAttackThreshold = dustThreshold
+
+/// Type Definitions
+
+// DustFilter detects dust attacks, tiny amounts sent to a wallet's addresses so that, once spent together with the
+// wallet's other utxos, they link its addresses on chain. Only the amount and origin of a utxo are considered, not
+// its script: a utxo is dust if it was received, rather than change or a swept key, and worth less than Threshold.
+type DustFilter struct {
+	Threshold int64 // satoshis
+}
+
+/// Constructors
+
+// NewDustFilter returns a pointer to a DustFilter treating incoming utxos below a threshold, in satoshis, as dust.
+// Returns error if the threshold is not positive.
+func NewDustFilter(threshold int64) (*DustFilter, error) {
+	if threshold <= 0 {
+		return nil, errors.New("dust threshold must be positive")
+	}
+	return &DustFilter{Threshold: threshold}, nil
+}
+
+// NewDefaultDustFilter returns a pointer to a DustFilter with the default threshold of 1,000 satoshis.
+func NewDefaultDustFilter() *DustFilter {
+	return &DustFilter{Threshold: defaultDustAttackThreshold}
+}
+
+/// Receiver functions
+
+// IsDust returns true if a utxo was received at a receive address, or one outside the wallet's derivation paths, and
+// is worth less than the threshold. Change and imported key utxos come from the user's own transactions and sweeps.
+func (f *DustFilter) IsDust(utxo *UTXO) bool {
+	if utxo == nil || utxo.ImportedPrivateKey != nil || utxo.Amount >= f.Threshold {
+		return false
+	}
+	return utxo.Path == nil || utxo.Path.IsCustom() || utxo.Path.Change == 0
+}
+
+// DustUTXOCount returns the number of utxos the filter treats as dust, frozen or not, or 0 if the filter is nil.
+func (m *UTXOManager) DustUTXOCount(filter *DustFilter) int {
+	if filter == nil {
+		return 0
+	}
+	count := 0
+	for _, utxo := range m.utxos {
+		if filter.IsDust(utxo) {
+			count++
+		}
+	}
+	return count
+}
+
+// FreezeDust freezes each utxo the filter treats as dust, except those the user has unfrozen, and returns the number
+// newly frozen. Apps call this after adding incoming utxos.
+func (m *UTXOManager) FreezeDust(filter *DustFilter) (int, error) {
+	if filter == nil {
+		return 0, errors.New("dust filter cannot be nil")
+	}
+	frozen := 0
+	for _, utxo := range m.utxos {
+		key := utxoKey(utxo.Txid, utxo.Index)
+		if !filter.IsDust(utxo) || m.frozen[key] || m.unfrozen[key] {
+			continue
+		}
+		m.frozen[key] = true
+		frozen++
+	}
+	return frozen, nil
+}
@@ -0,0 +1,56 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDustFilter_IsDust(t *testing.T) {
+	filter := NewDefaultDustFilter()
+	receive := NewDerivationPath(BaseCoinBip84MainNet, 0, 3)
+	change := NewDerivationPath(BaseCoinBip84MainNet, 1, 3)
+	custom, _ := ParseCustomDerivationPath("m/48'/0'/0'/2'/1/0")
+
+	assert.True(t, filter.IsDust(NewUTXO(utxoManagerTestTxid, 0, 546, receive, nil, true)))
+	assert.True(t, filter.IsDust(NewUTXO(utxoManagerTestTxid, 0, 999, nil, nil, false)))
+	assert.True(t, filter.IsDust(NewUTXO(utxoManagerTestTxid, 0, 999, custom, nil, false)))
+	assert.False(t, filter.IsDust(NewUTXO(utxoManagerTestTxid, 0, 1000, receive, nil, true)))
+	assert.False(t, filter.IsDust(NewUTXO(utxoManagerTestTxid, 0, 546, change, nil, true)))
+	assert.False(t, filter.IsDust(NewUTXO(utxoManagerTestTxid, 0, 546, nil, &ImportedPrivateKey{}, true)))
+	assert.False(t, filter.IsDust(nil))
+
+	custom5000, err := NewDustFilter(5000)
+	assert.Nil(t, err)
+	assert.True(t, custom5000.IsDust(NewUTXO(utxoManagerTestTxid, 0, 4999, receive, nil, true)))
+	_, err = NewDustFilter(0)
+	assert.NotNil(t, err)
+}
+
+func TestUTXOManager_FreezeDust(t *testing.T) {
+	manager := NewUTXOManager()
+	receive := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	change := NewDerivationPath(BaseCoinBip84MainNet, 1, 0)
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 0, 100000, receive, nil, true)))
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 1, 546, receive, nil, true)))
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 2, 600, change, nil, true)))
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 3, 700, receive, nil, false)))
+
+	filter := NewDefaultDustFilter()
+	assert.Equal(t, 2, manager.DustUTXOCount(filter))
+	frozen, err := manager.FreezeDust(filter)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, frozen)
+	assert.True(t, manager.IsFrozen(utxoManagerTestTxid, 1))
+	assert.True(t, manager.IsFrozen(utxoManagerTestTxid, 3))
+	assert.Equal(t, 100600, manager.SpendableAmount())
+
+	// the user's choice to spend dust sticks
+	assert.Nil(t, manager.Unfreeze(utxoManagerTestTxid, 1))
+	frozen, _ = manager.FreezeDust(filter)
+	assert.Equal(t, 0, frozen)
+	assert.False(t, manager.IsFrozen(utxoManagerTestTxid, 1))
+
+	_, err = manager.FreezeDust(nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,161 @@
+package cnlib
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// Following constants identify the cipher of an encryption envelope's payload.
+const (
+	// EnvelopeCipherCryptor is the ECDH, AES-256-CBC and HMAC-SHA256 payload returned by `EncryptMessage` and
+	// `EncryptWithEphemeralKey`, of payload version 3.
+	EnvelopeCipherCryptor int = 1
+)
+
+// Envelopes of version 2 authenticate their header with the payload's hmac, so it cannot be altered or stripped
+// undetected. Version 1 envelopes, whose header is not authenticated, are still made by `SealEncryptionEnvelope` for
+// payloads encrypted by the caller, and still decrypted.
+const (
+	encryptionEnvelopeVersionUnauthenticated = byte(1)
+	encryptionEnvelopeVersion                = byte(2)
+	encryptionEnvelopeHeaderSize             = 5
+)
+
+// encryptionEnvelopeMagic begins every encryption envelope. Unwrapped cryptor payloads begin with their version, 3,
+// so the two cannot be confused.
+var encryptionEnvelopeMagic = []byte("CNE")
+
+// Following errors are returned for encryption envelopes this version of the library cannot decrypt.
+var (
+	ErrEncryptionEnvelopeVersion = errors.New("unsupported encryption envelope version")
+	ErrEncryptionEnvelopeCipher  = errors.New("unsupported encryption envelope cipher")
+)
+
+/// Receiver functions
+
+// EncryptMessageEnvelope encrypts a payload as `EncryptMessage` does and wraps it in an encryption envelope whose
+// header is authenticated along with the payload, for `DecryptAuto`.
+func (wallet *HDWallet) EncryptMessageEnvelope(body []byte, recipientUncompressedPubkey string) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := parseHexPubKey(recipientUncompressedPubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey, err := wallet.signingPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	iv, err := randBytes(16)
+	if err != nil {
+		return nil, err
+	}
+
+	header := encryptionEnvelopeHeader(encryptionEnvelopeVersion, EnvelopeCipherCryptor)
+	payload, err := encryptWithAssociatedData(body, signingKey, publicKey, iv, header)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, payload...), nil
+}
+
+// DecryptAuto decrypts an encryption envelope, dispatching on its version and cipher, or an unwrapped cryptor payload
+// as `DecryptMessage` does, using the signing key (m/42). Returns ErrEncryptionEnvelopeVersion or
+// ErrEncryptionEnvelopeCipher if the envelope was made by a newer version of the library.
+func (wallet *HDWallet) DecryptAuto(body []byte) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	signingKey, err := wallet.signingPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptAuto(body, signingKey)
+}
+
+// DecryptAutoWithKeyFromDerivationPath decrypts as `DecryptAuto` does, with the key derived from given derivation
+// path, as `DecryptWithKeyFromDerivationPath` does.
+func (wallet *HDWallet) DecryptAutoWithKeyFromDerivationPath(path *DerivationPath, body []byte) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	if path == nil {
+		return nil, errors.New("derivation path cannot be nil")
+	}
+	pk, err := wallet.keyFactory().indexPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ecpk, err := pk.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptAuto(body, ecpk)
+}
+
+/// Exported functions
+
+// SealEncryptionEnvelope wraps an encrypted payload of an `EnvelopeCipher` in an encryption envelope: the magic "CNE",
+// the envelope version and the cipher id, followed by the payload. As the payload was encrypted without it, the header
+// is not authenticated, so the envelope is of version 1; prefer `EncryptMessageEnvelope`. Returns
+// ErrEncryptionEnvelopeCipher if the cipher is unknown, or error if the payload is not one of the cipher.
+func SealEncryptionEnvelope(payload []byte, cipher int) ([]byte, error) {
+	switch cipher {
+	case EnvelopeCipherCryptor:
+		if len(payload) < minPayloadSize || payload[0] != encryptionPayloadVersion {
+			return nil, errors.New("payload is not a cryptor payload")
+		}
+	default:
+		return nil, ErrEncryptionEnvelopeCipher
+	}
+
+	return append(encryptionEnvelopeHeader(encryptionEnvelopeVersionUnauthenticated, cipher), payload...), nil
+}
+
+/// Unexported functions
+
+// decryptAuto decrypts an envelope's payload with the decryption of its cipher, authenticating the header of version 2
+// envelopes, or an unwrapped cryptor payload.
+func decryptAuto(data []byte, privateKey *btcec.PrivateKey) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptionEnvelopeMagic) {
+		return decrypt(data, privateKey)
+	}
+	if len(data) < encryptionEnvelopeHeaderSize {
+		return nil, errors.New("insufficient data")
+	}
+
+	header := data[:encryptionEnvelopeHeaderSize]
+	switch header[len(encryptionEnvelopeMagic)] {
+	case encryptionEnvelopeVersion:
+	case encryptionEnvelopeVersionUnauthenticated:
+		header = nil
+	default:
+		return nil, ErrEncryptionEnvelopeVersion
+	}
+
+	payload := data[encryptionEnvelopeHeaderSize:]
+	switch int(data[len(encryptionEnvelopeMagic)+1]) {
+	case EnvelopeCipherCryptor:
+		return decryptWithAssociatedData(payload, privateKey, header)
+	default:
+		return nil, ErrEncryptionEnvelopeCipher
+	}
+}
+
+// encryptionEnvelopeHeader returns the header of an encryption envelope of a version and cipher.
+func encryptionEnvelopeHeader(version byte, cipher int) []byte {
+	header := make([]byte, 0, encryptionEnvelopeHeaderSize)
+	header = append(header, encryptionEnvelopeMagic...)
+	return append(header, version, byte(cipher))
+}
@@ -0,0 +1,84 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptMessageEnvelope_DecryptAuto(t *testing.T) {
+	aliceWallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	bobWallet := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	bobCPK, _ := bobWallet.CoinNinjaVerificationKeyHexString()
+
+	envelope, err := aliceWallet.EncryptMessageEnvelope([]byte("hey dude"), bobCPK)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{'C', 'N', 'E', 2, byte(EnvelopeCipherCryptor), encryptionPayloadVersion}, envelope[:6])
+
+	dec, err := bobWallet.DecryptAuto(envelope)
+	assert.Nil(t, err)
+	assert.Equal(t, "hey dude", string(dec))
+
+	// unwrapped payloads still decrypt
+	payload, _ := aliceWallet.EncryptMessage([]byte("hey dude"), bobCPK)
+	dec, err = bobWallet.DecryptAuto(payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "hey dude", string(dec))
+
+	// as do ephemeral key payloads sealed by the caller, with the key of a path
+	bobPath := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	bobPubkey, _ := bobWallet.CompressedPubKeyForPath(bobPath)
+	result, err := aliceWallet.EncryptWithEphemeralKey(bytes.Repeat([]byte{1}, 16), []byte("hey dude"), hex.EncodeToString(bobPubkey))
+	assert.Nil(t, err)
+	envelope, err = SealEncryptionEnvelope(result.Payload, EnvelopeCipherCryptor)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{'C', 'N', 'E', 1, byte(EnvelopeCipherCryptor)}, envelope[:5])
+	dec, err = bobWallet.DecryptAutoWithKeyFromDerivationPath(bobPath, envelope)
+	assert.Nil(t, err)
+	assert.Equal(t, "hey dude", string(dec))
+}
+
+func TestDecryptAuto_UnsupportedEnvelope(t *testing.T) {
+	aliceWallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	bobWallet := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	bobCPK, _ := bobWallet.CoinNinjaVerificationKeyHexString()
+	envelope, _ := aliceWallet.EncryptMessageEnvelope([]byte("hey dude"), bobCPK)
+
+	newerVersion := append([]byte{}, envelope...)
+	newerVersion[3] = 3
+	_, err := bobWallet.DecryptAuto(newerVersion)
+	assert.Equal(t, ErrEncryptionEnvelopeVersion, err)
+
+	newerCipher := append([]byte{}, envelope...)
+	newerCipher[4] = 2
+	_, err = bobWallet.DecryptAuto(newerCipher)
+	assert.Equal(t, ErrEncryptionEnvelopeCipher, err)
+
+	// the header is authenticated, so it can be neither downgraded nor stripped
+	downgraded := append([]byte{}, envelope...)
+	downgraded[3] = 1
+	_, err = bobWallet.DecryptAuto(downgraded)
+	assert.NotNil(t, err)
+	_, err = bobWallet.DecryptAuto(envelope[5:])
+	assert.NotNil(t, err)
+	_, err = bobWallet.DecryptMessage(envelope[5:])
+	assert.NotNil(t, err)
+
+	_, err = bobWallet.DecryptAuto(envelope[:4])
+	assert.NotNil(t, err)
+	_, err = bobWallet.DecryptAuto(envelope[:len(envelope)-70])
+	assert.NotNil(t, err)
+	_, err = bobWallet.DecryptAutoWithKeyFromDerivationPath(nil, envelope)
+	assert.NotNil(t, err)
+
+	_, err = SealEncryptionEnvelope(envelope[5:], 2)
+	assert.Equal(t, ErrEncryptionEnvelopeCipher, err)
+	_, err = SealEncryptionEnvelope(envelope, EnvelopeCipherCryptor)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, bobWallet.SetRole(WalletRoleHot))
+	_, err = bobWallet.DecryptAuto(envelope)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
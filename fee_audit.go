@@ -0,0 +1,105 @@
+package cnlib
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcutil"
+)
+
+// feeAuditOverpayPercent is how far above the quoted rate a fee may be before it is flagged, allowing for estimated
+// sizes which assume the largest signatures.
+const feeAuditOverpayPercent = 10
+
+// Following errors are returned when a transaction cannot be audited.
+var (
+	ErrFeeAuditUnsigned       = errors.New("transaction must be signed to audit its fee")
+	ErrFeeAuditPrevoutMissing = errors.New("previous outputs of every input are required to audit the fee")
+)
+
+/// Type Definitions
+
+// FeeAudit compares the fee a signed transaction pays with the fee quoted when it was built.
+type FeeAudit struct {
+	Txid            string
+	Weight          int
+	Vsize           int
+	FeeAmount       int // sum of previous outputs minus sum of outputs
+	FeeRate         int // FeeAmount divided by Vsize, rounded down
+	QuotedFeeAmount int
+	QuotedFeeRate   int // 0 for a flat fee
+	FeeAmountDelta  int // FeeAmount minus QuotedFeeAmount
+
+	IsFeeAmountMismatch bool // FeeAmount differs from QuotedFeeAmount
+	IsBelowQuotedRate   bool // FeeAmount is less than QuotedFeeRate times Vsize, so the size was underestimated
+	IsAboveQuotedRate   bool // FeeAmount is more than 10% above QuotedFeeRate times Vsize
+	IsAbsurdFee         bool // negative, or above the maximum fee rate of `ValidateFeeRate`
+}
+
+/// Receiver functions
+
+// HasDiscrepancy returns true if any discrepancy was flagged.
+func (a *FeeAudit) HasDiscrepancy() bool {
+	return a.IsFeeAmountMismatch || a.IsBelowQuotedRate || a.IsAboveQuotedRate || a.IsAbsurdFee
+}
+
+/// Exported functions
+
+// AuditTransactionFee recomputes the fee and fee rate of a signed, hex-encoded transaction from the previous outputs
+// of every input, and compares them with the fee amount and rate quoted at build time, such as a TransactionData's
+// FeeAmount and fee rate. Pass a quoted rate of 0 for flat fee transactions to skip the rate comparisons.
+func AuditTransactionFee(encodedTx string, prevouts *PrevoutSet, quotedFeeAmount int, quotedFeeRate int) (*FeeAudit, error) {
+	if prevouts == nil {
+		return nil, ErrFeeAuditPrevoutMissing
+	}
+	if quotedFeeAmount < 0 || quotedFeeRate < 0 {
+		return nil, errors.New("quoted fee amount and rate cannot be negative")
+	}
+	tx, err := decodeMsgTx(encodedTx)
+	if err != nil {
+		return nil, err
+	}
+	if len(tx.TxIn) == 0 {
+		return nil, errors.New("transaction has no inputs")
+	}
+
+	totalIn := 0
+	for _, txIn := range tx.TxIn {
+		if len(txIn.SignatureScript) == 0 && len(txIn.Witness) == 0 {
+			return nil, ErrFeeAuditUnsigned
+		}
+		info, ok := prevouts.prevouts[txIn.PreviousOutPoint]
+		if !ok {
+			return nil, ErrFeeAuditPrevoutMissing
+		}
+		totalIn += info.Amount
+	}
+	totalOut := 0
+	for _, txOut := range tx.TxOut {
+		totalOut += int(txOut.Value)
+	}
+
+	weight := int(blockchain.GetTransactionWeight(btcutil.NewTx(tx)))
+	vsize := (weight + blockchain.WitnessScaleFactor - 1) / blockchain.WitnessScaleFactor
+	fee := totalIn - totalOut
+
+	audit := FeeAudit{
+		Txid:            tx.TxHash().String(),
+		Weight:          weight,
+		Vsize:           vsize,
+		FeeAmount:       fee,
+		FeeRate:         fee / vsize,
+		QuotedFeeAmount: quotedFeeAmount,
+		QuotedFeeRate:   quotedFeeRate,
+		FeeAmountDelta:  fee - quotedFeeAmount,
+	}
+	audit.IsFeeAmountMismatch = audit.FeeAmountDelta != 0
+	if quotedFeeRate > 0 {
+		expected := quotedFeeRate * vsize
+		audit.IsBelowQuotedRate = fee < expected
+		audit.IsAboveQuotedRate = fee*100 > expected*(100+feeAuditOverpayPercent)
+	}
+	audit.IsAbsurdFee = fee < 0 || int64(fee)*vbytesPerKVByte > int64(vsize)*maxFeeRateSatPerKVByte
+
+	return &audit, nil
+}
@@ -0,0 +1,92 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const feeAuditTestTxid = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+
+func feeAuditTestTransaction(t *testing.T) (*TransactionMetadata, *TransactionData, string) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	utxoAddress, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	script, err := AddressScript(utxoAddress.Address)
+	assert.Nil(t, err)
+
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 0)
+	data := NewTransactionDataStandard("3BgxxADLtnoKu9oytQiiVzYUqvo8weCVy9", BaseCoinBip84MainNet, 50000, 10, changePath, 600000, NewRBFOption(AllowedToBeRBF))
+	utxo := NewUTXO(feeAuditTestTxid, 0, 100000, utxoAddress.DerivationPath, nil, true)
+	utxo.SetPrevout(script, 100000)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	meta, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	return meta, data.TransactionData, utxoAddress.Address
+}
+
+func TestAuditTransactionFee_MatchesQuote(t *testing.T) {
+	meta, data, address := feeAuditTestTransaction(t)
+	prevouts := NewPrevoutSet()
+	assert.Nil(t, prevouts.AddPrevout(NewPreviousOutputInfo(address, feeAuditTestTxid, 0, 100000)))
+
+	audit, err := AuditTransactionFee(meta.EncodedTx, prevouts, data.FeeAmount, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, meta.Txid, audit.Txid)
+	assert.Equal(t, data.FeeAmount, audit.FeeAmount)
+	assert.Equal(t, 0, audit.FeeAmountDelta)
+	assert.True(t, audit.Vsize > 0 && audit.Vsize*10 <= audit.FeeAmount)
+	assert.True(t, audit.FeeRate >= 10)
+	assert.False(t, audit.HasDiscrepancy())
+}
+
+func TestAuditTransactionFee_Discrepancies(t *testing.T) {
+	meta, data, address := feeAuditTestTransaction(t)
+	prevouts := NewPrevoutSet()
+	assert.Nil(t, prevouts.AddPrevout(NewPreviousOutputInfo(address, feeAuditTestTxid, 0, 100000)))
+
+	audit, err := AuditTransactionFee(meta.EncodedTx, prevouts, data.FeeAmount-100, 10)
+	assert.Nil(t, err)
+	assert.True(t, audit.IsFeeAmountMismatch)
+	assert.Equal(t, 100, audit.FeeAmountDelta)
+	assert.True(t, audit.HasDiscrepancy())
+
+	audit, _ = AuditTransactionFee(meta.EncodedTx, prevouts, data.FeeAmount, 20)
+	assert.True(t, audit.IsBelowQuotedRate)
+	assert.False(t, audit.IsAboveQuotedRate)
+
+	audit, _ = AuditTransactionFee(meta.EncodedTx, prevouts, data.FeeAmount, 5)
+	assert.False(t, audit.IsBelowQuotedRate)
+	assert.True(t, audit.IsAboveQuotedRate)
+
+	// flat fee quotes skip rate comparisons
+	audit, _ = AuditTransactionFee(meta.EncodedTx, prevouts, data.FeeAmount, 0)
+	assert.False(t, audit.HasDiscrepancy())
+
+	// a previous output amount far above what was built against
+	inflated := NewPrevoutSet()
+	assert.Nil(t, inflated.AddPrevout(NewPreviousOutputInfo(address, feeAuditTestTxid, 0, 100000000)))
+	audit, _ = AuditTransactionFee(meta.EncodedTx, inflated, data.FeeAmount, 10)
+	assert.True(t, audit.IsAbsurdFee)
+	assert.True(t, audit.IsAboveQuotedRate)
+}
+
+func TestAuditTransactionFee_Invalid(t *testing.T) {
+	meta, data, _ := feeAuditTestTransaction(t)
+	_, err := AuditTransactionFee(meta.EncodedTx, NewPrevoutSet(), data.FeeAmount, 10)
+	assert.Equal(t, ErrFeeAuditPrevoutMissing, err)
+	_, err = AuditTransactionFee(meta.EncodedTx, nil, data.FeeAmount, 10)
+	assert.Equal(t, ErrFeeAuditPrevoutMissing, err)
+	_, err = AuditTransactionFee(meta.EncodedTx, NewPrevoutSet(), -1, 10)
+	assert.NotNil(t, err)
+
+	rawTx, _ := NewRawTransaction(2, 0)
+	assert.Nil(t, rawTx.AddInput(NewRawTransactionInput(feeAuditTestTxid, 0, 0xfffffffd)))
+	outputScript, _ := AddressScript("3BgxxADLtnoKu9oytQiiVzYUqvo8weCVy9")
+	assert.Nil(t, rawTx.AddOutput(NewRawTransactionOutput(99000, outputScript)))
+	unsigned, _ := rawTx.Encode()
+	_, err = AuditTransactionFee(unsigned, NewPrevoutSet(), 1000, 10)
+	assert.Equal(t, ErrFeeAuditUnsigned, err)
+}
@@ -0,0 +1,180 @@
+package cnlib
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/btcsuite/btcd/blockchain"
+)
+
+const (
+	maxBlockVsize = blockchain.MaxBlockWeight / blockchain.WitnessScaleFactor
+
+	// typicalTxVsize is the size assumed for the transaction being estimated, and for each transaction arriving in
+	// the mempool, as the histogram holds no transaction counts.
+	typicalTxVsize = 250
+
+	// maxConfirmationEstimateBlocks bounds estimates to about a week of blocks.
+	maxConfirmationEstimateBlocks = 1008
+)
+
+/// Type Definitions
+
+// FeeHistogram is a snapshot of the mempool, as the vbytes of transactions waiting at each fee rate, used to estimate
+// how soon a transaction at a chosen fee rate would confirm. Add each bucket of the histogram reported by the app's
+// backend using `AddBucket`, as gomobile does not support custom arrays/slices.
+type FeeHistogram struct {
+	buckets              map[int]int // vbytes waiting, keyed by fee rate
+	inflowVbytesPerBlock int
+}
+
+// ConfirmationEstimate is the outlook for a transaction at a given fee rate. Transactions paying more are mined first,
+// so it confirms once the blocks mined cover those ahead of it, including any arriving later.
+type ConfirmationEstimate struct {
+	FeeRate        int
+	VbytesAhead    int // waiting at a higher fee rate, plus half of those at the same fee rate
+	ExpectedBlocks int // blocks until confirmation is more likely than not, or -1 if not within about a week
+	inflowAhead    float64
+}
+
+/// Constructors
+
+// NewFeeHistogram returns a pointer to an empty FeeHistogram.
+func NewFeeHistogram() *FeeHistogram {
+	return &FeeHistogram{buckets: make(map[int]int)}
+}
+
+/// Receiver functions
+
+// AddBucket adds vbytes of transactions waiting at a fee rate, in satoshis per vbyte. Buckets at the same fee rate are
+// summed.
+func (h *FeeHistogram) AddBucket(feeRate int, vbytes int) error {
+	if feeRate < 0 || vbytes < 0 {
+		return errors.New("fee rate and vbytes cannot be negative")
+	}
+	h.buckets[feeRate] += vbytes
+	return nil
+}
+
+// SetInflowRate sets the vbytes of new transactions expected to enter the mempool per block interval. Those paying
+// more than a chosen fee rate, assumed to be distributed across fee rates like the histogram, will be mined ahead of
+// it. Without an inflow rate, estimates assume the mempool only drains, and are certain.
+func (h *FeeHistogram) SetInflowRate(vbytesPerBlock int) error {
+	if vbytesPerBlock < 0 {
+		return errors.New("inflow rate cannot be negative")
+	}
+	h.inflowVbytesPerBlock = vbytesPerBlock
+	return nil
+}
+
+// TotalVbytes returns the vbytes of every transaction in the histogram.
+func (h *FeeHistogram) TotalVbytes() int {
+	total := 0
+	for _, vbytes := range h.buckets {
+		total += vbytes
+	}
+	return total
+}
+
+// Estimate returns the confirmation outlook for a transaction at a fee rate, in satoshis per vbyte.
+func (h *FeeHistogram) Estimate(feeRate int) (*ConfirmationEstimate, error) {
+	if feeRate < 0 {
+		return nil, errors.New("fee rate cannot be negative")
+	}
+
+	ahead := 0
+	for rate, vbytes := range h.buckets {
+		if rate > feeRate {
+			ahead += vbytes
+		} else if rate == feeRate {
+			ahead += vbytes / 2
+		}
+	}
+
+	estimate := ConfirmationEstimate{FeeRate: feeRate, VbytesAhead: ahead, ExpectedBlocks: -1}
+	if total := h.TotalVbytes(); total > 0 {
+		estimate.inflowAhead = float64(h.inflowVbytesPerBlock) * float64(ahead) / float64(total)
+	}
+	for blocks := 1; blocks <= maxConfirmationEstimateBlocks; blocks++ {
+		if estimate.likelihood(blocks) >= 0.5 {
+			estimate.ExpectedBlocks = blocks
+			break
+		}
+	}
+	return &estimate, nil
+}
+
+// MinFeeRateForLikelihood returns the lowest fee rate, in satoshis per vbyte, at which a transaction confirms within a
+// number of blocks with at least the given likelihood, between 0 and 1. Never more than 1 above the highest fee rate
+// in the histogram, which is ahead of every waiting transaction.
+func (h *FeeHistogram) MinFeeRateForLikelihood(blocks int, likelihood float64) (int, error) {
+	if likelihood < 0 || likelihood > 1 {
+		return 0, errors.New("likelihood must be between 0 and 1")
+	}
+	rates := []int{minRelayFeeRate}
+	for rate := range h.buckets {
+		if rate >= minRelayFeeRate {
+			rates = append(rates, rate, rate+1)
+		}
+	}
+	sort.Ints(rates)
+
+	for _, rate := range rates {
+		estimate, err := h.Estimate(rate)
+		if err != nil {
+			return 0, err
+		}
+		achieved, err := estimate.LikelihoodWithinBlocks(blocks)
+		if err != nil {
+			return 0, err
+		}
+		if achieved >= likelihood {
+			return rate, nil
+		}
+	}
+	return rates[len(rates)-1], nil
+}
+
+// LikelihoodWithinBlocks returns the likelihood, between 0 and 1, of confirming within a number of blocks.
+func (e *ConfirmationEstimate) LikelihoodWithinBlocks(blocks int) (float64, error) {
+	if blocks < 1 || blocks > maxConfirmationEstimateBlocks {
+		return 0, errors.New("blocks must be between 1 and 1008")
+	}
+	return e.likelihood(blocks), nil
+}
+
+/// Unexported functions
+
+// likelihood returns the probability that the transactions arriving ahead within a number of blocks, modeled as a
+// Poisson process of typical transactions, leave room for this one in the blocks mined.
+func (e *ConfirmationEstimate) likelihood(blocks int) float64 {
+	room := float64(blocks*maxBlockVsize - e.VbytesAhead - typicalTxVsize)
+	if room < 0 {
+		return 0
+	}
+	arrivals := math.Floor(room / typicalTxVsize)
+	return poissonCDF(arrivals, e.inflowAhead*float64(blocks)/typicalTxVsize)
+}
+
+// poissonCDF returns the probability of at most k events with a mean of lambda, using the normal approximation for
+// large means.
+func poissonCDF(k float64, lambda float64) float64 {
+	if lambda <= 0 {
+		return 1
+	}
+	if lambda > 100 {
+		z := (k + 0.5 - lambda) / math.Sqrt(lambda)
+		return 0.5 * math.Erfc(-z/math.Sqrt2)
+	}
+	if k > lambda+100 {
+		return 1
+	}
+
+	sum := 0.0
+	for i := 0.0; i <= k; i++ {
+		lgamma, _ := math.Lgamma(i + 1)
+		sum += math.Exp(i*math.Log(lambda) - lambda - lgamma)
+	}
+	return math.Min(sum, 1)
+}
@@ -0,0 +1,88 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFeeHistogram(t *testing.T) *FeeHistogram {
+	h := NewFeeHistogram()
+	assert.Nil(t, h.AddBucket(50, 500000))
+	assert.Nil(t, h.AddBucket(20, 1000000))
+	assert.Nil(t, h.AddBucket(10, 2000000))
+	assert.Nil(t, h.AddBucket(5, 1000000))
+	assert.Nil(t, h.AddBucket(5, 2000000))
+	return h
+}
+
+func TestFeeHistogram_WithoutInflow_DrainsInFeeRateOrder(t *testing.T) {
+	h := newTestFeeHistogram(t)
+	assert.Equal(t, 6500000, h.TotalVbytes())
+
+	top, err := h.Estimate(60)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, top.VbytesAhead)
+	assert.Equal(t, 1, top.ExpectedBlocks)
+
+	// half of the transactions at the same fee rate are assumed ahead
+	mid, err := h.Estimate(20)
+	assert.Nil(t, err)
+	assert.Equal(t, 1000000, mid.VbytesAhead)
+	assert.Equal(t, 2, mid.ExpectedBlocks)
+	likelihood, err := mid.LikelihoodWithinBlocks(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.0, likelihood)
+	likelihood, err = mid.LikelihoodWithinBlocks(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0, likelihood)
+
+	_, err = mid.LikelihoodWithinBlocks(0)
+	assert.EqualError(t, err, "blocks must be between 1 and 1008")
+}
+
+func TestFeeHistogram_WithInflow_ReducesLikelihood(t *testing.T) {
+	h := newTestFeeHistogram(t)
+	assert.Nil(t, h.SetInflowRate(1000000))
+
+	estimate, err := h.Estimate(10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2500000, estimate.VbytesAhead)
+	assert.Equal(t, 5, estimate.ExpectedBlocks)
+
+	three, err := estimate.LikelihoodWithinBlocks(3)
+	assert.Nil(t, err)
+	four, err := estimate.LikelihoodWithinBlocks(4)
+	assert.Nil(t, err)
+	five, err := estimate.LikelihoodWithinBlocks(5)
+	assert.Nil(t, err)
+	assert.True(t, three < 0.001)
+	assert.InDelta(t, 0.025, four, 0.01)
+	assert.True(t, five > 0.999)
+
+	// with the mempool filling faster than blocks clear it, the lowest fee rates never confirm
+	assert.Nil(t, h.SetInflowRate(10000000))
+	stuck, err := h.Estimate(5)
+	assert.Nil(t, err)
+	assert.Equal(t, -1, stuck.ExpectedBlocks)
+}
+
+func TestFeeHistogram_MinFeeRateForLikelihood(t *testing.T) {
+	h := newTestFeeHistogram(t)
+
+	rate, err := h.MinFeeRateForLikelihood(1, 0.9)
+	assert.Nil(t, err)
+	assert.Equal(t, 21, rate)
+
+	rate, err = h.MinFeeRateForLikelihood(3, 0.9)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, rate)
+
+	rate, err = NewFeeHistogram().MinFeeRateForLikelihood(1, 0.9)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rate)
+
+	_, err = h.MinFeeRateForLikelihood(1, 1.5)
+	assert.EqualError(t, err, "likelihood must be between 0 and 1")
+	assert.EqualError(t, h.AddBucket(-1, 100), "fee rate and vbytes cannot be negative")
+}
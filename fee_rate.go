@@ -0,0 +1,120 @@
+package cnlib
+
+import (
+	"errors"
+	"math"
+)
+
+// Units in which a fee rate may be given. BTC/kB is per 1000 virtual bytes, as reported by bitcoind's
+// estimatesmartfee and most backends using the name.
+const (
+	FeeRateUnitSatPerVByte = iota
+	FeeRateUnitSatPerKVByte
+	FeeRateUnitBTCPerKB
+)
+
+const (
+	vbytesPerKVByte = 1000
+	satoshisPerBTC  = 100000000
+
+	// minFeeRateSatPerKVByte is the default minimum relay fee rate.
+	minFeeRateSatPerKVByte = 1000
+
+	// maxFeeRateSatPerKVByte is 5000 sat/vB, well above any fee market so far, and below the sat/kvB value of almost
+	// any rate mistaken for sat/vB.
+	maxFeeRateSatPerKVByte = 5000 * vbytesPerKVByte
+)
+
+// Following errors are returned when a fee rate is not a plausible rate to pay.
+var (
+	ErrFeeRateUnit         = errors.New("unknown fee rate unit")
+	ErrFeeRateInvalid      = errors.New("fee rate must be a finite, non-negative number")
+	ErrFeeRateBelowMinimum = errors.New("fee rate is below the minimum relay fee rate of 1 sat/vB")
+	ErrFeeRateAboveMaximum = errors.New("fee rate is above the maximum of 5000 sat/vB")
+)
+
+/// Type Definitions
+
+// FeeRate is a validated fee rate, held in whole satoshis per 1000 virtual bytes, so that converting between units
+// loses nothing beyond the rounding of the rate given.
+type FeeRate struct {
+	satPerKVByte int64
+}
+
+/// Constructors
+
+// NewFeeRate returns a pointer to a FeeRate of a value in one of the FeeRateUnit constants. The value is rounded to the
+// nearest sat/kvB, which is exact for whole satoshi BTC/kB values and sat/vB values of up to three decimals. Returns
+// ErrFeeRateBelowMinimum or ErrFeeRateAboveMaximum if the rate is not between 1 and 5000 sat/vB.
+func NewFeeRate(value float64, unit int) (*FeeRate, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+		return nil, ErrFeeRateInvalid
+	}
+
+	var satPerKVByte float64
+	switch unit {
+	case FeeRateUnitSatPerVByte:
+		satPerKVByte = value * vbytesPerKVByte
+	case FeeRateUnitSatPerKVByte:
+		satPerKVByte = value
+	case FeeRateUnitBTCPerKB:
+		satPerKVByte = value * satoshisPerBTC
+	default:
+		return nil, ErrFeeRateUnit
+	}
+
+	// bounds are checked before converting to an integer, which could otherwise overflow
+	rounded := math.Round(satPerKVByte)
+	if rounded < minFeeRateSatPerKVByte {
+		return nil, ErrFeeRateBelowMinimum
+	}
+	if rounded > maxFeeRateSatPerKVByte {
+		return nil, ErrFeeRateAboveMaximum
+	}
+	return &FeeRate{satPerKVByte: int64(rounded)}, nil
+}
+
+/// Receiver functions
+
+// SatPerVByte returns the rate in whole sat/vB, as used when building transactions, rounded up so the fee paid is
+// never less than the rate given.
+func (f *FeeRate) SatPerVByte() int {
+	return int((f.satPerKVByte + vbytesPerKVByte - 1) / vbytesPerKVByte)
+}
+
+// SatPerKVByte returns the rate in sat/kvB.
+func (f *FeeRate) SatPerKVByte() int64 {
+	return f.satPerKVByte
+}
+
+// Value returns the rate in one of the FeeRateUnit constants, without rounding beyond that of floating point.
+func (f *FeeRate) Value(unit int) (float64, error) {
+	switch unit {
+	case FeeRateUnitSatPerVByte:
+		return float64(f.satPerKVByte) / vbytesPerKVByte, nil
+	case FeeRateUnitSatPerKVByte:
+		return float64(f.satPerKVByte), nil
+	case FeeRateUnitBTCPerKB:
+		return float64(f.satPerKVByte) / satoshisPerBTC, nil
+	default:
+		return 0, ErrFeeRateUnit
+	}
+}
+
+/// Exported functions
+
+// ConvertFeeRate converts a fee rate between FeeRateUnit constants, validating it as `NewFeeRate` does.
+func ConvertFeeRate(value float64, fromUnit int, toUnit int) (float64, error) {
+	rate, err := NewFeeRate(value, fromUnit)
+	if err != nil {
+		return 0, err
+	}
+	return rate.Value(toUnit)
+}
+
+// ValidateFeeRate returns nil if a fee rate in whole sat/vB, as passed to `NewTransactionDataStandard` and friends, is
+// between 1 and 5000 sat/vB, or the error describing why not.
+func ValidateFeeRate(satPerVByte int) error {
+	_, err := NewFeeRate(float64(satPerVByte), FeeRateUnitSatPerVByte)
+	return err
+}
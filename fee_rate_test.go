@@ -0,0 +1,80 @@
+package cnlib
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFeeRate_Units(t *testing.T) {
+	// 12.5 sat/vB from each backend's unit
+	for _, input := range []struct {
+		value float64
+		unit  int
+	}{
+		{12.5, FeeRateUnitSatPerVByte},
+		{12500, FeeRateUnitSatPerKVByte},
+		{0.000125, FeeRateUnitBTCPerKB},
+	} {
+		rate, err := NewFeeRate(input.value, input.unit)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(12500), rate.SatPerKVByte())
+		assert.Equal(t, 13, rate.SatPerVByte())
+
+		satPerVByte, _ := rate.Value(FeeRateUnitSatPerVByte)
+		assert.Equal(t, 12.5, satPerVByte)
+		btcPerKB, _ := rate.Value(FeeRateUnitBTCPerKB)
+		assert.Equal(t, 0.000125, btcPerKB)
+	}
+}
+
+func TestNewFeeRate_Rounding(t *testing.T) {
+	// BTC/kB values which are not exact in floating point round to the nearest satoshi
+	rate, err := NewFeeRate(0.00001, FeeRateUnitBTCPerKB)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1000), rate.SatPerKVByte())
+	assert.Equal(t, 1, rate.SatPerVByte())
+
+	rate, _ = NewFeeRate(1.0004, FeeRateUnitSatPerVByte)
+	assert.Equal(t, int64(1000), rate.SatPerKVByte())
+	rate, _ = NewFeeRate(1.0005, FeeRateUnitSatPerVByte)
+	assert.Equal(t, int64(1001), rate.SatPerKVByte())
+	assert.Equal(t, 2, rate.SatPerVByte())
+
+	converted, err := ConvertFeeRate(0.00020001, FeeRateUnitBTCPerKB, FeeRateUnitSatPerVByte)
+	assert.Nil(t, err)
+	assert.Equal(t, 20.001, converted)
+}
+
+func TestNewFeeRate_Bounds(t *testing.T) {
+	_, err := NewFeeRate(0.5, FeeRateUnitSatPerVByte)
+	assert.Equal(t, ErrFeeRateBelowMinimum, err)
+	_, err = NewFeeRate(999.4, FeeRateUnitSatPerKVByte)
+	assert.Equal(t, ErrFeeRateBelowMinimum, err)
+	_, err = NewFeeRate(999.5, FeeRateUnitSatPerKVByte)
+	assert.Nil(t, err)
+
+	// a sat/kvB value mistaken for sat/vB
+	_, err = NewFeeRate(20000, FeeRateUnitSatPerVByte)
+	assert.Equal(t, ErrFeeRateAboveMaximum, err)
+	// a BTC/kB value mistaken for sat/vB
+	_, err = NewFeeRate(0.0002, FeeRateUnitSatPerVByte)
+	assert.Equal(t, ErrFeeRateBelowMinimum, err)
+	_, err = NewFeeRate(1e300, FeeRateUnitBTCPerKB)
+	assert.Equal(t, ErrFeeRateAboveMaximum, err)
+
+	for _, value := range []float64{-1, math.NaN(), math.Inf(1)} {
+		_, err = NewFeeRate(value, FeeRateUnitSatPerVByte)
+		assert.Equal(t, ErrFeeRateInvalid, err)
+	}
+	_, err = NewFeeRate(10, 3)
+	assert.Equal(t, ErrFeeRateUnit, err)
+	_, err = ConvertFeeRate(10, FeeRateUnitSatPerVByte, -1)
+	assert.Equal(t, ErrFeeRateUnit, err)
+
+	assert.Nil(t, ValidateFeeRate(1))
+	assert.Nil(t, ValidateFeeRate(5000))
+	assert.Equal(t, ErrFeeRateBelowMinimum, ValidateFeeRate(0))
+	assert.Equal(t, ErrFeeRateAboveMaximum, ValidateFeeRate(5001))
+}
@@ -0,0 +1,425 @@
+package cnlib
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// Fountain codes as specified by BCR-2020-005 for multipart URs. Parts up to the fragment count each carry one
+// fragment; later parts XOR a pseudorandom selection of fragments together, so a scanner can recover the message
+// from any sufficient set of parts and does not wait for a missed part to come round again.
+
+const minFountainFragmentLength = 10
+
+/// Type Definitions
+
+type fountainEncoder struct {
+	messageLength int
+	checksum      uint32
+	fragments     [][]byte
+	seqNum        uint32
+}
+
+type fountainPart struct {
+	seqNum        uint32
+	seqLength     int
+	messageLength int
+	checksum      uint32
+	data          []byte
+}
+
+type fountainDecoder struct {
+	seqLength     int
+	messageLength int
+	checksum      uint32
+	fragmentSize  int
+	simple        map[int][]byte
+	mixed         map[string]*mixedFragment
+	message       []byte
+	err           error
+}
+
+type mixedFragment struct {
+	indexes []int
+	data    []byte
+}
+
+// xoshiro256 is the xoshiro256** generator, seeded as BCR-2020-005 requires so that encoder and decoder choose the
+// same fragments for a part.
+type xoshiro256 struct {
+	s [4]uint64
+}
+
+/// Constructors
+
+func newFountainEncoder(message []byte, maxFragmentLength int) (*fountainEncoder, error) {
+	if len(message) == 0 {
+		return nil, errors.New("message cannot be empty")
+	}
+	if maxFragmentLength < minFountainFragmentLength {
+		return nil, errors.New("fragment length too small")
+	}
+
+	fragmentLength := nominalFragmentLength(len(message), minFountainFragmentLength, maxFragmentLength)
+	count := (len(message) + fragmentLength - 1) / fragmentLength
+	padded := make([]byte, count*fragmentLength)
+	copy(padded, message)
+
+	fragments := make([][]byte, count)
+	for i := range fragments {
+		fragments[i] = padded[i*fragmentLength : (i+1)*fragmentLength]
+	}
+	return &fountainEncoder{
+		messageLength: len(message),
+		checksum:      crc32.ChecksumIEEE(message),
+		fragments:     fragments,
+	}, nil
+}
+
+func newFountainDecoder() *fountainDecoder {
+	return &fountainDecoder{simple: map[int][]byte{}, mixed: map[string]*mixedFragment{}}
+}
+
+func newXoshiro256(seed []byte) *xoshiro256 {
+	var x xoshiro256
+	for i := range x.s {
+		x.s[i] = binary.BigEndian.Uint64(seed[8*i : 8*i+8])
+	}
+	return &x
+}
+
+/// Receiver functions
+
+// nextPart returns the next part, with sequence numbers starting at 1.
+func (e *fountainEncoder) nextPart() *fountainPart {
+	e.seqNum++
+	indexes := chooseFountainFragments(e.seqNum, len(e.fragments), e.checksum)
+	data := make([]byte, len(e.fragments[0]))
+	for _, index := range indexes {
+		xorInto(data, e.fragments[index])
+	}
+	return &fountainPart{
+		seqNum:        e.seqNum,
+		seqLength:     len(e.fragments),
+		messageLength: e.messageLength,
+		checksum:      e.checksum,
+		data:          data,
+	}
+}
+
+func (p *fountainPart) cbor() ([]byte, error) {
+	return cborEncode([]interface{}{p.seqNum, p.seqLength, p.messageLength, p.checksum, p.data})
+}
+
+// receive adds a part, returning error if it does not belong to the same message as earlier parts.
+func (d *fountainDecoder) receive(part *fountainPart) error {
+	if d.isComplete() {
+		return nil
+	}
+	if part.seqLength < 1 || part.messageLength < 1 || len(part.data) == 0 {
+		return errors.New("invalid fountain part")
+	}
+	if d.seqLength == 0 {
+		if (part.messageLength+len(part.data)-1)/len(part.data) != part.seqLength {
+			return errors.New("invalid fountain part")
+		}
+		d.seqLength = part.seqLength
+		d.messageLength = part.messageLength
+		d.checksum = part.checksum
+		d.fragmentSize = len(part.data)
+	} else if part.seqLength != d.seqLength || part.messageLength != d.messageLength ||
+		part.checksum != d.checksum || len(part.data) != d.fragmentSize {
+		return errors.New("part belongs to a different message")
+	}
+
+	queue := []*mixedFragment{{
+		indexes: chooseFountainFragments(part.seqNum, part.seqLength, part.checksum),
+		data:    append([]byte{}, part.data...),
+	}}
+	for len(queue) > 0 {
+		fragment := queue[0]
+		queue = queue[1:]
+		queue = append(queue, d.process(fragment)...)
+	}
+
+	if len(d.simple) == d.seqLength {
+		message := make([]byte, 0, d.seqLength*d.fragmentSize)
+		for i := 0; i < d.seqLength; i++ {
+			message = append(message, d.simple[i]...)
+		}
+		message = message[:d.messageLength]
+		if crc32.ChecksumIEEE(message) != d.checksum {
+			d.err = errors.New("message checksum mismatch")
+		}
+		d.message = message
+	}
+	return nil
+}
+
+// process reduces a fragment by those already known, storing it, and returns mixed fragments that it reduced in turn.
+func (d *fountainDecoder) process(fragment *mixedFragment) []*mixedFragment {
+	fragment = d.reduceBySimple(fragment)
+	if len(fragment.indexes) == 0 {
+		return nil
+	}
+
+	if len(fragment.indexes) == 1 {
+		index := fragment.indexes[0]
+		if _, ok := d.simple[index]; ok {
+			return nil
+		}
+		d.simple[index] = fragment.data
+		var reduced []*mixedFragment
+		for key, mixed := range d.mixed {
+			if containsIndexes(mixed.indexes, fragment.indexes) {
+				delete(d.mixed, key)
+				reduced = append(reduced, mixed)
+			}
+		}
+		return reduced
+	}
+
+	for _, mixed := range d.mixed {
+		if containsIndexes(fragment.indexes, mixed.indexes) {
+			fragment = subtractFragment(fragment, mixed)
+		}
+	}
+	if len(fragment.indexes) == 1 {
+		return []*mixedFragment{fragment}
+	}
+	key := fragmentKey(fragment.indexes)
+	if _, ok := d.mixed[key]; ok {
+		return nil
+	}
+
+	var reduced []*mixedFragment
+	for otherKey, mixed := range d.mixed {
+		if containsIndexes(mixed.indexes, fragment.indexes) {
+			delete(d.mixed, otherKey)
+			reduced = append(reduced, subtractFragment(mixed, fragment))
+		}
+	}
+	d.mixed[key] = fragment
+	return reduced
+}
+
+func (d *fountainDecoder) reduceBySimple(fragment *mixedFragment) *mixedFragment {
+	result := &mixedFragment{data: append([]byte{}, fragment.data...)}
+	for _, index := range fragment.indexes {
+		if data, ok := d.simple[index]; ok && len(fragment.indexes) > 1 {
+			xorInto(result.data, data)
+		} else {
+			result.indexes = append(result.indexes, index)
+		}
+	}
+	return result
+}
+
+func (d *fountainDecoder) isComplete() bool {
+	return d.message != nil
+}
+
+// progress returns the fraction of fragments recovered.
+func (d *fountainDecoder) progress() float64 {
+	if d.seqLength == 0 {
+		return 0
+	}
+	return float64(len(d.simple)) / float64(d.seqLength)
+}
+
+func (x *xoshiro256) next() uint64 {
+	result := bits.RotateLeft64(x.s[1]*5, 7) * 9
+	t := x.s[1] << 17
+	x.s[2] ^= x.s[0]
+	x.s[3] ^= x.s[1]
+	x.s[1] ^= x.s[2]
+	x.s[0] ^= x.s[3]
+	x.s[2] ^= t
+	x.s[3] = bits.RotateLeft64(x.s[3], 45)
+	return result
+}
+
+func (x *xoshiro256) nextDouble() float64 {
+	return float64(x.next()) / (float64(math.MaxUint64) + 1)
+}
+
+// nextInt returns an integer between low and high inclusive.
+func (x *xoshiro256) nextInt(low int, high int) int {
+	return int(x.nextDouble()*float64(high-low+1)) + low
+}
+
+/// Unexported functions
+
+// nominalFragmentLength returns the smallest fragment length, no larger than maxFragmentLength, that splits the
+// message into as few fragments as possible.
+func nominalFragmentLength(messageLength int, minFragmentLength int, maxFragmentLength int) int {
+	maxFragmentCount := messageLength / minFragmentLength
+	if maxFragmentCount < 1 {
+		maxFragmentCount = 1
+	}
+	fragmentLength := messageLength
+	for count := 1; count <= maxFragmentCount; count++ {
+		fragmentLength = (messageLength + count - 1) / count
+		if fragmentLength <= maxFragmentLength {
+			break
+		}
+	}
+	return fragmentLength
+}
+
+// chooseFountainFragments returns the 0-based indexes of the fragments mixed into the part with a given sequence
+// number.
+func chooseFountainFragments(seqNum uint32, seqLength int, checksum uint32) []int {
+	if int(seqNum) <= seqLength {
+		return []int{int(seqNum) - 1}
+	}
+
+	seed := make([]byte, 8)
+	binary.BigEndian.PutUint32(seed[:4], seqNum)
+	binary.BigEndian.PutUint32(seed[4:], checksum)
+	digest := sha256.Sum256(seed)
+	rng := newXoshiro256(digest[:])
+
+	degree := chooseFountainDegree(seqLength, rng)
+	remaining := make([]int, seqLength)
+	for i := range remaining {
+		remaining[i] = i
+	}
+	shuffled := make([]int, 0, seqLength)
+	for len(remaining) > 0 {
+		index := rng.nextInt(0, len(remaining)-1)
+		shuffled = append(shuffled, remaining[index])
+		remaining = append(remaining[:index], remaining[index+1:]...)
+	}
+
+	indexes := shuffled[:degree]
+	sort.Ints(indexes)
+	return indexes
+}
+
+// chooseFountainDegree picks how many fragments to mix, with the probability of degree d proportional to 1/d, using
+// Vose's alias method.
+func chooseFountainDegree(seqLength int, rng *xoshiro256) int {
+	n := seqLength
+	scaled := make([]float64, n)
+	var sum float64
+	for i := range scaled {
+		scaled[i] = 1 / float64(i+1)
+		sum += scaled[i]
+	}
+	for i := range scaled {
+		scaled[i] = scaled[i] * float64(n) / sum
+	}
+
+	var small, large []int
+	for i := n - 1; i >= 0; i-- {
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+
+	probs := make([]float64, n)
+	aliases := make([]int, n)
+	for len(small) > 0 && len(large) > 0 {
+		a := small[len(small)-1]
+		small = small[:len(small)-1]
+		g := large[len(large)-1]
+		large = large[:len(large)-1]
+
+		probs[a] = scaled[a]
+		aliases[a] = g
+		scaled[g] += scaled[a] - 1
+		if scaled[g] < 1 {
+			small = append(small, g)
+		} else {
+			large = append(large, g)
+		}
+	}
+	for _, i := range large {
+		probs[i] = 1
+	}
+	for _, i := range small {
+		probs[i] = 1
+	}
+
+	r1 := rng.nextDouble()
+	r2 := rng.nextDouble()
+	i := int(float64(n) * r1)
+	if r2 < probs[i] {
+		return i + 1
+	}
+	return aliases[i] + 1
+}
+
+func parseFountainPart(data []byte) (*fountainPart, error) {
+	value, err := cborDecode(data)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := value.([]interface{})
+	if !ok || len(items) != 5 {
+		return nil, errors.New("invalid fountain part")
+	}
+	seqNum, ok1 := items[0].(uint64)
+	seqLength, ok2 := items[1].(uint64)
+	messageLength, ok3 := items[2].(uint64)
+	checksum, ok4 := items[3].(uint64)
+	fragment, ok5 := items[4].([]byte)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || seqNum == 0 || seqNum > math.MaxUint32 ||
+		checksum > math.MaxUint32 || seqLength > math.MaxUint16 || messageLength > math.MaxInt32 {
+		return nil, errors.New("invalid fountain part")
+	}
+	return &fountainPart{
+		seqNum:        uint32(seqNum),
+		seqLength:     int(seqLength),
+		messageLength: int(messageLength),
+		checksum:      uint32(checksum),
+		data:          fragment,
+	}, nil
+}
+
+// containsIndexes returns true if every index in subset is in set. Both must be sorted.
+func containsIndexes(set []int, subset []int) bool {
+	i := 0
+	for _, index := range subset {
+		for i < len(set) && set[i] < index {
+			i++
+		}
+		if i == len(set) || set[i] != index {
+			return false
+		}
+	}
+	return true
+}
+
+func subtractFragment(fragment *mixedFragment, subset *mixedFragment) *mixedFragment {
+	result := &mixedFragment{data: append([]byte{}, fragment.data...)}
+	xorInto(result.data, subset.data)
+	for _, index := range fragment.indexes {
+		if !containsIndexes(subset.indexes, []int{index}) {
+			result.indexes = append(result.indexes, index)
+		}
+	}
+	return result
+}
+
+func fragmentKey(indexes []int) string {
+	key := make([]byte, 0, 2*len(indexes))
+	for _, index := range indexes {
+		key = append(key, byte(index>>8), byte(index))
+	}
+	return string(key)
+}
+
+func xorInto(target []byte, source []byte) {
+	for i := range target {
+		target[i] ^= source[i]
+	}
+}
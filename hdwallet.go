@@ -1,6 +1,8 @@
 package cnlib
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"strings"
@@ -21,10 +23,40 @@ import (
 
 // HDWallet represents the user's current wallet.
 type HDWallet struct {
-	BaseCoin         *BaseCoin
-	WalletWords      string // space-separated string of user's recovery words
-	masterPrivateKey *hdkeychain.ExtendedKey
-	accountPublicKey *hdkeychain.ExtendedKey
+	BaseCoin          *BaseCoin
+	WalletWords       string // space-separated string of user's recovery words
+	masterPrivateKey  *hdkeychain.ExtendedKey
+	accountPublicKey  *hdkeychain.ExtendedKey
+	indexTracker      *UsedIndexTracker
+	role              int
+	masterFingerprint []byte // set on watch-only wallets only, see `SetMasterFingerprint`
+	keyCache          *derivationCache
+	addressCache      *AddressCache // nil unless enabled, see `AddressCache`
+	seed              []byte        // set on wallets created from a seed rather than words, see `NewHDWalletFromSeed`
+	observer          WalletObserver
+	isLite            bool // account key derived on first use, see `NewLiteHDWalletFromWords`
+
+	deterministicSchnorr bool // see `SetDeterministicSchnorrSignatures`
+}
+
+// Following errors are returned by `NewHDWalletFromExtendedKey` and `NewWatchOnlyWallet` for unusable keys.
+var (
+	ErrExtendedKeyNotPrivate = errors.New("extended key is not a private key")
+	ErrExtendedKeyNotPublic  = errors.New("extended key is not a public key")
+	ErrExtendedKeyNotMaster  = errors.New("extended key is not a master key")
+	ErrExtendedKeyNotAccount = errors.New("extended key is not an account key")
+	ErrExtendedKeyNetwork    = errors.New("extended key is not for the basecoin's network")
+	ErrExtendedKeyPurpose    = errors.New("extended key is not for the basecoin's purpose")
+)
+
+// ErrPrivateKeyNetwork is returned by `ImportPrivateKey` for a WIF private key encoded for another network.
+var ErrPrivateKeyNetwork = errors.New("private key is not for the basecoin's network")
+
+// EphemeralEncryptionResult is returned from EncryptWithEphemeralKey.
+type EphemeralEncryptionResult struct {
+	Payload                     []byte
+	EphemeralUncompressedPubkey string // hex-encoded public key of the ephemeral keypair derived from entropy
+	Version                     int    // version byte of the encrypted payload format
 }
 
 // GetFullBIP39WordListString returns all 2,048 BIP39 mnemonic words as a space-separated string.
@@ -32,20 +64,14 @@ func GetFullBIP39WordListString() string {
 	return strings.Join(wordlists.English, " ")
 }
 
-// NewWordListFromEntropy returns a space-separated list of mnemonic words from entropy.
+// NewWordListFromEntropy returns a space-separated list of English mnemonic words from entropy. See
+// `NewWordListFromEntropyForLanguage` for other languages.
 func NewWordListFromEntropy(entropy []byte) (string, error) {
-	words, err := bip39.NewMnemonic(entropy)
-	if err != nil {
-		return "", err
-	}
-	valid := bip39.IsMnemonicValid(words)
-	if !valid {
-		return "", errors.New("invalid mnemonic")
-	}
-	return words, nil
+	return NewWordListFromEntropyForLanguage(entropy, WordListLanguageEnglish)
 }
 
 // NewHDWalletFromWords returns a pointer to an HDWallet, containing the BaseCoin, words, and unexported master private key.
+// Words may be of any BIP39 wordlist language, and are not validated; see `ValidateWordString`.
 func NewHDWalletFromWords(wordString string, basecoin *BaseCoin) *HDWallet {
 	masterKey, err := masterPrivateKey(wordString, basecoin)
 	if err != nil {
@@ -56,22 +82,86 @@ func NewHDWalletFromWords(wordString string, basecoin *BaseCoin) *HDWallet {
 	if err != nil {
 		return nil
 	}
-	wallet := HDWallet{BaseCoin: basecoin, WalletWords: wordString, masterPrivateKey: masterKey, accountPublicKey: pubkey}
+	wallet := HDWallet{BaseCoin: basecoin, WalletWords: wordString, masterPrivateKey: masterKey, accountPublicKey: pubkey, keyCache: newDerivationCache()}
 	return &wallet
 }
 
-// NewHDWalletFromAccountExtendedPublicKey returns a pointer to an HDWallet, containing the BaseCoin, empty word list, nil master private key,
-// and unexported pointer to extended key for account-level extended master private key. Returns error if unable to parse x/y/zpub.
-func NewHDWalletFromAccountExtendedPublicKey(acctPubKeyStr string) (*HDWallet, error) {
-	key, err := hdkeychain.NewKeyFromString(acctPubKeyStr)
+// NewHDWalletFromSeed returns a pointer to an HDWallet from a raw BIP32 seed of 16 to 64 bytes, such as a hardware
+// wallet's seed, for wallets with no mnemonic. WalletWords is empty. Returns error if the seed length is invalid.
+func NewHDWalletFromSeed(seed []byte, basecoin *BaseCoin) (*HDWallet, error) {
+	masterKey, err := masterPrivateKeyFromSeed(seed, basecoin)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := newHDWalletFromMasterKey(masterKey, basecoin)
 	if err != nil {
 		return nil, err
 	}
+	wallet.seed = append([]byte{}, seed...)
+	return wallet, nil
+}
+
+// NewHDWalletFromExtendedKey returns a pointer to an HDWallet from a base58-encoded extended master private key, such
+// as a BIP85 XPRV child or `LightningNodeExtendedPrivateKey`. WalletWords is empty, and the wallet has no seed to
+// export. Returns error if the key is not a master private key for the BaseCoin's network.
+func NewHDWalletFromExtendedKey(extendedKey string, basecoin *BaseCoin) (*HDWallet, error) {
+	masterKey, err := hdkeychain.NewKeyFromString(extendedKey)
+	if err != nil {
+		return nil, err
+	}
+	if !masterKey.IsPrivate() {
+		return nil, ErrExtendedKeyNotPrivate
+	}
+	if masterKey.Depth() != 0 || masterKey.ParentFingerprint() != 0 {
+		return nil, ErrExtendedKeyNotMaster
+	}
+	if !masterKey.IsForNet(basecoin.defaultNetParams()) {
+		return nil, ErrExtendedKeyNetwork
+	}
+	return newHDWalletFromMasterKey(masterKey, basecoin)
+}
+
+// NewHDWalletFromAccountExtendedPublicKey returns a pointer to a watch-only HDWallet from an account extended public
+// key, as `NewWatchOnlyWallet` does, with the BaseCoin of the key's x/y/zpub version bytes. Returns error if unable to
+// parse the key.
+func NewHDWalletFromAccountExtendedPublicKey(acctPubKeyStr string) (*HDWallet, error) {
 	basecoin, err := NewBaseCoinFromAccountPubKey(acctPubKeyStr)
 	if err != nil {
 		return nil, err
 	}
-	wallet := HDWallet{BaseCoin: basecoin, WalletWords: "", masterPrivateKey: nil, accountPublicKey: key}
+	return NewWatchOnlyWallet(acctPubKeyStr, basecoin)
+}
+
+// NewWatchOnlyWallet returns a pointer to a watch-only HDWallet from a base58-encoded account extended public key, such
+// as an exported xpub or zpub, which derives receive and change MetaAddresses and runs `CheckForAddress` without any
+// private material. The BaseCoin's purpose and network select the address type, so a generic xpub or tpub may be given
+// for any purpose, and the account is taken from the key. Returns ErrExtendedKeyNotPublic, ErrExtendedKeyNotAccount,
+// ErrExtendedKeyNetwork or ErrExtendedKeyPurpose if the key is unusable with the BaseCoin.
+func NewWatchOnlyWallet(extendedPubKey string, basecoin *BaseCoin) (*HDWallet, error) {
+	if basecoin == nil {
+		return nil, errors.New("basecoin cannot be nil")
+	}
+	key, err := hdkeychain.NewKeyFromString(extendedPubKey)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsPrivate() {
+		return nil, ErrExtendedKeyNotPublic
+	}
+	if key.Depth() != 3 {
+		return nil, ErrExtendedKeyNotAccount
+	}
+
+	// bytes 0 through 3 are the version, and 9 through 12 the account's hardened child number
+	decoded := base58.Decode(extendedPubKey)
+	if err := checkAccountKeyVersion(decoded[:4], basecoin); err != nil {
+		return nil, err
+	}
+	account := int(binary.BigEndian.Uint32(decoded[9:13]) &^ hdkeychain.HardenedKeyStart)
+
+	coin := *basecoin
+	coin.Account = account
+	wallet := HDWallet{BaseCoin: &coin, accountPublicKey: key, role: WalletRoleHot, keyCache: newDerivationCache()}
 	return &wallet, nil
 }
 
@@ -79,6 +169,10 @@ func NewHDWalletFromAccountExtendedPublicKey(acctPubKeyStr string) (*HDWallet, e
 
 // SigningKey returns the private key at the m/42 path.
 func (wallet *HDWallet) SigningKey() ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
 	ec, err := wallet.signingPrivateKey()
 	if err != nil {
 		return nil, err
@@ -88,7 +182,7 @@ func (wallet *HDWallet) SigningKey() ([]byte, error) {
 
 // SigningPublicKey returns the public key at the m/42 path.
 func (wallet *HDWallet) SigningPublicKey() ([]byte, error) {
-	kf := keyFactory{masterPrivateKey: wallet.masterPrivateKey}
+	kf := wallet.keyFactory()
 
 	smk, err := kf.signingMasterKey()
 	if err != nil {
@@ -114,29 +208,31 @@ func (wallet *HDWallet) CoinNinjaVerificationKeyHexString() (string, error) {
 
 // ReceiveAddressForIndex returns a receive MetaAddress derived from the current wallet, BaseCoin, and index.
 func (wallet *HDWallet) ReceiveAddressForIndex(index int) (*MetaAddress, error) {
-	if wallet.masterPrivateKey != nil {
-		return wallet.metaAddress(0, index)
-	} else if wallet.accountPublicKey != nil {
-		return indexMetaAddressFromExtendedPubkey(wallet.accountPublicKey, wallet.BaseCoin, 0, uint32(index))
-	}
-
-	return nil, errors.New("no valid master private key or account extended public key found")
+	return wallet.addressForIndex(0, index)
 }
 
 // ChangeAddressForIndex returns a change MetaAddress derived from the current wallet, BaseCoin, and index.
 func (wallet *HDWallet) ChangeAddressForIndex(index int) (*MetaAddress, error) {
+	return wallet.addressForIndex(1, index)
+}
+
+// deriveAddressForIndex derives the MetaAddress at an index of the receive (0) or change (1) chain.
+func (wallet *HDWallet) deriveAddressForIndex(change int, index int) (*MetaAddress, error) {
 	if wallet.masterPrivateKey != nil {
-		return wallet.metaAddress(1, index)
+		return wallet.metaAddress(change, index)
 	} else if wallet.accountPublicKey != nil {
-		return indexMetaAddressFromExtendedPubkey(wallet.accountPublicKey, wallet.BaseCoin, 1, uint32(index))
+		return indexMetaAddressFromExtendedPubkey(wallet.accountPublicKey, wallet.keyCache, wallet.BaseCoin, uint32(change), uint32(index))
 	}
 
 	return nil, errors.New("no valid master private key or account extended public key found")
 }
 
 // indexMetaAddressFromExtendedPubkey is a private method to use shared code to create internal/external (change) MetaAddresses with a given index.
-func indexMetaAddressFromExtendedPubkey(extPubkey *hdkeychain.ExtendedKey, basecoin *BaseCoin, change uint32, index uint32) (*MetaAddress, error) {
-	changeKey, err := extPubkey.Child(change)
+func indexMetaAddressFromExtendedPubkey(extPubkey *hdkeychain.ExtendedKey, cache *derivationCache, basecoin *BaseCoin, change uint32, index uint32) (*MetaAddress, error) {
+	key := derivationCacheKey{purpose: basecoin.Purpose, coin: basecoin.Coin, account: basecoin.Account, change: int(change), public: true}
+	changeKey, err := cache.chainKey(key, func() (*hdkeychain.ExtendedKey, error) {
+		return extPubkey.Child(change)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -165,6 +261,9 @@ func (wallet *HDWallet) UpdateCoin(c *BaseCoin) {
 
 // CheckForAddress scans the wallet for a given address up to a given index on both receive/change chains.
 func (wallet *HDWallet) CheckForAddress(a string, upTo int) (*MetaAddress, error) {
+	if meta := wallet.cachedAddress(a); meta != nil && meta.DerivationPath.Index < upTo {
+		return meta, nil
+	}
 	for i := 0; i < upTo; i++ {
 		rma, err := wallet.ReceiveAddressForIndex(i)
 		if err != nil {
@@ -186,24 +285,64 @@ func (wallet *HDWallet) CheckForAddress(a string, upTo int) (*MetaAddress, error
 
 // SignData signs a given message and returns the signature in bytes.
 func (wallet *HDWallet) SignData(message []byte) ([]byte, error) {
-	kf := keyFactory{masterPrivateKey: wallet.masterPrivateKey}
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	kf := wallet.keyFactory()
 	return kf.signData(message)
 }
 
 // SignatureSigningData signs a given message and returns the signature in hex-encoded string format.
 func (wallet *HDWallet) SignatureSigningData(message []byte) (string, error) {
-	kf := keyFactory{masterPrivateKey: wallet.masterPrivateKey}
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return "", err
+	}
+
+	kf := wallet.keyFactory()
 	return kf.signatureSigningData(message)
 }
 
-// EncryptWithEphemeralKey encrypts a given body (byte slice) using ECDH symmetric key encryption by creating an ephemeral keypair from entropy and given uncompressed public key.
-func (wallet *HDWallet) EncryptWithEphemeralKey(entropy []byte, body []byte, recipientUncompressedPubkey string) ([]byte, error) {
-	pubkeyBytes, err := hex.DecodeString(recipientUncompressedPubkey)
+// SignDataWithPath signs a given message as `SignData` does, with the key derived from given derivation path, such as
+// a per-contact or per-channel identity key, and returns the signature in bytes. Verify it with `VerifySignature` and
+// the path's `CompressedPubKeyForPath`. Returns ErrKeyRoleMismatch for spending or encryption key paths, as an
+// arbitrary message could be a transaction's signature hash preimage.
+func (wallet *HDWallet) SignDataWithPath(message []byte, path *DerivationPath) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	if path == nil {
+		return nil, errors.New("derivation path cannot be nil")
+	}
+	if err := rejectKeyRoles(path, KeyRoleSpending, KeyRoleEncryption); err != nil {
+		return nil, err
+	}
+
+	kf := wallet.keyFactory()
+	return kf.signDataWithPath(message, path)
+}
+
+// SignatureSigningDataWithPath signs a given message as `SignDataWithPath` does and returns the signature in
+// hex-encoded string format.
+func (wallet *HDWallet) SignatureSigningDataWithPath(message []byte, path *DerivationPath) (string, error) {
+	sign, err := wallet.SignDataWithPath(message, path)
 	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(sign), nil
+}
+
+// EncryptWithEphemeralKey encrypts a given body (byte slice) using ECDH symmetric key encryption by creating an ephemeral keypair from entropy and given hex-encoded compressed or uncompressed public key.
+// Entropy must be 16 to 32 bytes, in 4 byte increments. The result includes the ephemeral public key and payload version so callers can record how the payload was produced.
+// Returns ErrPublicKeyHex, ErrPublicKeyLength or ErrPublicKeyPoint if the public key is malformed.
+func (wallet *HDWallet) EncryptWithEphemeralKey(entropy []byte, body []byte, recipientUncompressedPubkey string) (*EphemeralEncryptionResult, error) {
+	if err := validateEntropyLength(entropy); err != nil {
 		return nil, err
 	}
 
-	publicKey, err := btcec.ParsePubKey(pubkeyBytes, btcec.S256())
+	publicKey, err := parseHexPubKey(recipientUncompressedPubkey)
 	if err != nil {
 		return nil, err
 	}
@@ -213,18 +352,75 @@ func (wallet *HDWallet) EncryptWithEphemeralKey(entropy []byte, body []byte, rec
 		return nil, err
 	}
 
-	w := NewHDWalletFromWords(m, wallet.BaseCoin)
-	privateKey, err := w.masterPrivateKey.ECPrivKey()
+	ephemeralMasterKey, err := masterPrivateKey(m, wallet.BaseCoin)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := ephemeralMasterKey.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := encrypt(body, privateKey, publicKey)
 	if err != nil {
 		return nil, err
 	}
 
-	return encrypt(body, privateKey, publicKey)
+	return &EphemeralEncryptionResult{
+		Payload:                     payload,
+		EphemeralUncompressedPubkey: hex.EncodeToString(privateKey.PubKey().SerializeUncompressed()),
+		Version:                     int(encryptionPayloadVersion),
+	}, nil
+}
+
+// EncryptWithDeterministicEphemeralKey encrypts a given body to a given compressed or uncompressed public key like
+// `EncryptWithEphemeralKey`, but derives the ephemeral keypair from the signing key (m/42), the recipient's key and
+// a nonce, instead of from entropy. Retrying with the same body and nonce produces an identical payload, so the
+// backend can deduplicate it. Use a unique nonce per message, such as a memo id, as payloads with the same nonce
+// reveal whether their bodies are equal.
+func (wallet *HDWallet) EncryptWithDeterministicEphemeralKey(nonce []byte, body []byte, recipientUncompressedPubkey string) (*EphemeralEncryptionResult, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+	if len(nonce) == 0 {
+		return nil, errors.New("nonce cannot be empty")
+	}
+
+	publicKey, err := parseHexPubKey(recipientUncompressedPubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey, err := wallet.signingPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, iv, err := deterministicEphemeralKey(signingKey, publicKey, nonce, body)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := encryptWithIV(body, privateKey, publicKey, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EphemeralEncryptionResult{
+		Payload:                     payload,
+		EphemeralUncompressedPubkey: hex.EncodeToString(privateKey.PubKey().SerializeUncompressed()),
+		Version:                     int(encryptionPayloadVersion),
+	}, nil
 }
 
 // DecryptWithKeyFromDerivationPath decrypts a given payload with the key derived from given derivation path.
 func (wallet *HDWallet) DecryptWithKeyFromDerivationPath(path *DerivationPath, body []byte) ([]byte, error) {
-	kf := keyFactory{masterPrivateKey: wallet.masterPrivateKey}
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	kf := wallet.keyFactory()
 
 	pk, err := kf.indexPrivateKey(path)
 	if err != nil {
@@ -239,14 +435,53 @@ func (wallet *HDWallet) DecryptWithKeyFromDerivationPath(path *DerivationPath, b
 	return decrypt(body, ecpk)
 }
 
-// EncryptMessage encrypts a payload using signing key (m/42) and recipient's public key.
+// DeriveEncryptionKey returns a 32-byte symmetric key for encrypting app records, such as notes or contact data,
+// derived via HKDF-SHA256 from the private key at the given derivation path. Different labels produce independent keys.
+// The path must be an encryption key path from `NewEncryptionKeyPath`, or ErrKeyRoleMismatch is returned.
+func (wallet *HDWallet) DeriveEncryptionKey(path *DerivationPath, label string) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	if path == nil {
+		return nil, errors.New("derivation path cannot be nil")
+	}
+	if label == "" {
+		return nil, errors.New("label cannot be empty")
+	}
+	if err := requireKeyRole(path, KeyRoleEncryption); err != nil {
+		return nil, err
+	}
+
+	return wallet.deriveEncryptionKey(path, label)
+}
+
+// DeriveLegacyEncryptionKey returns the key `DeriveEncryptionKey` returned for any derivation path before encryption
+// key paths were required, such as a receive address path. Use it only to decrypt records encrypted under such paths,
+// and re-encrypt them with a key from an encryption key path.
+func (wallet *HDWallet) DeriveLegacyEncryptionKey(path *DerivationPath, label string) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	if path == nil {
+		return nil, errors.New("derivation path cannot be nil")
+	}
+	if label == "" {
+		return nil, errors.New("label cannot be empty")
+	}
+
+	return wallet.deriveEncryptionKey(path, label)
+}
+
+// EncryptMessage encrypts a payload using signing key (m/42) and recipient's hex-encoded compressed or uncompressed
+// public key. Returns ErrPublicKeyHex, ErrPublicKeyLength or ErrPublicKeyPoint if the public key is malformed.
 func (wallet *HDWallet) EncryptMessage(body []byte, recipientUncompressedPubkey string) ([]byte, error) {
-	pubkeyBytes, err := hex.DecodeString(recipientUncompressedPubkey)
-	if err != nil {
+	if err := wallet.requirePrivateKeys(); err != nil {
 		return nil, err
 	}
 
-	publicKey, err := btcec.ParsePubKey(pubkeyBytes, btcec.S256())
+	publicKey, err := parseHexPubKey(recipientUncompressedPubkey)
 	if err != nil {
 		return nil, err
 	}
@@ -261,6 +496,10 @@ func (wallet *HDWallet) EncryptMessage(body []byte, recipientUncompressedPubkey
 
 // DecryptMessage decrypts a payload using signing key (m/42) and included sender public key (expected to be last 65 bytes of payload).
 func (wallet *HDWallet) DecryptMessage(body []byte) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
 	signingKey, err := wallet.signingPrivateKey()
 	if err != nil {
 		return nil, err
@@ -270,17 +509,22 @@ func (wallet *HDWallet) DecryptMessage(body []byte) ([]byte, error) {
 }
 
 // ImportPrivateKey accepts an encoded private key from a paper wallet/QR code, decodes it, and returns a ref to an ImportedPrivateKey struct, or error if failed.
+// Returns `ErrPrivateKeyNetwork` if the key is WIF encoded for a network other than the wallet's.
 func (wallet *HDWallet) ImportPrivateKey(encodedKey string) (*ImportedPrivateKey, error) {
 	wif, err := btcutil.DecodeWIF(encodedKey)
 	if err != nil {
 		return nil, err
 	}
+	params := wallet.BaseCoin.defaultNetParams()
+	if !wif.IsForNet(params) {
+		return nil, ErrPrivateKeyNetwork
+	}
 
 	serializedPubkey := wif.SerializePubKey()
 	hash160 := btcutil.Hash160(serializedPubkey)
 
 	// legacy
-	legacy := base58.CheckEncode(hash160, 0)
+	legacy := base58.CheckEncode(hash160, params.PubKeyHashAddrID)
 
 	// legacy segwit
 	ls, err := bip49AddressFromPubkeyHash(hash160, wallet.BaseCoin)
@@ -301,9 +545,11 @@ func (wallet *HDWallet) ImportPrivateKey(encodedKey string) (*ImportedPrivateKey
 	return &retval, nil
 }
 
-// AccountExtendedMasterPublicKey returns the stringified base58 encoded master extended public key.
-func (wallet *HDWallet) AccountExtendedMasterPublicKey() (string, error) {
-	kf := keyFactory{masterPrivateKey: wallet.masterPrivateKey}
+// AccountExtendedPublicKey returns the neutered m/purpose'/coin'/account' extended public key, base58 encoded with
+// the SLIP-132 version bytes of the wallet's BaseCoin: xpub, ypub or zpub for BIP44, BIP49 or BIP84 on mainnet, and
+// tpub, upub or vpub on testnet. Watch-only wallets return the key they were created from, re-encoded for the BaseCoin.
+func (wallet *HDWallet) AccountExtendedPublicKey() (string, error) {
+	kf := wallet.keyFactory()
 	_, pubkeyString, err := kf.accountExtendedPublicKey(wallet.BaseCoin)
 	if err != nil {
 		return "", err
@@ -311,8 +557,19 @@ func (wallet *HDWallet) AccountExtendedMasterPublicKey() (string, error) {
 	return pubkeyString, nil
 }
 
+// AccountExtendedMasterPublicKey returns the stringified base58 encoded account extended public key.
+//
+// Deprecated: use `AccountExtendedPublicKey`.
+func (wallet *HDWallet) AccountExtendedMasterPublicKey() (string, error) {
+	return wallet.AccountExtendedPublicKey()
+}
+
 // BuildTransactionMetadata will generate the tx metadata needed for client to consume.
 func (wallet *HDWallet) BuildTransactionMetadata(data *TransactionData) (*TransactionMetadata, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
 	builder := transactionBuilder{wallet: wallet}
 	return builder.buildTxFromData(data)
 }
@@ -366,6 +623,28 @@ func (wallet *HDWallet) UncompressedPubKeyForPath(path *DerivationPath) ([]byte,
 	return key.SerializeUncompressed(), nil
 }
 
+// ExtendedPublicKeyForPath returns the extended public key at a path, encoded with the xpub or tpub prefix of the
+// wallet's network, such as for sharing a cosigner key of a multisig wallet at a custom path like m/48'/0'/0'/2'.
+// Returns ErrWatchOnlyWallet if the wallet has no private keys.
+func (wallet *HDWallet) ExtendedPublicKeyForPath(path *DerivationPath) (string, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return "", err
+	}
+	if path == nil {
+		return "", errors.New("derivation path cannot be nil")
+	}
+
+	key, err := wallet.keyFactory().indexPrivateKey(path)
+	if err != nil {
+		return "", err
+	}
+	neutered, err := key.Neuter()
+	if err != nil {
+		return "", err
+	}
+	return neutered.String(), nil
+}
+
 /// Unexported functions
 
 func (wallet *HDWallet) publicKey(path *DerivationPath) (*btcec.PublicKey, error) {
@@ -373,7 +652,14 @@ func (wallet *HDWallet) publicKey(path *DerivationPath) (*btcec.PublicKey, error
 		return nil, errors.New("derivation path cannot be nil")
 	}
 
-	keyFactory := keyFactory{masterPrivateKey: wallet.masterPrivateKey}
+	if wallet.masterPrivateKey == nil && wallet.accountPublicKey != nil {
+		if path.IsCustom() {
+			return nil, ErrWatchOnlyWallet
+		}
+		return wallet.accountChildPublicKey(path)
+	}
+
+	keyFactory := wallet.keyFactory()
 	privKey, err := keyFactory.indexPrivateKey(path)
 	if err != nil {
 		return nil, err
@@ -385,16 +671,34 @@ func (wallet *HDWallet) publicKey(path *DerivationPath) (*btcec.PublicKey, error
 	return pubKey, nil
 }
 
-func (wallet *HDWallet) metaAddress(change int, index int) (*MetaAddress, error) {
-	if change < 0 {
-		return nil, errors.New("change index cannot be negative")
+// accountChildPublicKey derives a public key for a path from the account extended public key, for watch-only wallets.
+func (wallet *HDWallet) accountChildPublicKey(path *DerivationPath) (*btcec.PublicKey, error) {
+	if err := path.Validate(); err != nil {
+		return nil, err
 	}
-
-	if index < 0 {
-		return nil, errors.New("index cannot be negative")
+	bc := wallet.BaseCoin
+	if path.Purpose != bc.Purpose || path.Coin != bc.Coin || path.Account != bc.Account {
+		return nil, errors.New("derivation path does not belong to wallet account")
+	}
+	key := derivationCacheKey{purpose: path.Purpose, coin: path.Coin, account: path.Account, change: path.Change, public: true}
+	changeKey, err := wallet.keyCache.chainKey(key, func() (*hdkeychain.ExtendedKey, error) {
+		return wallet.accountPublicKey.Child(uint32(path.Change))
+	})
+	if err != nil {
+		return nil, err
 	}
+	indexKey, err := changeKey.Child(uint32(path.Index))
+	if err != nil {
+		return nil, err
+	}
+	return indexKey.ECPubKey()
+}
 
+func (wallet *HDWallet) metaAddress(change int, index int) (*MetaAddress, error) {
 	path := NewDerivationPath(wallet.BaseCoin, change, index)
+	if err := path.Validate(); err != nil {
+		return nil, err
+	}
 
 	ua, err := newUsableAddressWithDerivationPath(wallet, path)
 	if err != nil {
@@ -404,12 +708,33 @@ func (wallet *HDWallet) metaAddress(change int, index int) (*MetaAddress, error)
 	return ua.MetaAddress()
 }
 
+func validateEntropyLength(entropy []byte) error {
+	size := len(entropy)
+	if size < 16 || size > 32 || size%4 != 0 {
+		return errors.New("entropy must be 16 to 32 bytes, in 4 byte increments")
+	}
+	return nil
+}
+
 func hardened(i int) uint32 {
 	return hdkeychain.HardenedKeyStart + uint32(i)
 }
 
 func masterPrivateKey(wordString string, basecoin *BaseCoin) (*hdkeychain.ExtendedKey, error) {
-	seed := bip39.NewSeed(wordString, "")
+	return masterPrivateKeyFromSeed(mnemonicSeed(wordString), basecoin)
+}
+
+func newHDWalletFromMasterKey(masterKey *hdkeychain.ExtendedKey, basecoin *BaseCoin) (*HDWallet, error) {
+	kf := keyFactory{masterPrivateKey: masterKey}
+	pubkey, _, err := kf.accountExtendedPublicKey(basecoin)
+	if err != nil {
+		return nil, err
+	}
+	wallet := HDWallet{BaseCoin: basecoin, masterPrivateKey: masterKey, accountPublicKey: pubkey, keyCache: newDerivationCache()}
+	return &wallet, nil
+}
+
+func masterPrivateKeyFromSeed(seed []byte, basecoin *BaseCoin) (*hdkeychain.ExtendedKey, error) {
 	defaultNet := basecoin.defaultNetParams()
 	masterKey, err := hdkeychain.NewMaster(seed, defaultNet)
 	if err != nil {
@@ -419,7 +744,7 @@ func masterPrivateKey(wordString string, basecoin *BaseCoin) (*hdkeychain.Extend
 }
 
 func (wallet *HDWallet) signingPrivateKey() (*btcec.PrivateKey, error) {
-	kf := keyFactory{masterPrivateKey: wallet.masterPrivateKey}
+	kf := wallet.keyFactory()
 
 	smk, err := kf.signingMasterKey()
 	if err != nil {
@@ -433,3 +758,33 @@ func (wallet *HDWallet) signingPrivateKey() (*btcec.PrivateKey, error) {
 
 	return ec, nil
 }
+
+// checkAccountKeyVersion returns nil if extended public key version bytes are of the BaseCoin's network and purpose,
+// or are the network's generic xpub or tpub, which wallets also export for purposes without their own version.
+func checkAccountKeyVersion(version []byte, basecoin *BaseCoin) error {
+	types := basecoin.network().extendedPubkeyTypes
+	if bytes.Equal(version, pubkeyIDs[types[basecoin.Purpose]]) || bytes.Equal(version, pubkeyIDs[types[bip44purpose]]) {
+		return nil
+	}
+	for _, idType := range types {
+		if bytes.Equal(version, pubkeyIDs[idType]) {
+			return ErrExtendedKeyPurpose
+		}
+	}
+	return ErrExtendedKeyNetwork
+}
+
+func (wallet *HDWallet) deriveEncryptionKey(path *DerivationPath, label string) ([]byte, error) {
+	kf := wallet.keyFactory()
+	pk, err := kf.indexPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ecpk, err := pk.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return deriveRecordKey(ecpk, label)
+}
@@ -8,15 +8,41 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/hex"
 	"errors"
+	"io"
+	"math/big"
 
 	"github.com/btcsuite/btcd/btcec"
+	"golang.org/x/crypto/hkdf"
 )
 
-const minPayloadSize = 131
+const (
+	minPayloadSize           = 131
+	encryptionPayloadVersion = byte(3)
+)
+
+const (
+	recordKeySize = 32
+	recordKeySalt = "cnlib record encryption key"
+)
+
+const deterministicEphemeralKeySalt = "cnlib deterministic ephemeral key"
+
+// Following errors are returned for malformed hex-encoded public keys.
+var (
+	ErrPublicKeyHex    = errors.New("public key is not valid hex")
+	ErrPublicKeyLength = errors.New("public key must be 33 bytes compressed or 65 bytes uncompressed")
+	ErrPublicKeyPoint  = errors.New("public key is not a valid secp256k1 point")
+)
 
 // decrypt data using public/private keypair
 func decrypt(data []byte, privateKey *btcec.PrivateKey) ([]byte, error) {
+	return decryptWithAssociatedData(data, privateKey, nil)
+}
+
+// decryptWithAssociatedData decrypts data as `decrypt` does, verifying its hmac covers the given associated data too.
+func decryptWithAssociatedData(data []byte, privateKey *btcec.PrivateKey, associatedData []byte) ([]byte, error) {
 
 	if len(data) < minPayloadSize {
 		return nil, errors.New("insufficient data")
@@ -51,7 +77,7 @@ func decrypt(data []byte, privateKey *btcec.PrivateKey) ([]byte, error) {
 	hmacKey := keyData[32:]
 
 	testHmac := hmac.New(sha256.New, hmacKey)
-	_, err = testHmac.Write(msg)
+	_, err = testHmac.Write(append(append([]byte{}, associatedData...), msg...))
 	if err != nil {
 		return nil, errors.New("failed to write testHmac")
 	}
@@ -73,30 +99,37 @@ func decrypt(data []byte, privateKey *btcec.PrivateKey) ([]byte, error) {
 	decrypter := cipher.NewCBCDecrypter(cipherBlock, iv)
 	decrypter.CryptBlocks(decrypted, decrypted)
 
-	// un-padd decrypted data
-	length := len(decrypted)
-	unpadding := int(decrypted[length-1])
-
-	return decrypted[:(length - unpadding)], nil
+	return unpadPKCS7(decrypted)
 }
 
 // encrypt Data using public/private keypair
 func encrypt(data []byte, privateKey *btcec.PrivateKey, publicKey *btcec.PublicKey) ([]byte, error) {
+	iv, err := randBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	return encryptWithIV(data, privateKey, publicKey, iv)
+}
+
+// encryptWithIV encrypts data as `encrypt` does, with a given IV. The IV must not be reused with the same keypair
+// for different data.
+func encryptWithIV(data []byte, privateKey *btcec.PrivateKey, publicKey *btcec.PublicKey, iv []byte) ([]byte, error) {
+	return encryptWithAssociatedData(data, privateKey, publicKey, iv, nil)
+}
+
+// encryptWithAssociatedData encrypts data as `encryptWithIV` does, with an hmac which also covers the given associated
+// data, such as the header of an encryption envelope, so it cannot be altered or stripped undetected.
+func encryptWithAssociatedData(data []byte, privateKey *btcec.PrivateKey, publicKey *btcec.PublicKey, iv []byte, associatedData []byte) ([]byte, error) {
 
 	secret := generateSharedSecretRFC4753(privateKey, publicKey)
 	keyData := sha512.Sum512(secret)
 	encKey := keyData[:32]
 	hmacKey := keyData[32:]
 
-	iv, err := randBytes(16)
-	if err != nil {
-		return nil, err
-	}
-
 	cipherText := make([]byte, len(data))
 	copy(cipherText, data)
 
-	version := byte(3)
+	version := encryptionPayloadVersion
 	options := byte(0) // No Password, No HMAC Salt, No Enryption Salt
 
 	msg := make([]byte, 0)
@@ -121,7 +154,7 @@ func encrypt(data []byte, privateKey *btcec.PrivateKey, publicKey *btcec.PublicK
 	msg = append(msg, cipherText...)
 
 	hmacSrc := hmac.New(sha256.New, hmacKey)
-	_, err = hmacSrc.Write(msg)
+	_, err = hmacSrc.Write(append(append([]byte{}, associatedData...), msg...))
 	if err != nil {
 		return nil, errors.New("failed to write hmacSrc")
 	}
@@ -134,6 +167,73 @@ func encrypt(data []byte, privateKey *btcec.PrivateKey, publicKey *btcec.PublicK
 	return msg, nil
 }
 
+// deriveRecordKey expands a private key into an independent symmetric key for the given label using HKDF-SHA256.
+func deriveRecordKey(privateKey *btcec.PrivateKey, label string) ([]byte, error) {
+	reader := hkdf.New(sha256.New, privateKey.Serialize(), []byte(recordKeySalt), []byte(label))
+	key := make([]byte, recordKeySize)
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// deterministicEphemeralKey derives an ephemeral private key from the sender's private key, the recipient's public
+// key and a message nonce via HKDF-SHA256, and an IV which also commits to the data, so encrypting the same data with
+// the same nonce produces identical payloads, while different data gets a different IV.
+func deterministicEphemeralKey(privateKey *btcec.PrivateKey, publicKey *btcec.PublicKey, nonce []byte, data []byte) (*btcec.PrivateKey, []byte, error) {
+	info := append(publicKey.SerializeCompressed(), nonce...)
+	reader := hkdf.New(sha256.New, privateKey.Serialize(), []byte(deterministicEphemeralKeySalt), info)
+
+	// retry with the next output in the negligible case the candidate is not a valid private key
+	curveOrder := btcec.S256().N
+	for {
+		candidate := make([]byte, 32)
+		if _, err := io.ReadFull(reader, candidate); err != nil {
+			return nil, nil, err
+		}
+		d := new(big.Int).SetBytes(candidate)
+		if d.Sign() == 0 || d.Cmp(curveOrder) >= 0 {
+			continue
+		}
+		ephemeral, _ := btcec.PrivKeyFromBytes(btcec.S256(), candidate)
+
+		ivSrc := hmac.New(sha256.New, candidate)
+		if _, err := ivSrc.Write(data); err != nil {
+			return nil, nil, err
+		}
+		return ephemeral, ivSrc.Sum(nil)[:16], nil
+	}
+}
+
+// parseHexPubKey strictly parses a hex-encoded compressed or uncompressed secp256k1 public key. Hybrid encodings are
+// rejected.
+func parseHexPubKey(encoded string) (*btcec.PublicKey, error) {
+	keyBytes, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrPublicKeyHex
+	}
+
+	switch len(keyBytes) {
+	case btcec.PubKeyBytesLenCompressed:
+		if keyBytes[0] != 0x02 && keyBytes[0] != 0x03 {
+			return nil, ErrPublicKeyPoint
+		}
+	case btcec.PubKeyBytesLenUncompressed:
+		if keyBytes[0] != 0x04 {
+			return nil, ErrPublicKeyPoint
+		}
+	default:
+		return nil, ErrPublicKeyLength
+	}
+
+	// compressed keys are not range or curve checked when parsed
+	publicKey, err := btcec.ParsePubKey(keyBytes, btcec.S256())
+	if err != nil || publicKey.X.Cmp(btcec.S256().P) >= 0 || !btcec.S256().IsOnCurve(publicKey.X, publicKey.Y) {
+		return nil, ErrPublicKeyPoint
+	}
+	return publicKey, nil
+}
+
 func randBytes(num int64) ([]byte, error) {
 	bits := make([]byte, num)
 	_, err := rand.Read(bits)
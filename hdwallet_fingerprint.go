@@ -0,0 +1,120 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// ErrMasterFingerprintUnknown describes an error in which a watch-only wallet was asked for a master fingerprint it
+// was never given.
+var ErrMasterFingerprintUnknown = errors.New("master fingerprint unknown")
+
+/// Receiver functions
+
+// Fingerprint returns the BIP32 fingerprint of the wallet's master key, the first 4 bytes of the hash160 of its
+// public key, as hex. Devices sharing a seed report the same fingerprint. Watch-only wallets return
+// ErrMasterFingerprintUnknown unless it was set with `SetMasterFingerprint`.
+func (wallet *HDWallet) Fingerprint() (string, error) {
+	fingerprint, err := wallet.masterFingerprintBytes()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(fingerprint), nil
+}
+
+// MasterFingerprint returns the wallet's master fingerprint as `Fingerprint` does, under the name used by PSBT and
+// descriptor tooling.
+func (wallet *HDWallet) MasterFingerprint() (string, error) {
+	return wallet.Fingerprint()
+}
+
+// KeyOriginForPath returns the key origin of the key at a path, as written in output descriptors and held by PSBT
+// BIP32 derivation fields, such as "[73c5da0a/84'/0'/0'/0/5]". Paths may be standard or custom. Returns
+// ErrMasterFingerprintUnknown for watch-only wallets without a master fingerprint, or error if the path is invalid.
+func (wallet *HDWallet) KeyOriginForPath(path *DerivationPath) (string, error) {
+	if path == nil {
+		return "", errors.New("derivation path cannot be nil")
+	}
+	if !path.IsCustom() {
+		if err := path.Validate(); err != nil {
+			return "", err
+		}
+	}
+	fingerprint, err := wallet.Fingerprint()
+	if err != nil {
+		return "", err
+	}
+	return "[" + fingerprint + strings.TrimPrefix(path.String(), "m") + "]", nil
+}
+
+// SetMasterFingerprint records the hex master fingerprint of a watch-only wallet, which cannot derive it from an
+// account extended public key, e.g. as reported by its paired cold wallet. Returns error if the fingerprint is not 4
+// bytes, or the wallet holds a master key with a different fingerprint.
+func (wallet *HDWallet) SetMasterFingerprint(fingerprint string) error {
+	decoded, err := hex.DecodeString(fingerprint)
+	if err != nil {
+		return err
+	}
+	if len(decoded) != 4 {
+		return errors.New("master fingerprint must be 4 bytes")
+	}
+	if wallet.masterPrivateKey != nil {
+		own, err := wallet.masterFingerprintBytes()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(own, decoded) {
+			return errors.New("master fingerprint does not match wallet")
+		}
+	}
+	wallet.masterFingerprint = decoded
+	return nil
+}
+
+/// Unexported functions
+
+func (wallet *HDWallet) masterFingerprintBytes() ([]byte, error) {
+	if wallet.masterPrivateKey == nil {
+		if wallet.masterFingerprint == nil {
+			return nil, ErrMasterFingerprintUnknown
+		}
+		return wallet.masterFingerprint, nil
+	}
+	pubkey, err := wallet.masterPrivateKey.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return btcutil.Hash160(pubkey.SerializeCompressed())[:4], nil
+}
+
+// psbtMasterFingerprint returns the master fingerprint as serialized in PSBT key origins, a little-endian uint32.
+// Returns false if the fingerprint is unknown.
+func (wallet *HDWallet) psbtMasterFingerprint() (uint32, bool, error) {
+	fingerprint, err := wallet.masterFingerprintBytes()
+	if err == ErrMasterFingerprintUnknown {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return binary.LittleEndian.Uint32(fingerprint), true, nil
+}
+
+// bip32Path returns the full path from the master key, with hardened purpose, coin and account levels.
+func (path *DerivationPath) bip32Path() []uint32 {
+	if path.IsCustom() {
+		return append([]uint32(nil), path.components...)
+	}
+	return []uint32{
+		hardened(path.Purpose),
+		hardened(path.Coin),
+		hardened(path.Account),
+		uint32(path.Change),
+		uint32(path.Index),
+	}
+}
@@ -0,0 +1,63 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHDWallet_Fingerprint(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	fingerprint, err := wallet.Fingerprint()
+	assert.Nil(t, err)
+	assert.Equal(t, "73c5da0a", fingerprint)
+
+	accountKey, _ := wallet.AccountExtendedPublicKey()
+	watchOnly, err := NewHDWalletFromAccountExtendedPublicKey(accountKey)
+	assert.Nil(t, err)
+	_, err = watchOnly.Fingerprint()
+	assert.Equal(t, ErrMasterFingerprintUnknown, err)
+}
+
+func TestHDWallet_MasterFingerprint(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	fingerprint, err := wallet.MasterFingerprint()
+	assert.Nil(t, err)
+	assert.Equal(t, "73c5da0a", fingerprint)
+
+	accountKey, _ := wallet.AccountExtendedPublicKey()
+	watchOnly, _ := NewHDWalletFromAccountExtendedPublicKey(accountKey)
+	_, err = watchOnly.MasterFingerprint()
+	assert.Equal(t, ErrMasterFingerprintUnknown, err)
+	assert.Nil(t, watchOnly.SetMasterFingerprint("73c5da0a"))
+	fingerprint, err = watchOnly.MasterFingerprint()
+	assert.Nil(t, err)
+	assert.Equal(t, "73c5da0a", fingerprint)
+}
+
+func TestHDWallet_KeyOriginForPath(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+
+	origin, err := wallet.KeyOriginForPath(NewDerivationPath(BaseCoinBip84MainNet, 0, 5))
+	assert.Nil(t, err)
+	assert.Equal(t, "[73c5da0a/84'/0'/0'/0/5]", origin)
+
+	custom, _ := ParseCustomDerivationPath("m/48h/0h/0h/2h")
+	origin, err = wallet.KeyOriginForPath(custom)
+	assert.Nil(t, err)
+	assert.Equal(t, "[73c5da0a/48'/0'/0'/2']", origin)
+
+	_, err = wallet.KeyOriginForPath(NewDerivationPath(BaseCoinBip84MainNet, 0, -1))
+	assert.Equal(t, ErrDerivationIndexNegative, err)
+	_, err = wallet.KeyOriginForPath(nil)
+	assert.NotNil(t, err)
+
+	accountKey, _ := wallet.AccountExtendedPublicKey()
+	watchOnly, _ := NewHDWalletFromAccountExtendedPublicKey(accountKey)
+	_, err = watchOnly.KeyOriginForPath(NewDerivationPath(BaseCoinBip84MainNet, 0, 5))
+	assert.Equal(t, ErrMasterFingerprintUnknown, err)
+	assert.Nil(t, watchOnly.SetMasterFingerprint("73c5da0a"))
+	origin, err = watchOnly.KeyOriginForPath(NewDerivationPath(BaseCoinBip84MainNet, 1, 2))
+	assert.Nil(t, err)
+	assert.Equal(t, "[73c5da0a/84'/0'/0'/1/2]", origin)
+}
@@ -0,0 +1,52 @@
+package cnlib
+
+import "errors"
+
+// Following constants are used for HDWallet roles.
+const (
+	WalletRoleStandard int = 0 // holds private keys, and signs and broadcasts its own transactions
+	WalletRoleHot      int = 1 // watch-only, holds account extended public keys; spends are exported as PSBTs
+	WalletRoleCold     int = 2 // offline signer paired with a hot wallet; signs PSBTs
+)
+
+// ErrWatchOnlyWallet describes an error in which the caller used an API requiring private keys on a watch-only wallet.
+var ErrWatchOnlyWallet = errors.New("operation requires private keys, unavailable in watch-only mode")
+
+/// Receiver functions
+
+// Role returns the wallet's role, `WalletRoleStandard` (0), `WalletRoleHot` (1), or `WalletRoleCold` (2).
+func (wallet *HDWallet) Role() int {
+	return wallet.role
+}
+
+// IsWatchOnly returns true if the wallet cannot use private keys, either because it was created from an account
+// extended public key or because it has been set to `WalletRoleHot`.
+func (wallet *HDWallet) IsWatchOnly() bool {
+	return wallet.role == WalletRoleHot || wallet.masterPrivateKey == nil
+}
+
+// SetRole updates the wallet's role. Setting `WalletRoleHot` disables all APIs which use private keys, even if the
+// wallet was created from words. Returns error if the role is unknown, or a wallet without private keys is set to a
+// role which requires them.
+func (wallet *HDWallet) SetRole(role int) error {
+	switch role {
+	case WalletRoleHot:
+	case WalletRoleStandard, WalletRoleCold:
+		if wallet.masterPrivateKey == nil {
+			return ErrWatchOnlyWallet
+		}
+	default:
+		return errors.New("invalid wallet role")
+	}
+	wallet.role = role
+	return nil
+}
+
+/// Unexported functions
+
+func (wallet *HDWallet) requirePrivateKeys() error {
+	if wallet.IsWatchOnly() {
+		return ErrWatchOnlyWallet
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package cnlib
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
@@ -146,6 +147,38 @@ func TestChangeAddressForIndex_InvalidIndex(t *testing.T) {
 	assert.Nil(t, ma)
 }
 
+func TestReceiveAddressForIndex_OverflowingIndex_ReturnsError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+
+	// 2^31 would otherwise derive the hardened key at index 0
+	ma, err := wallet.ReceiveAddressForIndex(1 << 31)
+	assert.Equal(t, ErrDerivationIndexOverflow, err)
+	assert.Nil(t, ma)
+
+	ma, err = wallet.ReceiveAddressForIndex(1<<31 - 1)
+	assert.Nil(t, err)
+	assert.NotNil(t, ma)
+}
+
+func TestDerivationPathValidate(t *testing.T) {
+	assert.Nil(t, NewDerivationPath(BaseCoinBip84MainNet, 1, 5).Validate())
+	assert.Equal(t, ErrDerivationPathMissingBaseCoin, NewDerivationPath(nil, 0, 0).Validate())
+	assert.Equal(t, ErrDerivationIndexNegative, NewDerivationPath(BaseCoinBip84MainNet, -1, 0).Validate())
+	assert.Equal(t, ErrDerivationIndexOverflow, NewDerivationPath(BaseCoinBip84MainNet, 0, 1<<32).Validate())
+	assert.Equal(t, ErrDerivationIndexOverflow, NewDerivationPath(NewBaseCoin(84, 0, 1<<31), 0, 0).Validate())
+
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	_, err := wallet.CompressedPubKeyForPath(NewDerivationPath(BaseCoinBip84MainNet, 0, -5))
+	assert.Equal(t, ErrDerivationIndexNegative, err)
+	_, err = wallet.CompressedPubKeyForPath(NewDerivationPath(NewBaseCoin(-84, 0, 0), 0, 0))
+	assert.Equal(t, ErrDerivationIndexNegative, err)
+
+	watchOnly, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	_, err = watchOnly.CompressedPubKeyForPath(NewDerivationPath(BaseCoinBip84MainNet, 0, 1<<31))
+	assert.Equal(t, ErrDerivationIndexOverflow, err)
+}
+
 func TestUpdateCoin(t *testing.T) {
 	wallet := NewHDWalletFromWords(w, BaseCoinBip49MainNet)
 	expAddr1 := "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"
@@ -201,8 +234,13 @@ func TestEncyptWithEphemeralKey(t *testing.T) {
 
 	assert.Equal(t, 130, len(bobUCPK))
 
-	enc, encErr := aliceWallet.EncryptWithEphemeralKey(entropy, message, bobUCPK)
+	result, encErr := aliceWallet.EncryptWithEphemeralKey(entropy, message, bobUCPK)
 	assert.Nil(t, encErr)
+	assert.Equal(t, 3, result.Version)
+
+	// ephemeral pubkey is appended to the payload
+	enc := result.Payload
+	assert.Equal(t, result.EphemeralUncompressedPubkey, hex.EncodeToString(enc[len(enc)-65:]))
 
 	bobPath := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
 	dec, err := bobWallet.DecryptWithKeyFromDerivationPath(bobPath, enc)
@@ -212,6 +250,88 @@ func TestEncyptWithEphemeralKey(t *testing.T) {
 	assert.Equal(t, messageString, decryptedString)
 }
 
+func TestEncyptWithEphemeralKey_InvalidEntropy_ReturnsError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	recipient, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+
+	for _, size := range []int{0, 15, 17, 36} {
+		result, err := wallet.EncryptWithEphemeralKey(make([]byte, size), []byte("hey dude"), recipient.UncompressedPublicKey)
+		assert.NotNil(t, err)
+		assert.Nil(t, result)
+	}
+}
+
+func TestEncryptWithDeterministicEphemeralKey_RetriesAreIdentical(t *testing.T) {
+	aliceWallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	bobWallet := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	bobAddr, err := bobWallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	nonce := []byte("memo-1")
+
+	first, err := aliceWallet.EncryptWithDeterministicEphemeralKey(nonce, []byte("hey dude"), bobAddr.UncompressedPublicKey)
+	assert.Nil(t, err)
+	retry, err := aliceWallet.EncryptWithDeterministicEphemeralKey(nonce, []byte("hey dude"), bobAddr.UncompressedPublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Payload, retry.Payload)
+	assert.Equal(t, first.EphemeralUncompressedPubkey, retry.EphemeralUncompressedPubkey)
+
+	other, err := aliceWallet.EncryptWithDeterministicEphemeralKey([]byte("memo-2"), []byte("hey dude"), bobAddr.UncompressedPublicKey)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.EphemeralUncompressedPubkey, other.EphemeralUncompressedPubkey)
+
+	// same nonce with a different body still uses a different IV
+	changed, err := aliceWallet.EncryptWithDeterministicEphemeralKey(nonce, []byte("hey dude!"), bobAddr.UncompressedPublicKey)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.Payload[2:18], changed.Payload[2:18])
+
+	dec, err := bobWallet.DecryptWithKeyFromDerivationPath(NewDerivationPath(BaseCoinBip84MainNet, 0, 0), first.Payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "hey dude", string(dec))
+
+	_, err = aliceWallet.EncryptWithDeterministicEphemeralKey(nil, []byte("hey dude"), bobAddr.UncompressedPublicKey)
+	assert.EqualError(t, err, "nonce cannot be empty")
+}
+
+func TestEncryptWithEphemeralKey_CompressedRecipientKey(t *testing.T) {
+	aliceWallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	bobWallet := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	bobPath := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	bobCPK, err := bobWallet.CompressedPubKeyForPath(bobPath)
+	assert.Nil(t, err)
+	entropy := bytes.Repeat([]byte{1}, 16)
+
+	result, err := aliceWallet.EncryptWithEphemeralKey(entropy, []byte("hey dude"), hex.EncodeToString(bobCPK))
+	assert.Nil(t, err)
+
+	dec, err := bobWallet.DecryptWithKeyFromDerivationPath(bobPath, result.Payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "hey dude", string(dec))
+}
+
+func TestEncryptWithEphemeralKey_MalformedRecipientKey_ReturnsTypedError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	recipient, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	entropy := bytes.Repeat([]byte{1}, 16)
+	uncompressed := recipient.UncompressedPublicKey
+
+	cases := map[string]error{
+		uncompressed[:len(uncompressed)-1]:        ErrPublicKeyHex,
+		"zz" + uncompressed[2:]:                   ErrPublicKeyHex,
+		uncompressed[:64]:                         ErrPublicKeyLength,
+		"06" + uncompressed[2:]:                   ErrPublicKeyPoint,
+		"02" + strings.Repeat("ff", 32):           ErrPublicKeyPoint,
+		uncompressed[:len(uncompressed)-2] + "00": ErrPublicKeyPoint,
+	}
+	for key, expected := range cases {
+		_, err := wallet.EncryptWithEphemeralKey(entropy, []byte("hey dude"), key)
+		assert.Equal(t, expected, err, key)
+		_, err = wallet.EncryptMessage([]byte("hey dude"), key)
+		assert.Equal(t, expected, err, key)
+	}
+}
+
 func TestEncryptionWithDefaultKeysEndToEnd(t *testing.T) {
 	aliceWords := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
 	bobWords := "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong"
@@ -384,6 +504,20 @@ func TestExtendedAccountPublicKey_BIP84(t *testing.T) {
 	assert.Equal(t, expectedKey, actualKey)
 }
 
+func TestAccountExtendedPublicKey_WatchOnlyRoundTrip(t *testing.T) {
+	zpub := "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	actualKey, err := wallet.AccountExtendedPublicKey()
+	assert.Nil(t, err)
+	assert.Equal(t, zpub, actualKey)
+
+	watchOnly, err := NewHDWalletFromAccountExtendedPublicKey(actualKey)
+	assert.Nil(t, err)
+	watchOnlyKey, err := watchOnly.AccountExtendedPublicKey()
+	assert.Nil(t, err)
+	assert.Equal(t, zpub, watchOnlyKey)
+}
+
 func TestExtendedAccountPublicKey_BIP44_Testnet(t *testing.T) {
 	bc := NewBaseCoin(44, 1, 0)
 	wallet := NewHDWalletFromWords(w, bc)
@@ -554,3 +688,150 @@ func TestChangeAddressForIndex_AccountPubKey_M_84_0_1(t *testing.T) {
 	addr := meta.Address
 	assert.Equal(t, expectedAddr, addr)
 }
+
+func TestDeriveEncryptionKey(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	path, err := NewEncryptionKeyPath(0)
+	assert.Nil(t, err)
+
+	notesKey, err := wallet.DeriveEncryptionKey(path, "notes")
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(notesKey))
+
+	again, err := wallet.DeriveEncryptionKey(path, "notes")
+	assert.Nil(t, err)
+	assert.Equal(t, notesKey, again)
+
+	contactsKey, err := wallet.DeriveEncryptionKey(path, "contacts")
+	assert.Nil(t, err)
+	assert.NotEqual(t, notesKey, contactsKey)
+
+	otherPath, _ := NewEncryptionKeyPath(1)
+	otherPathKey, err := wallet.DeriveEncryptionKey(otherPath, "notes")
+	assert.Nil(t, err)
+	assert.NotEqual(t, notesKey, otherPathKey)
+
+	_, err = wallet.DeriveEncryptionKey(NewDerivationPath(BaseCoinBip84MainNet, 0, 0), "notes")
+	assert.Equal(t, ErrKeyRoleMismatch, err)
+
+	_, err = wallet.DeriveEncryptionKey(path, "")
+	assert.NotNil(t, err)
+}
+
+func TestDeriveLegacyEncryptionKey(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+
+	// the key records were encrypted with before encryption key paths were required
+	legacyKey, err := wallet.DeriveLegacyEncryptionKey(path, "notes")
+	assert.Nil(t, err)
+	assert.Equal(t, "963e9d4696ef9264fabb1aadd449f2480d3d9f4fc705a2b433db209138cf1e1e", hex.EncodeToString(legacyKey))
+
+	encryptionPath, _ := NewEncryptionKeyPath(0)
+	key, err := wallet.DeriveEncryptionKey(encryptionPath, "notes")
+	assert.Nil(t, err)
+	sameKey, err := wallet.DeriveLegacyEncryptionKey(encryptionPath, "notes")
+	assert.Nil(t, err)
+	assert.Equal(t, key, sameKey)
+
+	_, err = wallet.DeriveLegacyEncryptionKey(path, "")
+	assert.NotNil(t, err)
+	_, err = wallet.DeriveLegacyEncryptionKey(nil, "notes")
+	assert.NotNil(t, err)
+}
+
+func TestNewWatchOnlyWallet(t *testing.T) {
+	zpub := "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	wallet, err := NewWatchOnlyWallet(zpub, BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	assert.True(t, wallet.IsWatchOnly())
+
+	receive, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", receive.Address)
+	change, err := wallet.ChangeAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el", change.Address)
+
+	meta, err := wallet.CheckForAddress("bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, meta.DerivationPath.Change)
+	assert.Equal(t, 0, meta.DerivationPath.Index)
+
+	_, err = wallet.SignData([]byte("message"))
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+
+	// a generic xpub derives addresses of the basecoin's purpose
+	xpub := "xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V"
+	generic, err := NewWatchOnlyWallet(xpub, BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	receive, err = generic.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", receive.Address)
+
+	_, err = NewWatchOnlyWallet(zpub, BaseCoinBip49MainNet)
+	assert.Equal(t, ErrExtendedKeyPurpose, err)
+	_, err = NewWatchOnlyWallet(zpub, BaseCoinBip84TestNet)
+	assert.Equal(t, ErrExtendedKeyNetwork, err)
+
+	words := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	_, err = NewWatchOnlyWallet(words.masterPrivateKey.String(), BaseCoinBip84MainNet)
+	assert.Equal(t, ErrExtendedKeyNotPublic, err)
+
+	chain, err := generic.accountPublicKey.Child(0)
+	assert.Nil(t, err)
+	_, err = NewWatchOnlyWallet(chain.String(), BaseCoinBip84MainNet)
+	assert.Equal(t, ErrExtendedKeyNotAccount, err)
+}
+
+func TestNewHDWalletFromSeed(t *testing.T) {
+	expected := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	expectedAddress, err := expected.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+
+	wallet, err := NewHDWalletFromSeed(mnemonicSeed(w), BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	assert.Equal(t, "", wallet.WalletWords)
+	address, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedAddress.Address, address.Address)
+
+	backup, err := wallet.ExportShamirShares(1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, backup.ShareCount())
+
+	_, err = NewHDWalletFromSeed(make([]byte, 15), BaseCoinBip84MainNet)
+	assert.NotNil(t, err)
+}
+
+func TestNewHDWalletFromExtendedKey(t *testing.T) {
+	expected := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	expectedAddress, err := expected.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+
+	xprv := expected.masterPrivateKey.String()
+	wallet, err := NewHDWalletFromExtendedKey(xprv, BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	address, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedAddress.Address, address.Address)
+
+	_, err = wallet.ExportShamirShares(1, 1)
+	assert.NotNil(t, err)
+
+	_, err = NewHDWalletFromExtendedKey(xprv, BaseCoinBip84TestNet)
+	assert.Equal(t, ErrExtendedKeyNetwork, err)
+
+	xpub, err := expected.masterPrivateKey.Neuter()
+	assert.Nil(t, err)
+	_, err = NewHDWalletFromExtendedKey(xpub.String(), BaseCoinBip84MainNet)
+	assert.Equal(t, ErrExtendedKeyNotPrivate, err)
+
+	child, err := expected.masterPrivateKey.Child(hardened(0))
+	assert.Nil(t, err)
+	_, err = NewHDWalletFromExtendedKey(child.String(), BaseCoinBip84MainNet)
+	assert.Equal(t, ErrExtendedKeyNotMaster, err)
+
+	_, err = NewHDWalletFromExtendedKey("not a key", BaseCoinBip84MainNet)
+	assert.NotNil(t, err)
+}
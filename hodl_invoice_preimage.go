@@ -0,0 +1,135 @@
+package cnlib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// hodlPreimageStateVersion 2 moved preimages from the BIP85 HEX application, whose entropy other apps may be given,
+// to their own. Version 1 states restore preimages from the HEX application.
+const (
+	hodlPreimageStateVersion       = 2
+	hodlPreimageLegacyStateVersion = 1
+	hodlPreimageLength             = 32
+	bip85ApplicationHodlPreimage   = 4635 // "hodl" on a phone keypad; not a BIP85 standard application
+)
+
+// Following errors are returned when a hodl invoice preimage cannot be regenerated.
+var (
+	ErrHodlPreimageNotFound = errors.New("no preimage up to the given index matches the payment hash")
+	ErrHodlPreimageMismatch = errors.New("preimage at the stored index does not match the stored payment hash")
+)
+
+/// Type Definitions
+
+// HodlInvoicePreimage is a preimage, and its payment hash, for a hold invoice: one the app settles or cancels itself
+// after the payer's HTLC arrives. Preimages are derived from the wallet's seed by index, so they can be regenerated
+// after reinstall from the index alone.
+type HodlInvoicePreimage struct {
+	Index       int
+	Preimage    string // hex-encoded 32 bytes; reveal only to settle the invoice
+	PaymentHash string // hex-encoded SHA256 of the preimage, used to create the invoice
+	legacy      bool   // derived from the BIP85 HEX application
+}
+
+// hodlPreimageState is the serialized form of a HodlInvoicePreimage. It omits the preimage, so the stored record
+// cannot settle an invoice without the wallet.
+type hodlPreimageState struct {
+	Version     int    `json:"version"`
+	Index       int    `json:"index"`
+	PaymentHash string `json:"payment_hash"`
+}
+
+/// Receiver functions
+
+// HodlPreimageForIndex returns the hold invoice preimage at a given index, the first 32 bytes of the wallet's BIP85
+// entropy at m/83696968'/4635'/index', an application of its own so that BIP85 HEX entropy given to other apps cannot
+// settle the wallet's invoices. Returns error if the wallet is watch-only or the index is out of range.
+func (wallet *HDWallet) HodlPreimageForIndex(index int) (*HodlInvoicePreimage, error) {
+	return wallet.hodlPreimage(index, false)
+}
+
+// FindHodlPreimage returns the hold invoice preimage for a hex-encoded payment hash, searching indexes 0 through upTo,
+// for when only the invoice survives a reinstall. Preimages of invoices created before preimages had their own BIP85
+// application are also found. Returns `ErrHodlPreimageNotFound` if none matches.
+func (wallet *HDWallet) FindHodlPreimage(paymentHash string, upTo int) (*HodlInvoicePreimage, error) {
+	if err := validateDerivationIndex(upTo); err != nil {
+		return nil, err
+	}
+	paymentHash = strings.ToLower(paymentHash)
+	for _, legacy := range []bool{false, true} {
+		for i := 0; i <= upTo; i++ {
+			preimage, err := wallet.hodlPreimage(i, legacy)
+			if err != nil {
+				return nil, err
+			}
+			if preimage.PaymentHash == paymentHash {
+				return preimage, nil
+			}
+		}
+	}
+	return nil, ErrHodlPreimageNotFound
+}
+
+// RestoreHodlPreimage regenerates the hold invoice preimage from a string returned by `SerializedState`. Returns
+// `ErrHodlPreimageMismatch` if the state was serialized by a wallet with a different seed.
+func (wallet *HDWallet) RestoreHodlPreimage(serializedState string) (*HodlInvoicePreimage, error) {
+	var state hodlPreimageState
+	if err := json.Unmarshal([]byte(serializedState), &state); err != nil {
+		return nil, err
+	}
+	if state.Version != hodlPreimageStateVersion && state.Version != hodlPreimageLegacyStateVersion {
+		return nil, errors.New("unsupported hodl preimage state version")
+	}
+	preimage, err := wallet.hodlPreimage(state.Index, state.Version == hodlPreimageLegacyStateVersion)
+	if err != nil {
+		return nil, err
+	}
+	if preimage.PaymentHash != strings.ToLower(state.PaymentHash) {
+		return nil, ErrHodlPreimageMismatch
+	}
+	return preimage, nil
+}
+
+// SerializedState returns the preimage's index and payment hash as a JSON string, suitable for persisting with the
+// invoice and passing to `RestoreHodlPreimage`. The preimage itself is not included.
+func (p *HodlInvoicePreimage) SerializedState() (string, error) {
+	state := hodlPreimageState{Version: hodlPreimageStateVersion, Index: p.Index, PaymentHash: p.PaymentHash}
+	if p.legacy {
+		state.Version = hodlPreimageLegacyStateVersion
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+/// Unexported functions
+
+// hodlPreimage returns the hold invoice preimage at a given index, from the BIP85 HEX application at
+// m/83696968'/128169'/32'/index' if legacy.
+func (wallet *HDWallet) hodlPreimage(index int, legacy bool) (*HodlInvoicePreimage, error) {
+	if err := validateDerivationIndex(index); err != nil {
+		return nil, err
+	}
+	path := []int{bip85ApplicationHodlPreimage, index}
+	if legacy {
+		path = []int{bip85ApplicationHex, hodlPreimageLength, index}
+	}
+	entropy, err := wallet.bip85Entropy(path...)
+	if err != nil {
+		return nil, err
+	}
+	preimage := entropy[:hodlPreimageLength]
+	hash := sha256.Sum256(preimage)
+	return &HodlInvoicePreimage{
+		Index:       index,
+		Preimage:    hex.EncodeToString(preimage),
+		PaymentHash: hex.EncodeToString(hash[:]),
+		legacy:      legacy,
+	}, nil
+}
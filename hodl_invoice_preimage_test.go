@@ -0,0 +1,97 @@
+package cnlib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHodlPreimageForIndex(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	preimage, err := wallet.HodlPreimageForIndex(3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, preimage.Index)
+	assert.Equal(t, 64, len(preimage.Preimage))
+
+	raw, err := hex.DecodeString(preimage.Preimage)
+	assert.Nil(t, err)
+	hash := sha256.Sum256(raw)
+	assert.Equal(t, hex.EncodeToString(hash[:]), preimage.PaymentHash)
+
+	again, err := NewHDWalletFromWords(w, BaseCoinBip84MainNet).HodlPreimageForIndex(3)
+	assert.Nil(t, err)
+	assert.Equal(t, preimage, again)
+
+	other, err := wallet.HodlPreimageForIndex(4)
+	assert.Nil(t, err)
+	assert.NotEqual(t, preimage.Preimage, other.Preimage)
+
+	_, err = wallet.HodlPreimageForIndex(-1)
+	assert.Equal(t, ErrDerivationIndexNegative, err)
+}
+
+func TestFindHodlPreimage(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	expected, err := wallet.HodlPreimageForIndex(5)
+	assert.Nil(t, err)
+
+	found, err := wallet.FindHodlPreimage(strings.ToUpper(expected.PaymentHash), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, found)
+
+	_, err = wallet.FindHodlPreimage(expected.PaymentHash, 4)
+	assert.Equal(t, ErrHodlPreimageNotFound, err)
+}
+
+func TestHodlPreimage_SerializedState(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	preimage, err := wallet.HodlPreimageForIndex(2)
+	assert.Nil(t, err)
+
+	state, err := preimage.SerializedState()
+	assert.Nil(t, err)
+	assert.NotContains(t, state, preimage.Preimage)
+
+	restored, err := wallet.RestoreHodlPreimage(state)
+	assert.Nil(t, err)
+	assert.Equal(t, preimage, restored)
+
+	other := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	_, err = other.RestoreHodlPreimage(state)
+	assert.Equal(t, ErrHodlPreimageMismatch, err)
+
+	_, err = wallet.RestoreHodlPreimage(`{"version":3,"index":2,"payment_hash":""}`)
+	assert.NotNil(t, err)
+
+	watchOnly, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	_, err = watchOnly.RestoreHodlPreimage(state)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
+
+func TestHodlPreimage_SeparateFromBIP85Hex(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	preimage, err := wallet.HodlPreimageForIndex(1)
+	assert.Nil(t, err)
+	hexEntropy, err := wallet.bip85Entropy(bip85ApplicationHex, hodlPreimageLength, 1)
+	assert.Nil(t, err)
+	assert.NotEqual(t, hex.EncodeToString(hexEntropy[:hodlPreimageLength]), preimage.Preimage)
+
+	// invoices created from the HEX application are still found and restored
+	legacy, err := wallet.hodlPreimage(1, true)
+	assert.Nil(t, err)
+	assert.Equal(t, hex.EncodeToString(hexEntropy[:hodlPreimageLength]), legacy.Preimage)
+	found, err := wallet.FindHodlPreimage(legacy.PaymentHash, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, legacy, found)
+
+	state, err := legacy.SerializedState()
+	assert.Nil(t, err)
+	assert.Contains(t, state, `"version":1`)
+	restored, err := wallet.RestoreHodlPreimage(state)
+	assert.Nil(t, err)
+	assert.Equal(t, legacy, restored)
+}
@@ -0,0 +1,149 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	identityAttestationVersion = 1
+	identityAttestationLabel   = "identity attestation"
+)
+
+// ErrIdentityAttestationFingerprint describes an attestation whose master fingerprint is not that of its master public
+// key.
+var ErrIdentityAttestationFingerprint = errors.New("master fingerprint does not match master public key")
+
+/// Type Definitions
+
+// IdentityAttestation links the wallet's m/42 identity key, used as the CoinNinja verification key, to the wallet's
+// master fingerprint at a point in time. It is signed by both the identity key and the master key, so the backend can
+// tell the verification key genuinely belongs to the wallet with that fingerprint. The master public key is included
+// to check the fingerprint against; its chain code is not, so no other keys can be derived from the attestation.
+type IdentityAttestation struct {
+	Version           int    `json:"version"`
+	IdentityPublicKey string `json:"identity_pubkey"` // hex-encoded compressed public key at m/42
+	MasterPublicKey   string `json:"master_pubkey"`   // hex-encoded compressed public key at m
+	MasterFingerprint string `json:"master_fingerprint"`
+	Timestamp         int64  `json:"timestamp"` // seconds since unix epoch
+	IdentitySignature string `json:"identity_signature"`
+	MasterSignature   string `json:"master_signature"`
+}
+
+/// Receiver functions
+
+// IdentityAttestation returns an attestation, signed now, linking the wallet's identity key to its master fingerprint.
+// Returns error if the wallet is watch-only.
+func (wallet *HDWallet) IdentityAttestation() (*IdentityAttestation, error) {
+	return wallet.identityAttestationAt(time.Now().Unix())
+}
+
+// Encode returns the attestation as a JSON string, suitable for sending to the backend and passing to
+// `DecodeIdentityAttestation`.
+func (a *IdentityAttestation) Encode() (string, error) {
+	encoded, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// Verify returns ErrIdentityAttestationFingerprint unless the master fingerprint is that of the master public key, or
+// ErrSignatureInvalid unless both the identity and master signatures are valid. Callers should also check that
+// Timestamp is recent, and that the fingerprint is the one they expect.
+func (a *IdentityAttestation) Verify() error {
+	if a.Version != identityAttestationVersion {
+		return errors.New("unsupported identity attestation version")
+	}
+	fingerprint, err := hex.DecodeString(a.MasterFingerprint)
+	if err != nil || len(fingerprint) != 4 {
+		return errors.New("master fingerprint must be 4 bytes")
+	}
+	masterPubkey, err := parseHexPubKey(a.MasterPublicKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(btcutil.Hash160(masterPubkey.SerializeCompressed())[:4], fingerprint) {
+		return ErrIdentityAttestationFingerprint
+	}
+	identityPubkey, err := parseHexPubKey(a.IdentityPublicKey)
+	if err != nil {
+		return err
+	}
+
+	identitySignature, err := hex.DecodeString(a.IdentitySignature)
+	if err != nil {
+		return err
+	}
+	masterSignature, err := hex.DecodeString(a.MasterSignature)
+	if err != nil {
+		return err
+	}
+	digest := a.digest()
+	if err := verifyDERSignature(identitySignature, digest, identityPubkey); err != nil {
+		return err
+	}
+	return verifyDERSignature(masterSignature, digest, masterPubkey)
+}
+
+/// Exported functions
+
+// DecodeIdentityAttestation parses and verifies an attestation from a string returned by `Encode`.
+func DecodeIdentityAttestation(encoded string) (*IdentityAttestation, error) {
+	var attestation IdentityAttestation
+	if err := json.Unmarshal([]byte(encoded), &attestation); err != nil {
+		return nil, err
+	}
+	if err := attestation.Verify(); err != nil {
+		return nil, err
+	}
+	return &attestation, nil
+}
+
+/// Unexported functions
+
+func (wallet *HDWallet) identityAttestationAt(timestamp int64) (*IdentityAttestation, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	masterKey, err := wallet.masterPrivateKey.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	identityKey, err := wallet.signingPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := wallet.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	attestation := &IdentityAttestation{
+		Version:           identityAttestationVersion,
+		IdentityPublicKey: hex.EncodeToString(identityKey.PubKey().SerializeCompressed()),
+		MasterPublicKey:   hex.EncodeToString(masterKey.PubKey().SerializeCompressed()),
+		MasterFingerprint: fingerprint,
+		Timestamp:         timestamp,
+	}
+	digest := attestation.digest()
+	if attestation.IdentitySignature, err = signDigestHex(identityKey, digest); err != nil {
+		return nil, err
+	}
+	if attestation.MasterSignature, err = signDigestHex(masterKey, digest); err != nil {
+		return nil, err
+	}
+	return attestation, nil
+}
+
+// digest returns the signed record digest of the attested fields.
+func (a *IdentityAttestation) digest() []byte {
+	return signedRecordDigest(identityAttestationLabel, a.Version, a.IdentityPublicKey, a.MasterPublicKey,
+		a.MasterFingerprint, a.Timestamp)
+}
@@ -0,0 +1,85 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityAttestation_RoundTrip(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	attestation, err := wallet.identityAttestationAt(1577836800)
+	assert.Nil(t, err)
+
+	verificationKey, err := wallet.CoinNinjaVerificationKeyHexString()
+	assert.Nil(t, err)
+	assert.Equal(t, verificationKey, attestation.IdentityPublicKey)
+	assert.Equal(t, "73c5da0a", attestation.MasterFingerprint)
+	assert.Nil(t, attestation.Verify())
+
+	encoded, err := attestation.Encode()
+	assert.Nil(t, err)
+	decoded, err := DecodeIdentityAttestation(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, attestation, decoded)
+}
+
+func TestIdentityAttestation_TamperedFields_FailVerification(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	other := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	attestation, err := wallet.IdentityAttestation()
+	assert.Nil(t, err)
+	otherAttestation, err := other.IdentityAttestation()
+	assert.Nil(t, err)
+
+	tampered := *attestation
+	tampered.Timestamp++
+	assert.Equal(t, ErrSignatureInvalid, tampered.Verify())
+
+	tampered = *attestation
+	tampered.MasterFingerprint = "01020304"
+	assert.Equal(t, ErrIdentityAttestationFingerprint, tampered.Verify())
+
+	tampered = *attestation
+	tampered.MasterFingerprint = "0102"
+	assert.NotNil(t, tampered.Verify())
+
+	// another wallet's identity key does not verify this wallet's signature
+	tampered = *attestation
+	tampered.IdentityPublicKey = otherAttestation.IdentityPublicKey
+	assert.Equal(t, ErrSignatureInvalid, tampered.Verify())
+}
+
+func TestIdentityAttestation_ForeignFingerprint_FailsVerification(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	other := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	otherAttestation, err := other.identityAttestationAt(1577836800)
+	assert.Nil(t, err)
+	identityKey, err := wallet.signingPrivateKey()
+	assert.Nil(t, err)
+
+	// the holder of an identity key names another wallet's fingerprint and master public key, re-signing with its own
+	// identity key, but cannot produce the other wallet's master signature
+	forged := *otherAttestation
+	forged.IdentityPublicKey = hex.EncodeToString(identityKey.PubKey().SerializeCompressed())
+	forged.IdentitySignature, err = signDigestHex(identityKey, forged.digest())
+	assert.Nil(t, err)
+	assert.Equal(t, ErrSignatureInvalid, forged.Verify())
+
+	// or signs with its own master key, which does not match the fingerprint
+	masterKey, err := wallet.masterPrivateKey.ECPrivKey()
+	assert.Nil(t, err)
+	forged.MasterPublicKey = hex.EncodeToString(masterKey.PubKey().SerializeCompressed())
+	forged.IdentitySignature, _ = signDigestHex(identityKey, forged.digest())
+	forged.MasterSignature, _ = signDigestHex(masterKey, forged.digest())
+	assert.Equal(t, ErrIdentityAttestationFingerprint, forged.Verify())
+}
+
+func TestIdentityAttestation_WatchOnlyWallet_ReturnsError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	assert.Nil(t, wallet.SetRole(WalletRoleHot))
+
+	_, err := wallet.IdentityAttestation()
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
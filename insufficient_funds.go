@@ -0,0 +1,85 @@
+package cnlib
+
+import "errors"
+
+// ErrInsufficientFunds is matched, with `errors.Is`, by every InsufficientFundsError.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+/// Type Definitions
+
+// InsufficientFundsError is returned by `Generate` when the available utxos cannot cover the amount and fee. All
+// amounts are in satoshis. MaxSendableAmount is what a send max transaction at the same fee would deliver, or 0 if
+// even that would be below the dust threshold, so a UI can offer to send max instead.
+type InsufficientFundsError struct {
+	SpendableBalance  int // sum of all available utxos
+	RequiredAmount    int // amount plus fee, spending all available utxos without change
+	Shortfall         int // RequiredAmount minus SpendableBalance
+	FeeRate           int // 0 for flat fee transactions
+	MaxSendableAmount int
+}
+
+/// Receiver functions
+
+func (e *InsufficientFundsError) Error() string {
+	return ErrInsufficientFunds.Error()
+}
+
+// Is reports whether target is ErrInsufficientFunds.
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == ErrInsufficientFunds
+}
+
+/// Exported functions
+
+// InsufficientFundsDetails returns the InsufficientFundsError wrapped by err, or nil if err is not one. Intended for
+// gomobile callers, which cannot type-assert Go errors.
+func InsufficientFundsDetails(err error) *InsufficientFundsError {
+	var details *InsufficientFundsError
+	if errors.As(err, &details) {
+		return details
+	}
+	return nil
+}
+
+/// Unexported functions
+
+// insufficientFundsError builds the error for a transaction which, spending every available utxo without change,
+// would pay the given fee. Returns any error computing the max sendable amount instead.
+func (td *TransactionData) insufficientFundsError(amount int64, fee int64) error {
+	spendable := totalUTXOAmount(td.availableUtxos)
+	required := amount + fee
+	maxSendable := spendable - fee
+	if td.feeRate > 0 {
+		outputBytes, err := td.basecoin.bytesPerDestination(td.PaymentAddress)
+		if err != nil {
+			return err
+		}
+		maxSendable, _, err = td.basecoin.maxSendable(td.availableUtxos, int64(td.feeRate), outputBytes)
+		if err != nil {
+			return err
+		}
+	}
+	if maxSendable < dustThreshold {
+		maxSendable = 0
+	}
+	shortfall := required - spendable
+	if shortfall < 0 {
+		shortfall = 0
+	}
+	return &InsufficientFundsError{
+		SpendableBalance:  int(spendable),
+		RequiredAmount:    int(required),
+		Shortfall:         int(shortfall),
+		FeeRate:           td.feeRate,
+		MaxSendableAmount: int(maxSendable),
+	}
+}
+
+// noChangeFee returns the fee, at the transaction's fee rate, of spending every available utxo without change.
+func (td *TransactionData) noChangeFee() (int64, error) {
+	totalBytes, err := td.basecoin.totalBytes(td.availableUtxos, td.PaymentAddress, false)
+	if err != nil {
+		return 0, err
+	}
+	return int64(td.feeRate) * int64(totalBytes), nil
+}
@@ -0,0 +1,188 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	signedInvoiceVersion = 1
+	signedInvoiceLabel   = "invoice"
+)
+
+/// Type Definitions
+
+// Invoice is a payment request binding an address to an amount, memo, and expiry.
+type Invoice struct {
+	MetaAddress *MetaAddress
+	Amount      int // satoshis, 0 if unspecified
+	Memo        string
+	ExpiresAt   int64 // seconds since unix epoch, 0 if the invoice does not expire
+}
+
+// VerifiedInvoice is an Invoice recovered from a signed JSON blob, along with the key that signed it.
+type VerifiedInvoice struct {
+	*Invoice
+	SignerPublicKey string // hex-encoded compressed m/42 public key of the sender
+}
+
+type invoicePayload struct {
+	Address   string `json:"address"`
+	Amount    int    `json:"amount"`
+	Memo      string `json:"memo,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+}
+
+type signedInvoiceEnvelope struct {
+	Version   int    `json:"version"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"` // hex-encoded DER signature of the signed record digest of the other fields
+	PublicKey string `json:"public_key"`
+}
+
+/// Constructors
+
+// NewInvoice instantiates a new Invoice and returns a ref to it.
+func NewInvoice(metaAddress *MetaAddress, amount int, memo string, expiresAt int64) *Invoice {
+	return &Invoice{MetaAddress: metaAddress, Amount: amount, Memo: memo, ExpiresAt: expiresAt}
+}
+
+/// Receiver functions
+
+// IsExpired returns true if the invoice has an expiry in the past.
+func (inv *Invoice) IsExpired() bool {
+	return inv.ExpiresAt != 0 && time.Now().Unix() > inv.ExpiresAt
+}
+
+// BIP21URI returns the invoice encoded as a BIP21 `bitcoin:` URI. The expiry is not part of BIP21 and is omitted.
+func (inv *Invoice) BIP21URI() (string, error) {
+	if err := inv.validate(); err != nil {
+		return "", err
+	}
+
+	params := []string{}
+	if inv.Amount > 0 {
+		btc := strconv.FormatFloat(btcutil.Amount(inv.Amount).ToBTC(), 'f', -1, 64)
+		params = append(params, "amount="+btc)
+	}
+	if inv.Memo != "" {
+		params = append(params, "message="+strings.Replace(url.QueryEscape(inv.Memo), "+", "%20", -1))
+	}
+
+	uri := "bitcoin:" + inv.MetaAddress.Address
+	if len(params) > 0 {
+		uri += "?" + strings.Join(params, "&")
+	}
+	return uri, nil
+}
+
+// SignedInvoiceJSON returns the invoice as a JSON blob signed with the wallet's signing key (m/42), so the recipient
+// can authenticate the sender. The signature is of an invoice-labelled record, so it cannot be mistaken for any other
+// signature of the m/42 key.
+func (wallet *HDWallet) SignedInvoiceJSON(inv *Invoice) (string, error) {
+	if inv == nil {
+		return "", errors.New("invoice cannot be nil")
+	}
+	if err := inv.validate(); err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(invoicePayload{
+		Address:   inv.MetaAddress.Address,
+		Amount:    inv.Amount,
+		Memo:      inv.Memo,
+		ExpiresAt: inv.ExpiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return "", err
+	}
+	signingKey, err := wallet.signingPrivateKey()
+	if err != nil {
+		return "", err
+	}
+
+	envelope := signedInvoiceEnvelope{
+		Version:   signedInvoiceVersion,
+		Payload:   string(payload),
+		PublicKey: hex.EncodeToString(signingKey.PubKey().SerializeCompressed()),
+	}
+	if envelope.Signature, err = signDigestHex(signingKey, envelope.digest()); err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(envelope)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+/// Exported functions
+
+// VerifySignedInvoiceJSON verifies a blob created by `SignedInvoiceJSON` and returns the invoice with the signer's
+// public key, or error if the signature is invalid. Callers should compare `SignerPublicKey` to the expected sender.
+func VerifySignedInvoiceJSON(signedJSON string) (*VerifiedInvoice, error) {
+	var envelope signedInvoiceEnvelope
+	if err := json.Unmarshal([]byte(signedJSON), &envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Version != signedInvoiceVersion {
+		return nil, errors.New("unsupported signed invoice version")
+	}
+
+	pubkey, err := parseHexPubKey(envelope.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := hex.DecodeString(envelope.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyDERSignature(signature, envelope.digest(), pubkey); err != nil {
+		return nil, err
+	}
+
+	var payload invoicePayload
+	if err := json.Unmarshal([]byte(envelope.Payload), &payload); err != nil {
+		return nil, err
+	}
+
+	inv := NewInvoice(NewMetaAddress(payload.Address, nil, ""), payload.Amount, payload.Memo, payload.ExpiresAt)
+	if err := inv.validate(); err != nil {
+		return nil, err
+	}
+
+	return &VerifiedInvoice{Invoice: inv, SignerPublicKey: envelope.PublicKey}, nil
+}
+
+/// Unexported functions
+
+// digest returns the signed record digest of the envelope's signed fields, with the payload last as its memo may
+// contain newlines.
+func (e *signedInvoiceEnvelope) digest() []byte {
+	return signedRecordDigest(signedInvoiceLabel, e.Version, e.PublicKey, e.Payload)
+}
+
+func (inv *Invoice) validate() error {
+	if inv.MetaAddress == nil || inv.MetaAddress.Address == "" {
+		return errors.New("invoice address cannot be empty")
+	}
+	if inv.Amount < 0 {
+		return errors.New("invoice amount cannot be negative")
+	}
+	if inv.ExpiresAt < 0 {
+		return errors.New("invoice expiry cannot be negative")
+	}
+	return nil
+}
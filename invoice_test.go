@@ -0,0 +1,58 @@
+package cnlib
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoice_BIP21URI(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	meta, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+
+	inv := NewInvoice(meta, 150000, "lunch & coffee", 0)
+	uri, err := inv.BIP21URI()
+	assert.Nil(t, err)
+	assert.Equal(t, "bitcoin:bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu?amount=0.0015&message=lunch%20%26%20coffee", uri)
+
+	bare, err := NewInvoice(meta, 0, "", 0).BIP21URI()
+	assert.Nil(t, err)
+	assert.Equal(t, "bitcoin:bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", bare)
+}
+
+func TestInvoice_SignedJSON_RoundTrip(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	meta, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+
+	signed, err := wallet.SignedInvoiceJSON(NewInvoice(meta, 150000, "lunch", 4102444800))
+	assert.Nil(t, err)
+
+	verified, err := VerifySignedInvoiceJSON(signed)
+	assert.Nil(t, err)
+	assert.Equal(t, meta.Address, verified.MetaAddress.Address)
+	assert.Equal(t, 150000, verified.Amount)
+	assert.Equal(t, "lunch", verified.Memo)
+	assert.False(t, verified.IsExpired())
+
+	expectedKey, _ := wallet.CoinNinjaVerificationKeyHexString()
+	assert.Equal(t, expectedKey, verified.SignerPublicKey)
+
+	tampered := strings.Replace(signed, "150000", "950000", 1)
+	_, err = VerifySignedInvoiceJSON(tampered)
+	assert.NotNil(t, err)
+
+	// a plain m/42 signature of the payload, as signed before invoices were labelled, is not an invoice signature
+	var envelope signedInvoiceEnvelope
+	assert.Nil(t, json.Unmarshal([]byte(signed), &envelope))
+	envelope.Signature, err = wallet.SignatureSigningData([]byte(envelope.Payload))
+	assert.Nil(t, err)
+	unlabelled, _ := json.Marshal(envelope)
+	_, err = VerifySignedInvoiceJSON(string(unlabelled))
+	assert.Equal(t, ErrSignatureInvalid, err)
+	_, err = VerifySignedInvoiceJSON(strings.Replace(signed, `"version":1`, `"version":2`, 1))
+	assert.NotNil(t, err)
+}
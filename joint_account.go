@@ -0,0 +1,216 @@
+package cnlib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+const (
+	jointAccountKeyVersion = 1
+	jointAccountThreshold  = 2
+	// jointAccountPathFormat is the BIP48 P2WSH account path of a joint account's key, by coin and account index.
+	jointAccountPathFormat = "m/48'/%d'/%d'/2'"
+)
+
+// Following errors describe a joint account which cannot be set up between two wallets.
+var (
+	ErrJointAccountNetwork = errors.New("joint account key is of another network")
+	ErrJointAccountBackup  = errors.New("multisig wallet backup is not a 2-of-2 joint account")
+)
+
+/// Type Definitions
+
+// JointAccountKey is a wallet's half of a joint account, the extended public key of its BIP48 P2WSH account with its
+// origin, to be sent to the other user's app, such as in a QR code, with `Encode`.
+type JointAccountKey struct {
+	MasterFingerprint string // hex-encoded
+	DerivationPath    string
+	ExtendedPublicKey string
+	coin              int
+	testNetwork       int
+}
+
+// JointAccount is a 2-of-2 P2WSH multisig account shared by two users, each holding one key, so spending takes both
+// users' approval. It wraps a MultisigWallet, for addresses, and its MultisigWalletBackup, which each user should store
+// alongside their words. Spends are proposed and approved with `NewSpendProposal` and `SignSpendProposal`.
+type JointAccount struct {
+	wallet   *HDWallet
+	backup   *MultisigWalletBackup
+	multisig *MultisigWallet
+}
+
+// jointAccountKeyState is the encoded form of a JointAccountKey.
+type jointAccountKeyState struct {
+	Version           int    `json:"version"`
+	Coin              int    `json:"coin"`
+	TestNetwork       int    `json:"test_network,omitempty"`
+	MasterFingerprint string `json:"master_fingerprint"`
+	DerivationPath    string `json:"path"`
+	ExtendedPublicKey string `json:"xpub"`
+}
+
+/// Constructors
+
+// JointAccountKey returns the wallet's key for the joint account at an account index, at "m/48'/coin'/account'/2'",
+// for the other user to pass to `NewJointAccount`. Returns error if the wallet is watch-only.
+func (wallet *HDWallet) JointAccountKey(account int) (*JointAccountKey, error) {
+	if err := validateDerivationIndex(account); err != nil {
+		return nil, err
+	}
+	path, err := ParseCustomDerivationPath(fmt.Sprintf(jointAccountPathFormat, wallet.BaseCoin.Coin, account))
+	if err != nil {
+		return nil, err
+	}
+	xpub, err := wallet.ExtendedPublicKeyForPath(path)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := wallet.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	return &JointAccountKey{
+		MasterFingerprint: fingerprint,
+		DerivationPath:    path.String(),
+		ExtendedPublicKey: xpub,
+		coin:              wallet.BaseCoin.Coin,
+		testNetwork:       wallet.BaseCoin.TestNetwork,
+	}, nil
+}
+
+// NewJointAccount returns the 2-of-2 joint account of the wallet's key at an account index and the other user's key,
+// decoded with `DecodeJointAccountKey`. Both users call this with the same account index and each other's key, and
+// derive the same addresses. Returns ErrJointAccountNetwork if the other key is of another network, or
+// ErrMultisigCosignerDuplicate if it is the wallet's own.
+func (wallet *HDWallet) NewJointAccount(account int, otherKey *JointAccountKey, firstUsedHeight int) (*JointAccount, error) {
+	if otherKey == nil {
+		return nil, errors.New("joint account key cannot be nil")
+	}
+	if otherKey.network().defaultNetParams() != wallet.BaseCoin.defaultNetParams() {
+		return nil, ErrJointAccountNetwork
+	}
+	own, err := wallet.JointAccountKey(account)
+	if err != nil {
+		return nil, err
+	}
+	backup, err := wallet.NewMultisigWalletBackup(own.DerivationPath, jointAccountThreshold, MultisigScriptTypeP2WSH, firstUsedHeight)
+	if err != nil {
+		return nil, err
+	}
+	if err := backup.AddCosigner(otherKey.MasterFingerprint, otherKey.DerivationPath, otherKey.ExtendedPublicKey); err != nil {
+		return nil, err
+	}
+	return wallet.JointAccountFromBackup(backup)
+}
+
+// JointAccountFromBackup restores a joint account from its backup, such as one decoded with
+// `DecodeMultisigWalletBackup` after restoring the wallet from its words. Returns ErrJointAccountBackup unless the
+// backup is 2-of-2 P2WSH, or ErrMultisigOwnKeyMismatch unless the wallet holds its own key.
+func (wallet *HDWallet) JointAccountFromBackup(backup *MultisigWalletBackup) (*JointAccount, error) {
+	if backup == nil {
+		return nil, errors.New("multisig wallet backup cannot be nil")
+	}
+	if backup.Threshold != jointAccountThreshold || backup.CosignerCount() != jointAccountThreshold ||
+		backup.ScriptType != MultisigScriptTypeP2WSH {
+		return nil, ErrJointAccountBackup
+	}
+	if err := backup.VerifyOwnKey(wallet); err != nil {
+		return nil, err
+	}
+	multisig, err := backup.MultisigWallet()
+	if err != nil {
+		return nil, err
+	}
+	return &JointAccount{wallet: wallet, backup: backup, multisig: multisig}, nil
+}
+
+/// Receiver functions
+
+// Encode returns the key as a JSON string, for passing to `DecodeJointAccountKey` in the other user's app.
+func (k *JointAccountKey) Encode() (string, error) {
+	state := jointAccountKeyState{
+		Version:           jointAccountKeyVersion,
+		Coin:              k.coin,
+		TestNetwork:       k.testNetwork,
+		MasterFingerprint: k.MasterFingerprint,
+		DerivationPath:    k.DerivationPath,
+		ExtendedPublicKey: k.ExtendedPublicKey,
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// ReceiveAddressForIndex returns the receive address at an index.
+func (a *JointAccount) ReceiveAddressForIndex(index int) (*MultisigAddress, error) {
+	return a.multisig.ReceiveAddressForIndex(index)
+}
+
+// ChangeAddressForIndex returns the change address at an index.
+func (a *JointAccount) ChangeAddressForIndex(index int) (*MultisigAddress, error) {
+	return a.multisig.ChangeAddressForIndex(index)
+}
+
+// Descriptor returns the account's wsh(sortedmulti) output descriptor, for importing into other coordinators.
+func (a *JointAccount) Descriptor() (string, error) {
+	return a.backup.Descriptor()
+}
+
+// Backup returns the account's backup, to encode and store alongside the wallet's words.
+func (a *JointAccount) Backup() *MultisigWalletBackup {
+	return a.backup
+}
+
+// MultisigWallet returns the account's underlying MultisigWallet.
+func (a *JointAccount) MultisigWallet() *MultisigWallet {
+	return a.multisig
+}
+
+/// Exported functions
+
+// DecodeJointAccountKey parses a key from a string returned by `Encode`. Returns ErrMultisigCosignerKey if the key is
+// not an extended public key of its network at the depth of its path, or error if it is otherwise malformed.
+func DecodeJointAccountKey(encoded string) (*JointAccountKey, error) {
+	var state jointAccountKeyState
+	if err := json.Unmarshal([]byte(encoded), &state); err != nil {
+		return nil, err
+	}
+	if state.Version != jointAccountKeyVersion {
+		return nil, errors.New("unsupported joint account key version")
+	}
+	if _, ok := networkRegistry[state.Coin]; !ok {
+		return nil, ErrInvalidCoinValue
+	}
+	path, err := ParseCustomDerivationPath(state.DerivationPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hdkeychain.NewKeyFromString(state.ExtendedPublicKey)
+	if err != nil || key.IsPrivate() || int(key.Depth()) != path.Depth() {
+		return nil, ErrMultisigCosignerKey
+	}
+	decoded := &JointAccountKey{
+		MasterFingerprint: strings.ToLower(state.MasterFingerprint),
+		DerivationPath:    path.String(),
+		ExtendedPublicKey: state.ExtendedPublicKey,
+		coin:              state.Coin,
+		testNetwork:       state.TestNetwork,
+	}
+	if !key.IsForNet(decoded.network().defaultNetParams()) {
+		return nil, ErrMultisigCosignerKey
+	}
+	return decoded, nil
+}
+
+/// Unexported functions
+
+// network returns a BaseCoin of the key's network.
+func (k *JointAccountKey) network() *BaseCoin {
+	return &BaseCoin{Coin: k.coin, TestNetwork: k.testNetwork}
+}
@@ -0,0 +1,102 @@
+package cnlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestJointAccounts returns each user's side of the joint account between `w` and another wallet.
+func newTestJointAccounts(t *testing.T) (*JointAccount, *JointAccount) {
+	alice := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	bob := multisigCosignerWallet(t, 0x01, BaseCoinBip84MainNet)
+	aliceKey, err := alice.JointAccountKey(0)
+	assert.Nil(t, err)
+	bobKey, err := bob.JointAccountKey(0)
+	assert.Nil(t, err)
+
+	// each key is exchanged encoded
+	encoded, err := bobKey.Encode()
+	assert.Nil(t, err)
+	bobKey, err = DecodeJointAccountKey(encoded)
+	assert.Nil(t, err)
+	encoded, _ = aliceKey.Encode()
+	aliceKey, err = DecodeJointAccountKey(encoded)
+	assert.Nil(t, err)
+
+	aliceAccount, err := alice.NewJointAccount(0, bobKey, 700000)
+	assert.Nil(t, err)
+	bobAccount, err := bob.NewJointAccount(0, aliceKey, 700000)
+	assert.Nil(t, err)
+	return aliceAccount, bobAccount
+}
+
+func TestJointAccount_BothUsersDeriveSameAddresses(t *testing.T) {
+	aliceAccount, bobAccount := newTestJointAccounts(t)
+	for i := 0; i < 3; i++ {
+		aliceAddress, err := aliceAccount.ReceiveAddressForIndex(i)
+		assert.Nil(t, err)
+		bobAddress, err := bobAccount.ReceiveAddressForIndex(i)
+		assert.Nil(t, err)
+		assert.Equal(t, aliceAddress.Address, bobAddress.Address)
+		assert.True(t, strings.HasPrefix(aliceAddress.Address, "bc1q"))
+		aliceChange, _ := aliceAccount.ChangeAddressForIndex(i)
+		bobChange, _ := bobAccount.ChangeAddressForIndex(i)
+		assert.Equal(t, aliceChange.Address, bobChange.Address)
+	}
+
+	assert.Equal(t, 2, aliceAccount.MultisigWallet().Threshold)
+	assert.Equal(t, 2, aliceAccount.MultisigWallet().CosignerCount())
+	aliceDescriptor, err := aliceAccount.Descriptor()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(aliceDescriptor, "wsh(sortedmulti(2,"))
+	assert.True(t, strings.Contains(aliceDescriptor, "/48'/0'/0'/2']xpub"))
+}
+
+func TestJointAccountFromBackup(t *testing.T) {
+	aliceAccount, _ := newTestJointAccounts(t)
+	encoded, err := aliceAccount.Backup().Encode()
+	assert.Nil(t, err)
+	backup, err := DecodeMultisigWalletBackup(encoded)
+	assert.Nil(t, err)
+
+	restored, err := NewHDWalletFromWords(w, BaseCoinBip84MainNet).JointAccountFromBackup(backup)
+	assert.Nil(t, err)
+	expected, _ := aliceAccount.ReceiveAddressForIndex(5)
+	address, _ := restored.ReceiveAddressForIndex(5)
+	assert.Equal(t, expected.Address, address.Address)
+
+	_, err = multisigCosignerWallet(t, 0x02, BaseCoinBip84MainNet).JointAccountFromBackup(backup)
+	assert.Equal(t, ErrMultisigOwnKeyMismatch, err)
+
+	_, larger := newTestMultisigWalletBackup(t)
+	_, err = NewHDWalletFromWords(w, BaseCoinBip84MainNet).JointAccountFromBackup(larger)
+	assert.Equal(t, ErrJointAccountBackup, err)
+}
+
+func TestNewJointAccount_Invalid(t *testing.T) {
+	alice := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	aliceKey, _ := alice.JointAccountKey(0)
+	_, err := alice.NewJointAccount(0, aliceKey, 0)
+	assert.Equal(t, ErrMultisigCosignerDuplicate, err)
+	_, err = alice.NewJointAccount(0, nil, 0)
+	assert.NotNil(t, err)
+
+	testnetKey, err := multisigCosignerWallet(t, 0x01, BaseCoinBip84TestNet).JointAccountKey(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "m/48'/1'/0'/2'", testnetKey.DerivationPath)
+	_, err = alice.NewJointAccount(0, testnetKey, 0)
+	assert.Equal(t, ErrJointAccountNetwork, err)
+
+	_, err = alice.JointAccountKey(-1)
+	assert.NotNil(t, err)
+
+	_, err = DecodeJointAccountKey(`{"version":2}`)
+	assert.NotNil(t, err)
+	encoded, _ := aliceKey.Encode()
+	_, err = DecodeJointAccountKey(strings.Replace(encoded, `"path":"m/48'/0'/0'/2'"`, `"path":"m/48'/0'/0'"`, 1))
+	assert.Equal(t, ErrMultisigCosignerKey, err)
+	_, err = DecodeJointAccountKey(strings.Replace(encoded, `"coin":0`, `"coin":1`, 1))
+	assert.Equal(t, ErrMultisigCosignerKey, err)
+}
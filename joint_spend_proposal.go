@@ -0,0 +1,409 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+const jointSpendProposalVersion = 1
+
+// ErrJointSpendProposalMismatch describes a spend proposal whose transaction does not spend only the joint account's
+// utxos to pay the amount, change and fee it shows, so it must not be approved.
+var ErrJointSpendProposalMismatch = errors.New("joint spend proposal transaction does not match its summary")
+
+// ErrJointSpendProposalUnknownUTXO describes a spend proposal spending a utxo the signer has not added with `AddUTXO`,
+// or with another amount. Segwit signatures commit only to their own input's amount, so a proposal overstating the
+// amounts of some inputs could otherwise get signatures for a transaction paying a far higher fee than it shows.
+var ErrJointSpendProposalUnknownUTXO = errors.New("joint spend proposal spends a utxo unknown to the signer")
+
+/// Type Definitions
+
+// JointSpendProposal is a spend from a JointAccount proposed by one user for the other to approve. It carries an
+// unsigned PSBT, signed by each user with `SignSpendProposal`, and is passed between the users' apps with `Encode`
+// and `DecodeSpendProposal`. Add the account's utxos one at a time with `AddUTXO`, as gomobile does not support custom
+// arrays/slices, then call `Generate`.
+type JointSpendProposal struct {
+	PaymentAddress string
+	Amount         int
+	FeeAmount      int
+	ChangeAmount   int // 0 if the change would be dust, which is added to the fee
+	ChangeIndex    int // index of the account's change address receiving any change
+	Memo           string
+	feeRate        int
+	account        *JointAccount
+	available      []*jointAccountInput
+	inputs         []*jointAccountInput
+	psbt           string
+}
+
+// jointAccountInput is a utxo of a joint account, with the receive or change address and index it pays to.
+type jointAccountInput struct {
+	Txid         string `json:"txid"`
+	Index        int    `json:"index"`
+	Amount       int64  `json:"amount"`
+	Change       int    `json:"change"`
+	AddressIndex int    `json:"address_index"`
+}
+
+// jointSpendProposalState is the encoded form of a JointSpendProposal.
+type jointSpendProposalState struct {
+	Version        int                  `json:"version"`
+	PaymentAddress string               `json:"payment_address"`
+	Amount         int                  `json:"amount"`
+	FeeAmount      int                  `json:"fee_amount"`
+	ChangeAmount   int                  `json:"change_amount"`
+	ChangeIndex    int                  `json:"change_index"`
+	Memo           string               `json:"memo,omitempty"`
+	Inputs         []*jointAccountInput `json:"inputs"`
+	PSBT           string               `json:"psbt"`
+}
+
+/// Constructors
+
+// NewSpendProposal returns a pointer to a proposal to pay an amount, in satoshis, to an address at a fee rate in
+// satoshis per vbyte, sending any change to the account's change address at changeIndex. Returns error if the address
+// is invalid or the amount is dust.
+func (a *JointAccount) NewSpendProposal(paymentAddress string, amount int, feeRate int, changeIndex int, memo string) (*JointSpendProposal, error) {
+	if _, err := AddressScript(paymentAddress); err != nil {
+		return nil, err
+	}
+	if amount < dustThreshold {
+		return nil, errors.New("amount is below dust threshold")
+	}
+	if feeRate < 1 {
+		return nil, errors.New("fee rate must be positive")
+	}
+	if err := validateDerivationIndex(changeIndex); err != nil {
+		return nil, err
+	}
+	return &JointSpendProposal{
+		PaymentAddress: paymentAddress,
+		Amount:         amount,
+		ChangeIndex:    changeIndex,
+		Memo:           memo,
+		feeRate:        feeRate,
+		account:        a,
+	}, nil
+}
+
+/// Receiver functions
+
+// AddUTXO adds a utxo of the account, paying to its receive (0) or change (1) address at an index, to those the
+// proposal may spend. The user approving a decoded proposal adds the account's utxos as their own app knows them, to
+// which `SignSpendProposal` checks the proposal's inputs.
+func (p *JointSpendProposal) AddUTXO(txid string, index int, amount int64, change int, addressIndex int) error {
+	if _, err := outPointFromInfo(txid, index); err != nil {
+		return err
+	}
+	if change != 0 && change != 1 {
+		return errors.New("change must be 0 or 1")
+	}
+	if err := validateDerivationIndex(addressIndex); err != nil {
+		return err
+	}
+	p.available = append(p.available, &jointAccountInput{Txid: txid, Index: index, Amount: amount, Change: change, AddressIndex: addressIndex})
+	return nil
+}
+
+// Generate selects utxos, in the order added, to pay the amount and fee, and builds the proposal's unsigned PSBT.
+// Returns ErrInsufficientFunds if the utxos cannot cover them.
+func (p *JointSpendProposal) Generate() error {
+	inputBytes, err := p.account.multisig.InputBytes()
+	if err != nil {
+		return err
+	}
+	outputBytes, err := p.account.multisig.basecoin.bytesPerOutputAddress(p.PaymentAddress)
+	if err != nil {
+		return err
+	}
+	change, err := p.account.ChangeAddressForIndex(p.ChangeIndex)
+	if err != nil {
+		return err
+	}
+	changeBytes, err := p.account.multisig.basecoin.bytesPerOutputAddress(change.Address)
+	if err != nil {
+		return err
+	}
+
+	size := baseSize + outputBytes
+	total := int64(0)
+	for i, input := range p.available {
+		total += input.Amount
+		size += inputBytes
+		if total < int64(p.Amount+p.feeRate*size) {
+			continue
+		}
+		p.inputs = p.available[:i+1]
+		p.ChangeAmount = 0
+		if changeAmount := total - int64(p.Amount+p.feeRate*(size+changeBytes)); changeAmount >= dustThreshold {
+			p.ChangeAmount = int(changeAmount)
+		}
+		p.FeeAmount = int(total) - p.Amount - p.ChangeAmount
+		encoded, err := p.buildPSBT()
+		if err != nil {
+			return err
+		}
+		p.psbt = encoded
+		return nil
+	}
+	return ErrInsufficientFunds
+}
+
+// PSBT returns the proposal's base64 encoded PSBT, with any signatures added, or "" if not generated.
+func (p *JointSpendProposal) PSBT() string {
+	return p.psbt
+}
+
+// UtxoCount returns the number of utxos the proposal spends.
+func (p *JointSpendProposal) UtxoCount() int {
+	return len(p.inputs)
+}
+
+// IsFullySigned returns true if both users have signed every input, so the account can finalize it.
+func (p *JointSpendProposal) IsFullySigned() bool {
+	packet, err := psbt.NewPsbt([]byte(p.psbt), true)
+	if err != nil {
+		return false
+	}
+	for _, input := range packet.Inputs {
+		if len(input.PartialSigs) < jointAccountThreshold {
+			return false
+		}
+	}
+	return true
+}
+
+// Encode returns the proposal, with its PSBT and any signatures, as a JSON string for passing to
+// `DecodeSpendProposal` in the other user's app. Returns error if not generated.
+func (p *JointSpendProposal) Encode() (string, error) {
+	if p.psbt == "" {
+		return "", errors.New("spend proposal has not been generated")
+	}
+	state := jointSpendProposalState{
+		Version:        jointSpendProposalVersion,
+		PaymentAddress: p.PaymentAddress,
+		Amount:         p.Amount,
+		FeeAmount:      p.FeeAmount,
+		ChangeAmount:   p.ChangeAmount,
+		ChangeIndex:    p.ChangeIndex,
+		Memo:           p.Memo,
+		Inputs:         p.inputs,
+		PSBT:           p.psbt,
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// DecodeSpendProposal parses a proposal of the account from a string returned by `Encode`, verifying that its PSBT
+// spends only the account's utxos to pay exactly the amount, change and fee it shows, so the user approves what will
+// be spent. Returns ErrJointSpendProposalMismatch if not.
+func (a *JointAccount) DecodeSpendProposal(encoded string) (*JointSpendProposal, error) {
+	var state jointSpendProposalState
+	if err := json.Unmarshal([]byte(encoded), &state); err != nil {
+		return nil, err
+	}
+	if state.Version != jointSpendProposalVersion {
+		return nil, errors.New("unsupported joint spend proposal version")
+	}
+	proposal := &JointSpendProposal{
+		PaymentAddress: state.PaymentAddress,
+		Amount:         state.Amount,
+		FeeAmount:      state.FeeAmount,
+		ChangeAmount:   state.ChangeAmount,
+		ChangeIndex:    state.ChangeIndex,
+		Memo:           state.Memo,
+		account:        a,
+		inputs:         state.Inputs,
+		psbt:           state.PSBT,
+	}
+	if err := proposal.verify(); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// SignSpendProposal approves a proposal of the account by adding the wallet's signature to each input of its PSBT.
+// Returns ErrJointSpendProposalUnknownUTXO unless each input is a utxo added to the proposal with `AddUTXO`, with the
+// same amount, address and index, or error if the wallet has already signed it.
+func (a *JointAccount) SignSpendProposal(proposal *JointSpendProposal) error {
+	if proposal == nil || proposal.psbt == "" {
+		return errors.New("spend proposal has not been generated")
+	}
+	if err := proposal.verify(); err != nil {
+		return err
+	}
+	if err := proposal.verifyKnownInputs(); err != nil {
+		return err
+	}
+	upTo := 0
+	for _, input := range proposal.inputs {
+		if input.AddressIndex+1 > upTo {
+			upTo = input.AddressIndex + 1
+		}
+	}
+	signed, err := a.wallet.SignMultisigPSBT(a.multisig, proposal.psbt, upTo)
+	if err != nil {
+		return err
+	}
+	proposal.psbt = signed
+	return nil
+}
+
+// FinalizeSpendProposal finalizes a proposal both users have signed and extracts the network-ready transaction.
+func (a *JointAccount) FinalizeSpendProposal(proposal *JointSpendProposal) (*TransactionMetadata, error) {
+	if proposal == nil || proposal.psbt == "" {
+		return nil, errors.New("spend proposal has not been generated")
+	}
+	if err := proposal.verify(); err != nil {
+		return nil, err
+	}
+	return a.wallet.FinalizePSBT(proposal.psbt)
+}
+
+/// Unexported functions
+
+// buildPSBT returns the unsigned PSBT of the selected inputs, payment and change, with each input's witness utxo and
+// script.
+func (p *JointSpendProposal) buildPSBT() (string, error) {
+	tx := wire.NewMsgTx(2)
+	addresses := make([]*MultisigAddress, len(p.inputs))
+	for i, input := range p.inputs {
+		outPoint, err := outPointFromInfo(input.Txid, input.Index)
+		if err != nil {
+			return "", err
+		}
+		txIn := wire.NewTxIn(outPoint, nil, nil)
+		txIn.Sequence = wire.MaxTxInSequenceNum - 2
+		tx.AddTxIn(txIn)
+		if addresses[i], err = p.account.multisig.addressForIndex(input.Change, input.AddressIndex); err != nil {
+			return "", err
+		}
+	}
+	paymentScript, err := AddressScript(p.PaymentAddress)
+	if err != nil {
+		return "", err
+	}
+	tx.AddTxOut(wire.NewTxOut(int64(p.Amount), paymentScript))
+	if p.ChangeAmount > 0 {
+		change, err := p.account.ChangeAddressForIndex(p.ChangeIndex)
+		if err != nil {
+			return "", err
+		}
+		changeScript, err := AddressScript(change.Address)
+		if err != nil {
+			return "", err
+		}
+		tx.AddTxOut(wire.NewTxOut(int64(p.ChangeAmount), changeScript))
+	}
+
+	packet, err := psbt.NewPsbtFromUnsignedTx(tx)
+	if err != nil {
+		return "", err
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return "", err
+	}
+	for i, address := range addresses {
+		pkScript, err := AddressScript(address.Address)
+		if err != nil {
+			return "", err
+		}
+		if err := updater.AddInWitnessUtxo(wire.NewTxOut(p.inputs[i].Amount, pkScript), i); err != nil {
+			return "", err
+		}
+		if err := updater.AddInWitnessScript(address.WitnessScript, i); err != nil {
+			return "", err
+		}
+	}
+	return packet.B64Encode()
+}
+
+// verifyKnownInputs returns ErrJointSpendProposalUnknownUTXO unless each input of the proposal is one of the utxos
+// added with `AddUTXO`.
+func (p *JointSpendProposal) verifyKnownInputs() error {
+	for _, input := range p.inputs {
+		known := false
+		for _, utxo := range p.available {
+			known = known || *utxo == *input
+		}
+		if !known {
+			return ErrJointSpendProposalUnknownUTXO
+		}
+	}
+	return nil
+}
+
+// verify returns ErrJointSpendProposalMismatch unless the proposal's PSBT spends exactly its inputs, each paying the
+// account's address it claims with its witness script, and pays its amount, change and fee.
+func (p *JointSpendProposal) verify() error {
+	packet, err := psbt.NewPsbt([]byte(p.psbt), true)
+	if err != nil {
+		return err
+	}
+	tx := packet.UnsignedTx
+	if len(p.inputs) == 0 || len(tx.TxIn) != len(p.inputs) {
+		return ErrJointSpendProposalMismatch
+	}
+
+	total := int64(0)
+	for i, input := range p.inputs {
+		if input == nil {
+			return ErrJointSpendProposalMismatch
+		}
+		outPoint, err := outPointFromInfo(input.Txid, input.Index)
+		if err != nil {
+			return err
+		}
+		address, err := p.account.multisig.addressForIndex(input.Change, input.AddressIndex)
+		if err != nil {
+			return err
+		}
+		pkScript, err := AddressScript(address.Address)
+		if err != nil {
+			return err
+		}
+		witnessUtxo := packet.Inputs[i].WitnessUtxo
+		if tx.TxIn[i].PreviousOutPoint != *outPoint || witnessUtxo == nil || witnessUtxo.Value != input.Amount ||
+			!bytes.Equal(witnessUtxo.PkScript, pkScript) || !bytes.Equal(packet.Inputs[i].WitnessScript, address.WitnessScript) {
+			return ErrJointSpendProposalMismatch
+		}
+		total += input.Amount
+	}
+
+	paymentScript, err := AddressScript(p.PaymentAddress)
+	if err != nil {
+		return err
+	}
+	expected := []*wire.TxOut{wire.NewTxOut(int64(p.Amount), paymentScript)}
+	if p.ChangeAmount > 0 {
+		change, err := p.account.ChangeAddressForIndex(p.ChangeIndex)
+		if err != nil {
+			return err
+		}
+		changeScript, err := AddressScript(change.Address)
+		if err != nil {
+			return err
+		}
+		expected = append(expected, wire.NewTxOut(int64(p.ChangeAmount), changeScript))
+	}
+	if len(tx.TxOut) != len(expected) {
+		return ErrJointSpendProposalMismatch
+	}
+	for i, out := range expected {
+		if tx.TxOut[i].Value != out.Value || !bytes.Equal(tx.TxOut[i].PkScript, out.PkScript) {
+			return ErrJointSpendProposalMismatch
+		}
+	}
+	if p.Amount <= 0 || p.ChangeAmount < 0 || total-int64(p.Amount+p.ChangeAmount) != int64(p.FeeAmount) {
+		return ErrJointSpendProposalMismatch
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package cnlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/stretchr/testify/assert"
+)
+
+const jointSpendProposalTestAddress = "bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6"
+
+func newTestJointSpendProposal(t *testing.T, account *JointAccount) *JointSpendProposal {
+	proposal, err := account.NewSpendProposal(jointSpendProposalTestAddress, 60000, 10, 0, "rent")
+	assert.Nil(t, err)
+	addTestJointAccountUTXOs(t, proposal)
+	assert.Nil(t, proposal.Generate())
+	return proposal
+}
+
+// addTestJointAccountUTXOs adds the utxos of the test joint account, as each user's app knows them.
+func addTestJointAccountUTXOs(t *testing.T, proposal *JointSpendProposal) {
+	assert.Nil(t, proposal.AddUTXO(descriptorTestTxid, 0, 50000, 0, 0))
+	assert.Nil(t, proposal.AddUTXO(descriptorTestTxid, 1, 40000, 0, 3))
+	assert.Nil(t, proposal.AddUTXO(descriptorTestTxid, 2, 30000, 1, 0))
+}
+
+func TestJointSpendProposal_ApprovedByBothUsers(t *testing.T) {
+	aliceAccount, bobAccount := newTestJointAccounts(t)
+	proposal := newTestJointSpendProposal(t, aliceAccount)
+	inputBytes, _ := aliceAccount.MultisigWallet().InputBytes()
+	assert.Equal(t, 2, proposal.UtxoCount())
+	assert.Equal(t, 10*(baseSize+2*inputBytes+2*p2wpkhOutputSize), proposal.FeeAmount)
+	assert.Equal(t, 90000-60000-proposal.FeeAmount, proposal.ChangeAmount)
+
+	// alice signs, and sends the proposal to bob
+	assert.Nil(t, aliceAccount.SignSpendProposal(proposal))
+	assert.False(t, proposal.IsFullySigned())
+	encoded, err := proposal.Encode()
+	assert.Nil(t, err)
+
+	received, err := bobAccount.DecodeSpendProposal(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, jointSpendProposalTestAddress, received.PaymentAddress)
+	assert.Equal(t, 60000, received.Amount)
+	assert.Equal(t, proposal.FeeAmount, received.FeeAmount)
+	assert.Equal(t, "rent", received.Memo)
+	assert.Equal(t, ErrJointSpendProposalUnknownUTXO, bobAccount.SignSpendProposal(received))
+	addTestJointAccountUTXOs(t, received)
+	assert.Nil(t, bobAccount.SignSpendProposal(received))
+	assert.True(t, received.IsFullySigned())
+	assert.NotNil(t, bobAccount.SignSpendProposal(received))
+
+	meta, err := bobAccount.FinalizeSpendProposal(received)
+	assert.Nil(t, err)
+	tx, err := decodeMsgTx(meta.EncodedTx)
+	assert.Nil(t, err)
+	packet, _ := psbt.NewPsbt([]byte(received.PSBT()), true)
+	for i := range tx.TxIn {
+		engine, err := txscript.NewEngine(packet.Inputs[i].WitnessUtxo.PkScript, tx, i, txscript.StandardVerifyFlags, nil,
+			txscript.NewTxSigHashes(tx), packet.Inputs[i].WitnessUtxo.Value)
+		assert.Nil(t, err)
+		assert.Nil(t, engine.Execute())
+	}
+	change, _ := aliceAccount.ChangeAddressForIndex(0)
+	changeScript, _ := AddressScript(change.Address)
+	assert.Equal(t, changeScript, tx.TxOut[1].PkScript)
+}
+
+func TestJointSpendProposal_RejectsMismatchedSummary(t *testing.T) {
+	aliceAccount, bobAccount := newTestJointAccounts(t)
+	encoded, err := newTestJointSpendProposal(t, aliceAccount).Encode()
+	assert.Nil(t, err)
+
+	// a summary showing less than the transaction pays
+	_, err = bobAccount.DecodeSpendProposal(strings.Replace(encoded, `"amount":60000`, `"amount":6000`, 1))
+	assert.Equal(t, ErrJointSpendProposalMismatch, err)
+	_, err = bobAccount.DecodeSpendProposal(strings.Replace(encoded, `"change_index":0`, `"change_index":1`, 1))
+	assert.Equal(t, ErrJointSpendProposalMismatch, err)
+	_, err = bobAccount.DecodeSpendProposal(strings.Replace(encoded, `"address_index":3`, `"address_index":4`, 1))
+	assert.Equal(t, ErrJointSpendProposalMismatch, err)
+
+	// a proposal of another joint account
+	carolKey, _ := multisigCosignerWallet(t, 0x02, BaseCoinBip84MainNet).JointAccountKey(0)
+	otherAccount, err := bobAccount.wallet.NewJointAccount(0, carolKey, 0)
+	assert.Nil(t, err)
+	_, err = otherAccount.DecodeSpendProposal(encoded)
+	assert.Equal(t, ErrJointSpendProposalMismatch, err)
+}
+
+func TestJointSpendProposal_RejectsOverstatedInputAmounts(t *testing.T) {
+	aliceAccount, bobAccount := newTestJointAccounts(t)
+
+	// the proposer overstates an input's amount in both the summary and its witness utxo, hiding a higher fee
+	proposal, err := aliceAccount.NewSpendProposal(jointSpendProposalTestAddress, 60000, 10, 0, "rent")
+	assert.Nil(t, err)
+	assert.Nil(t, proposal.AddUTXO(descriptorTestTxid, 0, 50000, 0, 0))
+	assert.Nil(t, proposal.AddUTXO(descriptorTestTxid, 1, 90000, 0, 3))
+	assert.Nil(t, proposal.Generate())
+	encoded, err := proposal.Encode()
+	assert.Nil(t, err)
+
+	received, err := bobAccount.DecodeSpendProposal(encoded)
+	assert.Nil(t, err)
+	addTestJointAccountUTXOs(t, received)
+	assert.Equal(t, ErrJointSpendProposalUnknownUTXO, bobAccount.SignSpendProposal(received))
+	packet, _ := psbt.NewPsbt([]byte(received.PSBT()), true)
+	assert.Equal(t, 0, len(packet.Inputs[0].PartialSigs))
+}
+
+func TestJointSpendProposal_Invalid(t *testing.T) {
+	aliceAccount, _ := newTestJointAccounts(t)
+	_, err := aliceAccount.NewSpendProposal("not an address", 60000, 10, 0, "")
+	assert.NotNil(t, err)
+	_, err = aliceAccount.NewSpendProposal(jointSpendProposalTestAddress, 500, 10, 0, "")
+	assert.NotNil(t, err)
+
+	proposal, err := aliceAccount.NewSpendProposal(jointSpendProposalTestAddress, 60000, 10, 0, "")
+	assert.Nil(t, err)
+	assert.NotNil(t, proposal.AddUTXO("not a txid", 0, 50000, 0, 0))
+	assert.NotNil(t, proposal.AddUTXO(descriptorTestTxid, 0, 50000, 2, 0))
+	assert.Nil(t, proposal.AddUTXO(descriptorTestTxid, 0, 50000, 0, 0))
+	_, err = proposal.Encode()
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrInsufficientFunds, proposal.Generate())
+	assert.NotNil(t, aliceAccount.SignSpendProposal(proposal))
+}
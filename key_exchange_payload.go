@@ -0,0 +1,173 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+const (
+	keyExchangePayloadVersion = byte(1)
+	keyExchangePayloadPrefix  = "cnkey:"
+	keyExchangePayloadLabel   = "key exchange"
+	keyExchangeSignatureSize  = 65
+	keyExchangeBodySize       = btcec.PubKeyBytesLenCompressed + 4 + 4 + keyExchangeSignatureSize
+)
+
+// ErrKeyExchangePayloadExpired describes an error in which a key exchange payload was verified after its expiry.
+var ErrKeyExchangePayloadExpired = errors.New("key exchange payload has expired")
+
+/// Type Definitions
+
+// KeyExchangePayload shares a user's identity key (m/42), used for encrypted messaging and verification, and their
+// wallet fingerprint with another user via QR code. It is signed by the identity key and expires, so a scanned code
+// cannot be altered or replayed indefinitely.
+//
+// Encoded as "cnkey:" followed by the base58check encoding, with the version as the version byte, of the compressed
+// public key (33 bytes), fingerprint (4 bytes), big-endian expiry in seconds since unix epoch (4 bytes) and compact
+// signature (65 bytes).
+type KeyExchangePayload struct {
+	PublicKey   string // hex-encoded compressed public key
+	Fingerprint string // hex-encoded master fingerprint
+	Expiry      int64  // seconds since unix epoch
+	signature   []byte
+}
+
+/// Constructors
+
+// NewKeyExchangePayload returns a payload sharing the wallet's identity key and fingerprint, valid until expiry, in
+// seconds since unix epoch. Returns error if the wallet is watch-only.
+func (wallet *HDWallet) NewKeyExchangePayload(expiry int64) (*KeyExchangePayload, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+	if expiry <= 0 || expiry > math.MaxUint32 {
+		return nil, errors.New("expiry out of bounds")
+	}
+
+	signingKey, err := wallet.signingPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := wallet.masterFingerprintBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	pubkey := signingKey.PubKey().SerializeCompressed()
+	signature, err := btcec.SignCompact(btcec.S256(), signingKey, keyExchangeDigest(pubkey, fingerprint, expiry), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyExchangePayload{
+		PublicKey:   hex.EncodeToString(pubkey),
+		Fingerprint: hex.EncodeToString(fingerprint),
+		Expiry:      expiry,
+		signature:   signature,
+	}, nil
+}
+
+/// Receiver functions
+
+// Encode returns the payload as a string for display in a QR code.
+func (p *KeyExchangePayload) Encode() (string, error) {
+	pubkey, fingerprint, err := p.decodedFields()
+	if err != nil {
+		return "", err
+	}
+	if len(p.signature) != keyExchangeSignatureSize {
+		return "", errors.New("key exchange payload is not signed")
+	}
+
+	body := make([]byte, 0, keyExchangeBodySize)
+	body = append(body, pubkey...)
+	body = append(body, fingerprint...)
+	body = append(body, keyExchangeExpiryBytes(p.Expiry)...)
+	body = append(body, p.signature...)
+	return keyExchangePayloadPrefix + base58.CheckEncode(body, keyExchangePayloadVersion), nil
+}
+
+// Verify returns error if the signature does not match the public key, or ErrKeyExchangePayloadExpired if the
+// payload has expired.
+func (p *KeyExchangePayload) Verify() error {
+	pubkey, fingerprint, err := p.decodedFields()
+	if err != nil {
+		return err
+	}
+
+	recovered, _, err := btcec.RecoverCompact(btcec.S256(), p.signature, keyExchangeDigest(pubkey, fingerprint, p.Expiry))
+	if err != nil || !bytes.Equal(recovered.SerializeCompressed(), pubkey) {
+		return errors.New("invalid key exchange signature")
+	}
+
+	if time.Now().Unix() > p.Expiry {
+		return ErrKeyExchangePayloadExpired
+	}
+	return nil
+}
+
+/// Exported functions
+
+// ParseKeyExchangePayload parses a string returned by `Encode`. It does not verify the payload; call `Verify`
+// before trusting its key.
+func ParseKeyExchangePayload(encoded string) (*KeyExchangePayload, error) {
+	if !strings.HasPrefix(strings.ToLower(encoded), keyExchangePayloadPrefix) {
+		return nil, errors.New("not a key exchange payload")
+	}
+
+	body, version, err := base58.CheckDecode(encoded[len(keyExchangePayloadPrefix):])
+	if err != nil {
+		return nil, err
+	}
+	if version != keyExchangePayloadVersion {
+		return nil, errors.New("unsupported key exchange payload version")
+	}
+	if len(body) != keyExchangeBodySize {
+		return nil, errors.New("invalid key exchange payload length")
+	}
+
+	pubkeyEnd := btcec.PubKeyBytesLenCompressed
+	return &KeyExchangePayload{
+		PublicKey:   hex.EncodeToString(body[:pubkeyEnd]),
+		Fingerprint: hex.EncodeToString(body[pubkeyEnd : pubkeyEnd+4]),
+		Expiry:      int64(binary.BigEndian.Uint32(body[pubkeyEnd+4 : pubkeyEnd+8])),
+		signature:   body[pubkeyEnd+8:],
+	}, nil
+}
+
+/// Unexported functions
+
+func (p *KeyExchangePayload) decodedFields() ([]byte, []byte, error) {
+	publicKey, err := parseHexPubKey(p.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	fingerprint, err := hex.DecodeString(p.Fingerprint)
+	if err != nil || len(fingerprint) != 4 {
+		return nil, nil, errors.New("master fingerprint must be 4 bytes")
+	}
+	if p.Expiry <= 0 || p.Expiry > math.MaxUint32 {
+		return nil, nil, errors.New("expiry out of bounds")
+	}
+	return publicKey.SerializeCompressed(), fingerprint, nil
+}
+
+// keyExchangeDigest returns the signed record digest of the version and the signed fields.
+func keyExchangeDigest(pubkey []byte, fingerprint []byte, expiry int64) []byte {
+	return signedRecordDigest(keyExchangePayloadLabel, keyExchangePayloadVersion, hex.EncodeToString(pubkey),
+		hex.EncodeToString(fingerprint), expiry)
+}
+
+func keyExchangeExpiryBytes(expiry int64) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(expiry))
+	return b
+}
@@ -0,0 +1,67 @@
+package cnlib
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyExchangePayload_EncodeParseVerify(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	expiry := time.Now().Unix() + 3600
+
+	payload, err := wallet.NewKeyExchangePayload(expiry)
+	assert.Nil(t, err)
+	identityKey, err := wallet.CoinNinjaVerificationKeyHexString()
+	assert.Nil(t, err)
+	assert.Equal(t, identityKey, payload.PublicKey)
+	assert.Equal(t, "73c5da0a", payload.Fingerprint)
+
+	encoded, err := payload.Encode()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "cnkey:"))
+
+	parsed, err := ParseKeyExchangePayload(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, payload, parsed)
+	assert.Nil(t, parsed.Verify())
+
+	// a recipient can encrypt to the shared key
+	_, err = wallet.EncryptMessage([]byte("hey dude"), parsed.PublicKey)
+	assert.Nil(t, err)
+}
+
+func TestKeyExchangePayload_AlteredFields_FailVerification(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	payload, err := wallet.NewKeyExchangePayload(time.Now().Unix() + 3600)
+	assert.Nil(t, err)
+
+	altered := *payload
+	altered.Expiry++
+	assert.EqualError(t, altered.Verify(), "invalid key exchange signature")
+
+	altered = *payload
+	altered.Fingerprint = "01020304"
+	assert.EqualError(t, altered.Verify(), "invalid key exchange signature")
+}
+
+func TestKeyExchangePayload_Expired_ReturnsError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	payload, err := wallet.NewKeyExchangePayload(time.Now().Unix() - 1)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrKeyExchangePayloadExpired, payload.Verify())
+}
+
+func TestParseKeyExchangePayload_Malformed_ReturnsError(t *testing.T) {
+	_, err := ParseKeyExchangePayload("bitcoin:bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu")
+	assert.EqualError(t, err, "not a key exchange payload")
+
+	_, err = ParseKeyExchangePayload("cnkey:1111")
+	assert.NotNil(t, err)
+
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	_, err = wallet.NewKeyExchangePayload(0)
+	assert.EqualError(t, err, "expiry out of bounds")
+}
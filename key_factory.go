@@ -1,6 +1,7 @@
 package cnlib
 
 import (
+	"bytes"
 	"encoding/hex"
 	"errors"
 
@@ -15,6 +16,7 @@ import (
 type keyFactory struct {
 	masterPrivateKey *hdkeychain.ExtendedKey
 	acctExtPubKey    *hdkeychain.ExtendedKey
+	cache            *derivationCache // optional, see `HDWallet.keyFactory`
 }
 
 var pubkeyIDs = map[string][]byte{
@@ -24,24 +26,35 @@ var pubkeyIDs = map[string][]byte{
 	tpub: []byte{0x04, 0x35, 0x87, 0xcf}, // m/44'/1'
 	upub: []byte{0x04, 0x4a, 0x52, 0x62}, // m/49'/1'
 	vpub: []byte{0x04, 0x5f, 0x1c, 0xf6}, // m/84'/1'
+	ltub: []byte{0x01, 0x9d, 0xa4, 0x62}, // m/44'/2'
+	mtub: []byte{0x01, 0xb2, 0x6e, 0xf6}, // m/49'/2'
 }
 
 /// Receiver methods
 
 func (kf keyFactory) indexPrivateKey(path *DerivationPath) (*hdkeychain.ExtendedKey, error) {
-	purposeKey, err := kf.masterPrivateKey.Child(hardened(path.Purpose))
-	if err != nil {
-		return nil, err
+	if path.IsCustom() {
+		return kf.customPathPrivateKey(path)
 	}
-	coinKey, err := purposeKey.Child(hardened(path.Coin))
-	if err != nil {
+	if err := path.Validate(); err != nil {
 		return nil, err
 	}
-	accountKey, err := coinKey.Child(hardened(path.Account))
-	if err != nil {
-		return nil, err
-	}
-	changeKey, err := accountKey.Child(uint32(path.Change))
+	key := derivationCacheKey{purpose: path.Purpose, coin: path.Coin, account: path.Account, change: path.Change}
+	changeKey, err := kf.cache.chainKey(key, func() (*hdkeychain.ExtendedKey, error) {
+		purposeKey, err := kf.masterPrivateKey.Child(hardened(path.Purpose))
+		if err != nil {
+			return nil, err
+		}
+		coinKey, err := purposeKey.Child(hardened(path.Coin))
+		if err != nil {
+			return nil, err
+		}
+		accountKey, err := coinKey.Child(hardened(path.Account))
+		if err != nil {
+			return nil, err
+		}
+		return accountKey.Child(uint32(path.Change))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -52,11 +65,31 @@ func (kf keyFactory) indexPrivateKey(path *DerivationPath) (*hdkeychain.Extended
 	return indexKey, nil
 }
 
+// customPathPrivateKey derives the key of a custom path from the master key, without caching.
+func (kf keyFactory) customPathPrivateKey(path *DerivationPath) (*hdkeychain.ExtendedKey, error) {
+	key := kf.masterPrivateKey
+	if key == nil {
+		return nil, errors.New("missing master private key")
+	}
+	for _, component := range path.components {
+		child, err := key.Child(component)
+		if err != nil {
+			return nil, err
+		}
+		key = child
+	}
+	return key, nil
+}
+
 // accountExtendedPublicKey returns the extended public key and its stringified version.
 func (kf keyFactory) accountExtendedPublicKey(bc *BaseCoin) (*hdkeychain.ExtendedKey, string, error) {
 	var key *hdkeychain.ExtendedKey
 
 	if kf.masterPrivateKey != nil {
+		if err := bc.validateIndices(); err != nil {
+			return nil, "", err
+		}
+
 		// derive account child
 		purposeKey, err := kf.masterPrivateKey.Child(hardened(bc.Purpose))
 		if err != nil {
@@ -80,35 +113,11 @@ func (kf keyFactory) accountExtendedPublicKey(bc *BaseCoin) (*hdkeychain.Extende
 		key = kf.acctExtPubKey
 	}
 
-	// base58check encode extended pubkey
-	neutered := key.String()
-
-	// get appropriate prefix
-	idType, err := bc.defaultExtendedPubkeyType()
-	if err != nil {
-		return nil, "", err
-	}
-	newPrefix := pubkeyIDs[idType]
-
-	// decode
-	decoded, version, err := base58.CheckDecode(neutered)
+	encoded, err := encodeExtendedPubkey(key, bc)
 	if err != nil {
 		return nil, "", err
 	}
 
-	if version != newPrefix[0] {
-		return nil, "", errors.New("version mismatch when decoding account pubkey")
-	}
-
-	// swap bytes. `version` has first byte, and needs to match first byte of prefix.
-	// `temp` does not need `version` to be first byte, CheckEncode will do that.
-	temp := make([]byte, len(decoded))
-	copy(temp[:3], newPrefix[1:4])
-	copy(temp[3:], decoded[3:])
-
-	// re-encode
-	encoded := base58.CheckEncode(temp, version)
-
 	return key, encoded, nil
 }
 
@@ -117,21 +126,49 @@ func (kf keyFactory) signingMasterKey() (*hdkeychain.ExtendedKey, error) {
 	if masterKey == nil {
 		return nil, errors.New("missing master private key")
 	}
-	childKey, err := masterKey.Child(42)
+	return kf.cache.signingMasterKey(func() (*hdkeychain.ExtendedKey, error) {
+		return masterKey.Child(42)
+	})
+}
+
+func (kf keyFactory) signData(message []byte) ([]byte, error) {
+	key, err := kf.signingMasterKey()
 	if err != nil {
 		return nil, err
 	}
-	return childKey, nil
-}
 
-func (kf keyFactory) signData(message []byte) ([]byte, error) {
-	messageHash := chainhash.DoubleHashB(message)
+	return signDataWithKey(message, key)
+}
 
-	key, err := kf.signingMasterKey()
+// signDataWithPath signs a message as `signData` does, with the key at a given derivation path.
+func (kf keyFactory) signDataWithPath(message []byte, path *DerivationPath) ([]byte, error) {
+	key, err := kf.indexPrivateKey(path)
 	if err != nil {
 		return nil, err
 	}
 
+	return signDataWithKey(message, key)
+}
+
+func (kf keyFactory) signatureSigningData(message []byte) (string, error) {
+	sign, err := kf.signData(message)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sign) == 0 {
+		return "", errors.New("signature is empty")
+	}
+
+	return hex.EncodeToString(sign), nil
+}
+
+/// Unexported functions
+
+// signDataWithKey signs the double-SHA256 hash of a message with an extended private key, returning a DER signature.
+func signDataWithKey(message []byte, key *hdkeychain.ExtendedKey) ([]byte, error) {
+	messageHash := chainhash.DoubleHashB(message)
+
 	privKey, err := key.ECPrivKey()
 	if err != nil {
 		return nil, err
@@ -152,15 +189,53 @@ func (kf keyFactory) signData(message []byte) ([]byte, error) {
 	return signature.Serialize(), nil
 }
 
-func (kf keyFactory) signatureSigningData(message []byte) (string, error) {
-	sign, err := kf.signData(message)
+// encodeExtendedPubkey base58check encodes an extended public key using the version prefix of the BaseCoin, such as
+// ypub or zpub.
+func encodeExtendedPubkey(key *hdkeychain.ExtendedKey, bc *BaseCoin) (string, error) {
+	// get appropriate prefix
+	idType, err := bc.defaultExtendedPubkeyType()
 	if err != nil {
 		return "", err
 	}
+	return encodeExtendedPubkeyWithType(key, idType)
+}
 
-	if len(sign) == 0 {
-		return "", errors.New("signature is empty")
+// encodeExtendedPubkeyWithType returns the extended public key encoded with the prefix of a given type, such as xpub.
+func encodeExtendedPubkeyWithType(key *hdkeychain.ExtendedKey, idType string) (string, error) {
+	if key == nil {
+		return "", errors.New("extended public key cannot be nil")
 	}
 
-	return hex.EncodeToString(sign), nil
+	// base58check encode extended pubkey
+	neutered := key.String()
+	newPrefix := pubkeyIDs[idType]
+
+	// decode
+	decoded, version, err := base58.CheckDecode(neutered)
+	if err != nil {
+		return "", err
+	}
+
+	if newPrefix == nil || len(decoded) < 3 || !isExtendedPubkeyVersion(append([]byte{version}, decoded[:3]...)) {
+		return "", errors.New("version mismatch when decoding account pubkey")
+	}
+
+	// swap bytes. CheckDecode split off the first byte of the version as `version`, and CheckEncode prepends the
+	// first byte of the new prefix, which differs for SLIP-132's Litecoin prefixes.
+	temp := make([]byte, len(decoded))
+	copy(temp[:3], newPrefix[1:4])
+	copy(temp[3:], decoded[3:])
+
+	// re-encode
+	return base58.CheckEncode(temp, newPrefix[0]), nil
+}
+
+// isExtendedPubkeyVersion returns true for the version bytes of any extended public key type.
+func isExtendedPubkeyVersion(version []byte) bool {
+	for _, id := range pubkeyIDs {
+		if bytes.Equal(version, id) {
+			return true
+		}
+	}
+	return false
 }
@@ -30,3 +30,42 @@ func TestSignatureSigningData(t *testing.T) {
 
 	assert.Equal(t, expectedSignString, str)
 }
+
+func TestSignDataWithPath(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	message := []byte("Hello World")
+
+	// the m/42 path signs as SignData does
+	identity, _ := ParseCustomDerivationPath("m/42")
+	signature, err := wallet.SignDataWithPath(message, identity)
+	assert.Nil(t, err)
+	assert.Equal(t, "3045022100c515fc2ed70810f6b1383cfe8e81b9b41b08682511e92d557f1b1719391b521d02200d9d734fd09ce60586ac48b0a7eb587a50958cd9fa548ffa39088fc6ada12eec", hex.EncodeToString(signature))
+
+	contact, _ := ParseCustomDerivationPath("m/1000'/7'")
+	str, err := wallet.SignatureSigningDataWithPath(message, contact)
+	assert.Nil(t, err)
+	pubkey, err := wallet.CompressedPubKeyForPath(contact)
+	assert.Nil(t, err)
+	signature, _ = hex.DecodeString(str)
+	assert.Nil(t, VerifySignature(message, signature, hex.EncodeToString(pubkey)))
+	signingPubkey, _ := wallet.SigningPublicKey()
+	assert.Equal(t, ErrSignatureInvalid, VerifySignature(message, signature, hex.EncodeToString(signingPubkey)))
+}
+
+func TestSignDataWithPath_Invalid(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	message := []byte("Hello World")
+
+	_, err := wallet.SignDataWithPath(message, NewDerivationPath(BaseCoinBip84MainNet, 0, 0))
+	assert.Equal(t, ErrKeyRoleMismatch, err)
+	encryption, _ := NewEncryptionKeyPath(0)
+	_, err = wallet.SignatureSigningDataWithPath(message, encryption)
+	assert.Equal(t, ErrKeyRoleMismatch, err)
+	_, err = wallet.SignDataWithPath(message, nil)
+	assert.NotNil(t, err)
+
+	contact, _ := ParseCustomDerivationPath("m/1000'/7'")
+	assert.Nil(t, wallet.SetRole(WalletRoleHot))
+	_, err = wallet.SignDataWithPath(message, contact)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
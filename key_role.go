@@ -0,0 +1,89 @@
+package cnlib
+
+import (
+	"errors"
+)
+
+// Following constants are used for the roles of a wallet's keys, each on its own derivation branch, as returned by
+// `KeyRoleForPath`.
+const (
+	KeyRoleUnassigned int = 0 // any other path, such as the app or protocol specific paths of Nostr or Lightning keys
+	KeyRoleIdentity   int = 1 // m/42 and below; signs app messages and payloads, as the CoinNinja verification key
+	KeyRoleEncryption int = 2 // m/42'/index'; derives symmetric keys for encrypting app records
+	KeyRoleSpending   int = 3 // BIP44, 48, 49, 84 and 86 paths of bitcoin, test networks or Litecoin; signs transactions only
+)
+
+const (
+	identityKeyBranch   = 42 // unhardened, as the long-standing m/42 signing key
+	encryptionKeyBranch = 42 // hardened, so its keys are unrelated to those of m/42
+)
+
+// spendingKeyPurposes are the BIP43 purposes of the wallet's transaction signing keys.
+var spendingKeyPurposes = map[uint32]bool{
+	hardened(44): true,
+	hardened(48): true,
+	hardened(49): true,
+	hardened(84): true,
+	hardened(86): true,
+}
+
+// ErrKeyRoleMismatch describes an error in which the caller passed a derivation path on the branch of a key role
+// other than the one the operation requires, such as a spending path to sign an arbitrary digest.
+var ErrKeyRoleMismatch = errors.New("derivation path is not on the branch of the key role required by this operation")
+
+/// Constructors
+
+// NewEncryptionKeyPath returns the path of the index'th encryption key, m/42'/index', for `DeriveEncryptionKey`.
+func NewEncryptionKeyPath(index int) (*DerivationPath, error) {
+	if err := validateDerivationIndex(index); err != nil {
+		return nil, err
+	}
+	return &DerivationPath{components: []uint32{hardened(encryptionKeyBranch), hardened(index)}}, nil
+}
+
+/// Exported functions
+
+// KeyRoleForPath returns the role of the key at a derivation path: `KeyRoleIdentity` (1), `KeyRoleEncryption` (2),
+// `KeyRoleSpending` (3), or `KeyRoleUnassigned` (0) for any other path.
+func KeyRoleForPath(path *DerivationPath) int {
+	if path == nil || (!path.IsCustom() && path.BaseCoin == nil) {
+		return KeyRoleUnassigned
+	}
+	components := path.bip32Path()
+	if len(components) == 0 {
+		return KeyRoleUnassigned
+	}
+
+	switch {
+	case components[0] == identityKeyBranch:
+		return KeyRoleIdentity
+	case components[0] == hardened(encryptionKeyBranch):
+		return KeyRoleEncryption
+	case spendingKeyPurposes[components[0]] && len(components) > 1:
+		if components[1] == hardened(mainnet) || components[1] == hardened(testnet) || components[1] == hardened(litecoin) {
+			return KeyRoleSpending
+		}
+	}
+	return KeyRoleUnassigned
+}
+
+/// Unexported functions
+
+// requireKeyRole returns ErrKeyRoleMismatch unless the path is on the branch of the given role.
+func requireKeyRole(path *DerivationPath, role int) error {
+	if KeyRoleForPath(path) != role {
+		return ErrKeyRoleMismatch
+	}
+	return nil
+}
+
+// rejectKeyRoles returns ErrKeyRoleMismatch if the path is on the branch of any of the given roles.
+func rejectKeyRoles(path *DerivationPath, roles ...int) error {
+	pathRole := KeyRoleForPath(path)
+	for _, role := range roles {
+		if pathRole == role {
+			return ErrKeyRoleMismatch
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRoleForPath(t *testing.T) {
+	encryption, err := NewEncryptionKeyPath(3)
+	assert.Nil(t, err)
+	assert.Equal(t, "m/42'/3'", encryption.String())
+	_, err = NewEncryptionKeyPath(-1)
+	assert.NotNil(t, err)
+
+	paths := map[string]int{
+		"m/42":            KeyRoleIdentity,
+		"m/42/0/1":        KeyRoleIdentity,
+		"m/42'/0'":        KeyRoleEncryption,
+		"m/44'/0'/0'/0/0": KeyRoleSpending,
+		"m/48'/1'/0'/2'":  KeyRoleSpending,
+		"m/86'/0'/0'/1/5": KeyRoleSpending,
+		"m/44'/1237'/0'":  KeyRoleUnassigned,
+		"m/1017'/0'/6'":   KeyRoleUnassigned,
+		"m/84'":           KeyRoleUnassigned,
+		"m/0":             KeyRoleUnassigned,
+	}
+	for encoded, role := range paths {
+		path, err := ParseCustomDerivationPath(encoded)
+		assert.Nil(t, err)
+		assert.Equal(t, role, KeyRoleForPath(path), encoded)
+	}
+	assert.Equal(t, KeyRoleSpending, KeyRoleForPath(NewDerivationPath(BaseCoinBip49TestNet, 1, 0)))
+	assert.Equal(t, KeyRoleSpending, KeyRoleForPath(NewDerivationPath(BaseCoinBip84Litecoin, 0, 0)))
+	assert.Equal(t, KeyRoleUnassigned, KeyRoleForPath(NewDerivationPath(NewBaseCoin(44, 1237, 0), 0, 0)))
+	assert.Equal(t, KeyRoleUnassigned, KeyRoleForPath(nil))
+	assert.Equal(t, KeyRoleUnassigned, KeyRoleForPath(&DerivationPath{}))
+}
+
+func TestKeyRole_SpendingAPIsRejectOtherKeys(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	identity, _ := ParseCustomDerivationPath("m/42")
+	encryption, _ := NewEncryptionKeyPath(0)
+
+	tree, _ := NewTaprootScriptTree(make([]byte, 32))
+	for _, path := range []*DerivationPath{identity, encryption} {
+		_, err := wallet.SignTaprootScriptPathInput("", 0, NewPrevoutSet(), tree, 0, path)
+		assert.Equal(t, ErrKeyRoleMismatch, err)
+		assert.Equal(t, ErrKeyRoleMismatch, wallet.SignTaprootMultisigSpend(&TaprootMultisigSpend{}, path))
+	}
+	_, err := wallet.SchnorrSignDigest(encryption, make([]byte, 32))
+	assert.Equal(t, ErrKeyRoleMismatch, err)
+
+	// an input paying to the identity key is not signed, though the key's origin is the wallet's
+	pubkey, _ := wallet.SigningPublicKey()
+	pkScript, _ := P2WPKHScript(btcutil.Hash160(pubkey))
+	rawTx, _ := NewRawTransaction(2, 0)
+	assert.Nil(t, rawTx.AddInput(NewRawTransactionInput(descriptorTestTxid, 0, 0xfffffffd)))
+	assert.Nil(t, rawTx.AddOutput(NewRawTransactionOutput(99000, pkScript)))
+	encodedTx, _ := rawTx.Encode()
+	tx, _ := decodeMsgTx(encodedTx)
+	packet, _ := psbt.NewPsbtFromUnsignedTx(tx)
+	updater, _ := psbt.NewUpdater(packet)
+	assert.Nil(t, updater.AddInWitnessUtxo(wire.NewTxOut(100000, pkScript), 0))
+	fingerprint, _, _ := wallet.psbtMasterFingerprint()
+	assert.Nil(t, updater.AddInBip32Derivation(fingerprint, identity.bip32Path(), pubkey, 0))
+	encoded, _ := packet.B64Encode()
+	_, err = wallet.SignPSBT(encoded, 0)
+	assert.EqualError(t, err, "no psbt inputs belong to wallet")
+}
@@ -0,0 +1,180 @@
+package cnlib
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+const keyTreeDiagnosticsVersion = 1
+
+// maxKeyTreeDiagnosticsIndex bounds the number of addresses per chain in a diagnostics dump.
+const maxKeyTreeDiagnosticsIndex = 1000
+
+/// Type Definitions
+
+// keyTreeDiagnostics is the serialized form of a redacted key tree dump. It holds only paths, fingerprints, public
+// keys and addresses; it never contains private keys, chain codes of private keys, or recovery words.
+type keyTreeDiagnostics struct {
+	Version           int                `json:"version"`
+	Network           string             `json:"network"`
+	Role              int                `json:"role"`
+	WatchOnly         bool               `json:"watch_only"`
+	MasterFingerprint string             `json:"master_fingerprint,omitempty"`
+	Account           keyTreeNode        `json:"account"`
+	Chains            []keyTreeChainNode `json:"chains"`
+}
+
+type keyTreeNode struct {
+	Path              string `json:"path"`
+	Fingerprint       string `json:"fingerprint"`
+	ParentFingerprint string `json:"parent_fingerprint"`
+	ExtendedPublicKey string `json:"xpub"`
+}
+
+type keyTreeChainNode struct {
+	keyTreeNode
+	Addresses []keyTreeAddressNode `json:"addresses"`
+}
+
+type keyTreeAddressNode struct {
+	Path        string `json:"path"`
+	Fingerprint string `json:"fingerprint"`
+	Address     string `json:"address"`
+}
+
+/// Receiver functions
+
+// KeyTreeDiagnostics returns a JSON dump of the wallet's account key tree for support tooling: the account and its
+// receive and change chains, with paths, fingerprints and extended public keys, and each chain's addresses up to a
+// given index. Private keys and recovery words are never included, so the dump is safe to share.
+func (wallet *HDWallet) KeyTreeDiagnostics(upTo int) (string, error) {
+	if upTo < 0 || upTo > maxKeyTreeDiagnosticsIndex {
+		return "", fmt.Errorf("index must be between 0 and %d", maxKeyTreeDiagnosticsIndex)
+	}
+
+	bc := wallet.BaseCoin
+	kf := wallet.keyFactory()
+	if kf.masterPrivateKey == nil && kf.acctExtPubKey == nil {
+		return "", errors.New("no valid master private key or account extended public key found")
+	}
+	accountKey, encodedAccountKey, err := kf.accountExtendedPublicKey(bc)
+	if err != nil {
+		return "", err
+	}
+
+	accountPath := fmt.Sprintf("m/%d'/%d'/%d'", bc.Purpose, bc.Coin, bc.Account)
+	accountFingerprint, err := extendedKeyFingerprint(accountKey)
+	if err != nil {
+		return "", err
+	}
+
+	dump := keyTreeDiagnostics{
+		Version:   keyTreeDiagnosticsVersion,
+		Network:   bc.network().name,
+		Role:      wallet.role,
+		WatchOnly: wallet.IsWatchOnly(),
+		Account: keyTreeNode{
+			Path:              accountPath,
+			Fingerprint:       accountFingerprint,
+			ParentFingerprint: fingerprintString(accountKey.ParentFingerprint()),
+			ExtendedPublicKey: encodedAccountKey,
+		},
+	}
+	fingerprint, err := wallet.Fingerprint()
+	if err != nil && err != ErrMasterFingerprintUnknown {
+		return "", err
+	}
+	dump.MasterFingerprint = fingerprint
+
+	for change := 0; change <= 1; change++ {
+		chain, err := keyTreeChain(accountKey, bc, accountPath, accountFingerprint, change, upTo)
+		if err != nil {
+			return "", err
+		}
+		dump.Chains = append(dump.Chains, *chain)
+	}
+
+	encoded, err := json.Marshal(dump)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+/// Unexported functions
+
+func keyTreeChain(accountKey *hdkeychain.ExtendedKey, bc *BaseCoin, accountPath string, accountFingerprint string, change int, upTo int) (*keyTreeChainNode, error) {
+	chainKey, err := accountKey.Child(uint32(change))
+	if err != nil {
+		return nil, err
+	}
+	encodedChainKey, err := encodeExtendedPubkey(chainKey, bc)
+	if err != nil {
+		return nil, err
+	}
+	chainFingerprint, err := extendedKeyFingerprint(chainKey)
+	if err != nil {
+		return nil, err
+	}
+
+	chainPath := fmt.Sprintf("%s/%d", accountPath, change)
+	chain := keyTreeChainNode{
+		keyTreeNode: keyTreeNode{
+			Path:              chainPath,
+			Fingerprint:       chainFingerprint,
+			ParentFingerprint: accountFingerprint,
+			ExtendedPublicKey: encodedChainKey,
+		},
+		Addresses: []keyTreeAddressNode{},
+	}
+
+	for i := 0; i < upTo; i++ {
+		indexKey, err := chainKey.Child(uint32(i))
+		if err != nil {
+			return nil, err
+		}
+		pubkey, err := indexKey.ECPubKey()
+		if err != nil {
+			return nil, err
+		}
+		address, err := generateAddress(NewDerivationPath(bc, change, i), pubkey)
+		if err != nil {
+			return nil, err
+		}
+		chain.Addresses = append(chain.Addresses, keyTreeAddressNode{
+			Path:        fmt.Sprintf("%s/%d", chainPath, i),
+			Fingerprint: pubkeyFingerprint(pubkey),
+			Address:     address,
+		})
+	}
+
+	return &chain, nil
+}
+
+// extendedKeyFingerprint returns the BIP32 fingerprint of an extended key, as hex.
+func extendedKeyFingerprint(key *hdkeychain.ExtendedKey) (string, error) {
+	pubkey, err := key.ECPubKey()
+	if err != nil {
+		return "", err
+	}
+	return pubkeyFingerprint(pubkey), nil
+}
+
+// pubkeyFingerprint returns the BIP32 fingerprint, the first 4 bytes of the hash160 of the compressed public key, as hex.
+func pubkeyFingerprint(pubkey *btcec.PublicKey) string {
+	return hex.EncodeToString(btcutil.Hash160(pubkey.SerializeCompressed())[:4])
+}
+
+// fingerprintString returns a fingerprint stored as a big-endian uint32, as hex.
+func fingerprintString(fingerprint uint32) string {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, fingerprint)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,52 @@
+package cnlib
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyTreeDiagnostics_WordsWallet(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+
+	dump, err := wallet.KeyTreeDiagnostics(2)
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(dump, "abandon"))
+	assert.False(t, strings.Contains(dump, "prv"))
+
+	var parsed keyTreeDiagnostics
+	assert.Nil(t, json.Unmarshal([]byte(dump), &parsed))
+	assert.Equal(t, "73c5da0a", parsed.MasterFingerprint)
+	assert.Equal(t, "m/84'/0'/0'", parsed.Account.Path)
+	assert.Equal(t, "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs", parsed.Account.ExtendedPublicKey)
+	assert.Equal(t, 2, len(parsed.Chains))
+	assert.Equal(t, "m/84'/0'/0'/1", parsed.Chains[1].Path)
+	assert.Equal(t, parsed.Account.Fingerprint, parsed.Chains[1].ParentFingerprint)
+	assert.Equal(t, "m/84'/0'/0'/0/0", parsed.Chains[0].Addresses[0].Path)
+	assert.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", parsed.Chains[0].Addresses[0].Address)
+	assert.Equal(t, "bc1qggnasd834t54yulsep6fta8lpjekv4zj6gv5rf", parsed.Chains[1].Addresses[1].Address)
+}
+
+func TestKeyTreeDiagnostics_WatchOnlyWalletMatchesTree(t *testing.T) {
+	words := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	xpub, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+
+	wordsDump, err := words.KeyTreeDiagnostics(3)
+	assert.Nil(t, err)
+	xpubDump, err := xpub.KeyTreeDiagnostics(3)
+	assert.Nil(t, err)
+
+	var fromWords, fromXpub keyTreeDiagnostics
+	assert.Nil(t, json.Unmarshal([]byte(wordsDump), &fromWords))
+	assert.Nil(t, json.Unmarshal([]byte(xpubDump), &fromXpub))
+	assert.Equal(t, "", fromXpub.MasterFingerprint)
+	assert.True(t, fromXpub.WatchOnly)
+	assert.Equal(t, fromWords.Account, fromXpub.Account)
+	assert.Equal(t, fromWords.Chains, fromXpub.Chains)
+
+	_, err = xpub.KeyTreeDiagnostics(-1)
+	assert.NotNil(t, err)
+}
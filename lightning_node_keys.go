@@ -0,0 +1,89 @@
+package cnlib
+
+import (
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+// Following constants are the key families of LND's keychain, used for a Lightning node's keys at
+// m/1017'/coin'/family'/0/index.
+const (
+	LightningKeyFamilyMultiSig       int = 0
+	LightningKeyFamilyRevocationBase int = 1
+	LightningKeyFamilyHtlcBase       int = 2
+	LightningKeyFamilyPaymentBase    int = 3
+	LightningKeyFamilyDelayBase      int = 4
+	LightningKeyFamilyRevocationRoot int = 5
+	LightningKeyFamilyNodeKey        int = 6
+	LightningKeyFamilyStaticBackup   int = 7
+	LightningKeyFamilyTowerSession   int = 8
+	LightningKeyFamilyTowerID        int = 9
+)
+
+const lightningKeychainPurpose = 1017 // LND's BIP43 purpose
+
+/// Receiver functions
+
+// LightningNodeExtendedPrivateKey returns the BIP32 root key of a companion Lightning node, base58 encoded as an
+// xprv, or tprv on testnet. The key is derived from the wallet's seed as BIP85 XPRV child `index`, so it is recovered
+// from the same backup, but shares no keys with the wallet's own accounts, so the node's on-chain funds do not
+// collide with the wallet's. Provision an LND node with it as its extended master key. Returns error if the wallet is
+// watch-only or the index is out of range.
+func (wallet *HDWallet) LightningNodeExtendedPrivateKey(index int) (string, error) {
+	root, err := wallet.lightningNodeRootKey(index)
+	if err != nil {
+		return "", err
+	}
+	return root.String(), nil
+}
+
+// LightningNodePublicKey returns the hex-encoded compressed identity public key, the first key of
+// `LightningKeyFamilyNodeKey`, that LND reports for a node provisioned with `LightningNodeExtendedPrivateKey`.
+func (wallet *HDWallet) LightningNodePublicKey(index int) (string, error) {
+	return wallet.LightningFamilyPublicKey(index, LightningKeyFamilyNodeKey, 0)
+}
+
+// LightningFamilyPublicKey returns the hex-encoded compressed public key at keyIndex of an LND key family, one of the
+// `LightningKeyFamily` constants, of the node provisioned with `LightningNodeExtendedPrivateKey(index)`. The coin is
+// that of the wallet's BaseCoin.
+func (wallet *HDWallet) LightningFamilyPublicKey(index int, family int, keyIndex int) (string, error) {
+	pubkey, err := wallet.lightningFamilyPublicKey(index, family, keyIndex)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(pubkey.SerializeCompressed()), nil
+}
+
+/// Unexported functions
+
+// lightningNodeRootKey returns the BIP85 XPRV child key at m/83696968'/32'/index': the chain code is the first half
+// of the entropy, and the private key the second.
+func (wallet *HDWallet) lightningNodeRootKey(index int) (*hdkeychain.ExtendedKey, error) {
+	if err := validateDerivationIndex(index); err != nil {
+		return nil, err
+	}
+	entropy, err := wallet.bip85Entropy(bip85ApplicationXPRV, index)
+	if err != nil {
+		return nil, err
+	}
+
+	version := wallet.BaseCoin.defaultNetParams().HDPrivateKeyID
+	return hdkeychain.NewExtendedKey(version[:], entropy[32:], entropy[:32], []byte{0, 0, 0, 0}, 0, 0, true), nil
+}
+
+// lightningFamilyPublicKey returns the public key at m/1017'/coin'/family'/0/keyIndex of the node's root key.
+func (wallet *HDWallet) lightningFamilyPublicKey(index int, family int, keyIndex int) (*btcec.PublicKey, error) {
+	root, err := wallet.lightningNodeRootKey(index)
+	if err != nil {
+		return nil, err
+	}
+	kf := keyFactory{masterPrivateKey: root}
+	path := NewDerivationPath(NewBaseCoin(lightningKeychainPurpose, wallet.BaseCoin.Coin, family), 0, keyIndex)
+	key, err := kf.indexPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.ECPubKey()
+}
@@ -0,0 +1,59 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLightningNodeExtendedPrivateKey_BIP85Vector(t *testing.T) {
+	wallet := bip85TestWallet(t)
+
+	key, err := wallet.LightningNodeExtendedPrivateKey(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "xprv9s21ZrQH143K2srSbCSg4m4kLvPMzcWydgmKEnMmoZUurYuBuYG46c6P71UGXMzmriLzCCBvKQWBUv3vPB3m1SATMhp3uEjXHJ42jFg7myX", key)
+
+	_, err = wallet.LightningNodeExtendedPrivateKey(-1)
+	assert.Equal(t, ErrDerivationIndexNegative, err)
+}
+
+func TestLightningNodePublicKey_MatchesFamilyKey(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	nodeKey, err := wallet.LightningNodePublicKey(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 66, len(nodeKey))
+
+	familyKey, err := wallet.LightningFamilyPublicKey(0, LightningKeyFamilyNodeKey, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, nodeKey, familyKey)
+
+	otherNode, err := wallet.LightningNodePublicKey(1)
+	assert.Nil(t, err)
+	assert.NotEqual(t, nodeKey, otherNode)
+
+	// the node key is derived from the node's root at m/1017'/0'/6'/0/0
+	encoded, err := wallet.LightningNodeExtendedPrivateKey(0)
+	assert.Nil(t, err)
+	root, err := hdkeychain.NewKeyFromString(encoded)
+	assert.Nil(t, err)
+	kf := keyFactory{masterPrivateKey: root}
+	expected, err := kf.indexPrivateKey(NewDerivationPath(NewBaseCoin(1017, 0, 6), 0, 0))
+	assert.Nil(t, err)
+	expectedPub, err := expected.ECPubKey()
+	assert.Nil(t, err)
+	assert.Equal(t, hex.EncodeToString(expectedPub.SerializeCompressed()), nodeKey)
+
+	testnet := NewHDWalletFromWords(w, BaseCoinBip84TestNet)
+	testnetKey, err := testnet.LightningNodeExtendedPrivateKey(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "tprv", testnetKey[:4])
+}
+
+func TestLightningNodeKeys_WatchOnly(t *testing.T) {
+	wallet, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	_, err = wallet.LightningNodePublicKey(0)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
@@ -0,0 +1,58 @@
+package cnlib
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+/// Constructors
+
+// NewLiteHDWalletFromWords returns a wallet as `NewHDWalletFromWords` does, for onboarding users who start with
+// messaging and other identity key features, deriving only the identity key (m/42) eagerly. The account key of the
+// BaseCoin, and its receive and change chains, are derived on first use, such as the first address or transaction,
+// which behave as on any wallet. Apps may call `WarmUp` in the background to derive them ahead of use. Returns error if
+// the BaseCoin is invalid.
+func NewLiteHDWalletFromWords(wordString string, basecoin *BaseCoin) (*HDWallet, error) {
+	if basecoin == nil {
+		return nil, errors.New("no basecoin provided")
+	}
+	if err := basecoin.validateIndices(); err != nil {
+		return nil, err
+	}
+	// as would deriving the account key
+	if _, err := basecoin.defaultExtendedPubkeyType(); err != nil {
+		return nil, err
+	}
+	masterKey, err := masterPrivateKey(wordString, basecoin)
+	if err != nil {
+		return nil, err
+	}
+	wallet := HDWallet{BaseCoin: basecoin, WalletWords: wordString, masterPrivateKey: masterKey, keyCache: newDerivationCache(), isLite: true}
+	if _, err := wallet.keyFactory().signingMasterKey(); err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+/// Receiver functions
+
+// IsLite returns true if the wallet was created by `NewLiteHDWalletFromWords`, whether or not its account key has
+// since been derived.
+func (wallet *HDWallet) IsLite() bool {
+	return wallet.isLite
+}
+
+/// Unexported functions
+
+// accountExtendedKey returns the wallet's account extended public key, deriving it on first use for lite wallets, or
+// nil if the wallet has neither.
+func (wallet *HDWallet) accountExtendedKey() (*hdkeychain.ExtendedKey, error) {
+	if wallet.accountPublicKey != nil || wallet.masterPrivateKey == nil {
+		return wallet.accountPublicKey, nil
+	}
+	return wallet.keyCache.accountExtendedKey(func() (*hdkeychain.ExtendedKey, error) {
+		key, _, err := keyFactory{masterPrivateKey: wallet.masterPrivateKey}.accountExtendedPublicKey(wallet.BaseCoin)
+		return key, err
+	})
+}
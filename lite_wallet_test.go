@@ -0,0 +1,57 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLiteHDWalletFromWords_DefersAccountKey(t *testing.T) {
+	full := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	lite, err := NewLiteHDWalletFromWords(w, BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	assert.True(t, lite.IsLite())
+	assert.False(t, full.IsLite())
+
+	// only the identity key is derived at construction
+	assert.Nil(t, lite.accountPublicKey)
+	assert.NotNil(t, lite.keyCache.signingKey)
+	assert.Nil(t, lite.keyCache.accountKey)
+	assert.Equal(t, 0, len(lite.keyCache.chainKeys))
+
+	expectedKey, _ := full.SigningPublicKey()
+	signingKey, err := lite.SigningPublicKey()
+	assert.Nil(t, err)
+	assert.Equal(t, expectedKey, signingKey)
+	assert.Equal(t, 0, len(lite.keyCache.chainKeys))
+
+	// spend chains behave as on any wallet once used
+	expected, _ := full.ReceiveAddressForIndex(4)
+	address, err := lite.ReceiveAddressForIndex(4)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Address, address.Address)
+	expectedFingerprint, _ := full.accountKeyFingerprint()
+	fingerprint, err := lite.accountKeyFingerprint()
+	assert.Nil(t, err)
+	assert.Equal(t, expectedFingerprint, fingerprint)
+	expectedXpub, _ := full.AccountExtendedPublicKey()
+	xpub, _ := lite.AccountExtendedPublicKey()
+	assert.Equal(t, expectedXpub, xpub)
+}
+
+func TestNewLiteHDWalletFromWords_WipeKeepsAccountKey(t *testing.T) {
+	lite, _ := NewLiteHDWalletFromWords(w, BaseCoinBip84MainNet)
+	expected, _ := lite.ReceiveAddressForIndex(0)
+	lite.wipePrivateKeys()
+	assert.True(t, lite.IsWatchOnly())
+	address, err := lite.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Address, address.Address)
+}
+
+func TestNewLiteHDWalletFromWords_Invalid(t *testing.T) {
+	_, err := NewLiteHDWalletFromWords(w, nil)
+	assert.NotNil(t, err)
+	_, err = NewLiteHDWalletFromWords(w, NewBaseCoin(85, 0, 0))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,179 @@
+package cnlib
+
+import (
+	"errors"
+	"math"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Following constants are used for LocktimeForTargetTime.
+const (
+	LocktimeTypeBlockHeight int = 0
+	LocktimeTypeTimestamp   int = 1
+)
+
+const (
+	targetBlockInterval = 600 // seconds
+
+	// medianTimePastLag is how far the median time past of the last 11 blocks, which timestamp locktimes are compared
+	// against per BIP113, typically trails the time of the tip: about six block intervals.
+	medianTimePastLag = 6 * targetBlockInterval
+
+	relativeLocktimeGranularity = 1 << wire.SequenceLockTimeGranularity // seconds
+	maxRelativeLocktimeValue    = wire.SequenceLockTimeMask
+)
+
+/// Type Definitions
+
+// AbsoluteLocktime describes a transaction locktime, or CHECKLOCKTIMEVERIFY argument, which is either the last block
+// height or the last median time past at which the transaction is invalid.
+type AbsoluteLocktime struct {
+	Value          int64
+	IsTimestamp    bool
+	MinBlockHeight int   // lowest height of a block which may include the transaction, 0 if IsTimestamp
+	MinTimestamp   int64 // median time past a block must reach to include the transaction, 0 unless IsTimestamp
+}
+
+// RelativeLocktime describes a BIP68 input sequence, or CHECKSEQUENCEVERIFY argument, which delays spending an output
+// by a number of blocks or an amount of time after it confirms. Only enforced in version 2 transactions.
+type RelativeLocktime struct {
+	Sequence    int64
+	IsTimeBased bool
+	Blocks      int   // 0 if IsTimeBased
+	Seconds     int64 // a multiple of 512, 0 unless IsTimeBased
+}
+
+/// Exported functions
+
+// LocktimeForMinBlockHeight returns the locktime which allows a transaction to be included from a given block height.
+func LocktimeForMinBlockHeight(height int) (*AbsoluteLocktime, error) {
+	if height < 1 || int64(height)-1 >= locktimeThreshold {
+		return nil, errors.New("block height out of range for locktime")
+	}
+	return DecodeLocktime(int64(height) - 1)
+}
+
+// LocktimeForMinTimestamp returns the locktime which allows a transaction to be included once the median time past
+// reaches a given unix timestamp. Note that the median time past trails the current time by about an hour.
+func LocktimeForMinTimestamp(timestamp int64) (*AbsoluteLocktime, error) {
+	if timestamp-1 < locktimeThreshold || timestamp-1 > math.MaxUint32 {
+		return nil, errors.New("timestamp out of range for locktime")
+	}
+	return DecodeLocktime(timestamp - 1)
+}
+
+// LocktimeForTargetTime returns a locktime of the given type, `LocktimeTypeBlockHeight` or `LocktimeTypeTimestamp`,
+// which is expected to allow a transaction to be mined from a target unix time, given the current block height and
+// time. Block heights are estimated at one block every ten minutes, so may be reached early or late; timestamps
+// account for the median time past trailing the current time.
+func LocktimeForTargetTime(targetTime int64, currentHeight int, now int64, locktimeType int) (*AbsoluteLocktime, error) {
+	switch locktimeType {
+	case LocktimeTypeBlockHeight:
+		return LocktimeForMinBlockHeight(EstimatedBlockHeightAtTime(currentHeight, now, targetTime))
+	case LocktimeTypeTimestamp:
+		return LocktimeForMinTimestamp(targetTime - medianTimePastLag)
+	}
+	return nil, errors.New("unrecognized locktime type")
+}
+
+// DecodeLocktime describes a locktime value, or error if out of range.
+func DecodeLocktime(locktime int64) (*AbsoluteLocktime, error) {
+	if locktime < 0 || locktime > math.MaxUint32 {
+		return nil, errors.New("locktime out of range")
+	}
+	l := AbsoluteLocktime{Value: locktime, IsTimestamp: locktime >= locktimeThreshold}
+	if l.IsTimestamp {
+		l.MinTimestamp = locktime + 1
+	} else {
+		l.MinBlockHeight = int(locktime) + 1
+	}
+	return &l, nil
+}
+
+// EstimatedBlockHeightAtTime returns the height of the block expected to be mined at a unix time, at one block every
+// ten minutes from the current height and time. Returns the next height for times already past.
+func EstimatedBlockHeightAtTime(currentHeight int, now int64, targetTime int64) int {
+	if targetTime <= now {
+		return currentHeight + 1
+	}
+	blocks := (targetTime - now + targetBlockInterval - 1) / targetBlockInterval
+	return currentHeight + int(blocks)
+}
+
+// EstimatedTimeAtBlockHeight returns the unix time at which a block height is expected to be mined, at one block
+// every ten minutes from the current height and time.
+func EstimatedTimeAtBlockHeight(currentHeight int, now int64, height int) int64 {
+	return now + int64(height-currentHeight)*targetBlockInterval
+}
+
+// RelativeLocktimeForBlocks returns the relative locktime delaying spending by a number of blocks after confirmation.
+func RelativeLocktimeForBlocks(blocks int) (*RelativeLocktime, error) {
+	if blocks < 0 || blocks > maxRelativeLocktimeValue {
+		return nil, errors.New("relative locktime blocks must be between 0 and 65535")
+	}
+	return DecodeRelativeLocktime(int64(blocks))
+}
+
+// RelativeLocktimeForSeconds returns the relative locktime delaying spending by at least a number of seconds after
+// confirmation, rounded up to the 512 second granularity BIP68 supports.
+func RelativeLocktimeForSeconds(seconds int64) (*RelativeLocktime, error) {
+	units := (seconds + relativeLocktimeGranularity - 1) / relativeLocktimeGranularity
+	if seconds < 0 || units > maxRelativeLocktimeValue {
+		return nil, errors.New("relative locktime seconds must be between 0 and 33553920")
+	}
+	return DecodeRelativeLocktime(int64(wire.SequenceLockTimeIsSeconds) | units)
+}
+
+// DecodeRelativeLocktime validates and describes a BIP68 sequence or CHECKSEQUENCEVERIFY argument. Returns error if
+// the disable flag is set, which would leave the output without a relative locktime, or if bits other than the type
+// flag and 16-bit value are set, which consensus ignores but which suggest a corrupted encoding.
+func DecodeRelativeLocktime(sequence int64) (*RelativeLocktime, error) {
+	if sequence < 0 || sequence > math.MaxUint32 {
+		return nil, errors.New("relative locktime out of range")
+	}
+	if sequence&wire.SequenceLockTimeDisabled != 0 {
+		return nil, errors.New("relative locktime is disabled")
+	}
+	if sequence&^(wire.SequenceLockTimeIsSeconds|wire.SequenceLockTimeMask) != 0 {
+		return nil, errors.New("relative locktime has reserved bits set")
+	}
+
+	r := RelativeLocktime{Sequence: sequence, IsTimeBased: sequence&wire.SequenceLockTimeIsSeconds != 0}
+	value := sequence & wire.SequenceLockTimeMask
+	if r.IsTimeBased {
+		r.Seconds = value * relativeLocktimeGranularity
+	} else {
+		r.Blocks = int(value)
+	}
+	return &r, nil
+}
+
+/// Receiver functions
+
+// IsSatisfiedAt returns true if the locktime allows the transaction to be included in a block at a given height,
+// whose previous blocks have a given median time past.
+func (l *AbsoluteLocktime) IsSatisfiedAt(blockHeight int, medianTimePast int64) bool {
+	if l.IsTimestamp {
+		return medianTimePast >= l.MinTimestamp
+	}
+	return blockHeight >= l.MinBlockHeight
+}
+
+// EstimatedTime returns the unix time from which the locktime is expected to allow the transaction to be mined, given
+// the current block height and time.
+func (l *AbsoluteLocktime) EstimatedTime(currentHeight int, now int64) int64 {
+	if l.IsTimestamp {
+		return l.MinTimestamp + medianTimePastLag
+	}
+	return EstimatedTimeAtBlockHeight(currentHeight, now, l.MinBlockHeight)
+}
+
+// IsSatisfiedAt returns true if an output with the given number of confirmations can be spent in the next block.
+// elapsedMedianTime is the median time past of the tip minus that of the block before the output's block.
+func (r *RelativeLocktime) IsSatisfiedAt(confirmations int, elapsedMedianTime int64) bool {
+	if r.IsTimeBased {
+		return r.Seconds == 0 || (confirmations > 0 && elapsedMedianTime >= r.Seconds)
+	}
+	return confirmations >= r.Blocks
+}
@@ -0,0 +1,90 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocktimeForMinBlockHeight(t *testing.T) {
+	l, err := LocktimeForMinBlockHeight(700000)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(699999), l.Value)
+	assert.False(t, l.IsTimestamp)
+	assert.False(t, l.IsSatisfiedAt(699999, 0))
+	assert.True(t, l.IsSatisfiedAt(700000, 0))
+
+	_, err = LocktimeForMinBlockHeight(500000001)
+	assert.EqualError(t, err, "block height out of range for locktime")
+	_, err = LocktimeForMinBlockHeight(0)
+	assert.NotNil(t, err)
+}
+
+func TestLocktimeForTargetTime(t *testing.T) {
+	now := int64(1700000000)
+	target := now + 24*60*60
+
+	byHeight, err := LocktimeForTargetTime(target, 800000, now, LocktimeTypeBlockHeight)
+	assert.Nil(t, err)
+	assert.Equal(t, 800144, byHeight.MinBlockHeight)
+	assert.Equal(t, target, byHeight.EstimatedTime(800000, now))
+
+	// median time past trails the tip by about an hour
+	byTime, err := LocktimeForTargetTime(target, 800000, now, LocktimeTypeTimestamp)
+	assert.Nil(t, err)
+	assert.True(t, byTime.IsTimestamp)
+	assert.Equal(t, target-3600, byTime.MinTimestamp)
+	assert.Equal(t, target, byTime.EstimatedTime(800000, now))
+	assert.False(t, byTime.IsSatisfiedAt(900000, target-3601))
+	assert.True(t, byTime.IsSatisfiedAt(0, target-3600))
+
+	_, err = LocktimeForTargetTime(target, 800000, now, 2)
+	assert.EqualError(t, err, "unrecognized locktime type")
+}
+
+func TestDecodeLocktime_MatchesReplaceabilitySemantics(t *testing.T) {
+	l, err := DecodeLocktime(500000000)
+	assert.Nil(t, err)
+	assert.True(t, l.IsTimestamp)
+	assert.Equal(t, int64(500000001), l.MinTimestamp)
+	assert.Equal(t, 0, l.MinBlockHeight)
+
+	_, err = DecodeLocktime(1 << 32)
+	assert.EqualError(t, err, "locktime out of range")
+	assert.Equal(t, 590583, EstimatedBlockHeightAtTime(590582, 1000, 900))
+}
+
+func TestRelativeLocktime(t *testing.T) {
+	blocks, err := RelativeLocktimeForBlocks(144)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(144), blocks.Sequence)
+	assert.False(t, blocks.IsSatisfiedAt(143, 0))
+	assert.True(t, blocks.IsSatisfiedAt(144, 0))
+
+	// rounded up to 512 second units, with the type flag set
+	week, err := RelativeLocktimeForSeconds(7 * 24 * 60 * 60)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1<<22|1182), week.Sequence)
+	assert.Equal(t, int64(1182*512), week.Seconds)
+	assert.False(t, week.IsSatisfiedAt(1000, 7*24*60*60))
+	assert.True(t, week.IsSatisfiedAt(1000, 1182*512))
+
+	_, err = RelativeLocktimeForBlocks(65536)
+	assert.NotNil(t, err)
+	_, err = RelativeLocktimeForSeconds(65536 * 512)
+	assert.NotNil(t, err)
+}
+
+func TestDecodeRelativeLocktime_InvalidEncodings(t *testing.T) {
+	decoded, err := DecodeRelativeLocktime(1<<22 | 10)
+	assert.Nil(t, err)
+	assert.True(t, decoded.IsTimeBased)
+	assert.Equal(t, int64(5120), decoded.Seconds)
+
+	_, err = DecodeRelativeLocktime(1<<31 | 10)
+	assert.EqualError(t, err, "relative locktime is disabled")
+	_, err = DecodeRelativeLocktime(1<<16 | 10)
+	assert.EqualError(t, err, "relative locktime has reserved bits set")
+	_, err = DecodeRelativeLocktime(-1)
+	assert.EqualError(t, err, "relative locktime out of range")
+}
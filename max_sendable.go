@@ -0,0 +1,107 @@
+package cnlib
+
+import "errors"
+
+// Following constants describe the output type of a destination, such as a send max destination whose address is not
+// yet known.
+const (
+	DestinationTypeP2PKH  int = 0
+	DestinationTypeP2SH   int = 1
+	DestinationTypeP2WPKH int = 2
+	DestinationTypeP2WSH  int = 3
+	DestinationTypeP2TR   int = 4
+	DestinationTypeP2PK   int = 5
+)
+
+/// Type Definitions
+
+// MaxSendableCalculator computes the largest amount a send max transaction can deliver, for a "Use max" button.
+// Add all of the wallet's spendable utxos one at a time using `AddUTXO`, as gomobile does not support custom
+// arrays/slices.
+type MaxSendableCalculator struct {
+	basecoin *BaseCoin
+	utxos    []*UTXO
+}
+
+/// Constructors
+
+// NewMaxSendableCalculator returns a pointer to an empty MaxSendableCalculator for the given BaseCoin.
+func NewMaxSendableCalculator(basecoin *BaseCoin) *MaxSendableCalculator {
+	return &MaxSendableCalculator{basecoin: basecoin, utxos: []*UTXO{}}
+}
+
+/// Receiver functions
+
+// AddUTXO adds a spendable utxo.
+func (c *MaxSendableCalculator) AddUTXO(utxo *UTXO) {
+	c.utxos = append(c.utxos, utxo)
+}
+
+// UtxoCount returns the number of utxos added.
+func (c *MaxSendableCalculator) UtxoCount() int {
+	return len(c.utxos)
+}
+
+// MaxSendableAmount returns the amount a `TransactionDataSendMax` to a destination of the given type would send at
+// feeRate, with no change output. Utxos worth no more than the fee to spend them are left out, as the builder does.
+// Returns 0 if the amount would be below the dust threshold.
+func (c *MaxSendableCalculator) MaxSendableAmount(feeRate int, destinationType int) (int, error) {
+	if feeRate < 0 {
+		return 0, errors.New("fee rate cannot be negative")
+	}
+	outputBytes, err := bytesPerDestinationType(destinationType)
+	if err != nil {
+		return 0, err
+	}
+
+	amount, _, err := c.basecoin.maxSendable(c.utxos, int64(feeRate), outputBytes)
+	if err != nil {
+		return 0, err
+	}
+	if amount < dustThreshold {
+		return 0, nil
+	}
+	return int(amount), nil
+}
+
+/// Unexported functions
+
+// maxSendable returns the amount left after fees from spending the economical utxos, those worth more than the fee
+// to spend them, to a single output of outputBytes, and the utxos spent. The amount may be negative.
+func (bc *BaseCoin) maxSendable(utxos []*UTXO, feeRate int64, outputBytes int) (int64, []*UTXO, error) {
+	spent := make([]*UTXO, 0, len(utxos))
+	total := int64(0)
+	totalBytes := baseSize + outputBytes
+
+	for _, utxo := range utxos {
+		inputBytes, err := bc.bytesPerInput(utxo)
+		if err != nil {
+			return 0, nil, err
+		}
+		if utxo.Amount <= feeRate*int64(inputBytes) {
+			continue
+		}
+		spent = append(spent, utxo)
+		total += utxo.Amount
+		totalBytes += inputBytes
+	}
+
+	return total - feeRate*int64(totalBytes), spent, nil
+}
+
+func bytesPerDestinationType(destinationType int) (int, error) {
+	switch destinationType {
+	case DestinationTypeP2PKH:
+		return p2pkhOutputSize, nil
+	case DestinationTypeP2SH:
+		return p2shOutputSize, nil
+	case DestinationTypeP2WPKH, DestinationTypeP2WSH:
+		// sized as the builder sizes P2WSH destinations
+		return p2wpkhOutputSize, nil
+	case DestinationTypeP2TR:
+		return p2trOutputSize, nil
+	case DestinationTypeP2PK:
+		return p2pkOutputSize, nil
+	}
+	return 0, errors.New("invalid destination type")
+}
@@ -0,0 +1,78 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxSendableCalculator_SkipsUneconomicalUTXOs(t *testing.T) {
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	utxo1 := NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", 0, 100000, path, nil, true)
+	utxo2 := NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", 1, 500, path, nil, true)
+	calculator := NewMaxSendableCalculator(BaseCoinBip84MainNet)
+	calculator.AddUTXO(utxo1)
+	calculator.AddUTXO(utxo2)
+
+	amount, err := calculator.MaxSendableAmount(10, DestinationTypeP2WPKH)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calculator.UtxoCount())
+	assert.Equal(t, 100000-10*(baseSize+p2wpkhSegwitInputSize+p2wpkhOutputSize), amount)
+
+	amount, err = calculator.MaxSendableAmount(10, DestinationTypeP2TR)
+	assert.Nil(t, err)
+	assert.Equal(t, 100000-10*(baseSize+p2wpkhSegwitInputSize+p2trOutputSize), amount)
+
+	amount, err = calculator.MaxSendableAmount(10, DestinationTypeP2PK)
+	assert.Nil(t, err)
+	assert.Equal(t, 100000-10*(baseSize+p2wpkhSegwitInputSize+p2pkOutputSize), amount)
+}
+
+func TestP2PKOutputSize_MatchesSerializedOutput(t *testing.T) {
+	pubkey, err := hex.DecodeString("02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9")
+	assert.Nil(t, err)
+	script, err := txscript.NewScriptBuilder().AddData(pubkey).AddOp(txscript.OP_CHECKSIG).Script()
+	assert.Nil(t, err)
+	assert.Equal(t, p2pkOutputSize, wire.NewTxOut(100000, script).SerializeSize())
+}
+
+func TestMaxSendableCalculator_MatchesSendMaxBuilder(t *testing.T) {
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	utxo1 := NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", 0, 100000, path, nil, true)
+	utxo2 := NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", 1, 500, path, nil, true)
+	calculator := NewMaxSendableCalculator(BaseCoinBip84MainNet)
+	data := NewTransactionDataSendingMax("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", BaseCoinBip84MainNet, 10, 500000)
+	for _, utxo := range []*UTXO{utxo1, utxo2} {
+		calculator.AddUTXO(utxo)
+		data.AddUTXO(utxo)
+	}
+
+	amount, err := calculator.MaxSendableAmount(10, DestinationTypeP2WPKH)
+	assert.Nil(t, err)
+	assert.Nil(t, data.Generate())
+	assert.Equal(t, amount, data.TransactionData.Amount)
+	assert.Equal(t, 1, data.TransactionData.UtxoCount())
+}
+
+func TestMaxSendableCalculator_BelowDust_ReturnsZero(t *testing.T) {
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	calculator := NewMaxSendableCalculator(BaseCoinBip84MainNet)
+	calculator.AddUTXO(NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", 0, 1500, path, nil, true))
+
+	amount, err := calculator.MaxSendableAmount(10, DestinationTypeP2WPKH)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, amount)
+}
+
+func TestMaxSendableCalculator_InvalidDestinationType_ReturnsError(t *testing.T) {
+	calculator := NewMaxSendableCalculator(BaseCoinBip84MainNet)
+
+	_, err := calculator.MaxSendableAmount(10, 9)
+	assert.EqualError(t, err, "invalid destination type")
+
+	_, err = calculator.MaxSendableAmount(-1, DestinationTypeP2WPKH)
+	assert.EqualError(t, err, "fee rate cannot be negative")
+}
@@ -0,0 +1,75 @@
+package cnlib
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// ErrSignedMessageAddressType describes an error in which the caller signed a message for an address type BIP137
+// does not define, such as taproot or multisig.
+var ErrSignedMessageAddressType = errors.New("signed messages are only defined for P2PKH, P2SH-P2WPKH and P2WPKH addresses")
+
+/// Receiver functions
+
+// SignMessageForAddress signs a message with the key of the address at a BIP44, 49 or 84 derivation path, and returns
+// the base64 encoded recoverable signature of Bitcoin Core's signmessage, with the BIP137 header of the address type,
+// as exchanges and airdrop tools accept as proof of ownership. The message is prefixed with "Bitcoin Signed Message:\n"
+// before hashing, so the signature cannot be that of a transaction. Returns ErrSignedMessageAddressType for other
+// paths, or ErrKeyRoleMismatch if the path is not of a spending key.
+func (wallet *HDWallet) SignMessageForAddress(message string, path *DerivationPath) (string, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return "", err
+	}
+	if path == nil {
+		return "", errors.New("derivation path cannot be nil")
+	}
+	if err := requireKeyRole(path, KeyRoleSpending); err != nil {
+		return "", err
+	}
+
+	var header byte
+	switch path.bip32Path()[0] {
+	case hardened(bip44purpose):
+		header = bip137HeaderP2PKH
+	case hardened(bip49purpose):
+		header = bip137HeaderP2SHP2WPKH
+	case hardened(bip84purpose):
+		header = bip137HeaderP2WPKH
+	default:
+		return "", ErrSignedMessageAddressType
+	}
+
+	key, err := wallet.keyFactory().indexPrivateKey(path)
+	if err != nil {
+		return "", err
+	}
+	privateKey, err := key.ECPrivKey()
+	if err != nil {
+		return "", err
+	}
+	signature, err := btcec.SignCompact(btcec.S256(), privateKey, legacyMessageHash(message), true)
+	if err != nil {
+		return "", err
+	}
+	signature[0] = header + (signature[0]-bip137HeaderP2PKH)%4
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+/// Exported functions
+
+// VerifySignedMessage verifies a base64 encoded signmessage signature, with a BIP137 or Electrum style header, of a
+// message by the key of a P2PKH, P2SH-P2WPKH or P2WPKH address on any supported network. Returns nil if valid,
+// ErrSignedMessageAddressMismatch if signed by another key, or error if malformed.
+func VerifySignedMessage(address string, message string, signature string) error {
+	network, err := networkForAddress(address)
+	if err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	return verifyLegacySignedMessage(address, message, decoded, network.chainParams)
+}
@@ -0,0 +1,91 @@
+package cnlib
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignMessageForAddress_RoundTrip(t *testing.T) {
+	tests := []struct {
+		basecoin *BaseCoin
+		header   byte
+	}{
+		{NewBaseCoin(44, 0, 0), bip137HeaderP2PKH},
+		{BaseCoinBip49MainNet, bip137HeaderP2SHP2WPKH},
+		{BaseCoinBip84MainNet, bip137HeaderP2WPKH},
+		{BaseCoinBip84TestNet, bip137HeaderP2WPKH},
+	}
+	for _, test := range tests {
+		wallet := NewHDWalletFromWords(w, test.basecoin)
+		path := NewDerivationPath(test.basecoin, 0, 2)
+		meta, err := wallet.ReceiveAddressForIndex(2)
+		assert.Nil(t, err)
+
+		signature, err := wallet.SignMessageForAddress("airdrop claim", path)
+		assert.Nil(t, err)
+		decoded, _ := base64.StdEncoding.DecodeString(signature)
+		assert.Equal(t, 65, len(decoded))
+		assert.True(t, decoded[0] >= test.header && decoded[0] < test.header+4)
+
+		assert.Nil(t, VerifySignedMessage(meta.Address, "airdrop claim", signature))
+		assert.Equal(t, ErrSignedMessageAddressMismatch, VerifySignedMessage(meta.Address, "airdrop claim!", signature))
+		other, _ := wallet.ReceiveAddressForIndex(3)
+		assert.Equal(t, ErrSignedMessageAddressMismatch, VerifySignedMessage(other.Address, "airdrop claim", signature))
+
+		again, _ := wallet.SignMessageForAddress("airdrop claim", path)
+		assert.Equal(t, signature, again)
+	}
+}
+
+func TestVerifySignedMessage_Headers(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	native, _ := wallet.ReceiveAddressForIndex(0)
+	signature, err := wallet.SignMessageForAddress("hello", path)
+	assert.Nil(t, err)
+	decoded, _ := base64.StdEncoding.DecodeString(signature)
+	recoveryID := (decoded[0] - bip137HeaderP2PKHUncompressed) % 4
+
+	// the P2WPKH header does not match a P2SH-P2WPKH address of the same key
+	key, _ := wallet.CompressedPubKeyForPath(path)
+	redeemScript, _ := P2WPKHScript(btcutil.Hash160(key))
+	nested, _ := btcutil.NewAddressScriptHash(redeemScript, &chaincfg.MainNetParams)
+	assert.Equal(t, ErrSignedMessageAddressMismatch, VerifySignedMessage(nested.EncodeAddress(), "hello", signature))
+
+	// Electrum signs segwit addresses with the compressed P2PKH header
+	decoded[0] = bip137HeaderP2PKH + recoveryID
+	electrum := base64.StdEncoding.EncodeToString(decoded)
+	assert.Nil(t, VerifySignedMessage(native.Address, "hello", electrum))
+	assert.Nil(t, VerifySignedMessage(nested.EncodeAddress(), "hello", electrum))
+
+	// the uncompressed header recovers no address of a compressed key
+	decoded[0] = bip137HeaderP2PKHUncompressed + recoveryID
+	assert.Equal(t, ErrSignedMessageAddressMismatch, VerifySignedMessage(native.Address, "hello", base64.StdEncoding.EncodeToString(decoded)))
+
+	decoded[0] = bip137HeaderEnd
+	assert.NotNil(t, VerifySignedMessage(native.Address, "hello", base64.StdEncoding.EncodeToString(decoded)))
+	assert.NotNil(t, VerifySignedMessage(native.Address, "hello", "not base64"))
+	assert.NotNil(t, VerifySignedMessage("not an address", "hello", signature))
+}
+
+func TestSignMessageForAddress_Invalid(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	_, err := wallet.SignMessageForAddress("hello", NewDerivationPath(BaseCoinBip86MainNet, 0, 0))
+	assert.Equal(t, ErrSignedMessageAddressType, err)
+	multisig, _ := ParseCustomDerivationPath("m/48'/0'/0'/2'/0/0")
+	_, err = wallet.SignMessageForAddress("hello", multisig)
+	assert.Equal(t, ErrSignedMessageAddressType, err)
+	identity, _ := ParseCustomDerivationPath("m/42")
+	_, err = wallet.SignMessageForAddress("hello", identity)
+	assert.Equal(t, ErrKeyRoleMismatch, err)
+	_, err = wallet.SignMessageForAddress("hello", nil)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, wallet.SetRole(WalletRoleHot))
+	_, err = wallet.SignMessageForAddress("hello", NewDerivationPath(BaseCoinBip84MainNet, 0, 0))
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
@@ -0,0 +1,422 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	sessionPayloadVersion      = byte(4)
+	messagingSessionVersion    = 1
+	maxSessionSkippedMessages  = 1000
+	sessionRootKeySalt         = "cnlib session root key"
+	sessionMessageKeySalt      = "cnlib session message key"
+	sessionChainKeyLabel       = "cnlib session chain key"
+	sessionKeySize             = btcec.PubKeyBytesLenCompressed
+	sessionHeaderSize          = 1 + 4 + 3*sessionKeySize // version, counter, ephemeral, sender ratchet and recipient keys
+	sessionMinPayloadSize      = sessionHeaderSize + aes.BlockSize + aes.BlockSize + sha256.Size
+	sessionChainKeySize        = 32
+	sessionMessageKeyBlockSize = 64 // 32 byte AES-256 key followed by 32 byte HMAC key
+)
+
+/// Type Definitions
+
+// MessagingSession encrypts memos and messages exchanged with one peer over the identity key (m/42) channel used by
+// `EncryptMessage`, adding forward secrecy. Each message is encrypted with a fresh ephemeral key to the peer's latest
+// ratchet key, a random key pair each peer advertises in the messages it sends, and its keys are also derived from a
+// per-direction chain key which is advanced through a one-way function after every message. Once the peer has used a
+// newer ratchet key, the private keys of older ones are deleted, so a later compromise of the wallet, including the
+// identity key, or of the session state does not expose the messages encrypted to them. Only messages sent before the
+// peer has seen any of this session's ratchet keys are encrypted to the identity key itself.
+//
+// Messages must be decrypted in the order they were sent. A message arriving after a later one cannot be decrypted,
+// as its keys were discarded; up to 1000 lost messages may be skipped.
+type MessagingSession struct {
+	wallet          *HDWallet
+	remotePubkey    *btcec.PublicKey
+	sendChainKey    []byte
+	sendCount       uint32
+	receiveChainKey []byte
+	receiveCount    uint32
+	ratchetKeys     []*btcec.PrivateKey // own ratchet keys the peer may still encrypt to, oldest first; the last is advertised
+	remoteRatchet   *btcec.PublicKey    // peer's latest advertised ratchet key, nil until a message is received
+	identityRetired bool                // set once the peer has encrypted to a ratchet key, after which the identity key is not accepted
+}
+
+// messagingSessionState is the serialized form of a MessagingSession.
+type messagingSessionState struct {
+	Version         int      `json:"version"`
+	RemotePubkey    string   `json:"remote_pubkey"`
+	SendChainKey    string   `json:"send_chain_key"`
+	SendCount       uint32   `json:"send_count"`
+	ReceiveChainKey string   `json:"receive_chain_key"`
+	ReceiveCount    uint32   `json:"receive_count"`
+	RatchetKeys     []string `json:"ratchet_keys"`
+	RemoteRatchet   string   `json:"remote_ratchet_pubkey,omitempty"`
+	IdentityRetired bool     `json:"identity_retired"`
+}
+
+/// Constructors
+
+// NewMessagingSession starts a session with the peer whose hex-encoded identity public key is given. Both peers
+// derive the same initial chain keys, so each can start a session independently before the first message.
+func (wallet *HDWallet) NewMessagingSession(remotePubkey string) (*MessagingSession, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	remote, err := parseHexPubKey(remotePubkey)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, err := wallet.signingPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	ownBytes := signingKey.PubKey().SerializeCompressed()
+	remoteBytes := remote.SerializeCompressed()
+	if bytes.Equal(ownBytes, remoteBytes) {
+		return nil, errors.New("cannot start a session with own identity key")
+	}
+
+	// chain keys are ordered by public key, so each peer's sending chain is the other's receiving chain
+	low, high := ownBytes, remoteBytes
+	if bytes.Compare(low, high) > 0 {
+		low, high = high, low
+	}
+	info := append(append([]byte{}, low...), high...)
+	secret := generateSharedSecretRFC4753(signingKey, remote)
+	root, err := hkdfBytes(secret, sessionRootKeySalt, info, 2*sessionChainKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	ratchetKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	session := &MessagingSession{wallet: wallet, remotePubkey: remote, ratchetKeys: []*btcec.PrivateKey{ratchetKey}}
+	if bytes.Equal(ownBytes, low) {
+		session.sendChainKey, session.receiveChainKey = root[:sessionChainKeySize], root[sessionChainKeySize:]
+	} else {
+		session.sendChainKey, session.receiveChainKey = root[sessionChainKeySize:], root[:sessionChainKeySize]
+	}
+	return session, nil
+}
+
+// RestoreMessagingSession restores a session from a string returned by `SerializedState`.
+func (wallet *HDWallet) RestoreMessagingSession(serializedState string) (*MessagingSession, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	var state messagingSessionState
+	if err := json.Unmarshal([]byte(serializedState), &state); err != nil {
+		return nil, err
+	}
+	if state.Version != messagingSessionVersion {
+		return nil, errors.New("unsupported messaging session state version")
+	}
+
+	remote, err := parseHexPubKey(state.RemotePubkey)
+	if err != nil {
+		return nil, err
+	}
+	sendChainKey, err := hex.DecodeString(state.SendChainKey)
+	if err != nil {
+		return nil, err
+	}
+	receiveChainKey, err := hex.DecodeString(state.ReceiveChainKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sendChainKey) != sessionChainKeySize || len(receiveChainKey) != sessionChainKeySize {
+		return nil, errors.New("invalid messaging session chain key")
+	}
+	if len(state.RatchetKeys) == 0 {
+		return nil, errors.New("messaging session has no ratchet keys")
+	}
+	ratchetKeys := make([]*btcec.PrivateKey, len(state.RatchetKeys))
+	for i, encoded := range state.RatchetKeys {
+		keyBytes, err := hex.DecodeString(encoded)
+		if err != nil || len(keyBytes) != btcec.PrivKeyBytesLen {
+			return nil, errors.New("invalid messaging session ratchet key")
+		}
+		ratchetKeys[i], _ = btcec.PrivKeyFromBytes(btcec.S256(), keyBytes)
+	}
+	var remoteRatchet *btcec.PublicKey
+	if state.RemoteRatchet != "" {
+		if remoteRatchet, err = parseHexPubKey(state.RemoteRatchet); err != nil {
+			return nil, err
+		}
+	}
+
+	return &MessagingSession{
+		wallet:          wallet,
+		remotePubkey:    remote,
+		sendChainKey:    sendChainKey,
+		sendCount:       state.SendCount,
+		receiveChainKey: receiveChainKey,
+		receiveCount:    state.ReceiveCount,
+		ratchetKeys:     ratchetKeys,
+		remoteRatchet:   remoteRatchet,
+		identityRetired: state.IdentityRetired,
+	}, nil
+}
+
+/// Receiver functions
+
+// Encrypt encrypts a message to the peer's latest ratchet key, or identity key if none has been received, and advances
+// the sending chain. Persist the session with `SerializedState` after each call, as reusing an earlier state would
+// reuse message keys.
+func (s *MessagingSession) Encrypt(body []byte) ([]byte, error) {
+	ephemeral, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	recipient := s.remotePubkey
+	if s.remoteRatchet != nil {
+		recipient = s.remoteRatchet
+	}
+
+	header := make([]byte, 0, sessionHeaderSize)
+	header = append(header, sessionPayloadVersion)
+	header = append(header, sessionCounterBytes(s.sendCount)...)
+	header = append(header, ephemeral.PubKey().SerializeCompressed()...)
+	header = append(header, s.ratchetKeys[len(s.ratchetKeys)-1].PubKey().SerializeCompressed()...)
+	header = append(header, recipient.SerializeCompressed()...)
+
+	secret := generateSharedSecretRFC4753(ephemeral, recipient)
+	encKey, hmacKey, err := sessionMessageKeys(s.sendChainKey, secret, s.sendCount)
+	if err != nil {
+		return nil, err
+	}
+
+	iv, err := randBytes(aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	cipherBlock, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(body)%aes.BlockSize
+	cipherText := append(append([]byte{}, body...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(cipherBlock, iv).CryptBlocks(cipherText, cipherText)
+
+	msg := append(header, iv...)
+	msg = append(msg, cipherText...)
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(msg)
+	msg = append(msg, mac.Sum(nil)...)
+
+	s.sendChainKey = nextSessionChainKey(s.sendChainKey)
+	s.sendCount++
+	return msg, nil
+}
+
+// Decrypt decrypts a message from the peer and advances the receiving chain past it. Once the peer has encrypted to
+// this session's latest ratchet key, a new one is advertised, and older keys are deleted. The session is unchanged if
+// decryption fails.
+func (s *MessagingSession) Decrypt(payload []byte) ([]byte, error) {
+	if len(payload) < sessionMinPayloadSize {
+		return nil, errors.New("insufficient data")
+	}
+	if payload[0] != sessionPayloadVersion {
+		return nil, errors.New("invalid payload version")
+	}
+
+	counter := binary.BigEndian.Uint32(payload[1:5])
+	if counter < s.receiveCount {
+		return nil, errors.New("message keys already discarded")
+	}
+	if counter-s.receiveCount > maxSessionSkippedMessages {
+		return nil, errors.New("too many skipped messages")
+	}
+
+	keysStart := 1 + 4
+	ephemeral, err := btcec.ParsePubKey(payload[keysStart:keysStart+sessionKeySize], btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	remoteRatchet, err := btcec.ParsePubKey(payload[keysStart+sessionKeySize:keysStart+2*sessionKeySize], btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	recipientKey, ratchetIndex, err := s.recipientKey(payload[keysStart+2*sessionKeySize : sessionHeaderSize])
+	if err != nil {
+		return nil, err
+	}
+
+	chainKey := s.receiveChainKey
+	for i := s.receiveCount; i < counter; i++ {
+		chainKey = nextSessionChainKey(chainKey)
+	}
+	secret := generateSharedSecretRFC4753(recipientKey, ephemeral)
+	encKey, hmacKey, err := sessionMessageKeys(chainKey, secret, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	macStart := len(payload) - sha256.Size
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(payload[:macStart])
+	if !hmac.Equal(mac.Sum(nil), payload[macStart:]) {
+		return nil, errors.New("invalid hmac")
+	}
+
+	iv := payload[sessionHeaderSize : sessionHeaderSize+aes.BlockSize]
+	cipherText := payload[sessionHeaderSize+aes.BlockSize : macStart]
+	if len(cipherText)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid ciphertext length")
+	}
+	cipherBlock, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	decrypted := make([]byte, len(cipherText))
+	cipher.NewCBCDecrypter(cipherBlock, iv).CryptBlocks(decrypted, cipherText)
+
+	plaintext, err := unpadPKCS7(decrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	ratchetKeys := s.ratchetKeys
+	if ratchetIndex >= 0 {
+		// the peer has seen the key at this index, so it will not encrypt to older ones again
+		ratchetKeys = ratchetKeys[ratchetIndex:]
+		if ratchetIndex == len(s.ratchetKeys)-1 {
+			next, err := btcec.NewPrivateKey(btcec.S256())
+			if err != nil {
+				return nil, err
+			}
+			ratchetKeys = append(append([]*btcec.PrivateKey{}, ratchetKeys...), next)
+		}
+		s.identityRetired = true
+	}
+	s.ratchetKeys = ratchetKeys
+	s.remoteRatchet = remoteRatchet
+	s.receiveChainKey = nextSessionChainKey(chainKey)
+	s.receiveCount = counter + 1
+	return plaintext, nil
+}
+
+// SendCount returns the number of messages encrypted in this session.
+func (s *MessagingSession) SendCount() int {
+	return int(s.sendCount)
+}
+
+// ReceiveCount returns the counter of the next message expected from the peer.
+func (s *MessagingSession) ReceiveCount() int {
+	return int(s.receiveCount)
+}
+
+// SerializedState returns the session as a JSON string, suitable for passing to `RestoreMessagingSession`. It
+// contains the current chain and ratchet keys, which decrypt future messages, so it must be stored as securely as the
+// words, and earlier states must be overwritten, as they hold ratchet keys since deleted.
+func (s *MessagingSession) SerializedState() (string, error) {
+	state := messagingSessionState{
+		Version:         messagingSessionVersion,
+		RemotePubkey:    hex.EncodeToString(s.remotePubkey.SerializeCompressed()),
+		SendChainKey:    hex.EncodeToString(s.sendChainKey),
+		SendCount:       s.sendCount,
+		ReceiveChainKey: hex.EncodeToString(s.receiveChainKey),
+		ReceiveCount:    s.receiveCount,
+		RatchetKeys:     make([]string, len(s.ratchetKeys)),
+		IdentityRetired: s.identityRetired,
+	}
+	for i, key := range s.ratchetKeys {
+		state.RatchetKeys[i] = hex.EncodeToString(key.Serialize())
+	}
+	if s.remoteRatchet != nil {
+		state.RemoteRatchet = hex.EncodeToString(s.remoteRatchet.SerializeCompressed())
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+/// Unexported functions
+
+// recipientKey returns the private key of the recipient key a message was encrypted to, with its index among the
+// ratchet keys, or -1 for the identity key. Returns error if the key has been deleted or was never this session's.
+func (s *MessagingSession) recipientKey(pubkey []byte) (*btcec.PrivateKey, int, error) {
+	for i, key := range s.ratchetKeys {
+		if bytes.Equal(key.PubKey().SerializeCompressed(), pubkey) {
+			return key, i, nil
+		}
+	}
+	signingKey, err := s.wallet.signingPrivateKey()
+	if err != nil {
+		return nil, 0, err
+	}
+	if !s.identityRetired && bytes.Equal(signingKey.PubKey().SerializeCompressed(), pubkey) {
+		return signingKey, -1, nil
+	}
+	return nil, 0, errors.New("message keys already discarded")
+}
+
+// unpadPKCS7 removes PKCS#7 padding, checking every padding byte.
+func unpadPKCS7(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("invalid padding")
+	}
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(data) {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range data[len(data)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return data[:len(data)-padding], nil
+}
+
+// sessionMessageKeys derives a message's encryption and HMAC keys from its chain key and the ECDH secret of its
+// ephemeral key, so both are needed to decrypt it.
+func sessionMessageKeys(chainKey []byte, secret []byte, counter uint32) ([]byte, []byte, error) {
+	ikm := append(append([]byte{}, chainKey...), secret...)
+	keys, err := hkdfBytes(ikm, sessionMessageKeySalt, sessionCounterBytes(counter), sessionMessageKeyBlockSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keys[:32], keys[32:], nil
+}
+
+// nextSessionChainKey advances a chain key through HMAC-SHA256, which cannot be reversed to recover earlier keys.
+func nextSessionChainKey(chainKey []byte) []byte {
+	mac := hmac.New(sha256.New, chainKey)
+	mac.Write([]byte(sessionChainKeyLabel))
+	return mac.Sum(nil)
+}
+
+func sessionCounterBytes(counter uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, counter)
+	return b
+}
+
+func hkdfBytes(ikm []byte, salt string, info []byte, size int) ([]byte, error) {
+	reader := hkdf.New(sha256.New, ikm, []byte(salt), info)
+	out := make([]byte, size)
+	if _, err := io.ReadFull(reader, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,174 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMessagingSessions(t *testing.T) (*MessagingSession, *MessagingSession, *HDWallet) {
+	alice := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	bob := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	aliceKey, err := alice.CoinNinjaVerificationKeyHexString()
+	assert.Nil(t, err)
+	bobKey, err := bob.CoinNinjaVerificationKeyHexString()
+	assert.Nil(t, err)
+
+	aliceSession, err := alice.NewMessagingSession(bobKey)
+	assert.Nil(t, err)
+	bobSession, err := bob.NewMessagingSession(aliceKey)
+	assert.Nil(t, err)
+	return aliceSession, bobSession, bob
+}
+
+func TestMessagingSession_ExchangesMessagesBothWays(t *testing.T) {
+	aliceSession, bobSession, _ := newTestMessagingSessions(t)
+
+	first, err := aliceSession.Encrypt([]byte("first memo"))
+	assert.Nil(t, err)
+	second, err := aliceSession.Encrypt([]byte("second memo"))
+	assert.Nil(t, err)
+	reply, err := bobSession.Encrypt([]byte("reply"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, aliceSession.SendCount())
+
+	decrypted, err := bobSession.Decrypt(first)
+	assert.Nil(t, err)
+	assert.Equal(t, "first memo", string(decrypted))
+	decrypted, err = bobSession.Decrypt(second)
+	assert.Nil(t, err)
+	assert.Equal(t, "second memo", string(decrypted))
+	decrypted, err = aliceSession.Decrypt(reply)
+	assert.Nil(t, err)
+	assert.Equal(t, "reply", string(decrypted))
+
+	// message keys are discarded once used
+	_, err = bobSession.Decrypt(first)
+	assert.EqualError(t, err, "message keys already discarded")
+}
+
+func TestMessagingSession_SameBodyProducesDifferentPayloads(t *testing.T) {
+	aliceSession, _, _ := newTestMessagingSessions(t)
+
+	first, err := aliceSession.Encrypt([]byte("memo"))
+	assert.Nil(t, err)
+	second, err := aliceSession.Encrypt([]byte("memo"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, first[5:5+sessionKeySize], second[5:5+sessionKeySize]) // ephemeral keys
+	assert.NotEqual(t, first, second)
+}
+
+func TestMessagingSession_SkipsLostMessages(t *testing.T) {
+	aliceSession, bobSession, _ := newTestMessagingSessions(t)
+
+	_, err := aliceSession.Encrypt([]byte("lost"))
+	assert.Nil(t, err)
+	delivered, err := aliceSession.Encrypt([]byte("delivered"))
+	assert.Nil(t, err)
+
+	decrypted, err := bobSession.Decrypt(delivered)
+	assert.Nil(t, err)
+	assert.Equal(t, "delivered", string(decrypted))
+	assert.Equal(t, 2, bobSession.ReceiveCount())
+}
+
+func TestMessagingSession_TamperedPayload_LeavesSessionUnchanged(t *testing.T) {
+	aliceSession, bobSession, _ := newTestMessagingSessions(t)
+
+	payload, err := aliceSession.Encrypt([]byte("memo"))
+	assert.Nil(t, err)
+	tampered := append([]byte{}, payload...)
+	tampered[len(tampered)-40] ^= 1
+
+	_, err = bobSession.Decrypt(tampered)
+	assert.EqualError(t, err, "invalid hmac")
+	assert.Equal(t, 0, bobSession.ReceiveCount())
+
+	decrypted, err := bobSession.Decrypt(payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "memo", string(decrypted))
+}
+
+func TestMessagingSession_RestoredFromSerializedState(t *testing.T) {
+	aliceSession, bobSession, bob := newTestMessagingSessions(t)
+
+	first, err := aliceSession.Encrypt([]byte("first"))
+	assert.Nil(t, err)
+	_, err = bobSession.Decrypt(first)
+	assert.Nil(t, err)
+
+	state, err := bobSession.SerializedState()
+	assert.Nil(t, err)
+	restored, err := bob.RestoreMessagingSession(state)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, restored.ReceiveCount())
+
+	second, err := aliceSession.Encrypt([]byte("second"))
+	assert.Nil(t, err)
+	decrypted, err := restored.Decrypt(second)
+	assert.Nil(t, err)
+	assert.Equal(t, "second", string(decrypted))
+}
+
+func TestMessagingSession_WithOwnKey_ReturnsError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	key, err := wallet.CoinNinjaVerificationKeyHexString()
+	assert.Nil(t, err)
+
+	_, err = wallet.NewMessagingSession(key)
+	assert.EqualError(t, err, "cannot start a session with own identity key")
+}
+
+func TestMessagingSession_RatchetKeys_DeletedOnceReplaced(t *testing.T) {
+	aliceSession, bobSession, _ := newTestMessagingSessions(t)
+
+	// the first message, sent before alice has seen a ratchet key of bob's, is encrypted to his identity key
+	first, err := aliceSession.Encrypt([]byte("first"))
+	assert.Nil(t, err)
+	_, err = bobSession.Decrypt(first)
+	assert.Nil(t, err)
+
+	// every later message is encrypted to the peer's latest ratchet key
+	reply, err := bobSession.Encrypt([]byte("reply"))
+	assert.Nil(t, err)
+	_, err = aliceSession.Decrypt(reply)
+	assert.Nil(t, err)
+	second, err := aliceSession.Encrypt([]byte("second"))
+	assert.Nil(t, err)
+	recipient := second[sessionHeaderSize-sessionKeySize : sessionHeaderSize]
+	_, err = bobSession.Decrypt(second)
+	assert.Nil(t, err)
+	assert.Len(t, bobSession.ratchetKeys, 2)
+
+	// once alice has encrypted to bob's newer ratchet key, the one used for the second message is deleted, and the
+	// identity key is no longer accepted, so neither the words nor the session state can decrypt it
+	secondReply, err := bobSession.Encrypt([]byte("second reply"))
+	assert.Nil(t, err)
+	_, err = aliceSession.Decrypt(secondReply)
+	assert.Nil(t, err)
+	third, err := aliceSession.Encrypt([]byte("third"))
+	assert.Nil(t, err)
+	decrypted, err := bobSession.Decrypt(third)
+	assert.Nil(t, err)
+	assert.Equal(t, "third", string(decrypted))
+
+	_, _, err = bobSession.recipientKey(recipient)
+	assert.EqualError(t, err, "message keys already discarded")
+	identity, err := bobSession.wallet.signingPrivateKey()
+	assert.Nil(t, err)
+	_, _, err = bobSession.recipientKey(identity.PubKey().SerializeCompressed())
+	assert.EqualError(t, err, "message keys already discarded")
+}
+
+func TestUnpadPKCS7_ChecksEveryPaddingByte(t *testing.T) {
+	unpadded, err := unpadPKCS7([]byte{'a', 'b', 3, 3, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ab"), unpadded)
+
+	_, err = unpadPKCS7([]byte{'a', 'b', 2, 3, 3})
+	assert.EqualError(t, err, "invalid padding")
+	_, err = unpadPKCS7([]byte{3, 3})
+	assert.EqualError(t, err, "invalid padding")
+	_, err = unpadPKCS7([]byte{'a', 0})
+	assert.EqualError(t, err, "invalid padding")
+}
@@ -0,0 +1,118 @@
+package cnlib
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxWordCorrectionDistance is the largest edit distance at which a wordlist word is suggested as a correction.
+const maxWordCorrectionDistance = 2
+
+/// Exported functions
+
+// SuggestWordsForPrefix returns up to limit English BIP39 words beginning with prefix, in wordlist order, as a
+// space-separated string. A limit of 0 or less returns every match. Returns an empty string if prefix is empty.
+func SuggestWordsForPrefix(prefix string, limit int) string {
+	suggestions, _ := SuggestWordsForPrefixForLanguage(prefix, limit, WordListLanguageEnglish)
+	return suggestions
+}
+
+// SuggestWordsForPrefixForLanguage is `SuggestWordsForPrefix` for the wordlist of a language, one of the
+// `WordListLanguage` constants. Returns error if the language is not recognized.
+func SuggestWordsForPrefixForLanguage(prefix string, limit int, language int) (string, error) {
+	list, ok := bip39WordLists[language]
+	if !ok {
+		return "", ErrWordListLanguageUnknown
+	}
+	prefix = normalizeMnemonic(strings.ToLower(strings.TrimSpace(prefix)))
+	if prefix == "" {
+		return "", nil
+	}
+
+	matches := []string{}
+	for _, word := range list {
+		if !strings.HasPrefix(word, prefix) {
+			continue
+		}
+		matches = append(matches, word)
+		if limit > 0 && len(matches) == limit {
+			break
+		}
+	}
+	return strings.Join(matches, " "), nil
+}
+
+// SuggestCorrectionsForWord returns up to limit English BIP39 words within a small edit distance of a mistyped word,
+// closest first, as a space-separated string. A word already in the wordlist is returned alone. A limit of 0 or less
+// returns every candidate.
+func SuggestCorrectionsForWord(word string, limit int) string {
+	suggestions, _ := SuggestCorrectionsForWordForLanguage(word, limit, WordListLanguageEnglish)
+	return suggestions
+}
+
+// SuggestCorrectionsForWordForLanguage is `SuggestCorrectionsForWord` for the wordlist of a language, one of the
+// `WordListLanguage` constants. Returns error if the language is not recognized.
+func SuggestCorrectionsForWordForLanguage(word string, limit int, language int) (string, error) {
+	list, ok := bip39WordLists[language]
+	if !ok {
+		return "", ErrWordListLanguageUnknown
+	}
+	word = normalizeMnemonic(strings.ToLower(strings.TrimSpace(word)))
+	if word == "" {
+		return "", nil
+	}
+	if _, ok := bip39WordIndexes[language][word]; ok {
+		return word, nil
+	}
+
+	type candidate struct {
+		word     string
+		distance int
+	}
+	candidates := []candidate{}
+	for _, listWord := range list {
+		if distance := editDistance(word, listWord); distance <= maxWordCorrectionDistance {
+			candidates = append(candidates, candidate{word: listWord, distance: distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	words := make([]string, len(candidates))
+	for i, c := range candidates {
+		words[i] = c.word
+	}
+	return strings.Join(words, " "), nil
+}
+
+/// Unexported functions
+
+// editDistance returns the Levenshtein distance between two strings, counting runes.
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
@@ -0,0 +1,37 @@
+package cnlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestWordsForPrefix(t *testing.T) {
+	assert.Equal(t, "abandon ability able", SuggestWordsForPrefix("ab", 3))
+	assert.Equal(t, "zoo", SuggestWordsForPrefix(" ZOO ", 0))
+	assert.Equal(t, "", SuggestWordsForPrefix("", 10))
+	assert.Equal(t, "", SuggestWordsForPrefix("xyz", 10))
+
+	_, err := SuggestWordsForPrefixForLanguage("ab", 3, 99)
+	assert.Equal(t, ErrWordListLanguageUnknown, err)
+
+	spanish, err := SuggestWordsForPrefixForLanguage("ába", 1, WordListLanguageSpanish)
+	assert.Nil(t, err)
+	assert.Equal(t, normalizeMnemonic("ábaco"), spanish)
+}
+
+func TestSuggestCorrectionsForWord(t *testing.T) {
+	assert.Equal(t, "abandon", SuggestCorrectionsForWord("abandun", 1))
+	assert.Equal(t, "about", SuggestCorrectionsForWord("about", 5))
+	assert.Equal(t, "zoo", strings.Fields(SuggestCorrectionsForWord("zooo", 0))[0])
+	assert.Equal(t, "", SuggestCorrectionsForWord("qqqqqqqq", 5))
+	assert.True(t, len(strings.Fields(SuggestCorrectionsForWord("cat", 3))) <= 3)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("zoo", "zoo"))
+	assert.Equal(t, 1, editDistance("zoo", "zo"))
+	assert.Equal(t, 3, editDistance("kitten", "sitting"))
+	assert.Equal(t, 1, editDistance("か", "が"))
+}
@@ -0,0 +1,100 @@
+package cnlib
+
+import "errors"
+
+/// Type Definitions
+
+// MnemonicWordResult describes one word of a mnemonic, for recovery UIs to highlight words not in the wordlist.
+type MnemonicWordResult struct {
+	Position      int    // zero-based position of the word in the mnemonic
+	Word          string // the word as normalized for lookup
+	InWordList    bool
+	WordListIndex int // index of the word in the wordlist, or -1 if not in the wordlist
+}
+
+// MnemonicValidationResult reports why a mnemonic is or is not valid. Language is the wordlist the words were checked
+// against: the detected language, or, if no wordlist contains every word, the one containing the most words.
+type MnemonicValidationResult struct {
+	IsValid               bool
+	Language              int // one of the `WordListLanguage` constants
+	WordCountValid        bool
+	ChecksumValid         bool // false unless every word is in the wordlist and the word count is valid
+	FirstInvalidWordIndex int  // position of the first word not in the wordlist, or -1 if every word is
+	InvalidWordCount      int
+	words                 []*MnemonicWordResult
+}
+
+/// Exported functions
+
+// ValidateWordString checks each word of a mnemonic against the BIP39 wordlists, the word count, and the checksum,
+// reporting every failure rather than only whether the mnemonic is usable. Recovery flows should check this before
+// `NewHDWalletFromWords`, which derives a wallet from any words.
+func ValidateWordString(words string) *MnemonicValidationResult {
+	normalized := mnemonicWords(words)
+	language, err := DetectWordListLanguage(words)
+	if err != nil {
+		language = closestWordListLanguage(normalized)
+	}
+
+	result := &MnemonicValidationResult{
+		Language:              language,
+		WordCountValid:        len(normalized) >= 12 && len(normalized) <= 24 && len(normalized)%3 == 0,
+		FirstInvalidWordIndex: -1,
+		words:                 make([]*MnemonicWordResult, len(normalized)),
+	}
+
+	indexes := bip39WordIndexes[language]
+	for i, word := range normalized {
+		index, ok := indexes[word]
+		if !ok {
+			index = -1
+			result.InvalidWordCount++
+			if result.FirstInvalidWordIndex < 0 {
+				result.FirstInvalidWordIndex = i
+			}
+		}
+		result.words[i] = &MnemonicWordResult{Position: i, Word: word, InWordList: ok, WordListIndex: index}
+	}
+
+	if result.InvalidWordCount == 0 && result.WordCountValid {
+		_, err := mnemonicEntropy(normalized, language)
+		result.ChecksumValid = err == nil
+	}
+	result.IsValid = result.ChecksumValid
+	return result
+}
+
+/// Receiver functions
+
+// WordCount returns the number of words in the mnemonic.
+func (r *MnemonicValidationResult) WordCount() int {
+	return len(r.words)
+}
+
+// WordAtIndex returns the result for the word at a given position, or error if out of bounds.
+func (r *MnemonicValidationResult) WordAtIndex(index int) (*MnemonicWordResult, error) {
+	if index < 0 || index > len(r.words)-1 {
+		return nil, errors.New("index must be within range of words")
+	}
+	return r.words[index], nil
+}
+
+/// Unexported functions
+
+// closestWordListLanguage returns the language whose wordlist contains the most of the words, preferring the first on
+// a tie, so that misspelled words can be pointed out.
+func closestWordListLanguage(words []string) int {
+	best, bestCount := WordListLanguageEnglish, -1
+	for language := WordListLanguageEnglish; language <= WordListLanguageChineseTraditional; language++ {
+		count := 0
+		for _, word := range words {
+			if _, ok := bip39WordIndexes[language][word]; ok {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = language, count
+		}
+	}
+	return best
+}
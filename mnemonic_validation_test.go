@@ -0,0 +1,56 @@
+package cnlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWordString_Valid(t *testing.T) {
+	result := ValidateWordString(w)
+	assert.True(t, result.IsValid)
+	assert.True(t, result.WordCountValid)
+	assert.True(t, result.ChecksumValid)
+	assert.Equal(t, WordListLanguageEnglish, result.Language)
+	assert.Equal(t, -1, result.FirstInvalidWordIndex)
+	assert.Equal(t, 12, result.WordCount())
+
+	last, err := result.WordAtIndex(11)
+	assert.Nil(t, err)
+	assert.Equal(t, "about", last.Word)
+	assert.Equal(t, 3, last.WordListIndex)
+	assert.True(t, last.InWordList)
+
+	_, err = result.WordAtIndex(12)
+	assert.NotNil(t, err)
+}
+
+func TestValidateWordString_MisspelledWord(t *testing.T) {
+	result := ValidateWordString(strings.Replace(w, "abandon", "abandun", 1) + " ")
+	assert.False(t, result.IsValid)
+	assert.True(t, result.WordCountValid)
+	assert.False(t, result.ChecksumValid)
+	assert.Equal(t, WordListLanguageEnglish, result.Language)
+	assert.Equal(t, 0, result.FirstInvalidWordIndex)
+	assert.Equal(t, 1, result.InvalidWordCount)
+
+	word, err := result.WordAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "abandun", word.Word)
+	assert.False(t, word.InWordList)
+	assert.Equal(t, -1, word.WordListIndex)
+}
+
+func TestValidateWordString_BadChecksumAndCount(t *testing.T) {
+	result := ValidateWordString(strings.Replace(w, "about", "abandon", 1))
+	assert.False(t, result.IsValid)
+	assert.True(t, result.WordCountValid)
+	assert.False(t, result.ChecksumValid)
+	assert.Equal(t, 0, result.InvalidWordCount)
+
+	result = ValidateWordString("abandon abandon about")
+	assert.False(t, result.IsValid)
+	assert.False(t, result.WordCountValid)
+	assert.Equal(t, -1, result.FirstInvalidWordIndex)
+}
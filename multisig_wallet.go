@@ -0,0 +1,376 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+// Following constants are used for MultisigWallet.ScriptType, and match the script type level of BIP48 paths.
+const (
+	MultisigScriptTypeP2SHP2WSH int = 1
+	MultisigScriptTypeP2WSH     int = 2
+)
+
+// ErrMultisigWalletIncomplete is returned when a MultisigWallet's threshold is not between 1 and its number of keys,
+// of which there must be 2 to 20.
+var ErrMultisigWalletIncomplete = errors.New("multisig threshold must be between 1 and the number of keys, of which there must be 2 to 20")
+
+// ErrMultisigScriptType is returned for a multisig script type other than the `MultisigScriptType` constants.
+var ErrMultisigScriptType = errors.New("unsupported multisig script type")
+
+/// Type Definitions
+
+// MultisigWallet derives the addresses of a k-of-n multisig wallet from each cosigner's account extended public key,
+// such as one at a BIP48 path "m/48'/0'/0'/2'". Each address's witness script is a CHECKMULTISIG of the cosigners'
+// keys at receive or change and index below their account keys, sorted per BIP67, as in a sortedmulti descriptor.
+// Add each cosigner using `AddCosigner`, as gomobile does not support custom arrays/slices.
+type MultisigWallet struct {
+	Threshold  int
+	ScriptType int // `MultisigScriptTypeP2WSH` or `MultisigScriptTypeP2SHP2WSH`
+	basecoin   *BaseCoin
+	cosigners  []*multisigWalletKey
+}
+
+// MultisigAddress is an address of a MultisigWallet, with the scripts needed to spend from it.
+type MultisigAddress struct {
+	Address       string
+	Change        int
+	Index         int
+	WitnessScript []byte
+	RedeemScript  []byte // nil unless P2SH-P2WSH
+}
+
+// multisigWalletKey is a cosigner's account extended public key, with its origin.
+type multisigWalletKey struct {
+	key         *hdkeychain.ExtendedKey
+	fingerprint string
+	path        *DerivationPath
+}
+
+/// Constructors
+
+// NewMultisigWallet returns a pointer to a MultisigWallet with no cosigners, requiring threshold signatures, of a
+// `MultisigScriptType` on the BaseCoin's network.
+func NewMultisigWallet(basecoin *BaseCoin, threshold int, scriptType int) (*MultisigWallet, error) {
+	if basecoin == nil {
+		return nil, errors.New("basecoin cannot be nil")
+	}
+	if !isMultisigScriptType(scriptType) {
+		return nil, ErrMultisigScriptType
+	}
+	copied := *basecoin
+	return &MultisigWallet{Threshold: threshold, ScriptType: scriptType, basecoin: &copied}, nil
+}
+
+/// Receiver functions
+
+// AddCosigner adds a cosigner's account extended public key, with the hex master fingerprint and path it was derived
+// at, which the cosigner's wallet needs to sign. Returns ErrMultisigCosignerKey if the key is not an extended public
+// key of the wallet's network at the depth of the path, or ErrMultisigCosignerDuplicate if it has already been added.
+func (m *MultisigWallet) AddCosigner(masterFingerprint string, derivationPath string, extendedPublicKey string) error {
+	fingerprint, err := hex.DecodeString(masterFingerprint)
+	if err != nil || len(fingerprint) != 4 {
+		return errors.New("master fingerprint must be 4 hex-encoded bytes")
+	}
+	path, err := ParseCustomDerivationPath(derivationPath)
+	if err != nil {
+		return err
+	}
+	key, err := hdkeychain.NewKeyFromString(extendedPublicKey)
+	if err != nil || key.IsPrivate() || int(key.Depth()) != path.Depth() || !key.IsForNet(m.basecoin.defaultNetParams()) {
+		return ErrMultisigCosignerKey
+	}
+	for _, cosigner := range m.cosigners {
+		if cosigner.key.String() == key.String() {
+			return ErrMultisigCosignerDuplicate
+		}
+	}
+	if len(m.cosigners) >= maxWitnessMultisigKeys {
+		return ErrMultisigWalletIncomplete
+	}
+	m.cosigners = append(m.cosigners, &multisigWalletKey{key: key, fingerprint: strings.ToLower(masterFingerprint), path: path})
+	return nil
+}
+
+// CosignerCount returns the number of cosigners.
+func (m *MultisigWallet) CosignerCount() int {
+	return len(m.cosigners)
+}
+
+// ReceiveAddressForIndex returns the receive address at an index.
+func (m *MultisigWallet) ReceiveAddressForIndex(index int) (*MultisigAddress, error) {
+	return m.addressForIndex(0, index)
+}
+
+// ChangeAddressForIndex returns the change address at an index.
+func (m *MultisigWallet) ChangeAddressForIndex(index int) (*MultisigAddress, error) {
+	return m.addressForIndex(1, index)
+}
+
+// InputBytes returns the virtual size of an input spending from the wallet, with signatures of maximum size, for fee
+// estimation.
+func (m *MultisigWallet) InputBytes() (int, error) {
+	if err := m.validate(); err != nil {
+		return 0, err
+	}
+	return multisigInputBytes(m.Threshold, len(m.cosigners), m.ScriptType == MultisigScriptTypeP2SHP2WSH), nil
+}
+
+// MultisigInputBytes returns the virtual size of an input spending a threshold of keyCount sortedmulti output of a
+// `MultisigScriptType`, with signatures of maximum size, for fee estimation.
+func (h *AddressHelper) MultisigInputBytes(threshold int, keyCount int, scriptType int) (int, error) {
+	if keyCount < 2 || keyCount > maxWitnessMultisigKeys || threshold < 1 || threshold > keyCount {
+		return 0, ErrMultisigWalletIncomplete
+	}
+	if !isMultisigScriptType(scriptType) {
+		return 0, ErrMultisigScriptType
+	}
+	return multisigInputBytes(threshold, keyCount, scriptType == MultisigScriptTypeP2SHP2WSH), nil
+}
+
+// MultisigWallet returns the MultisigWallet of the backup's cosigners, threshold and script type.
+func (b *MultisigWalletBackup) MultisigWallet() (*MultisigWallet, error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	wallet, err := NewMultisigWallet(&BaseCoin{Coin: b.coin, TestNetwork: b.testNetwork}, b.Threshold, b.ScriptType)
+	if err != nil {
+		return nil, err
+	}
+	for _, cosigner := range b.cosigners {
+		if err := wallet.AddCosigner(cosigner.MasterFingerprint, cosigner.DerivationPath, cosigner.ExtendedPublicKey); err != nil {
+			return nil, err
+		}
+	}
+	return wallet, nil
+}
+
+// SignMultisigPSBT signs each input of a base64 encoded PSBT whose witness utxo pays to one of a MultisigWallet's
+// receive or change addresses up to a given index, with the wallet's key for each cosigner whose origin fingerprint
+// and key it matches, and returns the updated PSBT. Witness and redeem scripts are added to the inputs signed. Inputs
+// already signed by the wallet, or belonging to other wallets, are left untouched.
+func (wallet *HDWallet) SignMultisigPSBT(multisig *MultisigWallet, encodedPSBT string, upTo int) (string, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return "", err
+	}
+	if multisig == nil {
+		return "", errors.New("multisig wallet cannot be nil")
+	}
+	own, err := wallet.ownMultisigCosigners(multisig)
+	if err != nil {
+		return "", err
+	}
+	if len(own) == 0 {
+		return "", ErrMultisigCosignerOwnMissing
+	}
+	for _, cosigner := range own {
+		if err := requireKeyRole(cosigner.path, KeyRoleSpending); err != nil {
+			return "", err
+		}
+	}
+
+	packet, err := psbt.NewPsbt([]byte(encodedPSBT), true)
+	if err != nil {
+		return "", err
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return "", err
+	}
+	known := make(map[string]*MultisigAddress, 2*upTo)
+	for i := 0; i < upTo; i++ {
+		for change := 0; change <= 1; change++ {
+			address, err := multisig.addressForIndex(change, i)
+			if err != nil {
+				return "", err
+			}
+			known[address.Address] = address
+		}
+	}
+
+	tx := packet.UnsignedTx
+	sigHashes := txscript.NewTxSigHashes(tx)
+	params := multisig.basecoin.defaultNetParams()
+	signed := 0
+	for i, input := range packet.Inputs {
+		if input.WitnessUtxo == nil {
+			continue
+		}
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(input.WitnessUtxo.PkScript, params)
+		if err != nil || len(addrs) != 1 {
+			continue
+		}
+		address, ok := known[addrs[0].EncodeAddress()]
+		if !ok {
+			continue
+		}
+
+		for _, cosigner := range own {
+			components := append(cosigner.path.bip32Path(), uint32(address.Change), uint32(address.Index))
+			key, err := wallet.keyFactory().indexPrivateKey(&DerivationPath{components: components})
+			if err != nil {
+				return "", err
+			}
+			privateKey, err := key.ECPrivKey()
+			if err != nil {
+				return "", err
+			}
+			pubkey := privateKey.PubKey().SerializeCompressed()
+			if hasPSBTPartialSig(packet.Inputs[i].PartialSigs, pubkey) {
+				continue
+			}
+			sig, err := txscript.RawTxInWitnessSignature(tx, sigHashes, i, input.WitnessUtxo.Value, address.WitnessScript, txscript.SigHashAll, privateKey)
+			if err != nil {
+				return "", err
+			}
+			if _, err := updater.Sign(i, sig, pubkey, address.RedeemScript, address.WitnessScript); err != nil {
+				return "", err
+			}
+			signed++
+		}
+	}
+
+	if signed == 0 {
+		return "", errors.New("no psbt inputs to sign belong to multisig wallet")
+	}
+	return packet.B64Encode()
+}
+
+/// Unexported functions
+
+// addressForIndex returns the address of the sorted cosigner keys at change and index below each account key.
+func (m *MultisigWallet) addressForIndex(change int, index int) (*MultisigAddress, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	if err := validateDerivationIndex(index); err != nil {
+		return nil, err
+	}
+
+	pubkeys := make([][]byte, 0, len(m.cosigners))
+	for _, cosigner := range m.cosigners {
+		changeKey, err := cosigner.key.Child(uint32(change))
+		if err != nil {
+			return nil, err
+		}
+		indexKey, err := changeKey.Child(uint32(index))
+		if err != nil {
+			return nil, err
+		}
+		pubkey, err := indexKey.ECPubKey()
+		if err != nil {
+			return nil, err
+		}
+		pubkeys = append(pubkeys, pubkey.SerializeCompressed())
+	}
+	sort.Slice(pubkeys, func(i, j int) bool { return bytes.Compare(pubkeys[i], pubkeys[j]) < 0 })
+
+	builder := txscript.NewScriptBuilder().AddInt64(int64(m.Threshold))
+	for _, pubkey := range pubkeys {
+		builder.AddData(pubkey)
+	}
+	witnessScript, err := builder.AddInt64(int64(len(pubkeys))).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	if err != nil {
+		return nil, err
+	}
+
+	params := m.basecoin.defaultNetParams()
+	hash := sha256.Sum256(witnessScript)
+	address := MultisigAddress{Change: change, Index: index, WitnessScript: witnessScript}
+	if m.ScriptType == MultisigScriptTypeP2SHP2WSH {
+		if address.RedeemScript, err = P2WSHScript(hash[:]); err != nil {
+			return nil, err
+		}
+		encoded, err := btcutil.NewAddressScriptHash(address.RedeemScript, params)
+		if err != nil {
+			return nil, err
+		}
+		address.Address = encoded.EncodeAddress()
+		return &address, nil
+	}
+	encoded, err := btcutil.NewAddressWitnessScriptHash(hash[:], params)
+	if err != nil {
+		return nil, err
+	}
+	address.Address = encoded.EncodeAddress()
+	return &address, nil
+}
+
+func (m *MultisigWallet) validate() error {
+	count := len(m.cosigners)
+	if count < 2 || count > maxWitnessMultisigKeys || m.Threshold < 1 || m.Threshold > count {
+		return ErrMultisigWalletIncomplete
+	}
+	return nil
+}
+
+// ownMultisigCosigners returns the cosigners whose origin fingerprint is the wallet's, and whose key the wallet derives
+// at their origin path.
+func (wallet *HDWallet) ownMultisigCosigners(multisig *MultisigWallet) ([]*multisigWalletKey, error) {
+	fingerprint, err := wallet.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	var own []*multisigWalletKey
+	for _, cosigner := range multisig.cosigners {
+		if cosigner.fingerprint != fingerprint {
+			continue
+		}
+		xpub, err := wallet.ExtendedPublicKeyForPath(cosigner.path)
+		if err != nil {
+			return nil, err
+		}
+		if xpub == cosigner.key.String() {
+			own = append(own, cosigner)
+		}
+	}
+	return own, nil
+}
+
+// hasPSBTPartialSig returns true if a PSBT input's partial signatures include one for a public key.
+func hasPSBTPartialSig(sigs []*psbt.PartialSig, pubkey []byte) bool {
+	for _, sig := range sigs {
+		if bytes.Equal(sig.PubKey, pubkey) {
+			return true
+		}
+	}
+	return false
+}
+
+// multisigInputBytes returns the virtual size of an input spending a CHECKMULTISIG witness script, nested in P2SH or
+// not, with signatures of maximum size.
+func multisigInputBytes(threshold int, keyCount int, nested bool) int {
+	// small integers up to 16 are a single opcode, larger ones a one byte push
+	scriptNumberSize := func(n int) int {
+		if n <= 16 {
+			return 1
+		}
+		return 2
+	}
+	witnessScriptSize := scriptNumberSize(threshold) + keyCount*(1+33) + scriptNumberSize(keyCount) + 1
+
+	// item count, empty CHECKMULTISIG dummy, signatures and witness script
+	witness := 1 + 1 + threshold*(1+dummySignatureSize) +
+		wire.VarIntSerializeSize(uint64(witnessScriptSize)) + witnessScriptSize
+	// outpoint, script length, sequence
+	base := 32 + 4 + 1 + 4
+	if nested {
+		// push of the 34-byte P2WSH redeem script
+		base += 1 + 34
+	}
+	return base + (witness+3)/4
+}
+
+func isMultisigScriptType(scriptType int) bool {
+	return scriptType == MultisigScriptTypeP2WSH || scriptType == MultisigScriptTypeP2SHP2WSH
+}
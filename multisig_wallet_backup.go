@@ -0,0 +1,368 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+const (
+	multisigWalletBackupVersion = 2
+	// multisigWalletBackupVersionP2WSH is the version of backups before the script type was stored, all of which were
+	// P2WSH.
+	multisigWalletBackupVersionP2WSH = 1
+	// multisigDerivationTemplate is the BIP389 path below each cosigner's key: receive or change, then address index.
+	multisigDerivationTemplate = "<0;1>/*"
+)
+
+// Following errors describe a multisig wallet backup which cannot restore the wallet.
+var (
+	ErrMultisigBackupChecksum     = errors.New("multisig wallet backup checksum does not match its contents")
+	ErrMultisigBackupThreshold    = errors.New("multisig threshold must be between 1 and the number of cosigners, of which there must be 2 to 20")
+	ErrMultisigCosignerDuplicate  = errors.New("cosigner extended public key has already been added")
+	ErrMultisigCosignerKey        = errors.New("cosigner key must be an extended public key of the backup's network, at the depth of its path")
+	ErrMultisigCosignerOwnMissing = errors.New("multisig wallet backup must contain exactly one cosigner key of this wallet")
+	ErrMultisigOwnKeyMismatch     = errors.New("wallet does not hold the key of the backup's own cosigner")
+)
+
+/// Type Definitions
+
+// MultisigWalletBackup holds everything needed to restore a multisig wallet other than private keys: this wallet's
+// extended public key and path, every cosigner's, the quorum, the derivation template and the block height of the
+// wallet's first transaction. Losing a cosigner's extended public key loses the ability to spend even with enough
+// private keys, so the backup should be stored alongside each cosigner's seed backup. The encoded backup carries a
+// checksum of its contents, verified by `DecodeMultisigWalletBackup`.
+type MultisigWalletBackup struct {
+	Threshold          int
+	ScriptType         int    // `MultisigScriptTypeP2WSH` or `MultisigScriptTypeP2SHP2WSH`
+	DerivationTemplate string // path below each cosigner's key to its addresses, in BIP389 multipath notation
+	FirstUsedHeight    int    // block height of the wallet's first transaction, from which to rescan on restore
+	coin               int
+	testNetwork        int
+	cosigners          []*MultisigCosigner
+}
+
+// MultisigCosigner is the extended public key of one cosigner of a multisig wallet, with its origin.
+type MultisigCosigner struct {
+	MasterFingerprint string `json:"master_fingerprint"` // hex-encoded
+	DerivationPath    string `json:"path"`               // path of the key from the cosigner's master key
+	ExtendedPublicKey string `json:"xpub"`
+	IsOwn             bool   `json:"is_own"` // true for this wallet's key
+}
+
+// multisigWalletBackupState is the encoded form of a MultisigWalletBackup.
+type multisigWalletBackupState struct {
+	Version            int                 `json:"version"`
+	Coin               int                 `json:"coin"`
+	TestNetwork        int                 `json:"test_network,omitempty"`
+	Threshold          int                 `json:"threshold"`
+	ScriptType         int                 `json:"script_type,omitempty"` // absent before version 2
+	DerivationTemplate string              `json:"derivation_template"`
+	FirstUsedHeight    int                 `json:"first_used_height"`
+	Cosigners          []*MultisigCosigner `json:"cosigners"`
+	Checksum           string              `json:"checksum"` // hex-encoded SHA256 of the other fields
+}
+
+/// Constructors
+
+// NewMultisigWalletBackup starts a backup of a multisig wallet of the wallet's network and a `MultisigScriptType`,
+// whose own key is the wallet's extended public key at an account path such as "m/48'/0'/0'/2'", to which cosigners
+// are added with `AddCosigner`. Returns error if the wallet is watch-only, the path is malformed or the script type is
+// unsupported.
+func (wallet *HDWallet) NewMultisigWalletBackup(accountPath string, threshold int, scriptType int, firstUsedHeight int) (*MultisigWalletBackup, error) {
+	path, err := ParseCustomDerivationPath(accountPath)
+	if err != nil {
+		return nil, err
+	}
+	if !isMultisigScriptType(scriptType) {
+		return nil, ErrMultisigScriptType
+	}
+	if firstUsedHeight < 0 {
+		return nil, errors.New("first used height cannot be negative")
+	}
+	xpub, err := wallet.ExtendedPublicKeyForPath(path)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := wallet.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	own := &MultisigCosigner{
+		MasterFingerprint: fingerprint,
+		DerivationPath:    path.String(),
+		ExtendedPublicKey: xpub,
+		IsOwn:             true,
+	}
+	return &MultisigWalletBackup{
+		Threshold:          threshold,
+		ScriptType:         scriptType,
+		DerivationTemplate: multisigDerivationTemplate,
+		FirstUsedHeight:    firstUsedHeight,
+		coin:               wallet.BaseCoin.Coin,
+		testNetwork:        wallet.BaseCoin.TestNetwork,
+		cosigners:          []*MultisigCosigner{own},
+	}, nil
+}
+
+/// Receiver functions
+
+// AddCosigner adds another cosigner's extended public key, with the hex master fingerprint and path it was derived
+// at. Returns ErrMultisigCosignerKey if the key is not an extended public key of the backup's network at the depth of
+// the path, or ErrMultisigCosignerDuplicate if it has already been added.
+func (b *MultisigWalletBackup) AddCosigner(masterFingerprint string, derivationPath string, extendedPublicKey string) error {
+	cosigner := &MultisigCosigner{
+		MasterFingerprint: strings.ToLower(masterFingerprint),
+		DerivationPath:    derivationPath,
+		ExtendedPublicKey: extendedPublicKey,
+	}
+	if err := b.validateCosigner(cosigner); err != nil {
+		return err
+	}
+	for _, existing := range b.cosigners {
+		if existing.ExtendedPublicKey == cosigner.ExtendedPublicKey {
+			return ErrMultisigCosignerDuplicate
+		}
+	}
+	if len(b.cosigners) >= maxWitnessMultisigKeys {
+		return ErrMultisigBackupThreshold
+	}
+	if path, err := ParseCustomDerivationPath(derivationPath); err == nil {
+		cosigner.DerivationPath = path.String()
+	}
+	b.cosigners = append(b.cosigners, cosigner)
+	return nil
+}
+
+// CosignerCount returns the number of cosigners, including this wallet.
+func (b *MultisigWalletBackup) CosignerCount() int {
+	return len(b.cosigners)
+}
+
+// CosignerAtIndex returns the cosigner at a given index, or error if out of bounds. This wallet is at index 0 of a
+// backup it created.
+func (b *MultisigWalletBackup) CosignerAtIndex(index int) (*MultisigCosigner, error) {
+	if index < 0 || index > len(b.cosigners)-1 {
+		return nil, errors.New("index must be within range of cosigners")
+	}
+	return b.cosigners[index], nil
+}
+
+// Descriptor returns the wallet's BIP380 wsh(sortedmulti) output descriptor with checksum, or sh(wsh(sortedmulti)) if
+// P2SH-P2WSH, covering receive and change addresses, for importing into other multisig coordinators.
+func (b *MultisigWalletBackup) Descriptor() (string, error) {
+	if err := b.validate(); err != nil {
+		return "", err
+	}
+	keys := make([]string, len(b.cosigners))
+	for i, cosigner := range b.cosigners {
+		origin := strings.TrimPrefix(cosigner.DerivationPath, "m/")
+		keys[i] = fmt.Sprintf("[%s/%s]%s/%s", cosigner.MasterFingerprint, origin, cosigner.ExtendedPublicKey, b.DerivationTemplate)
+	}
+	descriptor := fmt.Sprintf("wsh(sortedmulti(%d,%s))", b.Threshold, strings.Join(keys, ","))
+	if b.ScriptType == MultisigScriptTypeP2SHP2WSH {
+		descriptor = "sh(" + descriptor + ")"
+	}
+	checksum, err := DescriptorChecksum(descriptor)
+	if err != nil {
+		return "", err
+	}
+	return descriptor + "#" + checksum, nil
+}
+
+// Encode returns the backup as a JSON string with a checksum of its contents, for passing to
+// `DecodeMultisigWalletBackup`. Returns error if the backup is incomplete, such as having fewer cosigners than its
+// threshold.
+func (b *MultisigWalletBackup) Encode() (string, error) {
+	if err := b.validate(); err != nil {
+		return "", err
+	}
+	state := b.state()
+	state.Checksum = hex.EncodeToString(state.checksum())
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// VerifyOwnKey returns ErrMultisigOwnKeyMismatch unless the wallet derives the backup's own cosigner key, such as
+// after restoring the wallet from its words alongside the backup.
+func (b *MultisigWalletBackup) VerifyOwnKey(wallet *HDWallet) error {
+	own := b.ownCosigner()
+	if own == nil {
+		return ErrMultisigCosignerOwnMissing
+	}
+	path, err := ParseCustomDerivationPath(own.DerivationPath)
+	if err != nil {
+		return err
+	}
+	xpub, err := wallet.ExtendedPublicKeyForPath(path)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := wallet.Fingerprint()
+	if err != nil {
+		return err
+	}
+	if xpub != own.ExtendedPublicKey || fingerprint != own.MasterFingerprint {
+		return ErrMultisigOwnKeyMismatch
+	}
+	return nil
+}
+
+/// Exported functions
+
+// DecodeMultisigWalletBackup parses a backup from a string returned by `Encode`. Returns ErrMultisigBackupChecksum if
+// the backup was modified or corrupted, or error if it could not restore the wallet.
+func DecodeMultisigWalletBackup(encoded string) (*MultisigWalletBackup, error) {
+	var state multisigWalletBackupState
+	if err := json.Unmarshal([]byte(encoded), &state); err != nil {
+		return nil, err
+	}
+	switch state.Version {
+	case multisigWalletBackupVersion:
+	case multisigWalletBackupVersionP2WSH:
+		if state.ScriptType != 0 {
+			return nil, ErrMultisigBackupChecksum
+		}
+	default:
+		return nil, errors.New("unsupported multisig wallet backup version")
+	}
+	checksum, err := hex.DecodeString(state.Checksum)
+	if err != nil || !bytes.Equal(checksum, state.checksum()) {
+		return nil, ErrMultisigBackupChecksum
+	}
+
+	backup := &MultisigWalletBackup{
+		Threshold:          state.Threshold,
+		ScriptType:         state.ScriptType,
+		DerivationTemplate: state.DerivationTemplate,
+		FirstUsedHeight:    state.FirstUsedHeight,
+		coin:               state.Coin,
+		testNetwork:        state.TestNetwork,
+		cosigners:          state.Cosigners,
+	}
+	if state.Version == multisigWalletBackupVersionP2WSH {
+		backup.ScriptType = MultisigScriptTypeP2WSH
+	}
+	if err := backup.validate(); err != nil {
+		return nil, err
+	}
+	return backup, nil
+}
+
+/// Unexported functions
+
+func (b *MultisigWalletBackup) state() *multisigWalletBackupState {
+	return &multisigWalletBackupState{
+		Version:            multisigWalletBackupVersion,
+		Coin:               b.coin,
+		TestNetwork:        b.testNetwork,
+		Threshold:          b.Threshold,
+		ScriptType:         b.ScriptType,
+		DerivationTemplate: b.DerivationTemplate,
+		FirstUsedHeight:    b.FirstUsedHeight,
+		Cosigners:          b.cosigners,
+	}
+}
+
+// validate returns error unless the backup has a valid quorum, exactly one own key, and only valid, distinct keys.
+func (b *MultisigWalletBackup) validate() error {
+	if _, ok := networkRegistry[b.coin]; !ok {
+		return ErrInvalidCoinValue
+	}
+	count := len(b.cosigners)
+	if count < 2 || count > maxWitnessMultisigKeys || b.Threshold < 1 || b.Threshold > count {
+		return ErrMultisigBackupThreshold
+	}
+	if !isMultisigScriptType(b.ScriptType) {
+		return ErrMultisigScriptType
+	}
+	if b.DerivationTemplate != multisigDerivationTemplate {
+		return errors.New("unsupported multisig derivation template")
+	}
+	if b.FirstUsedHeight < 0 {
+		return errors.New("first used height cannot be negative")
+	}
+
+	own := 0
+	seen := make(map[string]bool, count)
+	for _, cosigner := range b.cosigners {
+		if cosigner == nil {
+			return ErrMultisigCosignerKey
+		}
+		if err := b.validateCosigner(cosigner); err != nil {
+			return err
+		}
+		if seen[cosigner.ExtendedPublicKey] {
+			return ErrMultisigCosignerDuplicate
+		}
+		seen[cosigner.ExtendedPublicKey] = true
+		if cosigner.IsOwn {
+			own++
+		}
+	}
+	if own != 1 {
+		return ErrMultisigCosignerOwnMissing
+	}
+	return nil
+}
+
+// validateCosigner returns error if a cosigner's fingerprint or path is malformed, or its key is not an extended
+// public key of the backup's network at the depth of its path.
+func (b *MultisigWalletBackup) validateCosigner(cosigner *MultisigCosigner) error {
+	fingerprint, err := hex.DecodeString(cosigner.MasterFingerprint)
+	if err != nil || len(fingerprint) != 4 {
+		return errors.New("master fingerprint must be 4 hex-encoded bytes")
+	}
+	path, err := ParseCustomDerivationPath(cosigner.DerivationPath)
+	if err != nil {
+		return err
+	}
+	key, err := hdkeychain.NewKeyFromString(cosigner.ExtendedPublicKey)
+	if err != nil || key.IsPrivate() || int(key.Depth()) != path.Depth() {
+		return ErrMultisigCosignerKey
+	}
+	bc := &BaseCoin{Coin: b.coin, TestNetwork: b.testNetwork}
+	if !key.IsForNet(bc.defaultNetParams()) {
+		return ErrMultisigCosignerKey
+	}
+	return nil
+}
+
+func (b *MultisigWalletBackup) ownCosigner() *MultisigCosigner {
+	for _, cosigner := range b.cosigners {
+		if cosigner.IsOwn {
+			return cosigner
+		}
+	}
+	return nil
+}
+
+// checksum returns the SHA256 of the backup's fields, each on its own line after a domain separator. The script type
+// is covered from version 2.
+func (s *multisigWalletBackupState) checksum() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "cnlib multisig wallet backup\n%d\n%d\n%d\n%d\n%s\n%d", s.Version, s.Coin, s.TestNetwork,
+		s.Threshold, s.DerivationTemplate, s.FirstUsedHeight)
+	if s.Version != multisigWalletBackupVersionP2WSH {
+		fmt.Fprintf(&buf, "\n%d", s.ScriptType)
+	}
+	for _, cosigner := range s.Cosigners {
+		if cosigner == nil {
+			continue
+		}
+		fmt.Fprintf(&buf, "\n%s %s %s %t", cosigner.MasterFingerprint, cosigner.DerivationPath,
+			cosigner.ExtendedPublicKey, cosigner.IsOwn)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return sum[:]
+}
@@ -0,0 +1,210 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const multisigAccountPath = "m/48'/0'/0'/2'"
+
+func multisigCosignerWallet(t *testing.T, fill byte, basecoin *BaseCoin) *HDWallet {
+	words, err := NewWordListFromEntropy(bytes.Repeat([]byte{fill}, 16))
+	assert.Nil(t, err)
+	return NewHDWalletFromWords(words, basecoin)
+}
+
+func multisigCosignerKey(t *testing.T, wallet *HDWallet, accountPath string) (string, string) {
+	path, err := ParseCustomDerivationPath(accountPath)
+	assert.Nil(t, err)
+	xpub, err := wallet.ExtendedPublicKeyForPath(path)
+	assert.Nil(t, err)
+	fingerprint, err := wallet.Fingerprint()
+	assert.Nil(t, err)
+	return fingerprint, xpub
+}
+
+func newTestMultisigWalletBackup(t *testing.T) (*HDWallet, *MultisigWalletBackup) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	backup, err := wallet.NewMultisigWalletBackup(multisigAccountPath, 2, MultisigScriptTypeP2WSH, 700000)
+	assert.Nil(t, err)
+	for _, fill := range []byte{0x01, 0x02} {
+		fingerprint, xpub := multisigCosignerKey(t, multisigCosignerWallet(t, fill, BaseCoinBip84MainNet), multisigAccountPath)
+		assert.Nil(t, backup.AddCosigner(fingerprint, "m/48h/0h/0h/2h", xpub))
+	}
+	return wallet, backup
+}
+
+func TestMultisigWalletBackup_RoundTrip(t *testing.T) {
+	wallet, backup := newTestMultisigWalletBackup(t)
+	assert.Equal(t, 3, backup.CosignerCount())
+	own, err := backup.CosignerAtIndex(0)
+	assert.Nil(t, err)
+	assert.True(t, own.IsOwn)
+	assert.Equal(t, multisigAccountPath, own.DerivationPath)
+	cosigner, err := backup.CosignerAtIndex(2)
+	assert.Nil(t, err)
+	assert.False(t, cosigner.IsOwn)
+	assert.Equal(t, multisigAccountPath, cosigner.DerivationPath)
+	_, err = backup.CosignerAtIndex(3)
+	assert.NotNil(t, err)
+
+	encoded, err := backup.Encode()
+	assert.Nil(t, err)
+	decoded, err := DecodeMultisigWalletBackup(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, decoded.Threshold)
+	assert.Equal(t, 700000, decoded.FirstUsedHeight)
+	assert.Equal(t, "<0;1>/*", decoded.DerivationTemplate)
+	assert.Equal(t, 3, decoded.CosignerCount())
+	for i := 0; i < 3; i++ {
+		original, _ := backup.CosignerAtIndex(i)
+		restored, _ := decoded.CosignerAtIndex(i)
+		assert.Equal(t, original, restored)
+	}
+
+	assert.Nil(t, decoded.VerifyOwnKey(wallet))
+	assert.Equal(t, ErrMultisigOwnKeyMismatch, decoded.VerifyOwnKey(multisigCosignerWallet(t, 0x01, BaseCoinBip84MainNet)))
+}
+
+func TestMultisigWalletBackup_Descriptor(t *testing.T) {
+	_, backup := newTestMultisigWalletBackup(t)
+	descriptor, err := backup.Descriptor()
+	assert.Nil(t, err)
+
+	own, _ := backup.CosignerAtIndex(0)
+	assert.True(t, strings.HasPrefix(descriptor, "wsh(sortedmulti(2,["+own.MasterFingerprint+"/48'/0'/0'/2']xpub"))
+	assert.Equal(t, 3, strings.Count(descriptor, "/<0;1>/*"))
+
+	parts := strings.Split(descriptor, "#")
+	assert.Equal(t, 2, len(parts))
+	checksum, err := DescriptorChecksum(parts[0])
+	assert.Nil(t, err)
+	assert.Equal(t, checksum, parts[1])
+}
+
+func TestDecodeMultisigWalletBackup_Tampered(t *testing.T) {
+	_, backup := newTestMultisigWalletBackup(t)
+	encoded, err := backup.Encode()
+	assert.Nil(t, err)
+
+	_, err = DecodeMultisigWalletBackup(strings.Replace(encoded, `"threshold":2`, `"threshold":1`, 1))
+	assert.Equal(t, ErrMultisigBackupChecksum, err)
+
+	cosigner, _ := backup.CosignerAtIndex(1)
+	_, err = DecodeMultisigWalletBackup(strings.Replace(encoded, cosigner.ExtendedPublicKey, "", 1))
+	assert.Equal(t, ErrMultisigBackupChecksum, err)
+
+	_, err = DecodeMultisigWalletBackup(strings.Replace(encoded, `"script_type":2`, `"script_type":1`, 1))
+	assert.Equal(t, ErrMultisigBackupChecksum, err)
+
+	_, err = DecodeMultisigWalletBackup(strings.Replace(encoded, `"version":2`, `"version":3`, 1))
+	assert.NotNil(t, err)
+}
+
+func TestMultisigWalletBackup_ScriptType(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	nestedPath := "m/48'/0'/0'/1'"
+	backup, err := wallet.NewMultisigWalletBackup(nestedPath, 2, MultisigScriptTypeP2SHP2WSH, 0)
+	assert.Nil(t, err)
+	fingerprint, xpub := multisigCosignerKey(t, multisigCosignerWallet(t, 0x01, BaseCoinBip84MainNet), nestedPath)
+	assert.Nil(t, backup.AddCosigner(fingerprint, nestedPath, xpub))
+
+	encoded, err := backup.Encode()
+	assert.Nil(t, err)
+	decoded, err := DecodeMultisigWalletBackup(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, MultisigScriptTypeP2SHP2WSH, decoded.ScriptType)
+	descriptor, err := decoded.Descriptor()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(descriptor, "sh(wsh(sortedmulti(2,["))
+	multisig, err := decoded.MultisigWallet()
+	assert.Nil(t, err)
+	assert.Equal(t, MultisigScriptTypeP2SHP2WSH, multisig.ScriptType)
+	address, err := multisig.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(address.Address, "3"))
+
+	_, err = wallet.NewMultisigWalletBackup(multisigAccountPath, 2, 3, 0)
+	assert.Equal(t, ErrMultisigScriptType, err)
+	backup.ScriptType = 0
+	_, err = backup.Encode()
+	assert.Equal(t, ErrMultisigScriptType, err)
+	_, err = backup.Descriptor()
+	assert.Equal(t, ErrMultisigScriptType, err)
+}
+
+func TestDecodeMultisigWalletBackup_Version1IsP2WSH(t *testing.T) {
+	_, backup := newTestMultisigWalletBackup(t)
+	state := backup.state()
+	state.Version = 1
+	state.ScriptType = 0
+	state.Checksum = hex.EncodeToString(state.checksum())
+	encoded, err := json.Marshal(state)
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(string(encoded), "script_type"))
+
+	decoded, err := DecodeMultisigWalletBackup(string(encoded))
+	assert.Nil(t, err)
+	assert.Equal(t, MultisigScriptTypeP2WSH, decoded.ScriptType)
+	expected, _ := backup.Descriptor()
+	descriptor, err := decoded.Descriptor()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, descriptor)
+
+	// a script type cannot be slipped into a version 1 backup
+	state.ScriptType = MultisigScriptTypeP2SHP2WSH
+	state.Checksum = hex.EncodeToString(state.checksum())
+	encoded, _ = json.Marshal(state)
+	_, err = DecodeMultisigWalletBackup(string(encoded))
+	assert.NotNil(t, err)
+}
+
+func TestMultisigWalletBackup_InvalidCosigners(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	backup, err := wallet.NewMultisigWalletBackup(multisigAccountPath, 2, MultisigScriptTypeP2WSH, 0)
+	assert.Nil(t, err)
+
+	// a single cosigner cannot meet a threshold of 2
+	_, err = backup.Encode()
+	assert.Equal(t, ErrMultisigBackupThreshold, err)
+
+	own, _ := backup.CosignerAtIndex(0)
+	assert.Equal(t, ErrMultisigCosignerDuplicate, backup.AddCosigner(own.MasterFingerprint, multisigAccountPath, own.ExtendedPublicKey))
+
+	other := multisigCosignerWallet(t, 0x03, BaseCoinBip84MainNet)
+	fingerprint, xpub := multisigCosignerKey(t, other, multisigAccountPath)
+	assert.Equal(t, ErrMultisigCosignerKey, backup.AddCosigner(fingerprint, "m/48'/0'/0'", xpub))
+	assert.NotNil(t, backup.AddCosigner("0102", multisigAccountPath, xpub))
+	assert.NotNil(t, backup.AddCosigner(fingerprint, "48'/0'/0'/2'", xpub))
+
+	testnetFingerprint, tpub := multisigCosignerKey(t, multisigCosignerWallet(t, 0x03, BaseCoinBip84TestNet), "m/48'/1'/0'/2'")
+	assert.Equal(t, ErrMultisigCosignerKey, backup.AddCosigner(testnetFingerprint, "m/48'/1'/0'/2'", tpub))
+
+	assert.Equal(t, 1, backup.CosignerCount())
+	assert.Nil(t, backup.AddCosigner(strings.ToUpper(fingerprint), multisigAccountPath, xpub))
+	_, err = backup.Encode()
+	assert.Nil(t, err)
+
+	backup.Threshold = 3
+	_, err = backup.Encode()
+	assert.Equal(t, ErrMultisigBackupThreshold, err)
+}
+
+func TestNewMultisigWalletBackup_Invalid(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	_, err := wallet.NewMultisigWalletBackup("48'/0'/0'/2'", 2, MultisigScriptTypeP2WSH, 0)
+	assert.NotNil(t, err)
+	_, err = wallet.NewMultisigWalletBackup(multisigAccountPath, 2, MultisigScriptTypeP2WSH, -1)
+	assert.NotNil(t, err)
+
+	accountKey, _ := wallet.AccountExtendedPublicKey()
+	watchOnly, err := NewHDWalletFromAccountExtendedPublicKey(accountKey)
+	assert.Nil(t, err)
+	_, err = watchOnly.NewMultisigWalletBackup(multisigAccountPath, 2, MultisigScriptTypeP2WSH, 0)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
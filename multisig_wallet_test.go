@@ -0,0 +1,181 @@
+package cnlib
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestMultisigWallet returns a 2-of-3 MultisigWallet of three cosigner wallets, the first of which is `w`.
+func newTestMultisigWallet(t *testing.T, scriptType int) (*MultisigWallet, []*HDWallet, []string) {
+	multisig, err := NewMultisigWallet(BaseCoinBip84MainNet, 2, scriptType)
+	assert.Nil(t, err)
+	wallets := []*HDWallet{
+		NewHDWalletFromWords(w, BaseCoinBip84MainNet),
+		multisigCosignerWallet(t, 0x01, BaseCoinBip84MainNet),
+		multisigCosignerWallet(t, 0x02, BaseCoinBip84MainNet),
+	}
+	var xpubs []string
+	for _, wallet := range wallets {
+		fingerprint, xpub := multisigCosignerKey(t, wallet, multisigAccountPath)
+		assert.Nil(t, multisig.AddCosigner(fingerprint, multisigAccountPath, xpub))
+		xpubs = append(xpubs, xpub)
+	}
+	return multisig, wallets, xpubs
+}
+
+func TestMultisigWallet_AddressesMatchDescriptor(t *testing.T) {
+	multisig, _, xpubs := newTestMultisigWallet(t, MultisigScriptTypeP2WSH)
+	assert.Equal(t, 3, multisig.CosignerCount())
+
+	for _, change := range []int{0, 1} {
+		var address *MultisigAddress
+		var err error
+		if change == 0 {
+			address, err = multisig.ReceiveAddressForIndex(7)
+		} else {
+			address, err = multisig.ChangeAddressForIndex(7)
+		}
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(address.Address, "bc1q"))
+		assert.Equal(t, change, address.Change)
+		assert.Equal(t, 7, address.Index)
+		assert.Nil(t, address.RedeemScript)
+
+		keys := make([]string, len(xpubs))
+		for i, xpub := range xpubs {
+			keys[i] = fmt.Sprintf("%s/%d/7", xpub, change)
+		}
+		descriptor, err := parseOutputDescriptor(fmt.Sprintf("wsh(sortedmulti(2,%s))", strings.Join(keys, ",")))
+		assert.Nil(t, err)
+		expected, err := descriptor.pkScript()
+		assert.Nil(t, err)
+		script, err := AddressScript(address.Address)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, script)
+	}
+
+	receive, _ := multisig.ReceiveAddressForIndex(0)
+	change, _ := multisig.ChangeAddressForIndex(0)
+	assert.NotEqual(t, receive.Address, change.Address)
+}
+
+func TestMultisigWallet_NestedSegwit(t *testing.T) {
+	native, _, _ := newTestMultisigWallet(t, MultisigScriptTypeP2WSH)
+	nested, _, _ := newTestMultisigWallet(t, MultisigScriptTypeP2SHP2WSH)
+	nativeAddress, _ := native.ReceiveAddressForIndex(0)
+	address, err := nested.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(address.Address, "3"))
+	assert.Equal(t, nativeAddress.WitnessScript, address.WitnessScript)
+	nativeScript, _ := AddressScript(nativeAddress.Address)
+	assert.Equal(t, nativeScript, address.RedeemScript)
+}
+
+func TestMultisigWallet_InputBytes(t *testing.T) {
+	multisig, _, _ := newTestMultisigWallet(t, MultisigScriptTypeP2WSH)
+	size, err := multisig.InputBytes()
+	assert.Nil(t, err)
+	assert.Equal(t, 105, size)
+
+	helper := NewAddressHelper()
+	size, err = helper.MultisigInputBytes(2, 3, MultisigScriptTypeP2SHP2WSH)
+	assert.Nil(t, err)
+	assert.Equal(t, 140, size)
+	size, err = helper.MultisigInputBytes(17, 20, MultisigScriptTypeP2WSH)
+	assert.Nil(t, err)
+	assert.Equal(t, 41+(2+17*73+3+685+3)/4, size)
+
+	_, err = helper.MultisigInputBytes(3, 2, MultisigScriptTypeP2WSH)
+	assert.Equal(t, ErrMultisigWalletIncomplete, err)
+	_, err = helper.MultisigInputBytes(2, 3, 0)
+	assert.NotNil(t, err)
+
+	// an actual spend is no larger than estimated
+	address, _ := multisig.ReceiveAddressForIndex(0)
+	witness := wire.TxWitness{{}, make([]byte, 72), make([]byte, 71), address.WitnessScript}
+	txIn := wire.NewTxIn(&wire.OutPoint{}, nil, witness)
+	actual := (txIn.SerializeSize()*4 + witness.SerializeSize() + 3) / 4
+	assert.True(t, actual <= 105)
+}
+
+func TestMultisigWallet_Invalid(t *testing.T) {
+	_, err := NewMultisigWallet(BaseCoinBip84MainNet, 2, 3)
+	assert.NotNil(t, err)
+	_, err = NewMultisigWallet(nil, 2, MultisigScriptTypeP2WSH)
+	assert.NotNil(t, err)
+
+	multisig, _ := NewMultisigWallet(BaseCoinBip84MainNet, 2, MultisigScriptTypeP2WSH)
+	fingerprint, xpub := multisigCosignerKey(t, NewHDWalletFromWords(w, BaseCoinBip84MainNet), multisigAccountPath)
+	assert.Nil(t, multisig.AddCosigner(fingerprint, multisigAccountPath, xpub))
+	_, err = multisig.ReceiveAddressForIndex(0)
+	assert.Equal(t, ErrMultisigWalletIncomplete, err)
+
+	assert.Equal(t, ErrMultisigCosignerDuplicate, multisig.AddCosigner(fingerprint, multisigAccountPath, xpub))
+	assert.Equal(t, ErrMultisigCosignerKey, multisig.AddCosigner(fingerprint, "m/48'/0'/0'", xpub))
+	assert.NotNil(t, multisig.AddCosigner("73c5da", multisigAccountPath, xpub))
+	_, tpub := multisigCosignerKey(t, NewHDWalletFromWords(w, BaseCoinBip84TestNet), "m/48'/1'/0'/2'")
+	assert.Equal(t, ErrMultisigCosignerKey, multisig.AddCosigner(fingerprint, "m/48'/1'/0'/2'", tpub))
+}
+
+func TestMultisigWalletBackup_MultisigWallet(t *testing.T) {
+	_, backup := newTestMultisigWalletBackup(t)
+	fromBackup, err := backup.MultisigWallet()
+	assert.Nil(t, err)
+	assert.Equal(t, MultisigScriptTypeP2WSH, fromBackup.ScriptType)
+	multisig, _, _ := newTestMultisigWallet(t, MultisigScriptTypeP2WSH)
+
+	expected, _ := multisig.ReceiveAddressForIndex(3)
+	address, err := fromBackup.ReceiveAddressForIndex(3)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Address, address.Address)
+}
+
+func TestSignMultisigPSBT(t *testing.T) {
+	multisig, wallets, _ := newTestMultisigWallet(t, MultisigScriptTypeP2SHP2WSH)
+	address, _ := multisig.ChangeAddressForIndex(3)
+	pkScript, _ := AddressScript(address.Address)
+
+	rawTx, _ := NewRawTransaction(2, 0)
+	assert.Nil(t, rawTx.AddInput(NewRawTransactionInput(descriptorTestTxid, 0, 0xfffffffd)))
+	outputScript, _ := AddressScript("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6")
+	assert.Nil(t, rawTx.AddOutput(NewRawTransactionOutput(99000, outputScript)))
+	encodedTx, _ := rawTx.Encode()
+	tx, _ := decodeMsgTx(encodedTx)
+	packet, _ := psbt.NewPsbtFromUnsignedTx(tx)
+	updater, _ := psbt.NewUpdater(packet)
+	assert.Nil(t, updater.AddInWitnessUtxo(wire.NewTxOut(100000, pkScript), 0))
+	unsigned, _ := packet.B64Encode()
+
+	// not within the addresses scanned, or not a cosigner
+	_, err := wallets[0].SignMultisigPSBT(multisig, unsigned, 3)
+	assert.NotNil(t, err)
+	outsider := multisigCosignerWallet(t, 0x03, BaseCoinBip84MainNet)
+	_, err = outsider.SignMultisigPSBT(multisig, unsigned, 5)
+	assert.Equal(t, ErrMultisigCosignerOwnMissing, err)
+
+	first, err := wallets[0].SignMultisigPSBT(multisig, unsigned, 5)
+	assert.Nil(t, err)
+	_, err = wallets[0].SignMultisigPSBT(multisig, first, 5)
+	assert.NotNil(t, err)
+	third, err := wallets[2].SignMultisigPSBT(multisig, unsigned, 5)
+	assert.Nil(t, err)
+
+	combined, err := CombinePSBTs([]string{first, third})
+	assert.Nil(t, err)
+	meta, err := wallets[1].FinalizePSBT(combined)
+	assert.Nil(t, err)
+
+	signed, _ := decodeMsgTx(meta.EncodedTx)
+	engine, err := txscript.NewEngine(pkScript, signed, 0, txscript.StandardVerifyFlags, nil, txscript.NewTxSigHashes(signed), 100000)
+	assert.Nil(t, err)
+	assert.Nil(t, engine.Execute())
+	hash := sha256.Sum256(address.WitnessScript)
+	assert.Equal(t, hash[:], address.RedeemScript[2:])
+}
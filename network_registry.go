@@ -0,0 +1,253 @@
+package cnlib
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+/// Type Definitions
+
+// Following constants select the network of a BaseCoin whose coin is 1, as BIP44 assigns every test network that coin.
+const (
+	TestNetworkRegtest int = 0 // default
+	TestNetworkTestnet int = 1
+	TestNetworkSignet  int = 2
+)
+
+// Genesis block hashes of the networks whose parameters btcd does not provide.
+const (
+	signetGenesisHash   = "00000008819873e925422c1ff0f99f7cc9bbb232af63a077a480a3633bee1ef6"
+	litecoinGenesisHash = "12a765e31ffd4059bada1e25190f6e98c99d9714d334efa41a195a7e7e04bfe2"
+)
+
+// NetworkInfo describes the parameters of a network supported by the library: the bech32 HRP and base58 version
+// bytes used to encode its addresses, and the purpose used for new wallets.
+type NetworkInfo struct {
+	Name             string
+	Coin             int // BIP44 coin type; 0 for mainnet, 1 for test networks, 2 for Litecoin
+	TestNetwork      int // `TestNetwork` constant selecting the network if Coin is 1
+	Bech32HRP        string
+	PubKeyHashAddrID int // base58 version byte of P2PKH addresses
+	ScriptHashAddrID int // base58 version byte of P2SH addresses
+	WIFPrivateKeyID  int // base58 version byte of WIF encoded private keys
+	DefaultPurpose   int
+}
+
+// networkParams is a registry entry, the single source of truth for a network's parameters.
+type networkParams struct {
+	name                string
+	coin                int
+	testNetwork         int
+	chainParams         *chaincfg.Params
+	extendedPubkeyTypes map[int]string // keyed by purpose
+}
+
+var (
+	mainnetNetwork = &networkParams{
+		name:        "mainnet",
+		coin:        mainnet,
+		chainParams: &chaincfg.MainNetParams,
+		extendedPubkeyTypes: map[int]string{
+			bip44purpose: xpub,
+			bip49purpose: ypub,
+			bip84purpose: zpub,
+		},
+	}
+	regtestNetwork = &networkParams{
+		name:                "regtest",
+		coin:                testnet,
+		testNetwork:         TestNetworkRegtest,
+		chainParams:         &chaincfg.RegressionNetParams,
+		extendedPubkeyTypes: testExtendedPubkeyTypes,
+	}
+	testnet3Network = &networkParams{
+		name:                "testnet",
+		coin:                testnet,
+		testNetwork:         TestNetworkTestnet,
+		chainParams:         &chaincfg.TestNet3Params,
+		extendedPubkeyTypes: testExtendedPubkeyTypes,
+	}
+	litecoinNetwork = &networkParams{
+		name:        "litecoin",
+		coin:        litecoin,
+		chainParams: newLitecoinParams(),
+		// SLIP-132's, so account keys decode as Litecoin's; BIP84 keys use Ltub, as SLIP-132 defines none for them
+		extendedPubkeyTypes: map[int]string{
+			bip44purpose: ltub,
+			bip49purpose: mtub,
+		},
+	}
+	signetNetwork = &networkParams{
+		name:                "signet",
+		coin:                testnet,
+		testNetwork:         TestNetworkSignet,
+		chainParams:         newSignetParams(),
+		extendedPubkeyTypes: testExtendedPubkeyTypes,
+	}
+)
+
+// testExtendedPubkeyTypes are the extended public key types of every test network.
+var testExtendedPubkeyTypes = map[int]string{
+	bip44purpose: tpub,
+	bip49purpose: upub,
+	bip84purpose: vpub,
+}
+
+// networkRegistry holds the default network of each coin, keyed by coin. Test coin wallets default to regtest.
+var networkRegistry = map[int]*networkParams{
+	mainnet:  mainnetNetwork,
+	testnet:  regtestNetwork,
+	litecoin: litecoinNetwork,
+}
+
+// testNetworkRegistry holds every test network, keyed by the `TestNetwork` constant selecting it.
+var testNetworkRegistry = map[int]*networkParams{
+	TestNetworkRegtest: regtestNetwork,
+	TestNetworkTestnet: testnet3Network,
+	TestNetworkSignet:  signetNetwork,
+}
+
+// supportedNetworks lists every network in the order addresses are matched against them. The test networks share
+// base58 version bytes, and testnet and signet share an HRP, so such addresses resolve to the earliest listed.
+var supportedNetworks = []*networkParams{mainnetNetwork, regtestNetwork, testnet3Network, signetNetwork, litecoinNetwork}
+
+// btcutil only decodes segwit addresses of registered networks. An app which registered Litecoin itself gets
+// `ErrDuplicateNet`, which is harmless.
+func init() {
+	_ = chaincfg.Register(litecoinNetwork.chainParams)
+}
+
+// defaultPurpose is the purpose used for new wallets on every network.
+const defaultPurpose = bip84purpose
+
+/// Exported functions
+
+// NetworkInfoForBaseCoin returns the parameters of the network a BaseCoin belongs to.
+func NetworkInfoForBaseCoin(bc *BaseCoin) (*NetworkInfo, error) {
+	if bc == nil {
+		return nil, errors.New("no basecoin provided")
+	}
+	return bc.network().info(), nil
+}
+
+// NetworkInfoForAddress returns the parameters of the network an address is encoded for, determined by its bech32
+// HRP or base58 version byte. Does not fully validate the address.
+func NetworkInfoForAddress(address string) (*NetworkInfo, error) {
+	network, err := networkForAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return network.info(), nil
+}
+
+/// Unexported functions
+
+// network returns the registry entry for the BaseCoin's coin and test network. Any coin other than mainnet's or
+// Litecoin's is treated as a test network, and an unknown `TestNetwork` as regtest.
+func (bc *BaseCoin) network() *networkParams {
+	if bc.Coin == litecoin {
+		return litecoinNetwork
+	}
+	if !bc.isTestNet() {
+		return mainnetNetwork
+	}
+	if network, ok := testNetworkRegistry[bc.TestNetwork]; ok {
+		return network
+	}
+	return regtestNetwork
+}
+
+func (n *networkParams) info() *NetworkInfo {
+	return &NetworkInfo{
+		Name:             n.name,
+		Coin:             n.coin,
+		TestNetwork:      n.testNetwork,
+		Bech32HRP:        n.chainParams.Bech32HRPSegwit,
+		PubKeyHashAddrID: int(n.chainParams.PubKeyHashAddrID),
+		ScriptHashAddrID: int(n.chainParams.ScriptHashAddrID),
+		WIFPrivateKeyID:  int(n.chainParams.PrivateKeyID),
+		DefaultPurpose:   defaultPurpose,
+	}
+}
+
+// networkForAddress looks up the registry entry matching an address's bech32 HRP or base58 version byte.
+func networkForAddress(address string) (*networkParams, error) {
+	if network := bech32NetworkForAddress(address); network != nil {
+		return network, nil
+	}
+
+	_, version, err := base58.CheckDecode(address)
+	if err == nil {
+		for _, network := range supportedNetworks {
+			if version == network.chainParams.PubKeyHashAddrID || version == network.chainParams.ScriptHashAddrID {
+				return network, nil
+			}
+		}
+	}
+
+	return nil, errors.New("address does not belong to a supported network")
+}
+
+// bech32NetworkForAddress returns the registry entry whose HRP and separator prefix the address, or nil if none.
+func bech32NetworkForAddress(address string) *networkParams {
+	lower := strings.ToLower(address)
+	for _, network := range supportedNetworks {
+		if strings.HasPrefix(lower, network.chainParams.Bech32HRPSegwit+"1") {
+			return network
+		}
+	}
+	return nil
+}
+
+// extendedPubkeyBaseCoin returns the purpose and coin of an extended public key version prefix, such as zpub.
+func extendedPubkeyBaseCoin(idType string) (int, int, bool) {
+	for _, network := range supportedNetworks {
+		for purpose, t := range network.extendedPubkeyTypes {
+			if t == idType {
+				return purpose, network.coin, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// newSignetParams returns the parameters of the default signet. The btcd release this library depends on predates
+// signet, so they are adapted from testnet's, with which signet shares its address and key encodings.
+func newSignetParams() *chaincfg.Params {
+	params := chaincfg.TestNet3Params
+	params.Name = "signet"
+	params.Net = 0x40cf030a
+	params.DefaultPort = "38333"
+	params.DNSSeeds = nil
+	params.Checkpoints = nil
+	params.GenesisBlock = nil
+	if hash, err := chainhash.NewHashFromStr(signetGenesisHash); err == nil {
+		params.GenesisHash = hash
+	}
+	return &params
+}
+
+// newLitecoinParams returns the parameters of Litecoin's main network, of which only the address and key encodings
+// are used.
+func newLitecoinParams() *chaincfg.Params {
+	params := chaincfg.MainNetParams
+	params.Name = "litecoin"
+	params.Net = 0xdbb6c0fb
+	params.DefaultPort = "9333"
+	params.DNSSeeds = nil
+	params.Checkpoints = nil
+	params.GenesisBlock = nil
+	if hash, err := chainhash.NewHashFromStr(litecoinGenesisHash); err == nil {
+		params.GenesisHash = hash
+	}
+	params.Bech32HRPSegwit = "ltc"
+	params.PubKeyHashAddrID = 0x30 // L
+	params.ScriptHashAddrID = 0x32 // M
+	params.PrivateKeyID = 0xb0
+	params.HDCoinType = litecoin
+	return &params
+}
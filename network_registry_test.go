@@ -0,0 +1,270 @@
+package cnlib
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkInfoForBaseCoin(t *testing.T) {
+	info, err := NetworkInfoForBaseCoin(BaseCoinBip49MainNet)
+	assert.Nil(t, err)
+	assert.Equal(t, "mainnet", info.Name)
+	assert.Equal(t, "bc", info.Bech32HRP)
+	assert.Equal(t, 0x00, info.PubKeyHashAddrID)
+	assert.Equal(t, 0x05, info.ScriptHashAddrID)
+	assert.Equal(t, 84, info.DefaultPurpose)
+
+	info, err = NetworkInfoForBaseCoin(BaseCoinBip84TestNet)
+	assert.Nil(t, err)
+	assert.Equal(t, "regtest", info.Name)
+	assert.Equal(t, 1, info.Coin)
+	assert.Equal(t, "bcrt", info.Bech32HRP)
+	assert.Equal(t, 0x6f, info.PubKeyHashAddrID)
+	assert.Equal(t, 0xc4, info.ScriptHashAddrID)
+
+	info, err = NetworkInfoForBaseCoin(&BaseCoin{Purpose: 84, Coin: 1, TestNetwork: TestNetworkTestnet})
+	assert.Nil(t, err)
+	assert.Equal(t, "testnet", info.Name)
+	assert.Equal(t, TestNetworkTestnet, info.TestNetwork)
+	assert.Equal(t, "tb", info.Bech32HRP)
+	assert.Equal(t, 0xef, info.WIFPrivateKeyID)
+
+	info, err = NetworkInfoForBaseCoin(&BaseCoin{Purpose: 84, Coin: 1, TestNetwork: TestNetworkSignet})
+	assert.Nil(t, err)
+	assert.Equal(t, "signet", info.Name)
+	assert.Equal(t, "tb", info.Bech32HRP)
+	assert.Equal(t, 0x6f, info.PubKeyHashAddrID)
+
+	_, err = NetworkInfoForBaseCoin(nil)
+	assert.NotNil(t, err)
+}
+
+func TestNetworkInfoForAddress(t *testing.T) {
+	cases := map[string]string{
+		"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu":   "mainnet",
+		"BC1QCR8TE4KR609GCAWUTMRZA0J4XV80JY8Z306FYU":   "mainnet",
+		"1B3kirKp5kmVnHJv6YyqaK8gbYkNCVo9WN":           "mainnet",
+		"3Cd4xEu2VvM352BVgd9cb1Ct5vxz318tVT":           "mainnet",
+		"bcrt1q6rz28mcfaxtmd6v789l9rrlrusdprr9pz3cppk": "regtest",
+		"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn":           "regtest",
+		"2MzQwSSnBHWHqSAqtTVQ6v47XtaisrJa1Vc":          "regtest",
+		"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx":   "testnet",
+		"ltc1qw508d6qejxtdg4y5r3zarvary0c5xw7kgmn4n9":  "litecoin",
+	}
+	for address, name := range cases {
+		info, err := NetworkInfoForAddress(address)
+		assert.Nil(t, err, address)
+		assert.Equal(t, name, info.Name, address)
+	}
+
+	_, err := NetworkInfoForAddress("xyz1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx")
+	assert.NotNil(t, err)
+	_, err = NetworkInfoForAddress("not an address")
+	assert.NotNil(t, err)
+}
+
+func TestNewBaseCoinFromAccountPubKey_UsesRegistry(t *testing.T) {
+	bc, err := NewBaseCoinFromAccountPubKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	assert.Equal(t, 84, bc.Purpose)
+	assert.Equal(t, 0, bc.Coin)
+
+	key, err := NewBaseCoin(49, 1, 0).defaultExtendedPubkeyType()
+	assert.Nil(t, err)
+	assert.Equal(t, upub, key)
+
+	_, err = NewBaseCoin(84, 3, 0).defaultExtendedPubkeyType()
+	assert.Equal(t, ErrInvalidCoinValue, err)
+}
+
+func TestNewTestNetworkBaseCoin(t *testing.T) {
+	bc, err := NewTestNetworkBaseCoin(84, 2, TestNetworkSignet)
+	assert.Nil(t, err)
+	assert.Equal(t, &BaseCoin{Purpose: 84, Coin: 1, Account: 2, TestNetwork: TestNetworkSignet}, bc)
+
+	_, err = NewTestNetworkBaseCoin(84, 0, 3)
+	assert.NotNil(t, err)
+}
+
+func TestTestNetworkAddresses(t *testing.T) {
+	prefixes := map[int]map[int]string{
+		TestNetworkRegtest: {49: "2", 84: "bcrt1q", 86: "bcrt1p"},
+		TestNetworkTestnet: {49: "2", 84: "tb1q", 86: "tb1p"},
+		TestNetworkSignet:  {49: "2", 84: "tb1q", 86: "tb1p"},
+	}
+	for testNetwork, byPurpose := range prefixes {
+		for purpose, prefix := range byPurpose {
+			bc, err := NewTestNetworkBaseCoin(purpose, 0, testNetwork)
+			assert.Nil(t, err)
+			wallet := NewHDWalletFromWords(w, bc)
+			regtest := NewHDWalletFromWords(w, NewBaseCoin(purpose, 1, 0))
+
+			for _, change := range []int{0, 1} {
+				meta, err := wallet.deriveAddressForIndex(change, 3)
+				assert.Nil(t, err)
+				assert.True(t, strings.HasPrefix(meta.Address, prefix), meta.Address)
+
+				// every test network pays the same script, encoded differently
+				script, err := AddressScript(meta.Address)
+				assert.Nil(t, err)
+				regtestMeta, err := regtest.deriveAddressForIndex(change, 3)
+				assert.Nil(t, err)
+				regtestScript, err := AddressScript(regtestMeta.Address)
+				assert.Nil(t, err)
+				assert.Equal(t, regtestScript, script)
+
+				found, err := wallet.CheckForAddress(meta.Address, 5)
+				assert.Nil(t, err)
+				assert.Equal(t, meta.Address, found.Address)
+			}
+		}
+	}
+}
+
+func TestTestNetworkImportPrivateKey(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x01}, 32))
+	hash := btcutil.Hash160(key.PubKey().SerializeCompressed())
+
+	testnetWIF, err := btcutil.NewWIF(key, &chaincfg.TestNet3Params, true)
+	assert.Nil(t, err)
+	mainnetWIF, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	assert.Nil(t, err)
+	legacy, err := btcutil.NewAddressPubKeyHash(hash, &chaincfg.TestNet3Params)
+	assert.Nil(t, err)
+	segwit, err := btcutil.NewAddressWitnessPubKeyHash(hash, &chaincfg.TestNet3Params)
+	assert.Nil(t, err)
+
+	bc, err := NewTestNetworkBaseCoin(84, 0, TestNetworkTestnet)
+	assert.Nil(t, err)
+	wallet := NewHDWalletFromWords(w, bc)
+	imported, err := wallet.ImportPrivateKey(testnetWIF.String())
+	assert.Nil(t, err)
+	addrs := strings.Split(imported.PossibleAddresses, " ")
+	assert.Contains(t, addrs, legacy.EncodeAddress())
+	assert.Contains(t, addrs, segwit.EncodeAddress())
+
+	_, err = wallet.ImportPrivateKey(mainnetWIF.String())
+	assert.Equal(t, ErrPrivateKeyNetwork, err)
+	_, err = NewHDWalletFromWords(w, BaseCoinBip84MainNet).ImportPrivateKey(testnetWIF.String())
+	assert.Equal(t, ErrPrivateKeyNetwork, err)
+}
+
+func TestTestNetworkAccountExtendedPublicKey(t *testing.T) {
+	regtest, err := NewHDWalletFromWords(w, BaseCoinBip84TestNet).AccountExtendedPublicKey()
+	assert.Nil(t, err)
+	for _, testNetwork := range []int{TestNetworkTestnet, TestNetworkSignet} {
+		bc, err := NewTestNetworkBaseCoin(84, 0, testNetwork)
+		assert.Nil(t, err)
+		key, err := NewHDWalletFromWords(w, bc).AccountExtendedPublicKey()
+		assert.Nil(t, err)
+		assert.Equal(t, regtest, key)
+	}
+}
+
+func TestLitecoinAddresses(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84Litecoin)
+	meta, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "ltc1qjmxnz78nmc8nq77wuxh25n2es7rzm5c2rkk4wh", meta.Address)
+	assert.Nil(t, AddressIsValidSegwitAddress(meta.Address))
+	hrp, err := BaseCoinBip84Litecoin.HRPFromAddress(meta.Address)
+	assert.Nil(t, err)
+	assert.Equal(t, "ltc", hrp)
+	hrp, err = BaseCoinBip84Litecoin.GetBech32HRP()
+	assert.Nil(t, err)
+	assert.Equal(t, "ltc", hrp)
+
+	nested, err := NewHDWalletFromWords(w, BaseCoinBip49Litecoin).ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "M", nested.Address[:1])
+
+	info, err := NetworkInfoForBaseCoin(BaseCoinBip84Litecoin)
+	assert.Nil(t, err)
+	assert.Equal(t, "litecoin", info.Name)
+	assert.Equal(t, 0x30, info.PubKeyHashAddrID)
+	assert.Equal(t, 0x32, info.ScriptHashAddrID)
+	assert.Equal(t, 0xb0, info.WIFPrivateKeyID)
+}
+
+func TestLitecoinAccountKeys_RoundTrip(t *testing.T) {
+	for _, basecoin := range []*BaseCoin{BaseCoinBip49Litecoin, BaseCoinBip84Litecoin} {
+		wallet := NewHDWalletFromWords(w, basecoin)
+		key, err := wallet.AccountExtendedPublicKey()
+		assert.Nil(t, err)
+		decoded, err := NewBaseCoinFromAccountPubKey(key)
+		assert.Nil(t, err)
+		assert.Equal(t, litecoin, decoded.Coin, key)
+
+		watchOnly, err := NewWatchOnlyWallet(key, basecoin)
+		assert.Nil(t, err)
+		expected, err := wallet.ReceiveAddressForIndex(3)
+		assert.Nil(t, err)
+		actual, err := watchOnly.ReceiveAddressForIndex(3)
+		assert.Nil(t, err)
+		assert.Equal(t, expected.Address, actual.Address)
+		again, err := watchOnly.AccountExtendedPublicKey()
+		assert.Nil(t, err)
+		assert.Equal(t, key, again)
+
+		// the same account's Bitcoin keys are not taken for Litecoin's
+		bitcoinKey, err := NewHDWalletFromWords(w, &BaseCoin{Purpose: basecoin.Purpose, Coin: mainnet}).AccountExtendedPublicKey()
+		assert.Nil(t, err)
+		_, err = NewWatchOnlyWallet(bitcoinKey, basecoin)
+		assert.Equal(t, ErrExtendedKeyNetwork, err)
+	}
+
+	nested, err := NewHDWalletFromWords(w, BaseCoinBip49Litecoin).AccountExtendedPublicKey()
+	assert.Nil(t, err)
+	assert.Equal(t, "Mtub", nested[:4])
+	restored, err := NewHDWalletFromAccountExtendedPublicKey(nested)
+	assert.Nil(t, err)
+	assert.Equal(t, BaseCoinBip49Litecoin.Purpose, restored.BaseCoin.Purpose)
+	assert.Equal(t, litecoin, restored.BaseCoin.Coin)
+}
+
+func TestLitecoinImportPrivateKey(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x01}, 32))
+	wif, err := btcutil.NewWIF(key, litecoinNetwork.chainParams, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "T", wif.String()[:1])
+
+	imported, err := NewHDWalletFromWords(w, BaseCoinBip84Litecoin).ImportPrivateKey(wif.String())
+	assert.Nil(t, err)
+	addrs := strings.Split(imported.PossibleAddresses, " ")
+	assert.Equal(t, 3, len(addrs))
+	assert.Equal(t, "L", addrs[0][:1])
+	assert.Equal(t, "M", addrs[1][:1])
+	assert.Equal(t, "ltc1q", addrs[2][:5])
+
+	_, err = NewHDWalletFromWords(w, BaseCoinBip84MainNet).ImportPrivateKey(wif.String())
+	assert.Equal(t, ErrPrivateKeyNetwork, err)
+}
+
+func TestLitecoinTransaction(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84Litecoin)
+	utxoAddress, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	payment, err := NewHDWalletFromWords(w, BaseCoinBip49Litecoin).ReceiveAddressForIndex(1)
+	assert.Nil(t, err)
+	script, err := AddressScript(utxoAddress.Address)
+	assert.Nil(t, err)
+
+	changePath := NewDerivationPath(BaseCoinBip84Litecoin, 1, 0)
+	data := NewTransactionDataStandard(payment.Address, BaseCoinBip84Litecoin, 50000, 10, changePath, 2000000, NewRBFOption(AllowedToBeRBF))
+	utxo := NewUTXO("4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b", 0, 100000, utxoAddress.DerivationPath, nil, true)
+	utxo.SetPrevout(script, 100000)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	meta, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, "ltc1q", meta.TransactionChangeMetadata.Address[:5])
+	assert.Equal(t, 1420, data.TransactionData.FeeAmount)
+}
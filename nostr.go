@@ -0,0 +1,307 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+const (
+	nostrPurpose     = 44
+	nostrCoin        = 1237 // NIP-06
+	nostrPubkeyHRP   = "npub"
+	nostrPrivkeyHRP  = "nsec"
+	nostrEventIDSize = sha256.Size
+)
+
+/// Type Definitions
+
+// NostrEvent is a NIP-01 event. Create one with `NewNostrEvent`, add tags, then sign it with `SignNostrEvent`, which
+// sets ID, PublicKey and Signature.
+type NostrEvent struct {
+	ID        string // hex-encoded sha256 of the serialized event
+	PublicKey string // hex-encoded x-only public key
+	CreatedAt int64  // seconds since unix epoch
+	Kind      int
+	tags      [][]string
+	Content   string
+	Signature string // hex-encoded BIP340 signature of ID
+}
+
+// nostrEventJSON is the wire form of a NostrEvent.
+type nostrEventJSON struct {
+	ID        string     `json:"id"`
+	PublicKey string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Signature string     `json:"sig"`
+}
+
+/// Constructors
+
+// NewNostrEvent instantiates an unsigned event.
+func NewNostrEvent(kind int, content string, createdAt int64) *NostrEvent {
+	return &NostrEvent{Kind: kind, Content: content, CreatedAt: createdAt, tags: [][]string{}}
+}
+
+/// Receiver functions
+
+// NostrPublicKey returns the hex-encoded x-only public key of the wallet's Nostr key, derived at m/44'/1237'/0'/0/0
+// per NIP-06. Returns error if the wallet is watch-only.
+func (wallet *HDWallet) NostrPublicKey() (string, error) {
+	key, err := wallet.nostrPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(padTo32(key.PubKey().X.Bytes())), nil
+}
+
+// NostrNpub returns the wallet's Nostr public key encoded as a NIP-19 npub.
+func (wallet *HDWallet) NostrNpub() (string, error) {
+	pubkey, err := wallet.NostrPublicKey()
+	if err != nil {
+		return "", err
+	}
+	return NpubFromPublicKey(pubkey)
+}
+
+// NostrNsec returns the wallet's Nostr private key encoded as a NIP-19 nsec, for importing into other Nostr clients.
+func (wallet *HDWallet) NostrNsec() (string, error) {
+	key, err := wallet.nostrPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	return encodeNostrBech32(nostrPrivkeyHRP, padTo32(key.D.Bytes()))
+}
+
+// SignNostrEvent sets the event's PublicKey and ID, and signs it with the wallet's Nostr key.
+func (wallet *HDWallet) SignNostrEvent(event *NostrEvent) error {
+	if event == nil {
+		return errors.New("event cannot be nil")
+	}
+
+	key, err := wallet.nostrPrivateKey()
+	if err != nil {
+		return err
+	}
+	pubkey := padTo32(key.PubKey().X.Bytes())
+	id := event.id(pubkey)
+	aux, err := randBytes(32)
+	if err != nil {
+		return err
+	}
+	signature, err := schnorrSign(key, id, aux)
+	if err != nil {
+		return err
+	}
+
+	event.PublicKey = hex.EncodeToString(pubkey)
+	event.ID = hex.EncodeToString(id)
+	event.Signature = hex.EncodeToString(signature)
+	return nil
+}
+
+// AddTag appends a tag with a name and single value, such as "p" and a public key.
+func (e *NostrEvent) AddTag(name string, value string) {
+	e.tags = append(e.tags, []string{name, value})
+}
+
+// AppendTagValue appends a further value, such as a relay URL, to the tag at a given index.
+func (e *NostrEvent) AppendTagValue(tagIndex int, value string) error {
+	if tagIndex < 0 || tagIndex >= len(e.tags) {
+		return errors.New("index must be within range of tags")
+	}
+	e.tags[tagIndex] = append(e.tags[tagIndex], value)
+	return nil
+}
+
+// TagCount returns the number of tags on the event.
+func (e *NostrEvent) TagCount() int {
+	return len(e.tags)
+}
+
+// Verify returns error unless the ID matches the event's contents and the signature is valid for the public key.
+func (e *NostrEvent) Verify() error {
+	pubkey, err := hex.DecodeString(e.PublicKey)
+	if err != nil || len(pubkey) != schnorrPubKeySize {
+		return errors.New("public key must be 32 bytes")
+	}
+	id := e.id(pubkey)
+	if hex.EncodeToString(id) != e.ID {
+		return errors.New("event id does not match contents")
+	}
+	signature, err := hex.DecodeString(e.Signature)
+	if err != nil {
+		return err
+	}
+	return schnorrVerify(pubkey, id, signature)
+}
+
+// Encode returns the event as a JSON string, suitable for publishing to a relay and passing to `DecodeNostrEvent`.
+func (e *NostrEvent) Encode() (string, error) {
+	encoded, err := json.Marshal(nostrEventJSON{
+		ID:        e.ID,
+		PublicKey: e.PublicKey,
+		CreatedAt: e.CreatedAt,
+		Kind:      e.Kind,
+		Tags:      e.tags,
+		Content:   e.Content,
+		Signature: e.Signature,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+/// Exported functions
+
+// DecodeNostrEvent parses and verifies an event from a JSON string.
+func DecodeNostrEvent(encoded string) (*NostrEvent, error) {
+	var decoded nostrEventJSON
+	if err := json.Unmarshal([]byte(encoded), &decoded); err != nil {
+		return nil, err
+	}
+
+	event := &NostrEvent{
+		ID:        decoded.ID,
+		PublicKey: decoded.PublicKey,
+		CreatedAt: decoded.CreatedAt,
+		Kind:      decoded.Kind,
+		tags:      decoded.Tags,
+		Content:   decoded.Content,
+		Signature: decoded.Signature,
+	}
+	if event.tags == nil {
+		event.tags = [][]string{}
+	}
+	if err := event.Verify(); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// NpubFromPublicKey encodes a hex-encoded x-only public key as a NIP-19 npub.
+func NpubFromPublicKey(publicKey string) (string, error) {
+	pubkey, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return "", err
+	}
+	if len(pubkey) != schnorrPubKeySize {
+		return "", errors.New("public key must be 32 bytes")
+	}
+	return encodeNostrBech32(nostrPubkeyHRP, pubkey)
+}
+
+// PublicKeyFromNpub decodes a NIP-19 npub to a hex-encoded x-only public key.
+func PublicKeyFromNpub(npub string) (string, error) {
+	hrp, data, err := bech32.Decode(npub)
+	if err != nil {
+		return "", err
+	}
+	if hrp != nostrPubkeyHRP {
+		return "", errors.New("not an npub")
+	}
+	pubkey, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return "", err
+	}
+	if len(pubkey) != schnorrPubKeySize {
+		return "", errors.New("public key must be 32 bytes")
+	}
+	if _, _, err := liftX(pubkey); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(pubkey), nil
+}
+
+/// Unexported functions
+
+func (wallet *HDWallet) nostrPrivateKey() (*btcec.PrivateKey, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	path := NewDerivationPath(NewBaseCoin(nostrPurpose, nostrCoin, 0), 0, 0)
+	kf := wallet.keyFactory()
+	key, err := kf.indexPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.ECPrivKey()
+}
+
+// id returns the sha256 of the event serialized per NIP-01: [0,pubkey,created_at,kind,tags,content].
+func (e *NostrEvent) id(pubkey []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`[0,"`)
+	buf.WriteString(hex.EncodeToString(pubkey))
+	buf.WriteString(`",`)
+	buf.WriteString(strconv.FormatInt(e.CreatedAt, 10))
+	buf.WriteByte(',')
+	buf.WriteString(strconv.Itoa(e.Kind))
+	buf.WriteString(",[")
+	for i, tag := range e.tags {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('[')
+		for j, value := range tag {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			writeNostrString(&buf, value)
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteString("],")
+	writeNostrString(&buf, e.Content)
+	buf.WriteByte(']')
+
+	id := sha256.Sum256(buf.Bytes())
+	return id[:nostrEventIDSize]
+}
+
+// writeNostrString writes a JSON string escaped as NIP-01 requires, which differs from encoding/json: only these
+// characters are escaped, and everything else, including HTML characters and other control characters, is written
+// as is.
+func writeNostrString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+func encodeNostrBech32(hrp string, data []byte) (string, error) {
+	converted, err := bech32.ConvertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32.Encode(hrp, converted)
+}
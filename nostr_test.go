@@ -0,0 +1,69 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// NIP-06 test vector
+const nostrTestWords = "leader monkey parrot ring guide accident before fence cannon height naive bean"
+
+func TestHDWallet_NostrKeys_MatchNIP06Vector(t *testing.T) {
+	wallet := NewHDWalletFromWords(nostrTestWords, BaseCoinBip84MainNet)
+
+	pubkey, err := wallet.NostrPublicKey()
+	assert.Nil(t, err)
+	assert.Equal(t, "17162c921dc4d2518f9a101db33695df1afb56ab82f5ff3e5da6eec3ca5cd917", pubkey)
+
+	npub, err := wallet.NostrNpub()
+	assert.Nil(t, err)
+	assert.Equal(t, "npub1zutzeysacnf9rru6zqwmxd54mud0k44tst6l70ja5mhv8jjumytsd2x7nu", npub)
+
+	nsec, err := wallet.NostrNsec()
+	assert.Nil(t, err)
+	assert.Equal(t, "nsec10allq0gjx7fddtzef0ax00mdps9t2kmtrldkyjfs8l5xruwvh2dq0lhhkp", nsec)
+
+	decoded, err := PublicKeyFromNpub(npub)
+	assert.Nil(t, err)
+	assert.Equal(t, pubkey, decoded)
+}
+
+func TestHDWallet_SignNostrEvent_RoundTrips(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+
+	event := NewNostrEvent(1, "gm <& \"nostr\"\n", 1700000000)
+	event.AddTag("p", "17162c921dc4d2518f9a101db33695df1afb56ab82f5ff3e5da6eec3ca5cd917")
+	assert.Nil(t, event.AppendTagValue(0, "wss://relay.example.com"))
+	assert.EqualError(t, event.AppendTagValue(1, "x"), "index must be within range of tags")
+	assert.Nil(t, wallet.SignNostrEvent(event))
+
+	pubkey, err := wallet.NostrPublicKey()
+	assert.Nil(t, err)
+	assert.Equal(t, pubkey, event.PublicKey)
+	assert.Nil(t, event.Verify())
+
+	encoded, err := event.Encode()
+	assert.Nil(t, err)
+	decoded, err := DecodeNostrEvent(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, event, decoded)
+
+	decoded.Content = "gn"
+	assert.EqualError(t, decoded.Verify(), "event id does not match contents")
+}
+
+func TestNostrEvent_ID_MatchesNIP01Serialization(t *testing.T) {
+	// id of [0,"79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",1,1,[],"a\"b<c"]
+	event := NewNostrEvent(1, "a\"b<c", 1)
+	pubkey, _ := hex.DecodeString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	assert.Equal(t, "4e961d0de6e2cda0756be7626a61afaa9251e9e5de770279a601d05ecb1b7fcb", hex.EncodeToString(event.id(pubkey)))
+}
+
+func TestHDWallet_NostrKeys_WatchOnly_ReturnsError(t *testing.T) {
+	wallet, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	_, err = wallet.NostrNpub()
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
@@ -0,0 +1,542 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+// output descriptor script types a utxo may be spent from
+const (
+	descriptorTypeWPKH = iota
+	descriptorTypeSHWPKH
+	descriptorTypeWSHMulti
+	descriptorTypeTR
+)
+
+const (
+	descriptorInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	maxWitnessMultisigKeys    = 20
+)
+
+/// Type Definitions
+
+// outputDescriptor is a parsed BIP380 output descriptor for a single output, without wildcards.
+type outputDescriptor struct {
+	scriptType int
+	keys       []*descriptorKey
+	threshold  int // wsh(multi) only
+}
+
+// descriptorKey is a public key in a descriptor, with the master fingerprint and full path from the master key when
+// the descriptor gives its origin.
+type descriptorKey struct {
+	pubkey      *btcec.PublicKey
+	fingerprint []byte
+	path        []uint32
+}
+
+/// Receiver functions
+
+// SetDescriptor describes the utxo's previous output with an output descriptor, such as
+// "wsh(multi(2,[73c5da0a/48'/0'/0'/2']xpub.../0/0,...))", so the wallet can spend outputs outside its own derivation
+// paths. Supports wpkh, sh(wpkh), wsh(multi), wsh(sortedmulti) and key path tr, with hex or extended public keys and
+// an optional checksum. Wildcards must be replaced by the utxo's index. The wallet signs with each key whose origin
+// fingerprint matches its own. Takes precedence over `Path` and `ImportedPrivateKey`.
+func (u *UTXO) SetDescriptor(descriptor string) error {
+	parsed, err := parseOutputDescriptor(descriptor)
+	if err != nil {
+		return err
+	}
+	u.descriptor = parsed
+	return nil
+}
+
+/// Exported functions
+
+// DescriptorChecksum returns the 8 character BIP380 checksum of a descriptor, without any existing checksum.
+func DescriptorChecksum(descriptor string) (string, error) {
+	if i := strings.IndexByte(descriptor, '#'); i >= 0 {
+		descriptor = descriptor[:i]
+	}
+	return descriptorChecksum(descriptor)
+}
+
+/// Unexported functions
+
+func parseOutputDescriptor(descriptor string) (*outputDescriptor, error) {
+	descriptor, err := stripDescriptorChecksum(descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	if inner, ok := unwrapDescriptorFunction(descriptor, "sh"); ok {
+		key, ok := unwrapDescriptorFunction(inner, "wpkh")
+		if !ok {
+			return nil, errors.New("unsupported descriptor")
+		}
+		return newSingleKeyDescriptor(descriptorTypeSHWPKH, key)
+	}
+	if key, ok := unwrapDescriptorFunction(descriptor, "wpkh"); ok {
+		return newSingleKeyDescriptor(descriptorTypeWPKH, key)
+	}
+	if key, ok := unwrapDescriptorFunction(descriptor, "tr"); ok {
+		if strings.Contains(key, ",") {
+			return nil, errors.New("taproot script paths are not supported")
+		}
+		return newSingleKeyDescriptor(descriptorTypeTR, key)
+	}
+	if inner, ok := unwrapDescriptorFunction(descriptor, "wsh"); ok {
+		if args, ok := unwrapDescriptorFunction(inner, "multi"); ok {
+			return newMultisigDescriptor(args, false)
+		}
+		if args, ok := unwrapDescriptorFunction(inner, "sortedmulti"); ok {
+			return newMultisigDescriptor(args, true)
+		}
+	}
+	return nil, errors.New("unsupported descriptor")
+}
+
+// stripDescriptorChecksum returns the descriptor without its checksum, or error if the checksum is present but invalid.
+func stripDescriptorChecksum(descriptor string) (string, error) {
+	descriptor = strings.TrimSpace(descriptor)
+	i := strings.IndexByte(descriptor, '#')
+	if i < 0 {
+		return descriptor, nil
+	}
+	expected, err := descriptorChecksum(descriptor[:i])
+	if err != nil {
+		return "", err
+	}
+	if descriptor[i+1:] != expected {
+		return "", errors.New("invalid descriptor checksum")
+	}
+	return descriptor[:i], nil
+}
+
+func newSingleKeyDescriptor(scriptType int, keyExpression string) (*outputDescriptor, error) {
+	key, err := parseDescriptorKey(keyExpression, scriptType == descriptorTypeTR)
+	if err != nil {
+		return nil, err
+	}
+	return &outputDescriptor{scriptType: scriptType, keys: []*descriptorKey{key}}, nil
+}
+
+func newMultisigDescriptor(args string, sorted bool) (*outputDescriptor, error) {
+	parts := strings.Split(args, ",")
+	if len(parts) < 2 || len(parts)-1 > maxWitnessMultisigKeys {
+		return nil, errors.New("multisig must have between 1 and 20 keys")
+	}
+	threshold, err := strconv.Atoi(parts[0])
+	if err != nil || threshold < 1 || threshold > len(parts)-1 {
+		return nil, errors.New("invalid multisig threshold")
+	}
+
+	keys := make([]*descriptorKey, 0, len(parts)-1)
+	for _, expression := range parts[1:] {
+		key, err := parseDescriptorKey(expression, false)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if sorted {
+		sort.SliceStable(keys, func(i, j int) bool {
+			return bytes.Compare(keys[i].pubkey.SerializeCompressed(), keys[j].pubkey.SerializeCompressed()) < 0
+		})
+	}
+	return &outputDescriptor{scriptType: descriptorTypeWSHMulti, keys: keys, threshold: threshold}, nil
+}
+
+// parseDescriptorKey parses "[fingerprint/origin/path]KEY/child/path", where KEY is a hex public key, x-only if
+// allowed, or an extended public key.
+func parseDescriptorKey(expression string, allowXOnly bool) (*descriptorKey, error) {
+	key := &descriptorKey{}
+	if strings.HasPrefix(expression, "[") {
+		end := strings.IndexByte(expression, ']')
+		if end < 0 {
+			return nil, errors.New("invalid key origin")
+		}
+		origin := strings.Split(expression[1:end], "/")
+		fingerprint, err := hex.DecodeString(origin[0])
+		if err != nil || len(fingerprint) != 4 {
+			return nil, errors.New("invalid key origin fingerprint")
+		}
+		key.fingerprint = fingerprint
+		for _, step := range origin[1:] {
+			index, err := parseDescriptorPathStep(step, true)
+			if err != nil {
+				return nil, err
+			}
+			key.path = append(key.path, index)
+		}
+		expression = expression[end+1:]
+	}
+
+	components := strings.Split(expression, "/")
+	switch {
+	case len(components[0]) == 64 && allowXOnly && len(components) == 1:
+		xOnly, err := hex.DecodeString(components[0])
+		if err != nil {
+			return nil, err
+		}
+		x, y, err := liftX(xOnly)
+		if err != nil {
+			return nil, err
+		}
+		key.pubkey = &btcec.PublicKey{Curve: btcec.S256(), X: x, Y: y}
+	case len(components[0]) == 66 && len(components) == 1:
+		pubkey, err := parseHexPubKey(components[0])
+		if err != nil {
+			return nil, err
+		}
+		key.pubkey = pubkey
+	default:
+		extended, err := hdkeychain.NewKeyFromString(components[0])
+		if err != nil {
+			return nil, errors.New("invalid descriptor key")
+		}
+		if extended.IsPrivate() {
+			return nil, errors.New("descriptor must contain only public keys")
+		}
+		for _, step := range components[1:] {
+			if step == "*" {
+				return nil, errors.New("descriptor must not contain wildcards")
+			}
+			index, err := parseDescriptorPathStep(step, false)
+			if err != nil {
+				return nil, err
+			}
+			if extended, err = extended.Child(index); err != nil {
+				return nil, err
+			}
+			key.path = append(key.path, index)
+		}
+		if key.pubkey, err = extended.ECPubKey(); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+func parseDescriptorPathStep(step string, allowHardened bool) (uint32, error) {
+	hardenedStep := strings.HasSuffix(step, "'") || strings.HasSuffix(step, "h")
+	if hardenedStep {
+		if !allowHardened {
+			return 0, errors.New("hardened derivation requires a private key")
+		}
+		step = step[:len(step)-1]
+	}
+	index, err := strconv.ParseUint(step, 10, 32)
+	if err != nil || index >= hdkeychain.HardenedKeyStart {
+		return 0, fmt.Errorf("invalid derivation path step %q", step)
+	}
+	if hardenedStep {
+		return uint32(index) + hdkeychain.HardenedKeyStart, nil
+	}
+	return uint32(index), nil
+}
+
+// unwrapDescriptorFunction returns the arguments of "name(...)".
+func unwrapDescriptorFunction(expression string, name string) (string, bool) {
+	if !strings.HasPrefix(expression, name+"(") || !strings.HasSuffix(expression, ")") {
+		return "", false
+	}
+	return expression[len(name)+1 : len(expression)-1], true
+}
+
+// descriptorChecksum implements the BIP380 checksum.
+func descriptorChecksum(descriptor string) (string, error) {
+	c := uint64(1)
+	cls, clsCount := 0, 0
+	for _, ch := range descriptor {
+		pos := strings.IndexRune(descriptorInputCharset, ch)
+		if pos < 0 {
+			return "", errors.New("invalid descriptor character")
+		}
+		c = descriptorPolymod(c, uint64(pos&31))
+		cls = cls*3 + pos>>5
+		if clsCount++; clsCount == 3 {
+			c = descriptorPolymod(c, uint64(cls))
+			cls, clsCount = 0, 0
+		}
+	}
+	if clsCount > 0 {
+		c = descriptorPolymod(c, uint64(cls))
+	}
+	for i := 0; i < 8; i++ {
+		c = descriptorPolymod(c, 0)
+	}
+	c ^= 1
+
+	checksum := make([]byte, 8)
+	for i := range checksum {
+		checksum[i] = descriptorChecksumCharset[(c>>(5*(7-uint(i))))&31]
+	}
+	return string(checksum), nil
+}
+
+func descriptorPolymod(c uint64, value uint64) uint64 {
+	c0 := c >> 35
+	c = ((c & 0x7ffffffff) << 5) ^ value
+	generators := []uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+	for i, generator := range generators {
+		if c0>>uint(i)&1 == 1 {
+			c ^= generator
+		}
+	}
+	return c
+}
+
+// pkScript returns the script of outputs described by the descriptor.
+func (d *outputDescriptor) pkScript() ([]byte, error) {
+	switch d.scriptType {
+	case descriptorTypeWPKH:
+		return P2WPKHScript(btcutil.Hash160(d.keys[0].pubkey.SerializeCompressed()))
+	case descriptorTypeSHWPKH:
+		redeemScript, err := d.redeemScript()
+		if err != nil {
+			return nil, err
+		}
+		return P2SHScript(btcutil.Hash160(redeemScript))
+	case descriptorTypeWSHMulti:
+		witnessScript, err := d.witnessScript()
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.Sum256(witnessScript)
+		return P2WSHScript(hash[:])
+	case descriptorTypeTR:
+		return P2TRScript(taprootOutputKey(d.keys[0].pubkey))
+	}
+	return nil, errors.New("unsupported descriptor")
+}
+
+// redeemScript returns the P2SH redeem script of sh(wpkh) descriptors.
+func (d *outputDescriptor) redeemScript() ([]byte, error) {
+	return P2WPKHScript(btcutil.Hash160(d.keys[0].pubkey.SerializeCompressed()))
+}
+
+// witnessScript returns the P2WSH witness script of wsh(multi) descriptors.
+func (d *outputDescriptor) witnessScript() ([]byte, error) {
+	builder := txscript.NewScriptBuilder().AddInt64(int64(d.threshold))
+	for _, key := range d.keys {
+		builder.AddData(key.pubkey.SerializeCompressed())
+	}
+	return builder.AddInt64(int64(len(d.keys))).AddOp(txscript.OP_CHECKMULTISIG).Script()
+}
+
+// inputBytes returns the virtual size of an input spending the descriptor, with signatures of maximum size.
+func (d *outputDescriptor) inputBytes() (int, error) {
+	switch d.scriptType {
+	case descriptorTypeWPKH:
+		return p2wpkhSegwitInputSize, nil
+	case descriptorTypeSHWPKH:
+		return p2shSegwitInputSize, nil
+	case descriptorTypeTR:
+		return p2trKeyPathInputSize, nil
+	case descriptorTypeWSHMulti:
+		return multisigInputBytes(d.threshold, len(d.keys), false), nil
+	}
+	return 0, errors.New("unsupported descriptor")
+}
+
+// descriptorPrivateKeys returns the wallet's private keys for the descriptor's keys, by index, for each key whose origin
+// fingerprint matches the wallet and whose derived public key matches the descriptor.
+func (wallet *HDWallet) descriptorPrivateKeys(d *outputDescriptor) (map[int]*btcec.PrivateKey, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+	fingerprint, err := wallet.masterFingerprintBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[int]*btcec.PrivateKey{}
+	for i, key := range d.keys {
+		if !bytes.Equal(key.fingerprint, fingerprint) {
+			continue
+		}
+		if err := requireKeyRole(&DerivationPath{components: key.path}, KeyRoleSpending); err != nil {
+			return nil, err
+		}
+		extended := wallet.masterPrivateKey
+		for _, index := range key.path {
+			if extended, err = extended.Child(index); err != nil {
+				return nil, err
+			}
+		}
+		priv, err := extended.ECPrivKey()
+		if err != nil {
+			return nil, err
+		}
+		if d.scriptType == descriptorTypeTR {
+			if priv.PubKey().X.Cmp(key.pubkey.X) != 0 {
+				return nil, fmt.Errorf("descriptor key %d does not match its origin", i)
+			}
+		} else if !priv.PubKey().IsEqual(key.pubkey) {
+			return nil, fmt.Errorf("descriptor key %d does not match its origin", i)
+		}
+		keys[i] = priv
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("wallet holds no keys for descriptor")
+	}
+	return keys, nil
+}
+
+// signDescriptorInput signs an input spending the descriptor with the wallet's keys, given the previous outputs of
+// every input.
+func (wallet *HDWallet) signDescriptorInput(tx *wire.MsgTx, idx int, prevouts []*wire.TxOut, d *outputDescriptor) error {
+	keys, err := wallet.descriptorPrivateKeys(d)
+	if err != nil {
+		return err
+	}
+	prevout := prevouts[idx]
+	txIn := tx.TxIn[idx]
+
+	switch d.scriptType {
+	case descriptorTypeWPKH, descriptorTypeSHWPKH:
+		return signInputWithHashType(tx, idx, prevout.PkScript, prevout.Value, txscript.SigHashAll, keys[0])
+	case descriptorTypeWSHMulti:
+		if len(keys) < d.threshold {
+			return fmt.Errorf("wallet holds %d of %d keys required by descriptor", len(keys), d.threshold)
+		}
+		witnessScript, err := d.witnessScript()
+		if err != nil {
+			return err
+		}
+		sigHashes := txscript.NewTxSigHashes(tx)
+
+		// CHECKMULTISIG pops an extra item, and expects signatures in the order of their keys
+		witness := wire.TxWitness{nil}
+		for i := range d.keys {
+			priv, ok := keys[i]
+			if !ok {
+				continue
+			}
+			sig, err := txscript.RawTxInWitnessSignature(tx, sigHashes, idx, prevout.Value, witnessScript, txscript.SigHashAll, priv)
+			if err != nil {
+				return err
+			}
+			witness = append(witness, sig)
+			if len(witness) == d.threshold+1 {
+				break
+			}
+		}
+		txIn.Witness = append(witness, witnessScript)
+		txIn.SignatureScript = nil
+		return nil
+	case descriptorTypeTR:
+		aux, err := wallet.schnorrAuxRandomness()
+		if err != nil {
+			return err
+		}
+		return signTaprootKeyPathInput(tx, idx, prevouts, keys[0], aux)
+	}
+	return errors.New("unsupported descriptor")
+}
+
+// addPSBTInput adds the witness utxo of a PSBT input spending the descriptor, its redeem or witness script, and the
+// BIP32 derivation of each key with an origin, so each wallet holding a key can sign with `SignPSBT`.
+func (d *outputDescriptor) addPSBTInput(updater *psbt.Updater, index int, utxo *UTXO) error {
+	pkScript, err := d.pkScript()
+	if err != nil {
+		return err
+	}
+	if err := utxo.verifyPrevout(pkScript); err != nil {
+		return err
+	}
+	if err := updater.AddInWitnessUtxo(wire.NewTxOut(utxo.Amount, pkScript), index); err != nil {
+		return err
+	}
+
+	switch d.scriptType {
+	case descriptorTypeSHWPKH:
+		redeemScript, err := d.redeemScript()
+		if err != nil {
+			return err
+		}
+		if err := updater.AddInRedeemScript(redeemScript, index); err != nil {
+			return err
+		}
+	case descriptorTypeWSHMulti:
+		witnessScript, err := d.witnessScript()
+		if err != nil {
+			return err
+		}
+		if err := updater.AddInWitnessScript(witnessScript, index); err != nil {
+			return err
+		}
+	case descriptorTypeTR:
+		key := d.keys[0]
+		var fingerprint uint32
+		if key.fingerprint != nil {
+			fingerprint = binary.LittleEndian.Uint32(key.fingerprint)
+		}
+		addPSBTTaprootInput(updater, index, key.pubkey, fingerprint, key.fingerprint != nil, key.path)
+		return nil
+	}
+
+	for _, key := range d.keys {
+		if key.fingerprint == nil {
+			continue
+		}
+		fingerprint := binary.LittleEndian.Uint32(key.fingerprint)
+		if err := updater.AddInBip32Derivation(fingerprint, key.path, key.pubkey.SerializeCompressed(), index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dummyInputScripts returns placeholder scripts the size of a signed input spending the descriptor, for fee estimation.
+func (d *outputDescriptor) dummyInputScripts() ([]byte, wire.TxWitness, error) {
+	dummySig := make([]byte, dummySignatureSize)
+	switch d.scriptType {
+	case descriptorTypeWPKH:
+		return nil, wire.TxWitness{dummySig, d.keys[0].pubkey.SerializeCompressed()}, nil
+	case descriptorTypeSHWPKH:
+		redeemScript, err := d.redeemScript()
+		if err != nil {
+			return nil, nil, err
+		}
+		sigScript, err := txscript.NewScriptBuilder().AddData(redeemScript).Script()
+		if err != nil {
+			return nil, nil, err
+		}
+		return sigScript, wire.TxWitness{dummySig, d.keys[0].pubkey.SerializeCompressed()}, nil
+	case descriptorTypeWSHMulti:
+		witnessScript, err := d.witnessScript()
+		if err != nil {
+			return nil, nil, err
+		}
+		witness := wire.TxWitness{nil}
+		for i := 0; i < d.threshold; i++ {
+			witness = append(witness, dummySig)
+		}
+		return nil, append(witness, witnessScript), nil
+	case descriptorTypeTR:
+		return nil, wire.TxWitness{make([]byte, 64)}, nil
+	}
+	return nil, nil, errors.New("unsupported descriptor")
+}
+
+// isTaprootScript returns true for segwit v1 output scripts paying to a 32-byte key.
+func isTaprootScript(script []byte) bool {
+	return len(script) == 34 && script[0] == txscript.OP_1 && script[1] == txscript.OP_DATA_32
+}
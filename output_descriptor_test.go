@@ -0,0 +1,178 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+const descriptorTestTxid = "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69"
+
+// testDescriptorKey returns a descriptor key expression with origin for the wallet's key at a path.
+func testDescriptorKey(t *testing.T, wallet *HDWallet, xOnly bool, path ...uint32) string {
+	extended := wallet.masterPrivateKey
+	fingerprint, err := wallet.masterFingerprintBytes()
+	assert.Nil(t, err)
+	origin := hex.EncodeToString(fingerprint)
+	for _, index := range path {
+		extended, err = extended.Child(index)
+		assert.Nil(t, err)
+		if index >= hardened(0) {
+			origin += fmt.Sprintf("/%dh", index-hardened(0))
+		} else {
+			origin += fmt.Sprintf("/%d", index)
+		}
+	}
+	pubkey, err := extended.ECPubKey()
+	assert.Nil(t, err)
+	serialized := pubkey.SerializeCompressed()
+	if xOnly {
+		serialized = serialized[1:]
+	}
+	return fmt.Sprintf("[%s]%s", origin, hex.EncodeToString(serialized))
+}
+
+// setDescriptorPrevout sets the utxo's previous output to the script of its descriptor.
+func setDescriptorPrevout(t *testing.T, utxo *UTXO) {
+	pkScript, err := utxo.descriptor.pkScript()
+	assert.Nil(t, err)
+	utxo.SetPrevout(pkScript, utxo.Amount)
+}
+
+func buildDescriptorTestTx(t *testing.T, wallet *HDWallet, utxos ...*UTXO) *wire.MsgTx {
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataStandard("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 50000, 5, changePath, 590582, NewRBFOption(AllowedToBeRBF))
+	for _, utxo := range utxos {
+		data.AddUTXO(utxo)
+	}
+	assert.Nil(t, data.Generate())
+
+	meta, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	raw, err := hex.DecodeString(meta.EncodedTx)
+	assert.Nil(t, err)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	assert.Nil(t, tx.Deserialize(bytes.NewReader(raw)))
+	return tx
+}
+
+func TestDescriptorChecksum(t *testing.T) {
+	checksum, err := DescriptorChecksum("raw(deadbeef)")
+	assert.Nil(t, err)
+	assert.Equal(t, "89f8spxm", checksum)
+
+	utxo := NewUTXO(descriptorTestTxid, 0, 100000, nil, nil, true)
+	assert.Nil(t, utxo.SetDescriptor("wpkh(02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9)#8zl0zxma"))
+	assert.EqualError(t, utxo.SetDescriptor("wpkh(02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9)#8zl0zxmb"), "invalid descriptor checksum")
+}
+
+func TestSetDescriptor_Unsupported_ReturnsError(t *testing.T) {
+	zpub := "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	utxo := NewUTXO(descriptorTestTxid, 0, 100000, nil, nil, true)
+	assert.NotNil(t, utxo.SetDescriptor("pkh(02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9)"))
+	assert.EqualError(t, utxo.SetDescriptor("wpkh("+zpub+"/0/*)"), "descriptor must not contain wildcards")
+	assert.EqualError(t, utxo.SetDescriptor("wpkh([73c5da0a/84h/0h/0h]"+zpub+"/0h/0)"), "hardened derivation requires a private key")
+}
+
+func TestDescriptor_TaprootOutputKey_MatchesBIP86Vector(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	key := testDescriptorKey(t, wallet, true, hardened(86), hardened(0), hardened(0), 0, 0)
+	assert.Equal(t, "[73c5da0a/86h/0h/0h/0/0]cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115", key)
+
+	descriptor, err := parseOutputDescriptor("tr(" + key + ")")
+	assert.Nil(t, err)
+	pkScript, err := descriptor.pkScript()
+	assert.Nil(t, err)
+	assert.Equal(t, "5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c", hex.EncodeToString(pkScript))
+}
+
+func TestBuildTransaction_WPKHDescriptor_SignsInput(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	key := testDescriptorKey(t, wallet, false, hardened(84), hardened(0), hardened(0), 0, 1)
+	utxo := NewUTXO(descriptorTestTxid, 0, 96537, nil, nil, true)
+	assert.Nil(t, utxo.SetDescriptor("wpkh("+key+")"))
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+
+	tx := buildDescriptorTestTx(t, wallet, utxo)
+	assert.Equal(t, 2, len(tx.TxIn[0].Witness))
+}
+
+func TestBuildTransaction_WSHMultiDescriptor_SignsWithHeldKeys(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	first := testDescriptorKey(t, wallet, false, hardened(48), hardened(0), hardened(0), hardened(2), 0, 0)
+	second := testDescriptorKey(t, wallet, false, hardened(48), hardened(0), hardened(0), hardened(2), 0, 1)
+	foreign := "[deadbeef/48h/0h/0h/2h/0/0]02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9"
+
+	utxo := NewUTXO(descriptorTestTxid, 0, 100000, nil, nil, true)
+	assert.Nil(t, utxo.SetDescriptor(fmt.Sprintf("wsh(multi(2,%s,%s,%s))", first, foreign, second)))
+	setDescriptorPrevout(t, utxo)
+	tx := buildDescriptorTestTx(t, wallet, utxo)
+
+	// dummy, two signatures and the witness script, which the script engine verified
+	witness := tx.TxIn[0].Witness
+	assert.Equal(t, 4, len(witness))
+	assert.Equal(t, 0, len(witness[0]))
+	script, err := utxo.descriptor.witnessScript()
+	assert.Nil(t, err)
+	assert.Equal(t, script, []byte(witness[3]))
+
+	// with only one key held by the wallet, the threshold cannot be met
+	lone := NewUTXO(descriptorTestTxid, 0, 100000, nil, nil, true)
+	assert.Nil(t, lone.SetDescriptor(fmt.Sprintf("wsh(multi(2,%s,%s))", first, foreign)))
+	setDescriptorPrevout(t, lone)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataStandard("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 50000, 5, changePath, 590582, NewRBFOption(AllowedToBeRBF))
+	data.AddUTXO(lone)
+	assert.Nil(t, data.Generate())
+	_, err = wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.EqualError(t, err, "input 0: wallet holds 1 of 2 keys required by descriptor")
+}
+
+func TestBuildTransaction_TaprootDescriptor_SignsKeyPathAlongsideWalletInputs(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	key := testDescriptorKey(t, wallet, true, hardened(86), hardened(0), hardened(0), 0, 0)
+	taproot := NewUTXO(descriptorTestTxid, 1, 40000, nil, nil, true)
+	assert.Nil(t, taproot.SetDescriptor("tr("+key+")"))
+	setDescriptorPrevout(t, taproot)
+	standard := NewUTXO(descriptorTestTxid, 0, 30000, NewDerivationPath(BaseCoinBip84MainNet, 0, 1), nil, true)
+	standard.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 30000)
+
+	tx := buildDescriptorTestTx(t, wallet, taproot, standard)
+	assert.Equal(t, 2, len(tx.TxIn))
+
+	prevScript, err := taproot.descriptor.pkScript()
+	assert.Nil(t, err)
+	standardScript, err := hex.DecodeString("00149c90f934ea51fa0f6504177043e0908da6929983")
+	assert.Nil(t, err)
+	prevouts := []*wire.TxOut{wire.NewTxOut(40000, prevScript), wire.NewTxOut(30000, standardScript)}
+	sigHash, err := taprootKeyPathSigHash(tx, 0, prevouts)
+	assert.Nil(t, err)
+
+	witness := tx.TxIn[0].Witness
+	assert.Equal(t, 1, len(witness))
+	assert.Nil(t, schnorrVerify(prevScript[2:], sigHash, witness[0]))
+
+	// the wallet input is still signed and verified by the script engine
+	vm, err := txscript.NewEngine(standardScript, tx, 1, txscript.StandardVerifyFlags, nil, nil, 30000)
+	assert.Nil(t, err)
+	assert.Nil(t, vm.Execute())
+}
+
+func TestBuildTransaction_DescriptorForAnotherWallet_ReturnsError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	utxo := NewUTXO(descriptorTestTxid, 0, 100000, nil, nil, true)
+	assert.Nil(t, utxo.SetDescriptor("wpkh([deadbeef/84h/0h/0h/0/0]02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9)"))
+	setDescriptorPrevout(t, utxo)
+
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataStandard("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 50000, 5, changePath, 590582, NewRBFOption(AllowedToBeRBF))
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+	_, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.EqualError(t, err, "input 0: wallet holds no keys for descriptor")
+}
@@ -0,0 +1,77 @@
+package cnlib
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// maxOpReturnDataSize is the largest OP_RETURN payload relayed by default by Bitcoin Core.
+const maxOpReturnDataSize = 80
+
+/// Exported functions
+
+// P2PKHScript returns the output script paying to a 20-byte public key hash.
+func P2PKHScript(pubkeyHash []byte) ([]byte, error) {
+	if len(pubkeyHash) != 20 {
+		return nil, errors.New("public key hash must be 20 bytes")
+	}
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_DUP).
+		AddOp(txscript.OP_HASH160).
+		AddData(pubkeyHash).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+}
+
+// P2SHScript returns the output script paying to a 20-byte redeem script hash.
+func P2SHScript(scriptHash []byte) ([]byte, error) {
+	if len(scriptHash) != 20 {
+		return nil, errors.New("script hash must be 20 bytes")
+	}
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_HASH160).
+		AddData(scriptHash).
+		AddOp(txscript.OP_EQUAL).
+		Script()
+}
+
+// P2WPKHScript returns the segwit v0 output script paying to a 20-byte public key hash. This is also the redeem
+// script of a P2SH-P2WPKH output.
+func P2WPKHScript(pubkeyHash []byte) ([]byte, error) {
+	if len(pubkeyHash) != 20 {
+		return nil, errors.New("public key hash must be 20 bytes")
+	}
+	return txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(pubkeyHash).Script()
+}
+
+// P2WSHScript returns the segwit v0 output script paying to a 32-byte witness script hash.
+func P2WSHScript(witnessScriptHash []byte) ([]byte, error) {
+	if len(witnessScriptHash) != 32 {
+		return nil, errors.New("witness script hash must be 32 bytes")
+	}
+	return txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(witnessScriptHash).Script()
+}
+
+// P2TRScript returns the segwit v1 output script paying to a 32-byte x-only taproot output key.
+func P2TRScript(outputKey []byte) ([]byte, error) {
+	if len(outputKey) != 32 {
+		return nil, errors.New("taproot output key must be 32 bytes")
+	}
+	return txscript.NewScriptBuilder().AddOp(txscript.OP_1).AddData(outputKey).Script()
+}
+
+// AddressScript returns the output script paying to an address of any supported network, such as the scriptPubKey to
+// pass to `UTXO.SetPrevout`.
+func AddressScript(address string) ([]byte, error) {
+	return pkScriptForAnyNetwork(address)
+}
+
+// OpReturnScript returns a provably unspendable output script carrying up to 80 bytes of data.
+func OpReturnScript(data []byte) ([]byte, error) {
+	if len(data) > maxOpReturnDataSize {
+		return nil, errors.New("OP_RETURN data cannot exceed 80 bytes")
+	}
+	return txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddData(data).Script()
+}
@@ -0,0 +1,69 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputScripts_MatchAddressScripts(t *testing.T) {
+	hash20 := bytes.Repeat([]byte{0xab}, 20)
+	hash32 := bytes.Repeat([]byte{0xcd}, 32)
+	params := BaseCoinBip84MainNet.defaultNetParams()
+
+	p2pkh, err := P2PKHScript(hash20)
+	assert.Nil(t, err)
+	addr, _ := btcutil.NewAddressPubKeyHash(hash20, params)
+	expected, _ := txscript.PayToAddrScript(addr)
+	assert.Equal(t, expected, p2pkh)
+
+	p2sh, err := P2SHScript(hash20)
+	assert.Nil(t, err)
+	shAddr, _ := btcutil.NewAddressScriptHashFromHash(hash20, params)
+	expected, _ = txscript.PayToAddrScript(shAddr)
+	assert.Equal(t, expected, p2sh)
+
+	p2wpkh, err := P2WPKHScript(hash20)
+	assert.Nil(t, err)
+	assert.Equal(t, "0014"+hex.EncodeToString(hash20), hex.EncodeToString(p2wpkh))
+
+	p2wsh, err := P2WSHScript(hash32)
+	assert.Nil(t, err)
+	assert.Equal(t, "0020"+hex.EncodeToString(hash32), hex.EncodeToString(p2wsh))
+
+	p2tr, err := P2TRScript(hash32)
+	assert.Nil(t, err)
+	assert.Equal(t, "5120"+hex.EncodeToString(hash32), hex.EncodeToString(p2tr))
+
+	opReturn, err := OpReturnScript([]byte("hello"))
+	assert.Nil(t, err)
+	assert.Equal(t, "6a0568656c6c6f", hex.EncodeToString(opReturn))
+}
+
+func TestOutputScripts_InvalidLengths_ReturnError(t *testing.T) {
+	_, err := P2PKHScript(make([]byte, 19))
+	assert.NotNil(t, err)
+	_, err = P2WSHScript(make([]byte, 20))
+	assert.NotNil(t, err)
+	_, err = P2TRScript(make([]byte, 33))
+	assert.NotNil(t, err)
+	_, err = OpReturnScript(make([]byte, 81))
+	assert.NotNil(t, err)
+}
+
+func TestAddressScript(t *testing.T) {
+	script, err := AddressScript("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu")
+	assert.Nil(t, err)
+	assert.Equal(t, "0014c0cebcd6c3d3ca8c75dc5ec62ebe55330ef910e2", hex.EncodeToString(script))
+
+	script, err = AddressScript("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0")
+	assert.Nil(t, err)
+	assert.Equal(t, "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", hex.EncodeToString(script))
+
+	_, err = AddressScript("not an address")
+	assert.NotNil(t, err)
+}
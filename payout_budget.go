@@ -0,0 +1,167 @@
+package cnlib
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/blockchain"
+)
+
+// Following errors are returned when an addition would exceed a PayoutBudget's limits. The draft is left unchanged.
+var (
+	ErrPayoutBudgetWeight  = errors.New("addition would exceed the maximum transaction weight")
+	ErrPayoutBudgetFee     = errors.New("addition would exceed the maximum fee")
+	ErrPayoutBudgetOutputs = errors.New("addition would exceed the maximum number of outputs")
+)
+
+/// Type Definitions
+
+// PayoutBudget keeps a running weight and fee total for a draft payout transaction as utxos and outputs are added,
+// rejecting additions that would exceed its limits. The totals always reserve room for a change output. Set MaxFee or
+// MaxOutputs to 0 for no limit.
+type PayoutBudget struct {
+	basecoin     *BaseCoin
+	feeRate      int
+	utxos        []*UTXO
+	outputs      []*BatchRecipient
+	MaxWeight    int // largest draft, in weight units
+	MaxFee       int // largest fee, in satoshis
+	MaxOutputs   int // most recipient outputs, excluding change
+	Vsize        int
+	FeeAmount    int
+	InputAmount  int
+	OutputAmount int
+}
+
+/// Constructors
+
+// NewPayoutBudget returns a pointer to an empty PayoutBudget for the given BaseCoin at feeRate, in satoshis per
+// virtual byte, limited to Bitcoin Core's standard transaction weight.
+func NewPayoutBudget(basecoin *BaseCoin, feeRate int) (*PayoutBudget, error) {
+	if basecoin == nil {
+		return nil, errors.New("no basecoin provided")
+	}
+	if feeRate < 0 {
+		return nil, errors.New("fee rate cannot be negative")
+	}
+	b := &PayoutBudget{
+		basecoin:  basecoin,
+		feeRate:   feeRate,
+		utxos:     []*UTXO{},
+		outputs:   []*BatchRecipient{},
+		MaxWeight: maxStandardTxWeight,
+		Vsize:     baseSize + basecoin.bytesPerChangeOuptut(),
+	}
+	b.FeeAmount = b.Vsize * feeRate
+	return b, nil
+}
+
+/// Receiver functions
+
+// AddUTXO adds a utxo to fund the draft. Returns ErrPayoutBudgetWeight or ErrPayoutBudgetFee if spending it would
+// exceed a limit, or error if the utxo's input size cannot be estimated.
+func (b *PayoutBudget) AddUTXO(utxo *UTXO) error {
+	inputBytes, err := b.basecoin.bytesPerInput(utxo)
+	if err != nil {
+		return err
+	}
+	if err := b.checkLimits(inputBytes, len(b.outputs)); err != nil {
+		return err
+	}
+	b.utxos = append(b.utxos, utxo)
+	b.InputAmount += int(utxo.Amount)
+	b.grow(inputBytes)
+	return nil
+}
+
+// AddOutput appends a payment to the draft. Returns ErrPayoutBudgetWeight, ErrPayoutBudgetFee or
+// ErrPayoutBudgetOutputs if it would exceed a limit, or error if the amount is dust or the address is not supported.
+func (b *PayoutBudget) AddOutput(address string, amount int) error {
+	if amount < dustThreshold {
+		return errors.New("amount is below dust threshold")
+	}
+	outputBytes, err := b.basecoin.bytesPerOutputAddress(address)
+	if err != nil {
+		return err
+	}
+	if err := b.checkLimits(outputBytes, len(b.outputs)+1); err != nil {
+		return err
+	}
+	b.outputs = append(b.outputs, &BatchRecipient{Address: address, Amount: amount})
+	b.OutputAmount += amount
+	b.grow(outputBytes)
+	return nil
+}
+
+// Weight returns the estimated weight of the draft, including a change output.
+func (b *PayoutBudget) Weight() int {
+	return b.Vsize * blockchain.WitnessScaleFactor
+}
+
+// RemainingWeight returns the weight that may still be added before reaching MaxWeight.
+func (b *PayoutBudget) RemainingWeight() int {
+	remaining := b.MaxWeight - b.Weight()
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// ChangeAmount returns the change the draft would return, or 0 if it is unfunded or change would be dust.
+func (b *PayoutBudget) ChangeAmount() int {
+	change := b.InputAmount - b.OutputAmount - b.FeeAmount
+	if change < dustThreshold {
+		return 0
+	}
+	return change
+}
+
+// IsFunded returns true if the utxos added cover the outputs and the fee.
+func (b *PayoutBudget) IsFunded() bool {
+	return b.InputAmount >= b.OutputAmount+b.FeeAmount
+}
+
+// UtxoCount returns the number of utxos added.
+func (b *PayoutBudget) UtxoCount() int {
+	return len(b.utxos)
+}
+
+// UTXOAtIndex returns the utxo at a given index, or error if out of bounds.
+func (b *PayoutBudget) UTXOAtIndex(index int) (*UTXO, error) {
+	if index < 0 || index > len(b.utxos)-1 {
+		return nil, errors.New("index must be within range of utxos")
+	}
+	return b.utxos[index], nil
+}
+
+// OutputCount returns the number of outputs added, excluding change.
+func (b *PayoutBudget) OutputCount() int {
+	return len(b.outputs)
+}
+
+// OutputAtIndex returns the output at a given index, or error if out of bounds.
+func (b *PayoutBudget) OutputAtIndex(index int) (*BatchRecipient, error) {
+	if index < 0 || index > len(b.outputs)-1 {
+		return nil, errors.New("index must be within range of outputs")
+	}
+	return b.outputs[index], nil
+}
+
+/// Unexported functions
+
+func (b *PayoutBudget) checkLimits(addedVsize int, outputCount int) error {
+	if b.MaxOutputs > 0 && outputCount > b.MaxOutputs {
+		return ErrPayoutBudgetOutputs
+	}
+	if (b.Vsize+addedVsize)*blockchain.WitnessScaleFactor > b.MaxWeight {
+		return ErrPayoutBudgetWeight
+	}
+	if b.MaxFee > 0 && (b.Vsize+addedVsize)*b.feeRate > b.MaxFee {
+		return ErrPayoutBudgetFee
+	}
+	return nil
+}
+
+func (b *PayoutBudget) grow(addedVsize int) {
+	b.Vsize += addedVsize
+	b.FeeAmount = b.Vsize * b.feeRate
+}
@@ -0,0 +1,67 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPayoutBudget(t *testing.T, feeRate int) *PayoutBudget {
+	budget, err := NewPayoutBudget(BaseCoinBip84MainNet, feeRate)
+	assert.Nil(t, err)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	assert.Nil(t, budget.AddUTXO(NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", 0, 100000, path, nil, true)))
+	return budget
+}
+
+func TestPayoutBudget_RunningTotals(t *testing.T) {
+	budget := newTestPayoutBudget(t, 5)
+	assert.Nil(t, budget.AddOutput("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", 10000))
+	assert.Nil(t, budget.AddOutput("37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf", 20000))
+
+	expectedVsize := baseSize + p2wpkhSegwitInputSize + p2wpkhOutputSize + p2shOutputSize + p2wpkhOutputSize
+	assert.Equal(t, expectedVsize, budget.Vsize)
+	assert.Equal(t, expectedVsize*4, budget.Weight())
+	assert.Equal(t, expectedVsize*5, budget.FeeAmount)
+	assert.Equal(t, 30000, budget.OutputAmount)
+	assert.Equal(t, 100000-30000-budget.FeeAmount, budget.ChangeAmount())
+	assert.True(t, budget.IsFunded())
+	assert.Equal(t, 2, budget.OutputCount())
+	assert.Equal(t, 1, budget.UtxoCount())
+
+	output, err := budget.OutputAtIndex(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 20000, output.Amount)
+	_, err = budget.OutputAtIndex(2)
+	assert.NotNil(t, err)
+}
+
+func TestPayoutBudget_RejectsAdditionsOverLimits(t *testing.T) {
+	budget := newTestPayoutBudget(t, 10)
+	budget.MaxWeight = budget.Weight() + p2wpkhOutputSize*4
+	assert.Nil(t, budget.AddOutput("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", 10000))
+	assert.Equal(t, 0, budget.RemainingWeight())
+	assert.Equal(t, ErrPayoutBudgetWeight, budget.AddOutput("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", 10000))
+	assert.Equal(t, 1, budget.OutputCount())
+
+	budget.MaxWeight = maxStandardTxWeight
+	budget.MaxFee = budget.FeeAmount
+	assert.Equal(t, ErrPayoutBudgetFee, budget.AddOutput("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", 10000))
+
+	budget.MaxFee = 0
+	budget.MaxOutputs = 1
+	assert.Equal(t, ErrPayoutBudgetOutputs, budget.AddOutput("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", 10000))
+	assert.Equal(t, 10000, budget.OutputAmount)
+
+	assert.NotNil(t, budget.AddOutput("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", 1))
+}
+
+func TestPayoutBudget_Unfunded(t *testing.T) {
+	budget := newTestPayoutBudget(t, 1)
+	assert.Nil(t, budget.AddOutput("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", 99900))
+	assert.False(t, budget.IsFunded())
+	assert.Equal(t, 0, budget.ChangeAmount())
+
+	_, err := NewPayoutBudget(BaseCoinBip84MainNet, -1)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,256 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+const pendingTransactionStateVersion = 1
+
+/// Type Definitions
+
+// PendingTransaction is an in-progress spend: the generated TransactionData, with its already selected utxos, and a
+// PSBT collecting signatures. Persist it with `SerializedState` when a spend is interrupted, e.g. by app suspension
+// or while awaiting a cosigner, and resume it with `RestorePendingTransaction` without repeating utxo selection.
+type PendingTransaction struct {
+	wallet *HDWallet
+	data   *TransactionData
+	PSBT   string // base64 encoded
+}
+
+// pendingTransactionState is the serialized form of a PendingTransaction.
+type pendingTransactionState struct {
+	Version        int                      `json:"version"`
+	Purpose        int                      `json:"purpose"`
+	Coin           int                      `json:"coin"`
+	Account        int                      `json:"account"`
+	TestNetwork    int                      `json:"test_network,omitempty"`
+	PaymentAddress string                   `json:"payment_address"`
+	Amount         int                      `json:"amount"`
+	FeeAmount      int                      `json:"fee_amount"`
+	ChangeAmount   int                      `json:"change_amount"`
+	ChangePath     *derivationPathState     `json:"change_path,omitempty"`
+	Locktime       int                      `json:"locktime"`
+	RBFOption      int                      `json:"rbf_option"`
+	UTXOs          []pendingTransactionUTXO `json:"utxos"`
+	PSBT           string                   `json:"psbt"`
+}
+
+type pendingTransactionUTXO struct {
+	Txid          string               `json:"txid"`
+	Index         int                  `json:"index"`
+	Amount        int64                `json:"amount"`
+	Path          *derivationPathState `json:"path"`
+	IsConfirmed   bool                 `json:"is_confirmed"`
+	PrevoutScript string               `json:"prevout_script,omitempty"`
+}
+
+type derivationPathState struct {
+	Purpose     int `json:"purpose"`
+	Coin        int `json:"coin"`
+	Account     int `json:"account"`
+	TestNetwork int `json:"test_network,omitempty"`
+	Change      int `json:"change"`
+	Index       int `json:"index"`
+}
+
+/// Constructors
+
+// NewPendingTransaction starts an in-progress spend from generated transaction data, exporting it as an unsigned PSBT.
+// All required utxos must have a derivation path; imported private key sweeps cannot be persisted.
+func (wallet *HDWallet) NewPendingTransaction(data *TransactionData) (*PendingTransaction, error) {
+	encoded, err := wallet.ExportUnsignedPSBT(data)
+	if err != nil {
+		return nil, err
+	}
+	return &PendingTransaction{wallet: wallet, data: data, PSBT: encoded}, nil
+}
+
+// RestorePendingTransaction restores an in-progress spend from a string returned by `SerializedState`. Returns error
+// if the state was serialized for a different BaseCoin, or its PSBT does not spend the persisted utxos.
+func (wallet *HDWallet) RestorePendingTransaction(serializedState string) (*PendingTransaction, error) {
+	var state pendingTransactionState
+	if err := json.Unmarshal([]byte(serializedState), &state); err != nil {
+		return nil, err
+	}
+	if state.Version != pendingTransactionStateVersion {
+		return nil, errors.New("unsupported pending transaction state version")
+	}
+	bc := wallet.BaseCoin
+	if state.Purpose != bc.Purpose || state.Coin != bc.Coin || state.Account != bc.Account || state.TestNetwork != bc.TestNetwork {
+		return nil, errors.New("pending transaction state does not match wallet basecoin")
+	}
+	if len(state.UTXOs) == 0 {
+		return nil, errors.New("pending transaction state has no utxos")
+	}
+
+	data := &TransactionData{
+		PaymentAddress: state.PaymentAddress,
+		basecoin:       bc,
+		Amount:         state.Amount,
+		FeeAmount:      state.FeeAmount,
+		ChangeAmount:   state.ChangeAmount,
+		ChangePath:     state.ChangePath.derivationPath(),
+		Locktime:       state.Locktime,
+		RBFOption:      NewRBFOption(state.RBFOption),
+	}
+	for _, u := range state.UTXOs {
+		if u.Path == nil {
+			return nil, errors.New("pending transaction utxo requires a derivation path")
+		}
+		utxo := NewUTXO(u.Txid, u.Index, u.Amount, u.Path.derivationPath(), nil, u.IsConfirmed)
+		if u.PrevoutScript != "" {
+			script, err := hex.DecodeString(u.PrevoutScript)
+			if err != nil {
+				return nil, err
+			}
+			utxo.SetPrevout(script, u.Amount)
+		}
+		data.availableUtxos = append(data.availableUtxos, utxo)
+		data.requiredUtxos = append(data.requiredUtxos, utxo)
+	}
+
+	pending := &PendingTransaction{wallet: wallet, data: data}
+	if err := pending.UpdatePSBT(state.PSBT); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+/// Receiver functions
+
+// TransactionData returns the transaction data the spend was generated from.
+func (p *PendingTransaction) TransactionData() *TransactionData {
+	return p.data
+}
+
+// UpdatePSBT replaces the PSBT with one carrying additional signatures, e.g. returned by a cosigner. Returns error if
+// the PSBT does not spend the same transaction.
+func (p *PendingTransaction) UpdatePSBT(encodedPSBT string) error {
+	packet, err := psbt.NewPsbt([]byte(encodedPSBT), true)
+	if err != nil {
+		return err
+	}
+
+	builder := transactionBuilder{wallet: p.wallet, unsigned: true}
+	tx, _, err := builder.buildMsgTx(p.data)
+	if err != nil {
+		return err
+	}
+	if packet.UnsignedTx.TxHash() != tx.TxHash() {
+		return errors.New("psbt does not match pending transaction")
+	}
+
+	p.PSBT = encodedPSBT
+	return nil
+}
+
+// Sign adds the wallet's signatures to the PSBT. Returns error if the wallet is watch-only.
+func (p *PendingTransaction) Sign() error {
+	signed, err := p.wallet.SignPSBT(p.PSBT, p.maxDerivationIndex()+1)
+	if err != nil {
+		return err
+	}
+	p.PSBT = signed
+	return nil
+}
+
+// SignedInputCount returns the number of inputs which have collected at least one signature.
+func (p *PendingTransaction) SignedInputCount() (int, error) {
+	packet, err := psbt.NewPsbt([]byte(p.PSBT), true)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, input := range packet.Inputs {
+		if len(input.PartialSigs) > 0 || input.FinalScriptWitness != nil || input.FinalScriptSig != nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Finalize finalizes the fully signed PSBT and returns the network-ready transaction.
+func (p *PendingTransaction) Finalize() (*TransactionMetadata, error) {
+	return p.wallet.FinalizePSBT(p.PSBT)
+}
+
+// SerializedState returns the pending transaction as a JSON string, suitable for persisting and passing to
+// `RestorePendingTransaction`.
+func (p *PendingTransaction) SerializedState() (string, error) {
+	bc := p.wallet.BaseCoin
+	state := pendingTransactionState{
+		Version:        pendingTransactionStateVersion,
+		Purpose:        bc.Purpose,
+		Coin:           bc.Coin,
+		Account:        bc.Account,
+		TestNetwork:    bc.TestNetwork,
+		PaymentAddress: p.data.PaymentAddress,
+		Amount:         p.data.Amount,
+		FeeAmount:      p.data.FeeAmount,
+		ChangeAmount:   p.data.ChangeAmount,
+		ChangePath:     newDerivationPathState(p.data.ChangePath),
+		Locktime:       p.data.Locktime,
+		PSBT:           p.PSBT,
+	}
+	if p.data.RBFOption != nil {
+		state.RBFOption = p.data.RBFOption.Value
+	}
+	for _, utxo := range p.data.requiredUtxos {
+		u := pendingTransactionUTXO{
+			Txid:        utxo.Txid,
+			Index:       utxo.Index,
+			Amount:      utxo.Amount,
+			Path:        newDerivationPathState(utxo.Path),
+			IsConfirmed: utxo.IsConfirmed,
+		}
+		if utxo.prevout != nil {
+			u.PrevoutScript = hex.EncodeToString(utxo.prevout.PkScript)
+		}
+		state.UTXOs = append(state.UTXOs, u)
+	}
+
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+/// Unexported functions
+
+// maxDerivationIndex returns the highest address index among the required utxos.
+func (p *PendingTransaction) maxDerivationIndex() int {
+	max := 0
+	for _, utxo := range p.data.requiredUtxos {
+		if utxo.Path != nil {
+			max = Max(max, utxo.Path.Index)
+		}
+	}
+	return max
+}
+
+func newDerivationPathState(path *DerivationPath) *derivationPathState {
+	if path == nil {
+		return nil
+	}
+	return &derivationPathState{
+		Purpose:     path.Purpose,
+		Coin:        path.Coin,
+		Account:     path.Account,
+		TestNetwork: path.TestNetwork,
+		Change:      path.Change,
+		Index:       path.Index,
+	}
+}
+
+func (s *derivationPathState) derivationPath() *DerivationPath {
+	if s == nil {
+		return nil
+	}
+	bc := &BaseCoin{Purpose: s.Purpose, Coin: s.Coin, Account: s.Account, TestNetwork: s.TestNetwork}
+	return NewDerivationPath(bc, s.Change, s.Index)
+}
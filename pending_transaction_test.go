@@ -0,0 +1,84 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pendingTestTransactionData() *TransactionDataFlatFee {
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	return data
+}
+
+func TestPendingTransaction_ResumesAfterRestore(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	data := pendingTestTransactionData()
+	assert.Nil(t, data.Generate())
+
+	pending, err := wallet.NewPendingTransaction(data.TransactionData)
+	assert.Nil(t, err)
+	count, err := pending.SignedInputCount()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	state, err := pending.SerializedState()
+	assert.Nil(t, err)
+
+	// app relaunched
+	restoredWallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	restored, err := restoredWallet.RestorePendingTransaction(state)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, restored.TransactionData().UtxoCount())
+	assert.Equal(t, data.TransactionData.ChangeAmount, restored.TransactionData().ChangeAmount)
+	assert.Equal(t, 1, restored.TransactionData().ChangePath.Index)
+
+	assert.Nil(t, restored.Sign())
+	count, err = restored.SignedInputCount()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	// signatures survive another round trip
+	state, err = restored.SerializedState()
+	assert.Nil(t, err)
+	restored, err = restoredWallet.RestorePendingTransaction(state)
+	assert.Nil(t, err)
+
+	meta, err := restored.Finalize()
+	assert.Nil(t, err)
+
+	expected, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Txid, meta.Txid)
+	assert.Equal(t, expected.EncodedTx, meta.EncodedTx)
+}
+
+func TestPendingTransaction_RejectsMismatchedState(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	data := pendingTestTransactionData()
+	assert.Nil(t, data.Generate())
+
+	pending, err := wallet.NewPendingTransaction(data.TransactionData)
+	assert.Nil(t, err)
+	state, err := pending.SerializedState()
+	assert.Nil(t, err)
+
+	otherWallet := NewHDWalletFromWords(w, BaseCoinBip49MainNet)
+	_, err = otherWallet.RestorePendingTransaction(state)
+	assert.NotNil(t, err)
+
+	_, err = wallet.RestorePendingTransaction(`{"version":99}`)
+	assert.NotNil(t, err)
+
+	other := pendingTestTransactionData()
+	other.TransactionData.Amount = 10000
+	assert.Nil(t, other.Generate())
+	otherPending, err := wallet.NewPendingTransaction(other.TransactionData)
+	assert.Nil(t, err)
+	assert.NotNil(t, pending.UpdatePSBT(otherPending.PSBT))
+}
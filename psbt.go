@@ -0,0 +1,481 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+/// Receiver functions
+
+// ExportUnsignedPSBT builds the transaction described by data without signing it, and returns it as a base64 encoded
+// PSBT (BIP174) with witness utxos populated, to be signed by a paired cold wallet. Inputs and change carry their key
+// origin, the master fingerprint and path, if the fingerprint is known; taproot inputs carry their BIP371 internal key
+// and key origin instead. P2PKH inputs carry their previous transaction, which must be set on each utxo with
+// `SetPreviousTransaction`. Does not require private keys.
+func (wallet *HDWallet) ExportUnsignedPSBT(data *TransactionData) (string, error) {
+	if data == nil {
+		return "", errors.New("transaction data cannot be nil")
+	}
+
+	builder := transactionBuilder{wallet: wallet, unsigned: true}
+	tx, change, err := builder.buildMsgTx(data)
+	if err != nil {
+		return "", err
+	}
+
+	packet, err := psbt.NewPsbtFromUnsignedTx(tx)
+	if err != nil {
+		return "", err
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return "", err
+	}
+	fingerprint, hasFingerprint, err := wallet.psbtMasterFingerprint()
+	if err != nil {
+		return "", err
+	}
+
+	for i := range tx.TxIn {
+		utxo, err := data.RequiredUTXOAtIndex(i)
+		if err != nil {
+			return "", err
+		}
+		if utxo.descriptor != nil {
+			if err := utxo.descriptor.addPSBTInput(updater, i, utxo); err != nil {
+				return "", fmt.Errorf("input %d: %w", i, err)
+			}
+			continue
+		}
+		pkScript, redeemScript, err := wallet.prevoutScriptsForPath(utxo.Path)
+		if err != nil {
+			return "", err
+		}
+		if err := utxo.verifyPrevout(pkScript); err != nil {
+			return "", fmt.Errorf("input %d: %w", i, err)
+		}
+		if txscript.GetScriptClass(pkScript) == txscript.PubKeyHashTy {
+			if utxo.prevTx == nil {
+				return "", fmt.Errorf("input %d: %w", i, ErrPrevoutTxMissing)
+			}
+			if err := updater.AddInNonWitnessUtxo(utxo.prevTx, i); err != nil {
+				return "", err
+			}
+		} else if err := updater.AddInWitnessUtxo(wire.NewTxOut(utxo.Amount, pkScript), i); err != nil {
+			return "", err
+		}
+		if redeemScript != nil {
+			if err := updater.AddInRedeemScript(redeemScript, i); err != nil {
+				return "", err
+			}
+		}
+		pubkey, err := wallet.publicKey(utxo.Path)
+		if err != nil {
+			return "", err
+		}
+		if isTaprootScript(pkScript) {
+			addPSBTTaprootInput(updater, i, pubkey, fingerprint, hasFingerprint, utxo.Path.bip32Path())
+		} else if hasFingerprint {
+			if err := updater.AddInBip32Derivation(fingerprint, utxo.Path.bip32Path(), pubkey.SerializeCompressed(), i); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if hasFingerprint && change != nil {
+		pubkey, err := wallet.publicKey(change.Path)
+		if err != nil {
+			return "", err
+		}
+		if err := updater.AddOutBip32Derivation(fingerprint, change.Path.bip32Path(), pubkey.SerializeCompressed(), change.VoutIndex); err != nil {
+			return "", err
+		}
+	}
+
+	return packet.B64Encode()
+}
+
+// SignPSBT signs each input of a base64 encoded PSBT whose previous output pays to one of the wallet's receive or
+// change addresses up to a given index, or to the single key of one of the input's BIP32 or BIP371 taproot derivations
+// from the wallet's master key, as coordinators and other wallets populate, and returns the updated PSBT. P2PKH, P2WPKH,
+// P2SH-P2WPKH and BIP86 taproot key path inputs are supported; P2PKH inputs need their non-witness utxo, and taproot
+// inputs the previous output of every input. Multisig witness script inputs get a partial signature from each of their
+// keys the wallet derives, for `FinalizePSBT` to combine once the threshold is met. Inputs belonging to other wallets
+// are left untouched.
+func (wallet *HDWallet) SignPSBT(encodedPSBT string, upTo int) (string, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return "", err
+	}
+
+	packet, err := psbt.NewPsbt([]byte(encodedPSBT), true)
+	if err != nil {
+		return "", err
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return "", err
+	}
+
+	known, err := wallet.derivedAddressMap(upTo)
+	if err != nil {
+		return "", err
+	}
+
+	tx := packet.UnsignedTx
+	sigHashes := txscript.NewTxSigHashes(tx)
+	params := wallet.BaseCoin.defaultNetParams()
+	signed := 0
+
+	for i, input := range packet.Inputs {
+		if input.WitnessUtxo == nil && input.NonWitnessUtxo == nil {
+			continue
+		}
+		if input.WitnessUtxo != nil && input.WitnessScript != nil {
+			count, err := wallet.signPSBTMultisigInput(updater, i, sigHashes)
+			if err != nil {
+				return "", fmt.Errorf("input %d: %w", i, err)
+			}
+			signed += count
+			continue
+		}
+		prevout, err := psbtPrevout(packet, i)
+		if err != nil {
+			return "", fmt.Errorf("input %d: %w", i, err)
+		}
+		path, err := wallet.psbtInputPath(input, prevout.PkScript, known, params)
+		if err != nil {
+			return "", err
+		}
+		if path == nil {
+			continue
+		}
+
+		signer, err := newUsableAddressWithDerivationPath(wallet, path)
+		if err != nil {
+			return "", err
+		}
+		pubkey := signer.derivedPrivateKey.PubKey().SerializeCompressed()
+
+		switch {
+		case isTaprootScript(prevout.PkScript):
+			prevouts, err := psbtPrevouts(packet)
+			if err != nil {
+				return "", fmt.Errorf("input %d: %w", i, err)
+			}
+			added, err := wallet.signPSBTTaprootInput(updater, i, prevouts, signer.derivedPrivateKey)
+			if err != nil {
+				return "", fmt.Errorf("input %d: %w", i, err)
+			}
+			if added {
+				signed++
+			}
+			continue
+		case txscript.GetScriptClass(prevout.PkScript) == txscript.PubKeyHashTy:
+			sig, err := txscript.RawTxInSignature(tx, i, prevout.PkScript, txscript.SigHashAll, signer.derivedPrivateKey)
+			if err != nil {
+				return "", err
+			}
+			if _, err := updater.Sign(i, sig, pubkey, nil, nil); err != nil {
+				return "", err
+			}
+			signed++
+			continue
+		}
+
+		subScript := prevout.PkScript
+		var redeemScript []byte
+		if txscript.IsPayToScriptHash(subScript) {
+			redeemScript, err = P2WPKHScript(btcutil.Hash160(pubkey))
+			if err != nil {
+				return "", err
+			}
+			subScript = redeemScript
+		}
+
+		sig, err := txscript.RawTxInWitnessSignature(tx, sigHashes, i, prevout.Value, subScript, txscript.SigHashAll, signer.derivedPrivateKey)
+		if err != nil {
+			return "", err
+		}
+		if _, err := updater.Sign(i, sig, pubkey, redeemScript, nil); err != nil {
+			return "", err
+		}
+		signed++
+	}
+
+	if signed == 0 {
+		return "", errors.New("no psbt inputs belong to wallet")
+	}
+
+	return packet.B64Encode()
+}
+
+// FinalizePSBT finalizes a fully signed base64 encoded PSBT and extracts the network-ready transaction. Multisig inputs
+// need only their threshold of signatures. Does not require private keys.
+func (wallet *HDWallet) FinalizePSBT(encodedPSBT string) (*TransactionMetadata, error) {
+	packet, err := psbt.NewPsbt([]byte(encodedPSBT), true)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range packet.Inputs {
+		if err := finalizePSBTMultisigInput(packet, i); err != nil {
+			return nil, err
+		}
+		if err := finalizePSBTTaprootInput(packet, i); err != nil {
+			return nil, err
+		}
+	}
+	if err := psbt.MaybeFinalizeAll(packet); err != nil {
+		return nil, err
+	}
+
+	txBytes, err := psbt.Extract(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return nil, err
+	}
+
+	return &TransactionMetadata{Txid: tx.TxHash().String(), EncodedTx: hex.EncodeToString(txBytes)}, nil
+}
+
+/// Unexported functions
+
+// finalizePSBTMultisigInput finalizes an input spending a CHECKMULTISIG witness script with at least its threshold of
+// signatures, which the psbt package only finalizes when every key has signed. Other inputs are left untouched.
+func finalizePSBTMultisigInput(packet *psbt.Psbt, index int) error {
+	input := packet.Inputs[index]
+	if input.WitnessUtxo == nil || input.WitnessScript == nil || input.FinalScriptWitness != nil {
+		return nil
+	}
+	if txscript.GetScriptClass(input.WitnessScript) != txscript.MultiSigTy {
+		return nil
+	}
+	pushes, err := txscript.PushedData(input.WitnessScript)
+	if err != nil {
+		return err
+	}
+	_, threshold, err := txscript.CalcMultiSigStats(input.WitnessScript)
+	if err != nil {
+		return err
+	}
+
+	// signatures in the order of their keys in the script, as CHECKMULTISIG requires
+	witness := wire.TxWitness{nil}
+	for _, pubkey := range pushes {
+		for _, sig := range input.PartialSigs {
+			if len(witness)-1 < threshold && bytes.Equal(sig.PubKey, pubkey) {
+				witness = append(witness, sig.Signature)
+			}
+		}
+	}
+	if len(witness)-1 < threshold {
+		return nil
+	}
+	witness = append(witness, input.WitnessScript)
+
+	var buf bytes.Buffer
+	if err := wire.WriteVarInt(&buf, 0, uint64(len(witness))); err != nil {
+		return err
+	}
+	for _, item := range witness {
+		if err := wire.WriteVarBytes(&buf, 0, item); err != nil {
+			return err
+		}
+	}
+	finalized := psbt.NewPsbtInput(nil, input.WitnessUtxo)
+	finalized.FinalScriptWitness = buf.Bytes()
+	if input.RedeemScript != nil {
+		if finalized.FinalScriptSig, err = txscript.NewScriptBuilder().AddData(input.RedeemScript).Script(); err != nil {
+			return err
+		}
+	}
+	packet.Inputs[index] = *finalized
+	return nil
+}
+
+// signPSBTMultisigInput adds a partial signature to a CHECKMULTISIG witness script input for each BIP32 derivation
+// from the wallet's master key whose public key is in the script, and returns the number of signatures added.
+func (wallet *HDWallet) signPSBTMultisigInput(updater *psbt.Updater, index int, sigHashes *txscript.TxSigHashes) (int, error) {
+	input := updater.Upsbt.Inputs[index]
+	if txscript.GetScriptClass(input.WitnessScript) != txscript.MultiSigTy {
+		return 0, errors.New("unsupported witness script")
+	}
+	scriptHash := sha256.Sum256(input.WitnessScript)
+	pkScript, err := P2WSHScript(scriptHash[:])
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(input.WitnessUtxo.PkScript, pkScript) {
+		return 0, errors.New("witness utxo does not pay to witness script")
+	}
+	pushes, err := txscript.PushedData(input.WitnessScript)
+	if err != nil {
+		return 0, err
+	}
+	fingerprint, hasFingerprint, err := wallet.psbtMasterFingerprint()
+	if err != nil || !hasFingerprint {
+		return 0, err
+	}
+
+	signed := 0
+	for _, derivation := range input.Bip32Derivation {
+		if derivation.MasterKeyFingerprint != fingerprint || hasPartialSig(input.PartialSigs, derivation.PubKey) {
+			continue
+		}
+		inScript := false
+		for _, push := range pushes {
+			inScript = inScript || bytes.Equal(push, derivation.PubKey)
+		}
+		if !inScript {
+			continue
+		}
+		path := &DerivationPath{components: append([]uint32(nil), derivation.Bip32Path...)}
+		if err := requireKeyRole(path, KeyRoleSpending); err != nil {
+			return 0, err
+		}
+		extended, err := wallet.keyFactory().indexPrivateKey(path)
+		if err != nil {
+			return 0, err
+		}
+		priv, err := extended.ECPrivKey()
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.Equal(priv.PubKey().SerializeCompressed(), derivation.PubKey) {
+			return 0, errors.New("psbt key origin does not match its public key")
+		}
+		sig, err := txscript.RawTxInWitnessSignature(updater.Upsbt.UnsignedTx, sigHashes, index, input.WitnessUtxo.Value, input.WitnessScript, txscript.SigHashAll, priv)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := updater.Sign(index, sig, derivation.PubKey, nil, nil); err != nil {
+			return 0, err
+		}
+		signed++
+	}
+	return signed, nil
+}
+
+// hasPartialSig returns true if a PSBT input already has a partial signature by a public key.
+func hasPartialSig(sigs []*psbt.PartialSig, pubkey []byte) bool {
+	for _, sig := range sigs {
+		if bytes.Equal(sig.PubKey, pubkey) {
+			return true
+		}
+	}
+	return false
+}
+
+// psbtInputPath returns the derivation path of the wallet key the previous output of a PSBT input pays to, found among
+// the known addresses, or else among the input's BIP32 and BIP371 taproot derivations from the wallet's master key.
+// Returns nil if neither.
+func (wallet *HDWallet) psbtInputPath(input psbt.PInput, pkScript []byte, known map[string]*MetaAddress, params *chaincfg.Params) (*DerivationPath, error) {
+	if meta, ok := known[pkScriptAddress(pkScript, params)]; ok {
+		return meta.DerivationPath, nil
+	}
+
+	fingerprint, hasFingerprint, err := wallet.psbtMasterFingerprint()
+	if err != nil || !hasFingerprint {
+		return nil, err
+	}
+	derivations := append(append([]*psbt.Bip32Derivation(nil), input.Bip32Derivation...), tapBip32Derivations(input)...)
+	for _, derivation := range derivations {
+		depth := len(derivation.Bip32Path)
+		if derivation.MasterKeyFingerprint != fingerprint || depth < 1 || depth > maxCustomDerivationPathDepth {
+			continue
+		}
+		path := &DerivationPath{components: append([]uint32(nil), derivation.Bip32Path...)}
+		if KeyRoleForPath(path) != KeyRoleSpending {
+			continue
+		}
+		pubkey, err := wallet.publicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		serialized := pubkey.SerializeCompressed()
+		if !bytes.Equal(serialized, derivation.PubKey) && !bytes.Equal(padTo32(pubkey.X.Bytes()), derivation.PubKey) {
+			continue
+		}
+
+		// only single key outputs, as signed by `SignPSBT`
+		scripts, err := singleKeyPkScripts(pubkey)
+		if err != nil {
+			return nil, err
+		}
+		for _, script := range scripts {
+			if bytes.Equal(pkScript, script) {
+				return path, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// singleKeyPkScripts returns the P2PKH, P2WPKH, P2SH-P2WPKH and BIP86 taproot output scripts of a public key.
+func singleKeyPkScripts(pubkey *btcec.PublicKey) ([][]byte, error) {
+	hash := btcutil.Hash160(pubkey.SerializeCompressed())
+	legacy, err := P2PKHScript(hash)
+	if err != nil {
+		return nil, err
+	}
+	witnessProgram, err := P2WPKHScript(hash)
+	if err != nil {
+		return nil, err
+	}
+	nested, err := P2SHScript(btcutil.Hash160(witnessProgram))
+	if err != nil {
+		return nil, err
+	}
+	taproot, err := P2TRScript(taprootOutputKey(pubkey))
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{legacy, witnessProgram, nested, taproot}, nil
+}
+
+// prevoutScriptsForPath returns the output script, and redeem script if nested segwit, for a wallet derivation path.
+func (wallet *HDWallet) prevoutScriptsForPath(path *DerivationPath) ([]byte, []byte, error) {
+	if path == nil {
+		return nil, nil, errors.New("psbt inputs require a derivation path")
+	}
+
+	pubkey, err := wallet.publicKey(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash := btcutil.Hash160(pubkey.SerializeCompressed())
+
+	switch path.Purpose {
+	case bip44purpose:
+		pkScript, err := P2PKHScript(hash)
+		return pkScript, nil, err
+	case bip84purpose:
+		pkScript, err := P2WPKHScript(hash)
+		return pkScript, nil, err
+	case bip49purpose:
+		redeemScript, err := P2WPKHScript(hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		pkScript, err := P2SHScript(btcutil.Hash160(redeemScript))
+		return pkScript, redeemScript, err
+	case bip86purpose:
+		pkScript, err := P2TRScript(taprootOutputKey(pubkey))
+		return pkScript, nil, err
+	}
+	return nil, nil, ErrInvalidPurposeValue
+}
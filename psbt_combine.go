@@ -0,0 +1,305 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+// Following errors are returned when combining PSBTs or adding signatures to them.
+var (
+	ErrPSBTCombineMismatch  = errors.New("psbts do not share the same unsigned transaction")
+	ErrPSBTCombineEmpty     = errors.New("at least one psbt is required to combine")
+	ErrPSBTSignatureInvalid = errors.New("signature does not verify for the psbt input and public key")
+	ErrPSBTSignatureKey     = errors.New("public key is not paid to by the psbt input's previous output or witness script")
+)
+
+/// Type Definitions
+
+// PSBTCombiner merges base64 encoded PSBTs (BIP174) of the same unsigned transaction, such as those returned by each
+// signer of a multisig, into one holding every signature and key origin. Add each PSBT using `AddPSBT`, as gomobile
+// does not support custom arrays/slices.
+type PSBTCombiner struct {
+	packets []*psbt.Psbt
+}
+
+/// Constructors
+
+// NewPSBTCombiner returns a pointer to an empty PSBTCombiner.
+func NewPSBTCombiner() *PSBTCombiner {
+	return &PSBTCombiner{}
+}
+
+/// Receiver functions
+
+// AddPSBT adds a base64 encoded PSBT. Returns ErrPSBTCombineMismatch if its unsigned transaction differs from that of
+// those already added.
+func (c *PSBTCombiner) AddPSBT(encodedPSBT string) error {
+	packet, err := psbt.NewPsbt([]byte(encodedPSBT), true)
+	if err != nil {
+		return err
+	}
+	if len(c.packets) > 0 && c.packets[0].UnsignedTx.TxHash() != packet.UnsignedTx.TxHash() {
+		return ErrPSBTCombineMismatch
+	}
+	c.packets = append(c.packets, packet)
+	return nil
+}
+
+// PSBTCount returns the number of PSBTs added.
+func (c *PSBTCombiner) PSBTCount() int {
+	return len(c.packets)
+}
+
+// Combine returns the base64 encoded PSBT merging every PSBT added. Partial signatures, key origins and unknown fields
+// are the union of all PSBTs; other fields are taken from the first PSBT which has them. Pass the result to
+// `FinalizePSBT` once enough signatures are present.
+func (c *PSBTCombiner) Combine() (string, error) {
+	if len(c.packets) == 0 {
+		return "", ErrPSBTCombineEmpty
+	}
+	encoded, err := c.packets[0].B64Encode()
+	if err != nil {
+		return "", err
+	}
+	combined, err := psbt.NewPsbt([]byte(encoded), true)
+	if err != nil {
+		return "", err
+	}
+
+	for _, packet := range c.packets[1:] {
+		for i := range packet.Unknowns {
+			if !hasPSBTUnknown(combined.Unknowns, packet.Unknowns[i].Key) {
+				combined.Unknowns = append(combined.Unknowns, packet.Unknowns[i])
+			}
+		}
+		for i := range combined.Inputs {
+			combinePSBTInput(&combined.Inputs[i], &packet.Inputs[i])
+		}
+		for i := range combined.Outputs {
+			out, other := &combined.Outputs[i], &packet.Outputs[i]
+			if out.RedeemScript == nil {
+				out.RedeemScript = other.RedeemScript
+			}
+			if out.WitnessScript == nil {
+				out.WitnessScript = other.WitnessScript
+			}
+			out.Bip32Derivation = combinePSBTDerivations(out.Bip32Derivation, other.Bip32Derivation)
+		}
+	}
+
+	if err := combined.SanityCheck(); err != nil {
+		return "", err
+	}
+	return combined.B64Encode()
+}
+
+/// Exported functions
+
+// CombinePSBTs returns the base64 encoded PSBT merging base64 encoded PSBTs of the same unsigned transaction, as
+// `PSBTCombiner` does. For callers outside gomobile.
+func CombinePSBTs(encodedPSBTs []string) (string, error) {
+	combiner := NewPSBTCombiner()
+	for i, encoded := range encodedPSBTs {
+		if err := combiner.AddPSBT(encoded); err != nil {
+			return "", fmt.Errorf("psbt %d: %w", i, err)
+		}
+	}
+	return combiner.Combine()
+}
+
+// AddPSBTSignature adds a signature made elsewhere, such as by a hardware wallet, to an input of a base64 encoded PSBT,
+// for a 33-byte compressed public key, and returns the updated PSBT. The signature is DER encoded with its sighash type
+// byte appended, and must verify against the input's witness utxo, so the input must be P2WPKH, P2SH-P2WPKH or have its
+// witness script. Returns ErrPSBTSignatureKey unless the input's previous output pays to the public key, or its witness
+// script, paid to by the previous output, holds it, or ErrPSBTSignatureInvalid if the signature does not verify.
+func AddPSBTSignature(encodedPSBT string, inputIndex int, pubkey []byte, signature []byte) (string, error) {
+	packet, err := psbt.NewPsbt([]byte(encodedPSBT), true)
+	if err != nil {
+		return "", err
+	}
+	if inputIndex < 0 || inputIndex > len(packet.Inputs)-1 {
+		return "", errors.New("index must be within range of inputs")
+	}
+	input := packet.Inputs[inputIndex]
+	if input.WitnessUtxo == nil {
+		return "", errors.New("psbt input must have a witness utxo")
+	}
+	if len(signature) < 2 {
+		return "", ErrPSBTSignatureInvalid
+	}
+	publicKey, err := btcec.ParsePubKey(pubkey, btcec.S256())
+	if err != nil {
+		return "", err
+	}
+	hasKey, err := psbtInputHasKey(input, pubkey)
+	if err != nil {
+		return "", err
+	}
+	if !hasKey {
+		return "", ErrPSBTSignatureKey
+	}
+
+	// the script signed, and the redeem script of nested P2WPKH if not already present
+	script := input.WitnessScript
+	var redeemScript []byte
+	if script == nil {
+		if script, err = P2WPKHScript(btcutil.Hash160(pubkey)); err != nil {
+			return "", err
+		}
+		if input.RedeemScript == nil && txscript.IsPayToScriptHash(input.WitnessUtxo.PkScript) {
+			redeemScript = script
+		}
+	}
+
+	hashType := txscript.SigHashType(signature[len(signature)-1])
+	sigHash, err := txscript.CalcWitnessSigHash(script, txscript.NewTxSigHashes(packet.UnsignedTx), hashType, packet.UnsignedTx, inputIndex, input.WitnessUtxo.Value)
+	if err != nil {
+		return "", err
+	}
+	if verifyDERSignature(signature[:len(signature)-1], sigHash, publicKey) != nil {
+		return "", ErrPSBTSignatureInvalid
+	}
+
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return "", err
+	}
+	if _, err := updater.Sign(inputIndex, signature, pubkey, redeemScript, nil); err != nil {
+		return "", err
+	}
+	return packet.B64Encode()
+}
+
+/// Unexported functions
+
+// psbtInputHasKey returns true if a PSBT input's witness utxo pays to a public key as P2WPKH or P2SH-P2WPKH, or to a
+// witness script, as P2WSH or P2SH-P2WSH, whose pushed data includes the key.
+func psbtInputHasKey(input psbt.PInput, pubkey []byte) (bool, error) {
+	pkScript := input.WitnessUtxo.PkScript
+	var program []byte
+	if input.WitnessScript != nil {
+		scriptHash := sha256.Sum256(input.WitnessScript)
+		script, err := P2WSHScript(scriptHash[:])
+		if err != nil {
+			return false, err
+		}
+		program = script
+	} else {
+		script, err := P2WPKHScript(btcutil.Hash160(pubkey))
+		if err != nil {
+			return false, err
+		}
+		program = script
+	}
+	nested, err := P2SHScript(btcutil.Hash160(program))
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(pkScript, program) && !bytes.Equal(pkScript, nested) {
+		return false, nil
+	}
+	if input.WitnessScript == nil {
+		return true, nil
+	}
+
+	pushes, err := txscript.PushedData(input.WitnessScript)
+	if err != nil {
+		return false, err
+	}
+	for _, push := range pushes {
+		if bytes.Equal(push, pubkey) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// combinePSBTInput merges the fields of another PSBT's input into an input.
+func combinePSBTInput(in *psbt.PInput, other *psbt.PInput) {
+	if in.NonWitnessUtxo == nil {
+		in.NonWitnessUtxo = other.NonWitnessUtxo
+	}
+	if in.WitnessUtxo == nil {
+		in.WitnessUtxo = other.WitnessUtxo
+	}
+	if in.SighashType == 0 {
+		in.SighashType = other.SighashType
+	}
+	if in.RedeemScript == nil {
+		in.RedeemScript = other.RedeemScript
+	}
+	if in.WitnessScript == nil {
+		in.WitnessScript = other.WitnessScript
+	}
+	if in.FinalScriptSig == nil {
+		in.FinalScriptSig = other.FinalScriptSig
+	}
+	if in.FinalScriptWitness == nil {
+		in.FinalScriptWitness = other.FinalScriptWitness
+	}
+	for _, sig := range other.PartialSigs {
+		duplicate := false
+		for _, existing := range in.PartialSigs {
+			if bytes.Equal(existing.PubKey, sig.PubKey) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			in.PartialSigs = append(in.PartialSigs, sig)
+		}
+	}
+	in.Bip32Derivation = combinePSBTDerivations(in.Bip32Derivation, other.Bip32Derivation)
+	in.Unknowns = combinePSBTUnknowns(in.Unknowns, other.Unknowns)
+}
+
+// combinePSBTDerivations returns the union of two sets of key origins, keyed by public key.
+func combinePSBTDerivations(derivations []*psbt.Bip32Derivation, others []*psbt.Bip32Derivation) []*psbt.Bip32Derivation {
+	for _, derivation := range others {
+		duplicate := false
+		for _, existing := range derivations {
+			if bytes.Equal(existing.PubKey, derivation.PubKey) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			derivations = append(derivations, derivation)
+		}
+	}
+	return derivations
+}
+
+// combinePSBTUnknowns returns the union of two sets of unknown fields, keyed by key.
+func combinePSBTUnknowns(unknowns []*psbt.Unknown, others []*psbt.Unknown) []*psbt.Unknown {
+	for _, unknown := range others {
+		duplicate := false
+		for _, existing := range unknowns {
+			if bytes.Equal(existing.Key, unknown.Key) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			unknowns = append(unknowns, unknown)
+		}
+	}
+	return unknowns
+}
+
+// hasPSBTUnknown returns true if global unknown fields include a key.
+func hasPSBTUnknown(unknowns []psbt.Unknown, key []byte) bool {
+	for _, unknown := range unknowns {
+		if bytes.Equal(unknown.Key, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package cnlib
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/stretchr/testify/assert"
+)
+
+// psbtCombineTestMultisig returns an unsigned PSBT spending a 2-of-2 P2WSH output, with the signers' keys.
+func psbtCombineTestMultisig(t *testing.T) (string, []*btcec.PrivateKey, []byte, int64) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	var keys []*btcec.PrivateKey
+	builder := txscript.NewScriptBuilder().AddOp(txscript.OP_2)
+	for i := 0; i < 2; i++ {
+		key, err := wallet.keyFactory().indexPrivateKey(NewDerivationPath(BaseCoinBip84MainNet, 0, i))
+		assert.Nil(t, err)
+		privateKey, err := key.ECPrivKey()
+		assert.Nil(t, err)
+		keys = append(keys, privateKey)
+		builder.AddData(privateKey.PubKey().SerializeCompressed())
+	}
+	witnessScript, err := builder.AddOp(txscript.OP_2).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	assert.Nil(t, err)
+	scriptHash := sha256.Sum256(witnessScript)
+	pkScript, err := P2WSHScript(scriptHash[:])
+	assert.Nil(t, err)
+
+	rawTx, _ := NewRawTransaction(2, 0)
+	assert.Nil(t, rawTx.AddInput(NewRawTransactionInput(descriptorTestTxid, 0, 0xfffffffd)))
+	outputScript, _ := AddressScript("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6")
+	assert.Nil(t, rawTx.AddOutput(NewRawTransactionOutput(99000, outputScript)))
+	encodedTx, _ := rawTx.Encode()
+	tx, err := decodeMsgTx(encodedTx)
+	assert.Nil(t, err)
+
+	packet, err := psbt.NewPsbtFromUnsignedTx(tx)
+	assert.Nil(t, err)
+	updater, _ := psbt.NewUpdater(packet)
+	amount := int64(100000)
+	assert.Nil(t, updater.AddInWitnessUtxo(wire.NewTxOut(amount, pkScript), 0))
+	assert.Nil(t, updater.AddInWitnessScript(witnessScript, 0))
+	encoded, err := packet.B64Encode()
+	assert.Nil(t, err)
+	return encoded, keys, witnessScript, amount
+}
+
+func TestCombinePSBTs_Multisig(t *testing.T) {
+	unsigned, keys, witnessScript, amount := psbtCombineTestMultisig(t)
+	packet, _ := psbt.NewPsbt([]byte(unsigned), true)
+	sigHashes := txscript.NewTxSigHashes(packet.UnsignedTx)
+
+	// each signer adds their signature to their own copy
+	var partials []string
+	var signatures [][]byte
+	for _, key := range keys {
+		signature, err := txscript.RawTxInWitnessSignature(packet.UnsignedTx, sigHashes, 0, amount, witnessScript, txscript.SigHashAll, key)
+		assert.Nil(t, err)
+		signatures = append(signatures, signature)
+		partial, err := AddPSBTSignature(unsigned, 0, key.PubKey().SerializeCompressed(), signature)
+		assert.Nil(t, err)
+		partials = append(partials, partial)
+	}
+
+	// a single signature cannot be finalized
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	_, err := wallet.FinalizePSBT(partials[0])
+	assert.NotNil(t, err)
+
+	combined, err := CombinePSBTs(partials)
+	assert.Nil(t, err)
+	meta, err := wallet.FinalizePSBT(combined)
+	assert.Nil(t, err)
+
+	tx, err := decodeMsgTx(meta.EncodedTx)
+	assert.Nil(t, err)
+	assert.Equal(t, wire.TxWitness{{}, signatures[0], signatures[1], witnessScript}, tx.TxIn[0].Witness)
+	engine, err := txscript.NewEngine(packet.Inputs[0].WitnessUtxo.PkScript, tx, 0, txscript.StandardVerifyFlags, nil, txscript.NewTxSigHashes(tx), amount)
+	assert.Nil(t, err)
+	assert.Nil(t, engine.Execute())
+
+	// combining is idempotent, and order independent
+	combiner := NewPSBTCombiner()
+	assert.Nil(t, combiner.AddPSBT(partials[1]))
+	assert.Nil(t, combiner.AddPSBT(combined))
+	assert.Nil(t, combiner.AddPSBT(partials[0]))
+	assert.Equal(t, 3, combiner.PSBTCount())
+	recombined, err := combiner.Combine()
+	assert.Nil(t, err)
+	remeta, err := wallet.FinalizePSBT(recombined)
+	assert.Nil(t, err)
+	assert.Equal(t, meta.EncodedTx, remeta.EncodedTx)
+}
+
+func TestAddPSBTSignature_SingleKey(t *testing.T) {
+	cold := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+	unsigned, err := cold.ExportUnsignedPSBT(data.TransactionData)
+	assert.Nil(t, err)
+
+	// signed as an external device would
+	packet, _ := psbt.NewPsbt([]byte(unsigned), true)
+	key, _ := cold.keyFactory().indexPrivateKey(path)
+	privateKey, _ := key.ECPrivKey()
+	signature, err := txscript.RawTxInWitnessSignature(packet.UnsignedTx, txscript.NewTxSigHashes(packet.UnsignedTx), 0, 96537, packet.Inputs[0].WitnessUtxo.PkScript, txscript.SigHashAll, privateKey)
+	assert.Nil(t, err)
+	pubkey := privateKey.PubKey().SerializeCompressed()
+
+	// wrong key, tampered signature, and out of range input
+	other, _ := cold.CompressedPubKeyForPath(NewDerivationPath(BaseCoinBip84MainNet, 0, 2))
+	_, err = AddPSBTSignature(unsigned, 0, other, signature)
+	assert.Equal(t, ErrPSBTSignatureKey, err)
+	tampered := append([]byte{}, signature...)
+	tampered[len(tampered)-2] ^= 0x01
+	_, err = AddPSBTSignature(unsigned, 0, pubkey, tampered)
+	assert.Equal(t, ErrPSBTSignatureInvalid, err)
+	_, err = AddPSBTSignature(unsigned, 1, pubkey, signature)
+	assert.NotNil(t, err)
+
+	signed, err := AddPSBTSignature(unsigned, 0, pubkey, signature)
+	assert.Nil(t, err)
+	meta, err := cold.FinalizePSBT(signed)
+	assert.Nil(t, err)
+	expected, err := cold.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.EncodedTx, meta.EncodedTx)
+}
+
+func TestAddPSBTSignature_RejectsKeyOutsideScript(t *testing.T) {
+	unsigned, _, witnessScript, amount := psbtCombineTestMultisig(t)
+	packet, _ := psbt.NewPsbt([]byte(unsigned), true)
+
+	// a valid signature by a key the witness script does not hold
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	key, _ := wallet.keyFactory().indexPrivateKey(NewDerivationPath(BaseCoinBip84MainNet, 0, 2))
+	outsider, _ := key.ECPrivKey()
+	signature, err := txscript.RawTxInWitnessSignature(packet.UnsignedTx, txscript.NewTxSigHashes(packet.UnsignedTx), 0, amount, witnessScript, txscript.SigHashAll, outsider)
+	assert.Nil(t, err)
+	_, err = AddPSBTSignature(unsigned, 0, outsider.PubKey().SerializeCompressed(), signature)
+	assert.Equal(t, ErrPSBTSignatureKey, err)
+
+	// a witness script holding the key, but not paid to by the witness utxo
+	replaced, err := txscript.NewScriptBuilder().AddOp(txscript.OP_1).AddData(outsider.PubKey().SerializeCompressed()).
+		AddOp(txscript.OP_1).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	assert.Nil(t, err)
+	packet.Inputs[0].WitnessScript = replaced
+	swapped, err := packet.B64Encode()
+	assert.Nil(t, err)
+	signature, err = txscript.RawTxInWitnessSignature(packet.UnsignedTx, txscript.NewTxSigHashes(packet.UnsignedTx), 0, amount, replaced, txscript.SigHashAll, outsider)
+	assert.Nil(t, err)
+	_, err = AddPSBTSignature(swapped, 0, outsider.PubKey().SerializeCompressed(), signature)
+	assert.Equal(t, ErrPSBTSignatureKey, err)
+}
+
+func TestCombinePSBTs_Invalid(t *testing.T) {
+	_, err := CombinePSBTs(nil)
+	assert.Equal(t, ErrPSBTCombineEmpty, err)
+	_, err = CombinePSBTs([]string{"not a psbt"})
+	assert.NotNil(t, err)
+
+	unsigned, _, _, _ := psbtCombineTestMultisig(t)
+	packet, _ := psbt.NewPsbt([]byte(unsigned), true)
+	packet.UnsignedTx.LockTime = 1
+	different, err := packet.B64Encode()
+	assert.Nil(t, err)
+	combiner := NewPSBTCombiner()
+	assert.Nil(t, combiner.AddPSBT(unsigned))
+	assert.Equal(t, ErrPSBTCombineMismatch, combiner.AddPSBT(different))
+	_, err = CombinePSBTs([]string{unsigned, different})
+	assert.True(t, errors.Is(err, ErrPSBTCombineMismatch))
+}
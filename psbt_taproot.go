@@ -0,0 +1,175 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+// BIP371 taproot PSBT input fields, which the psbt package in use predates and keeps as unknowns. Output fields are
+// not supported, as the package rejects unknown output keys.
+const (
+	psbtInTapKeySig          = byte(0x13)
+	psbtInTapBip32Derivation = byte(0x16)
+	psbtInTapInternalKey     = byte(0x17)
+)
+
+/// Unexported functions
+
+// addPSBTTaprootInput adds the BIP371 internal key of a PSBT input spending a BIP86 output, and its key origin if the
+// master fingerprint is known, so a wallet holding the key can sign with `SignPSBT`.
+func addPSBTTaprootInput(updater *psbt.Updater, index int, internalKey *btcec.PublicKey, fingerprint uint32, hasFingerprint bool, path []uint32) {
+	xOnly := padTo32(internalKey.X.Bytes())
+	input := &updater.Upsbt.Inputs[index]
+	input.Unknowns = append(input.Unknowns, &psbt.Unknown{Key: []byte{psbtInTapInternalKey}, Value: xOnly})
+	if !hasFingerprint {
+		return
+	}
+
+	// no leaf hashes, as key path spends commit to none
+	value := []byte{0x00}
+	value = appendUint32LE(value, fingerprint)
+	for _, component := range path {
+		value = appendUint32LE(value, component)
+	}
+	key := append([]byte{psbtInTapBip32Derivation}, xOnly...)
+	input.Unknowns = append(input.Unknowns, &psbt.Unknown{Key: key, Value: value})
+}
+
+// tapBip32Derivations returns the BIP371 key origins of a PSBT input's x-only keys, skipping malformed ones.
+func tapBip32Derivations(input psbt.PInput) []*psbt.Bip32Derivation {
+	var derivations []*psbt.Bip32Derivation
+	for _, unknown := range input.Unknowns {
+		if len(unknown.Key) != 33 || unknown.Key[0] != psbtInTapBip32Derivation {
+			continue
+		}
+		reader := bytes.NewReader(unknown.Value)
+		leafCount, err := wire.ReadVarInt(reader, 0)
+		if err != nil || leafCount > uint64(reader.Len()/32) {
+			continue
+		}
+		origin := unknown.Value[len(unknown.Value)-reader.Len()+int(leafCount)*32:]
+		if len(origin) < 4 || len(origin)%4 != 0 {
+			continue
+		}
+		path := make([]uint32, 0, len(origin)/4-1)
+		for i := 4; i < len(origin); i += 4 {
+			path = append(path, binary.LittleEndian.Uint32(origin[i:i+4]))
+		}
+		derivations = append(derivations, &psbt.Bip32Derivation{
+			PubKey:               unknown.Key[1:],
+			MasterKeyFingerprint: binary.LittleEndian.Uint32(origin[:4]),
+			Bip32Path:            path,
+		})
+	}
+	return derivations
+}
+
+// tapKeySig returns the BIP371 key path signature of a PSBT input, or nil if it has none.
+func tapKeySig(input psbt.PInput) []byte {
+	for _, unknown := range input.Unknowns {
+		if len(unknown.Key) == 1 && unknown.Key[0] == psbtInTapKeySig {
+			return unknown.Value
+		}
+	}
+	return nil
+}
+
+// signPSBTTaprootInput adds the BIP371 key path signature of a PSBT input paying to the BIP86 output key of a private
+// key, given the previous outputs of every input. Returns false if the input was already signed.
+func (wallet *HDWallet) signPSBTTaprootInput(updater *psbt.Updater, index int, prevouts []*wire.TxOut, priv *btcec.PrivateKey) (bool, error) {
+	input := &updater.Upsbt.Inputs[index]
+	if tapKeySig(*input) != nil {
+		return false, nil
+	}
+	if !bytes.Equal(prevouts[index].PkScript[2:], taprootOutputKey(priv.PubKey())) {
+		return false, errors.New("psbt input does not pay to the key's taproot output key")
+	}
+	tweaked, err := taprootTweakedPrivateKey(priv)
+	if err != nil {
+		return false, err
+	}
+	sigHash, err := taprootKeyPathSigHash(updater.Upsbt.UnsignedTx, index, prevouts)
+	if err != nil {
+		return false, err
+	}
+	aux, err := wallet.schnorrAuxRandomness()
+	if err != nil {
+		return false, err
+	}
+	sig, err := schnorrSign(tweaked, sigHash, aux)
+	if err != nil {
+		return false, err
+	}
+	input.Unknowns = append(input.Unknowns, &psbt.Unknown{Key: []byte{psbtInTapKeySig}, Value: sig})
+	return true, nil
+}
+
+// finalizePSBTTaprootInput finalizes an input spending a taproot output with its BIP371 key path signature, which
+// the psbt package cannot finalize. Other inputs are left untouched.
+func finalizePSBTTaprootInput(packet *psbt.Psbt, index int) error {
+	input := packet.Inputs[index]
+	sig := tapKeySig(input)
+	if sig == nil || input.FinalScriptWitness != nil {
+		return nil
+	}
+	prevout, err := psbtPrevout(packet, index)
+	if err != nil {
+		return err
+	}
+	if !isTaprootScript(prevout.PkScript) {
+		return errors.New("taproot key path signature on a non-taproot input")
+	}
+
+	var buf bytes.Buffer
+	if err := wire.WriteVarInt(&buf, 0, 1); err != nil {
+		return err
+	}
+	if err := wire.WriteVarBytes(&buf, 0, sig); err != nil {
+		return err
+	}
+	finalized := psbt.NewPsbtInput(nil, prevout)
+	finalized.FinalScriptWitness = buf.Bytes()
+	packet.Inputs[index] = *finalized
+	return nil
+}
+
+// psbtPrevout returns the previous output a PSBT input spends, from its witness utxo, or else its non-witness utxo
+// once checked to be the transaction the input spends from.
+func psbtPrevout(packet *psbt.Psbt, index int) (*wire.TxOut, error) {
+	input := packet.Inputs[index]
+	if input.WitnessUtxo != nil {
+		return input.WitnessUtxo, nil
+	}
+	if input.NonWitnessUtxo == nil {
+		return nil, errors.New("psbt input has no previous output")
+	}
+	outpoint := packet.UnsignedTx.TxIn[index].PreviousOutPoint
+	if input.NonWitnessUtxo.TxHash() != outpoint.Hash || int(outpoint.Index) >= len(input.NonWitnessUtxo.TxOut) {
+		return nil, errors.New("psbt non-witness utxo is not the transaction spent by its input")
+	}
+	return input.NonWitnessUtxo.TxOut[outpoint.Index], nil
+}
+
+// psbtPrevouts returns the previous output of every input of a PSBT, as taproot signature hashes commit to.
+func psbtPrevouts(packet *psbt.Psbt) ([]*wire.TxOut, error) {
+	prevouts := make([]*wire.TxOut, len(packet.Inputs))
+	for i := range packet.Inputs {
+		prevout, err := psbtPrevout(packet, i)
+		if err != nil {
+			return nil, err
+		}
+		prevouts[i] = prevout
+	}
+	return prevouts, nil
+}
+
+func appendUint32LE(b []byte, value uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], value)
+	return append(b, buf[:]...)
+}
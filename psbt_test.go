@@ -0,0 +1,327 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPSBT_HotColdPairing_ProducesSignedTransaction(t *testing.T) {
+	cold := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	assert.Nil(t, cold.SetRole(WalletRoleCold))
+
+	xpub, err := cold.AccountExtendedMasterPublicKey()
+	assert.Nil(t, err)
+	hot, err := NewHDWalletFromAccountExtendedPublicKey(xpub)
+	assert.Nil(t, err)
+	assert.Equal(t, WalletRoleHot, hot.Role())
+
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	// hot wallet cannot sign
+	_, err = hot.BuildTransactionMetadata(data.TransactionData)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+
+	unsigned, err := hot.ExportUnsignedPSBT(data.TransactionData)
+	assert.Nil(t, err)
+
+	_, err = hot.SignPSBT(unsigned, 5)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+
+	signed, err := cold.SignPSBT(unsigned, 5)
+	assert.Nil(t, err)
+
+	meta, err := hot.FinalizePSBT(signed)
+	assert.Nil(t, err)
+
+	// same as signing directly, since ECDSA signatures are deterministic
+	expected, err := cold.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Txid, meta.Txid)
+	assert.Equal(t, expected.EncodedTx, meta.EncodedTx)
+}
+
+func TestSetRole_HotDisablesKeyAPIs(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	assert.False(t, wallet.IsWatchOnly())
+
+	assert.Nil(t, wallet.SetRole(WalletRoleHot))
+	assert.True(t, wallet.IsWatchOnly())
+
+	_, err := wallet.SigningKey()
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+	_, err = wallet.SignatureSigningData([]byte("hi"))
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+
+	// addresses are still available
+	_, err = wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+
+	assert.Nil(t, wallet.SetRole(WalletRoleStandard))
+	_, err = wallet.SigningKey()
+	assert.Nil(t, err)
+
+	assert.NotNil(t, wallet.SetRole(5))
+}
+
+func TestSetRole_WatchOnlyWalletCannotBecomeCold(t *testing.T) {
+	wallet, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	assert.Equal(t, ErrWatchOnlyWallet, wallet.SetRole(WalletRoleCold))
+}
+
+func TestFingerprint_WatchOnlyWalletRequiresSetFingerprint(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	fingerprint, err := wallet.Fingerprint()
+	assert.Nil(t, err)
+	assert.Equal(t, "73c5da0a", fingerprint)
+	assert.EqualError(t, wallet.SetMasterFingerprint("01020304"), "master fingerprint does not match wallet")
+
+	xpub, err := wallet.AccountExtendedMasterPublicKey()
+	assert.Nil(t, err)
+	hot, err := NewHDWalletFromAccountExtendedPublicKey(xpub)
+	assert.Nil(t, err)
+	_, err = hot.Fingerprint()
+	assert.Equal(t, ErrMasterFingerprintUnknown, err)
+
+	assert.EqualError(t, hot.SetMasterFingerprint("73c5da"), "master fingerprint must be 4 bytes")
+	assert.Nil(t, hot.SetMasterFingerprint("73c5da0a"))
+	fingerprint, err = hot.Fingerprint()
+	assert.Nil(t, err)
+	assert.Equal(t, "73c5da0a", fingerprint)
+}
+
+func TestExportUnsignedPSBT_IncludesKeyOrigins(t *testing.T) {
+	cold := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	xpub, err := cold.AccountExtendedMasterPublicKey()
+	assert.Nil(t, err)
+	hot, err := NewHDWalletFromAccountExtendedPublicKey(xpub)
+	assert.Nil(t, err)
+	assert.Nil(t, hot.SetMasterFingerprint("73c5da0a"))
+
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	unsigned, err := hot.ExportUnsignedPSBT(data.TransactionData)
+	assert.Nil(t, err)
+
+	packet, err := psbt.NewPsbt([]byte(unsigned), true)
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(packet.Inputs[0].Bip32Derivation)) {
+		derivation := packet.Inputs[0].Bip32Derivation[0]
+		assert.Equal(t, uint32(0x0adac573), derivation.MasterKeyFingerprint)
+		assert.Equal(t, []uint32{hardened(84), hardened(0), hardened(0), 0, 1}, derivation.Bip32Path)
+		pubkey, err := hot.CompressedPubKeyForPath(path)
+		assert.Nil(t, err)
+		assert.Equal(t, pubkey, derivation.PubKey)
+	}
+	assert.Equal(t, 0, len(packet.Outputs[0].Bip32Derivation))
+	if assert.Equal(t, 1, len(packet.Outputs[1].Bip32Derivation)) {
+		assert.Equal(t, []uint32{hardened(84), hardened(0), hardened(0), 1, 1}, packet.Outputs[1].Bip32Derivation[0].Bip32Path)
+	}
+
+	signed, err := cold.SignPSBT(unsigned, 5)
+	assert.Nil(t, err)
+	meta, err := hot.FinalizePSBT(signed)
+	assert.Nil(t, err)
+	expected, err := cold.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.EncodedTx, meta.EncodedTx)
+}
+
+func TestSignPSBT_ByKeyOrigin(t *testing.T) {
+	cold := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	xpub, err := cold.AccountExtendedMasterPublicKey()
+	assert.Nil(t, err)
+	hot, err := NewHDWalletFromAccountExtendedPublicKey(xpub)
+	assert.Nil(t, err)
+	assert.Nil(t, hot.SetMasterFingerprint("73c5da0a"))
+
+	// an address beyond any gap limit the signer would scan
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1000)
+	pkScript, _, err := hot.prevoutScriptsForPath(path)
+	assert.Nil(t, err)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(pkScript, 96537)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	unsigned, err := hot.ExportUnsignedPSBT(data.TransactionData)
+	assert.Nil(t, err)
+	signed, err := cold.SignPSBT(unsigned, 0)
+	assert.Nil(t, err)
+	meta, err := hot.FinalizePSBT(signed)
+	assert.Nil(t, err)
+	expected, err := cold.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.EncodedTx, meta.EncodedTx)
+
+	// key origins of another master key, or naming a key the witness utxo does not pay to, are not signed
+	packet, err := psbt.NewPsbt([]byte(unsigned), true)
+	assert.Nil(t, err)
+	packet.Inputs[0].Bip32Derivation[0].MasterKeyFingerprint++
+	other, err := packet.B64Encode()
+	assert.Nil(t, err)
+	_, err = cold.SignPSBT(other, 0)
+	assert.NotNil(t, err)
+
+	packet.Inputs[0].Bip32Derivation[0].MasterKeyFingerprint--
+	packet.Inputs[0].Bip32Derivation[0].Bip32Path[4] = 999
+	packet.Inputs[0].Bip32Derivation[0].PubKey, _ = hot.CompressedPubKeyForPath(NewDerivationPath(BaseCoinBip84MainNet, 0, 999))
+	mismatched, err := packet.B64Encode()
+	assert.Nil(t, err)
+	_, err = cold.SignPSBT(mismatched, 0)
+	assert.NotNil(t, err)
+}
+
+func TestSignPSBT_WSHMultiDescriptor_SignsPartially(t *testing.T) {
+	first := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	second := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	foreign := "[deadbeef/48h/0h/0h/2h/0/0]02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9"
+	path := []uint32{hardened(48), hardened(0), hardened(0), hardened(2), 0, 0}
+
+	utxo := NewUTXO(descriptorTestTxid, 0, 100000, nil, nil, true)
+	descriptor := fmt.Sprintf("wsh(multi(2,%s,%s,%s))", testDescriptorKey(t, first, false, path...), foreign, testDescriptorKey(t, second, false, path...))
+	assert.Nil(t, utxo.SetDescriptor(descriptor))
+	setDescriptorPrevout(t, utxo)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataStandard("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 50000, 5, changePath, 590582, NewRBFOption(AllowedToBeRBF))
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	// neither wallet holds the threshold of keys alone
+	_, err := first.BuildTransactionMetadata(data.TransactionData)
+	assert.EqualError(t, err, "input 0: wallet holds 1 of 2 keys required by descriptor")
+
+	unsigned, err := first.ExportUnsignedPSBT(data.TransactionData)
+	assert.Nil(t, err)
+	partial, err := first.SignPSBT(unsigned, 0)
+	assert.Nil(t, err)
+	_, err = first.FinalizePSBT(partial)
+	assert.NotNil(t, err)
+
+	// signing again adds nothing
+	_, err = first.SignPSBT(partial, 0)
+	assert.NotNil(t, err)
+
+	signed, err := second.SignPSBT(partial, 0)
+	assert.Nil(t, err)
+	meta, err := first.FinalizePSBT(signed)
+	assert.Nil(t, err)
+
+	raw, err := hex.DecodeString(meta.EncodedTx)
+	assert.Nil(t, err)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	assert.Nil(t, tx.Deserialize(bytes.NewReader(raw)))
+	assert.Nil(t, validateMsgTx(tx, [][]byte{utxo.prevout.PkScript}, []btcutil.Amount{btcutil.Amount(utxo.Amount)}))
+}
+
+func TestPSBT_Taproot_HotColdPairing(t *testing.T) {
+	cold := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	cold.SetDeterministicSchnorrSignatures(true)
+
+	path := NewDerivationPath(BaseCoinBip86MainNet, 0, 1000)
+	pkScript, _, err := cold.prevoutScriptsForPath(path)
+	assert.Nil(t, err)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(pkScript, 96537)
+	changePath := NewDerivationPath(BaseCoinBip86MainNet, 1, 1)
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip86MainNet, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	unsigned, err := cold.ExportUnsignedPSBT(data.TransactionData)
+	assert.Nil(t, err)
+	packet, err := psbt.NewPsbt([]byte(unsigned), true)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(packet.Inputs[0].Bip32Derivation))
+	derivations := tapBip32Derivations(packet.Inputs[0])
+	if assert.Equal(t, 1, len(derivations)) {
+		xOnly, _ := cold.SchnorrPublicKeyForPath(path)
+		assert.Equal(t, xOnly, derivations[0].PubKey)
+		assert.Equal(t, uint32(0x0adac573), derivations[0].MasterKeyFingerprint)
+		assert.Equal(t, path.bip32Path(), derivations[0].Bip32Path)
+	}
+
+	// found by its BIP371 key origin, beyond the addresses scanned
+	signed, err := cold.SignPSBT(unsigned, 0)
+	assert.Nil(t, err)
+	_, err = cold.SignPSBT(signed, 0)
+	assert.NotNil(t, err)
+	meta, err := cold.FinalizePSBT(signed)
+	assert.Nil(t, err)
+	expected, err := cold.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.EncodedTx, meta.EncodedTx)
+}
+
+func TestPSBT_P2PKH_RequiresPreviousTransaction(t *testing.T) {
+	basecoin := NewBaseCoin(44, 0, 0)
+	wallet := NewHDWalletFromWords(w, basecoin)
+
+	path := NewDerivationPath(basecoin, 0, 1)
+	pkScript, _, err := wallet.prevoutScriptsForPath(path)
+	assert.Nil(t, err)
+	prevTx := wire.NewMsgTx(wire.TxVersion)
+	prevTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	prevTx.AddTxOut(wire.NewTxOut(1000, pkScript))
+	prevTx.AddTxOut(wire.NewTxOut(96537, pkScript))
+	var buf bytes.Buffer
+	assert.Nil(t, prevTx.Serialize(&buf))
+
+	utxo := NewUTXO(prevTx.TxHash().String(), 1, 96537, path, nil, true)
+	utxo.SetPrevout(pkScript, 96537)
+	changePath := NewDerivationPath(basecoin, 1, 1)
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", basecoin, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	_, err = wallet.ExportUnsignedPSBT(data.TransactionData)
+	assert.True(t, errors.Is(err, ErrPrevoutTxMissing))
+
+	assert.NotNil(t, NewUTXO(utxoManagerTestTxid, 1, 96537, path, nil, true).SetPreviousTransaction(hex.EncodeToString(buf.Bytes())))
+	assert.NotNil(t, NewUTXO(utxo.Txid, 2, 96537, path, nil, true).SetPreviousTransaction(hex.EncodeToString(buf.Bytes())))
+	assert.Nil(t, utxo.SetPreviousTransaction(hex.EncodeToString(buf.Bytes())))
+	unsigned, err := wallet.ExportUnsignedPSBT(data.TransactionData)
+	assert.Nil(t, err)
+	packet, err := psbt.NewPsbt([]byte(unsigned), true)
+	assert.Nil(t, err)
+	assert.Nil(t, packet.Inputs[0].WitnessUtxo)
+	assert.Equal(t, prevTx.TxHash(), packet.Inputs[0].NonWitnessUtxo.TxHash())
+
+	signed, err := wallet.SignPSBT(unsigned, 5)
+	assert.Nil(t, err)
+	meta, err := wallet.FinalizePSBT(signed)
+	assert.Nil(t, err)
+	expected, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.EncodedTx, meta.EncodedTx)
+
+	// a non-witness utxo other than the transaction spent is rejected
+	packet.Inputs[0].NonWitnessUtxo.LockTime++
+	tampered, err := packet.B64Encode()
+	assert.Nil(t, err)
+	_, err = wallet.SignPSBT(tampered, 5)
+	assert.NotNil(t, err)
+}
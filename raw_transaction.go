@@ -0,0 +1,212 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+/// Type Definitions
+
+// RawTransaction wraps a transaction in the network wire format, for tools that manipulate transactions directly.
+// Inputs and outputs are accessed one at a time, as gomobile does not support custom arrays/slices.
+type RawTransaction struct {
+	msgTx *wire.MsgTx
+}
+
+// RawTransactionInput is a single input of a RawTransaction.
+type RawTransactionInput struct {
+	Txid            string // txid of the previous output, in display (reversed) byte order
+	Index           int    // must be in UInt32 range
+	SignatureScript []byte
+	Sequence        int64 // must be in UInt32 range
+	witness         [][]byte
+}
+
+// RawTransactionOutput is a single output of a RawTransaction.
+type RawTransactionOutput struct {
+	Amount   int
+	PkScript []byte
+}
+
+/// Constructors
+
+// NewRawTransaction returns a pointer to an empty RawTransaction with the given version and locktime.
+func NewRawTransaction(version int, locktime int) (*RawTransaction, error) {
+	if locktime < 0 || int64(locktime) > math.MaxUint32 {
+		return nil, errors.New("Locktime out of bounds")
+	}
+	tx := wire.NewMsgTx(int32(version))
+	tx.LockTime = uint32(locktime)
+	return &RawTransaction{msgTx: tx}, nil
+}
+
+// DecodeRawTransaction decodes a hex-encoded transaction, with or without the segwit marker and flag.
+func DecodeRawTransaction(encodedTx string) (*RawTransaction, error) {
+	tx, err := decodeMsgTx(encodedTx)
+	if err != nil {
+		return nil, err
+	}
+	return &RawTransaction{msgTx: tx}, nil
+}
+
+// NewRawTransactionInput returns a pointer to an unsigned input spending a given previous output.
+func NewRawTransactionInput(txid string, index int, sequence int64) *RawTransactionInput {
+	return &RawTransactionInput{Txid: txid, Index: index, Sequence: sequence, witness: [][]byte{}}
+}
+
+// NewRawTransactionOutput returns a pointer to an output paying amount to pkScript.
+func NewRawTransactionOutput(amount int, pkScript []byte) *RawTransactionOutput {
+	return &RawTransactionOutput{Amount: amount, PkScript: pkScript}
+}
+
+/// Receiver functions
+
+// Version returns the transaction version.
+func (rt *RawTransaction) Version() int {
+	return int(rt.msgTx.Version)
+}
+
+// Locktime returns the transaction locktime.
+func (rt *RawTransaction) Locktime() int64 {
+	return int64(rt.msgTx.LockTime)
+}
+
+// Txid returns the transaction id, which excludes witness data.
+func (rt *RawTransaction) Txid() string {
+	return rt.msgTx.TxHash().String()
+}
+
+// Wtxid returns the witness transaction id, which equals Txid for transactions without witness data.
+func (rt *RawTransaction) Wtxid() string {
+	return rt.msgTx.WitnessHash().String()
+}
+
+// HasWitness returns true if any input carries witness data, in which case it is encoded with the segwit marker and flag.
+func (rt *RawTransaction) HasWitness() bool {
+	return rt.msgTx.HasWitness()
+}
+
+// InputCount returns the number of inputs.
+func (rt *RawTransaction) InputCount() int {
+	return len(rt.msgTx.TxIn)
+}
+
+// OutputCount returns the number of outputs.
+func (rt *RawTransaction) OutputCount() int {
+	return len(rt.msgTx.TxOut)
+}
+
+// InputAtIndex returns a copy of the input at a given index, or error if out of bounds.
+func (rt *RawTransaction) InputAtIndex(index int) (*RawTransactionInput, error) {
+	if index < 0 || index > len(rt.msgTx.TxIn)-1 {
+		return nil, errors.New("index must be within range of inputs")
+	}
+	txIn := rt.msgTx.TxIn[index]
+	input := RawTransactionInput{
+		Txid:            txIn.PreviousOutPoint.Hash.String(),
+		Index:           int(txIn.PreviousOutPoint.Index),
+		SignatureScript: txIn.SignatureScript,
+		Sequence:        int64(txIn.Sequence),
+		witness:         txIn.Witness,
+	}
+	return &input, nil
+}
+
+// OutputAtIndex returns a copy of the output at a given index, or error if out of bounds.
+func (rt *RawTransaction) OutputAtIndex(index int) (*RawTransactionOutput, error) {
+	if index < 0 || index > len(rt.msgTx.TxOut)-1 {
+		return nil, errors.New("index must be within range of outputs")
+	}
+	txOut := rt.msgTx.TxOut[index]
+	return NewRawTransactionOutput(int(txOut.Value), txOut.PkScript), nil
+}
+
+// AddInput appends an input to the transaction.
+func (rt *RawTransaction) AddInput(input *RawTransactionInput) error {
+	if input == nil {
+		return errors.New("input cannot be nil")
+	}
+	if input.Sequence < 0 || input.Sequence > math.MaxUint32 {
+		return errors.New("sequence out of bounds")
+	}
+	outpoint, err := outPointFromInfo(input.Txid, input.Index)
+	if err != nil {
+		return err
+	}
+	txIn := wire.NewTxIn(outpoint, input.SignatureScript, input.witness)
+	txIn.Sequence = uint32(input.Sequence)
+	rt.msgTx.AddTxIn(txIn)
+	return nil
+}
+
+// AddOutput appends an output to the transaction.
+func (rt *RawTransaction) AddOutput(output *RawTransactionOutput) error {
+	if output == nil {
+		return errors.New("output cannot be nil")
+	}
+	if output.Amount < 0 {
+		return errors.New("output amount cannot be negative")
+	}
+	rt.msgTx.AddTxOut(wire.NewTxOut(int64(output.Amount), output.PkScript))
+	return nil
+}
+
+// Encode returns the hex-encoded transaction, including witness data if present.
+func (rt *RawTransaction) Encode() (string, error) {
+	return encodeMsgTx(rt.msgTx)
+}
+
+// EncodeWithoutWitness returns the hex-encoded transaction in the legacy format, omitting the marker, flag and witnesses.
+func (rt *RawTransaction) EncodeWithoutWitness() (string, error) {
+	var buf bytes.Buffer
+	if err := rt.msgTx.SerializeNoWitness(&buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// WitnessItemCount returns the number of items in the input's witness stack.
+func (in *RawTransactionInput) WitnessItemCount() int {
+	return len(in.witness)
+}
+
+// WitnessItemAtIndex returns the witness stack item at a given index, or error if out of bounds.
+func (in *RawTransactionInput) WitnessItemAtIndex(index int) ([]byte, error) {
+	if index < 0 || index > len(in.witness)-1 {
+		return nil, errors.New("index must be within range of witness items")
+	}
+	return in.witness[index], nil
+}
+
+// AddWitnessItem appends an item to the input's witness stack.
+func (in *RawTransactionInput) AddWitnessItem(item []byte) {
+	in.witness = append(in.witness, item)
+}
+
+/// Unexported functions
+
+// decodeMsgTx decodes a hex-encoded transaction in the network wire format.
+func decodeMsgTx(encodedTx string) (*wire.MsgTx, error) {
+	txBytes, err := hex.DecodeString(encodedTx)
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// encodeMsgTx returns the hex-encoded transaction in the network wire format, including witnesses if present.
+func encodeMsgTx(tx *wire.MsgTx) (string, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,69 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeRawTransaction_Segwit_RoundTrip(t *testing.T) {
+	rt, err := DecodeRawTransaction(analysisEncodedTx)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, rt.Version())
+	assert.True(t, rt.HasWitness())
+	assert.Equal(t, "fe7f9a6de3203eb300cc66159e762251d675b5555dbd215c3574e75a762ca402", rt.Txid())
+	assert.NotEqual(t, rt.Txid(), rt.Wtxid())
+	assert.Equal(t, 1, rt.InputCount())
+	assert.Equal(t, 2, rt.OutputCount())
+
+	input, err := rt.InputAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", input.Txid)
+	assert.Equal(t, 2, input.WitnessItemCount())
+
+	output, err := rt.OutputAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 9755, output.Amount)
+
+	encoded, err := rt.Encode()
+	assert.Nil(t, err)
+	assert.Equal(t, analysisEncodedTx, encoded)
+
+	legacy, err := rt.EncodeWithoutWitness()
+	assert.Nil(t, err)
+	stripped, err := DecodeRawTransaction(legacy)
+	assert.Nil(t, err)
+	assert.False(t, stripped.HasWitness())
+	assert.Equal(t, rt.Txid(), stripped.Txid())
+}
+
+func TestRawTransaction_BuildFromParts(t *testing.T) {
+	rt, err := NewRawTransaction(2, 500000)
+	assert.Nil(t, err)
+
+	input := NewRawTransactionInput("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 0xfffffffd)
+	input.AddWitnessItem([]byte{0x01})
+	assert.Nil(t, rt.AddInput(input))
+
+	pkScript, _ := hex.DecodeString("0014933c5165df610846d08f026d18332610c13eef7f")
+	assert.Nil(t, rt.AddOutput(NewRawTransactionOutput(9755, pkScript)))
+
+	encoded, err := rt.Encode()
+	assert.Nil(t, err)
+	decoded, err := DecodeRawTransaction(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(500000), decoded.Locktime())
+
+	decodedInput, err := decoded.InputAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0xfffffffd), decodedInput.Sequence)
+	item, err := decodedInput.WitnessItemAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x01}, item)
+
+	assert.NotNil(t, rt.AddInput(NewRawTransactionInput("a89a", 0, -1)))
+	_, err = decoded.OutputAtIndex(1)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,100 @@
+package cnlib
+
+import (
+	"errors"
+	"time"
+)
+
+// defaultGapLimit is the BIP44 address gap limit; rotating past it risks funds not being found on restore.
+const defaultGapLimit = 20
+
+/// Type Definitions
+
+// ReceiveAddressPolicy decides which receive address to present to the user. The presented address rotates once it
+// has been used, as recorded by the wallet's UsedIndexTracker, or once it has been presented for longer than TTLSeconds.
+type ReceiveAddressPolicy struct {
+	tracker        *UsedIndexTracker
+	TTLSeconds     int64 // 0 disables time-based rotation
+	GapLimit       int   // maximum number of unused addresses time-based rotation may skip ahead
+	presentedIndex int
+	presentedAt    int64
+}
+
+// PresentedReceiveAddress is the receive address to show, with the index the backend should subscribe to.
+type PresentedReceiveAddress struct {
+	Address    string
+	Index      int
+	ExpiresAt  int64 // seconds since unix epoch, 0 if the address does not expire
+	DidRotate  bool  // true if this is a different address than previously presented
+	AtGapLimit bool  // true if the address expired but cannot rotate until used, as the gap limit was reached
+}
+
+/// Constructors
+
+// NewReceiveAddressPolicy returns a pointer to a ReceiveAddressPolicy backed by the wallet's UsedIndexTracker.
+func (wallet *HDWallet) NewReceiveAddressPolicy(ttlSeconds int64) *ReceiveAddressPolicy {
+	return &ReceiveAddressPolicy{
+		tracker:        wallet.UsedIndexTracker(),
+		TTLSeconds:     ttlSeconds,
+		GapLimit:       defaultGapLimit,
+		presentedIndex: -1,
+	}
+}
+
+/// Receiver functions
+
+// CurrentReceiveAddress returns the receive address to present now, rotating it if used or expired.
+func (p *ReceiveAddressPolicy) CurrentReceiveAddress() (*PresentedReceiveAddress, error) {
+	return p.currentReceiveAddressAt(time.Now().Unix())
+}
+
+/// Unexported functions
+
+func (p *ReceiveAddressPolicy) currentReceiveAddressAt(now int64) (*PresentedReceiveAddress, error) {
+	if p.TTLSeconds < 0 {
+		return nil, errors.New("ttl cannot be negative")
+	}
+
+	nextUnused := p.tracker.NextReceiveIndex()
+	index := p.presentedIndex
+	atGapLimit := false
+
+	if index < nextUnused {
+		// never presented, or presented address has been used
+		index = nextUnused
+	} else if p.isExpired(now) {
+		atGapLimit = index+1-nextUnused >= p.GapLimit
+		if !atGapLimit {
+			index++
+		}
+	}
+
+	didRotate := index != p.presentedIndex
+	if didRotate {
+		p.presentedIndex = index
+		p.presentedAt = now
+	}
+
+	meta, err := p.tracker.wallet.ReceiveAddressForIndex(index)
+	if err != nil {
+		return nil, err
+	}
+
+	// an address held at the gap limit no longer expires, rather than presenting an expiry in the past
+	expiresAt := int64(0)
+	if p.TTLSeconds > 0 && !atGapLimit {
+		expiresAt = p.presentedAt + p.TTLSeconds
+	}
+
+	return &PresentedReceiveAddress{
+		Address:    meta.Address,
+		Index:      index,
+		ExpiresAt:  expiresAt,
+		DidRotate:  didRotate,
+		AtGapLimit: atGapLimit,
+	}, nil
+}
+
+func (p *ReceiveAddressPolicy) isExpired(now int64) bool {
+	return p.TTLSeconds > 0 && now >= p.presentedAt+p.TTLSeconds
+}
@@ -0,0 +1,71 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiveAddressPolicy_RotatesAfterUse(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	policy := wallet.NewReceiveAddressPolicy(0)
+
+	first, err := policy.currentReceiveAddressAt(1000)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, first.Index)
+	assert.True(t, first.DidRotate)
+	assert.Equal(t, int64(0), first.ExpiresAt)
+
+	same, err := policy.currentReceiveAddressAt(5000)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Address, same.Address)
+	assert.False(t, same.DidRotate)
+
+	assert.Nil(t, wallet.UsedIndexTracker().MarkReceiveIndexUsed(0))
+
+	rotated, err := policy.currentReceiveAddressAt(5001)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rotated.Index)
+	assert.True(t, rotated.DidRotate)
+}
+
+func TestReceiveAddressPolicy_RotatesAfterTTL_WithinGapLimit(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	policy := wallet.NewReceiveAddressPolicy(60)
+	policy.GapLimit = 2
+
+	first, err := policy.currentReceiveAddressAt(1000)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1060), first.ExpiresAt)
+
+	notExpired, err := policy.currentReceiveAddressAt(1059)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, notExpired.Index)
+
+	expired, err := policy.currentReceiveAddressAt(1060)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, expired.Index)
+	assert.True(t, expired.DidRotate)
+	assert.Equal(t, int64(1120), expired.ExpiresAt)
+
+	// gap limit reached, keep presenting the same address
+	capped, err := policy.currentReceiveAddressAt(2000)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, capped.Index)
+	assert.False(t, capped.DidRotate)
+	assert.True(t, capped.AtGapLimit)
+	assert.Equal(t, int64(0), capped.ExpiresAt)
+	capped, err = policy.currentReceiveAddressAt(3000)
+	assert.Nil(t, err)
+	assert.True(t, capped.AtGapLimit)
+	assert.Equal(t, int64(0), capped.ExpiresAt)
+
+	// once used, the next address is presented with a fresh expiry
+	assert.Nil(t, wallet.UsedIndexTracker().MarkReceiveIndexUsed(1))
+	used, err := policy.currentReceiveAddressAt(3100)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, used.Index)
+	assert.True(t, used.DidRotate)
+	assert.False(t, used.AtGapLimit)
+	assert.Equal(t, int64(3160), used.ExpiresAt)
+}
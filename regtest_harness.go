@@ -0,0 +1,186 @@
+package cnlib
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// RegtestCoinbaseMaturity is the number of blocks a coinbase output must be buried under before it may be spent.
+const RegtestCoinbaseMaturity = 100
+
+// Following errors are returned by a RegtestHarness.
+var (
+	ErrRegtestHarnessNetwork = errors.New("regtest harness requires a test network wallet")
+	ErrRegtestNoSpendable    = errors.New("no mature utxos are available to spend")
+)
+
+/// Type Definitions
+
+// RegtestHarness builds and signs transactions for a wallet against regtest parameters, for integration tests run
+// against a local bitcoind. It tracks the wallet's utxos, and the chain tip, so coinbase outputs from
+// `generatetoaddress` are only spent once mature.
+type RegtestHarness struct {
+	wallet      *HDWallet
+	tipHeight   int
+	changeIndex int
+	utxos       []*regtestUTXO
+}
+
+// regtestUTXO is a utxo tracked by a RegtestHarness, with the height of the block that confirmed it, or 0 if
+// unconfirmed.
+type regtestUTXO struct {
+	utxo       *UTXO
+	height     int
+	isCoinbase bool
+}
+
+/// Constructors
+
+// NewRegtestHarness returns a RegtestHarness spending from a wallet of a test network BaseCoin, whose chain tip is
+// at the given height. Returns error if the wallet is watch-only, or is a mainnet wallet.
+func NewRegtestHarness(wallet *HDWallet, tipHeight int) (*RegtestHarness, error) {
+	if wallet == nil || !wallet.BaseCoin.isTestNet() {
+		return nil, ErrRegtestHarnessNetwork
+	}
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+	if tipHeight < 0 {
+		return nil, errors.New("tip height cannot be negative")
+	}
+	return &RegtestHarness{wallet: wallet, tipHeight: tipHeight, utxos: []*regtestUTXO{}}, nil
+}
+
+/// Exported functions
+
+// RegtestBlockSubsidy returns the subsidy, in satoshis, of a regtest block at a given height, which halves every 150
+// blocks rather than mainnet's 210,000.
+func RegtestBlockSubsidy(height int) int64 {
+	return blockchain.CalcBlockSubsidy(int32(height), &chaincfg.RegressionNetParams)
+}
+
+/// Receiver functions
+
+// TipHeight returns the height of the chain tip the harness builds transactions against.
+func (h *RegtestHarness) TipHeight() int {
+	return h.tipHeight
+}
+
+// SetTipHeight updates the height of the chain tip, such as after mining blocks with `generatetoaddress`.
+func (h *RegtestHarness) SetTipHeight(height int) error {
+	if height < 0 {
+		return errors.New("tip height cannot be negative")
+	}
+	h.tipHeight = height
+	return nil
+}
+
+// AddUTXO tracks an output paying to the wallet's receive or change address at a given index, confirmed at a block
+// height, or 0 if unconfirmed. The output's script is derived from the address, so the utxo is ready to sign.
+func (h *RegtestHarness) AddUTXO(txid string, index int, amount int64, change int, addressIndex int, height int) error {
+	return h.addUTXO(txid, index, amount, change, addressIndex, height, false)
+}
+
+// AddCoinbaseUTXO tracks a coinbase output paying to the wallet's receive address at a given index, mined at a block
+// height. It is only spent once `CoinbaseBlocksToMaturity` returns 0.
+func (h *RegtestHarness) AddCoinbaseUTXO(txid string, index int, amount int64, addressIndex int, height int) error {
+	if height < 1 {
+		return errors.New("coinbase height must be at least 1")
+	}
+	return h.addUTXO(txid, index, amount, 0, addressIndex, height, true)
+}
+
+// CoinbaseBlocksToMaturity returns the number of blocks to mine before a coinbase output mined at a given height can
+// be spent in the next block, or 0 if it is spendable now. bitcoind's wallet reports such outputs as immature for one
+// block longer than consensus requires.
+func (h *RegtestHarness) CoinbaseBlocksToMaturity(height int) int {
+	remaining := height + RegtestCoinbaseMaturity - (h.tipHeight + 1)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// SpendableBalance returns the total, in satoshis, of the utxos which may be spent in the next block.
+func (h *RegtestHarness) SpendableBalance() int64 {
+	return totalUTXOAmount(h.spendableUTXOs())
+}
+
+// SpendableUTXOCount returns the number of utxos which may be spent in the next block.
+func (h *RegtestHarness) SpendableUTXOCount() int {
+	return len(h.spendableUTXOs())
+}
+
+// BuildTransaction selects from the spendable utxos, and returns a signed transaction paying an amount to a regtest
+// address at a fee rate, with its locktime set to the tip height. Change is sent to the wallet's next unused change
+// address. Spent utxos remain tracked until `RemoveUTXO` is called, so a failed broadcast can be retried.
+func (h *RegtestHarness) BuildTransaction(address string, amount int, feeRate int) (*TransactionMetadata, error) {
+	spendable := h.spendableUTXOs()
+	if len(spendable) == 0 {
+		return nil, ErrRegtestNoSpendable
+	}
+	changePath := NewDerivationPath(h.wallet.BaseCoin, 1, h.changeIndex)
+	data := NewTransactionDataStandard(address, h.wallet.BaseCoin, amount, feeRate, changePath, h.tipHeight, NewRBFOption(AllowedToBeRBF))
+	for _, utxo := range spendable {
+		data.AddUTXO(utxo)
+	}
+	if err := data.Generate(); err != nil {
+		return nil, err
+	}
+	meta, err := h.wallet.BuildTransactionMetadata(data.TransactionData)
+	if err != nil {
+		return nil, err
+	}
+	if meta.TransactionChangeMetadata != nil {
+		h.changeIndex++
+	}
+	return meta, nil
+}
+
+// RemoveUTXO stops tracking a utxo, such as after a transaction spending it is broadcast.
+func (h *RegtestHarness) RemoveUTXO(txid string, index int) {
+	remaining := []*regtestUTXO{}
+	for _, tracked := range h.utxos {
+		if tracked.utxo.Txid != txid || tracked.utxo.Index != index {
+			remaining = append(remaining, tracked)
+		}
+	}
+	h.utxos = remaining
+}
+
+/// Unexported functions
+
+func (h *RegtestHarness) addUTXO(txid string, index int, amount int64, change int, addressIndex int, height int, isCoinbase bool) error {
+	if _, err := outPointFromInfo(txid, index); err != nil {
+		return err
+	}
+	if height < 0 {
+		return errors.New("utxo height cannot be negative")
+	}
+	meta, err := h.wallet.deriveAddressForIndex(change, addressIndex)
+	if err != nil {
+		return err
+	}
+	script, err := pkScriptForNetworkAddress(meta.Address, h.wallet.BaseCoin.defaultNetParams())
+	if err != nil {
+		return err
+	}
+	utxo := NewUTXO(txid, index, amount, meta.DerivationPath, nil, height > 0)
+	utxo.SetPrevout(script, amount)
+	h.utxos = append(h.utxos, &regtestUTXO{utxo: utxo, height: height, isCoinbase: isCoinbase})
+	return nil
+}
+
+// spendableUTXOs returns the tracked utxos, excluding coinbase outputs which are not mature in the next block.
+func (h *RegtestHarness) spendableUTXOs() []*UTXO {
+	utxos := []*UTXO{}
+	for _, tracked := range h.utxos {
+		if tracked.isCoinbase && h.CoinbaseBlocksToMaturity(tracked.height) > 0 {
+			continue
+		}
+		utxos = append(utxos, tracked.utxo)
+	}
+	return utxos
+}
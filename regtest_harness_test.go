@@ -0,0 +1,78 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const regtestCoinbaseTxid = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+
+func TestNewRegtestHarness_RequiresTestNetwork(t *testing.T) {
+	_, err := NewRegtestHarness(NewHDWalletFromWords(w, BaseCoinBip84MainNet), 0)
+	assert.Equal(t, ErrRegtestHarnessNetwork, err)
+
+	_, err = NewRegtestHarness(NewHDWalletFromWords(w, BaseCoinBip84TestNet), -1)
+	assert.NotNil(t, err)
+}
+
+func TestRegtestBlockSubsidy(t *testing.T) {
+	assert.Equal(t, int64(5000000000), RegtestBlockSubsidy(1))
+	assert.Equal(t, int64(5000000000), RegtestBlockSubsidy(149))
+	assert.Equal(t, int64(2500000000), RegtestBlockSubsidy(150))
+}
+
+func TestRegtestHarness_CoinbaseMaturity(t *testing.T) {
+	harness, err := NewRegtestHarness(NewHDWalletFromWords(w, BaseCoinBip84TestNet), 99)
+	assert.Nil(t, err)
+	assert.Nil(t, harness.AddCoinbaseUTXO(regtestCoinbaseTxid, 0, RegtestBlockSubsidy(1), 0, 1))
+
+	assert.Equal(t, 1, harness.CoinbaseBlocksToMaturity(1))
+	assert.Equal(t, 0, harness.SpendableUTXOCount())
+	_, err = harness.BuildTransaction("bcrt1q6rz28mcfaxtmd6v789l9rrlrusdprr9pz3cppk", 100000, 2)
+	assert.Equal(t, ErrRegtestNoSpendable, err)
+
+	assert.Nil(t, harness.SetTipHeight(100))
+	assert.Equal(t, 0, harness.CoinbaseBlocksToMaturity(1))
+	assert.Equal(t, 1, harness.SpendableUTXOCount())
+	assert.Equal(t, RegtestBlockSubsidy(1), harness.SpendableBalance())
+}
+
+func TestRegtestHarness_BuildTransaction(t *testing.T) {
+	for _, basecoin := range []*BaseCoin{BaseCoinBip49TestNet, BaseCoinBip84TestNet, BaseCoinBip86TestNet} {
+		wallet := NewHDWalletFromWords(w, basecoin)
+		harness, err := NewRegtestHarness(wallet, 101)
+		assert.Nil(t, err)
+		assert.Nil(t, harness.AddCoinbaseUTXO(regtestCoinbaseTxid, 0, RegtestBlockSubsidy(1), 0, 1))
+		assert.Nil(t, harness.AddCoinbaseUTXO(regtestCoinbaseTxid, 1, RegtestBlockSubsidy(90), 1, 90))
+
+		meta, err := harness.BuildTransaction("bcrt1q6rz28mcfaxtmd6v789l9rrlrusdprr9pz3cppk", 100000, 2)
+		assert.Nil(t, err)
+		assert.NotNil(t, meta.TransactionChangeMetadata)
+		assert.Equal(t, 1, meta.TransactionChangeMetadata.Path.Change)
+		assert.Equal(t, 0, meta.TransactionChangeMetadata.Path.Index)
+
+		tx, err := DecodeRawTransaction(meta.EncodedTx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, tx.InputCount())
+		assert.Equal(t, int64(101), tx.Locktime())
+
+		input, err := tx.InputAtIndex(0)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, input.Index)
+
+		next, err := harness.BuildTransaction("bcrt1q6rz28mcfaxtmd6v789l9rrlrusdprr9pz3cppk", 100000, 2)
+		assert.Nil(t, err)
+		assert.NotEqual(t, meta.TransactionChangeMetadata.Address, next.TransactionChangeMetadata.Address)
+	}
+}
+
+func TestRegtestHarness_RemoveUTXO(t *testing.T) {
+	harness, err := NewRegtestHarness(NewHDWalletFromWords(w, BaseCoinBip84TestNet), 200)
+	assert.Nil(t, err)
+	assert.Nil(t, harness.AddUTXO(regtestCoinbaseTxid, 3, 50000, 1, 2, 0))
+	assert.Equal(t, 1, harness.SpendableUTXOCount())
+
+	harness.RemoveUTXO(regtestCoinbaseTxid, 3)
+	assert.Equal(t, 0, harness.SpendableUTXOCount())
+}
@@ -0,0 +1,100 @@
+package cnlib
+
+import (
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// locktimeThreshold is the locktime value below which a locktime is a block height, and at or above which it is a
+// unix timestamp.
+const locktimeThreshold = txscript.LockTimeThreshold
+
+/// Type Definitions
+
+// ReplaceabilityReport describes whether a transaction can be replaced or fee-bumped by the wallet, and the earliest
+// point at which it can be mined.
+type ReplaceabilityReport struct {
+	Txid            string
+	SignalsRBF      bool  // true if any input explicitly signals BIP125 replaceability; inherited signaling is not detected
+	LocktimeEnabled bool  // true if the locktime is non-zero and enforced by at least one input's sequence
+	MinBlockHeight  int   // lowest height of a block which may include the transaction, 0 if not height-locked
+	MinTimestamp    int64 // median time past a block must exceed to include the transaction, 0 if not time-locked
+	OwnsInput       bool  // true if any input spends a previous output belonging to the wallet
+	OwnsOutput      bool  // true if any output pays to a wallet address
+	CanBumpFeeRBF   bool  // true if the wallet can replace the transaction with a higher fee version
+	CanBumpFeeCPFP  bool  // true if the wallet can spend one of its outputs in a higher fee child transaction
+}
+
+/// Receiver functions
+
+// CanBumpFee returns true if the wallet can raise the transaction's effective fee rate by either RBF or CPFP.
+func (r *ReplaceabilityReport) CanBumpFee() bool {
+	return r.CanBumpFeeRBF || r.CanBumpFeeCPFP
+}
+
+// IsFinalAt returns true if the transaction's locktime allows it to be included in a block at a given height, whose
+// previous blocks have a given median time past.
+func (r *ReplaceabilityReport) IsFinalAt(blockHeight int, medianTimePast int64) bool {
+	if !r.LocktimeEnabled {
+		return true
+	}
+	if r.MinBlockHeight > 0 {
+		return blockHeight >= r.MinBlockHeight
+	}
+	return medianTimePast >= r.MinTimestamp
+}
+
+// InspectReplaceability reports on a hex-encoded transaction's BIP125 signaling and locktime, and whether the wallet
+// can fee-bump it by comparing against the wallet's receive and change addresses up to a given index. Previous outputs
+// are optional, but without them the wallet cannot tell whether it owns an input, so `CanBumpFeeRBF` will be false.
+func (wallet *HDWallet) InspectReplaceability(encodedTx string, prevouts *PrevoutSet, upTo int) (*ReplaceabilityReport, error) {
+	tx, err := decodeMsgTx(encodedTx)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := wallet.derivedAddressMap(upTo)
+	if err != nil {
+		return nil, err
+	}
+
+	if prevouts == nil {
+		prevouts = NewPrevoutSet()
+	}
+
+	report := ReplaceabilityReport{Txid: tx.TxHash().String()}
+
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence < wire.MaxTxInSequenceNum-1 {
+			report.SignalsRBF = true
+		}
+		if txIn.Sequence != wire.MaxTxInSequenceNum && tx.LockTime > 0 {
+			report.LocktimeEnabled = true
+		}
+		if info, ok := prevouts.prevouts[txIn.PreviousOutPoint]; ok {
+			if _, owned := known[info.SelectedAddress]; owned {
+				report.OwnsInput = true
+			}
+		}
+	}
+
+	if report.LocktimeEnabled {
+		// a locktime is the last height or time at which the transaction is invalid
+		if tx.LockTime < locktimeThreshold {
+			report.MinBlockHeight = int(tx.LockTime) + 1
+		} else {
+			report.MinTimestamp = int64(tx.LockTime) + 1
+		}
+	}
+
+	for _, txOut := range tx.TxOut {
+		if _, ok := known[pkScriptAddress(txOut.PkScript, wallet.BaseCoin.defaultNetParams())]; ok {
+			report.OwnsOutput = true
+		}
+	}
+
+	report.CanBumpFeeRBF = report.SignalsRBF && report.OwnsInput
+	report.CanBumpFeeCPFP = report.OwnsOutput
+
+	return &report, nil
+}
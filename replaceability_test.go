@@ -0,0 +1,78 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspectReplaceability_OwnedRBFTransaction(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	source, err := wallet.ReceiveAddressForIndex(1)
+	assert.Nil(t, err)
+
+	prevouts := NewPrevoutSet()
+	err = prevouts.AddPrevout(NewPreviousOutputInfo(source.Address, "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537))
+	assert.Nil(t, err)
+
+	report, err := wallet.InspectReplaceability(analysisEncodedTx, prevouts, 5)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "fe7f9a6de3203eb300cc66159e762251d675b5555dbd215c3574e75a762ca402", report.Txid)
+	assert.True(t, report.SignalsRBF)
+	assert.True(t, report.LocktimeEnabled)
+	assert.Equal(t, 590583, report.MinBlockHeight)
+	assert.Equal(t, int64(0), report.MinTimestamp)
+	assert.True(t, report.OwnsInput)
+	assert.True(t, report.OwnsOutput)
+	assert.True(t, report.CanBumpFeeRBF)
+	assert.True(t, report.CanBumpFeeCPFP)
+	assert.True(t, report.CanBumpFee())
+
+	assert.False(t, report.IsFinalAt(590582, 0))
+	assert.True(t, report.IsFinalAt(590583, 0))
+}
+
+func TestInspectReplaceability_WithoutPrevoutsOnlyCPFP(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+
+	report, err := wallet.InspectReplaceability(analysisEncodedTx, nil, 5)
+	assert.Nil(t, err)
+	assert.False(t, report.OwnsInput)
+	assert.False(t, report.CanBumpFeeRBF)
+	assert.True(t, report.CanBumpFeeCPFP)
+}
+
+func TestInspectReplaceability_FinalNonRBFTransaction(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+
+	rt, err := NewRawTransaction(2, 1600000000)
+	assert.Nil(t, err)
+	assert.Nil(t, rt.AddInput(NewRawTransactionInput("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 0xfffffffe)))
+	pkScript, err := P2WPKHScript(make([]byte, 20))
+	assert.Nil(t, err)
+	assert.Nil(t, rt.AddOutput(NewRawTransactionOutput(5000, pkScript)))
+	encoded, err := rt.Encode()
+	assert.Nil(t, err)
+
+	report, err := wallet.InspectReplaceability(encoded, nil, 5)
+	assert.Nil(t, err)
+	assert.False(t, report.SignalsRBF)
+	assert.True(t, report.LocktimeEnabled)
+	assert.Equal(t, 0, report.MinBlockHeight)
+	assert.Equal(t, int64(1600000001), report.MinTimestamp)
+	assert.False(t, report.CanBumpFee())
+	assert.False(t, report.IsFinalAt(700000, 1600000000))
+	assert.True(t, report.IsFinalAt(700000, 1600000001))
+}
+
+func TestInspectReplaceability_BIP86TaprootChange_CanBumpFeeCPFP(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	change, err := wallet.ChangeAddressForIndex(1)
+	assert.Nil(t, err)
+
+	report, err := wallet.InspectReplaceability(newTestTaprootWalletTx(t, descriptorTestTxid, change.Address), nil, 5)
+	assert.Nil(t, err)
+	assert.True(t, report.OwnsOutput)
+	assert.True(t, report.CanBumpFeeCPFP)
+}
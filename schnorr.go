@@ -0,0 +1,223 @@
+package cnlib
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+const (
+	schnorrAuxTag       = "BIP0340/aux"
+	schnorrNonceTag     = "BIP0340/nonce"
+	schnorrChallengeTag = "BIP0340/challenge"
+
+	schnorrDigestSize    = 32
+	schnorrPubKeySize    = 32
+	schnorrSignatureSize = 64
+)
+
+/// Receiver functions
+
+// SchnorrPublicKeyForPath returns the 32-byte x-only public key, as used by BIP340, for a given derivation path.
+func (wallet *HDWallet) SchnorrPublicKeyForPath(path *DerivationPath) ([]byte, error) {
+	key, err := wallet.publicKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return padTo32(key.X.Bytes()), nil
+}
+
+// SchnorrSignDigest signs a 32-byte digest using BIP340 Schnorr with the key derived from a given derivation path,
+// and returns the 64-byte signature. Returns ErrKeyRoleMismatch for spending or encryption key paths, as an arbitrary
+// digest could be a transaction's.
+func (wallet *HDWallet) SchnorrSignDigest(path *DerivationPath, digest []byte) ([]byte, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+
+	if path == nil {
+		return nil, errors.New("derivation path cannot be nil")
+	}
+	if err := rejectKeyRoles(path, KeyRoleSpending, KeyRoleEncryption); err != nil {
+		return nil, err
+	}
+
+	kf := wallet.keyFactory()
+	pk, err := kf.indexPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ecpk, err := pk.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	aux, err := randBytes(32)
+	if err != nil {
+		return nil, err
+	}
+
+	return schnorrSign(ecpk, digest, aux)
+}
+
+/// Exported functions
+
+// VerifySchnorrSignature verifies a 64-byte BIP340 signature over a 32-byte digest against a 32-byte x-only public key.
+// Returns nil if valid, or error if invalid.
+func VerifySchnorrSignature(pubkey []byte, digest []byte, signature []byte) error {
+	return schnorrVerify(pubkey, digest, signature)
+}
+
+/// Unexported functions
+
+// schnorrSign implements BIP340 signing with the given auxiliary randomness.
+func schnorrSign(privKey *btcec.PrivateKey, digest []byte, aux []byte) ([]byte, error) {
+	if len(digest) != schnorrDigestSize {
+		return nil, errors.New("digest must be 32 bytes")
+	}
+	if len(aux) != 32 {
+		return nil, errors.New("auxiliary randomness must be 32 bytes")
+	}
+
+	curve := btcec.S256()
+	n := curve.N
+
+	d := new(big.Int).Set(privKey.D)
+	if d.Sign() == 0 || d.Cmp(n) >= 0 {
+		return nil, errors.New("invalid private key")
+	}
+	px, py := curve.ScalarBaseMult(padTo32(d.Bytes()))
+	if py.Bit(0) == 1 {
+		d.Sub(n, d)
+	}
+	pxBytes := padTo32(px.Bytes())
+
+	t := padTo32(d.Bytes())
+	auxHash := taggedHash(schnorrAuxTag, aux)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+
+	nonceData := append(append(t, pxBytes...), digest...)
+	k := new(big.Int).SetBytes(taggedHash(schnorrNonceTag, nonceData))
+	k.Mod(k, n)
+	if k.Sign() == 0 {
+		return nil, errors.New("failed to generate nonce")
+	}
+
+	rx, ry := curve.ScalarBaseMult(padTo32(k.Bytes()))
+	if ry.Bit(0) == 1 {
+		k.Sub(n, k)
+	}
+	rxBytes := padTo32(rx.Bytes())
+
+	e := schnorrChallenge(rxBytes, pxBytes, digest)
+
+	s := new(big.Int).Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, n)
+
+	sig := append(rxBytes, padTo32(s.Bytes())...)
+
+	if err := schnorrVerify(pxBytes, digest, sig); err != nil {
+		return nil, errors.New("failed to sign data")
+	}
+
+	return sig, nil
+}
+
+// schnorrVerify implements BIP340 verification.
+func schnorrVerify(pubkey []byte, digest []byte, signature []byte) error {
+	if len(pubkey) != schnorrPubKeySize {
+		return errors.New("public key must be 32 bytes")
+	}
+	if len(digest) != schnorrDigestSize {
+		return errors.New("digest must be 32 bytes")
+	}
+	if len(signature) != schnorrSignatureSize {
+		return errors.New("signature must be 64 bytes")
+	}
+
+	curve := btcec.S256()
+
+	px, py, err := liftX(pubkey)
+	if err != nil {
+		return err
+	}
+
+	r := new(big.Int).SetBytes(signature[:32])
+	if r.Cmp(curve.P) >= 0 {
+		return errors.New("invalid signature")
+	}
+	s := new(big.Int).SetBytes(signature[32:])
+	if s.Cmp(curve.N) >= 0 {
+		return errors.New("invalid signature")
+	}
+
+	e := schnorrChallenge(signature[:32], pubkey, digest)
+
+	// R = s*G - e*P
+	sgx, sgy := curve.ScalarBaseMult(signature[32:])
+	epx, epy := curve.ScalarMult(px, py, padTo32(e.Bytes()))
+	epy.Sub(curve.P, epy)
+	rx, ry := curve.Add(sgx, sgy, epx, epy)
+
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return errors.New("invalid signature")
+	}
+	if ry.Bit(0) == 1 || rx.Cmp(r) != 0 {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// schnorrChallenge returns the BIP340 challenge hash as an integer mod n.
+func schnorrChallenge(rx []byte, px []byte, digest []byte) *big.Int {
+	data := make([]byte, 0, 96)
+	data = append(data, rx...)
+	data = append(data, px...)
+	data = append(data, digest...)
+	e := new(big.Int).SetBytes(taggedHash(schnorrChallengeTag, data))
+	return e.Mod(e, btcec.S256().N)
+}
+
+// liftX returns the point with the given x coordinate and an even y coordinate, or error if none exists.
+func liftX(xBytes []byte) (*big.Int, *big.Int, error) {
+	curve := btcec.S256()
+	p := curve.P
+
+	x := new(big.Int).SetBytes(xBytes)
+	if x.Cmp(p) >= 0 {
+		return nil, nil, errors.New("public key not on curve")
+	}
+
+	// c = x^3 + 7 mod p
+	c := new(big.Int).Exp(x, big.NewInt(3), p)
+	c.Add(c, curve.B)
+	c.Mod(c, p)
+
+	// y = c^((p+1)/4) mod p
+	exp := new(big.Int).Add(p, big.NewInt(1))
+	exp.Rsh(exp, 2)
+	y := new(big.Int).Exp(c, exp, p)
+
+	if new(big.Int).Exp(y, big.NewInt(2), p).Cmp(c) != 0 {
+		return nil, nil, errors.New("public key not on curve")
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(p, y)
+	}
+	return x, y, nil
+}
+
+// padTo32 left-pads a big-endian byte slice with zeroes to 32 bytes.
+func padTo32(b []byte) []byte {
+	if len(b) >= 32 {
+		return b
+	}
+	padded := make([]byte, 32)
+	copy(padded[32-len(b):], b)
+	return padded
+}
@@ -0,0 +1,89 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeHexUpper(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(strings.ToLower(s))
+	assert.Nil(t, err)
+	return b
+}
+
+func TestSchnorrSign_BIP340Vectors(t *testing.T) {
+	vectors := []struct {
+		secKey, pubKey, aux, msg, sig string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000003",
+			"F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		},
+		{
+			"B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+			"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			"6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		},
+	}
+
+	for _, v := range vectors {
+		privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), decodeHexUpper(t, v.secKey))
+		sig, err := schnorrSign(privKey, decodeHexUpper(t, v.msg), decodeHexUpper(t, v.aux))
+		assert.Nil(t, err)
+		assert.Equal(t, strings.ToLower(v.sig), hex.EncodeToString(sig))
+		assert.Nil(t, VerifySchnorrSignature(decodeHexUpper(t, v.pubKey), decodeHexUpper(t, v.msg), sig))
+	}
+}
+
+func TestVerifySchnorrSignature_BIP340Vectors(t *testing.T) {
+	// vector 4, valid
+	err := VerifySchnorrSignature(
+		decodeHexUpper(t, "D69C3509BB99E412E68B0FE8544E72837DFA30746D8BE2AA65975F29D22DC7B9"),
+		decodeHexUpper(t, "4DF3C3F68FCC83B27E9D42C90431A72499F17875C81A599B566C9889B9696703"),
+		decodeHexUpper(t, "00000000000000000000003B78CE563F89A0ED9414F5AA28AD0D96D6795F9C6376AFB1548AF603B3EB45C9F8207DEE1060CB71C04E80F593060B07D28308D7F4"),
+	)
+	assert.Nil(t, err)
+
+	// vector 5, public key not on the curve
+	err = VerifySchnorrSignature(
+		decodeHexUpper(t, "EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34"),
+		decodeHexUpper(t, "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89"),
+		decodeHexUpper(t, "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B"),
+	)
+	assert.NotNil(t, err)
+}
+
+func TestSchnorrSignDigest_WithWalletKey(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	path, _ := ParseCustomDerivationPath("m/1000'/0'/0")
+	digest := make([]byte, 32)
+	digest[0] = 1
+
+	sig, err := wallet.SchnorrSignDigest(path, digest)
+	assert.Nil(t, err)
+	assert.Equal(t, 64, len(sig))
+
+	pubkey, err := wallet.SchnorrPublicKeyForPath(path)
+	assert.Nil(t, err)
+	assert.Nil(t, VerifySchnorrSignature(pubkey, digest, sig))
+
+	digest[0] = 2
+	assert.NotNil(t, VerifySchnorrSignature(pubkey, digest, sig))
+
+	_, err = wallet.SchnorrSignDigest(path, []byte("too short"))
+	assert.NotNil(t, err)
+
+	// spending keys cannot sign arbitrary digests
+	digest[0] = 1
+	_, err = wallet.SchnorrSignDigest(NewDerivationPath(BaseCoinBip84MainNet, 0, 0), digest)
+	assert.Equal(t, ErrKeyRoleMismatch, err)
+}
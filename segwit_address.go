@@ -0,0 +1,156 @@
+package cnlib
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+// BIP173 and BIP350 segwit address encoding. The btcutil version in use only knows bech32 and witness version 0, so
+// witness version 1 and later addresses, which use bech32m, are handled here.
+
+const (
+	bech32Const      = 1
+	bech32mConst     = 0x2bc830a3
+	bech32Charset    = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32MaxLength  = 90
+	bech32ChecksumSz = 6
+
+	maxWitnessVersion       = 16
+	minWitnessProgramLength = 2
+	maxWitnessProgramLength = 40
+)
+
+// Following errors are returned for malformed segwit addresses.
+var (
+	ErrSegwitAddressEncoding = errors.New("address is not a bech32 or bech32m encoded segwit address")
+	ErrSegwitAddressChecksum = errors.New("segwit address checksum is invalid, or the wrong encoding for its witness version")
+	ErrSegwitAddressProgram  = errors.New("segwit address witness version or program length is invalid")
+)
+
+/// Unexported functions
+
+// decodeSegwitAddress returns the witness version and program of a segwit address with a given HRP, validated per
+// BIP350: version 0 addresses must use bech32 and have a 20 or 32 byte program, later versions must use bech32m and
+// have a 2 to 40 byte program.
+func decodeSegwitAddress(address string, hrp string) (int, []byte, error) {
+	if len(address) > bech32MaxLength || (strings.ToLower(address) != address && strings.ToUpper(address) != address) {
+		return 0, nil, ErrSegwitAddressEncoding
+	}
+	lower := strings.ToLower(address)
+	prefix := hrp + "1"
+	if !strings.HasPrefix(lower, prefix) {
+		return 0, nil, ErrSegwitAddressEncoding
+	}
+
+	data := make([]byte, 0, len(lower)-len(prefix))
+	for _, c := range lower[len(prefix):] {
+		i := strings.IndexRune(bech32Charset, c)
+		if i < 0 {
+			return 0, nil, ErrSegwitAddressEncoding
+		}
+		data = append(data, byte(i))
+	}
+	if len(data) < 1+bech32ChecksumSz {
+		return 0, nil, ErrSegwitAddressEncoding
+	}
+
+	version := int(data[0])
+	if version > maxWitnessVersion {
+		return 0, nil, ErrSegwitAddressProgram
+	}
+	expectedConst := bech32mConst
+	if version == 0 {
+		expectedConst = bech32Const
+	}
+	if bech32Polymod(hrp, data) != expectedConst {
+		return 0, nil, ErrSegwitAddressChecksum
+	}
+
+	program, err := bech32.ConvertBits(data[1:len(data)-bech32ChecksumSz], 5, 8, false)
+	if err != nil {
+		return 0, nil, ErrSegwitAddressEncoding
+	}
+	if len(program) < minWitnessProgramLength || len(program) > maxWitnessProgramLength {
+		return 0, nil, ErrSegwitAddressProgram
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return 0, nil, ErrSegwitAddressProgram
+	}
+	return version, program, nil
+}
+
+// encodeSegwitAddress returns the segwit address of a witness version and program, in bech32 for version 0 and
+// bech32m for later versions.
+func encodeSegwitAddress(hrp string, version int, program []byte) (string, error) {
+	if version < 0 || version > maxWitnessVersion || len(program) < minWitnessProgramLength || len(program) > maxWitnessProgramLength {
+		return "", ErrSegwitAddressProgram
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return "", ErrSegwitAddressProgram
+	}
+	converted, err := bech32.ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	checksumConst := bech32mConst
+	if version == 0 {
+		checksumConst = bech32Const
+	}
+	data := append([]byte{byte(version)}, converted...)
+	data = append(data, bech32ChecksumFor(hrp, data, checksumConst)...)
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteString("1")
+	for _, b := range data {
+		sb.WriteByte(bech32Charset[b])
+	}
+	return sb.String(), nil
+}
+
+// witnessProgramScript returns the output script paying to a witness version and program.
+func witnessProgramScript(version int, program []byte) ([]byte, error) {
+	opcode := byte(txscript.OP_0)
+	if version > 0 {
+		opcode = byte(txscript.OP_1 + version - 1)
+	}
+	return txscript.NewScriptBuilder().AddOp(opcode).AddData(program).Script()
+}
+
+func bech32ChecksumFor(hrp string, data []byte, checksumConst int) []byte {
+	values := append(append([]byte{}, data...), make([]byte, bech32ChecksumSz)...)
+	polymod := bech32Polymod(hrp, values) ^ checksumConst
+	checksum := make([]byte, bech32ChecksumSz)
+	for i := range checksum {
+		checksum[i] = byte(polymod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+func bech32Polymod(hrp string, data []byte) int {
+	generator := [5]int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := 1
+	step := func(v int) {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ v
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	for i := 0; i < len(hrp); i++ {
+		step(int(hrp[i] >> 5))
+	}
+	step(0)
+	for i := 0; i < len(hrp); i++ {
+		step(int(hrp[i] & 31))
+	}
+	for _, v := range data {
+		step(int(v))
+	}
+	return chk
+}
@@ -0,0 +1,110 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeSegwitAddress_BIP350ValidVectors(t *testing.T) {
+	vectors := []struct {
+		address string
+		hrp     string
+		version int
+		program string
+	}{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "bc", 0, "751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "tb", 0, "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", "bc", 1, "751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", "bc", 16, "751e"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", "bc", 2, "751e76e8199196d454941c45d1b3a323"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "bc", 1, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+	}
+	for _, v := range vectors {
+		version, program, err := decodeSegwitAddress(v.address, v.hrp)
+		assert.Nil(t, err, v.address)
+		assert.Equal(t, v.version, version, v.address)
+		assert.Equal(t, v.program, hex.EncodeToString(program), v.address)
+
+		raw, err := hex.DecodeString(v.program)
+		assert.Nil(t, err)
+		encoded, err := encodeSegwitAddress(v.hrp, v.version, raw)
+		assert.Nil(t, err)
+		assert.Equal(t, strings.ToLower(v.address), encoded)
+	}
+}
+
+func TestDecodeSegwitAddress_BIP350InvalidVectors(t *testing.T) {
+	for _, address := range []string{
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd",
+		"BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL",
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",
+		"bc1rw5uspcuh",
+		"bc10w508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kw5rljs90",
+		"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P",
+		"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4",
+		"bc1zw508d6qejxtdg4y5r3zarvaryvqyzf3du",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j",
+		"bc1gmk9yu",
+		"bC1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+	} {
+		_, _, err := decodeSegwitAddress(address, "bc")
+		assert.NotNil(t, err, address)
+	}
+}
+
+// testSegwitAddress encodes a witness version and program with a given checksum constant, bypassing validation.
+func testSegwitAddress(t *testing.T, version int, program []byte, checksumConst int) string {
+	converted, err := bech32.ConvertBits(program, 8, 5, true)
+	assert.Nil(t, err)
+	data := append([]byte{byte(version)}, converted...)
+	data = append(data, bech32ChecksumFor("bc", data, checksumConst)...)
+	address := "bc1"
+	for _, b := range data {
+		address += string(bech32Charset[b])
+	}
+	return address
+}
+
+func TestDecodeSegwitAddress_EncodingAndLengthRules(t *testing.T) {
+	program20 := make([]byte, 20)
+	program32 := make([]byte, 32)
+
+	_, _, err := decodeSegwitAddress(testSegwitAddress(t, 0, program20, bech32Const), "bc")
+	assert.Nil(t, err)
+	_, _, err = decodeSegwitAddress(testSegwitAddress(t, 0, program20, bech32mConst), "bc")
+	assert.Equal(t, ErrSegwitAddressChecksum, err)
+	_, _, err = decodeSegwitAddress(testSegwitAddress(t, 1, program32, bech32Const), "bc")
+	assert.Equal(t, ErrSegwitAddressChecksum, err)
+
+	_, _, err = decodeSegwitAddress(testSegwitAddress(t, 0, make([]byte, 16), bech32Const), "bc")
+	assert.Equal(t, ErrSegwitAddressProgram, err)
+	_, _, err = decodeSegwitAddress(testSegwitAddress(t, 1, make([]byte, 1), bech32mConst), "bc")
+	assert.Equal(t, ErrSegwitAddressProgram, err)
+	_, _, err = decodeSegwitAddress(testSegwitAddress(t, 1, make([]byte, 41), bech32mConst), "bc")
+	assert.NotNil(t, err)
+	_, _, err = decodeSegwitAddress(testSegwitAddress(t, 17, program32, bech32mConst), "bc")
+	assert.Equal(t, ErrSegwitAddressProgram, err)
+	_, _, err = decodeSegwitAddress(testSegwitAddress(t, 2, make([]byte, 40), bech32mConst), "bc")
+	assert.Nil(t, err)
+
+	_, err = encodeSegwitAddress("bc", 0, make([]byte, 16))
+	assert.Equal(t, ErrSegwitAddressProgram, err)
+}
+
+func TestAddressIsValidSegwitAddress_Bech32m(t *testing.T) {
+	assert.Nil(t, AddressIsValidSegwitAddress("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"))
+	assert.Nil(t, AddressIsValidSegwitAddress("bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y"))
+	assert.Nil(t, AddressIsValidSegwitAddress("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"))
+	assert.Equal(t, ErrSegwitAddressChecksum, AddressIsValidSegwitAddress("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd"))
+	assert.Equal(t, ErrSegwitAddressChecksum, AddressIsValidSegwitAddress("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh"))
+}
+
+func TestPkScriptForNetworkAddress_FutureWitnessVersion(t *testing.T) {
+	script, err := pkScriptForNetworkAddress("bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", BaseCoinBip84MainNet.defaultNetParams())
+	assert.Nil(t, err)
+	assert.Equal(t, "5210751e76e8199196d454941c45d1b3a323", hex.EncodeToString(script))
+}
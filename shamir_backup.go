@@ -0,0 +1,509 @@
+package cnlib
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// SLIP-39 share format and encryption parameters.
+const (
+	slip39RadixBits         = 10
+	slip39RadixMask         = 1<<slip39RadixBits - 1
+	slip39HeaderWords       = 4
+	slip39ChecksumWords     = 3
+	slip39MinSecretLength   = 16
+	slip39MaxShareCount     = 16
+	slip39BaseIterations    = 10000
+	slip39RoundCount        = 4
+	slip39IterationExponent = 1
+	slip39DigestLength      = 4
+	slip39DigestIndex       = 254
+	slip39SecretIndex       = 255
+)
+
+var slip39ChecksumGenerators = [10]uint32{
+	0xE0E040, 0x1C1C080, 0x3838100, 0x7070200, 0xE0E0009, 0x1C0C2412, 0x38086C24, 0x3090FC48, 0x21B1F890, 0x3F3F120,
+}
+
+// Following errors describe a SLIP-39 share which cannot be used to recover a seed.
+var (
+	ErrShamirShareUnknownWord   = errors.New("share contains a word not in the SLIP-39 wordlist")
+	ErrShamirShareChecksum      = errors.New("share checksum is invalid")
+	ErrShamirShareFormat        = errors.New("share is malformed")
+	ErrShamirShareMismatch      = errors.New("share does not belong to the same backup as the other shares")
+	ErrShamirShareDuplicate     = errors.New("share has already been added")
+	ErrShamirSharesInsufficient = errors.New("not enough shares to recover the seed")
+	ErrShamirSharesDigest       = errors.New("shares do not recover a valid seed")
+)
+
+// slip39Exp and slip39Log are the exponent and logarithm tables of GF(256) with the Rijndael polynomial, generator 3.
+var slip39Exp, slip39Log = func() ([255]byte, [256]byte) {
+	var exp [255]byte
+	var log [256]byte
+	poly := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(poly)
+		log[poly] = byte(i)
+		poly = (poly << 1) ^ poly
+		if poly&0x100 != 0 {
+			poly ^= 0x11b
+		}
+	}
+	return exp, log
+}()
+
+/// Type Definitions
+
+// ShamirBackup is a set of SLIP-39 shares of a wallet's seed, any Threshold of which recover the wallet.
+type ShamirBackup struct {
+	Threshold int
+	shares    []string
+}
+
+// ShamirShareRecovery collects SLIP-39 shares until enough are present to recover a wallet. Add shares one at a time
+// using `AddShare`, as gomobile does not support custom arrays/slices.
+type ShamirShareRecovery struct {
+	shares []*slip39Share
+}
+
+// slip39Share is a decoded SLIP-39 share mnemonic.
+type slip39Share struct {
+	identifier        int
+	extendable        bool
+	iterationExponent int
+	groupIndex        int
+	groupThreshold    int
+	groupCount        int
+	memberIndex       int
+	memberThreshold   int
+	value             []byte
+}
+
+// slip39Point is a share value at an x coordinate of the sharing polynomials.
+type slip39Point struct {
+	x     byte
+	value []byte
+}
+
+/// Constructors
+
+// NewShamirShareRecovery returns a pointer to an empty ShamirShareRecovery.
+func NewShamirShareRecovery() *ShamirShareRecovery {
+	return &ShamirShareRecovery{shares: []*slip39Share{}}
+}
+
+/// Receiver functions
+
+// ExportShamirShares splits the wallet's seed into total SLIP-39 shares, any threshold of which recover the wallet
+// with `ShamirShareRecovery`, while fewer reveal nothing about it. The seed is the BIP39 seed of the recovery words,
+// without a SLIP-39 passphrase, so another SLIP-39 wallet supporting 512 bit secrets recovers the same keys. Shares are
+// 59 words. Returns error if the wallet is watch-only, or if threshold and total are not 1 <= threshold <= total <= 16;
+// a threshold of 1 is only allowed for a single share.
+func (wallet *HDWallet) ExportShamirShares(threshold int, total int) (*ShamirBackup, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+	if threshold < 1 || total > slip39MaxShareCount || threshold > total {
+		return nil, errors.New("threshold and total must satisfy 1 <= threshold <= total <= 16")
+	}
+	if threshold == 1 && total > 1 {
+		return nil, errors.New("a threshold of 1 is only allowed for a single share")
+	}
+	seed, err := wallet.masterSeed()
+	if err != nil {
+		return nil, err
+	}
+
+	var id [2]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	identifier := int(binary.BigEndian.Uint16(id[:]) >> 1)
+	encrypted := slip39Feistel(seed, nil, slip39IterationExponent, identifier, true, false)
+
+	// a single group holding every member share
+	values, err := slip39SplitSecret(threshold, total, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	backup := &ShamirBackup{Threshold: threshold, shares: make([]string, total)}
+	for i, value := range values {
+		share := &slip39Share{
+			identifier:        identifier,
+			extendable:        true,
+			iterationExponent: slip39IterationExponent,
+			groupThreshold:    1,
+			groupCount:        1,
+			memberIndex:       i,
+			memberThreshold:   threshold,
+			value:             value,
+		}
+		backup.shares[i] = share.mnemonic()
+	}
+	return backup, nil
+}
+
+// ShareCount returns the number of shares in the backup.
+func (b *ShamirBackup) ShareCount() int {
+	return len(b.shares)
+}
+
+// ShareAtIndex returns the space-separated words of the share at a given index, or error if out of bounds.
+func (b *ShamirBackup) ShareAtIndex(index int) (string, error) {
+	if index < 0 || index > len(b.shares)-1 {
+		return "", errors.New("index must be within range of shares")
+	}
+	return b.shares[index], nil
+}
+
+// AddShare adds a SLIP-39 share mnemonic. Returns ErrShamirShareUnknownWord, ErrShamirShareChecksum or
+// ErrShamirShareFormat if the share cannot be read, ErrShamirShareMismatch if it belongs to a different backup than
+// the shares already added, or ErrShamirShareDuplicate if it was already added.
+func (r *ShamirShareRecovery) AddShare(mnemonic string) error {
+	share, err := decodeSlip39Share(mnemonic)
+	if err != nil {
+		return err
+	}
+	for _, existing := range r.shares {
+		if existing.identifier != share.identifier || existing.extendable != share.extendable ||
+			existing.iterationExponent != share.iterationExponent || existing.groupThreshold != share.groupThreshold ||
+			existing.groupCount != share.groupCount || len(existing.value) != len(share.value) {
+			return ErrShamirShareMismatch
+		}
+		if existing.groupIndex != share.groupIndex {
+			continue
+		}
+		if existing.memberThreshold != share.memberThreshold {
+			return ErrShamirShareMismatch
+		}
+		if existing.memberIndex == share.memberIndex {
+			return ErrShamirShareDuplicate
+		}
+	}
+	r.shares = append(r.shares, share)
+	return nil
+}
+
+// ShareCount returns the number of shares added.
+func (r *ShamirShareRecovery) ShareCount() int {
+	return len(r.shares)
+}
+
+// IsComplete returns true if enough shares have been added to recover the wallet.
+func (r *ShamirShareRecovery) IsComplete() bool {
+	_, err := r.completeGroups()
+	return err == nil
+}
+
+// Wallet returns the HDWallet recovered from the shares added, for a BaseCoin. The wallet has no recovery words, as
+// the shares hold its seed. Returns ErrShamirSharesInsufficient if more shares are needed, or ErrShamirSharesDigest
+// if the shares are inconsistent.
+func (r *ShamirShareRecovery) Wallet(basecoin *BaseCoin) (*HDWallet, error) {
+	seed, err := r.combine(nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewHDWalletFromSeed(seed, basecoin)
+}
+
+/// Unexported functions
+
+// masterSeed returns the seed of the wallet's master key.
+func (wallet *HDWallet) masterSeed() ([]byte, error) {
+	if wallet.seed != nil {
+		return wallet.seed, nil
+	}
+	if wallet.WalletWords == "" {
+		return nil, errors.New("wallet has no seed")
+	}
+	return mnemonicSeed(wallet.WalletWords), nil
+}
+
+// completeGroups returns the shares of the first groupThreshold groups, by group index, holding at least their
+// member threshold of shares.
+func (r *ShamirShareRecovery) completeGroups() ([][]*slip39Share, error) {
+	if len(r.shares) == 0 {
+		return nil, ErrShamirSharesInsufficient
+	}
+	groups := make(map[int][]*slip39Share)
+	for _, share := range r.shares {
+		groups[share.groupIndex] = append(groups[share.groupIndex], share)
+	}
+	indexes := []int{}
+	for index, members := range groups {
+		if len(members) >= members[0].memberThreshold {
+			indexes = append(indexes, index)
+		}
+	}
+	threshold := r.shares[0].groupThreshold
+	if len(indexes) < threshold {
+		return nil, ErrShamirSharesInsufficient
+	}
+	sort.Ints(indexes)
+	complete := make([][]*slip39Share, threshold)
+	for i := range complete {
+		complete[i] = groups[indexes[i]]
+	}
+	return complete, nil
+}
+
+// combine recovers the master secret from the shares added, decrypted with passphrase.
+func (r *ShamirShareRecovery) combine(passphrase []byte) ([]byte, error) {
+	groups, err := r.completeGroups()
+	if err != nil {
+		return nil, err
+	}
+	groupPoints := make([]slip39Point, len(groups))
+	for i, members := range groups {
+		points := make([]slip39Point, len(members))
+		for j, member := range members {
+			points[j] = slip39Point{x: byte(member.memberIndex), value: member.value}
+		}
+		value, err := slip39RecoverSecret(members[0].memberThreshold, points)
+		if err != nil {
+			return nil, err
+		}
+		groupPoints[i] = slip39Point{x: byte(members[0].groupIndex), value: value}
+	}
+	encrypted, err := slip39RecoverSecret(len(groups), groupPoints)
+	if err != nil {
+		return nil, err
+	}
+	first := r.shares[0]
+	return slip39Feistel(encrypted, passphrase, first.iterationExponent, first.identifier, first.extendable, true), nil
+}
+
+// slip39SplitSecret returns count shares of secret, any threshold of which recover it. Shares are at x = 0 to
+// count-1 of polynomials through the secret at slip39SecretIndex and its digest at slip39DigestIndex.
+func slip39SplitSecret(threshold int, count int, secret []byte) ([][]byte, error) {
+	if threshold == 1 {
+		values := make([][]byte, count)
+		for i := range values {
+			values[i] = append([]byte{}, secret...)
+		}
+		return values, nil
+	}
+
+	randomCount := threshold - 2
+	base := make([]slip39Point, 0, threshold)
+	for i := 0; i < randomCount; i++ {
+		value := make([]byte, len(secret))
+		if _, err := rand.Read(value); err != nil {
+			return nil, err
+		}
+		base = append(base, slip39Point{x: byte(i), value: value})
+	}
+	randomPart := make([]byte, len(secret)-slip39DigestLength)
+	if _, err := rand.Read(randomPart); err != nil {
+		return nil, err
+	}
+	digest := append(slip39Digest(randomPart, secret), randomPart...)
+	base = append(base, slip39Point{x: slip39DigestIndex, value: digest}, slip39Point{x: slip39SecretIndex, value: secret})
+
+	values := make([][]byte, count)
+	for i := range values {
+		if i < randomCount {
+			values[i] = base[i].value
+			continue
+		}
+		values[i] = slip39Interpolate(base, byte(i))
+	}
+	return values, nil
+}
+
+// slip39RecoverSecret returns the secret shared by points, verifying its digest.
+func slip39RecoverSecret(threshold int, points []slip39Point) ([]byte, error) {
+	if threshold == 1 {
+		return points[0].value, nil
+	}
+	secret := slip39Interpolate(points, slip39SecretIndex)
+	digest := slip39Interpolate(points, slip39DigestIndex)
+	if !hmac.Equal(digest[:slip39DigestLength], slip39Digest(digest[slip39DigestLength:], secret)) {
+		return nil, ErrShamirSharesDigest
+	}
+	return secret, nil
+}
+
+func slip39Digest(randomPart []byte, secret []byte) []byte {
+	mac := hmac.New(sha256.New, randomPart)
+	mac.Write(secret)
+	return mac.Sum(nil)[:slip39DigestLength]
+}
+
+// slip39Interpolate returns the value at x of the polynomials through points, by Lagrange interpolation in GF(256).
+func slip39Interpolate(points []slip39Point, x byte) []byte {
+	result := make([]byte, len(points[0].value))
+	for i, point := range points {
+		basis := byte(1)
+		for j, other := range points {
+			if i != j {
+				basis = gf256Mul(basis, gf256Div(x^other.x, point.x^other.x))
+			}
+		}
+		for k, b := range point.value {
+			result[k] ^= gf256Mul(basis, b)
+		}
+	}
+	return result
+}
+
+func gf256Mul(a byte, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return slip39Exp[(int(slip39Log[a])+int(slip39Log[b]))%255]
+}
+
+func gf256Div(a byte, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return slip39Exp[(int(slip39Log[a])+255-int(slip39Log[b]))%255]
+}
+
+// slip39Feistel encrypts, or decrypts, a master secret with the SLIP-39 four round Feistel network.
+func slip39Feistel(data []byte, passphrase []byte, iterationExponent int, identifier int, extendable bool, decrypt bool) []byte {
+	half := len(data) / 2
+	l := append([]byte{}, data[:half]...)
+	r := append([]byte{}, data[half:]...)
+	salt := []byte{}
+	if !extendable {
+		salt = append([]byte("shamir"), byte(identifier>>8), byte(identifier))
+	}
+	iterations := (slip39BaseIterations << uint(iterationExponent)) / slip39RoundCount
+
+	for round := 0; round < slip39RoundCount; round++ {
+		i := round
+		if decrypt {
+			i = slip39RoundCount - 1 - round
+		}
+		key := append([]byte{byte(i)}, passphrase...)
+		f := pbkdf2.Key(key, append(append([]byte{}, salt...), r...), iterations, len(r), sha256.New)
+		for k := range l {
+			l[k] ^= f[k]
+		}
+		l, r = r, l
+	}
+	return append(r, l...)
+}
+
+// mnemonic returns the share encoded as SLIP-39 words.
+func (s *slip39Share) mnemonic() string {
+	ext := 0
+	if s.extendable {
+		ext = 1
+	}
+	id := s.identifier<<5 | ext<<4 | s.iterationExponent
+	params := s.groupIndex<<16 | (s.groupThreshold-1)<<12 | (s.groupCount-1)<<8 | s.memberIndex<<4 | (s.memberThreshold - 1)
+	indexes := []int{id >> slip39RadixBits, id & slip39RadixMask, params >> slip39RadixBits, params & slip39RadixMask}
+
+	valueWords := (len(s.value)*8 + slip39RadixBits - 1) / slip39RadixBits
+	value := new(big.Int).SetBytes(s.value)
+	for i := valueWords - 1; i >= 0; i-- {
+		shift := uint(i * slip39RadixBits)
+		indexes = append(indexes, int(new(big.Int).Rsh(value, shift).Int64()&slip39RadixMask))
+	}
+
+	checksum := slip39Polymod(slip39Customization(s.extendable), append(indexes, 0, 0, 0)) ^ 1
+	for i := slip39ChecksumWords - 1; i >= 0; i-- {
+		indexes = append(indexes, int(checksum>>uint(i*slip39RadixBits))&slip39RadixMask)
+	}
+
+	words := make([]string, len(indexes))
+	for i, index := range indexes {
+		words[i] = slip39WordList[index]
+	}
+	return strings.Join(words, " ")
+}
+
+// decodeSlip39Share returns the share encoded by SLIP-39 words, verifying its checksum.
+func decodeSlip39Share(mnemonic string) (*slip39Share, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	indexes := make([]int, len(words))
+	for i, word := range words {
+		index, ok := slip39WordIndexes[word]
+		if !ok {
+			return nil, ErrShamirShareUnknownWord
+		}
+		indexes[i] = index
+	}
+	valueWords := len(indexes) - slip39HeaderWords - slip39ChecksumWords
+	if valueWords*slip39RadixBits < slip39MinSecretLength*8 {
+		return nil, ErrShamirShareFormat
+	}
+
+	id := indexes[0]<<slip39RadixBits | indexes[1]
+	extendable := id>>4&1 == 1
+	if slip39Polymod(slip39Customization(extendable), indexes) != 1 {
+		return nil, ErrShamirShareChecksum
+	}
+
+	params := indexes[2]<<slip39RadixBits | indexes[3]
+	share := &slip39Share{
+		identifier:        id >> 5,
+		extendable:        extendable,
+		iterationExponent: id & 0xf,
+		groupIndex:        params >> 16,
+		groupThreshold:    params>>12&0xf + 1,
+		groupCount:        params>>8&0xf + 1,
+		memberIndex:       params >> 4 & 0xf,
+		memberThreshold:   params&0xf + 1,
+	}
+	if share.groupThreshold > share.groupCount {
+		return nil, ErrShamirShareFormat
+	}
+
+	padding := valueWords * slip39RadixBits % 16
+	if padding > 8 {
+		return nil, ErrShamirShareFormat
+	}
+	value := new(big.Int)
+	for _, index := range indexes[slip39HeaderWords : slip39HeaderWords+valueWords] {
+		value.Lsh(value, slip39RadixBits)
+		value.Or(value, big.NewInt(int64(index)))
+	}
+	length := (valueWords*slip39RadixBits - padding) / 8
+	if value.BitLen() > length*8 {
+		return nil, ErrShamirShareFormat
+	}
+	share.value = make([]byte, length)
+	encoded := value.Bytes()
+	copy(share.value[length-len(encoded):], encoded)
+	return share, nil
+}
+
+func slip39Customization(extendable bool) []byte {
+	if extendable {
+		return []byte("shamir_extendable")
+	}
+	return []byte("shamir")
+}
+
+// slip39Polymod returns the RS1024 checksum state after the customization string and word indexes.
+func slip39Polymod(customization []byte, indexes []int) uint32 {
+	checksum := uint32(1)
+	step := func(v uint32) {
+		top := checksum >> 20
+		checksum = (checksum&0xfffff)<<slip39RadixBits ^ v
+		for i, generator := range slip39ChecksumGenerators {
+			if top>>uint(i)&1 == 1 {
+				checksum ^= generator
+			}
+		}
+	}
+	for _, c := range customization {
+		step(uint32(c))
+	}
+	for _, index := range indexes {
+		step(uint32(index))
+	}
+	return checksum
+}
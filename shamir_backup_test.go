@@ -0,0 +1,90 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShamirShareRecovery_SLIP39Vectors(t *testing.T) {
+	recovery := NewShamirShareRecovery()
+	assert.Nil(t, recovery.AddShare("duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard"))
+	assert.True(t, recovery.IsComplete())
+	secret, err := recovery.combine([]byte("TREZOR"))
+	assert.Nil(t, err)
+	assert.Equal(t, "bb54aac4b89dc868ba37d9cc21b2cece", hex.EncodeToString(secret))
+
+	recovery = NewShamirShareRecovery()
+	assert.Nil(t, recovery.AddShare("shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed"))
+	assert.False(t, recovery.IsComplete())
+	assert.Nil(t, recovery.AddShare("shadow pistol academic acid actress prayer class unknown daughter sweater depict flip twice unkind craft early superior advocate guest smoking"))
+	assert.True(t, recovery.IsComplete())
+	secret, err = recovery.combine([]byte("TREZOR"))
+	assert.Nil(t, err)
+	assert.Equal(t, "b43ceb7e57a0ea8766221624d01b0864", hex.EncodeToString(secret))
+}
+
+func TestShamirShareRecovery_RejectsBadShares(t *testing.T) {
+	recovery := NewShamirShareRecovery()
+	share := "duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard"
+	assert.Equal(t, ErrShamirShareChecksum, recovery.AddShare(strings.Replace(share, "keyboard", "kidney", 1)))
+	assert.Equal(t, ErrShamirShareUnknownWord, recovery.AddShare(strings.Replace(share, "keyboard", "keyboards", 1)))
+	assert.Equal(t, ErrShamirShareFormat, recovery.AddShare("duckling enlarge academic"))
+
+	assert.Nil(t, recovery.AddShare("shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed"))
+	assert.Equal(t, ErrShamirShareDuplicate, recovery.AddShare("shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed"))
+	assert.Equal(t, ErrShamirShareMismatch, recovery.AddShare(share))
+	assert.Equal(t, 1, recovery.ShareCount())
+
+	_, err := recovery.Wallet(BaseCoinBip84MainNet)
+	assert.Equal(t, ErrShamirSharesInsufficient, err)
+}
+
+func TestExportShamirShares_RecoversWallet(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	backup, err := wallet.ExportShamirShares(3, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, backup.ShareCount())
+	assert.Equal(t, 3, backup.Threshold)
+	_, err = backup.ShareAtIndex(5)
+	assert.NotNil(t, err)
+
+	expected, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+
+	recovery := NewShamirShareRecovery()
+	for _, index := range []int{4, 1, 2} {
+		share, err := backup.ShareAtIndex(index)
+		assert.Nil(t, err)
+		assert.Equal(t, 59, len(strings.Fields(share)))
+		assert.False(t, recovery.IsComplete())
+		assert.Nil(t, recovery.AddShare(share))
+	}
+	recovered, err := recovery.Wallet(BaseCoinBip84MainNet)
+	assert.Nil(t, err)
+	actual, err := recovered.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Address, actual.Address)
+
+	reexported, err := recovered.ExportShamirShares(2, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, reexported.ShareCount())
+}
+
+func TestExportShamirShares_InvalidParameters(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	for _, params := range [][2]int{{0, 3}, {4, 3}, {2, 17}, {1, 2}} {
+		_, err := wallet.ExportShamirShares(params[0], params[1])
+		assert.NotNil(t, err)
+	}
+	backup, err := wallet.ExportShamirShares(1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, backup.ShareCount())
+
+	watchOnly, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	_, err = watchOnly.ExportShamirShares(2, 3)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
@@ -0,0 +1,80 @@
+package cnlib
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+const (
+	sharedKeySize = 32
+	sharedKeySalt = "cnlib shared key"
+)
+
+/// Receiver functions
+
+// SharedSecret returns the 32-byte ECDH shared secret, the x coordinate of the product of the private key derived from
+// given derivation path and a hex-encoded compressed or uncompressed public key, for protocols other than the
+// encryption payloads of `EncryptMessage`. The other party derives the same secret from their private key and this
+// path's public key. Use it as key material only through a key derivation function, as `DeriveSharedKey` does.
+// Returns ErrKeyRoleMismatch for spending key paths, or ErrPublicKeyHex, ErrPublicKeyLength or ErrPublicKeyPoint if
+// the public key is malformed.
+func (wallet *HDWallet) SharedSecret(recipientPubkeyHex string, path *DerivationPath) ([]byte, error) {
+	privateKey, publicKey, err := wallet.sharedSecretKeys(recipientPubkeyHex, path)
+	if err != nil {
+		return nil, err
+	}
+	return btcec.GenerateSharedSecret(privateKey, publicKey), nil
+}
+
+// DeriveSharedKey returns a 32-byte symmetric key derived via HKDF-SHA256 from the `SharedSecret` with a public key,
+// bound to both parties' public keys, such as a per-conversation key. Both parties derive the same key with the same
+// label, and different labels produce independent keys.
+func (wallet *HDWallet) DeriveSharedKey(recipientPubkeyHex string, path *DerivationPath, label string) ([]byte, error) {
+	if label == "" {
+		return nil, errors.New("label cannot be empty")
+	}
+	privateKey, publicKey, err := wallet.sharedSecretKeys(recipientPubkeyHex, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// public keys are ordered, so both parties bind the same info
+	low, high := privateKey.PubKey().SerializeCompressed(), publicKey.SerializeCompressed()
+	if bytes.Compare(low, high) > 0 {
+		low, high = high, low
+	}
+	info := append(append(append([]byte(label), 0), low...), high...)
+	return hkdfBytes(btcec.GenerateSharedSecret(privateKey, publicKey), sharedKeySalt, info, sharedKeySize)
+}
+
+/// Unexported functions
+
+// sharedSecretKeys returns the private key at a path, which must not be a spending key, and the parsed public key.
+func (wallet *HDWallet) sharedSecretKeys(pubkeyHex string, path *DerivationPath) (*btcec.PrivateKey, *btcec.PublicKey, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, nil, err
+	}
+
+	if path == nil {
+		return nil, nil, errors.New("derivation path cannot be nil")
+	}
+	if err := rejectKeyRoles(path, KeyRoleSpending); err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := parseHexPubKey(pubkeyHex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := wallet.keyFactory().indexPrivateKey(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, err := key.ECPrivKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	return privateKey, publicKey, nil
+}
@@ -0,0 +1,64 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedSecret_BothPartiesAgree(t *testing.T) {
+	alice := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	bob := multisigCosignerWallet(t, 0x01, BaseCoinBip84MainNet)
+	alicePath, _ := ParseCustomDerivationPath("m/1000'/3'")
+	bobPath, _ := ParseCustomDerivationPath("m/42")
+	alicePubkey, _ := alice.CompressedPubKeyForPath(alicePath)
+	bobPubkey, _ := bob.UncompressedPubKeyForPath(bobPath)
+
+	aliceSecret, err := alice.SharedSecret(hex.EncodeToString(bobPubkey), alicePath)
+	assert.Nil(t, err)
+	bobSecret, err := bob.SharedSecret(hex.EncodeToString(alicePubkey), bobPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(aliceSecret))
+	assert.Equal(t, aliceSecret, bobSecret)
+
+	// the x coordinate of the product
+	bobKey, _ := btcec.ParsePubKey(bobPubkey, btcec.S256())
+	aliceKey, _ := alice.keyFactory().indexPrivateKey(alicePath)
+	alicePrivateKey, _ := aliceKey.ECPrivKey()
+	x, _ := btcec.S256().ScalarMult(bobKey.X, bobKey.Y, alicePrivateKey.D.Bytes())
+	assert.Equal(t, x.Bytes(), aliceSecret)
+
+	aliceKeyBytes, err := alice.DeriveSharedKey(hex.EncodeToString(bobPubkey), alicePath, "conversation 1")
+	assert.Nil(t, err)
+	bobKeyBytes, err := bob.DeriveSharedKey(hex.EncodeToString(alicePubkey), bobPath, "conversation 1")
+	assert.Nil(t, err)
+	assert.Equal(t, sharedKeySize, len(aliceKeyBytes))
+	assert.Equal(t, aliceKeyBytes, bobKeyBytes)
+	assert.NotEqual(t, aliceSecret, aliceKeyBytes)
+
+	otherKeyBytes, _ := alice.DeriveSharedKey(hex.EncodeToString(bobPubkey), alicePath, "conversation 2")
+	assert.NotEqual(t, aliceKeyBytes, otherKeyBytes)
+}
+
+func TestSharedSecret_Invalid(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	identity, _ := ParseCustomDerivationPath("m/42")
+	pubkey, _ := wallet.SigningPublicKey()
+
+	_, err := wallet.SharedSecret(hex.EncodeToString(pubkey), NewDerivationPath(BaseCoinBip84MainNet, 0, 0))
+	assert.Equal(t, ErrKeyRoleMismatch, err)
+	_, err = wallet.SharedSecret(hex.EncodeToString(pubkey), nil)
+	assert.NotNil(t, err)
+	_, err = wallet.SharedSecret("not hex", identity)
+	assert.Equal(t, ErrPublicKeyHex, err)
+	_, err = wallet.SharedSecret(hex.EncodeToString(pubkey[:32]), identity)
+	assert.Equal(t, ErrPublicKeyLength, err)
+	_, err = wallet.DeriveSharedKey(hex.EncodeToString(pubkey), identity, "")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, wallet.SetRole(WalletRoleHot))
+	_, err = wallet.SharedSecret(hex.EncodeToString(pubkey), identity)
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+}
@@ -0,0 +1,64 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ErrSignatureInvalid describes a well-formed signature which was not made by the given key over the given message.
+var ErrSignatureInvalid = errors.New("signature is not valid for message and public key")
+
+/// Exported functions
+
+// VerifySignature verifies a DER encoded signature of a message, as returned by `HDWallet.SignData` or the decoded
+// `HDWallet.SignatureSigningData`, such as those of CoinNinja server payloads or peer messages, against a hex-encoded
+// compressed or uncompressed public key. The message is double-SHA256 hashed, as when signing. Returns nil if valid,
+// ErrSignatureInvalid if not, ErrPublicKeyHex, ErrPublicKeyLength or ErrPublicKeyPoint if the public key is malformed,
+// or error if the signature is not DER.
+func VerifySignature(message []byte, signatureDER []byte, compressedPubkeyHex string) error {
+	pubkey, err := parseHexPubKey(compressedPubkeyHex)
+	if err != nil {
+		return err
+	}
+	return verifyDERSignature(signatureDER, chainhash.DoubleHashB(message), pubkey)
+}
+
+/// Unexported functions
+
+// verifyDERSignature verifies a DER encoded signature of a digest against a public key.
+func verifyDERSignature(signatureDER []byte, digest []byte, pubkey *btcec.PublicKey) error {
+	signature, err := btcec.ParseDERSignature(signatureDER, btcec.S256())
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(digest, pubkey) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// signedRecordDigest returns the double-SHA256 of a signed record's fields, each on its own line after the domain
+// separator "cnlib " and the record's label, as signed by attestations, receipts and signed payloads. Fields which may
+// contain newlines must be last.
+func signedRecordDigest(label string, fields ...interface{}) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("cnlib " + label)
+	for _, field := range fields {
+		fmt.Fprintf(&buf, "\n%v", field)
+	}
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+// signDigestHex returns the hex-encoded DER signature of a digest, for the signature fields of signed records.
+func signDigestHex(privateKey *btcec.PrivateKey, digest []byte) (string, error) {
+	signature, err := privateKey.Sign(digest)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(signature.Serialize()), nil
+}
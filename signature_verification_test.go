@@ -0,0 +1,35 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySignature(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	pubkey, _ := wallet.SigningPublicKey()
+	compressed := hex.EncodeToString(pubkey)
+	message := []byte("{\"memo\":\"hello\"}")
+
+	signature, err := wallet.SignData(message)
+	assert.Nil(t, err)
+	assert.Nil(t, VerifySignature(message, signature, compressed))
+
+	encoded, _ := wallet.SignatureSigningData(message)
+	decoded, _ := hex.DecodeString(encoded)
+	assert.Nil(t, VerifySignature(message, decoded, compressed))
+
+	// uncompressed keys verify too
+	key, _ := parseHexPubKey(compressed)
+	assert.Nil(t, VerifySignature(message, signature, hex.EncodeToString(key.SerializeUncompressed())))
+
+	assert.Equal(t, ErrSignatureInvalid, VerifySignature([]byte("{\"memo\":\"hi\"}"), signature, compressed))
+	other, _ := NewHDWalletFromWords(w, BaseCoinBip84MainNet).CompressedPubKeyForPath(NewDerivationPath(BaseCoinBip84MainNet, 0, 0))
+	assert.Equal(t, ErrSignatureInvalid, VerifySignature(message, signature, hex.EncodeToString(other)))
+	assert.Equal(t, ErrPublicKeyHex, VerifySignature(message, signature, "zz"))
+	assert.Equal(t, ErrPublicKeyLength, VerifySignature(message, signature, compressed[:64]))
+	assert.NotNil(t, VerifySignature(message, signature[:len(signature)-1], compressed))
+	assert.NotNil(t, VerifySignature(message, nil, compressed))
+}
@@ -0,0 +1,244 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	legacyMessageMagic = "Bitcoin Signed Message:\n"
+	bip322MessageTag   = "BIP0322-signed-message"
+)
+
+// Following constants are the first of the four BIP137 header bytes, one per recovery id, of each address type.
+const (
+	bip137HeaderP2PKHUncompressed = 27
+	bip137HeaderP2PKH             = 31
+	bip137HeaderP2SHP2WPKH        = 35
+	bip137HeaderP2WPKH            = 39
+	bip137HeaderEnd               = 43
+)
+
+// ErrSignedMessageAddressMismatch describes a message signature that is valid, but by a key other than the address's.
+var ErrSignedMessageAddressMismatch = errors.New("signature does not match address")
+
+/// Unexported functions
+
+// legacyMessageHash returns the double-sha256 digest used by Bitcoin Core's signmessage.
+func legacyMessageHash(message string) []byte {
+	var buf bytes.Buffer
+	_ = wire.WriteVarString(&buf, 0, legacyMessageMagic)
+	_ = wire.WriteVarString(&buf, 0, message)
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+// verifyLegacySignedMessage verifies a 65-byte compact signature, as produced by signmessage (BIP137), over message
+// against the given address. Headers of compressed P2PKH keys match any single key address of the key, as Electrum
+// and others sign for segwit addresses, while the BIP137 segwit headers match only their own address type.
+func verifyLegacySignedMessage(address string, message string, signature []byte, params *chaincfg.Params) error {
+	if len(signature) != 65 || signature[0] < bip137HeaderP2PKHUncompressed || signature[0] >= bip137HeaderEnd {
+		return errors.New("invalid compact signature header")
+	}
+	header := signature[0]
+	if header >= bip137HeaderP2SHP2WPKH {
+		// btcec only recovers P2PKH headers
+		signature = append([]byte{bip137HeaderP2PKH + (header-bip137HeaderP2PKHUncompressed)%4}, signature[1:]...)
+	}
+	pubkey, wasCompressed, err := btcec.RecoverCompact(btcec.S256(), signature, legacyMessageHash(message))
+	if err != nil {
+		return err
+	}
+
+	var serialized []byte
+	if wasCompressed {
+		serialized = pubkey.SerializeCompressed()
+	} else {
+		serialized = pubkey.SerializeUncompressed()
+	}
+	hash := btcutil.Hash160(serialized)
+
+	isP2PKHHeader := header < bip137HeaderP2SHP2WPKH
+	isNestedHeader := header >= bip137HeaderP2SHP2WPKH && header < bip137HeaderP2WPKH
+	isWitnessHeader := header >= bip137HeaderP2WPKH
+
+	candidates := []btcutil.Address{}
+	if isP2PKHHeader {
+		if p2pkh, err := btcutil.NewAddressPubKeyHash(hash, params); err == nil {
+			candidates = append(candidates, p2pkh)
+		}
+	}
+	if wasCompressed && (isP2PKHHeader || isWitnessHeader) {
+		if p2wpkh, err := btcutil.NewAddressWitnessPubKeyHash(hash, params); err == nil {
+			candidates = append(candidates, p2wpkh)
+		}
+	}
+	if wasCompressed && (isP2PKHHeader || isNestedHeader) {
+		if redeemScript, err := P2WPKHScript(hash); err == nil {
+			if p2sh, err := btcutil.NewAddressScriptHash(redeemScript, params); err == nil {
+				candidates = append(candidates, p2sh)
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if candidate.EncodeAddress() == address {
+			return nil
+		}
+	}
+	return ErrSignedMessageAddressMismatch
+}
+
+// bip322MessageHash returns the BIP340-style tagged hash of a message used by BIP322.
+func bip322MessageHash(message string) []byte {
+	return taggedHash(bip322MessageTag, []byte(message))
+}
+
+// taggedHash returns sha256(sha256(tag) || sha256(tag) || data).
+func taggedHash(tag string, data []byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// bip322ToSpend returns the virtual transaction whose only output is spent by a BIP322 proof.
+func bip322ToSpend(message string, pkScript []byte) (*wire.MsgTx, error) {
+	sigScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(bip322MessageHash(message)).Script()
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(0)
+	txIn := wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), sigScript, nil)
+	txIn.Sequence = 0
+	tx.AddTxIn(txIn)
+	tx.AddTxOut(wire.NewTxOut(0, pkScript))
+	return tx, nil
+}
+
+// bip322ToSign returns the unsigned virtual transaction spending toSpend, whose witness carries a BIP322 proof.
+func bip322ToSign(toSpend *wire.MsgTx) *wire.MsgTx {
+	toSpendHash := toSpend.TxHash()
+	tx := wire.NewMsgTx(0)
+	txIn := wire.NewTxIn(wire.NewOutPoint(&toSpendHash, 0), nil, nil)
+	txIn.Sequence = 0
+	tx.AddTxIn(txIn)
+	tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+	return tx
+}
+
+// serializeWitness encodes a witness stack using the consensus encoding, as used by BIP322 simple signatures.
+func serializeWitness(witness wire.TxWitness) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := wire.WriteVarInt(&buf, 0, uint64(len(witness))); err != nil {
+		return nil, err
+	}
+	for _, item := range witness {
+		if err := wire.WriteVarBytes(&buf, 0, item); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// deserializeWitness decodes a consensus encoded witness stack.
+func deserializeWitness(data []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(data)
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(len(data)) {
+		return nil, errors.New("invalid witness item count")
+	}
+	witness := make(wire.TxWitness, count)
+	for i := range witness {
+		item, err := wire.ReadVarBytes(r, 0, uint32(len(data)), "witness item")
+		if err != nil {
+			return nil, err
+		}
+		witness[i] = item
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("trailing bytes after witness")
+	}
+	return witness, nil
+}
+
+// verifyBIP322SimpleSignedMessage verifies a BIP322 "simple" signature, a serialized witness stack, over message
+// against the given segwit or taproot address.
+func verifyBIP322SimpleSignedMessage(address string, message string, signature []byte, params *chaincfg.Params) error {
+	pkScript, err := bip322AddressScript(address, params)
+	if err != nil {
+		return err
+	}
+	if !txscript.IsWitnessProgram(pkScript) {
+		return errors.New("simple signatures require a segwit address")
+	}
+
+	witness, err := deserializeWitness(signature)
+	if err != nil {
+		return err
+	}
+
+	toSpend, err := bip322ToSpend(message, pkScript)
+	if err != nil {
+		return err
+	}
+	toSign := bip322ToSign(toSpend)
+	toSign.TxIn[0].Witness = witness
+	if isTaprootScript(pkScript) {
+		return verifyBIP322TaprootWitness(toSign, toSpend.TxOut[0], witness)
+	}
+
+	vm, err := txscript.NewEngine(pkScript, toSign, 0, txscript.StandardVerifyFlags, nil, txscript.NewTxSigHashes(toSign), 0)
+	if err != nil {
+		return err
+	}
+	return vm.Execute()
+}
+
+// bip322AddressScript returns the output script of an address on the given network, including taproot addresses,
+// which the btcutil version in use cannot decode.
+func bip322AddressScript(address string, params *chaincfg.Params) ([]byte, error) {
+	if outputKey, err := decodeTaprootAddress(address, params); err == nil {
+		return P2TRScript(outputKey)
+	}
+	decoded, err := btcutil.DecodeAddress(address, params)
+	if err != nil {
+		return nil, err
+	}
+	if !decoded.IsForNet(params) {
+		return nil, errors.New("address is for another network")
+	}
+	return txscript.PayToAddrScript(decoded)
+}
+
+// verifyBIP322TaprootWitness verifies the key path spend of a BIP86 taproot output in a BIP322 witness, a single
+// 64-byte SIGHASH_DEFAULT or 65-byte SIGHASH_ALL signature, which the btcd script engine in use cannot.
+func verifyBIP322TaprootWitness(toSign *wire.MsgTx, prevout *wire.TxOut, witness wire.TxWitness) error {
+	if len(witness) != 1 {
+		return errors.New("taproot signatures require a single key path witness item")
+	}
+	signature, hashType, err := parseTaprootSignature(witness[0])
+	if err != nil {
+		return err
+	}
+	if hashType != taprootSigHashDefault && hashType != byte(txscript.SigHashAll) {
+		return errors.New("unsupported taproot signature hash type")
+	}
+	sigHash, err := taprootSigHashWithType(toSign, 0, []*wire.TxOut{prevout}, nil, hashType)
+	if err != nil {
+		return err
+	}
+	return schnorrVerify(prevout.PkScript[2:], sigHash, signature)
+}
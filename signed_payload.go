@@ -0,0 +1,132 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	signedPayloadVersion = 1
+	signedPayloadLabel   = "signed payload"
+	// signedPayloadClockSkew is how far in the future a payload's timestamp may be, for clocks running ahead.
+	signedPayloadClockSkew = 5 * 60
+)
+
+// Following errors are returned when creating or verifying a SignedPayload.
+var (
+	ErrSignedPayloadBody        = errors.New("signed payload body must be valid JSON")
+	ErrSignedPayloadKeyMismatch = errors.New("signed payload key fingerprint does not match the public key")
+	ErrSignedPayloadSignature   = errors.New("signed payload signature is invalid")
+	ErrSignedPayloadExpired     = errors.New("signed payload timestamp is outside the accepted window")
+)
+
+/// Type Definitions
+
+// SignedPayload is a JSON request body for CoinNinja APIs, signed with the wallet's m/42 verification key along with
+// the time of signing, so the backend can tell who sent it and reject replays of old requests. The body is carried
+// verbatim as a string, so it need not be re-serialized identically to verify.
+type SignedPayload struct {
+	Version        int    `json:"version"`
+	Body           string `json:"body"`
+	Timestamp      int64  `json:"timestamp"`       // seconds since unix epoch
+	KeyFingerprint string `json:"key_fingerprint"` // hex-encoded first 4 bytes of HASH160 of the m/42 public key
+	Signature      string `json:"signature"`       // hex-encoded DER signature of the digest of the other fields
+}
+
+/// Receiver functions
+
+// NewSignedPayload returns a payload of a JSON body, signed now with the wallet's m/42 key. Returns
+// ErrSignedPayloadBody if the body is not valid JSON, or error if the wallet is watch-only.
+func (wallet *HDWallet) NewSignedPayload(body string) (*SignedPayload, error) {
+	return wallet.signedPayloadAt(body, time.Now().Unix())
+}
+
+// Encode returns the payload as a JSON string, for sending to the backend and passing to `DecodeSignedPayload`.
+func (p *SignedPayload) Encode() (string, error) {
+	encoded, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// Verify returns nil if the payload was signed by the m/42 key with a hex-encoded compressed or uncompressed public
+// key, within maxAgeSeconds of now, or any time if maxAgeSeconds is 0. Returns ErrSignedPayloadKeyMismatch,
+// ErrSignedPayloadSignature or ErrSignedPayloadExpired otherwise.
+func (p *SignedPayload) Verify(publicKey string, maxAgeSeconds int) error {
+	return p.verifyAt(publicKey, maxAgeSeconds, time.Now().Unix())
+}
+
+/// Exported functions
+
+// DecodeSignedPayload parses a payload from a string returned by `Encode`. Call `Verify` with the sender's public key
+// before trusting its body.
+func DecodeSignedPayload(encoded string) (*SignedPayload, error) {
+	var payload SignedPayload
+	if err := json.Unmarshal([]byte(encoded), &payload); err != nil {
+		return nil, err
+	}
+	if payload.Version != signedPayloadVersion {
+		return nil, errors.New("unsupported signed payload version")
+	}
+	return &payload, nil
+}
+
+/// Unexported functions
+
+func (wallet *HDWallet) signedPayloadAt(body string, timestamp int64) (*SignedPayload, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(body)) {
+		return nil, ErrSignedPayloadBody
+	}
+	key, err := wallet.signingPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &SignedPayload{
+		Version:        signedPayloadVersion,
+		Body:           body,
+		Timestamp:      timestamp,
+		KeyFingerprint: hex.EncodeToString(btcutil.Hash160(key.PubKey().SerializeCompressed())[:4]),
+	}
+	if payload.Signature, err = signDigestHex(key, payload.digest()); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (p *SignedPayload) verifyAt(publicKey string, maxAgeSeconds int, now int64) error {
+	if p.Version != signedPayloadVersion {
+		return errors.New("unsupported signed payload version")
+	}
+	if maxAgeSeconds < 0 {
+		return errors.New("max age cannot be negative")
+	}
+	pubkey, err := parseHexPubKey(publicKey)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(btcutil.Hash160(pubkey.SerializeCompressed())[:4]) != p.KeyFingerprint {
+		return ErrSignedPayloadKeyMismatch
+	}
+	signature, err := hex.DecodeString(p.Signature)
+	if err != nil || verifyDERSignature(signature, p.digest(), pubkey) != nil {
+		return ErrSignedPayloadSignature
+	}
+	if p.Timestamp > now+signedPayloadClockSkew || (maxAgeSeconds > 0 && p.Timestamp < now-int64(maxAgeSeconds)) {
+		return ErrSignedPayloadExpired
+	}
+	return nil
+}
+
+// digest returns the signed record digest of the signed fields, with the body last as it may itself contain newlines.
+func (p *SignedPayload) digest() []byte {
+	return signedRecordDigest(signedPayloadLabel, p.Version, p.KeyFingerprint, p.Timestamp, p.Body)
+}
@@ -0,0 +1,75 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedPayload_RoundTrip(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	pubkey, err := wallet.SigningPublicKey()
+	assert.Nil(t, err)
+	publicKey := hex.EncodeToString(pubkey)
+
+	body := "{\"amount\": 50000,\n \"memo\": \"lunch\"}"
+	payload, err := wallet.NewSignedPayload(body)
+	assert.Nil(t, err)
+	assert.Equal(t, body, payload.Body)
+	assert.InDelta(t, time.Now().Unix(), payload.Timestamp, 1)
+	assert.Equal(t, 8, len(payload.KeyFingerprint))
+
+	encoded, err := payload.Encode()
+	assert.Nil(t, err)
+	decoded, err := DecodeSignedPayload(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, payload, decoded)
+	assert.Nil(t, decoded.Verify(publicKey, 60))
+	assert.Nil(t, decoded.Verify(publicKey, 0))
+}
+
+func TestSignedPayload_Rejected(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	pubkey, _ := wallet.SigningPublicKey()
+	publicKey := hex.EncodeToString(pubkey)
+	now := int64(1700000000)
+	payload, err := wallet.signedPayloadAt("{\"a\":1}", now)
+	assert.Nil(t, err)
+	assert.Nil(t, payload.verifyAt(publicKey, 300, now+300))
+
+	// stale, or from too far in the future
+	assert.Equal(t, ErrSignedPayloadExpired, payload.verifyAt(publicKey, 300, now+301))
+	assert.Nil(t, payload.verifyAt(publicKey, 0, now+86400))
+	assert.Equal(t, ErrSignedPayloadExpired, payload.verifyAt(publicKey, 300, now-signedPayloadClockSkew-1))
+
+	// another sender's key
+	other := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	otherPubkey, _ := other.SigningPublicKey()
+	assert.Equal(t, ErrSignedPayloadKeyMismatch, payload.verifyAt(hex.EncodeToString(otherPubkey), 300, now))
+
+	// tampered fields
+	tampered := *payload
+	tampered.Body = "{\"a\":2}"
+	assert.Equal(t, ErrSignedPayloadSignature, tampered.verifyAt(publicKey, 300, now))
+	tampered = *payload
+	tampered.Timestamp++
+	assert.Equal(t, ErrSignedPayloadSignature, tampered.verifyAt(publicKey, 300, now))
+
+	assert.NotNil(t, payload.verifyAt("02abcd", 300, now))
+	assert.NotNil(t, payload.verifyAt(publicKey, -1, now))
+}
+
+func TestNewSignedPayload_Invalid(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	_, err := wallet.NewSignedPayload("{not json")
+	assert.Equal(t, ErrSignedPayloadBody, err)
+
+	assert.Nil(t, wallet.SetRole(WalletRoleHot))
+	_, err = wallet.NewSignedPayload("{}")
+	assert.Equal(t, ErrWatchOnlyWallet, err)
+
+	_, err = DecodeSignedPayload("{\"version\":2}")
+	assert.NotNil(t, err)
+}
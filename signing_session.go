@@ -0,0 +1,211 @@
+package cnlib
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Following errors are returned by a SigningSession which can no longer sign.
+var (
+	ErrSigningSessionExpired   = errors.New("signing session has expired")
+	ErrSigningSessionExhausted = errors.New("signing session has no operations remaining")
+	ErrSigningSessionClosed    = errors.New("signing session has been closed")
+)
+
+/// Type Definitions
+
+// SigningSession holds a wallet's key material, unlocked once from the user's words or decrypted seed, for a bounded
+// number of operations or seconds, whichever runs out first, such as the signatures of a batched send. Once either
+// runs out, or the session is closed, the session zeroes the wallet's master key, seed and cached keys, and every
+// operation returns error, so secrets do not stay in memory for the life of the app. The session wipes itself when it
+// expires even if unused. Safe for concurrent use.
+//
+// Key material already handed to the caller, such as the words passed to `NewSigningSessionFromWords`, is not wiped.
+type SigningSession struct {
+	mu        sync.Mutex
+	wallet    *HDWallet // nil once wiped
+	remaining int
+	expiresAt time.Time
+	timer     *time.Timer
+	err       error // reason the session was wiped
+}
+
+/// Constructors
+
+// NewSigningSessionFromWords unlocks a wallet from words for a session allowing up to maxOperations operations within
+// ttlSeconds. Returns error if either limit is not positive, or the words are invalid.
+func NewSigningSessionFromWords(wordString string, basecoin *BaseCoin, maxOperations int, ttlSeconds int64) (*SigningSession, error) {
+	if err := validateSigningSessionLimits(maxOperations, ttlSeconds); err != nil {
+		return nil, err
+	}
+	language, err := DetectWordListLanguage(wordString)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateWordStringForLanguage(wordString, language); err != nil {
+		return nil, err
+	}
+	wallet := NewHDWalletFromWords(wordString, basecoin)
+	if wallet == nil {
+		return nil, errors.New("unable to create wallet from words")
+	}
+	return newSigningSession(wallet, maxOperations, time.Duration(ttlSeconds)*time.Second), nil
+}
+
+// NewSigningSessionFromSeed unlocks a wallet from a raw BIP32 seed, as `NewHDWalletFromSeed`, for a session allowing
+// up to maxOperations operations within ttlSeconds. The session copies the seed; callers should zero theirs once the
+// session is created.
+func NewSigningSessionFromSeed(seed []byte, basecoin *BaseCoin, maxOperations int, ttlSeconds int64) (*SigningSession, error) {
+	if err := validateSigningSessionLimits(maxOperations, ttlSeconds); err != nil {
+		return nil, err
+	}
+	wallet, err := NewHDWalletFromSeed(seed, basecoin)
+	if err != nil {
+		return nil, err
+	}
+	return newSigningSession(wallet, maxOperations, time.Duration(ttlSeconds)*time.Second), nil
+}
+
+/// Receiver functions
+
+// SignData signs a message with the wallet's signing key (m/42), as `HDWallet.SignData`, using one operation.
+func (s *SigningSession) SignData(message []byte) ([]byte, error) {
+	var signature []byte
+	err := s.perform(func(wallet *HDWallet) (err error) {
+		signature, err = wallet.SignData(message)
+		return err
+	})
+	return signature, err
+}
+
+// BuildTransactionMetadata builds and signs a transaction, as `HDWallet.BuildTransactionMetadata`, using one
+// operation.
+func (s *SigningSession) BuildTransactionMetadata(data *TransactionData) (*TransactionMetadata, error) {
+	var meta *TransactionMetadata
+	err := s.perform(func(wallet *HDWallet) (err error) {
+		meta, err = wallet.BuildTransactionMetadata(data)
+		return err
+	})
+	return meta, err
+}
+
+// SignPSBT signs the wallet's inputs of a PSBT, as `HDWallet.SignPSBT`, using one operation.
+func (s *SigningSession) SignPSBT(encodedPSBT string, upTo int) (string, error) {
+	var signed string
+	err := s.perform(func(wallet *HDWallet) (err error) {
+		signed, err = wallet.SignPSBT(encodedPSBT, upTo)
+		return err
+	})
+	return signed, err
+}
+
+// RemainingOperations returns the number of operations the session may still perform, 0 once it has been wiped.
+func (s *SigningSession) RemainingOperations() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.wallet == nil {
+		return 0
+	}
+	return s.remaining
+}
+
+// ExpiresAt returns the time, in seconds since unix epoch, after which the session can no longer sign.
+func (s *SigningSession) ExpiresAt() int64 {
+	return s.expiresAt.Unix()
+}
+
+// IsActive returns true until the session has been wiped.
+func (s *SigningSession) IsActive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wallet != nil
+}
+
+// Close wipes the session's key material now. Further operations return ErrSigningSessionClosed. Closing a wiped
+// session has no effect.
+func (s *SigningSession) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wipe(ErrSigningSessionClosed)
+}
+
+/// Unexported functions
+
+func newSigningSession(wallet *HDWallet, maxOperations int, ttl time.Duration) *SigningSession {
+	s := &SigningSession{
+		wallet:    wallet,
+		remaining: maxOperations,
+		expiresAt: time.Now().Add(ttl),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timer = time.AfterFunc(ttl, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.wipe(ErrSigningSessionExpired)
+	})
+	return s
+}
+
+// perform runs an operation with the session's wallet if the session is active and unexpired, counting it against
+// the session's operations whether or not it succeeds, and wipes the session once none remain.
+func (s *SigningSession) perform(operation func(wallet *HDWallet) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.wallet != nil && !time.Now().Before(s.expiresAt) {
+		s.wipe(ErrSigningSessionExpired)
+	}
+	if s.wallet == nil {
+		return s.err
+	}
+
+	s.remaining--
+	err := operation(s.wallet)
+	if s.remaining <= 0 {
+		s.wipe(ErrSigningSessionExhausted)
+	}
+	return err
+}
+
+// wipe zeroes the session's key material, recording why. The caller must hold the session's lock.
+func (s *SigningSession) wipe(reason error) {
+	if s.wallet == nil {
+		return
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.wallet.wipePrivateKeys()
+	s.wallet = nil
+	s.err = reason
+}
+
+// wipePrivateKeys zeroes and discards the wallet's master key, seed and cached keys, leaving it watch-only.
+func (wallet *HDWallet) wipePrivateKeys() {
+	if wallet.accountPublicKey == nil {
+		// a lite wallet keeps the account key it would otherwise derive from the master key
+		wallet.accountPublicKey, _ = wallet.accountExtendedKey()
+	}
+	if wallet.masterPrivateKey != nil {
+		wallet.masterPrivateKey.Zero()
+		wallet.masterPrivateKey = nil
+	}
+	for i := range wallet.seed {
+		wallet.seed[i] = 0
+	}
+	wallet.seed = nil
+	wallet.WalletWords = ""
+	wallet.keyCache.wipe()
+}
+
+func validateSigningSessionLimits(maxOperations int, ttlSeconds int64) error {
+	if maxOperations <= 0 {
+		return errors.New("max operations must be positive")
+	}
+	if ttlSeconds <= 0 {
+		return errors.New("ttl must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package cnlib
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSigningSession_Invalid(t *testing.T) {
+	_, err := NewSigningSessionFromWords(w, BaseCoinBip84MainNet, 0, 60)
+	assert.NotNil(t, err)
+	_, err = NewSigningSessionFromWords(w, BaseCoinBip84MainNet, 1, 0)
+	assert.NotNil(t, err)
+	_, err = NewSigningSessionFromWords("abandon abandon abandon", BaseCoinBip84MainNet, 1, 60)
+	assert.NotNil(t, err)
+	_, err = NewSigningSessionFromSeed([]byte{0x01}, BaseCoinBip84MainNet, 1, 60)
+	assert.NotNil(t, err)
+}
+
+func TestSigningSession_SignData(t *testing.T) {
+	session, err := NewSigningSessionFromWords(w, BaseCoinBip84MainNet, 2, 60)
+	assert.Nil(t, err)
+	defer session.Close()
+	assert.True(t, session.IsActive())
+	assert.Equal(t, 2, session.RemainingOperations())
+	assert.InDelta(t, time.Now().Unix()+60, session.ExpiresAt(), 1)
+
+	message := []byte("batched send")
+	signature, err := session.SignData(message)
+	assert.Nil(t, err)
+	expected, _ := NewHDWalletFromWords(w, BaseCoinBip84MainNet).SignData(message)
+	assert.Equal(t, expected, signature)
+	assert.Equal(t, 1, session.RemainingOperations())
+}
+
+func TestSigningSession_Exhausted(t *testing.T) {
+	session, err := NewSigningSessionFromWords(w, BaseCoinBip84MainNet, 1, 60)
+	assert.Nil(t, err)
+	wallet := session.wallet
+	cache := wallet.keyCache
+
+	_, err = session.SignData([]byte("only"))
+	assert.Nil(t, err)
+	assert.False(t, session.IsActive())
+	assert.Equal(t, 0, session.RemainingOperations())
+
+	_, err = session.SignData([]byte("again"))
+	assert.Equal(t, ErrSigningSessionExhausted, err)
+
+	// the wallet's key material has been wiped
+	assert.True(t, wallet.IsWatchOnly())
+	assert.Equal(t, "", wallet.WalletWords)
+	assert.Nil(t, cache.signingKey)
+	assert.Equal(t, 0, len(cache.chainKeys))
+}
+
+func TestSigningSession_Close(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x0f}, 32)
+	session, err := NewSigningSessionFromSeed(seed, BaseCoinBip84MainNet, 5, 60)
+	assert.Nil(t, err)
+	wallet := session.wallet
+	walletSeed := wallet.seed
+
+	session.Close()
+	session.Close()
+	assert.False(t, session.IsActive())
+	assert.Equal(t, make([]byte, 32), walletSeed)
+	assert.Equal(t, bytes.Repeat([]byte{0x0f}, 32), seed)
+
+	_, err = session.SignPSBT("", 10)
+	assert.Equal(t, ErrSigningSessionClosed, err)
+}
+
+func TestSigningSession_Expires(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	session := newSigningSession(wallet, 5, 20*time.Millisecond)
+
+	assert.Eventually(t, func() bool { return !session.IsActive() }, time.Second, 5*time.Millisecond)
+	assert.True(t, wallet.IsWatchOnly())
+	_, err := session.SignData([]byte("late"))
+	assert.Equal(t, ErrSigningSessionExpired, err)
+}
+
+func TestSigningSession_BuildTransactionMetadata(t *testing.T) {
+	session, err := NewSigningSessionFromWords(w, BaseCoinBip84MainNet, 3, 60)
+	assert.Nil(t, err)
+	defer session.Close()
+
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	utxoAddress, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	script, err := AddressScript(utxoAddress.Address)
+	assert.Nil(t, err)
+
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 0)
+	data := NewTransactionDataStandard("3BgxxADLtnoKu9oytQiiVzYUqvo8weCVy9", BaseCoinBip84MainNet, 50000, 10, changePath, 600000, NewRBFOption(AllowedToBeRBF))
+	utxo := NewUTXO("4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b", 0, 100000, utxoAddress.DerivationPath, nil, true)
+	utxo.SetPrevout(script, 100000)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	meta, err := session.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	expected, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.EncodedTx, meta.EncodedTx)
+	assert.Equal(t, 2, session.RemainingOperations())
+
+	// failed operations still count against the session
+	_, err = session.SignPSBT("not a psbt", 10)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, session.RemainingOperations())
+}
@@ -0,0 +1,186 @@
+package cnlib
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// Following constants are used for StandardnessViolation.Kind.
+const (
+	ViolationKindDustOutput          int = 0
+	ViolationKindTxWeight            int = 1
+	ViolationKindNonStandardScript   int = 2
+	ViolationKindFeeRateTooLow       int = 3
+	ViolationKindNonStandardInput    int = 4
+	ViolationKindNonStandardVersion  int = 5
+	ViolationKindMultipleDataOutputs int = 6
+)
+
+// Bitcoin Core default relay policy. Fee rates are in satoshis per virtual byte.
+const (
+	maxStandardTxWeight      = maxStandardTxVsize * blockchain.WitnessScaleFactor
+	maxStandardTxVersion     = 2
+	maxStandardScriptSigSize = 1650
+	maxStandardBareMultisig  = 3
+	minRelayFeeRate          = 1
+	dustRelayFeeRate         = 3
+)
+
+// sizes of the input spending an output, used to compute the dust threshold of an output as Bitcoin Core does
+const (
+	dustSpendInputSize        = 32 + 4 + 1 + 107 + 4
+	dustSpendWitnessInputSize = 32 + 4 + 1 + 107/blockchain.WitnessScaleFactor + 4
+)
+
+/// Type Definitions
+
+// StandardnessViolation is a single relay policy rule broken by a transaction.
+type StandardnessViolation struct {
+	Kind    int // one of the `ViolationKind` constants
+	Index   int // index of the offending input or output, -1 if the violation applies to the whole transaction
+	Message string
+}
+
+// StandardnessReport is the result of checking a transaction against Bitcoin Core's default relay policy. A
+// non-standard transaction is valid, but is rejected by default mempools and rarely reaches miners.
+type StandardnessReport struct {
+	Txid       string
+	Weight     int
+	Vsize      int
+	FeeRate    int  // fee divided by Vsize, rounded down; only meaningful if IsFeeKnown
+	IsFeeKnown bool // true if all previous outputs were provided
+	violations []*StandardnessViolation
+}
+
+/// Receiver functions
+
+// IsStandard returns true if no violations were found.
+func (r *StandardnessReport) IsStandard() bool {
+	return len(r.violations) == 0
+}
+
+// ViolationCount returns the number of violations found.
+func (r *StandardnessReport) ViolationCount() int {
+	return len(r.violations)
+}
+
+// ViolationAtIndex returns the violation at a given index, or error if out of bounds.
+func (r *StandardnessReport) ViolationAtIndex(index int) (*StandardnessViolation, error) {
+	if index < 0 || index > len(r.violations)-1 {
+		return nil, errors.New("index must be within range of violations")
+	}
+	return r.violations[index], nil
+}
+
+/// Exported functions
+
+// CheckStandardness checks a signed, hex-encoded transaction against common relay policies before broadcast: version,
+// weight, output script types, dust outputs and signature scripts. Previous outputs are optional, but without all of
+// them the fee rate cannot be checked.
+func CheckStandardness(encodedTx string, prevouts *PrevoutSet) (*StandardnessReport, error) {
+	tx, err := decodeMsgTx(encodedTx)
+	if err != nil {
+		return nil, err
+	}
+
+	weight := int(blockchain.GetTransactionWeight(btcutil.NewTx(tx)))
+	report := &StandardnessReport{
+		Txid:       tx.TxHash().String(),
+		Weight:     weight,
+		Vsize:      (weight + blockchain.WitnessScaleFactor - 1) / blockchain.WitnessScaleFactor,
+		violations: []*StandardnessViolation{},
+	}
+
+	if tx.Version < 1 || tx.Version > maxStandardTxVersion {
+		report.add(ViolationKindNonStandardVersion, -1, fmt.Sprintf("version %d is not standard", tx.Version))
+	}
+	if weight > maxStandardTxWeight {
+		report.add(ViolationKindTxWeight, -1, fmt.Sprintf("weight %d exceeds %d", weight, maxStandardTxWeight))
+	}
+
+	for i, txIn := range tx.TxIn {
+		if len(txIn.SignatureScript) > maxStandardScriptSigSize {
+			report.add(ViolationKindNonStandardInput, i, "signature script is too large")
+		} else if !txscript.IsPushOnlyScript(txIn.SignatureScript) {
+			report.add(ViolationKindNonStandardInput, i, "signature script is not push only")
+		}
+	}
+
+	dataOutputs := 0
+	for i, txOut := range tx.TxOut {
+		switch txscript.GetScriptClass(txOut.PkScript) {
+		case txscript.NullDataTy:
+			dataOutputs++
+			continue
+		case txscript.MultiSigTy:
+			if keys, _, err := txscript.CalcMultiSigStats(txOut.PkScript); err != nil || keys > maxStandardBareMultisig {
+				report.add(ViolationKindNonStandardScript, i, "bare multisig output has too many keys")
+			}
+		case txscript.NonStandardTy:
+			if !isFutureWitnessProgram(txOut.PkScript) {
+				report.add(ViolationKindNonStandardScript, i, "output script is not standard")
+				continue
+			}
+		}
+
+		if threshold := dustThresholdForOutput(txOut); txOut.Value < threshold {
+			report.add(ViolationKindDustOutput, i, fmt.Sprintf("amount %d is below dust threshold %d", txOut.Value, threshold))
+		}
+	}
+	if dataOutputs > 1 {
+		report.add(ViolationKindMultipleDataOutputs, -1, "transaction has more than one data output")
+	}
+
+	if prevouts != nil && len(tx.TxIn) > 0 {
+		report.IsFeeKnown = true
+		fee := int64(0)
+		for _, txIn := range tx.TxIn {
+			info, ok := prevouts.prevouts[txIn.PreviousOutPoint]
+			if !ok {
+				report.IsFeeKnown = false
+				break
+			}
+			fee += int64(info.Amount)
+		}
+		for _, txOut := range tx.TxOut {
+			fee -= txOut.Value
+		}
+		if report.IsFeeKnown {
+			report.FeeRate = int(fee / int64(report.Vsize))
+			if fee < int64(report.Vsize*minRelayFeeRate) {
+				report.add(ViolationKindFeeRateTooLow, -1, fmt.Sprintf("fee %d is below minimum relay fee %d", fee, report.Vsize*minRelayFeeRate))
+			}
+		}
+	}
+
+	return report, nil
+}
+
+/// Unexported functions
+
+func (r *StandardnessReport) add(kind int, index int, message string) {
+	r.violations = append(r.violations, &StandardnessViolation{Kind: kind, Index: index, Message: message})
+}
+
+// isFutureWitnessProgram returns true for witness programs of versions 1 to 16, such as taproot, which are standard
+// to create but not recognized by the script classifier.
+func isFutureWitnessProgram(pkScript []byte) bool {
+	return txscript.IsWitnessProgram(pkScript) && pkScript[0] != txscript.OP_0
+}
+
+// dustThresholdForOutput returns the smallest amount an output may carry, the cost at the dust relay fee rate of
+// creating and later spending it.
+func dustThresholdForOutput(txOut *wire.TxOut) int64 {
+	size := txOut.SerializeSize()
+	if txscript.IsWitnessProgram(txOut.PkScript) {
+		size += dustSpendWitnessInputSize
+	} else {
+		size += dustSpendInputSize
+	}
+	return int64(size * dustRelayFeeRate)
+}
@@ -0,0 +1,78 @@
+package cnlib
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckStandardness_StandardTransaction(t *testing.T) {
+	prevouts := NewPrevoutSet()
+	err := prevouts.AddPrevout(NewPreviousOutputInfo("bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrn9d67m", "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537))
+	assert.Nil(t, err)
+
+	report, err := CheckStandardness(analysisEncodedTx, prevouts)
+	assert.Nil(t, err)
+	assert.True(t, report.IsStandard())
+	assert.Equal(t, 0, report.ViolationCount())
+	assert.True(t, report.IsFeeKnown)
+	assert.Equal(t, 846/report.Vsize, report.FeeRate)
+}
+
+func TestCheckStandardness_WithoutPrevouts_SkipsFeeRate(t *testing.T) {
+	report, err := CheckStandardness(analysisEncodedTx, nil)
+	assert.Nil(t, err)
+	assert.True(t, report.IsStandard())
+	assert.False(t, report.IsFeeKnown)
+}
+
+func TestCheckStandardness_ReportsViolations(t *testing.T) {
+	txid := "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69"
+	tx, err := NewRawTransaction(3, 0)
+	assert.Nil(t, err)
+	input := NewRawTransactionInput(txid, 0, 0xffffffff)
+	input.SignatureScript = []byte{txscript.OP_DUP}
+	assert.Nil(t, tx.AddInput(input))
+
+	p2wpkh, _ := P2WPKHScript(bytes.Repeat([]byte{1}, 20))
+	p2tr, _ := P2TRScript(bytes.Repeat([]byte{2}, 32))
+	data, _ := OpReturnScript([]byte("cnlib"))
+	assert.Nil(t, tx.AddOutput(NewRawTransactionOutput(100, p2wpkh)))
+	assert.Nil(t, tx.AddOutput(NewRawTransactionOutput(10000, []byte{txscript.OP_TRUE})))
+	assert.Nil(t, tx.AddOutput(NewRawTransactionOutput(10000, p2tr)))
+	assert.Nil(t, tx.AddOutput(NewRawTransactionOutput(0, data)))
+	assert.Nil(t, tx.AddOutput(NewRawTransactionOutput(0, data)))
+	encoded, err := tx.Encode()
+	assert.Nil(t, err)
+
+	prevouts := NewPrevoutSet()
+	assert.Nil(t, prevouts.AddPrevout(NewPreviousOutputInfo("", txid, 0, 20100)))
+
+	report, err := CheckStandardness(encoded, prevouts)
+	assert.Nil(t, err)
+	assert.False(t, report.IsStandard())
+
+	expected := []StandardnessViolation{
+		{Kind: ViolationKindNonStandardVersion, Index: -1},
+		{Kind: ViolationKindNonStandardInput, Index: 0},
+		{Kind: ViolationKindDustOutput, Index: 0},
+		{Kind: ViolationKindNonStandardScript, Index: 1},
+		{Kind: ViolationKindMultipleDataOutputs, Index: -1},
+		{Kind: ViolationKindFeeRateTooLow, Index: -1},
+	}
+	if assert.Equal(t, len(expected), report.ViolationCount()) {
+		for i, e := range expected {
+			violation, err := report.ViolationAtIndex(i)
+			assert.Nil(t, err)
+			assert.Equal(t, e.Kind, violation.Kind)
+			assert.Equal(t, e.Index, violation.Index)
+		}
+	}
+	violation, _ := report.ViolationAtIndex(2)
+	assert.Equal(t, "amount 100 is below dust threshold 294", violation.Message)
+
+	_, err = report.ViolationAtIndex(len(expected))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,223 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// staticChannelBackupMultiVersion is the only version of LND's multi-channel backup format.
+const staticChannelBackupMultiVersion = 0
+
+// channelBackupKeysSize is the size of the fields following a channel backup's capacity: the local CSV delay and 5 key
+// locators, the remote CSV delay and 5 public keys, and the shachain root public key and locator.
+const channelBackupKeysSize = 2 + 5*8 + 2 + 5*33 + 33 + 8
+
+// Following errors are returned for unusable static channel backups.
+var (
+	ErrChannelBackupVersion   = errors.New("unsupported static channel backup version")
+	ErrChannelBackupMalformed = errors.New("static channel backup is malformed")
+	ErrChannelBackupDecrypt   = errors.New("static channel backup could not be decrypted with the node's key")
+)
+
+/// Type Definitions
+
+// StaticChannelBackup is a parsed LND multi-channel static backup, as exported by `lncli exportchanbackup --all` or
+// the channel.backup file, describing the channels a node can recover after its seed is restored.
+type StaticChannelBackup struct {
+	Version  int
+	channels []*ChannelBackup
+}
+
+// ChannelBackup describes one channel of a StaticChannelBackup. Only the fields needed to identify a channel are
+// parsed; the channel's key locators are validated by length only.
+type ChannelBackup struct {
+	Version             int
+	IsInitiator         bool
+	ChainHash           string // hex-encoded, in display byte order, hash of the genesis block of the channel's chain
+	FundingTxid         string
+	FundingOutputIndex  int
+	ShortChannelID      string // "block:tx:output", or "0:0:0" if unconfirmed
+	RemoteNodePublicKey string // hex-encoded compressed public key of the channel's peer
+	Capacity            int    // in satoshis
+	addresses           []string
+}
+
+/// Constructors
+
+// ParseStaticChannelBackup returns a StaticChannelBackup parsed from a decrypted multi-channel backup. Channels whose
+// version is newer than this library knows are still parsed, as every version shares the leading fields.
+func ParseStaticChannelBackup(plaintext []byte) (*StaticChannelBackup, error) {
+	r := bytes.NewReader(plaintext)
+	var version byte
+	var count uint32
+	if err := lnwire.ReadElements(r, &version); err != nil {
+		return nil, ErrChannelBackupMalformed
+	}
+	if version != staticChannelBackupMultiVersion {
+		return nil, ErrChannelBackupVersion
+	}
+	if err := lnwire.ReadElements(r, &count); err != nil {
+		return nil, ErrChannelBackupMalformed
+	}
+
+	backup := StaticChannelBackup{Version: int(version), channels: []*ChannelBackup{}}
+	for ; count > 0; count-- {
+		channel, err := readChannelBackup(r)
+		if err != nil {
+			return nil, err
+		}
+		backup.channels = append(backup.channels, channel)
+	}
+	if r.Len() != 0 {
+		return nil, ErrChannelBackupMalformed
+	}
+	return &backup, nil
+}
+
+/// Receiver functions
+
+// ChannelCount returns the number of channels in the backup.
+func (b *StaticChannelBackup) ChannelCount() int {
+	return len(b.channels)
+}
+
+// ChannelAtIndex returns the channel at a given index, or error if out of bounds.
+func (b *StaticChannelBackup) ChannelAtIndex(index int) (*ChannelBackup, error) {
+	if index < 0 || index > len(b.channels)-1 {
+		return nil, errors.New("index must be within range of channels")
+	}
+	return b.channels[index], nil
+}
+
+// AddressCount returns the number of network addresses known for the channel's peer.
+func (c *ChannelBackup) AddressCount() int {
+	return len(c.addresses)
+}
+
+// AddressAtIndex returns the peer's network address at a given index, as host:port, or error if out of bounds.
+func (c *ChannelBackup) AddressAtIndex(index int) (string, error) {
+	if index < 0 || index > len(c.addresses)-1 {
+		return "", errors.New("index must be within range of addresses")
+	}
+	return c.addresses[index], nil
+}
+
+// DecryptStaticChannelBackup decrypts and parses a packed multi-channel backup exported by the Lightning node
+// provisioned with `LightningNodeExtendedPrivateKey(nodeIndex)`. LND encrypts backups with XChaCha20-Poly1305, keyed
+// by the SHA256 of its first `LightningKeyFamilyStaticBackup` public key, so a backup from a node with a different
+// seed returns `ErrChannelBackupDecrypt`.
+func (wallet *HDWallet) DecryptStaticChannelBackup(packed []byte, nodeIndex int) (*StaticChannelBackup, error) {
+	plaintext, err := wallet.openStaticChannelBackup(packed, nodeIndex)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStaticChannelBackup(plaintext)
+}
+
+// EncryptStaticChannelBackup validates a decrypted multi-channel backup, and encrypts it to the key of the Lightning
+// node provisioned with `LightningNodeExtendedPrivateKey(nodeIndex)`, in the packed format LND restores from. The
+// result may be stored alongside the wallet's other backups, as only the wallet's seed can decrypt it.
+func (wallet *HDWallet) EncryptStaticChannelBackup(plaintext []byte, nodeIndex int) ([]byte, error) {
+	if _, err := ParseStaticChannelBackup(plaintext); err != nil {
+		return nil, err
+	}
+	aead, err := wallet.staticChannelBackupCipher(nodeIndex)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	// LND passes the nonce as associated data, and prepends it to the ciphertext
+	return aead.Seal(nonce, nonce, plaintext, nonce), nil
+}
+
+/// Unexported functions
+
+func (wallet *HDWallet) openStaticChannelBackup(packed []byte, nodeIndex int) ([]byte, error) {
+	aead, err := wallet.staticChannelBackupCipher(nodeIndex)
+	if err != nil {
+		return nil, err
+	}
+	if len(packed) < chacha20poly1305.NonceSizeX+aead.Overhead() {
+		return nil, ErrChannelBackupMalformed
+	}
+	nonce := packed[:chacha20poly1305.NonceSizeX]
+	plaintext, err := aead.Open(nil, nonce, packed[chacha20poly1305.NonceSizeX:], nonce)
+	if err != nil {
+		return nil, ErrChannelBackupDecrypt
+	}
+	return plaintext, nil
+}
+
+func (wallet *HDWallet) staticChannelBackupCipher(nodeIndex int) (cipher.AEAD, error) {
+	pubkey, err := wallet.lightningFamilyPublicKey(nodeIndex, LightningKeyFamilyStaticBackup, 0)
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256(pubkey.SerializeCompressed())
+	return chacha20poly1305.NewX(key[:])
+}
+
+// readChannelBackup reads one version and length prefixed channel backup, parsing its leading fields.
+func readChannelBackup(r io.Reader) (*ChannelBackup, error) {
+	var version byte
+	var length uint16
+	if err := lnwire.ReadElements(r, &version, &length); err != nil {
+		return nil, ErrChannelBackupMalformed
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r, int64(length)))
+	if err != nil || len(body) != int(length) {
+		return nil, ErrChannelBackupMalformed
+	}
+
+	var (
+		isInitiator    bool
+		chainHash      chainhash.Hash
+		outpoint       wire.OutPoint
+		shortChannelID lnwire.ShortChannelID
+		remotePubkey   *btcec.PublicKey
+		addrs          []net.Addr
+		capacity       btcutil.Amount
+	)
+	br := bytes.NewReader(body)
+	err = lnwire.ReadElements(br, &isInitiator, chainHash[:], &outpoint, &shortChannelID, &remotePubkey, &addrs, &capacity)
+	if err != nil {
+		return nil, ErrChannelBackupMalformed
+	}
+	// the remainder holds the CSV delays, key locators and remote keys of both parties
+	if br.Len() < channelBackupKeysSize {
+		return nil, ErrChannelBackupMalformed
+	}
+
+	channel := ChannelBackup{
+		Version:             int(version),
+		IsInitiator:         isInitiator,
+		ChainHash:           chainHash.String(),
+		FundingTxid:         outpoint.Hash.String(),
+		FundingOutputIndex:  int(outpoint.Index),
+		ShortChannelID:      shortChannelID.String(),
+		RemoteNodePublicKey: hex.EncodeToString(remotePubkey.SerializeCompressed()),
+		Capacity:            int(capacity),
+		addresses:           []string{},
+	}
+	for _, addr := range addrs {
+		channel.addresses = append(channel.addresses, addr.String())
+	}
+	return &channel, nil
+}
@@ -0,0 +1,109 @@
+package cnlib
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/assert"
+)
+
+// testChannelBackupPlaintext serializes a multi-channel backup of one channel as LND does.
+func testChannelBackupPlaintext(t *testing.T) []byte {
+	remoteKey, err := btcec.NewPrivateKey(btcec.S256())
+	assert.Nil(t, err)
+	fundingHash, err := chainhash.NewHashFromStr("f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16")
+	assert.Nil(t, err)
+	addr := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 9735}
+
+	var body bytes.Buffer
+	err = lnwire.WriteElements(&body,
+		true,
+		chaincfg.MainNetParams.GenesisHash[:],
+		wire.OutPoint{Hash: *fundingHash, Index: 1},
+		lnwire.NewShortChanIDFromInt(uint64(600000)<<40|uint64(12)<<16|1),
+		remoteKey.PubKey(),
+		[]net.Addr{addr},
+		btcutil.Amount(500000),
+	)
+	assert.Nil(t, err)
+	body.Write(make([]byte, channelBackupKeysSize))
+
+	var multi bytes.Buffer
+	assert.Nil(t, lnwire.WriteElements(&multi, byte(0), uint32(1), byte(1), uint16(body.Len()), body.Bytes()))
+	return multi.Bytes()
+}
+
+func TestParseStaticChannelBackup(t *testing.T) {
+	plaintext := testChannelBackupPlaintext(t)
+	backup, err := ParseStaticChannelBackup(plaintext)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, backup.ChannelCount())
+
+	channel, err := backup.ChannelAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, channel.Version)
+	assert.True(t, channel.IsInitiator)
+	assert.Equal(t, chaincfg.MainNetParams.GenesisHash.String(), channel.ChainHash)
+	assert.Equal(t, "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16", channel.FundingTxid)
+	assert.Equal(t, 1, channel.FundingOutputIndex)
+	assert.Equal(t, "600000:12:1", channel.ShortChannelID)
+	assert.Equal(t, 66, len(channel.RemoteNodePublicKey))
+	assert.Equal(t, 500000, channel.Capacity)
+	assert.Equal(t, 1, channel.AddressCount())
+	addr, err := channel.AddressAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "203.0.113.7:9735", addr)
+
+	_, err = backup.ChannelAtIndex(1)
+	assert.NotNil(t, err)
+}
+
+func TestParseStaticChannelBackup_Invalid(t *testing.T) {
+	plaintext := testChannelBackupPlaintext(t)
+
+	_, err := ParseStaticChannelBackup(plaintext[:len(plaintext)-1])
+	assert.Equal(t, ErrChannelBackupMalformed, err)
+
+	_, err = ParseStaticChannelBackup(append(plaintext, 0))
+	assert.Equal(t, ErrChannelBackupMalformed, err)
+
+	badVersion := append([]byte{1}, plaintext[1:]...)
+	_, err = ParseStaticChannelBackup(badVersion)
+	assert.Equal(t, ErrChannelBackupVersion, err)
+
+	_, err = ParseStaticChannelBackup(nil)
+	assert.Equal(t, ErrChannelBackupMalformed, err)
+}
+
+func TestEncryptStaticChannelBackup_RoundTrip(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	plaintext := testChannelBackupPlaintext(t)
+
+	packed, err := wallet.EncryptStaticChannelBackup(plaintext, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, len(plaintext)+24+16, len(packed))
+
+	backup, err := wallet.DecryptStaticChannelBackup(packed, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, backup.ChannelCount())
+
+	_, err = wallet.DecryptStaticChannelBackup(packed, 1)
+	assert.Equal(t, ErrChannelBackupDecrypt, err)
+
+	other := NewHDWalletFromWords("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", BaseCoinBip84MainNet)
+	_, err = other.DecryptStaticChannelBackup(packed, 0)
+	assert.Equal(t, ErrChannelBackupDecrypt, err)
+
+	_, err = wallet.DecryptStaticChannelBackup(packed[:30], 0)
+	assert.Equal(t, ErrChannelBackupMalformed, err)
+
+	_, err = wallet.EncryptStaticChannelBackup([]byte{0, 0, 0, 0, 1}, 0)
+	assert.Equal(t, ErrChannelBackupMalformed, err)
+}
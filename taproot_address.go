@@ -0,0 +1,78 @@
+package cnlib
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	taprootWitnessVersion = 1
+	taprootOutputKeySize  = 32
+)
+
+var errNotTaprootAddress = errors.New("address is not a bech32m encoded taproot address")
+
+/// Unexported functions
+
+// buildTaprootAddress returns the BIP86 P2TR address of a public key, committing to no script tree.
+func buildTaprootAddress(path *DerivationPath, pubkey *btcec.PublicKey) (string, error) {
+	return encodeTaprootAddress(taprootOutputKey(pubkey), path.BaseCoin.defaultNetParams())
+}
+
+// encodeTaprootAddress returns the bech32m address of a segwit v1 output paying to a 32-byte x-only output key.
+func encodeTaprootAddress(outputKey []byte, params *chaincfg.Params) (string, error) {
+	if len(outputKey) != taprootOutputKeySize {
+		return "", errors.New("taproot output key must be 32 bytes")
+	}
+	return encodeSegwitAddress(params.Bech32HRPSegwit, taprootWitnessVersion, outputKey)
+}
+
+// decodeTaprootAddress returns the x-only output key of a segwit v1 address for a network, or errNotTaprootAddress
+// if the address is not one.
+func decodeTaprootAddress(address string, params *chaincfg.Params) ([]byte, error) {
+	version, program, err := decodeSegwitAddress(address, params.Bech32HRPSegwit)
+	if err != nil || version != taprootWitnessVersion || len(program) != taprootOutputKeySize {
+		return nil, errNotTaprootAddress
+	}
+	return program, nil
+}
+
+// isTaprootAddress returns true if an address is a valid P2TR address for a network.
+func isTaprootAddress(address string, params *chaincfg.Params) bool {
+	_, err := decodeTaprootAddress(address, params)
+	return err == nil
+}
+
+// pkScriptAddress returns the address of a network an output script pays to, including P2TR outputs, which the
+// pinned btcd cannot extract. Returns an empty string if the script does not pay to a single address.
+func pkScriptAddress(pkScript []byte, params *chaincfg.Params) string {
+	if isTaprootScript(pkScript) {
+		address, err := encodeTaprootAddress(pkScript[2:], params)
+		if err != nil {
+			return ""
+		}
+		return address
+	}
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, params)
+	if err != nil || len(addrs) != 1 {
+		return ""
+	}
+	return addrs[0].EncodeAddress()
+}
+
+// pkScriptForNetworkAddress returns the output script paying to an address of a network, including segwit v1 and
+// later addresses.
+func pkScriptForNetworkAddress(address string, params *chaincfg.Params) ([]byte, error) {
+	if version, program, err := decodeSegwitAddress(address, params.Bech32HRPSegwit); err == nil && version > 0 {
+		return witnessProgramScript(version, program)
+	}
+	decoded, err := btcutil.DecodeAddress(address, params)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(decoded)
+}
@@ -0,0 +1,104 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiveAddressForIndex_BIP86Vectors(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+
+	first, err := wallet.ReceiveAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr", first.Address)
+
+	second, err := wallet.ReceiveAddressForIndex(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh", second.Address)
+
+	change, err := wallet.ChangeAddressForIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7", change.Address)
+
+	xpub, err := wallet.AccountExtendedPublicKey()
+	assert.Nil(t, err)
+	assert.Equal(t, "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ", xpub)
+
+	found, err := wallet.CheckForAddress("bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, found.DerivationPath.Index)
+
+	hrp, err := BaseCoinBip86MainNet.GetBech32HRP()
+	assert.Nil(t, err)
+	assert.Equal(t, "bc", hrp)
+}
+
+func TestDecodeTaprootAddress(t *testing.T) {
+	outputKey, err := decodeTaprootAddress("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", &chaincfg.MainNetParams)
+	assert.Nil(t, err)
+	assert.Equal(t, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", hex.EncodeToString(outputKey))
+
+	encoded, err := encodeTaprootAddress(outputKey, &chaincfg.MainNetParams)
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", encoded)
+
+	upper, err := decodeTaprootAddress("BC1P0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQZK5JJ0", &chaincfg.MainNetParams)
+	assert.Nil(t, err)
+	assert.Equal(t, outputKey, upper)
+
+	_, err = decodeTaprootAddress("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj1", &chaincfg.MainNetParams)
+	assert.NotNil(t, err)
+
+	_, err = decodeTaprootAddress("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", &chaincfg.MainNetParams)
+	assert.Equal(t, errNotTaprootAddress, err)
+	_, err = decodeTaprootAddress("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", &chaincfg.RegressionNetParams)
+	assert.Equal(t, errNotTaprootAddress, err)
+
+	assert.Nil(t, AddressIsValidSegwitAddress("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"))
+}
+
+func TestBytesPerOutputAddress_Taproot(t *testing.T) {
+	size, err := BaseCoinBip84MainNet.bytesPerOutputAddress("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0")
+	assert.Nil(t, err)
+	assert.Equal(t, p2trOutputSize, size)
+
+	assert.Equal(t, p2trOutputSize, BaseCoinBip86MainNet.bytesPerChangeOuptut())
+	inputSize, err := BaseCoinBip86MainNet.bytesPerInput(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, p2trKeyPathInputSize, inputSize)
+}
+
+func TestBuildTransaction_BIP86UTXO_SignsKeyPath(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	path := NewDerivationPath(BaseCoinBip86MainNet, 0, 0)
+	prevScript, _, err := wallet.prevoutScriptsForPath(path)
+	assert.Nil(t, err)
+	utxo := NewUTXO(descriptorTestTxid, 0, 100000, path, nil, true)
+	utxo.SetPrevout(prevScript, 100000)
+
+	changePath := NewDerivationPath(BaseCoinBip86MainNet, 1, 0)
+	data := NewTransactionDataStandard("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", BaseCoinBip86MainNet, 50000, 5, changePath, 590582, NewRBFOption(AllowedToBeRBF))
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	meta, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7", meta.TransactionChangeMetadata.Address)
+	raw, err := hex.DecodeString(meta.EncodedTx)
+	assert.Nil(t, err)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	assert.Nil(t, tx.Deserialize(bytes.NewReader(raw)))
+
+	for _, txOut := range tx.TxOut {
+		assert.True(t, isTaprootScript(txOut.PkScript))
+	}
+	sigHash, err := taprootKeyPathSigHash(tx, 0, []*wire.TxOut{wire.NewTxOut(100000, prevScript)})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(tx.TxIn[0].Witness))
+	assert.Nil(t, schnorrVerify(prevScript[2:], sigHash, tx.TxIn[0].Witness[0]))
+}
@@ -0,0 +1,91 @@
+package cnlib
+
+import "errors"
+
+/// Type Definitions
+
+// TaprootMigrationReport estimates the fee savings of consolidating a wallet's utxos into a single P2TR output.
+// Add all of the wallet's utxos one at a time using `AddUTXO`, as gomobile does not support custom arrays/slices.
+type TaprootMigrationReport struct {
+	basecoin *BaseCoin
+	utxos    []*UTXO
+}
+
+// TaprootMigrationEstimate holds the result of a TaprootMigrationReport for a given pair of fee rates.
+type TaprootMigrationEstimate struct {
+	MigrationFeeRate int
+	FutureFeeRate    int
+	MigrationFee     int // fee to consolidate all utxos into one P2TR output at MigrationFeeRate
+	CurrentSpendFee  int // fee to spend all utxos as they are at FutureFeeRate
+	TaprootSpendFee  int // fee to spend the consolidated P2TR output at FutureFeeRate
+	Savings          int // CurrentSpendFee minus MigrationFee and TaprootSpendFee, may be negative
+	IsRecommended    bool
+}
+
+/// Constructors
+
+// NewTaprootMigrationReport returns a pointer to an empty TaprootMigrationReport for the given BaseCoin.
+func NewTaprootMigrationReport(basecoin *BaseCoin) *TaprootMigrationReport {
+	return &TaprootMigrationReport{basecoin: basecoin, utxos: []*UTXO{}}
+}
+
+/// Receiver functions
+
+// AddUTXO adds a utxo to the set being analyzed.
+func (r *TaprootMigrationReport) AddUTXO(utxo *UTXO) {
+	r.utxos = append(r.utxos, utxo)
+}
+
+// UtxoCount returns the number of utxos being analyzed.
+func (r *TaprootMigrationReport) UtxoCount() int {
+	return len(r.utxos)
+}
+
+// EstimateAtFeeRates estimates the savings of migrating now at migrationFeeRate, then spending later at futureFeeRate.
+// Call repeatedly with different rates to chart the savings of migrating during a low-fee period.
+func (r *TaprootMigrationReport) EstimateAtFeeRates(migrationFeeRate int, futureFeeRate int) (*TaprootMigrationEstimate, error) {
+	if migrationFeeRate < 0 || futureFeeRate < 0 {
+		return nil, errors.New("fee rate cannot be negative")
+	}
+	if len(r.utxos) == 0 {
+		return nil, errors.New("no utxos to migrate")
+	}
+
+	inputBytes, err := r.inputBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	migrationBytes := baseSize + inputBytes + p2trOutputSize
+
+	estimate := TaprootMigrationEstimate{
+		MigrationFeeRate: migrationFeeRate,
+		FutureFeeRate:    futureFeeRate,
+		MigrationFee:     migrationBytes * migrationFeeRate,
+		CurrentSpendFee:  inputBytes * futureFeeRate,
+		TaprootSpendFee:  p2trKeyPathInputSize * futureFeeRate,
+	}
+	estimate.Savings = estimate.CurrentSpendFee - estimate.MigrationFee - estimate.TaprootSpendFee
+	estimate.IsRecommended = estimate.Savings > 0
+
+	return &estimate, nil
+}
+
+// EstimateAtFeeRate estimates the savings of migrating and later spending at the same fee rate.
+func (r *TaprootMigrationReport) EstimateAtFeeRate(feeRate int) (*TaprootMigrationEstimate, error) {
+	return r.EstimateAtFeeRates(feeRate, feeRate)
+}
+
+/// Unexported functions
+
+func (r *TaprootMigrationReport) inputBytes() (int, error) {
+	total := 0
+	for _, utxo := range r.utxos {
+		bytes, err := r.basecoin.bytesPerInput(utxo)
+		if err != nil {
+			return 0, err
+		}
+		total += bytes
+	}
+	return total, nil
+}
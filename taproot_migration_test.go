@@ -0,0 +1,42 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaprootMigrationReport_LowFeeMigration_IsRecommended(t *testing.T) {
+	report := NewTaprootMigrationReport(BaseCoinBip49MainNet)
+	for i := 0; i < 5; i++ {
+		path := NewDerivationPath(BaseCoinBip49MainNet, 0, i)
+		report.AddUTXO(NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", i, 100000, path, nil, true))
+	}
+
+	estimate, err := report.EstimateAtFeeRates(1, 50)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 5, report.UtxoCount())
+	assert.Equal(t, (baseSize+5*p2shSegwitInputSize+p2trOutputSize)*1, estimate.MigrationFee)
+	assert.Equal(t, 5*p2shSegwitInputSize*50, estimate.CurrentSpendFee)
+	assert.Equal(t, p2trKeyPathInputSize*50, estimate.TaprootSpendFee)
+	assert.Equal(t, estimate.CurrentSpendFee-estimate.MigrationFee-estimate.TaprootSpendFee, estimate.Savings)
+	assert.True(t, estimate.IsRecommended)
+}
+
+func TestTaprootMigrationReport_SingleNativeUTXO_NotRecommended(t *testing.T) {
+	report := NewTaprootMigrationReport(BaseCoinBip84MainNet)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	report.AddUTXO(NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", 0, 100000, path, nil, true))
+
+	estimate, err := report.EstimateAtFeeRate(10)
+	assert.Nil(t, err)
+	assert.False(t, estimate.IsRecommended)
+}
+
+func TestTaprootMigrationReport_NoUTXOs_ReturnsError(t *testing.T) {
+	report := NewTaprootMigrationReport(BaseCoinBip84MainNet)
+	estimate, err := report.EstimateAtFeeRate(10)
+	assert.NotNil(t, err)
+	assert.Nil(t, estimate)
+}
@@ -0,0 +1,273 @@
+package cnlib
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BIP342 multisig leaves, "<key1> OP_CHECKSIG <key2> OP_CHECKSIGADD ... <keyN> OP_CHECKSIGADD <k> OP_NUMEQUAL",
+// which verify one signature per key present instead of OP_CHECKMULTISIG's trial matching, and reveal only the keys
+// of the leaf spent.
+
+const (
+	opCheckSigAdd          = 0xba // OP_CHECKSIGADD, which the btcd version in use predates
+	maxTaprootMultisigKeys = 999  // BIP387 multi_a limit
+)
+
+// Following errors are returned when building or spending a TaprootMultisig leaf.
+var (
+	ErrTaprootMultisigThreshold    = errors.New("threshold must be between 1 and the number of keys")
+	ErrTaprootMultisigKeyDuplicate = errors.New("public key is already in the multisig")
+	ErrTaprootMultisigLeaf         = errors.New("leaf script is not a CHECKSIGADD multisig")
+	ErrTaprootMultisigUnknownKey   = errors.New("public key is not in the multisig leaf")
+	ErrTaprootMultisigSignature    = errors.New("signature does not verify for the public key")
+	ErrTaprootMultisigInsufficient = errors.New("not enough signatures to satisfy the multisig threshold")
+)
+
+/// Type Definitions
+
+// TaprootMultisig is a k-of-n tapscript multisig leaf. Keys are added one at a time, as gomobile does not support
+// custom arrays/slices, and appear in the script in the order added.
+type TaprootMultisig struct {
+	Threshold int
+	keys      [][]byte // x-only
+}
+
+// TaprootMultisigSpend collects the signatures of an input spending an output of a script tree through a
+// TaprootMultisig leaf, from the wallet and from cosigners, then finalizes the input once the threshold is met.
+type TaprootMultisigSpend struct {
+	spend      *taprootLeafSpend
+	threshold  int
+	keys       [][]byte
+	signatures [][]byte // by key index, nil if not signed
+}
+
+/// Constructors
+
+// NewTaprootMultisig returns a pointer to a TaprootMultisig requiring threshold signatures, with no keys.
+func NewTaprootMultisig(threshold int) *TaprootMultisig {
+	return &TaprootMultisig{Threshold: threshold}
+}
+
+// NewTaprootMultisigSpend returns a pointer to a TaprootMultisigSpend of an input of a hex-encoded transaction,
+// spending an output of a script tree through the multisig leaf at leafIndex. Previous outputs of every input are
+// required, with SelectedAddress the address each paid to.
+func (wallet *HDWallet) NewTaprootMultisigSpend(encodedTx string, inputIndex int, prevouts *PrevoutSet, tree *TaprootScriptTree, leafIndex int) (*TaprootMultisigSpend, error) {
+	spend, err := wallet.newTaprootLeafSpend(encodedTx, inputIndex, prevouts, tree, leafIndex)
+	if err != nil {
+		return nil, err
+	}
+	threshold, keys, err := parseTaprootMultisigScript(spend.script)
+	if err != nil {
+		return nil, err
+	}
+	return &TaprootMultisigSpend{
+		spend:      spend,
+		threshold:  threshold,
+		keys:       keys,
+		signatures: make([][]byte, len(keys)),
+	}, nil
+}
+
+/// Receiver functions
+
+// AddPublicKey adds a 32-byte x-only public key, such as one returned by `SchnorrPublicKeyForPath`.
+func (m *TaprootMultisig) AddPublicKey(pubkey []byte) error {
+	if len(pubkey) != schnorrPubKeySize {
+		return errors.New("public key must be 32 bytes")
+	}
+	if _, _, err := liftX(pubkey); err != nil {
+		return err
+	}
+	if len(m.keys) >= maxTaprootMultisigKeys {
+		return errors.New("multisig cannot have more than 999 keys")
+	}
+	for _, key := range m.keys {
+		if bytes.Equal(key, pubkey) {
+			return ErrTaprootMultisigKeyDuplicate
+		}
+	}
+	m.keys = append(m.keys, append([]byte{}, pubkey...))
+	return nil
+}
+
+// KeyCount returns the number of keys in the multisig.
+func (m *TaprootMultisig) KeyCount() int {
+	return len(m.keys)
+}
+
+// KeyAtIndex returns the x-only public key at a given index, or error if out of bounds.
+func (m *TaprootMultisig) KeyAtIndex(index int) ([]byte, error) {
+	if index < 0 || index > len(m.keys)-1 {
+		return nil, errors.New("index must be within range of keys")
+	}
+	return m.keys[index], nil
+}
+
+// Script returns the leaf script, or ErrTaprootMultisigThreshold if the threshold is not between 1 and the number of
+// keys.
+func (m *TaprootMultisig) Script() ([]byte, error) {
+	if m.Threshold < 1 || m.Threshold > len(m.keys) {
+		return nil, ErrTaprootMultisigThreshold
+	}
+	builder := txscript.NewScriptBuilder()
+	for i, key := range m.keys {
+		builder.AddData(key)
+		if i == 0 {
+			builder.AddOp(txscript.OP_CHECKSIG)
+		} else {
+			builder.AddOp(opCheckSigAdd)
+		}
+	}
+	return builder.AddInt64(int64(m.Threshold)).AddOp(txscript.OP_NUMEQUAL).Script()
+}
+
+// AddMultisigLeaf adds the leaf of a TaprootMultisig, returning its index.
+func (t *TaprootScriptTree) AddMultisigLeaf(multisig *TaprootMultisig) (int, error) {
+	if multisig == nil {
+		return 0, errors.New("multisig cannot be nil")
+	}
+	script, err := multisig.Script()
+	if err != nil {
+		return 0, err
+	}
+	return t.AddLeaf(script)
+}
+
+// Threshold returns the number of signatures the leaf requires.
+func (s *TaprootMultisigSpend) Threshold() int {
+	return s.threshold
+}
+
+// SignatureCount returns the number of keys of the leaf with a signature.
+func (s *TaprootMultisigSpend) SignatureCount() int {
+	count := 0
+	for _, signature := range s.signatures {
+		if signature != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// SigHash returns the 32-byte BIP342 signature hash each key signs, for cosigners signing elsewhere.
+func (s *TaprootMultisigSpend) SigHash() []byte {
+	return append([]byte{}, s.spend.sigHash...)
+}
+
+// AddSignature adds a cosigner's 64-byte BIP340 signature of `SigHash` for an x-only public key of the leaf. Returns
+// ErrTaprootMultisigUnknownKey if the key is not in the leaf, or ErrTaprootMultisigSignature if the signature does
+// not verify.
+func (s *TaprootMultisigSpend) AddSignature(pubkey []byte, signature []byte) error {
+	index := s.keyIndex(pubkey)
+	if index < 0 {
+		return ErrTaprootMultisigUnknownKey
+	}
+	if len(signature) != schnorrSignatureSize || schnorrVerify(pubkey, s.spend.sigHash, signature) != nil {
+		return ErrTaprootMultisigSignature
+	}
+	s.signatures[index] = append([]byte{}, signature...)
+	return nil
+}
+
+// Finalize sets the input's witness to the signatures of the first Threshold signed keys, empty for every other key,
+// followed by the leaf script and its control block, and returns the hex-encoded transaction. Returns
+// ErrTaprootMultisigInsufficient if fewer than Threshold keys have signed.
+func (s *TaprootMultisigSpend) Finalize() (string, error) {
+	if s.SignatureCount() < s.threshold {
+		return "", ErrTaprootMultisigInsufficient
+	}
+	// OP_NUMEQUAL requires exactly threshold valid signatures
+	items := make(wire.TxWitness, len(s.keys))
+	used := 0
+	for i, signature := range s.signatures {
+		item := []byte{}
+		if signature != nil && used < s.threshold {
+			item = signature
+			used++
+		}
+		// the first key's signature is consumed first, so must be last on the stack
+		items[len(s.keys)-1-i] = item
+	}
+	return s.spend.finalize(items)
+}
+
+// SignTaprootMultisigSpend adds the signature of the wallet's key at a spending derivation path, whose x-only public key must
+// be in the spend's multisig leaf.
+func (wallet *HDWallet) SignTaprootMultisigSpend(spend *TaprootMultisigSpend, path *DerivationPath) error {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return err
+	}
+	if spend == nil || path == nil {
+		return errors.New("spend and derivation path are required")
+	}
+	if err := requireKeyRole(path, KeyRoleSpending); err != nil {
+		return err
+	}
+	key, err := wallet.keyFactory().indexPrivateKey(path)
+	if err != nil {
+		return err
+	}
+	privateKey, err := key.ECPrivKey()
+	if err != nil {
+		return err
+	}
+	index := spend.keyIndex(padTo32(privateKey.PubKey().X.Bytes()))
+	if index < 0 {
+		return ErrTaprootLeafKeyMismatch
+	}
+	signature, err := spend.spend.sign(privateKey)
+	if err != nil {
+		return err
+	}
+	spend.signatures[index] = signature
+	return nil
+}
+
+/// Unexported functions
+
+// keyIndex returns the index of an x-only public key in the leaf, or -1.
+func (s *TaprootMultisigSpend) keyIndex(pubkey []byte) int {
+	for i, key := range s.keys {
+		if bytes.Equal(key, pubkey) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseTaprootMultisigScript returns the threshold and keys of a script built by `TaprootMultisig.Script`.
+func parseTaprootMultisigScript(script []byte) (int, [][]byte, error) {
+	var keys [][]byte
+	rest := script
+	for len(rest) >= 2+schnorrPubKeySize && rest[0] == txscript.OP_DATA_32 {
+		op := rest[1+schnorrPubKeySize]
+		if (len(keys) == 0 && op != txscript.OP_CHECKSIG) || (len(keys) > 0 && op != opCheckSigAdd) {
+			return 0, nil, ErrTaprootMultisigLeaf
+		}
+		keys = append(keys, rest[1:1+schnorrPubKeySize])
+		rest = rest[2+schnorrPubKeySize:]
+	}
+	if len(keys) == 0 || len(rest) < 2 || rest[len(rest)-1] != txscript.OP_NUMEQUAL {
+		return 0, nil, ErrTaprootMultisigLeaf
+	}
+
+	threshold := 0
+	switch push := rest[:len(rest)-1]; {
+	case len(push) == 1 && push[0] >= txscript.OP_1 && push[0] <= txscript.OP_16:
+		threshold = int(push[0]-txscript.OP_1) + 1
+	case len(push) == 2 && push[0] == txscript.OP_DATA_1 && push[1] > 16 && push[1] < 0x80:
+		threshold = int(push[1])
+	case len(push) == 3 && push[0] == txscript.OP_DATA_2 && push[2] < 0x80 && (push[2] != 0 || push[1] >= 0x80):
+		threshold = int(push[1]) | int(push[2])<<8
+	default:
+		return 0, nil, ErrTaprootMultisigLeaf
+	}
+	if threshold > len(keys) {
+		return 0, nil, ErrTaprootMultisigLeaf
+	}
+	return threshold, keys, nil
+}
@@ -0,0 +1,177 @@
+package cnlib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaprootMultisig_Script(t *testing.T) {
+	keyA, _ := hex.DecodeString("187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27")
+	keyB, _ := hex.DecodeString("d6889cb081036e0faefa3a35157ad71086b123b2b144b649798b494c300a961d")
+	multisig := NewTaprootMultisig(2)
+	assert.Nil(t, multisig.AddPublicKey(keyA))
+	_, err := multisig.Script()
+	assert.Equal(t, ErrTaprootMultisigThreshold, err)
+	assert.Equal(t, ErrTaprootMultisigKeyDuplicate, multisig.AddPublicKey(keyA))
+	assert.Nil(t, multisig.AddPublicKey(keyB))
+	assert.Equal(t, 2, multisig.KeyCount())
+
+	script, err := multisig.Script()
+	assert.Nil(t, err)
+	expected := "20" + hex.EncodeToString(keyA) + "ac" + "20" + hex.EncodeToString(keyB) + "ba" + "52" + "9c"
+	assert.Equal(t, expected, hex.EncodeToString(script))
+
+	threshold, keys, err := parseTaprootMultisigScript(script)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, threshold)
+	assert.Equal(t, [][]byte{keyA, keyB}, keys)
+
+	assert.NotNil(t, multisig.AddPublicKey(keyA[:31]))
+	_, err = multisig.KeyAtIndex(2)
+	assert.NotNil(t, err)
+	multisig.Threshold = 0
+	_, err = multisig.Script()
+	assert.Equal(t, ErrTaprootMultisigThreshold, err)
+}
+
+func TestTaprootMultisig_LargeThresholds(t *testing.T) {
+	multisig := NewTaprootMultisig(0)
+	for multisig.KeyCount() < 200 {
+		key, _ := btcec.NewPrivateKey(btcec.S256())
+		assert.Nil(t, multisig.AddPublicKey(padTo32(key.PubKey().X.Bytes())))
+	}
+	for _, k := range []int{1, 16, 17, 127, 128, 200} {
+		multisig.Threshold = k
+		script, err := multisig.Script()
+		assert.Nil(t, err)
+		threshold, keys, err := parseTaprootMultisigScript(script)
+		assert.Nil(t, err)
+		assert.Equal(t, k, threshold)
+		assert.Equal(t, 200, len(keys))
+	}
+
+	_, _, err := parseTaprootMultisigScript([]byte{0x51})
+	assert.Equal(t, ErrTaprootMultisigLeaf, err)
+	keyLeaf, _ := hex.DecodeString("20d85a959b0290bf19bb89ed43c916be835475d013da4b362117393e25a48229b8ac")
+	_, _, err = parseTaprootMultisigScript(keyLeaf)
+	assert.Equal(t, ErrTaprootMultisigLeaf, err)
+}
+
+func TestTaprootMultisigSpend(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	cosigner, err := btcec.NewPrivateKey(btcec.S256())
+	assert.Nil(t, err)
+	cosignerKey := padTo32(cosigner.PubKey().X.Bytes())
+
+	// 2-of-3 of two wallet keys and a cosigner's, signed by the second wallet key and the cosigner
+	firstPath := NewDerivationPath(BaseCoinBip86MainNet, 0, 1)
+	secondPath := NewDerivationPath(BaseCoinBip86MainNet, 0, 2)
+	firstKey, _ := wallet.SchnorrPublicKeyForPath(firstPath)
+	secondKey, _ := wallet.SchnorrPublicKeyForPath(secondPath)
+	multisig := NewTaprootMultisig(2)
+	assert.Nil(t, multisig.AddPublicKey(firstKey))
+	assert.Nil(t, multisig.AddPublicKey(secondKey))
+	assert.Nil(t, multisig.AddPublicKey(cosignerKey))
+
+	internalKey, _ := wallet.SchnorrPublicKeyForPath(NewDerivationPath(BaseCoinBip86MainNet, 0, 0))
+	tree, _ := NewTaprootScriptTree(internalKey)
+	_, err = tree.AddKeyLeaf(internalKey)
+	assert.Nil(t, err)
+	leaf, err := tree.AddMultisigLeaf(multisig)
+	assert.Nil(t, err)
+	address, _ := tree.Address(BaseCoinBip86MainNet)
+
+	rawTx, _ := NewRawTransaction(2, 0)
+	assert.Nil(t, rawTx.AddInput(NewRawTransactionInput(descriptorTestTxid, 0, 0xfffffffd)))
+	outputScript, _ := AddressScript("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0")
+	assert.Nil(t, rawTx.AddOutput(NewRawTransactionOutput(99000, outputScript)))
+	encodedTx, _ := rawTx.Encode()
+	prevouts := NewPrevoutSet()
+	assert.Nil(t, prevouts.AddPrevout(NewPreviousOutputInfo(address, descriptorTestTxid, 0, 100000)))
+
+	// the key leaf is not a multisig
+	_, err = wallet.NewTaprootMultisigSpend(encodedTx, 0, prevouts, tree, 0)
+	assert.Equal(t, ErrTaprootMultisigLeaf, err)
+
+	spend, err := wallet.NewTaprootMultisigSpend(encodedTx, 0, prevouts, tree, leaf)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, spend.Threshold())
+
+	assert.Nil(t, wallet.SignTaprootMultisigSpend(spend, secondPath))
+	assert.Equal(t, 1, spend.SignatureCount())
+	_, err = spend.Finalize()
+	assert.Equal(t, ErrTaprootMultisigInsufficient, err)
+	assert.Equal(t, ErrTaprootLeafKeyMismatch, wallet.SignTaprootMultisigSpend(spend, NewDerivationPath(BaseCoinBip86MainNet, 0, 3)))
+
+	signature, err := schnorrSign(cosigner, spend.SigHash(), make([]byte, 32))
+	assert.Nil(t, err)
+	assert.Equal(t, ErrTaprootMultisigUnknownKey, spend.AddSignature(internalKey, signature))
+	signature[0] ^= 0x01
+	assert.Equal(t, ErrTaprootMultisigSignature, spend.AddSignature(cosignerKey, signature))
+	signature[0] ^= 0x01
+	assert.Nil(t, spend.AddSignature(cosignerKey, signature))
+	assert.Equal(t, 2, spend.SignatureCount())
+
+	signed, err := spend.Finalize()
+	assert.Nil(t, err)
+	tx, err := decodeMsgTx(signed)
+	assert.Nil(t, err)
+
+	// stack bottom to top: cosigner's, second key's, then an empty item for the first key
+	witness := tx.TxIn[0].Witness
+	assert.Equal(t, 5, len(witness))
+	assert.Equal(t, signature, []byte(witness[0]))
+	assert.Equal(t, 0, len(witness[2]))
+	script, _ := tree.LeafScriptAtIndex(leaf)
+	controlBlock, _ := tree.ControlBlock(leaf)
+	assert.Equal(t, script, []byte(witness[3]))
+	assert.Equal(t, controlBlock, []byte(witness[4]))
+
+	pkScript, _ := tree.PkScript()
+	sigHash, err := taprootSigHash(tx, 0, []*wire.TxOut{wire.NewTxOut(100000, pkScript)}, taprootLeafHash(script))
+	assert.Nil(t, err)
+	assert.Nil(t, schnorrVerify(secondKey, sigHash, witness[1]))
+}
+
+func TestTaprootMultisigSpend_ExactThreshold(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	multisig := NewTaprootMultisig(1)
+	var paths []*DerivationPath
+	for i := 0; i < 3; i++ {
+		path := NewDerivationPath(BaseCoinBip86MainNet, 0, i)
+		key, _ := wallet.SchnorrPublicKeyForPath(path)
+		assert.Nil(t, multisig.AddPublicKey(key))
+		paths = append(paths, path)
+	}
+	internalKey, _ := multisig.KeyAtIndex(0)
+	tree, _ := NewTaprootScriptTree(internalKey)
+	leaf, _ := tree.AddMultisigLeaf(multisig)
+	address, _ := tree.Address(BaseCoinBip86MainNet)
+
+	rawTx, _ := NewRawTransaction(2, 0)
+	assert.Nil(t, rawTx.AddInput(NewRawTransactionInput(descriptorTestTxid, 0, 0xfffffffd)))
+	outputScript, _ := AddressScript("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0")
+	assert.Nil(t, rawTx.AddOutput(NewRawTransactionOutput(99000, outputScript)))
+	encodedTx, _ := rawTx.Encode()
+	prevouts := NewPrevoutSet()
+	assert.Nil(t, prevouts.AddPrevout(NewPreviousOutputInfo(address, descriptorTestTxid, 0, 100000)))
+
+	spend, err := wallet.NewTaprootMultisigSpend(encodedTx, 0, prevouts, tree, leaf)
+	assert.Nil(t, err)
+	for _, path := range paths[1:] {
+		assert.Nil(t, wallet.SignTaprootMultisigSpend(spend, path))
+	}
+
+	// only one of the two signatures is used, as a surplus would fail OP_NUMEQUAL
+	signed, err := spend.Finalize()
+	assert.Nil(t, err)
+	tx, _ := decodeMsgTx(signed)
+	witness := tx.TxIn[0].Witness
+	assert.Equal(t, 0, len(witness[0]))
+	assert.Equal(t, 64, len(witness[1]))
+	assert.Equal(t, 0, len(witness[2]))
+}
@@ -0,0 +1,376 @@
+package cnlib
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BIP341 script trees and BIP342 script path spending, which the btcd version in use predates.
+
+const (
+	taprootLeafTag       = "TapLeaf"
+	taprootBranchTag     = "TapBranch"
+	taprootLeafVersion   = 0xc0
+	maxTaprootLeaves     = 1 << 7 // keeps control blocks within the 128 level limit
+	maxTaprootLockBlocks = 0xffff // BIP68 relative locktimes in blocks are 16 bits
+)
+
+// Following errors are returned when spending an output through a leaf of a TaprootScriptTree.
+var (
+	ErrTaprootScriptTreeMismatch = errors.New("previous output does not pay to the taproot script tree")
+	ErrTaprootLeafKeyMismatch    = errors.New("leaf script does not contain the x-only public key of the signing key")
+)
+
+/// Type Definitions
+
+// TaprootScriptTree is a BIP341 taproot output committing to an internal key and a tree of leaf scripts, such as a
+// key which may spend at any time and a fallback key which may spend only after a relative timelock. The output may
+// be spent with the tweaked internal key, or by satisfying any one leaf script, revealing only that leaf. Add leaves
+// one at a time, as gomobile does not support custom arrays/slices; they are paired in the order added, into a tree
+// as balanced as their number allows.
+type TaprootScriptTree struct {
+	internalKey []byte // x-only
+	leaves      [][]byte
+}
+
+/// Constructors
+
+// NewTaprootScriptTree returns a pointer to a TaprootScriptTree with no leaves, for a 32-byte x-only internal key such
+// as one returned by `SchnorrPublicKeyForPath`. Returns error if the key is not on the curve.
+func NewTaprootScriptTree(internalKey []byte) (*TaprootScriptTree, error) {
+	if len(internalKey) != schnorrPubKeySize {
+		return nil, errors.New("internal key must be 32 bytes")
+	}
+	if _, _, err := liftX(internalKey); err != nil {
+		return nil, err
+	}
+	return &TaprootScriptTree{internalKey: append([]byte{}, internalKey...)}, nil
+}
+
+/// Receiver functions
+
+// AddLeaf adds a tapscript leaf, returning its index.
+func (t *TaprootScriptTree) AddLeaf(script []byte) (int, error) {
+	if len(script) == 0 {
+		return 0, errors.New("leaf script cannot be empty")
+	}
+	if len(t.leaves) >= maxTaprootLeaves {
+		return 0, errors.New("script tree cannot have more than 128 leaves")
+	}
+	t.leaves = append(t.leaves, append([]byte{}, script...))
+	return len(t.leaves) - 1, nil
+}
+
+// AddKeyLeaf adds a leaf spendable by a signature of a 32-byte x-only public key, "<key> OP_CHECKSIG", returning
+// its index.
+func (t *TaprootScriptTree) AddKeyLeaf(pubkey []byte) (int, error) {
+	if len(pubkey) != schnorrPubKeySize {
+		return 0, errors.New("public key must be 32 bytes")
+	}
+	script, err := txscript.NewScriptBuilder().AddData(pubkey).AddOp(txscript.OP_CHECKSIG).Script()
+	if err != nil {
+		return 0, err
+	}
+	return t.AddLeaf(script)
+}
+
+// AddTimelockLeaf adds a leaf spendable by a signature of a 32-byte x-only public key once the output has a given
+// number of confirmations, "<blocks> OP_CHECKSEQUENCEVERIFY OP_DROP <key> OP_CHECKSIG", returning its index. The
+// spending transaction must be version 2 or later, with the input's sequence set to at least blocks.
+func (t *TaprootScriptTree) AddTimelockLeaf(pubkey []byte, blocks int) (int, error) {
+	if len(pubkey) != schnorrPubKeySize {
+		return 0, errors.New("public key must be 32 bytes")
+	}
+	if blocks < 1 || blocks > maxTaprootLockBlocks {
+		return 0, errors.New("blocks must be between 1 and 65535")
+	}
+	script, err := txscript.NewScriptBuilder().
+		AddInt64(int64(blocks)).AddOp(txscript.OP_CHECKSEQUENCEVERIFY).AddOp(txscript.OP_DROP).
+		AddData(pubkey).AddOp(txscript.OP_CHECKSIG).Script()
+	if err != nil {
+		return 0, err
+	}
+	return t.AddLeaf(script)
+}
+
+// LeafCount returns the number of leaves in the tree.
+func (t *TaprootScriptTree) LeafCount() int {
+	return len(t.leaves)
+}
+
+// LeafScriptAtIndex returns the script of the leaf at a given index, or error if out of bounds.
+func (t *TaprootScriptTree) LeafScriptAtIndex(index int) ([]byte, error) {
+	if index < 0 || index > len(t.leaves)-1 {
+		return nil, errors.New("index must be within range of leaves")
+	}
+	return t.leaves[index], nil
+}
+
+// MerkleRoot returns the 32-byte root of the script tree, or nil if the tree has no leaves.
+func (t *TaprootScriptTree) MerkleRoot() []byte {
+	root, _ := t.merkleTree()
+	return root
+}
+
+// OutputKey returns the 32-byte x-only output key, the internal key tweaked with the merkle root. Without leaves, it
+// is the BIP86 output key of the internal key.
+func (t *TaprootScriptTree) OutputKey() ([]byte, error) {
+	outputKey, _, err := taprootTweakedOutputKey(t.internalKey, t.MerkleRoot())
+	return outputKey, err
+}
+
+// PkScript returns the segwit v1 output script paying to the tree.
+func (t *TaprootScriptTree) PkScript() ([]byte, error) {
+	outputKey, err := t.OutputKey()
+	if err != nil {
+		return nil, err
+	}
+	return P2TRScript(outputKey)
+}
+
+// Address returns the bech32m P2TR address paying to the tree on the BaseCoin's network.
+func (t *TaprootScriptTree) Address(basecoin *BaseCoin) (string, error) {
+	if basecoin == nil {
+		return "", errors.New("basecoin cannot be nil")
+	}
+	outputKey, err := t.OutputKey()
+	if err != nil {
+		return "", err
+	}
+	return encodeTaprootAddress(outputKey, basecoin.defaultNetParams())
+}
+
+// ControlBlock returns the BIP341 control block proving the leaf at a given index is committed to by the output key,
+// the last witness item of a script path spend: the leaf version and output key parity, the internal key, then the
+// hashes of the leaf's siblings from the leaf to the root.
+func (t *TaprootScriptTree) ControlBlock(leafIndex int) ([]byte, error) {
+	if leafIndex < 0 || leafIndex > len(t.leaves)-1 {
+		return nil, errors.New("index must be within range of leaves")
+	}
+	root, proofs := t.merkleTree()
+	_, isOdd, err := taprootTweakedOutputKey(t.internalKey, root)
+	if err != nil {
+		return nil, err
+	}
+
+	header := byte(taprootLeafVersion)
+	if isOdd {
+		header |= 0x01
+	}
+	controlBlock := append([]byte{header}, t.internalKey...)
+	for _, sibling := range proofs[leafIndex] {
+		controlBlock = append(controlBlock, sibling...)
+	}
+	return controlBlock, nil
+}
+
+// SignTaprootScriptPathInput signs an input of a hex-encoded transaction spending an output of a script tree through
+// the leaf at leafIndex, with the wallet's key at a spending derivation path, whose x-only public key the leaf script must
+// contain. The input's witness is set to the signature, the leaf script and its control block, which satisfies
+// single-signature leaves such as those of `AddKeyLeaf` and `AddTimelockLeaf`. Previous outputs of every input are
+// required, with SelectedAddress the address each paid to. Returns the hex-encoded transaction.
+func (wallet *HDWallet) SignTaprootScriptPathInput(encodedTx string, inputIndex int, prevouts *PrevoutSet, tree *TaprootScriptTree, leafIndex int, path *DerivationPath) (string, error) {
+	if err := wallet.requirePrivateKeys(); err != nil {
+		return "", err
+	}
+	if path == nil {
+		return "", errors.New("derivation path cannot be nil")
+	}
+	if err := requireKeyRole(path, KeyRoleSpending); err != nil {
+		return "", err
+	}
+	spend, err := wallet.newTaprootLeafSpend(encodedTx, inputIndex, prevouts, tree, leafIndex)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := wallet.keyFactory().indexPrivateKey(path)
+	if err != nil {
+		return "", err
+	}
+	privateKey, err := key.ECPrivKey()
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Contains(spend.script, padTo32(privateKey.PubKey().X.Bytes())) {
+		return "", ErrTaprootLeafKeyMismatch
+	}
+	signature, err := spend.sign(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return spend.finalize(wire.TxWitness{signature})
+}
+
+/// Unexported functions
+
+// taprootLeafSpend is an input of a transaction spending an output of a script tree through one of its leaves.
+type taprootLeafSpend struct {
+	tx           *wire.MsgTx
+	inputIndex   int
+	prevouts     []*wire.TxOut
+	script       []byte
+	controlBlock []byte
+	sigHash      []byte
+}
+
+// newTaprootLeafSpend decodes a transaction, and checks the previous output of an input pays to a script tree whose
+// leaf will be revealed to spend it.
+func (wallet *HDWallet) newTaprootLeafSpend(encodedTx string, inputIndex int, prevouts *PrevoutSet, tree *TaprootScriptTree, leafIndex int) (*taprootLeafSpend, error) {
+	if tree == nil || prevouts == nil {
+		return nil, errors.New("script tree and previous outputs are required")
+	}
+	tx, err := decodeMsgTx(encodedTx)
+	if err != nil {
+		return nil, err
+	}
+	if inputIndex < 0 || inputIndex > len(tx.TxIn)-1 {
+		return nil, errors.New("index must be within range of inputs")
+	}
+	spent, err := wallet.taprootPrevouts(tx, prevouts)
+	if err != nil {
+		return nil, err
+	}
+
+	pkScript, err := tree.PkScript()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(spent[inputIndex].PkScript, pkScript) {
+		return nil, ErrTaprootScriptTreeMismatch
+	}
+	script, err := tree.LeafScriptAtIndex(leafIndex)
+	if err != nil {
+		return nil, err
+	}
+	controlBlock, err := tree.ControlBlock(leafIndex)
+	if err != nil {
+		return nil, err
+	}
+	sigHash, err := taprootSigHash(tx, inputIndex, spent, taprootLeafHash(script))
+	if err != nil {
+		return nil, err
+	}
+	return &taprootLeafSpend{
+		tx:           tx,
+		inputIndex:   inputIndex,
+		prevouts:     spent,
+		script:       script,
+		controlBlock: controlBlock,
+		sigHash:      sigHash,
+	}, nil
+}
+
+// sign returns a BIP340 signature of the spend's signature hash.
+func (s *taprootLeafSpend) sign(privateKey *btcec.PrivateKey) ([]byte, error) {
+	aux, err := randBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	return schnorrSign(privateKey, s.sigHash, aux)
+}
+
+// finalize sets the input's witness to the items satisfying the leaf script, followed by the script and control
+// block, and returns the hex-encoded transaction.
+func (s *taprootLeafSpend) finalize(items wire.TxWitness) (string, error) {
+	witness := append(wire.TxWitness{}, items...)
+	witness = append(witness, s.script, s.controlBlock)
+	s.tx.TxIn[s.inputIndex].Witness = witness
+	s.tx.TxIn[s.inputIndex].SignatureScript = nil
+	return encodeMsgTx(s.tx)
+}
+
+// merkleTree returns the root of the tree, and for each leaf the hashes of its siblings from the leaf to the root.
+// Nodes are paired in order at each level, with an odd node carried up to the next.
+func (t *TaprootScriptTree) merkleTree() ([]byte, [][][]byte) {
+	if len(t.leaves) == 0 {
+		return nil, nil
+	}
+
+	type node struct {
+		hash   []byte
+		leaves []int
+	}
+	proofs := make([][][]byte, len(t.leaves))
+	level := make([]*node, len(t.leaves))
+	for i, script := range t.leaves {
+		level[i] = &node{hash: taprootLeafHash(script), leaves: []int{i}}
+	}
+	for len(level) > 1 {
+		next := make([]*node, 0, (len(level)+1)/2)
+		for i := 0; i+1 < len(level); i += 2 {
+			left, right := level[i], level[i+1]
+			for _, leaf := range left.leaves {
+				proofs[leaf] = append(proofs[leaf], right.hash)
+			}
+			for _, leaf := range right.leaves {
+				proofs[leaf] = append(proofs[leaf], left.hash)
+			}
+			next = append(next, &node{
+				hash:   taprootBranchHash(left.hash, right.hash),
+				leaves: append(append([]int{}, left.leaves...), right.leaves...),
+			})
+		}
+		if len(level)%2 == 1 {
+			next = append(next, level[len(level)-1])
+		}
+		level = next
+	}
+	return level[0].hash, proofs
+}
+
+// taprootPrevouts returns the previous output of every input of a transaction, with scripts for the wallet's
+// network.
+func (wallet *HDWallet) taprootPrevouts(tx *wire.MsgTx, prevouts *PrevoutSet) ([]*wire.TxOut, error) {
+	spent := make([]*wire.TxOut, len(tx.TxIn))
+	for i, txIn := range tx.TxIn {
+		info, ok := prevouts.prevouts[txIn.PreviousOutPoint]
+		if !ok {
+			return nil, errors.New("taproot signing requires every previous output")
+		}
+		pkScript, err := pkScriptForNetworkAddress(info.SelectedAddress, wallet.BaseCoin.defaultNetParams())
+		if err != nil {
+			return nil, err
+		}
+		spent[i] = wire.NewTxOut(int64(info.Amount), pkScript)
+	}
+	return spent, nil
+}
+
+// taprootLeafHash returns the tapleaf hash of a script with the BIP342 leaf version.
+func taprootLeafHash(script []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(taprootLeafVersion)
+	wire.WriteVarBytes(&buf, 0, script)
+	return taggedHash(taprootLeafTag, buf.Bytes())
+}
+
+// taprootBranchHash returns the hash of a branch of two nodes, which are sorted so that proofs need no direction.
+func taprootBranchHash(a []byte, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return taggedHash(taprootBranchTag, append(append([]byte{}, a...), b...))
+}
+
+// taprootTweakedOutputKey returns the x-only output key of an x-only internal key committing to a merkle root, or to
+// no script tree if the root is nil, and whether the output key's y coordinate is odd.
+func taprootTweakedOutputKey(internalKey []byte, merkleRoot []byte) ([]byte, bool, error) {
+	curve := btcec.S256()
+	px, py, err := liftX(internalKey)
+	if err != nil {
+		return nil, false, err
+	}
+	tweak := new(big.Int).SetBytes(taggedHash(taprootTweakTag, append(append([]byte{}, internalKey...), merkleRoot...)))
+	if tweak.Cmp(curve.N) >= 0 {
+		return nil, false, errors.New("invalid taproot tweak")
+	}
+	tx, ty := curve.ScalarBaseMult(padTo32(tweak.Bytes()))
+	qx, qy := curve.Add(px, py, tx, ty)
+	return padTo32(qx.Bytes()), qy.Bit(0) == 1, nil
+}
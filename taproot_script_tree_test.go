@@ -0,0 +1,177 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+// BIP341 wallet test vectors, scriptPubKey section.
+func TestTaprootScriptTree_BIP341Vectors(t *testing.T) {
+	internalKey, _ := hex.DecodeString("d6889cb081036e0faefa3a35157ad71086b123b2b144b649798b494c300a961d")
+	tree, err := NewTaprootScriptTree(internalKey)
+	assert.Nil(t, err)
+	assert.Nil(t, tree.MerkleRoot())
+	outputKey, err := tree.OutputKey()
+	assert.Nil(t, err)
+	assert.Equal(t, "53a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343", hex.EncodeToString(outputKey))
+
+	internalKey, _ = hex.DecodeString("187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27")
+	tree, err = NewTaprootScriptTree(internalKey)
+	assert.Nil(t, err)
+	script, _ := hex.DecodeString("20d85a959b0290bf19bb89ed43c916be835475d013da4b362117393e25a48229b8ac")
+	index, err := tree.AddLeaf(script)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, index)
+	assert.Equal(t, "5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21", hex.EncodeToString(tree.MerkleRoot()))
+	outputKey, err = tree.OutputKey()
+	assert.Nil(t, err)
+	assert.Equal(t, "147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3", hex.EncodeToString(outputKey))
+	controlBlock, err := tree.ControlBlock(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "c1187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27", hex.EncodeToString(controlBlock))
+}
+
+func TestTaprootScriptTree_ControlBlocksProveRoot(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	internalKey, err := wallet.SchnorrPublicKeyForPath(NewDerivationPath(BaseCoinBip86MainNet, 0, 0))
+	assert.Nil(t, err)
+	tree, err := NewTaprootScriptTree(internalKey)
+	assert.Nil(t, err)
+
+	for i := 1; i <= 5; i++ {
+		key, _ := wallet.SchnorrPublicKeyForPath(NewDerivationPath(BaseCoinBip86MainNet, 0, i))
+		_, err := tree.AddTimelockLeaf(key, 144*i)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 5, tree.LeafCount())
+
+	root := tree.MerkleRoot()
+	for i := 0; i < tree.LeafCount(); i++ {
+		script, err := tree.LeafScriptAtIndex(i)
+		assert.Nil(t, err)
+		controlBlock, err := tree.ControlBlock(i)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, (len(controlBlock)-33)%32)
+		assert.Equal(t, byte(taprootLeafVersion), controlBlock[0]&0xfe)
+		assert.Equal(t, internalKey, controlBlock[1:33])
+
+		node := taprootLeafHash(script)
+		for j := 33; j < len(controlBlock); j += 32 {
+			node = taprootBranchHash(node, controlBlock[j:j+32])
+		}
+		assert.Equal(t, root, node)
+	}
+
+	_, err = tree.ControlBlock(5)
+	assert.NotNil(t, err)
+	_, err = tree.LeafScriptAtIndex(-1)
+	assert.NotNil(t, err)
+}
+
+func TestTaprootScriptTree_Invalid(t *testing.T) {
+	_, err := NewTaprootScriptTree(make([]byte, 31))
+	assert.NotNil(t, err)
+	offCurve, _ := hex.DecodeString("eefdea4cdb677750a420fee807eacf21eb9898ae79b9768766e4faa04a2d4a34")
+	_, err = NewTaprootScriptTree(offCurve)
+	assert.NotNil(t, err)
+
+	internalKey, _ := hex.DecodeString("187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27")
+	tree, _ := NewTaprootScriptTree(internalKey)
+	_, err = tree.AddLeaf(nil)
+	assert.NotNil(t, err)
+	_, err = tree.AddKeyLeaf(internalKey[:31])
+	assert.NotNil(t, err)
+	_, err = tree.AddTimelockLeaf(internalKey, 0)
+	assert.NotNil(t, err)
+	_, err = tree.AddTimelockLeaf(internalKey, 65536)
+	assert.NotNil(t, err)
+}
+
+func TestSignTaprootScriptPathInput(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	ownerPath := NewDerivationPath(BaseCoinBip86MainNet, 0, 0)
+	fallbackPath := NewDerivationPath(BaseCoinBip86MainNet, 0, 1)
+	ownerKey, _ := wallet.SchnorrPublicKeyForPath(ownerPath)
+	fallbackKey, _ := wallet.SchnorrPublicKeyForPath(fallbackPath)
+
+	tree, err := NewTaprootScriptTree(ownerKey)
+	assert.Nil(t, err)
+	_, err = tree.AddKeyLeaf(ownerKey)
+	assert.Nil(t, err)
+	fallbackLeaf, err := tree.AddTimelockLeaf(fallbackKey, 4320)
+	assert.Nil(t, err)
+	address, err := tree.Address(BaseCoinBip86MainNet)
+	assert.Nil(t, err)
+	assert.True(t, isTaprootAddress(address, BaseCoinBip86MainNet.defaultNetParams()))
+
+	rawTx, _ := NewRawTransaction(2, 0)
+	assert.Nil(t, rawTx.AddInput(NewRawTransactionInput(descriptorTestTxid, 0, 4320)))
+	outputScript, _ := AddressScript("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0")
+	assert.Nil(t, rawTx.AddOutput(NewRawTransactionOutput(99000, outputScript)))
+	encodedTx, _ := rawTx.Encode()
+
+	prevouts := NewPrevoutSet()
+	assert.Nil(t, prevouts.AddPrevout(NewPreviousOutputInfo(address, descriptorTestTxid, 0, 100000)))
+
+	signed, err := wallet.SignTaprootScriptPathInput(encodedTx, 0, prevouts, tree, fallbackLeaf, fallbackPath)
+	assert.Nil(t, err)
+	tx, err := decodeMsgTx(signed)
+	assert.Nil(t, err)
+
+	witness := tx.TxIn[0].Witness
+	assert.Equal(t, 3, len(witness))
+	script, _ := tree.LeafScriptAtIndex(fallbackLeaf)
+	controlBlock, _ := tree.ControlBlock(fallbackLeaf)
+	assert.Equal(t, script, []byte(witness[1]))
+	assert.Equal(t, controlBlock, []byte(witness[2]))
+
+	pkScript, _ := tree.PkScript()
+	sigHash, err := taprootSigHash(tx, 0, []*wire.TxOut{wire.NewTxOut(100000, pkScript)}, taprootLeafHash(script))
+	assert.Nil(t, err)
+	assert.Nil(t, schnorrVerify(fallbackKey, sigHash, witness[0]))
+
+	// the script path signature hash commits to the leaf, so differs from the key path's
+	keyPathHash, _ := taprootKeyPathSigHash(tx, 0, []*wire.TxOut{wire.NewTxOut(100000, pkScript)})
+	assert.False(t, bytes.Equal(keyPathHash, sigHash))
+}
+
+func TestSignTaprootScriptPathInput_Mismatch(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	path := NewDerivationPath(BaseCoinBip86MainNet, 0, 0)
+	key, _ := wallet.SchnorrPublicKeyForPath(path)
+	tree, _ := NewTaprootScriptTree(key)
+	leaf, _ := tree.AddKeyLeaf(key)
+	address, _ := tree.Address(BaseCoinBip86MainNet)
+
+	rawTx, _ := NewRawTransaction(2, 0)
+	assert.Nil(t, rawTx.AddInput(NewRawTransactionInput(descriptorTestTxid, 0, 0xfffffffd)))
+	outputScript, _ := AddressScript("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0")
+	assert.Nil(t, rawTx.AddOutput(NewRawTransactionOutput(99000, outputScript)))
+	encodedTx, _ := rawTx.Encode()
+
+	prevouts := NewPrevoutSet()
+	assert.Nil(t, prevouts.AddPrevout(NewPreviousOutputInfo(address, descriptorTestTxid, 0, 100000)))
+
+	// signing key not in the leaf script
+	_, err := wallet.SignTaprootScriptPathInput(encodedTx, 0, prevouts, tree, leaf, NewDerivationPath(BaseCoinBip86MainNet, 0, 1))
+	assert.Equal(t, ErrTaprootLeafKeyMismatch, err)
+
+	// previous output pays elsewhere
+	other := NewPrevoutSet()
+	assert.Nil(t, other.AddPrevout(NewPreviousOutputInfo("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", descriptorTestTxid, 0, 100000)))
+	_, err = wallet.SignTaprootScriptPathInput(encodedTx, 0, other, tree, leaf, path)
+	assert.Equal(t, ErrTaprootScriptTreeMismatch, err)
+
+	// missing previous output
+	_, err = wallet.SignTaprootScriptPathInput(encodedTx, 0, NewPrevoutSet(), tree, leaf, path)
+	assert.NotNil(t, err)
+
+	_, err = wallet.SignTaprootScriptPathInput(encodedTx, 1, prevouts, tree, leaf, path)
+	assert.NotNil(t, err)
+	_, err = wallet.SignTaprootScriptPathInput(encodedTx, 0, prevouts, tree, 1, path)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,251 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BIP341 key and script path spending, which the btcd version in use predates.
+
+const (
+	taprootTweakTag   = "TapTweak"
+	taprootSighashTag = "TapSighash"
+
+	taprootSigHashDefault = byte(0x00)
+)
+
+/// Receiver functions
+
+// SetDeterministicSchnorrSignatures makes the wallet sign taproot inputs with all-zero BIP340 auxiliary randomness,
+// so signed transactions are identical across runs, as test vectors need. Off by default: the randomness protects
+// the signing key against side channel attacks.
+func (wallet *HDWallet) SetDeterministicSchnorrSignatures(deterministic bool) {
+	wallet.deterministicSchnorr = deterministic
+}
+
+/// Unexported functions
+
+// schnorrAuxRandomness returns the BIP340 auxiliary randomness for the wallet's next taproot signature, fresh unless
+// the wallet signs deterministically.
+func (wallet *HDWallet) schnorrAuxRandomness() ([]byte, error) {
+	if wallet.deterministicSchnorr {
+		return make([]byte, 32), nil
+	}
+	return randBytes(32)
+}
+
+// taprootOutputKey returns the x-only output key committing to an internal key with no script tree, per BIP86.
+func taprootOutputKey(internalKey *btcec.PublicKey) []byte {
+	curve := btcec.S256()
+	px := padTo32(internalKey.X.Bytes())
+	tweak := taggedHash(taprootTweakTag, px)
+
+	// lift to the even y point, as the internal key is used x-only
+	y := new(big.Int).Set(internalKey.Y)
+	if y.Bit(0) == 1 {
+		y.Sub(curve.P, y)
+	}
+	tx, ty := curve.ScalarBaseMult(tweak)
+	qx, _ := curve.Add(internalKey.X, y, tx, ty)
+	return padTo32(qx.Bytes())
+}
+
+// taprootTweakedPrivateKey returns the private key for the output key of taprootOutputKey.
+func taprootTweakedPrivateKey(priv *btcec.PrivateKey) (*btcec.PrivateKey, error) {
+	curve := btcec.S256()
+	d := new(big.Int).Set(priv.D)
+	if priv.PubKey().Y.Bit(0) == 1 {
+		d.Sub(curve.N, d)
+	}
+
+	tweak := new(big.Int).SetBytes(taggedHash(taprootTweakTag, padTo32(priv.PubKey().X.Bytes())))
+	if tweak.Cmp(curve.N) >= 0 {
+		return nil, errors.New("invalid taproot tweak")
+	}
+	d.Add(d, tweak)
+	d.Mod(d, curve.N)
+	if d.Sign() == 0 {
+		return nil, errors.New("invalid taproot tweak")
+	}
+
+	tweaked, _ := btcec.PrivKeyFromBytes(curve, padTo32(d.Bytes()))
+	return tweaked, nil
+}
+
+// signTaprootKeyPathInput signs a key path spend of an input paying to the BIP86 output key of a private key, given
+// the previous outputs of every input and the BIP340 auxiliary randomness.
+func signTaprootKeyPathInput(tx *wire.MsgTx, idx int, prevouts []*wire.TxOut, priv *btcec.PrivateKey, aux []byte) error {
+	tweaked, err := taprootTweakedPrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	sigHash, err := taprootKeyPathSigHash(tx, idx, prevouts)
+	if err != nil {
+		return err
+	}
+	sig, err := schnorrSign(tweaked, sigHash, aux)
+	if err != nil {
+		return err
+	}
+	tx.TxIn[idx].Witness = wire.TxWitness{sig}
+	tx.TxIn[idx].SignatureScript = nil
+	return nil
+}
+
+// taprootKeyPathSigHash returns the BIP341 SIGHASH_DEFAULT signature hash for a key path spend of an input, given
+// the previous outputs of every input.
+func taprootKeyPathSigHash(tx *wire.MsgTx, idx int, prevouts []*wire.TxOut) ([]byte, error) {
+	return taprootSigHash(tx, idx, prevouts, nil)
+}
+
+// taprootSigHash returns the BIP341 SIGHASH_DEFAULT signature hash of an input, for a script path spend of the leaf
+// with a given tapleaf hash, per BIP342, or for a key path spend if the leaf hash is nil.
+func taprootSigHash(tx *wire.MsgTx, idx int, prevouts []*wire.TxOut, leafHash []byte) ([]byte, error) {
+	return taprootSigHashWithType(tx, idx, prevouts, leafHash, taprootSigHashDefault)
+}
+
+// taprootSigHashWithType returns the BIP341 signature hash of an input as `taprootSigHash`, with any BIP341 hash
+// type: SIGHASH_DEFAULT, or SIGHASH_ALL, SIGHASH_NONE or SIGHASH_SINGLE, optionally with SIGHASH_ANYONECANPAY.
+func taprootSigHashWithType(tx *wire.MsgTx, idx int, prevouts []*wire.TxOut, leafHash []byte, hashType byte) ([]byte, error) {
+	if !isTaprootSigHashType(hashType) {
+		return nil, errors.New("unsupported taproot signature hash type")
+	}
+	if len(prevouts) != len(tx.TxIn) {
+		return nil, errors.New("taproot signing requires every previous output")
+	}
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, errors.New("input index out of range")
+	}
+	anyoneCanPay := hashType&byte(txscript.SigHashAnyOneCanPay) != 0
+	outputType := hashType & 0x03
+	if outputType == byte(txscript.SigHashSingle) && idx >= len(tx.TxOut) {
+		return nil, errors.New("no output at the index of a SIGHASH_SINGLE input")
+	}
+
+	var msg bytes.Buffer
+	msg.WriteByte(0x00) // epoch
+	msg.WriteByte(hashType)
+	binary.Write(&msg, binary.LittleEndian, tx.Version)
+	binary.Write(&msg, binary.LittleEndian, tx.LockTime)
+	if !anyoneCanPay {
+		var outpoints, amounts, scripts, sequences bytes.Buffer
+		for i, txIn := range tx.TxIn {
+			outpoints.Write(txIn.PreviousOutPoint.Hash[:])
+			binary.Write(&outpoints, binary.LittleEndian, txIn.PreviousOutPoint.Index)
+			binary.Write(&amounts, binary.LittleEndian, prevouts[i].Value)
+			if err := wire.WriteVarBytes(&scripts, 0, prevouts[i].PkScript); err != nil {
+				return nil, err
+			}
+			binary.Write(&sequences, binary.LittleEndian, txIn.Sequence)
+		}
+		for _, buf := range []*bytes.Buffer{&outpoints, &amounts, &scripts, &sequences} {
+			hash := sha256.Sum256(buf.Bytes())
+			msg.Write(hash[:])
+		}
+	}
+	if outputType != byte(txscript.SigHashNone) && outputType != byte(txscript.SigHashSingle) {
+		var outputs bytes.Buffer
+		for _, txOut := range tx.TxOut {
+			if err := wire.WriteTxOut(&outputs, 0, 0, txOut); err != nil {
+				return nil, err
+			}
+		}
+		hash := sha256.Sum256(outputs.Bytes())
+		msg.Write(hash[:])
+	}
+
+	if leafHash == nil {
+		msg.WriteByte(0x00) // key path spend, no annex
+	} else {
+		msg.WriteByte(0x02) // script path spend, no annex
+	}
+	if anyoneCanPay {
+		txIn := tx.TxIn[idx]
+		msg.Write(txIn.PreviousOutPoint.Hash[:])
+		binary.Write(&msg, binary.LittleEndian, txIn.PreviousOutPoint.Index)
+		binary.Write(&msg, binary.LittleEndian, prevouts[idx].Value)
+		if err := wire.WriteVarBytes(&msg, 0, prevouts[idx].PkScript); err != nil {
+			return nil, err
+		}
+		binary.Write(&msg, binary.LittleEndian, txIn.Sequence)
+	} else {
+		binary.Write(&msg, binary.LittleEndian, uint32(idx))
+	}
+	if outputType == byte(txscript.SigHashSingle) {
+		var output bytes.Buffer
+		if err := wire.WriteTxOut(&output, 0, 0, tx.TxOut[idx]); err != nil {
+			return nil, err
+		}
+		hash := sha256.Sum256(output.Bytes())
+		msg.Write(hash[:])
+	}
+	if leafHash != nil {
+		msg.Write(leafHash)
+		msg.WriteByte(0x00)                                         // key version
+		binary.Write(&msg, binary.LittleEndian, uint32(0xffffffff)) // no OP_CODESEPARATOR executed
+	}
+
+	return taggedHash(taprootSighashTag, msg.Bytes()), nil
+}
+
+// isTaprootSigHashType returns true for the hash types BIP341 defines.
+func isTaprootSigHashType(hashType byte) bool {
+	switch hashType &^ byte(txscript.SigHashAnyOneCanPay) {
+	case taprootSigHashDefault:
+		return hashType == taprootSigHashDefault
+	case byte(txscript.SigHashAll), byte(txscript.SigHashNone), byte(txscript.SigHashSingle):
+		return true
+	}
+	return false
+}
+
+// verifyTaprootKeyPathInput verifies the key path spend of an input paying to a taproot output key, given the
+// previous outputs of every input, which the btcd script engine in use cannot. Script path spends and annexes are
+// not supported.
+func verifyTaprootKeyPathInput(tx *wire.MsgTx, idx int, prevouts []*wire.TxOut) error {
+	if idx < 0 || idx >= len(tx.TxIn) || len(prevouts) != len(tx.TxIn) {
+		return errors.New("taproot verification requires every previous output")
+	}
+	if !isTaprootScript(prevouts[idx].PkScript) {
+		return errors.New("previous output is not a taproot output")
+	}
+	witness := tx.TxIn[idx].Witness
+	if len(witness) != 1 {
+		return errors.New("taproot inputs require a single key path witness item")
+	}
+	signature, hashType, err := parseTaprootSignature(witness[0])
+	if err != nil {
+		return err
+	}
+	sigHash, err := taprootSigHashWithType(tx, idx, prevouts, nil, hashType)
+	if err != nil {
+		return err
+	}
+	return schnorrVerify(prevouts[idx].PkScript[2:], sigHash, signature)
+}
+
+// parseTaprootSignature splits a BIP341 signature into its 64-byte Schnorr signature and hash type, which is
+// SIGHASH_DEFAULT when implicit.
+func parseTaprootSignature(signature []byte) ([]byte, byte, error) {
+	switch len(signature) {
+	case schnorrSignatureSize:
+		return signature, taprootSigHashDefault, nil
+	case schnorrSignatureSize + 1:
+		hashType := signature[schnorrSignatureSize]
+		if hashType == taprootSigHashDefault {
+			return nil, 0, errors.New("SIGHASH_DEFAULT must be implicit")
+		}
+		if !isTaprootSigHashType(hashType) {
+			return nil, 0, errors.New("unsupported taproot signature hash type")
+		}
+		return signature[:schnorrSignatureSize], hashType, nil
+	}
+	return nil, 0, errors.New("invalid taproot signature size")
+}
@@ -0,0 +1,169 @@
+package cnlib
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+/// Type Definitions
+
+// Following constants are used for AnalyzedOutput.Kind.
+const (
+	OutputKindExternal int = 0
+	OutputKindReceived int = 1
+	OutputKindChange   int = 2
+)
+
+// PrevoutSet holds info about the previous outputs spent by a transaction being analyzed.
+// Add each known previous output using `AddPrevout`, as gomobile does not support custom arrays/slices.
+type PrevoutSet struct {
+	prevouts map[wire.OutPoint]*PreviousOutputInfo
+}
+
+// AnalyzedOutput describes a single output of an analyzed transaction.
+type AnalyzedOutput struct {
+	Index          int
+	Address        string // empty if the output script does not encode an address
+	Amount         int
+	Kind           int             // `OutputKindExternal` (0), `OutputKindReceived` (1), or `OutputKindChange` (2)
+	DerivationPath *DerivationPath // nil if `Kind` is `OutputKindExternal`
+}
+
+// TransactionAnalysis is the result of classifying a transaction against the wallet's derived addresses.
+type TransactionAnalysis struct {
+	Txid           string
+	NetAmount      int  // value received by the wallet minus value spent by the wallet, negative if outgoing
+	FeePaid        int  // only meaningful if IsFeeKnown
+	IsFeeKnown     bool // true if all previous outputs were provided
+	IsSelfTransfer bool // true if all inputs and outputs belong to the wallet
+	outputs        []*AnalyzedOutput
+}
+
+/// Constructors
+
+// NewPrevoutSet returns a pointer to an empty PrevoutSet.
+func NewPrevoutSet() *PrevoutSet {
+	return &PrevoutSet{prevouts: make(map[wire.OutPoint]*PreviousOutputInfo)}
+}
+
+/// Receiver functions
+
+// AddPrevout adds info about a previous output. `SelectedAddress` is the address which owned the previous output.
+func (ps *PrevoutSet) AddPrevout(info *PreviousOutputInfo) error {
+	if info == nil {
+		return errors.New("previous output info cannot be nil")
+	}
+	outpoint, err := outPointFromInfo(info.Txid, info.Index)
+	if err != nil {
+		return err
+	}
+	ps.prevouts[*outpoint] = info
+	return nil
+}
+
+// OutputCount returns the number of outputs in the analyzed transaction.
+func (ta *TransactionAnalysis) OutputCount() int {
+	return len(ta.outputs)
+}
+
+// OutputAtIndex returns the analyzed output at a given index, or error if out of bounds.
+func (ta *TransactionAnalysis) OutputAtIndex(index int) (*AnalyzedOutput, error) {
+	if index < 0 || index > len(ta.outputs)-1 {
+		return nil, errors.New("index must be within range of outputs")
+	}
+	return ta.outputs[index], nil
+}
+
+// AnalyzeTransaction classifies each output of a hex-encoded transaction as received, change, or external, by
+// comparing against the wallet's receive and change addresses up to a given index. Previous outputs are optional;
+// when provided, they are used to compute the value spent by the wallet and the fee paid.
+func (wallet *HDWallet) AnalyzeTransaction(encodedTx string, prevouts *PrevoutSet, upTo int) (*TransactionAnalysis, error) {
+	tx, err := decodeMsgTx(encodedTx)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := wallet.derivedAddressMap(upTo)
+	if err != nil {
+		return nil, err
+	}
+
+	if prevouts == nil {
+		prevouts = NewPrevoutSet()
+	}
+
+	analysis := TransactionAnalysis{Txid: tx.TxHash().String(), outputs: []*AnalyzedOutput{}}
+
+	totalIn := 0
+	spent := 0
+	allInputsKnown := true
+	allInputsOwned := len(tx.TxIn) > 0
+	for _, txIn := range tx.TxIn {
+		info, ok := prevouts.prevouts[txIn.PreviousOutPoint]
+		if !ok {
+			allInputsKnown = false
+			allInputsOwned = false
+			continue
+		}
+		totalIn += info.Amount
+		if _, owned := known[info.SelectedAddress]; owned {
+			spent += info.Amount
+		} else {
+			allInputsOwned = false
+		}
+	}
+
+	totalOut := 0
+	received := 0
+	allOutputsOwned := true
+	for i, txOut := range tx.TxOut {
+		output := AnalyzedOutput{Index: i, Amount: int(txOut.Value), Kind: OutputKindExternal}
+		totalOut += output.Amount
+
+		output.Address = pkScriptAddress(txOut.PkScript, wallet.BaseCoin.defaultNetParams())
+
+		if meta, ok := known[output.Address]; ok && output.Address != "" {
+			output.DerivationPath = meta.DerivationPath
+			output.Kind = OutputKindChange
+			if meta.IsReceiveAddress() {
+				output.Kind = OutputKindReceived
+			}
+			received += output.Amount
+		} else {
+			allOutputsOwned = false
+		}
+
+		analysis.outputs = append(analysis.outputs, &output)
+	}
+
+	analysis.NetAmount = received - spent
+	analysis.IsFeeKnown = allInputsKnown && len(tx.TxIn) > 0
+	if analysis.IsFeeKnown {
+		analysis.FeePaid = totalIn - totalOut
+	}
+	analysis.IsSelfTransfer = allInputsOwned && allOutputsOwned
+
+	return &analysis, nil
+}
+
+/// Unexported functions
+
+// derivedAddressMap returns the wallet's receive and change MetaAddresses up to a given index, keyed by address.
+func (wallet *HDWallet) derivedAddressMap(upTo int) (map[string]*MetaAddress, error) {
+	known := make(map[string]*MetaAddress)
+	for i := 0; i < upTo; i++ {
+		rma, err := wallet.ReceiveAddressForIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		known[rma.Address] = rma
+
+		cma, err := wallet.ChangeAddressForIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		known[cma.Address] = cma
+	}
+	return known, nil
+}
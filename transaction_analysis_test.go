@@ -0,0 +1,92 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const analysisEncodedTx = "01000000000101699a3389145d5c84658eb362d714f10b2f0ffdf758ca0d1aa0ac2d1fed9b9aa80000000000fdffffff021b26000000000000160014933c5165df610846d08f026d18332610c13eef7fb04f0100000000001600144227d834f1aae95273f0c87495f4ff0cb366545202473044022024b8f49fddcc119fc30990d6c970d8a1e0fa56d951d31591bed76c0867dbd11d0220755bb57af82993facbf413e523a8fa6fbccf8055ec95d1764da5e98b54e16bf2012103e775fd51f0dfb8cd865d9ff1cca2a158cf651fe997fdc9fee9c1d3b5e995ea77f6020900"
+
+func TestAnalyzeTransaction_OutgoingWithChange(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	source, err := wallet.ReceiveAddressForIndex(1)
+	assert.Nil(t, err)
+
+	prevouts := NewPrevoutSet()
+	err = prevouts.AddPrevout(NewPreviousOutputInfo(source.Address, "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537))
+	assert.Nil(t, err)
+
+	analysis, err := wallet.AnalyzeTransaction(analysisEncodedTx, prevouts, 5)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "fe7f9a6de3203eb300cc66159e762251d675b5555dbd215c3574e75a762ca402", analysis.Txid)
+	assert.Equal(t, 2, analysis.OutputCount())
+
+	payment, err := analysis.OutputAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, OutputKindExternal, payment.Kind)
+	assert.Equal(t, "bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", payment.Address)
+	assert.Nil(t, payment.DerivationPath)
+
+	change, err := analysis.OutputAtIndex(1)
+	assert.Nil(t, err)
+	assert.Equal(t, OutputKindChange, change.Kind)
+	assert.Equal(t, 1, change.DerivationPath.Index)
+
+	assert.True(t, analysis.IsFeeKnown)
+	assert.Equal(t, 846, analysis.FeePaid)
+	assert.Equal(t, 85936-96537, analysis.NetAmount)
+	assert.False(t, analysis.IsSelfTransfer)
+}
+
+func TestAnalyzeTransaction_WithoutPrevouts_FeeUnknown(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+
+	analysis, err := wallet.AnalyzeTransaction(analysisEncodedTx, nil, 5)
+	assert.Nil(t, err)
+
+	assert.False(t, analysis.IsFeeKnown)
+	assert.Equal(t, 0, analysis.FeePaid)
+	assert.Equal(t, 85936, analysis.NetAmount)
+
+	_, err = analysis.OutputAtIndex(2)
+	assert.NotNil(t, err)
+}
+
+// newTestTaprootWalletTx returns a transaction spending an outpoint, paying 5000 to an external address and 90000 to an
+// address of the wallet.
+func newTestTaprootWalletTx(t *testing.T, txid string, walletAddress string) string {
+	rt, err := NewRawTransaction(2, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, rt.AddInput(NewRawTransactionInput(txid, 0, 0xfffffffd)))
+	external, err := AddressScript("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6")
+	assert.Nil(t, err)
+	assert.Nil(t, rt.AddOutput(NewRawTransactionOutput(5000, external)))
+	own, err := AddressScript(walletAddress)
+	assert.Nil(t, err)
+	assert.Nil(t, rt.AddOutput(NewRawTransactionOutput(90000, own)))
+	encoded, err := rt.Encode()
+	assert.Nil(t, err)
+	return encoded
+}
+
+func TestAnalyzeTransaction_BIP86TaprootChange(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	source, err := wallet.ReceiveAddressForIndex(1)
+	assert.Nil(t, err)
+	change, err := wallet.ChangeAddressForIndex(1)
+	assert.Nil(t, err)
+	prevouts := NewPrevoutSet()
+	assert.Nil(t, prevouts.AddPrevout(NewPreviousOutputInfo(source.Address, descriptorTestTxid, 0, 96000)))
+
+	analysis, err := wallet.AnalyzeTransaction(newTestTaprootWalletTx(t, descriptorTestTxid, change.Address), prevouts, 5)
+	assert.Nil(t, err)
+	output, err := analysis.OutputAtIndex(1)
+	assert.Nil(t, err)
+	assert.Equal(t, OutputKindChange, output.Kind)
+	assert.Equal(t, change.Address, output.Address)
+	assert.Equal(t, 1, output.DerivationPath.Index)
+	assert.Equal(t, 1000, analysis.FeePaid)
+	assert.Equal(t, 90000-96000, analysis.NetAmount)
+}
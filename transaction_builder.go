@@ -17,7 +17,9 @@ import (
 )
 
 type transactionBuilder struct {
-	wallet *HDWallet
+	wallet   *HDWallet
+	dryRun   bool // substitute correctly sized dummy signatures instead of signing
+	unsigned bool // leave inputs unsigned, for export as a PSBT
 }
 
 type cnSecretsSource struct {
@@ -34,7 +36,7 @@ func (s cnSecretsSource) GetScript(addr btcutil.Address) ([]byte, error) {
 	script := s.usableAddresses[addr.EncodeAddress()]
 	pk := script.derivedPrivateKey
 	hash := btcutil.Hash160(pk.PubKey().SerializeCompressed())
-	scriptSig, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(hash).Script()
+	scriptSig, err := P2WPKHScript(hash)
 	if err != nil {
 		return nil, err
 	}
@@ -50,17 +52,36 @@ func (s cnSecretsSource) ChainParams() *chaincfg.Params {
 }
 
 func (tb transactionBuilder) buildTxFromData(data *TransactionData) (*TransactionMetadata, error) {
+	tx, transactionChangeMetadata, err := tb.buildMsgTx(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// encode and return
+	txid := tx.TxHash().String()
+	var encodedBytes bytes.Buffer
+	err = tx.Serialize(&encodedBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	tm := TransactionMetadata{Txid: txid, EncodedTx: hex.EncodeToString(encodedBytes.Bytes())}
+	tm.TransactionChangeMetadata = transactionChangeMetadata
+	return &tm, nil
+}
+
+func (tb transactionBuilder) buildMsgTx(data *TransactionData) (*wire.MsgTx, *TransactionChangeMetadata, error) {
+	if err := data.validateBalance(); err != nil {
+		return nil, nil, err
+	}
+
 	// create transaction with version
 	tx := wire.NewMsgTx(wire.TxVersion)
 
 	// populate tx with payment data
-	decAddr, decAddrErr := btcutil.DecodeAddress(data.PaymentAddress, data.basecoin.defaultNetParams())
-	if decAddrErr != nil {
-		return nil, decAddrErr
-	}
-	destPkScript, err := txscript.PayToAddrScript(decAddr)
+	destPkScript, err := pkScriptForNetworkAddress(data.PaymentAddress, data.basecoin.defaultNetParams())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	txout := wire.NewTxOut(int64(data.Amount), destPkScript)
 	tx.AddTxOut(txout)
@@ -70,18 +91,13 @@ func (tb transactionBuilder) buildTxFromData(data *TransactionData) (*Transactio
 	if data.shouldAddChangeToTransaction() {
 		changeMetaAddr, err := tb.wallet.ChangeAddressForIndex(data.ChangePath.Index)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		changeAddr := changeMetaAddr.Address
-		decChange, err := btcutil.DecodeAddress(changeAddr, data.basecoin.defaultNetParams())
-		if err != nil {
-			return nil, err
-		}
-
-		changePkScript, err := txscript.PayToAddrScript(decChange)
+		changePkScript, err := pkScriptForNetworkAddress(changeAddr, data.basecoin.defaultNetParams())
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		changeOut := wire.NewTxOut(int64(data.ChangeAmount), changePkScript)
@@ -89,21 +105,26 @@ func (tb transactionBuilder) buildTxFromData(data *TransactionData) (*Transactio
 		metadata := TransactionChangeMetadata{Address: changeAddr, Path: data.ChangePath, VoutIndex: 1}
 		transactionChangeMetadata = &metadata
 	}
+	if transactionChangeMetadata != nil {
+		transactionChangeMetadata.VoutIndex = data.sortOutputs(tx, transactionChangeMetadata.VoutIndex)
+	} else {
+		data.sortOutputs(tx, -1)
+	}
 
 	// populate utxos as inputs
 	for i := 0; i < data.UtxoCount(); i++ {
 		utxo, utxoErr := data.RequiredUTXOAtIndex(i)
 		if utxoErr != nil {
-			return nil, utxoErr
+			return nil, nil, utxoErr
 		}
 
 		// prev tx outpoint
 		if utxo.Index < 0 || utxo.Index > int(math.MaxInt32) {
-			return nil, errors.New("previous utxo index out of bounds")
+			return nil, nil, errors.New("previous utxo index out of bounds")
 		}
 		newHash, newHashErr := chainhash.NewHashFromStr(utxo.Txid)
 		if newHashErr != nil {
-			return nil, newHashErr
+			return nil, nil, newHashErr
 		}
 		outpoint := wire.NewOutPoint(newHash, uint32(utxo.Index))
 
@@ -119,74 +140,96 @@ func (tb transactionBuilder) buildTxFromData(data *TransactionData) (*Transactio
 
 	// set locktime
 	if data.Locktime < 0 || data.Locktime > int(math.MaxInt32) {
-		return nil, errors.New("Locktime out of bounds")
+		return nil, nil, errors.New("Locktime out of bounds")
 	}
 	tx.LockTime = uint32(data.Locktime)
 
-	// sign inputs
-	err = tb.signInputsForTx(tx, data)
-	if err != nil {
-		return nil, err
+	if tb.unsigned {
+		return tx, transactionChangeMetadata, nil
 	}
 
-	// encode and return
-	txid := tx.TxHash().String()
-	var encodedBytes bytes.Buffer
-	err = tx.Serialize(&encodedBytes)
+	// sign inputs
+	if tb.dryRun {
+		err = tb.addDummySignaturesForTx(tx, data)
+	} else {
+		err = tb.signInputsForTx(tx, data)
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	tm := TransactionMetadata{Txid: txid, EncodedTx: hex.EncodeToString(encodedBytes.Bytes())}
-	tm.TransactionChangeMetadata = transactionChangeMetadata
-	return &tm, nil
+	return tx, transactionChangeMetadata, nil
 }
 
 func (tb transactionBuilder) signInputsForTx(tx *wire.MsgTx, data *TransactionData) error {
 	prevPkScripts := make([][]byte, data.UtxoCount())
 	inputValues := make([]btcutil.Amount, data.UtxoCount())
+	signers := make([]*usableAddress, data.UtxoCount())
 	secretsSource := cnSecretsSource{wallet: tb.wallet, usableAddresses: make(map[string]*usableAddress)}
+	signSeparately := false
 
 	for i := range tx.TxIn {
 		utxo, _ := data.RequiredUTXOAtIndex(i)
 
-		var address string
-		if utxo.Path != nil {
-			signer, err := newUsableAddressWithDerivationPath(tb.wallet, utxo.Path)
+		var pkScript []byte
+		if utxo.descriptor != nil {
+			signSeparately = true
+			script, err := utxo.descriptor.pkScript()
 			if err != nil {
 				return err
 			}
-			meta, err := signer.MetaAddress()
+			pkScript = script
+		} else {
+			signer, address, err := tb.usableAddressForUTXO(utxo)
 			if err != nil {
 				return err
 			}
-			address = meta.Address
 			secretsSource.usableAddresses[address] = signer
-		} else if utxo.ImportedPrivateKey != nil && utxo.ImportedPrivateKey.SelectedAddress != "" {
-			signer := newUsableAddressWithImportedPrivateKey(tb.wallet, utxo.ImportedPrivateKey)
-			address = utxo.ImportedPrivateKey.SelectedAddress
-			secretsSource.usableAddresses[address] = signer
-		} else {
-			return errors.New("no source address available to sign input")
-		}
+			signers[i] = signer
 
-		sourceAddress, err := btcutil.DecodeAddress(address, tb.wallet.BaseCoin.defaultNetParams())
-		if err != nil {
-			return err
+			script, err := tb.pkScriptForAddress(address)
+			if err != nil {
+				return err
+			}
+			pkScript = script
+			signSeparately = signSeparately || isTaprootScript(pkScript)
 		}
-
-		pkScript, err := txscript.PayToAddrScript(sourceAddress)
-		if err != nil {
-			return err
+		if err := utxo.verifyPrevout(pkScript); err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
 		}
 
 		prevPkScripts[i] = pkScript
 		inputValues[i] = btcutil.Amount(utxo.Amount)
 	}
 
-	scriptsErr := txauthor.AddAllInputScripts(tx, prevPkScripts, inputValues, secretsSource)
-	if scriptsErr != nil {
-		return scriptsErr
+	if signSeparately {
+		// txauthor only handles segwit v0 and legacy script types, so sign each input separately
+		prevouts := make([]*wire.TxOut, len(tx.TxIn))
+		for i := range prevouts {
+			prevouts[i] = wire.NewTxOut(int64(inputValues[i]), prevPkScripts[i])
+		}
+		for i := range tx.TxIn {
+			utxo, _ := data.RequiredUTXOAtIndex(i)
+			var err error
+			if utxo.descriptor != nil {
+				err = tb.wallet.signDescriptorInput(tx, i, prevouts, utxo.descriptor)
+			} else if isTaprootScript(prevPkScripts[i]) {
+				var aux []byte
+				if aux, err = tb.wallet.schnorrAuxRandomness(); err == nil {
+					err = signTaprootKeyPathInput(tx, i, prevouts, signers[i].derivedPrivateKey, aux)
+				}
+			} else {
+				err = signInputWithHashType(tx, i, prevPkScripts[i], utxo.Amount, txscript.SigHashAll, signers[i].derivedPrivateKey)
+			}
+			if err != nil {
+				return fmt.Errorf("input %d: %w", i, err)
+			}
+		}
+	} else {
+		scriptsErr := txauthor.AddAllInputScripts(tx, prevPkScripts, inputValues, secretsSource)
+		if scriptsErr != nil {
+			return scriptsErr
+		}
 	}
 
 	// verify
@@ -199,10 +242,85 @@ func (tb transactionBuilder) signInputsForTx(tx *wire.MsgTx, data *TransactionDa
 	return nil
 }
 
+// usableAddressForUTXO returns the signer and source address able to spend a given utxo.
+func (tb transactionBuilder) usableAddressForUTXO(utxo *UTXO) (*usableAddress, string, error) {
+	if utxo.Path != nil {
+		signer, err := newUsableAddressWithDerivationPath(tb.wallet, utxo.Path)
+		if err != nil {
+			return nil, "", err
+		}
+		meta, err := signer.MetaAddress()
+		if err != nil {
+			return nil, "", err
+		}
+		return signer, meta.Address, nil
+	} else if utxo.ImportedPrivateKey != nil && utxo.ImportedPrivateKey.SelectedAddress != "" {
+		signer := newUsableAddressWithImportedPrivateKey(tb.wallet, utxo.ImportedPrivateKey)
+		return signer, utxo.ImportedPrivateKey.SelectedAddress, nil
+	}
+	return nil, "", errors.New("no source address available to sign input")
+}
+
+func (tb transactionBuilder) pkScriptForAddress(address string) ([]byte, error) {
+	return pkScriptForNetworkAddress(address, tb.wallet.BaseCoin.defaultNetParams())
+}
+
+// signInputWithHashType signs a single input spending pkScript with the given sighash type, populating its
+// signature script and/or witness. Supports P2PKH, P2SH-P2WPKH and P2WPKH previous outputs.
+func signInputWithHashType(tx *wire.MsgTx, idx int, pkScript []byte, amount int64, hashType txscript.SigHashType, privKey *btcec.PrivateKey) error {
+	txIn := tx.TxIn[idx]
+	switch txscript.GetScriptClass(pkScript) {
+	case txscript.PubKeyHashTy:
+		sigScript, err := txscript.SignatureScript(tx, idx, pkScript, hashType, privKey, true)
+		if err != nil {
+			return err
+		}
+		txIn.SignatureScript = sigScript
+	case txscript.ScriptHashTy:
+		hash := btcutil.Hash160(privKey.PubKey().SerializeCompressed())
+		redeemScript, err := P2WPKHScript(hash)
+		if err != nil {
+			return err
+		}
+		sigHashes := txscript.NewTxSigHashes(tx)
+		witness, err := txscript.WitnessSignature(tx, sigHashes, idx, amount, redeemScript, hashType, privKey, true)
+		if err != nil {
+			return err
+		}
+		sigScript, err := txscript.NewScriptBuilder().AddData(redeemScript).Script()
+		if err != nil {
+			return err
+		}
+		txIn.SignatureScript = sigScript
+		txIn.Witness = witness
+	case txscript.WitnessV0PubKeyHashTy:
+		sigHashes := txscript.NewTxSigHashes(tx)
+		witness, err := txscript.WitnessSignature(tx, sigHashes, idx, amount, pkScript, hashType, privKey, true)
+		if err != nil {
+			return err
+		}
+		txIn.Witness = witness
+	default:
+		return errors.New("unsupported previous output script type")
+	}
+	return nil
+}
+
 func validateMsgTx(tx *wire.MsgTx, prevScripts [][]byte, inputValues []btcutil.Amount) error {
 	hashCache := txscript.NewTxSigHashes(tx)
 	flags := txscript.StandardVerifyFlags
+	prevouts := make([]*wire.TxOut, len(prevScripts))
+	for i, prevScript := range prevScripts {
+		prevouts[i] = wire.NewTxOut(int64(inputValues[i]), prevScript)
+	}
 	for i, prevScript := range prevScripts {
+		if isTaprootScript(prevScript) {
+			// the script engine predates taproot
+			if err := verifyTaprootKeyPathInput(tx, i, prevouts); err != nil {
+				return fmt.Errorf("cannot validate transaction: %s", err)
+			}
+			continue
+		}
 		vm, err := txscript.NewEngine(prevScript, tx, i, flags, nil, hashCache, int64(inputValues[i]))
 		if err != nil {
 			return fmt.Errorf("cannot create script engine: %s", err)
@@ -1,11 +1,14 @@
 package cnlib
 
+import "encoding/hex"
+import "errors"
 import "testing"
 import "github.com/stretchr/testify/assert"
 
 func TestTransactionBuilderBuildsTxCorrect(t *testing.T) {
 	inputPath := NewDerivationPath(BaseCoinBip49MainNet, 1, 53)
 	utxo := NewUTXO("1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87", 1, 2788424, inputPath, nil, true)
+	utxo.SetPrevout(testPrevoutScript("a914b5ffa0e0fe15880fb85f586ce6629a17f662d92387"), 2788424)
 	amount := 13584
 	feeAmount := 3000
 	changeAmount := 2771840
@@ -41,7 +44,9 @@ func TestTransactionBuilder_TwoInputs_BuildsTransaction(t *testing.T) {
 	path1 := NewDerivationPath(BaseCoinBip49MainNet, 1, 56)
 	path2 := NewDerivationPath(BaseCoinBip49MainNet, 1, 57)
 	utxo1 := NewUTXO("24cc9150963a2369d7f413af8b18c3d0243b438ba742d6d083ec8ed492d312f9", 1, 2769977, path1, nil, true)
+	utxo1.SetPrevout(testPrevoutScript("a914e0bc3e6f5f4080b4f007c6307ba579595e459a0687"), 2769977)
 	utxo2 := NewUTXO("ed611c20fc9088aa5ec1c86de88dd017965358c150c58f71eda721cdb2ac0a48", 1, 314605, path2, nil, true)
+	utxo2.SetPrevout(testPrevoutScript("a914f19099e6c1ce6e77b0d30508b1de0c883c75dd1087"), 314605)
 	amount := 3000000
 	feeAmount := 4000
 	changeAmount := 80582
@@ -77,6 +82,7 @@ func TestTransactionBuilder_TwoInputs_BuildsTransaction(t *testing.T) {
 func TestTransactionBuilder_BuildsNativeSegwitTransaction(t *testing.T) {
 	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
 	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
 	amount := 9755
 	feeAmount := 846
 	changeAmount := 85936
@@ -111,6 +117,7 @@ func TestTransactionBuilder_BuildsNativeSegwitTransaction(t *testing.T) {
 func TestTransactionBuilder_BuildP2KH_NoChange(t *testing.T) {
 	path := NewDerivationPath(BaseCoinBip49MainNet, 1, 7)
 	utxo := NewUTXO("f14914f76ad26e0c1aa5a68c82b021b854c93850fde12f8e3188c14be6dc384e", 1, 33255, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("a9144a859e98fbc59ce6891b8401f719a2a4c65df50f87"), 33255)
 	amount := 23147
 	feeAmount := 10108
 	changePath := NewDerivationPath(BaseCoinBip49MainNet, 1, 2)
@@ -140,6 +147,7 @@ func TestTransactionBuilder_BuildP2KH_NoChange(t *testing.T) {
 func TestTransationBuilder_BuildSingleUTXO(t *testing.T) {
 	path := NewDerivationPath(BaseCoinBip49MainNet, 0, 0)
 	utxo := NewUTXO("3480e31ea00efeb570472983ff914694f62804e768a6c6b4d1b6cd70a1cd3efa", 1, 449893, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("a9143fb6e95812e57bb4691f9a4a628862a61a4f769b87"), 449893)
 	amount := 218384
 	feeAmount := 668
 	changeAmount := 230841
@@ -171,6 +179,7 @@ func TestTransationBuilder_BuildSingleUTXO(t *testing.T) {
 func TestTransactionBuilder_TestNet(t *testing.T) {
 	path := NewDerivationPath(BaseCoinBip49TestNet, 0, 0)
 	utxo := NewUTXO("1cfd000efbe248c48b499b0a5d76ea7687ee76cad8481f71277ee283df32af26", 0, 1250000000, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("a914336caa13e08b96080a32b5d818d59b4ab3b3674287"), 1250000000)
 	amount := 9523810
 	feeAmount := 830
 	changeAmount := 1240475360
@@ -203,6 +212,7 @@ func TestTransactionBuilder_TestNet(t *testing.T) {
 func TestTransactionBuilder_SendToNativeSegwit_BuildsProperly(t *testing.T) {
 	path := NewDerivationPath(BaseCoinBip49MainNet, 0, 80)
 	utxo := NewUTXO("94b5bcfbd52a405b291d906e636c8e133407e68a75b0a1ccc492e131ff5d8f90", 0, 10261, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("a914b511ac39eeb6e63225954f2eb5bae5dfea2a678387"), 10261)
 	amount := 5000
 	feeAmount := 1000
 	changeAmount := 4261
@@ -231,3 +241,56 @@ func TestTransactionBuilder_SendToNativeSegwit_BuildsProperly(t *testing.T) {
 	assert.Equal(t, 102, meta.TransactionChangeMetadata.Path.Index)
 	assert.Equal(t, changeAmount, data.TransactionData.ChangeAmount)
 }
+
+func TestTransactionBuilder_PrevoutMismatch_ReturnsError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+
+	build := func(utxo *UTXO) error {
+		data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 9755, 846, changePath, 590582)
+		data.AddUTXO(utxo)
+		assert.Nil(t, data.Generate())
+		_, err := wallet.BuildTransactionMetadata(data.TransactionData)
+		return err
+	}
+
+	// no prevout reported
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, NewDerivationPath(BaseCoinBip84MainNet, 0, 1), nil, true)
+	assert.True(t, errors.Is(build(utxo), ErrPrevoutMissing))
+
+	// wrong derivation path claimed for the prevout
+	utxo = NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, NewDerivationPath(BaseCoinBip84MainNet, 0, 2), nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+	assert.True(t, errors.Is(build(utxo), ErrPrevoutScriptMismatch))
+
+	// wrong amount claimed for the prevout
+	utxo = NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, NewDerivationPath(BaseCoinBip84MainNet, 0, 1), nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 965370)
+	assert.True(t, errors.Is(build(utxo), ErrPrevoutAmountMismatch))
+}
+
+func TestTransactionBuilder_InputsDoNotCoverOutputs_ReturnsError(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	// caller overstates change after selection
+	data.TransactionData.ChangeAmount += 1
+	_, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.EqualError(t, err, "inputs do not cover outputs and fee")
+
+	_, err = wallet.SimulateTransactionMetadata(data.TransactionData)
+	assert.EqualError(t, err, "inputs do not cover outputs and fee")
+}
+
+// testPrevoutScript decodes a hex previous output script, as reported by the backend for a utxo.
+func testPrevoutScript(script string) []byte {
+	decoded, _ := hex.DecodeString(script)
+	return decoded
+}
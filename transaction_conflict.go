@@ -0,0 +1,156 @@
+package cnlib
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Following constants are used for TransactionConflict.Kind.
+const (
+	ConflictKindNone                 int = 0 // the transactions spend no common previous output
+	ConflictKindWalletReplacement    int = 1 // both were authored by the wallet, e.g. a fee bump or cancellation
+	ConflictKindCounterpartyReplaced int = 2 // neither was authored by the wallet, e.g. a sender replacing a payment
+	ConflictKindPartialAuthorship    int = 3 // only one was authored by the wallet, e.g. a counterparty double-spending its input to a joint transaction
+)
+
+/// Type Definitions
+
+// ConflictingInput is a previous output spent by both of two conflicting transactions.
+type ConflictingInput struct {
+	Txid             string
+	Index            int
+	FirstInputIndex  int  // index of the input spending it in the first transaction
+	SecondInputIndex int  // index of the input spending it in the second transaction
+	IsOwned          bool // true if the previous output belongs to the wallet; false if unknown
+}
+
+// TransactionConflict describes whether two transactions double-spend each other, and which the wallet authored, so
+// the app can explain a replaced or failed payment. A transaction is authored by the wallet if any of its inputs
+// spends a previous output belonging to the wallet, so authorship is only known for inputs whose previous outputs are
+// provided.
+type TransactionConflict struct {
+	FirstTxid              string
+	SecondTxid             string
+	Kind                   int  // one of the `ConflictKind` constants
+	FirstAuthoredByWallet  bool // true if any input of the first transaction spends a wallet output
+	SecondAuthoredByWallet bool
+	FirstPaysWallet        bool // true if any output of the first transaction pays a wallet address
+	SecondPaysWallet       bool
+	conflicts              []*ConflictingInput
+}
+
+/// Receiver functions
+
+// Conflicts returns true if the transactions spend at least one common previous output, so at most one can confirm.
+func (c *TransactionConflict) Conflicts() bool {
+	return len(c.conflicts) > 0
+}
+
+// ConflictingInputCount returns the number of previous outputs spent by both transactions.
+func (c *TransactionConflict) ConflictingInputCount() int {
+	return len(c.conflicts)
+}
+
+// ConflictingInputAtIndex returns the conflicting input at a given index, or error if out of bounds.
+func (c *TransactionConflict) ConflictingInputAtIndex(index int) (*ConflictingInput, error) {
+	if index < 0 || index > len(c.conflicts)-1 {
+		return nil, errors.New("index must be within range of conflicting inputs")
+	}
+	return c.conflicts[index], nil
+}
+
+// DetectConflict compares two hex-encoded transactions for inputs spending the same previous output, and reports which
+// the wallet authored and which pay it, by comparing against the wallet's receive and change addresses up to a given
+// index. Previous outputs are optional, but without them neither transaction is reported as authored by the wallet.
+// Two encodings of the same transaction, with the same txid, do not conflict.
+func (wallet *HDWallet) DetectConflict(firstEncodedTx string, secondEncodedTx string, prevouts *PrevoutSet, upTo int) (*TransactionConflict, error) {
+	first, err := decodeMsgTx(firstEncodedTx)
+	if err != nil {
+		return nil, err
+	}
+	second, err := decodeMsgTx(secondEncodedTx)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := wallet.derivedAddressMap(upTo)
+	if err != nil {
+		return nil, err
+	}
+
+	if prevouts == nil {
+		prevouts = NewPrevoutSet()
+	}
+
+	conflict := TransactionConflict{
+		FirstTxid:              first.TxHash().String(),
+		SecondTxid:             second.TxHash().String(),
+		FirstAuthoredByWallet:  spendsWalletOutput(first, prevouts, known),
+		SecondAuthoredByWallet: spendsWalletOutput(second, prevouts, known),
+		FirstPaysWallet:        wallet.paysWallet(first, known),
+		SecondPaysWallet:       wallet.paysWallet(second, known),
+		conflicts:              []*ConflictingInput{},
+	}
+	if conflict.FirstTxid == conflict.SecondTxid {
+		return &conflict, nil
+	}
+
+	secondInputs := make(map[wire.OutPoint]int)
+	for i, txIn := range second.TxIn {
+		secondInputs[txIn.PreviousOutPoint] = i
+	}
+	for i, txIn := range first.TxIn {
+		j, ok := secondInputs[txIn.PreviousOutPoint]
+		if !ok {
+			continue
+		}
+		input := &ConflictingInput{
+			Txid:             txIn.PreviousOutPoint.Hash.String(),
+			Index:            int(txIn.PreviousOutPoint.Index),
+			FirstInputIndex:  i,
+			SecondInputIndex: j,
+		}
+		if info, ok := prevouts.prevouts[txIn.PreviousOutPoint]; ok {
+			_, input.IsOwned = known[info.SelectedAddress]
+		}
+		conflict.conflicts = append(conflict.conflicts, input)
+	}
+
+	switch {
+	case !conflict.Conflicts():
+		conflict.Kind = ConflictKindNone
+	case conflict.FirstAuthoredByWallet && conflict.SecondAuthoredByWallet:
+		conflict.Kind = ConflictKindWalletReplacement
+	case conflict.FirstAuthoredByWallet || conflict.SecondAuthoredByWallet:
+		conflict.Kind = ConflictKindPartialAuthorship
+	default:
+		conflict.Kind = ConflictKindCounterpartyReplaced
+	}
+
+	return &conflict, nil
+}
+
+/// Unexported functions
+
+// spendsWalletOutput returns true if any input of tx spends a previous output belonging to a known address.
+func spendsWalletOutput(tx *wire.MsgTx, prevouts *PrevoutSet, known map[string]*MetaAddress) bool {
+	for _, txIn := range tx.TxIn {
+		if info, ok := prevouts.prevouts[txIn.PreviousOutPoint]; ok {
+			if _, owned := known[info.SelectedAddress]; owned {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// paysWallet returns true if any output of tx pays a known address.
+func (wallet *HDWallet) paysWallet(tx *wire.MsgTx, known map[string]*MetaAddress) bool {
+	for _, txOut := range tx.TxOut {
+		if _, ok := known[pkScriptAddress(txOut.PkScript, wallet.BaseCoin.defaultNetParams())]; ok {
+			return true
+		}
+	}
+	return false
+}
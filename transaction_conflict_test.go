@@ -0,0 +1,89 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestConflictingTx(t *testing.T, txid string, index int) string {
+	rt, err := NewRawTransaction(2, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, rt.AddInput(NewRawTransactionInput(txid, index, 0xfffffffd)))
+	pkScript, err := P2WPKHScript(make([]byte, 20))
+	assert.Nil(t, err)
+	assert.Nil(t, rt.AddOutput(NewRawTransactionOutput(90000, pkScript)))
+	encoded, err := rt.Encode()
+	assert.Nil(t, err)
+	return encoded
+}
+
+func TestDetectConflict_WalletReplacement(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	source, err := wallet.ReceiveAddressForIndex(1)
+	assert.Nil(t, err)
+	prevouts := NewPrevoutSet()
+	assert.Nil(t, prevouts.AddPrevout(NewPreviousOutputInfo(source.Address, "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537)))
+
+	replacement := newTestConflictingTx(t, "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0)
+	conflict, err := wallet.DetectConflict(analysisEncodedTx, replacement, prevouts, 5)
+	assert.Nil(t, err)
+	assert.True(t, conflict.Conflicts())
+	assert.Equal(t, ConflictKindWalletReplacement, conflict.Kind)
+	assert.Equal(t, "fe7f9a6de3203eb300cc66159e762251d675b5555dbd215c3574e75a762ca402", conflict.FirstTxid)
+	assert.True(t, conflict.FirstAuthoredByWallet)
+	assert.True(t, conflict.SecondAuthoredByWallet)
+	assert.True(t, conflict.FirstPaysWallet)
+	assert.False(t, conflict.SecondPaysWallet)
+	assert.Equal(t, 1, conflict.ConflictingInputCount())
+
+	input, err := conflict.ConflictingInputAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", input.Txid)
+	assert.Equal(t, 0, input.Index)
+	assert.True(t, input.IsOwned)
+	_, err = conflict.ConflictingInputAtIndex(1)
+	assert.NotNil(t, err)
+}
+
+func TestDetectConflict_CounterpartyReplacedWithoutPrevouts(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	replacement := newTestConflictingTx(t, "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0)
+
+	conflict, err := wallet.DetectConflict(analysisEncodedTx, replacement, nil, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, ConflictKindCounterpartyReplaced, conflict.Kind)
+	assert.False(t, conflict.FirstAuthoredByWallet)
+	assert.True(t, conflict.FirstPaysWallet)
+}
+
+func TestDetectConflict_NoConflict(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	unrelated := newTestConflictingTx(t, "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 1)
+
+	conflict, err := wallet.DetectConflict(analysisEncodedTx, unrelated, nil, 5)
+	assert.Nil(t, err)
+	assert.False(t, conflict.Conflicts())
+	assert.Equal(t, ConflictKindNone, conflict.Kind)
+
+	same, err := wallet.DetectConflict(analysisEncodedTx, analysisEncodedTx, nil, 5)
+	assert.Nil(t, err)
+	assert.False(t, same.Conflicts())
+
+	_, err = wallet.DetectConflict(analysisEncodedTx, "zz", nil, 5)
+	assert.NotNil(t, err)
+}
+
+func TestDetectConflict_BIP86TaprootPayment(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip86MainNet)
+	receive, err := wallet.ReceiveAddressForIndex(2)
+	assert.Nil(t, err)
+
+	payment := newTestTaprootWalletTx(t, descriptorTestTxid, receive.Address)
+	replacement := newTestConflictingTx(t, descriptorTestTxid, 0)
+	conflict, err := wallet.DetectConflict(payment, replacement, nil, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, ConflictKindCounterpartyReplaced, conflict.Kind)
+	assert.True(t, conflict.FirstPaysWallet)
+	assert.False(t, conflict.SecondPaysWallet)
+}
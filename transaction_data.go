@@ -42,6 +42,14 @@ type TransactionData struct {
 	ChangePath     *DerivationPath
 	Locktime       int
 	RBFOption      *RBFOption
+
+	// set by `ApplyPreset`; the zero values keep the original behavior
+	selectionStrategy int
+	ordering          int
+	changePolicy      int
+
+	// set by `SetAccountMixingAllowed`
+	forbidAccountMixing bool
 }
 
 // TransactionDataStandard adopts the Transaction interface, customizing the generation of the transaction.
@@ -225,20 +233,68 @@ func (t *TransactionDataStandard) Generate() error {
 		return err
 	}
 
-	totalFromUTXOs := 0
-	totalSendingValue := 0
-	currentFee := 0
+	return t.TransactionData.generateWithinAccount(t.generate)
+}
+
+// Generate is called after all available utxo's have been added, to configure the transaction data. Builds a standard transaction with a flat fee.
+func (t *TransactionDataFlatFee) Generate() error {
+
+	err := t.TransactionData.validate()
+	if err != nil {
+		t.TransactionData = nil
+		return err
+	}
+
+	return t.TransactionData.generateWithinAccount(t.generate)
+}
+
+// Generate is called after all available utxo's have been added, to configure the transaction data. Builds a transaction sending max with a fee rate.
+func (t *TransactionDataSendMax) Generate() error {
+	err := t.TransactionData.generateWithinAccount(t.generate)
+	if err == errTransactionTooSmall {
+		t.TransactionData = nil
+	}
+	return err
+}
+
+// UtxoCount returns count of UTXOs required to satisfy the transaction, not all UTXOs passed in before calling `Generate`.
+func (td *TransactionData) UtxoCount() int {
+	return len(td.requiredUtxos)
+}
+
+/// Unexported Functions
+
+// generate selects utxos from those available for a standard transaction.
+func (t *TransactionDataStandard) generate() error {
+	if t.TransactionData.selectionStrategy == SelectionStrategyAll {
+		if err := t.generateSpendingAll(); err != nil {
+			return err
+		}
+		t.TransactionData.sortRequiredUTXOs()
+		return nil
+	}
+
+	utxos, err := t.TransactionData.utxosForSelection()
+	if err != nil {
+		return err
+	}
+
+	amount := int64(t.TransactionData.Amount)
+	feeRate := int64(t.TransactionData.feeRate)
+	totalFromUTXOs := int64(0)
+	totalSendingValue := int64(0)
+	currentFee := int64(0)
 	tempUTXOs := make([]*UTXO, 0)
 
-	for i := 0; i < len(t.TransactionData.availableUtxos); i++ {
-		utxo := t.TransactionData.availableUtxos[i]
+	for i := 0; i < len(utxos); i++ {
+		utxo := utxos[i]
 		bytes, err := t.TransactionData.basecoin.bytesPerInput(utxo)
 		if err != nil {
 			t.TransactionData = nil
 			return err
 		}
-		feePerInput := t.TransactionData.feeRate * bytes
-		totalSendingValue = t.TransactionData.Amount + currentFee
+		feePerInput := feeRate * int64(bytes)
+		totalSendingValue = amount + currentFee
 
 		if totalSendingValue > totalFromUTXOs {
 			tempUTXOs = append(tempUTXOs, utxo)
@@ -247,8 +303,8 @@ func (t *TransactionDataStandard) Generate() error {
 			if err != nil {
 				return err
 			}
-			currentFee = t.TransactionData.feeRate * totalBytes
-			totalSendingValue = t.TransactionData.Amount + currentFee
+			currentFee = feeRate * int64(totalBytes)
+			totalSendingValue = amount + currentFee
 
 			changeValue := totalFromUTXOs - totalSendingValue
 
@@ -256,7 +312,12 @@ func (t *TransactionDataStandard) Generate() error {
 				continue
 			}
 
-			if (changeValue > 0) && (changeValue < (feePerInput + dustThreshold)) {
+			keepChange, err := t.TransactionData.keepsChange(changeValue, feePerInput)
+			if err != nil {
+				return err
+			}
+
+			if (changeValue > 0) && !keepChange {
 				// it is not beneficial to add change, would just dust self with change
 				currentFee += changeValue
 				break
@@ -266,40 +327,39 @@ func (t *TransactionDataStandard) Generate() error {
 					return err
 				}
 				totalBytes = estBytes
-				currentFee = t.TransactionData.feeRate * totalBytes
-				changeValue = totalFromUTXOs - t.TransactionData.Amount - currentFee
-				t.TransactionData.ChangeAmount = changeValue
+				currentFee = feeRate * int64(totalBytes)
+				changeValue = totalFromUTXOs - amount - currentFee
+				t.TransactionData.ChangeAmount = int(changeValue)
 				break
 			} else if changeValue < 0 {
 				currentFee += changeValue
-				changeValue = 0
-				t.TransactionData.ChangeAmount = changeValue
+				t.TransactionData.ChangeAmount = 0
 			}
 		} else {
 			break
 		}
 	}
 
-	t.TransactionData.FeeAmount = currentFee
+	t.TransactionData.FeeAmount = int(currentFee)
 	t.TransactionData.requiredUtxos = tempUTXOs
+	t.TransactionData.sortRequiredUTXOs()
 
 	if totalFromUTXOs < totalSendingValue {
-		return errors.New("insufficient funds")
+		fee, err := t.TransactionData.noChangeFee()
+		if err != nil {
+			return err
+		}
+		return t.TransactionData.insufficientFundsError(amount, fee)
 	}
 
 	return nil
 }
 
-// Generate is called after all available utxo's have been added, to configure the transaction data. Builds a standard transaction with a flat fee.
-func (t *TransactionDataFlatFee) Generate() error {
-
-	err := t.TransactionData.validate()
-	if err != nil {
-		t.TransactionData = nil
-		return err
-	}
-
-	totalFromUTXOs := 0
+// generate selects utxos from those available for a flat fee transaction.
+func (t *TransactionDataFlatFee) generate() error {
+	amount := int64(t.TransactionData.Amount)
+	feeAmount := int64(t.TransactionData.FeeAmount)
+	totalFromUTXOs := int64(0)
 	tempUTXOs := make([]*UTXO, 0)
 
 	for i := 0; i < len(t.TransactionData.availableUtxos); i++ {
@@ -307,23 +367,23 @@ func (t *TransactionDataFlatFee) Generate() error {
 		tempUTXOs = append(tempUTXOs, utxo)
 		totalFromUTXOs += utxo.Amount
 
-		possibleChange := totalFromUTXOs - t.TransactionData.Amount - t.TransactionData.FeeAmount
-		tempChangeAmount := Max(0, possibleChange)
+		possibleChange := totalFromUTXOs - amount - feeAmount
+		tempChangeAmount := Max(0, int(possibleChange))
 		t.TransactionData.ChangeAmount = tempChangeAmount
 
-		if totalFromUTXOs >= t.TransactionData.Amount && tempChangeAmount > 0 {
+		if totalFromUTXOs >= amount && tempChangeAmount > 0 {
 			if tempChangeAmount < dustThreshold {
 				t.TransactionData.ChangeAmount = 0
 			}
 		}
 
-		if totalFromUTXOs >= (t.TransactionData.FeeAmount + t.TransactionData.Amount) {
+		if totalFromUTXOs >= feeAmount+amount {
 			break
 		}
 	}
 
-	if totalFromUTXOs < (t.TransactionData.FeeAmount + t.TransactionData.Amount) {
-		return errors.New("insufficient funds")
+	if totalFromUTXOs < feeAmount+amount {
+		return t.TransactionData.insufficientFundsError(amount, feeAmount)
 	}
 
 	t.TransactionData.requiredUtxos = tempUTXOs
@@ -331,44 +391,30 @@ func (t *TransactionDataFlatFee) Generate() error {
 	return nil
 }
 
-// Generate is called after all available utxo's have been added, to configure the transaction data. Builds a transaction sending max with a fee rate.
-func (t *TransactionDataSendMax) Generate() error {
-	tempUTXOs := t.TransactionData.availableUtxos
-	totalFromUTXOs := 0
-	for _, utxo := range t.TransactionData.availableUtxos {
-		totalFromUTXOs += utxo.Amount
-	}
-
-	totalBytes, err := t.TransactionData.basecoin.totalBytes(tempUTXOs, t.TransactionData.PaymentAddress, false)
+// generate selects utxos from those available for a send max transaction.
+func (t *TransactionDataSendMax) generate() error {
+	td := t.TransactionData
+	outputBytes, err := td.basecoin.bytesPerDestination(td.PaymentAddress)
 	if err != nil {
 		return err
 	}
 
-	feeAmount := t.TransactionData.feeRate * totalBytes
-	amountForValidation := totalFromUTXOs - feeAmount
-	if amountForValidation < 0 {
-		return errors.New("insufficient funds")
-	}
-	t.TransactionData.Amount = amountForValidation
-	t.TransactionData.FeeAmount = feeAmount
-	t.TransactionData.requiredUtxos = tempUTXOs
-
-	err = t.TransactionData.validate()
+	// utxos worth less than the fee to spend them are left behind, as spending them would reduce the amount sent
+	amountForValidation, tempUTXOs, err := td.basecoin.maxSendable(td.availableUtxos, int64(td.feeRate), outputBytes)
 	if err != nil {
-		t.TransactionData = nil
 		return err
 	}
+	feeAmount := totalUTXOAmount(tempUTXOs) - amountForValidation
+	if amountForValidation < 0 {
+		return td.insufficientFundsError(0, feeAmount)
+	}
+	td.Amount = int(amountForValidation)
+	td.FeeAmount = int(feeAmount)
+	td.requiredUtxos = tempUTXOs
 
-	return nil
+	return td.validate()
 }
 
-// UtxoCount returns count of UTXOs required to satisfy the transaction, not all UTXOs passed in before calling `Generate`.
-func (td *TransactionData) UtxoCount() int {
-	return len(td.requiredUtxos)
-}
-
-/// Unexported Functions
-
 func (td *TransactionData) shouldAddChangeToTransaction() bool {
 	return td.ChangeAmount > 0
 }
@@ -393,9 +439,37 @@ func (td *TransactionData) getSuggestedSequence() uint32 {
 	return wire.MaxTxInSequenceNum
 }
 
+// validateBalance returns error if the required utxos do not cover the outputs and fee. The builder checks this
+// itself, rather than trusting the amounts set by `Generate` or restored by a caller.
+func (td *TransactionData) validateBalance() error {
+	inputs := totalUTXOAmount(td.requiredUtxos)
+	outputs := int64(td.Amount)
+	if td.shouldAddChangeToTransaction() {
+		outputs += int64(td.ChangeAmount)
+	}
+	if td.Amount < 0 || td.ChangeAmount < 0 || td.FeeAmount < 0 {
+		return errors.New("amounts cannot be negative")
+	}
+	if inputs < outputs+int64(td.FeeAmount) {
+		return errors.New("inputs do not cover outputs and fee")
+	}
+	return nil
+}
+
+// totalUTXOAmount returns the sum, in satoshis, of the given utxos.
+func totalUTXOAmount(utxos []*UTXO) int64 {
+	total := int64(0)
+	for _, utxo := range utxos {
+		total += utxo.Amount
+	}
+	return total
+}
+
+var errTransactionTooSmall = errors.New("transaction too small")
+
 func (td *TransactionData) validate() error {
 	if td.Amount < 1000 {
-		return errors.New("transaction too small")
+		return errTransactionTooSmall
 	}
 	return nil
 }
@@ -14,7 +14,7 @@ func TestNewTransactionDataStandard_SingleOutput_SingleInput_SatisfiesAmount(t *
 	utxoAmount := 100000000 // 1.0 BTC
 	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 0)
 	utxoPath := NewDerivationPath(BaseCoinBip49MainNet, 0, 0)
-	utxo := NewUTXO("previous txid", 0, utxoAmount, utxoPath, nil, true)
+	utxo := NewUTXO("previous txid", 0, int64(utxoAmount), utxoPath, nil, true)
 	utxos := []*UTXO{utxo}
 	feeRate := 30
 	totalBytes, err := BaseCoinBip49MainNet.totalBytes(utxos, address, true)
@@ -52,8 +52,8 @@ func TestTransactionDataStandard_SingleOutput_DoubleInput_WithChange(t *testing.
 	utxoAmount := 30000000    // 0.3 BTC
 	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 0)
 	utxoPath := NewDerivationPath(BaseCoinBip49MainNet, 0, 0)
-	utxo1 := NewUTXO("previous txid", 0, utxoAmount, utxoPath, nil, true)
-	utxo2 := NewUTXO("previous txid", 1, utxoAmount, utxoPath, nil, true)
+	utxo1 := NewUTXO("previous txid", 0, int64(utxoAmount), utxoPath, nil, true)
+	utxo2 := NewUTXO("previous txid", 1, int64(utxoAmount), utxoPath, nil, true)
 	utxos := []*UTXO{utxo1, utxo2}
 	feeRate := 30
 	totalBytes, err := BaseCoinBip84MainNet.totalBytes(utxos, address, true)
@@ -62,7 +62,7 @@ func TestTransactionDataStandard_SingleOutput_DoubleInput_WithChange(t *testing.
 	expectedFeeAmount := feeRate * totalBytes // 7,680
 	amountFromUTXOs := 0
 	for _, utxo := range utxos {
-		amountFromUTXOs += utxo.Amount
+		amountFromUTXOs += int(utxo.Amount)
 	}
 	expectedChangeAmount := amountFromUTXOs - paymentAmount - expectedFeeAmount
 	expectedNumberOfUTXOs := 2
@@ -94,7 +94,7 @@ func TestNewTransactionDataStandard_SingleInput_SingleOutput_NoChange(t *testing
 	utxoAmount := 50004020    // 0.50004020 BTC
 	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 0)
 	utxoPath := NewDerivationPath(BaseCoinBip49MainNet, 0, 0)
-	utxo := NewUTXO("previous txid", 0, utxoAmount, utxoPath, nil, true)
+	utxo := NewUTXO("previous txid", 0, int64(utxoAmount), utxoPath, nil, true)
 	utxos := []*UTXO{utxo}
 	feeRate := 30
 	totalBytes, err := BaseCoinBip84MainNet.totalBytes(utxos, address, false)
@@ -103,7 +103,7 @@ func TestNewTransactionDataStandard_SingleInput_SingleOutput_NoChange(t *testing
 	expectedFeeAmount := feeRate * totalBytes // 4,020
 	amountFromUTXOs := 0
 	for _, utxo := range utxos {
-		amountFromUTXOs += utxo.Amount
+		amountFromUTXOs += int(utxo.Amount)
 	}
 	expectedChangeAmount := amountFromUTXOs - paymentAmount - expectedFeeAmount
 	expectedNumberOfUTXOs := 1
@@ -136,8 +136,8 @@ func TestNewTransactionStandard_SingleOutput_DoubleInput_NoChange(t *testing.T)
 	utxoAmount2 := 30005000   // 0.30005000 BTC
 	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 0)
 	utxoPath := NewDerivationPath(BaseCoinBip49MainNet, 0, 0)
-	utxo1 := NewUTXO("previous txid", 0, utxoAmount1, utxoPath, nil, true)
-	utxo2 := NewUTXO("previous txid", 1, utxoAmount2, utxoPath, nil, true)
+	utxo1 := NewUTXO("previous txid", 0, int64(utxoAmount1), utxoPath, nil, true)
+	utxo2 := NewUTXO("previous txid", 1, int64(utxoAmount2), utxoPath, nil, true)
 	utxos := []*UTXO{utxo1, utxo2}
 	feeRate := 30
 	totalBytes, err := BaseCoinBip84MainNet.totalBytes(utxos, address, false)
@@ -146,7 +146,7 @@ func TestNewTransactionStandard_SingleOutput_DoubleInput_NoChange(t *testing.T)
 	expectedFeeAmount := feeRate * totalBytes // 6, 750
 	amountFromUTXOs := 0
 	for _, utxo := range utxos {
-		amountFromUTXOs += utxo.Amount
+		amountFromUTXOs += int(utxo.Amount)
 	}
 	expectedChangeAmount := 0
 	expectedNumberOfUTXOs := len(utxos)
@@ -179,8 +179,8 @@ func TestNewTransactionStandard_SingleOutput_DoubleInput_InsufficientFunds(t *te
 	utxoAmount2 := 10000000   // 0.10000000 BTC
 	changePath := NewDerivationPath(BaseCoinBip49MainNet, 1, 0)
 	utxoPath := NewDerivationPath(BaseCoinBip49MainNet, 0, 0)
-	utxo1 := NewUTXO("previous txid", 0, utxoAmount1, utxoPath, nil, true)
-	utxo2 := NewUTXO("previous txid", 1, utxoAmount2, utxoPath, nil, true)
+	utxo1 := NewUTXO("previous txid", 0, int64(utxoAmount1), utxoPath, nil, true)
+	utxo2 := NewUTXO("previous txid", 1, int64(utxoAmount2), utxoPath, nil, true)
 	utxos := []*UTXO{utxo1, utxo2}
 	feeRate := 30
 	rbf := NewRBFOption(AllowedToBeRBF)
@@ -194,6 +194,16 @@ func TestNewTransactionStandard_SingleOutput_DoubleInput_InsufficientFunds(t *te
 
 	// then
 	assert.EqualError(t, errors.New("insufficient funds"), err.Error())
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+	noChangeBytes := 225 // 11 base + 2 * 91 p2sh-p2wpkh inputs + 32 p2sh output
+	details := InsufficientFundsDetails(err)
+	if assert.NotNil(t, details) {
+		assert.Equal(t, utxoAmount1+utxoAmount2, details.SpendableBalance)
+		assert.Equal(t, paymentAmount+feeRate*noChangeBytes, details.RequiredAmount)
+		assert.Equal(t, details.RequiredAmount-details.SpendableBalance, details.Shortfall)
+		assert.Equal(t, feeRate, details.FeeRate)
+		assert.Equal(t, utxoAmount1+utxoAmount2-feeRate*noChangeBytes, details.MaxSendableAmount)
+	}
 }
 
 func TestNewTransactionDataStandard_SingleBIP84Output_SingleBIP49Input(t *testing.T) {
@@ -204,8 +214,8 @@ func TestNewTransactionDataStandard_SingleBIP84Output_SingleBIP49Input(t *testin
 	utxoAmount2 := 30000000   // 0.3 BTC
 	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 0)
 	utxoPath := NewDerivationPath(BaseCoinBip49MainNet, 0, 0)
-	utxo1 := NewUTXO("previous txid", 0, utxoAmount1, utxoPath, nil, true)
-	utxo2 := NewUTXO("previous txid", 1, utxoAmount2, utxoPath, nil, true)
+	utxo1 := NewUTXO("previous txid", 0, int64(utxoAmount1), utxoPath, nil, true)
+	utxo2 := NewUTXO("previous txid", 1, int64(utxoAmount2), utxoPath, nil, true)
 	utxos := []*UTXO{utxo1, utxo2}
 	feeRate := 30
 	totalBytes, err := BaseCoinBip84MainNet.totalBytes(utxos, address, true)
@@ -237,6 +247,26 @@ func TestNewTransactionDataStandard_SingleBIP84Output_SingleBIP49Input(t *testin
 	assert.Equal(t, expectedRBFOption.Value, data.TransactionData.RBFOption.Value)
 }
 
+func TestNewTransactionDataStandard_BIP44Descriptor_SizesP2PKH(t *testing.T) {
+	// given
+	wallet, err := NewWalletFromDescriptor("pkh([73c5da0a/44'/0'/0']xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/<0;1>/*)")
+	assert.Nil(t, err)
+	address := "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+	utxoPath := NewDerivationPath(wallet.BaseCoin, 0, 0)
+	changePath := NewDerivationPath(wallet.BaseCoin, 1, 0)
+	utxo := NewUTXO("previous txid", 0, 100000, utxoPath, nil, true)
+
+	// when
+	data := NewTransactionDataStandard(address, wallet.BaseCoin, 50000, 10, changePath, 500000, NewRBFOption(AllowedToBeRBF))
+	data.AddUTXO(utxo)
+	err = data.Generate()
+
+	// then, one P2PKH input, P2WPKH payment and P2PKH change
+	assert.Nil(t, err)
+	assert.Equal(t, 2230, data.TransactionData.FeeAmount)
+	assert.Equal(t, 100000-50000-2230, data.TransactionData.ChangeAmount)
+}
+
 func TestNewTransactionDataStandard_CostOfChangeIsBeneficial(t *testing.T) {
 	// given
 	address := "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"
@@ -252,7 +282,7 @@ func TestNewTransactionDataStandard_CostOfChangeIsBeneficial(t *testing.T) {
 
 	dustyChange := 1100
 	expectedFeeAmount := feeRate*totalBytes + dustyChange
-	paymentAmount := utxo1.Amount + utxo2.Amount - expectedFeeAmount
+	paymentAmount := int(utxo1.Amount) + int(utxo2.Amount) - expectedFeeAmount
 	expectedLocktime := 500000
 	expectedRBFOption := NewRBFOption(AllowedToBeRBF)
 
@@ -325,6 +355,28 @@ func TestSendingDust_FlatFeeTransaction_ReturnsError(t *testing.T) {
 	assert.Nil(t, data.TransactionData)
 }
 
+func TestNewTransactionDataFlatFee_InsufficientFunds_ReturnsDetails(t *testing.T) {
+	address := "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"
+	path := NewDerivationPath(BaseCoinBip49MainNet, 0, 2)
+	utxo := NewUTXO("419a7a7d27e0c4341ca868d0b9744ae7babb18fd691e39be608b556961c00ade", 0, 15935, path, nil, true)
+	paymentAmount := 20000
+	flatFee := 1000
+
+	data := NewTransactionDataFlatFee(address, BaseCoinBip49MainNet, paymentAmount, flatFee, nil, 500000)
+	data.AddUTXO(utxo)
+	err := data.Generate()
+
+	details := InsufficientFundsDetails(err)
+	if assert.NotNil(t, details) {
+		assert.Equal(t, 15935, details.SpendableBalance)
+		assert.Equal(t, 21000, details.RequiredAmount)
+		assert.Equal(t, 5065, details.Shortfall)
+		assert.Equal(t, 0, details.FeeRate)
+		assert.Equal(t, 14935, details.MaxSendableAmount)
+	}
+	assert.Nil(t, InsufficientFundsDetails(errors.New("transaction too small")))
+}
+
 func TestNewTransactionDataFlatFee_WithChange(t *testing.T) {
 	// given
 	address := "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"
@@ -437,7 +489,7 @@ func TestNewTransactionDataSendMax_UsesAllUTXOs_AmountIsTotalMinusFee(t *testing
 	utxo1 := NewUTXO("909ac6e0a31c68fe345cc72d568bbab75afb5229b648753c486518f11c0d0009", 1, 20000, path1, nil, true)
 	utxo2 := NewUTXO("419a7a7d27e0c4341ca868d0b9744ae7babb18fd691e39be608b556961c00ade", 0, 10000, path2, nil, true)
 	utxos := []*UTXO{utxo1, utxo2}
-	inputAmount := utxo1.Amount + utxo2.Amount
+	inputAmount := int(utxo1.Amount + utxo2.Amount)
 	totalBytes, err := BaseCoinBip49MainNet.totalBytes(utxos, address, false)
 	assert.Nil(t, err)
 
@@ -510,7 +562,7 @@ func TestNewTransactionDataSendMax_ToNativeSegwit(t *testing.T) {
 	utxo1 := NewUTXO("909ac6e0a31c68fe345cc72d568bbab75afb5229b648753c486518f11c0d0009", 1, 20000, path1, nil, true)
 	utxo2 := NewUTXO("419a7a7d27e0c4341ca868d0b9744ae7babb18fd691e39be608b556961c00ade", 0, 10000, path2, nil, true)
 	utxos := []*UTXO{utxo1, utxo2}
-	inputAmount := utxo1.Amount + utxo2.Amount
+	inputAmount := int(utxo1.Amount + utxo2.Amount)
 	totalBytes, err := BaseCoinBip49MainNet.totalBytes(utxos, address, false) // 224
 	assert.Nil(t, err)
 
@@ -544,8 +596,10 @@ func TestNewTransactionDataStandard_TwoSegwitInputs_TwoSegwitOutputs(t *testing.
 	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 20)
 	utxo1 := NewUTXO("ca470899cad4aa48487e5cabb6abd387b0ff7a4ef380d3544a6a738f3c101e37", 0, 13770, path1, nil, true)
 	utxo2 := NewUTXO("16ce8aaf23d15f3440e4369600a3004e47ca0940d4756eb45a655c538dcaaa4a", 1, 197171, path2, nil, true)
+	utxo1.SetPrevout(testPrevoutScript("001442f90a7287eb310a66e10331ffb289d69b3c8a6e"), 13770)
+	utxo2.SetPrevout(testPrevoutScript("0014a3b43969f2bc6883d0a6482b7e8d264e97d8ec1a"), 197171)
 	utxos := []*UTXO{utxo1, utxo2}
-	inputAmount := utxo1.Amount + utxo2.Amount
+	inputAmount := int(utxo1.Amount + utxo2.Amount)
 	paymentAmount := 200000
 	totalBytes, err := BaseCoinBip84MainNet.totalBytes(utxos, address, true)
 	assert.Nil(t, err)
@@ -593,7 +647,7 @@ func TestSweepingPrivatekey_P2PKH_BuildsProperly(t *testing.T) {
 	expectedAmount := amount - expectedFeeAmount
 	info := NewPreviousOutputInfo(pkAddress, "txid string", 0, amount)
 	imported := ImportedPrivateKey{wif: wif, PossibleAddresses: pkAddress, PrivateKeyAsWIF: pkString, PreviousOutputInfo: info}
-	utxo := NewUTXO(info.Txid, info.Index, info.Amount, nil, &imported, true)
+	utxo := NewUTXO(info.Txid, info.Index, int64(info.Amount), nil, &imported, true)
 
 	// when
 	data := NewTransactionDataSendingMax(address, BaseCoinBip84MainNet, feeRate, 614024)
@@ -0,0 +1,217 @@
+package cnlib
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Following constants are used for ApplyPreset.
+const (
+	TransactionPresetEconomical    int = 0
+	TransactionPresetFast          int = 1
+	TransactionPresetPrivacy       int = 2
+	TransactionPresetConsolidation int = 3
+)
+
+// Following constants are used for TransactionPreset.SelectionStrategy.
+const (
+	SelectionStrategyInOrder          int = 0 // utxos in the order they were added
+	SelectionStrategyLargestFirst     int = 1
+	SelectionStrategyConfirmedFirst   int = 2 // confirmed utxos largest first, then unconfirmed
+	SelectionStrategySingleInputFirst int = 3 // the smallest single utxo covering the payment, else largest first
+	SelectionStrategyAll              int = 4 // every utxo, consolidating them into change
+)
+
+// Following constants are used for TransactionPreset.Ordering.
+const (
+	OrderingPaymentFirst int = 0 // inputs as selected, payment output before change
+	OrderingBIP69        int = 1 // inputs and outputs sorted per BIP69, so change cannot be told apart by position
+)
+
+// Following constants are used for TransactionPreset.ChangePolicy.
+const (
+	// ChangePolicyStandard adds change unless it is below the dust threshold plus the fee of the last input.
+	ChangePolicyStandard int = 0
+	// ChangePolicyAvoidSmall also gives up change worth less than the fee to create and later spend it, as such change
+	// is of little use and links the payment to a future transaction.
+	ChangePolicyAvoidSmall int = 1
+)
+
+/// Type Definitions
+
+// TransactionPreset bundles the settings applied by a named preset, such as TransactionPresetPrivacy, so every
+// platform configures a transaction the same way.
+type TransactionPreset struct {
+	SelectionStrategy int
+	Ordering          int
+	RBFOption         int
+	ChangePolicy      int
+}
+
+/// Exported functions
+
+// TransactionPresetSettings returns the settings of a named preset, or error if unrecognized.
+//
+// - Economical: largest utxos first, for the fewest inputs, and replaceable so a low fee can be bumped.
+// - Fast: confirmed utxos first, so the transaction does not wait on unconfirmed parents, and not replaceable, which
+// some recipients require to accept it before it confirms.
+// - Privacy: a single input where possible, BIP69 ordering and avoiding small change, to limit what the
+// transaction reveals about the wallet.
+// - Consolidation: every utxo, returning the remainder as change, at a fee rate the caller should set low.
+func TransactionPresetSettings(preset int) (*TransactionPreset, error) {
+	switch preset {
+	case TransactionPresetEconomical:
+		return &TransactionPreset{SelectionStrategyLargestFirst, OrderingPaymentFirst, MustBeRBF, ChangePolicyStandard}, nil
+	case TransactionPresetFast:
+		return &TransactionPreset{SelectionStrategyConfirmedFirst, OrderingPaymentFirst, MustNotBeRBF, ChangePolicyStandard}, nil
+	case TransactionPresetPrivacy:
+		return &TransactionPreset{SelectionStrategySingleInputFirst, OrderingBIP69, AllowedToBeRBF, ChangePolicyAvoidSmall}, nil
+	case TransactionPresetConsolidation:
+		return &TransactionPreset{SelectionStrategyAll, OrderingBIP69, MustBeRBF, ChangePolicyStandard}, nil
+	}
+	return nil, errors.New("unrecognized transaction preset")
+}
+
+/// Receiver functions
+
+// ApplyPreset configures utxo selection, ordering, RBF and change for a named preset, replacing the RBFOption given
+// to the constructor. Call before `Generate`.
+func (t *TransactionDataStandard) ApplyPreset(preset int) error {
+	settings, err := TransactionPresetSettings(preset)
+	if err != nil {
+		return err
+	}
+	td := t.TransactionData
+	td.selectionStrategy = settings.SelectionStrategy
+	td.ordering = settings.Ordering
+	td.changePolicy = settings.ChangePolicy
+	td.RBFOption = NewRBFOption(settings.RBFOption)
+	return nil
+}
+
+/// Unexported functions
+
+// utxosForSelection returns the available utxos in the order the selection strategy considers them.
+func (td *TransactionData) utxosForSelection() ([]*UTXO, error) {
+	utxos := append([]*UTXO{}, td.availableUtxos...)
+	largestFirst := func(i, j int) bool { return utxos[i].Amount > utxos[j].Amount }
+
+	switch td.selectionStrategy {
+	case SelectionStrategyLargestFirst:
+		sort.SliceStable(utxos, largestFirst)
+	case SelectionStrategyConfirmedFirst:
+		sort.SliceStable(utxos, func(i, j int) bool {
+			if utxos[i].IsConfirmed != utxos[j].IsConfirmed {
+				return utxos[i].IsConfirmed
+			}
+			return largestFirst(i, j)
+		})
+	case SelectionStrategySingleInputFirst:
+		sort.SliceStable(utxos, largestFirst)
+		single := -1
+		for i, utxo := range utxos {
+			totalBytes, err := td.basecoin.totalBytes([]*UTXO{utxo}, td.PaymentAddress, true)
+			if err != nil {
+				return nil, err
+			}
+			if utxo.Amount >= int64(td.Amount)+int64(td.feeRate)*int64(totalBytes) {
+				single = i // keep going, to find the smallest
+			}
+		}
+		if single > 0 {
+			utxos = append(append([]*UTXO{utxos[single]}, utxos[:single]...), utxos[single+1:]...)
+		}
+	}
+	return utxos, nil
+}
+
+// keepsChange returns true if change of a given value should be added, given the fee of the last input selected.
+func (td *TransactionData) keepsChange(changeValue int64, feePerInput int64) (bool, error) {
+	threshold := feePerInput + dustThreshold
+	if td.changePolicy == ChangePolicyAvoidSmall {
+		changeInputBytes, err := td.basecoin.bytesPerInput(nil)
+		if err != nil {
+			return false, err
+		}
+		cost := int64(td.feeRate) * int64(td.basecoin.bytesPerChangeOuptut()+changeInputBytes)
+		if cost+dustThreshold > threshold {
+			threshold = cost + dustThreshold
+		}
+	}
+	return changeValue >= threshold, nil
+}
+
+// generateSpendingAll selects every available utxo for SelectionStrategyAll.
+func (t *TransactionDataStandard) generateSpendingAll() error {
+	td := t.TransactionData
+	amount := int64(td.Amount)
+	feeRate := int64(td.feeRate)
+	total := totalUTXOAmount(td.availableUtxos)
+
+	withChangeBytes, err := td.basecoin.totalBytes(td.availableUtxos, td.PaymentAddress, true)
+	if err != nil {
+		return err
+	}
+	fee := feeRate * int64(withChangeBytes)
+	change := total - amount - fee
+	keep, err := td.keepsChange(change, 0)
+	if err != nil {
+		return err
+	}
+	if !keep {
+		noChangeFee, err := td.noChangeFee()
+		if err != nil {
+			return err
+		}
+		if total < amount+noChangeFee {
+			return td.insufficientFundsError(amount, noChangeFee)
+		}
+		fee, change = total-amount, 0
+	}
+
+	td.FeeAmount = int(fee)
+	td.ChangeAmount = int(change)
+	td.requiredUtxos = append([]*UTXO{}, td.availableUtxos...)
+	return nil
+}
+
+// sortRequiredUTXOs orders the selected utxos, and so the inputs built from them, per BIP69 when requested.
+func (td *TransactionData) sortRequiredUTXOs() {
+	if td.ordering != OrderingBIP69 {
+		return
+	}
+	sort.SliceStable(td.requiredUtxos, func(i, j int) bool {
+		a, b := td.requiredUtxos[i], td.requiredUtxos[j]
+		if a.Txid != b.Txid {
+			return a.Txid < b.Txid
+		}
+		return a.Index < b.Index
+	})
+}
+
+// sortOutputs orders outputs per BIP69 when requested, returning the new index of the output at changeIndex.
+func (td *TransactionData) sortOutputs(tx *wire.MsgTx, changeIndex int) int {
+	if td.ordering != OrderingBIP69 {
+		return changeIndex
+	}
+	var change *wire.TxOut
+	if changeIndex >= 0 {
+		change = tx.TxOut[changeIndex]
+	}
+	sort.SliceStable(tx.TxOut, func(i, j int) bool {
+		a, b := tx.TxOut[i], tx.TxOut[j]
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		return bytes.Compare(a.PkScript, b.PkScript) < 0
+	})
+	for i, out := range tx.TxOut {
+		if out == change {
+			return i
+		}
+	}
+	return changeIndex
+}
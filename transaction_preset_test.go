@@ -0,0 +1,123 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/btcsuite/btcutil/txsort"
+	"github.com/stretchr/testify/assert"
+)
+
+const presetTestAddress = "bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6"
+
+func newPresetTestData(amount int, feeRate int, utxos ...*UTXO) *TransactionDataStandard {
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataStandard(presetTestAddress, BaseCoinBip84MainNet, amount, feeRate, changePath, 590582, NewRBFOption(AllowedToBeRBF))
+	for _, utxo := range utxos {
+		data.AddUTXO(utxo)
+	}
+	return data
+}
+
+func newPresetTestUTXO(txid string, amount int64, isConfirmed bool) *UTXO {
+	return NewUTXO(txid, 0, amount, NewDerivationPath(BaseCoinBip84MainNet, 0, 1), nil, isConfirmed)
+}
+
+func TestTransactionPresetSettings(t *testing.T) {
+	privacy, err := TransactionPresetSettings(TransactionPresetPrivacy)
+	assert.Nil(t, err)
+	assert.Equal(t, &TransactionPreset{SelectionStrategySingleInputFirst, OrderingBIP69, AllowedToBeRBF, ChangePolicyAvoidSmall}, privacy)
+
+	_, err = TransactionPresetSettings(4)
+	assert.EqualError(t, err, "unrecognized transaction preset")
+
+	data := newPresetTestData(50000, 10)
+	assert.EqualError(t, data.ApplyPreset(-1), "unrecognized transaction preset")
+}
+
+func TestApplyPreset_Economical_SelectsLargestFirst(t *testing.T) {
+	small := newPresetTestUTXO("1111111111111111111111111111111111111111111111111111111111111111", 30000, true)
+	large := newPresetTestUTXO("2222222222222222222222222222222222222222222222222222222222222222", 90000, true)
+
+	data := newPresetTestData(50000, 10, small, large)
+	assert.Nil(t, data.ApplyPreset(TransactionPresetEconomical))
+	assert.Nil(t, data.Generate())
+	assert.Equal(t, 1, data.TransactionData.UtxoCount())
+	selected, err := data.TransactionData.RequiredUTXOAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, large, selected)
+	assert.Equal(t, MustBeRBF, data.TransactionData.RBFOption.Value)
+
+	// without a preset, utxos are used in the order added
+	data = newPresetTestData(50000, 10, small, large)
+	assert.Nil(t, data.Generate())
+	assert.Equal(t, 2, data.TransactionData.UtxoCount())
+}
+
+func TestApplyPreset_Fast_PrefersConfirmedUTXOs(t *testing.T) {
+	unconfirmed := newPresetTestUTXO("1111111111111111111111111111111111111111111111111111111111111111", 90000, false)
+	confirmed := newPresetTestUTXO("2222222222222222222222222222222222222222222222222222222222222222", 60000, true)
+
+	data := newPresetTestData(50000, 10, unconfirmed, confirmed)
+	assert.Nil(t, data.ApplyPreset(TransactionPresetFast))
+	assert.Nil(t, data.Generate())
+	assert.Equal(t, 1, data.TransactionData.UtxoCount())
+	selected, err := data.TransactionData.RequiredUTXOAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, confirmed, selected)
+	assert.Equal(t, MustNotBeRBF, data.TransactionData.RBFOption.Value)
+}
+
+func TestApplyPreset_Privacy_SelectsSmallestSingleInputAndAvoidsSmallChange(t *testing.T) {
+	utxos := []*UTXO{
+		newPresetTestUTXO("1111111111111111111111111111111111111111111111111111111111111111", 40000, true),
+		newPresetTestUTXO("2222222222222222222222222222222222222222222222222222222222222222", 200000, true),
+		newPresetTestUTXO("3333333333333333333333333333333333333333333333333333333333333333", 52900, true),
+	}
+
+	// 1800 sats left before change, which the standard policy keeps but costs 990 sats to create and spend
+	data := newPresetTestData(50000, 10, utxos...)
+	assert.Nil(t, data.ApplyPreset(TransactionPresetPrivacy))
+	assert.Nil(t, data.Generate())
+	assert.Equal(t, 1, data.TransactionData.UtxoCount())
+	selected, err := data.TransactionData.RequiredUTXOAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, utxos[2], selected)
+	assert.Equal(t, 0, data.TransactionData.ChangeAmount)
+	assert.Equal(t, 2900, data.TransactionData.FeeAmount)
+
+	data = newPresetTestData(50000, 10, utxos[2])
+	assert.Nil(t, data.Generate())
+	assert.Equal(t, 1490, data.TransactionData.ChangeAmount)
+}
+
+func TestApplyPreset_Consolidation_SpendsAllInBIP69Order(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	utxos := []*UTXO{
+		newPresetTestUTXO("3333333333333333333333333333333333333333333333333333333333333333", 40000, true),
+		newPresetTestUTXO("1111111111111111111111111111111111111111111111111111111111111111", 30000, true),
+		newPresetTestUTXO("2222222222222222222222222222222222222222222222222222222222222222", 20000, false),
+	}
+	for _, utxo := range utxos {
+		utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), utxo.Amount)
+	}
+
+	data := newPresetTestData(10000, 2, utxos...)
+	assert.Nil(t, data.ApplyPreset(TransactionPresetConsolidation))
+	assert.Nil(t, data.Generate())
+	td := data.TransactionData
+	assert.Equal(t, 3, td.UtxoCount())
+	assert.Equal(t, 2*(11+3*68+31+31), td.FeeAmount)
+	assert.Equal(t, 90000-10000-td.FeeAmount, td.ChangeAmount)
+
+	unsigned, err := wallet.ExportUnsignedPSBT(td)
+	assert.Nil(t, err)
+	packet, err := psbt.NewPsbt([]byte(unsigned), true)
+	assert.Nil(t, err)
+	assert.True(t, txsort.IsSorted(packet.UnsignedTx))
+
+	// change is larger than the payment, so it sorts last, and keeps its key origin
+	assert.Equal(t, int64(td.ChangeAmount), packet.UnsignedTx.TxOut[1].Value)
+	assert.Equal(t, 1, len(packet.Outputs[1].Bip32Derivation))
+	assert.Equal(t, 0, len(packet.Outputs[0].Bip32Derivation))
+}
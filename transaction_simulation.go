@@ -0,0 +1,137 @@
+package cnlib
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// sizes used for dummy signatures in dry-run mode. A DER encoded low-S ECDSA signature with its sighash byte is at
+// most 72 bytes, so simulated sizes may overestimate a real signature by a byte per input.
+const (
+	dummySignatureSize = 72
+	dummyPubKeySize    = 33
+)
+
+/// Type Definitions
+
+// TransactionSimulation is the result of a dry-run build of a transaction with dummy signatures.
+type TransactionSimulation struct {
+	Weight    int
+	Vsize     int
+	FeeAmount int
+	FeeRate   int // FeeAmount divided by Vsize, rounded down
+}
+
+/// Receiver functions
+
+// SimulateTransactionMetadata runs the full transaction build pipeline in dry-run mode, substituting correctly sized
+// dummy signatures for real ones, and returns the final size and fee. No private keys are used, so this also works
+// for wallets created from an account extended public key.
+func (wallet *HDWallet) SimulateTransactionMetadata(data *TransactionData) (*TransactionSimulation, error) {
+	if data == nil {
+		return nil, errors.New("transaction data cannot be nil")
+	}
+
+	builder := transactionBuilder{wallet: wallet, dryRun: true}
+	tx, _, err := builder.buildMsgTx(data)
+	if err != nil {
+		return nil, err
+	}
+
+	weight := int(blockchain.GetTransactionWeight(btcutil.NewTx(tx)))
+	vsize := (weight + blockchain.WitnessScaleFactor - 1) / blockchain.WitnessScaleFactor
+
+	return &TransactionSimulation{
+		Weight:    weight,
+		Vsize:     vsize,
+		FeeAmount: data.FeeAmount,
+		FeeRate:   data.FeeAmount / vsize,
+	}, nil
+}
+
+/// Unexported functions
+
+// addDummySignaturesForTx populates each input's signature script and witness with placeholder data matching the
+// size of a real signature for the input's previous output type.
+func (tb transactionBuilder) addDummySignaturesForTx(tx *wire.MsgTx, data *TransactionData) error {
+	dummySig := make([]byte, dummySignatureSize)
+	dummyPubKey := make([]byte, dummyPubKeySize)
+
+	for i, txIn := range tx.TxIn {
+		utxo, err := data.RequiredUTXOAtIndex(i)
+		if err != nil {
+			return err
+		}
+
+		if utxo.descriptor != nil {
+			if txIn.SignatureScript, txIn.Witness, err = utxo.descriptor.dummyInputScripts(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if utxo.Path != nil && utxo.Path.Purpose == bip86purpose {
+			txIn.Witness = wire.TxWitness{make([]byte, 64)}
+			continue
+		}
+
+		class, err := tb.prevScriptClassForUTXO(utxo)
+		if err != nil {
+			return err
+		}
+
+		switch class {
+		case txscript.PubKeyHashTy:
+			sigScript, err := txscript.NewScriptBuilder().AddData(dummySig).AddData(dummyPubKey).Script()
+			if err != nil {
+				return err
+			}
+			txIn.SignatureScript = sigScript
+		case txscript.ScriptHashTy:
+			redeemScript, err := P2WPKHScript(make([]byte, 20))
+			if err != nil {
+				return err
+			}
+			sigScript, err := txscript.NewScriptBuilder().AddData(redeemScript).Script()
+			if err != nil {
+				return err
+			}
+			txIn.SignatureScript = sigScript
+			txIn.Witness = wire.TxWitness{dummySig, dummyPubKey}
+		case txscript.WitnessV0PubKeyHashTy:
+			txIn.Witness = wire.TxWitness{dummySig, dummyPubKey}
+		default:
+			return errors.New("unsupported previous output script type")
+		}
+	}
+	return nil
+}
+
+// prevScriptClassForUTXO determines the script type of a utxo's previous output without deriving private keys.
+func (tb transactionBuilder) prevScriptClassForUTXO(utxo *UTXO) (txscript.ScriptClass, error) {
+	if utxo.Path != nil {
+		switch utxo.Path.Purpose {
+		case bip84purpose:
+			return txscript.WitnessV0PubKeyHashTy, nil
+		case bip49purpose:
+			return txscript.ScriptHashTy, nil
+		case bip44purpose:
+			return txscript.PubKeyHashTy, nil
+		}
+		return txscript.NonStandardTy, ErrInvalidPurposeValue
+	}
+
+	if utxo.ImportedPrivateKey != nil && utxo.ImportedPrivateKey.SelectedAddress != "" {
+		pkScript, err := tb.pkScriptForAddress(utxo.ImportedPrivateKey.SelectedAddress)
+		if err != nil {
+			return txscript.NonStandardTy, err
+		}
+		return txscript.GetScriptClass(pkScript), nil
+	}
+
+	return txscript.NonStandardTy, errors.New("no source address available to sign input")
+}
@@ -0,0 +1,55 @@
+package cnlib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulateTransactionMetadata_MatchesSignedSize(t *testing.T) {
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	simulation, err := wallet.SimulateTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+
+	meta, err := wallet.BuildTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	txBytes, _ := hex.DecodeString(meta.EncodedTx)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	assert.Nil(t, tx.Deserialize(bytes.NewReader(txBytes)))
+	signedWeight := int(blockchain.GetTransactionWeight(btcutil.NewTx(tx)))
+
+	// dummy signatures are sized for the worst case, at most one byte larger per input
+	assert.True(t, simulation.Weight >= signedWeight)
+	assert.True(t, simulation.Weight-signedWeight <= 1)
+	assert.Equal(t, 846, simulation.FeeAmount)
+	assert.Equal(t, 846/simulation.Vsize, simulation.FeeRate)
+}
+
+func TestSimulateTransactionMetadata_WatchOnlyWallet(t *testing.T) {
+	wallet, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	simulation, err := wallet.SimulateTransactionMetadata(data.TransactionData)
+	assert.Nil(t, err)
+	assert.Equal(t, 141, simulation.Vsize)
+}
@@ -0,0 +1,570 @@
+package cnlib
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+// Following constants are the UR types supported for exchange with hardware wallets.
+const (
+	URTypeBytes         = "bytes"
+	URTypeCryptoPSBT    = "crypto-psbt"
+	URTypeCryptoAccount = "crypto-account"
+	URTypeCryptoHDKey   = "crypto-hdkey"
+)
+
+// CBOR tags from the BCR-2020-006 registry.
+const (
+	cborTagCryptoHDKey    = 303
+	cborTagCryptoKeypath  = 304
+	cborTagCryptoCoinInfo = 305
+	cborTagScriptHash     = 400
+	cborTagPubkeyHash     = 403
+	cborTagWitnessPubkey  = 404
+	cborTagTaproot        = 409
+)
+
+/// Type Definitions
+
+// UREncoder encodes a payload as a BC-UR (BCR-2020-005), such as "ur:crypto-psbt/...", as read by hardware wallets
+// like Keystone and Passport. Payloads too large for a single QR code are split into a multipart UR, whose parts are
+// shown in turn as an animated QR code.
+type UREncoder struct {
+	urType   string
+	message  []byte
+	fountain *fountainEncoder
+}
+
+// URDecoder reassembles a UR from scanned parts, received in any order.
+type URDecoder struct {
+	urType   string
+	fountain *fountainDecoder
+	message  []byte
+}
+
+// URAccount is a crypto-account UR: the account extended public keys a hardware wallet exports for one seed.
+type URAccount struct {
+	MasterFingerprint string
+	keys              []*URAccountKey
+}
+
+// URAccountKey is an account-level extended public key from a crypto-account or crypto-hdkey UR, encoded with the
+// version prefix matching its purpose, e.g. zpub for m/84'/0'/0', for use with
+// `NewHDWalletFromAccountExtendedPublicKey`.
+type URAccountKey struct {
+	ExtendedPublicKey string
+	MasterFingerprint string // empty if the UR did not include the key's origin fingerprint
+	Purpose           int
+	Coin              int
+	Account           int
+}
+
+/// Constructors
+
+// NewPSBTUREncoder returns an encoder for a base64 encoded PSBT as a crypto-psbt UR, splitting it into parts of at
+// most maxFragmentLength bytes.
+func NewPSBTUREncoder(encodedPSBT string, maxFragmentLength int) (*UREncoder, error) {
+	raw, err := base64.StdEncoding.DecodeString(encodedPSBT)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := psbt.NewPsbt(raw, false); err != nil {
+		return nil, err
+	}
+
+	message, err := cborEncode(raw)
+	if err != nil {
+		return nil, err
+	}
+	return newUREncoder(URTypeCryptoPSBT, message, maxFragmentLength)
+}
+
+// NewBytesUREncoder returns an encoder for an arbitrary payload too large for one QR code, such as a signed
+// transaction or a memo, as a bytes UR, splitting it into parts of at most maxFragmentLength bytes. Prefer
+// `NewPSBTUREncoder` for PSBTs, which hardware wallets recognize by type.
+func NewBytesUREncoder(data []byte, maxFragmentLength int) (*UREncoder, error) {
+	if len(data) == 0 {
+		return nil, errors.New("payload cannot be empty")
+	}
+	message, err := cborEncode(data)
+	if err != nil {
+		return nil, err
+	}
+	return newUREncoder(URTypeBytes, message, maxFragmentLength)
+}
+
+// NewURDecoder instantiates an empty decoder.
+func NewURDecoder() *URDecoder {
+	return &URDecoder{}
+}
+
+func newUREncoder(urType string, message []byte, maxFragmentLength int) (*UREncoder, error) {
+	encoder := &UREncoder{urType: urType, message: message}
+	if len(message) > maxFragmentLength {
+		fountain, err := newFountainEncoder(message, maxFragmentLength)
+		if err != nil {
+			return nil, err
+		}
+		encoder.fountain = fountain
+	}
+	return encoder, nil
+}
+
+/// Receiver functions
+
+// IsSinglePart returns true if the payload fits in one part.
+func (e *UREncoder) IsSinglePart() bool {
+	return e.fountain == nil
+}
+
+// PartCount returns the number of fragments the payload was split into. Displaying that many parts is enough if none
+// are missed; `NextPart` continues with parts mixing several fragments, so keep cycling until the scanner finishes.
+func (e *UREncoder) PartCount() int {
+	if e.fountain == nil {
+		return 1
+	}
+	return len(e.fountain.fragments)
+}
+
+// NextPart returns the next part to display. A single part UR always returns the same string.
+func (e *UREncoder) NextPart() (string, error) {
+	if e.fountain == nil {
+		return fmt.Sprintf("ur:%s/%s", e.urType, encodeMinimalBytewords(e.message)), nil
+	}
+	part := e.fountain.nextPart()
+	encoded, err := part.cbor()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ur:%s/%d-%d/%s", e.urType, part.seqNum, part.seqLength, encodeMinimalBytewords(encoded)), nil
+}
+
+// ReceivePart adds a scanned part, in upper or lower case. Returns error if the part is malformed, or belongs to a
+// different UR than the parts already received.
+func (d *URDecoder) ReceivePart(part string) error {
+	urType, sequence, body, err := splitURPart(part)
+	if err != nil {
+		return err
+	}
+	if d.urType != "" && urType != d.urType {
+		return errors.New("part belongs to a different ur")
+	}
+	if d.IsComplete() {
+		return nil
+	}
+
+	data, err := decodeMinimalBytewords(body)
+	if err != nil {
+		return err
+	}
+
+	if sequence == "" {
+		if d.fountain != nil {
+			return errors.New("part belongs to a different ur")
+		}
+		d.urType = urType
+		d.message = data
+		return nil
+	}
+
+	fountainPart, err := parseFountainPart(data)
+	if err != nil {
+		return err
+	}
+	if sequence != fmt.Sprintf("%d-%d", fountainPart.seqNum, fountainPart.seqLength) {
+		return errors.New("ur sequence does not match part")
+	}
+	if d.fountain == nil {
+		d.fountain = newFountainDecoder()
+	}
+	if err := d.fountain.receive(fountainPart); err != nil {
+		return err
+	}
+	d.urType = urType
+	if d.fountain.isComplete() {
+		if d.fountain.err != nil {
+			return d.fountain.err
+		}
+		d.message = d.fountain.message
+	}
+	return nil
+}
+
+// IsComplete returns true once the UR has been fully received.
+func (d *URDecoder) IsComplete() bool {
+	return d.message != nil
+}
+
+// Progress returns the fraction of fragments recovered, between 0 and 1.
+func (d *URDecoder) Progress() float64 {
+	if d.IsComplete() {
+		return 1
+	}
+	if d.fountain == nil {
+		return 0
+	}
+	return d.fountain.progress()
+}
+
+// URType returns the type of the UR being received, such as "crypto-psbt", or an empty string before the first part.
+func (d *URDecoder) URType() string {
+	return d.urType
+}
+
+// ResultBytes returns the payload of a received bytes UR.
+func (d *URDecoder) ResultBytes() ([]byte, error) {
+	value, err := d.result(URTypeBytes)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := value.([]byte)
+	if !ok {
+		return nil, errors.New("invalid bytes ur")
+	}
+	return data, nil
+}
+
+// ResultPSBT returns a received crypto-psbt UR as a base64 encoded PSBT.
+func (d *URDecoder) ResultPSBT() (string, error) {
+	value, err := d.result(URTypeCryptoPSBT)
+	if err != nil {
+		return "", err
+	}
+	raw, ok := value.([]byte)
+	if !ok {
+		return "", errors.New("invalid crypto-psbt")
+	}
+	if _, err := psbt.NewPsbt(raw, false); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// ResultAccount returns a received crypto-account UR. Only single-key descriptors for BIP44, BIP49, BIP84 and BIP86
+// accounts are included; others, such as multisig, are skipped.
+func (d *URDecoder) ResultAccount() (*URAccount, error) {
+	value, err := d.result(URTypeCryptoAccount)
+	if err != nil {
+		return nil, err
+	}
+	entries, ok := value.(map[uint64]interface{})
+	if !ok {
+		return nil, errors.New("invalid crypto-account")
+	}
+	fingerprint, ok := entries[1].(uint64)
+	if !ok || fingerprint > 0xffffffff {
+		return nil, errors.New("invalid crypto-account master fingerprint")
+	}
+	descriptors, ok := entries[2].([]interface{})
+	if !ok {
+		return nil, errors.New("invalid crypto-account descriptors")
+	}
+
+	account := &URAccount{MasterFingerprint: fmt.Sprintf("%08x", fingerprint)}
+	for _, descriptor := range descriptors {
+		hdkey, purpose, ok := unwrapOutputDescriptor(descriptor)
+		if !ok {
+			continue
+		}
+		key, err := parseCryptoHDKey(hdkey)
+		if err != nil {
+			return nil, err
+		}
+		if key.Purpose != purpose {
+			return nil, errors.New("descriptor script does not match key origin")
+		}
+		if key.MasterFingerprint == "" {
+			key.MasterFingerprint = account.MasterFingerprint
+		}
+		account.keys = append(account.keys, key)
+	}
+	return account, nil
+}
+
+// ResultHDKey returns the account extended public key from a received crypto-hdkey UR.
+func (d *URDecoder) ResultHDKey() (*URAccountKey, error) {
+	value, err := d.result(URTypeCryptoHDKey)
+	if err != nil {
+		return nil, err
+	}
+	return parseCryptoHDKey(value)
+}
+
+// KeyCount returns the number of account keys.
+func (a *URAccount) KeyCount() int {
+	return len(a.keys)
+}
+
+// KeyAtIndex returns the account key at a given index.
+func (a *URAccount) KeyAtIndex(index int) (*URAccountKey, error) {
+	if index < 0 || index >= len(a.keys) {
+		return nil, errors.New("index must be within range of keys")
+	}
+	return a.keys[index], nil
+}
+
+// CryptoAccountUR returns the wallet's account extended public keys as a crypto-account UR, for pairing with
+// software expecting a hardware wallet export. Wallets with words include BIP44, BIP49 and BIP84 accounts for the
+// wallet's coin and account; watch-only wallets include only their own account. Returns ErrMasterFingerprintUnknown
+// if a watch-only wallet's fingerprint was never set.
+func (wallet *HDWallet) CryptoAccountUR() (string, error) {
+	fingerprint, err := wallet.masterFingerprintBytes()
+	if err != nil {
+		return "", err
+	}
+
+	coins := []*BaseCoin{wallet.BaseCoin}
+	if wallet.masterPrivateKey != nil {
+		coins = []*BaseCoin{
+			wallet.BaseCoin.withPurpose(bip44purpose),
+			wallet.BaseCoin.withPurpose(bip49purpose),
+			wallet.BaseCoin.withPurpose(bip84purpose),
+		}
+	}
+
+	descriptors := make([]interface{}, 0, len(coins))
+	for _, bc := range coins {
+		hdkey, err := wallet.cryptoHDKey(bc, fingerprint)
+		if err != nil {
+			return "", err
+		}
+		descriptors = append(descriptors, wrapOutputDescriptor(cborTag{tag: cborTagCryptoHDKey, value: hdkey}, bc.Purpose))
+	}
+
+	message, err := cborEncode(map[uint64]interface{}{
+		1: binary.BigEndian.Uint32(fingerprint),
+		2: descriptors,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ur:%s/%s", URTypeCryptoAccount, encodeMinimalBytewords(message)), nil
+}
+
+// CryptoHDKeyUR returns the wallet's account extended public key as a crypto-hdkey UR. The key origin includes the
+// master fingerprint when it is known.
+func (wallet *HDWallet) CryptoHDKeyUR() (string, error) {
+	fingerprint, err := wallet.masterFingerprintBytes()
+	if err != nil && err != ErrMasterFingerprintUnknown {
+		return "", err
+	}
+
+	hdkey, err := wallet.cryptoHDKey(wallet.BaseCoin, fingerprint)
+	if err != nil {
+		return "", err
+	}
+	message, err := cborEncode(hdkey)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ur:%s/%s", URTypeCryptoHDKey, encodeMinimalBytewords(message)), nil
+}
+
+/// Unexported functions
+
+func (d *URDecoder) result(urType string) (interface{}, error) {
+	if !d.IsComplete() {
+		return nil, errors.New("ur is incomplete")
+	}
+	if d.urType != urType {
+		return nil, fmt.Errorf("expected %s ur, received %s", urType, d.urType)
+	}
+	return cborDecode(d.message)
+}
+
+// cryptoHDKey returns the BCR-2020-007 map for the account extended public key of a BaseCoin. fingerprint may be nil.
+func (wallet *HDWallet) cryptoHDKey(bc *BaseCoin, fingerprint []byte) (map[uint64]interface{}, error) {
+	kf := wallet.keyFactory()
+	key, _, err := kf.accountExtendedPublicKey(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	// version (4), depth (1), parent fingerprint (4), child number (4), chain code (32), public key (33)
+	decoded, _, err := base58.CheckDecode(key.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) != 77 {
+		return nil, errors.New("invalid extended public key length")
+	}
+
+	origin := map[uint64]interface{}{
+		1: []interface{}{bc.Purpose, true, bc.Coin, true, bc.Account, true},
+		3: int(decoded[3]),
+	}
+	if fingerprint != nil {
+		origin[2] = binary.BigEndian.Uint32(fingerprint)
+	}
+	return map[uint64]interface{}{
+		3: decoded[44:77],
+		4: decoded[12:44],
+		5: cborTag{tag: cborTagCryptoCoinInfo, value: map[uint64]interface{}{1: 0, 2: bc.Coin}},
+		6: cborTag{tag: cborTagCryptoKeypath, value: origin},
+		8: binary.BigEndian.Uint32(decoded[4:8]),
+	}, nil
+}
+
+// parseCryptoHDKey parses a BCR-2020-007 map, optionally tagged, for an account-level public key.
+func parseCryptoHDKey(value interface{}) (*URAccountKey, error) {
+	if tagged, ok := value.(cborTag); ok {
+		if tagged.tag != cborTagCryptoHDKey {
+			return nil, errors.New("invalid crypto-hdkey tag")
+		}
+		value = tagged.value
+	}
+	entries, ok := value.(map[uint64]interface{})
+	if !ok {
+		return nil, errors.New("invalid crypto-hdkey")
+	}
+	if isPrivate, _ := entries[2].(bool); isPrivate {
+		return nil, errors.New("private crypto-hdkey not supported")
+	}
+	keyData, ok := entries[3].([]byte)
+	if !ok || len(keyData) != 33 {
+		return nil, errors.New("invalid crypto-hdkey key data")
+	}
+	if _, err := parseHexPubKey(hex.EncodeToString(keyData)); err != nil {
+		return nil, err
+	}
+	chainCode, ok := entries[4].([]byte)
+	if !ok || len(chainCode) != 32 {
+		return nil, errors.New("crypto-hdkey chain code required")
+	}
+
+	taggedOrigin, ok := entries[6].(cborTag)
+	if !ok || taggedOrigin.tag != cborTagCryptoKeypath {
+		return nil, errors.New("crypto-hdkey origin required")
+	}
+	origin, ok := taggedOrigin.value.(map[uint64]interface{})
+	if !ok {
+		return nil, errors.New("invalid crypto-hdkey origin")
+	}
+	path, err := parseCryptoKeypathComponents(origin[1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := &URAccountKey{Purpose: int(path[0]), Coin: int(path[1]), Account: int(path[2])}
+	if sourceFingerprint, ok := origin[2].(uint64); ok && sourceFingerprint <= 0xffffffff {
+		key.MasterFingerprint = fmt.Sprintf("%08x", sourceFingerprint)
+	}
+
+	network, ok := networkRegistry[key.Coin]
+	if !ok {
+		return nil, ErrInvalidCoinValue
+	}
+	parentFingerprint := make([]byte, 4)
+	if parent, ok := entries[8].(uint64); ok && parent <= 0xffffffff {
+		binary.BigEndian.PutUint32(parentFingerprint, uint32(parent))
+	}
+	extendedKey := hdkeychain.NewExtendedKey(network.chainParams.HDPublicKeyID[:], keyData, chainCode, parentFingerprint,
+		3, hardened(key.Account), false)
+
+	bc := NewBaseCoin(key.Purpose, key.Coin, key.Account)
+	if _, err := bc.defaultExtendedPubkeyType(); err != nil {
+		key.ExtendedPublicKey = extendedKey.String()
+	} else if key.ExtendedPublicKey, err = encodeExtendedPubkey(extendedKey, bc); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// parseCryptoKeypathComponents returns the indexes of a fully hardened three level account path.
+func parseCryptoKeypathComponents(value interface{}) ([]uint32, error) {
+	components, ok := value.([]interface{})
+	if !ok || len(components) != 6 {
+		return nil, errors.New("crypto-hdkey must be an account-level key")
+	}
+	path := make([]uint32, 0, 3)
+	for i := 0; i < len(components); i += 2 {
+		index, ok := components[i].(uint64)
+		isHardened, _ := components[i+1].(bool)
+		if !ok || !isHardened || index >= hdkeychain.HardenedKeyStart {
+			return nil, errors.New("crypto-hdkey must be an account-level key")
+		}
+		path = append(path, uint32(index))
+	}
+	return path, nil
+}
+
+func wrapOutputDescriptor(hdkey cborTag, purpose int) cborTag {
+	switch purpose {
+	case bip44purpose:
+		return cborTag{tag: cborTagPubkeyHash, value: hdkey}
+	case bip49purpose:
+		return cborTag{tag: cborTagScriptHash, value: cborTag{tag: cborTagWitnessPubkey, value: hdkey}}
+	case bip86purpose:
+		return cborTag{tag: cborTagTaproot, value: hdkey}
+	default:
+		return cborTag{tag: cborTagWitnessPubkey, value: hdkey}
+	}
+}
+
+// unwrapOutputDescriptor returns the key of a single-key output descriptor and the purpose matching its script, or
+// false for unsupported scripts.
+func unwrapOutputDescriptor(value interface{}) (interface{}, int, bool) {
+	outer, ok := value.(cborTag)
+	if !ok {
+		return nil, 0, false
+	}
+	switch outer.tag {
+	case cborTagPubkeyHash:
+		return outer.value, bip44purpose, true
+	case cborTagWitnessPubkey:
+		return outer.value, bip84purpose, true
+	case cborTagTaproot:
+		return outer.value, bip86purpose, true
+	case cborTagScriptHash:
+		if inner, ok := outer.value.(cborTag); ok && inner.tag == cborTagWitnessPubkey {
+			return inner.value, bip49purpose, true
+		}
+	}
+	return nil, 0, false
+}
+
+// splitURPart splits "ur:<type>/[<seq>-<count>/]<bytewords>" into its components.
+func splitURPart(part string) (string, string, string, error) {
+	part = strings.ToLower(strings.TrimSpace(part))
+	if !strings.HasPrefix(part, "ur:") {
+		return "", "", "", errors.New("not a ur")
+	}
+	components := strings.Split(part[len("ur:"):], "/")
+	switch len(components) {
+	case 2:
+		return components[0], "", components[1], validateURType(components[0])
+	case 3:
+		sequence := strings.Split(components[1], "-")
+		if len(sequence) != 2 {
+			return "", "", "", errors.New("invalid ur sequence")
+		}
+		for _, n := range sequence {
+			if _, err := strconv.ParseUint(n, 10, 32); err != nil {
+				return "", "", "", errors.New("invalid ur sequence")
+			}
+		}
+		return components[0], components[1], components[2], validateURType(components[0])
+	}
+	return "", "", "", errors.New("invalid ur")
+}
+
+func validateURType(urType string) error {
+	if urType == "" {
+		return errors.New("invalid ur type")
+	}
+	for _, c := range urType {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return errors.New("invalid ur type")
+		}
+	}
+	return nil
+}
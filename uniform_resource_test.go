@@ -0,0 +1,173 @@
+package cnlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testUnsignedPSBT(t *testing.T) string {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 1)
+	utxo := NewUTXO("a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69", 0, 96537, path, nil, true)
+	utxo.SetPrevout(testPrevoutScript("00149c90f934ea51fa0f6504177043e0908da6929983"), 96537)
+	changePath := NewDerivationPath(BaseCoinBip84MainNet, 1, 1)
+	data := NewTransactionDataFlatFee("bc1qjv79zewlvyyyd5y0qfk3svexzrqnammllj7mw6", BaseCoinBip84MainNet, 9755, 846, changePath, 590582)
+	data.AddUTXO(utxo)
+	assert.Nil(t, data.Generate())
+
+	unsigned, err := wallet.ExportUnsignedPSBT(data.TransactionData)
+	assert.Nil(t, err)
+	return unsigned
+}
+
+func TestBytewords_MatchesBCRVector(t *testing.T) {
+	assert.Equal(t, "aeadaolazmjendeoti", encodeMinimalBytewords([]byte{0, 1, 2, 128, 255}))
+
+	decoded, err := decodeMinimalBytewords("AEADAOLAZMJENDEOTI")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0, 1, 2, 128, 255}, decoded)
+
+	_, err = decodeMinimalBytewords("aeadaolazmjendeota")
+	assert.EqualError(t, err, "invalid bytewords checksum")
+}
+
+func TestXoshiro256_MatchesBCRVector(t *testing.T) {
+	seed := sha256.Sum256([]byte("Wolf"))
+	rng := newXoshiro256(seed[:])
+	expected := []uint64{42, 81, 85, 8, 82, 84, 76, 73, 70, 88}
+	for _, n := range expected {
+		assert.Equal(t, n, rng.next()%100)
+	}
+}
+
+func TestPSBTUR_SinglePart_RoundTrips(t *testing.T) {
+	unsigned := testUnsignedPSBT(t)
+	encoder, err := NewPSBTUREncoder(unsigned, 1000)
+	assert.Nil(t, err)
+	assert.True(t, encoder.IsSinglePart())
+
+	part, err := encoder.NextPart()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(part, "ur:crypto-psbt/"))
+
+	decoder := NewURDecoder()
+	assert.Nil(t, decoder.ReceivePart(strings.ToUpper(part)))
+	assert.True(t, decoder.IsComplete())
+	result, err := decoder.ResultPSBT()
+	assert.Nil(t, err)
+	assert.Equal(t, unsigned, result)
+}
+
+func TestPSBTUR_Multipart_RecoversFromMissedParts(t *testing.T) {
+	unsigned := testUnsignedPSBT(t)
+	encoder, err := NewPSBTUREncoder(unsigned, 30)
+	assert.Nil(t, err)
+	assert.False(t, encoder.IsSinglePart())
+	count := encoder.PartCount()
+	assert.True(t, count > 3)
+
+	decoder := NewURDecoder()
+	for i := 0; i < 10*count && !decoder.IsComplete(); i++ {
+		part, err := encoder.NextPart()
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(part, "ur:crypto-psbt/"))
+		if i%3 == 1 {
+			continue // missed by the scanner
+		}
+		assert.Nil(t, decoder.ReceivePart(part))
+	}
+
+	assert.True(t, decoder.IsComplete())
+	assert.Equal(t, 1.0, decoder.Progress())
+	assert.Equal(t, URTypeCryptoPSBT, decoder.URType())
+	result, err := decoder.ResultPSBT()
+	assert.Nil(t, err)
+	assert.Equal(t, unsigned, result)
+
+	_, err = decoder.ResultAccount()
+	assert.EqualError(t, err, "expected crypto-account ur, received crypto-psbt")
+}
+
+func TestBytesUR_Multipart_RoundTrips(t *testing.T) {
+	data := bytes.Repeat([]byte("cnlib animated qr payload "), 20)
+	encoder, err := NewBytesUREncoder(data, 50)
+	assert.Nil(t, err)
+	assert.False(t, encoder.IsSinglePart())
+
+	decoder := NewURDecoder()
+	for i := 0; i < 10*encoder.PartCount() && !decoder.IsComplete(); i++ {
+		part, err := encoder.NextPart()
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(part, "ur:bytes/"))
+		assert.Nil(t, decoder.ReceivePart(part))
+	}
+	assert.Equal(t, URTypeBytes, decoder.URType())
+	result, err := decoder.ResultBytes()
+	assert.Nil(t, err)
+	assert.Equal(t, data, result)
+
+	_, err = decoder.ResultPSBT()
+	assert.EqualError(t, err, "expected crypto-psbt ur, received bytes")
+	_, err = NewBytesUREncoder(nil, 50)
+	assert.NotNil(t, err)
+}
+
+func TestCryptoAccountUR_RoundTripsAccountKeys(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	encoded, err := wallet.CryptoAccountUR()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "ur:crypto-account/"))
+
+	decoder := NewURDecoder()
+	assert.Nil(t, decoder.ReceivePart(encoded))
+	account, err := decoder.ResultAccount()
+	assert.Nil(t, err)
+	assert.Equal(t, "73c5da0a", account.MasterFingerprint)
+	assert.Equal(t, 3, account.KeyCount())
+
+	key, err := account.KeyAtIndex(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 84, key.Purpose)
+	assert.Equal(t, 0, key.Coin)
+	assert.Equal(t, "73c5da0a", key.MasterFingerprint)
+	assert.Equal(t, "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs", key.ExtendedPublicKey)
+
+	bip49, err := account.KeyAtIndex(1)
+	assert.Nil(t, err)
+	expected49, err := NewHDWalletFromWords(w, BaseCoinBip49MainNet).AccountExtendedMasterPublicKey()
+	assert.Nil(t, err)
+	assert.Equal(t, expected49, bip49.ExtendedPublicKey)
+
+	_, err = account.KeyAtIndex(3)
+	assert.EqualError(t, err, "index must be within range of keys")
+}
+
+func TestCryptoHDKeyUR_WatchOnlyWallet_RoundTrips(t *testing.T) {
+	zpub := "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	wallet, err := NewHDWalletFromAccountExtendedPublicKey(zpub)
+	assert.Nil(t, err)
+
+	_, err = wallet.CryptoAccountUR()
+	assert.Equal(t, ErrMasterFingerprintUnknown, err)
+
+	encoded, err := wallet.CryptoHDKeyUR()
+	assert.Nil(t, err)
+	decoder := NewURDecoder()
+	assert.Nil(t, decoder.ReceivePart(encoded))
+	key, err := decoder.ResultHDKey()
+	assert.Nil(t, err)
+	assert.Equal(t, zpub, key.ExtendedPublicKey)
+	assert.Equal(t, "", key.MasterFingerprint)
+}
+
+func TestURDecoder_MalformedPart_ReturnsError(t *testing.T) {
+	decoder := NewURDecoder()
+	assert.EqualError(t, decoder.ReceivePart("bitcoin:bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"), "not a ur")
+	assert.EqualError(t, decoder.ReceivePart("ur:crypto-psbt/aeadaolazmjendeota"), "invalid bytewords checksum")
+	assert.EqualError(t, decoder.ReceivePart("ur:crypto-psbt/1-x/aeadaolazmjendeoti"), "invalid ur sequence")
+	assert.False(t, decoder.IsComplete())
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 
 	"github.com/btcsuite/btcd/btcec"
-	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcutil"
 )
 
@@ -22,7 +21,7 @@ type usableAddress struct {
 
 // newUsableAddressWithDerivationPath accepts a wallet and derivation path, and returns a pointer to a UsableAddress.
 func newUsableAddressWithDerivationPath(wallet *HDWallet, derivationPath *DerivationPath) (*usableAddress, error) {
-	kf := keyFactory{masterPrivateKey: wallet.masterPrivateKey}
+	kf := wallet.keyFactory()
 
 	indexKey, err := kf.indexPrivateKey(derivationPath)
 	if err != nil {
@@ -74,7 +73,7 @@ func (ua *usableAddress) MetaAddress() (*MetaAddress, error) {
 
 // BIP49AddressFromPubkeyHash returns a P2SH-P2WPKH address from a pubkey's Hash160.
 func bip49AddressFromPubkeyHash(hash []byte, basecoin *BaseCoin) (string, error) {
-	scriptSig, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(hash).Script()
+	scriptSig, err := P2WPKHScript(hash)
 	if err != nil {
 		return "", err
 	}
@@ -101,10 +100,24 @@ func generateAddress(path *DerivationPath, pubkey *btcec.PublicKey) (string, err
 		return buildSegwitAddress(path, pubkey)
 	} else if purpose == bip49purpose {
 		return buildBIP49Address(path, pubkey)
+	} else if purpose == bip86purpose {
+		return buildTaprootAddress(path, pubkey)
+	} else if purpose == bip44purpose {
+		return buildBIP44Address(path, pubkey)
 	}
 	return "", errors.New("Unrecognized Address Purpose")
 }
 
+// buildBIP44Address returns the legacy P2PKH address of a compressed public key, for watching BIP44 accounts.
+func buildBIP44Address(path *DerivationPath, pubkey *btcec.PublicKey) (string, error) {
+	keyHash := btcutil.Hash160(pubkey.SerializeCompressed())
+	addrHash, err := btcutil.NewAddressPubKeyHash(keyHash, path.BaseCoin.defaultNetParams())
+	if err != nil {
+		return "", err
+	}
+	return addrHash.EncodeAddress(), nil
+}
+
 func buildBIP49Address(path *DerivationPath, pubkey *btcec.PublicKey) (string, error) {
 	pubkeyBytes := pubkey.SerializeCompressed()
 	keyHash := btcutil.Hash160(pubkeyBytes)
@@ -0,0 +1,139 @@
+package cnlib
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const usedIndexTrackerStateVersion = 1
+
+/// Type Definition
+
+// UsedIndexTracker records the highest used receive and change indices of a wallet, to provide the next unused
+// addresses. A value of -1 means no index on that chain has been used.
+type UsedIndexTracker struct {
+	wallet                  *HDWallet
+	HighestUsedReceiveIndex int
+	HighestUsedChangeIndex  int
+}
+
+// usedIndexTrackerState is the serialized form of a UsedIndexTracker.
+type usedIndexTrackerState struct {
+	Version int `json:"version"`
+	Purpose int `json:"purpose"`
+	Coin    int `json:"coin"`
+	Account int `json:"account"`
+	Receive int `json:"receive"`
+	Change  int `json:"change"`
+}
+
+/// Receiver functions
+
+// UsedIndexTracker returns the wallet's tracker of used address indices, creating an empty one if needed.
+func (wallet *HDWallet) UsedIndexTracker() *UsedIndexTracker {
+	if wallet.indexTracker == nil {
+		wallet.indexTracker = &UsedIndexTracker{wallet: wallet, HighestUsedReceiveIndex: -1, HighestUsedChangeIndex: -1}
+	}
+	return wallet.indexTracker
+}
+
+// RestoreUsedIndexTracker replaces the wallet's tracker with one restored from a string returned by `SerializedState`.
+// Returns error if the state was serialized for a different BaseCoin.
+func (wallet *HDWallet) RestoreUsedIndexTracker(serializedState string) error {
+	var state usedIndexTrackerState
+	if err := json.Unmarshal([]byte(serializedState), &state); err != nil {
+		return err
+	}
+	if state.Version != usedIndexTrackerStateVersion {
+		return errors.New("unsupported tracker state version")
+	}
+	bc := wallet.BaseCoin
+	if state.Purpose != bc.Purpose || state.Coin != bc.Coin || state.Account != bc.Account {
+		return errors.New("tracker state does not match wallet basecoin")
+	}
+	if state.Receive < -1 || state.Change < -1 {
+		return errors.New("tracker state index cannot be less than -1")
+	}
+
+	wallet.indexTracker = &UsedIndexTracker{
+		wallet:                  wallet,
+		HighestUsedReceiveIndex: state.Receive,
+		HighestUsedChangeIndex:  state.Change,
+	}
+	return nil
+}
+
+// MarkReceiveIndexUsed records a receive index as used. Indices at or below the current watermark are ignored.
+func (t *UsedIndexTracker) MarkReceiveIndexUsed(index int) error {
+	if index < 0 {
+		return errors.New("index cannot be negative")
+	}
+	if index <= t.HighestUsedReceiveIndex {
+		return nil
+	}
+	t.HighestUsedReceiveIndex = index
+	return t.wallet.notifyAddressUsed(0, index)
+}
+
+// MarkChangeIndexUsed records a change index as used. Indices at or below the current watermark are ignored.
+func (t *UsedIndexTracker) MarkChangeIndexUsed(index int) error {
+	if index < 0 {
+		return errors.New("index cannot be negative")
+	}
+	if index <= t.HighestUsedChangeIndex {
+		return nil
+	}
+	t.HighestUsedChangeIndex = index
+	return t.wallet.notifyAddressUsed(1, index)
+}
+
+// MarkAddressUsed scans the wallet up to a given index for an address and records its index as used on its chain.
+func (t *UsedIndexTracker) MarkAddressUsed(address string, upTo int) error {
+	meta, err := t.wallet.CheckForAddress(address, upTo)
+	if err != nil {
+		return err
+	}
+	if meta.IsReceiveAddress() {
+		return t.MarkReceiveIndexUsed(meta.DerivationPath.Index)
+	}
+	return t.MarkChangeIndexUsed(meta.DerivationPath.Index)
+}
+
+// NextReceiveIndex returns the first receive index above the highest used one.
+func (t *UsedIndexTracker) NextReceiveIndex() int {
+	return t.HighestUsedReceiveIndex + 1
+}
+
+// NextChangeIndex returns the first change index above the highest used one.
+func (t *UsedIndexTracker) NextChangeIndex() int {
+	return t.HighestUsedChangeIndex + 1
+}
+
+// NextReceiveAddress returns the receive MetaAddress at the first unused index.
+func (t *UsedIndexTracker) NextReceiveAddress() (*MetaAddress, error) {
+	return t.wallet.ReceiveAddressForIndex(t.NextReceiveIndex())
+}
+
+// NextChangeAddress returns the change MetaAddress at the first unused index.
+func (t *UsedIndexTracker) NextChangeAddress() (*MetaAddress, error) {
+	return t.wallet.ChangeAddressForIndex(t.NextChangeIndex())
+}
+
+// SerializedState returns the tracker's state as a JSON string, suitable for persisting and passing to
+// `RestoreUsedIndexTracker`.
+func (t *UsedIndexTracker) SerializedState() (string, error) {
+	bc := t.wallet.BaseCoin
+	state := usedIndexTrackerState{
+		Version: usedIndexTrackerStateVersion,
+		Purpose: bc.Purpose,
+		Coin:    bc.Coin,
+		Account: bc.Account,
+		Receive: t.HighestUsedReceiveIndex,
+		Change:  t.HighestUsedChangeIndex,
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
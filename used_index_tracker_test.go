@@ -0,0 +1,47 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsedIndexTracker_NextAddresses(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	tracker := wallet.UsedIndexTracker()
+
+	first, err := tracker.NextReceiveAddress()
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", first.Address)
+
+	assert.Nil(t, tracker.MarkReceiveIndexUsed(3))
+	assert.Nil(t, tracker.MarkReceiveIndexUsed(1))
+	assert.Equal(t, 4, tracker.NextReceiveIndex())
+
+	change, err := tracker.NextChangeAddress()
+	assert.Nil(t, err)
+	assert.Equal(t, "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el", change.Address)
+
+	assert.Nil(t, tracker.MarkAddressUsed("bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el", 5))
+	assert.Equal(t, 1, tracker.NextChangeIndex())
+
+	assert.NotNil(t, tracker.MarkChangeIndexUsed(-1))
+}
+
+func TestUsedIndexTracker_SerializeAndRestore(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	tracker := wallet.UsedIndexTracker()
+	assert.Nil(t, tracker.MarkReceiveIndexUsed(7))
+	assert.Nil(t, tracker.MarkChangeIndexUsed(2))
+
+	state, err := tracker.SerializedState()
+	assert.Nil(t, err)
+
+	restored := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	assert.Nil(t, restored.RestoreUsedIndexTracker(state))
+	assert.Equal(t, 8, restored.UsedIndexTracker().NextReceiveIndex())
+	assert.Equal(t, 3, restored.UsedIndexTracker().NextChangeIndex())
+
+	otherCoin := NewHDWalletFromWords(w, BaseCoinBip49MainNet)
+	assert.NotNil(t, otherCoin.RestoreUsedIndexTracker(state))
+}
@@ -1,21 +1,44 @@
 package cnlib
 
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
 /// Type Definition
 
-// UTXO is a type used to manage an unspent transaction output. Use `Path` if deriving a private key from wallet's derivation path, or `ImportedPrivateKey` if sweeping a direct private key.
+// UTXO is a type used to manage an unspent transaction output. Use `Path` if deriving a private key from wallet's derivation path, or `ImportedPrivateKey` if sweeping a direct private key,
+// or call `SetDescriptor` for an output outside the wallet's derivation paths.
+// Call `SetPrevout` with the output as reported by the chain before signing.
 type UTXO struct {
 	Txid               string
-	Index              int // must be in UInt32 range
-	Amount             int // must be in UInt32 range
+	Index              int   // must be in UInt32 range
+	Amount             int64 // satoshis
 	Path               *DerivationPath
 	ImportedPrivateKey *ImportedPrivateKey
 	IsConfirmed        bool
+	prevout            *wire.TxOut
+	prevTx             *wire.MsgTx
+	descriptor         *outputDescriptor
 }
 
+// Following errors describe a utxo whose previous output, as reported by the chain, does not match the claimed utxo.
+var (
+	ErrPrevoutMissing        = errors.New("utxo previous output script and amount are required to sign")
+	ErrPrevoutScriptMismatch = errors.New("utxo previous output script does not match derivation path")
+	ErrPrevoutAmountMismatch = errors.New("utxo previous output amount does not match utxo amount")
+	ErrPrevoutTxMissing      = errors.New("utxo previous transaction is required for P2PKH psbt inputs")
+)
+
 /// Constructor
 
 // NewUTXO instantiates a new UTXO object and returns a ref to it.
-func NewUTXO(txid string, index int, amount int, path *DerivationPath, importedPrivateKey *ImportedPrivateKey, isConfirmed bool) *UTXO {
+func NewUTXO(txid string, index int, amount int64, path *DerivationPath, importedPrivateKey *ImportedPrivateKey, isConfirmed bool) *UTXO {
 	u := UTXO{
 		Txid:               txid,
 		Index:              index,
@@ -26,3 +49,64 @@ func NewUTXO(txid string, index int, amount int, path *DerivationPath, importedP
 	}
 	return &u
 }
+
+/// Receiver functions
+
+// SetPrevout records the scriptPubKey and amount of the previous output, as reported by the chain, which the utxo
+// spends. Before signing, these are verified against the script derived from the utxo's `Path` or imported key, and
+// against its `Amount`, so a wrong path or amount is rejected rather than producing an invalid signature.
+func (u *UTXO) SetPrevout(pkScript []byte, amount int64) {
+	u.prevout = wire.NewTxOut(amount, pkScript)
+}
+
+// SetPreviousTransaction records the hex encoded transaction creating the utxo, and its output as `SetPrevout` does.
+// PSBTs carry the whole transaction for P2PKH inputs, whose signatures do not commit to the amount spent. Returns
+// error if the transaction's txid or outputs do not match the utxo.
+func (u *UTXO) SetPreviousTransaction(encodedTx string) error {
+	txBytes, err := hex.DecodeString(encodedTx)
+	if err != nil {
+		return err
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return err
+	}
+	if tx.TxHash().String() != u.Txid {
+		return errors.New("previous transaction txid does not match utxo")
+	}
+	if u.Index < 0 || u.Index >= len(tx.TxOut) {
+		return errors.New("previous transaction has no output at utxo index")
+	}
+	u.prevTx = tx
+	u.prevout = tx.TxOut[u.Index]
+	return nil
+}
+
+/// Unexported functions
+
+// verifyPrevout returns error if the utxo has no previous output recorded, or if it does not match the given expected
+// script or the utxo's amount.
+func (u *UTXO) verifyPrevout(expectedPkScript []byte) error {
+	if u.prevout == nil {
+		return ErrPrevoutMissing
+	}
+	if !bytes.Equal(u.prevout.PkScript, expectedPkScript) {
+		return ErrPrevoutScriptMismatch
+	}
+	if u.prevout.Value != u.Amount {
+		return ErrPrevoutAmountMismatch
+	}
+	return nil
+}
+
+// outPointFromInfo returns a wire outpoint for a given txid and output index, or error if either is invalid.
+func outPointFromInfo(txid string, index int) (*wire.OutPoint, error) {
+	if index < 0 || index > int(math.MaxInt32) {
+		return nil, errors.New("previous utxo index out of bounds")
+	}
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewOutPoint(hash, uint32(index)), nil
+}
@@ -0,0 +1,280 @@
+package cnlib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const utxoManagerStateVersion = 1
+
+// ErrUTXONotFound describes an error in which the caller referenced a utxo the UTXOManager does not hold.
+var ErrUTXONotFound = errors.New("utxo not found")
+
+/// Type Definitions
+
+// UTXOManager holds the wallet's utxos, the state of each, from unconfirmed to spent, and which of them are frozen,
+// excluded from spending until unfrozen, such as dust sent to track the wallet. Add utxos one at a time using
+// `AddUTXO`, as gomobile does not support custom arrays/slices, move them between states as the chain changes, and
+// pass spendable ones to transactions with `SpendableUTXOAtIndex`.
+type UTXOManager struct {
+	utxos         []*UTXO
+	states        map[string]int
+	spendingTxids map[string]string
+	frozen        map[string]bool
+	unfrozen      map[string]bool // unfrozen by the user, and so not frozen again automatically
+	observer      WalletObserver
+}
+
+// utxoManagerState is the serialized form of a UTXOManager's utxo states and frozen utxos, keyed by "txid:index".
+type utxoManagerState struct {
+	Version  int               `json:"version"`
+	Frozen   []string          `json:"frozen"`
+	Unfrozen []string          `json:"unfrozen"`
+	States   []*utxoStateEntry `json:"states,omitempty"`
+}
+
+// utxoStateEntry is the serialized state of a utxo.
+type utxoStateEntry struct {
+	Outpoint     string `json:"outpoint"`
+	State        int    `json:"state"`
+	SpendingTxid string `json:"spending_txid,omitempty"`
+}
+
+/// Constructors
+
+// NewUTXOManager returns a pointer to an empty UTXOManager.
+func NewUTXOManager() *UTXOManager {
+	return &UTXOManager{states: map[string]int{}, spendingTxids: map[string]string{}, frozen: map[string]bool{}, unfrozen: map[string]bool{}}
+}
+
+/// Receiver functions
+
+// AddUTXO adds a utxo, replacing any with the same txid and index. Its state is unconfirmed or confirmed, as its
+// `IsConfirmed` field says, unless a transition or `RestoreSerializedState` already moved it to a spent or reorged
+// out state. A utxo frozen before it was added stays frozen.
+func (m *UTXOManager) AddUTXO(utxo *UTXO) error {
+	if utxo == nil {
+		return errors.New("utxo cannot be nil")
+	}
+	if _, err := outPointFromInfo(utxo.Txid, utxo.Index); err != nil {
+		return err
+	}
+	key := utxoKey(utxo.Txid, utxo.Index)
+	if state, ok := m.states[key]; !ok || isSpendableUTXOState(state) {
+		m.states[key] = UTXOStateUnconfirmed
+		if utxo.IsConfirmed {
+			m.states[key] = UTXOStateConfirmed
+		}
+	}
+	for i, existing := range m.utxos {
+		if utxoKey(existing.Txid, existing.Index) == key {
+			m.utxos[i] = utxo
+			return nil
+		}
+	}
+	m.utxos = append(m.utxos, utxo)
+	if m.observer != nil {
+		m.observer.OnUTXOAdded(utxo)
+	}
+	return nil
+}
+
+// RemoveUTXO removes a utxo, along with its state, such as once spent deeply enough not to be reorged out. Returns
+// ErrUTXONotFound if not held.
+func (m *UTXOManager) RemoveUTXO(txid string, index int) error {
+	key := utxoKey(txid, index)
+	for i, existing := range m.utxos {
+		if utxoKey(existing.Txid, existing.Index) == key {
+			m.utxos = append(m.utxos[:i], m.utxos[i+1:]...)
+			delete(m.states, key)
+			delete(m.spendingTxids, key)
+			delete(m.frozen, key)
+			delete(m.unfrozen, key)
+			return nil
+		}
+	}
+	return ErrUTXONotFound
+}
+
+// UTXOCount returns the number of utxos held, in any state, frozen or not.
+func (m *UTXOManager) UTXOCount() int {
+	return len(m.utxos)
+}
+
+// UTXOAtIndex returns the utxo at an index, in the order added.
+func (m *UTXOManager) UTXOAtIndex(index int) (*UTXO, error) {
+	if index < 0 || index >= len(m.utxos) {
+		return nil, errors.New("index out of range")
+	}
+	return m.utxos[index], nil
+}
+
+// Freeze excludes a utxo from spending. Returns ErrUTXONotFound if not held.
+func (m *UTXOManager) Freeze(txid string, index int) error {
+	key := utxoKey(txid, index)
+	if !m.holds(key) {
+		return ErrUTXONotFound
+	}
+	m.frozen[key] = true
+	delete(m.unfrozen, key)
+	return nil
+}
+
+// Unfreeze makes a frozen utxo spendable again, as the user's decision, so it is not frozen automatically again.
+// Returns ErrUTXONotFound if not held.
+func (m *UTXOManager) Unfreeze(txid string, index int) error {
+	key := utxoKey(txid, index)
+	if !m.holds(key) {
+		return ErrUTXONotFound
+	}
+	delete(m.frozen, key)
+	m.unfrozen[key] = true
+	return nil
+}
+
+// IsFrozen returns true if a utxo is frozen.
+func (m *UTXOManager) IsFrozen(txid string, index int) bool {
+	return m.frozen[utxoKey(txid, index)]
+}
+
+// SpendableUTXOCount returns the number of unconfirmed or confirmed utxos not frozen.
+func (m *UTXOManager) SpendableUTXOCount() int {
+	return len(m.spendable())
+}
+
+// SpendableUTXOAtIndex returns the utxo at an index of the unconfirmed or confirmed utxos not frozen, in the order
+// added.
+func (m *UTXOManager) SpendableUTXOAtIndex(index int) (*UTXO, error) {
+	spendable := m.spendable()
+	if index < 0 || index >= len(spendable) {
+		return nil, errors.New("index out of range")
+	}
+	return spendable[index], nil
+}
+
+// SpendableAmount returns the total amount of the unconfirmed or confirmed utxos not frozen, in satoshis.
+func (m *UTXOManager) SpendableAmount() int {
+	total := 0
+	for _, utxo := range m.spendable() {
+		total += int(utxo.Amount)
+	}
+	return total
+}
+
+// SerializedState returns the state of each utxo, which are frozen, and which the user unfroze, as a JSON string
+// suitable for persisting and passing to `RestoreSerializedState`. The utxos themselves are not included.
+func (m *UTXOManager) SerializedState() (string, error) {
+	state := utxoManagerState{Version: utxoManagerStateVersion, Frozen: sortedKeys(m.frozen), Unfrozen: sortedKeys(m.unfrozen)}
+	for _, key := range sortedKeys(m.stateKeys()) {
+		state.States = append(state.States, &utxoStateEntry{Outpoint: key, State: m.states[key], SpendingTxid: m.spendingTxids[key]})
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// RestoreSerializedState replaces the utxo and frozen states with ones returned by `SerializedState`, including those
+// of utxos not yet added.
+func (m *UTXOManager) RestoreSerializedState(serializedState string) error {
+	var state utxoManagerState
+	if err := json.Unmarshal([]byte(serializedState), &state); err != nil {
+		return err
+	}
+	if state.Version != utxoManagerStateVersion {
+		return errors.New("unsupported utxo manager state version")
+	}
+	frozen := make(map[string]bool, len(state.Frozen))
+	for _, key := range state.Frozen {
+		frozen[key] = true
+	}
+	unfrozen := make(map[string]bool, len(state.Unfrozen))
+	for _, key := range state.Unfrozen {
+		if frozen[key] {
+			return fmt.Errorf("utxo %s cannot be both frozen and unfrozen", key)
+		}
+		unfrozen[key] = true
+	}
+	states := make(map[string]int, len(state.States))
+	spendingTxids := map[string]string{}
+	for _, entry := range state.States {
+		if entry == nil || entry.State < UTXOStateUnconfirmed || entry.State > UTXOStateReorgedOut {
+			return errors.New("utxo manager state contains an invalid utxo state")
+		}
+		spent := entry.State == UTXOStateSpentPending || entry.State == UTXOStateSpent
+		if spent != (entry.SpendingTxid != "") {
+			return fmt.Errorf("utxo %s has an invalid spending txid", entry.Outpoint)
+		}
+		states[entry.Outpoint] = entry.State
+		if spent {
+			spendingTxids[entry.Outpoint] = entry.SpendingTxid
+		}
+	}
+	for _, utxo := range m.utxos {
+		key := utxoKey(utxo.Txid, utxo.Index)
+		if _, ok := states[key]; !ok {
+			states[key] = UTXOStateUnconfirmed
+			if utxo.IsConfirmed {
+				states[key] = UTXOStateConfirmed
+			}
+		}
+	}
+	m.states = states
+	m.spendingTxids = spendingTxids
+	m.frozen = frozen
+	m.unfrozen = unfrozen
+	return nil
+}
+
+/// Unexported functions
+
+func (m *UTXOManager) holds(key string) bool {
+	return m.utxo(key) != nil
+}
+
+// utxo returns the held utxo with a "txid:index" key, or nil.
+func (m *UTXOManager) utxo(key string) *UTXO {
+	for _, utxo := range m.utxos {
+		if utxoKey(utxo.Txid, utxo.Index) == key {
+			return utxo
+		}
+	}
+	return nil
+}
+
+// stateKeys returns the keys of the held utxos, and of restored utxo states not yet added, as a set.
+func (m *UTXOManager) stateKeys() map[string]bool {
+	keys := make(map[string]bool, len(m.states))
+	for key := range m.states {
+		keys[key] = true
+	}
+	return keys
+}
+
+func (m *UTXOManager) spendable() []*UTXO {
+	spendable := make([]*UTXO, 0, len(m.utxos))
+	for _, utxo := range m.utxos {
+		key := utxoKey(utxo.Txid, utxo.Index)
+		if !m.frozen[key] && isSpendableUTXOState(m.states[key]) {
+			spendable = append(spendable, utxo)
+		}
+	}
+	return spendable
+}
+
+// utxoKey returns the "txid:index" key of a utxo's outpoint.
+func utxoKey(txid string, index int) string {
+	return fmt.Sprintf("%s:%d", txid, index)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,94 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const utxoManagerTestTxid = "a89a9bed1f2daca01a0dca58f7fd0f2f0bf114d762b38e65845c5d1489339a69"
+
+func TestUTXOManager_AddAndRemove(t *testing.T) {
+	manager := NewUTXOManager()
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 0, 50000, path, nil, true)))
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 1, 20000, path, nil, false)))
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 1, 25000, path, nil, false)))
+	assert.Equal(t, 2, manager.UTXOCount())
+	assert.Equal(t, 2, manager.SpendableUTXOCount())
+	assert.Equal(t, 75000, manager.SpendableAmount())
+	utxo, err := manager.UTXOAtIndex(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(25000), utxo.Amount)
+	_, err = manager.UTXOAtIndex(2)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, manager.RemoveUTXO(utxoManagerTestTxid, 0))
+	assert.Equal(t, 1, manager.UTXOCount())
+	spendable, err := manager.SpendableUTXOAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, spendable.Index)
+	_, err = manager.SpendableUTXOAtIndex(1)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrUTXONotFound, manager.RemoveUTXO(utxoManagerTestTxid, 0))
+	assert.NotNil(t, manager.AddUTXO(NewUTXO("not a txid", 0, 1000, path, nil, true)))
+	assert.NotNil(t, manager.AddUTXO(nil))
+}
+
+func TestUTXOManager_FreezeAndSpend(t *testing.T) {
+	manager := NewUTXOManager()
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 0, 50000, path, nil, true)))
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 1, 20000, path, nil, true)))
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 1, 25000, path, nil, true)))
+	assert.Equal(t, 2, manager.UTXOCount())
+	assert.Equal(t, 75000, manager.SpendableAmount())
+
+	assert.Nil(t, manager.Freeze(utxoManagerTestTxid, 0))
+	assert.True(t, manager.IsFrozen(utxoManagerTestTxid, 0))
+	assert.Equal(t, 1, manager.SpendableUTXOCount())
+	spendable, err := manager.SpendableUTXOAtIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, spendable.Index)
+	assert.Equal(t, 25000, manager.SpendableAmount())
+	_, err = manager.SpendableUTXOAtIndex(1)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, manager.Unfreeze(utxoManagerTestTxid, 0))
+	assert.False(t, manager.IsFrozen(utxoManagerTestTxid, 0))
+	assert.Equal(t, 2, manager.SpendableUTXOCount())
+
+	assert.Nil(t, manager.RemoveUTXO(utxoManagerTestTxid, 0))
+	assert.Equal(t, 1, manager.UTXOCount())
+	assert.Equal(t, ErrUTXONotFound, manager.RemoveUTXO(utxoManagerTestTxid, 0))
+	assert.Equal(t, ErrUTXONotFound, manager.Freeze(utxoManagerTestTxid, 0))
+	assert.Equal(t, ErrUTXONotFound, manager.Unfreeze(utxoManagerTestTxid, 0))
+	assert.NotNil(t, manager.AddUTXO(NewUTXO("not a txid", 0, 1000, path, nil, true)))
+	assert.NotNil(t, manager.AddUTXO(nil))
+}
+
+func TestUTXOManager_SerializedState_RestoresFrozenUTXOs(t *testing.T) {
+	manager := NewUTXOManager()
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, i, 50000, path, nil, true)))
+	}
+	assert.Nil(t, manager.Freeze(utxoManagerTestTxid, 2))
+	assert.Nil(t, manager.Freeze(utxoManagerTestTxid, 1))
+	assert.Nil(t, manager.Unfreeze(utxoManagerTestTxid, 1))
+	state, err := manager.SerializedState()
+	assert.Nil(t, err)
+
+	// restored before the utxos are added again
+	restored := NewUTXOManager()
+	assert.Nil(t, restored.RestoreSerializedState(state))
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, restored.AddUTXO(NewUTXO(utxoManagerTestTxid, i, 50000, path, nil, true)))
+	}
+	assert.True(t, restored.IsFrozen(utxoManagerTestTxid, 2))
+	assert.False(t, restored.IsFrozen(utxoManagerTestTxid, 1))
+	again, _ := restored.SerializedState()
+	assert.Equal(t, state, again)
+
+	assert.NotNil(t, restored.RestoreSerializedState(`{"version":1,"frozen":["a:0"],"unfrozen":["a:0"]}`))
+}
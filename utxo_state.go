@@ -0,0 +1,169 @@
+package cnlib
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Following constants describe the lifecycle state of a utxo held by a UTXOManager. Only unconfirmed and confirmed
+// utxos are spendable.
+const (
+	UTXOStateUnconfirmed  int = 0 // created by a transaction in the mempool
+	UTXOStateConfirmed    int = 1 // created by a transaction in a block
+	UTXOStateSpentPending int = 2 // spent by a transaction in the mempool
+	UTXOStateSpent        int = 3 // spent by a transaction in a block
+	UTXOStateReorgedOut   int = 4 // its transaction left the chain and mempool, such as in a reorg or double spend
+)
+
+// ErrInvalidUTXOStateTransition describes an error in which the caller moved a utxo to a state it cannot reach from
+// its current one, such as confirming a spent utxo.
+var ErrInvalidUTXOStateTransition = errors.New("invalid utxo state transition")
+
+// utxoStateTransitions lists the states each state may move to.
+var utxoStateTransitions = map[int][]int{
+	UTXOStateUnconfirmed:  {UTXOStateConfirmed, UTXOStateSpentPending, UTXOStateReorgedOut},
+	UTXOStateConfirmed:    {UTXOStateUnconfirmed, UTXOStateSpentPending, UTXOStateSpent, UTXOStateReorgedOut},
+	UTXOStateSpentPending: {UTXOStateUnconfirmed, UTXOStateConfirmed, UTXOStateSpentPending, UTXOStateSpent, UTXOStateReorgedOut},
+	UTXOStateSpent:        {UTXOStateSpentPending, UTXOStateReorgedOut},
+	UTXOStateReorgedOut:   {UTXOStateUnconfirmed, UTXOStateConfirmed},
+}
+
+/// Receiver functions
+
+// UTXOState returns the state of a utxo, one of the UTXOState constants. Returns ErrUTXONotFound if not held.
+func (m *UTXOManager) UTXOState(txid string, index int) (int, error) {
+	key := utxoKey(txid, index)
+	if !m.holds(key) {
+		return 0, ErrUTXONotFound
+	}
+	return m.states[key], nil
+}
+
+// SpendingTxid returns the txid of the transaction spending a utxo, or an empty string if it is not spent or pending.
+func (m *UTXOManager) SpendingTxid(txid string, index int) string {
+	return m.spendingTxids[utxoKey(txid, index)]
+}
+
+// MarkConfirmed records that the transaction creating a utxo was mined, from the unconfirmed or reorged out state.
+// Use `ConfirmTransaction` to confirm every utxo a transaction creates or spends and notify the observer.
+func (m *UTXOManager) MarkConfirmed(txid string, index int) error {
+	return m.transition(txid, index, UTXOStateConfirmed, "")
+}
+
+// ConfirmTransaction records that a transaction was mined: each unconfirmed or reorged out utxo it creates becomes
+// confirmed, and each utxo pending its spend becomes spent. Returns the number of utxos moved, and reports the
+// transaction to the observer, if any, when there was at least one.
+func (m *UTXOManager) ConfirmTransaction(txid string) (int, error) {
+	if _, err := outPointFromInfo(txid, 0); err != nil {
+		return 0, err
+	}
+	moved := 0
+	for _, utxo := range m.utxos {
+		key := utxoKey(utxo.Txid, utxo.Index)
+		state := m.states[key]
+		switch {
+		case utxo.Txid == txid && (state == UTXOStateUnconfirmed || state == UTXOStateReorgedOut):
+			if err := m.transition(utxo.Txid, utxo.Index, UTXOStateConfirmed, ""); err != nil {
+				return moved, err
+			}
+		case state == UTXOStateSpentPending && m.spendingTxids[key] == txid:
+			if err := m.transition(utxo.Txid, utxo.Index, UTXOStateSpent, txid); err != nil {
+				return moved, err
+			}
+		default:
+			continue
+		}
+		moved++
+	}
+	if moved > 0 && m.observer != nil {
+		m.observer.OnTxConfirmed(txid)
+	}
+	return moved, nil
+}
+
+// MarkUnconfirmed records that the block holding the transaction creating a confirmed or reorged out utxo was
+// disconnected, and the transaction is back in the mempool.
+func (m *UTXOManager) MarkUnconfirmed(txid string, index int) error {
+	return m.transition(txid, index, UTXOStateUnconfirmed, "")
+}
+
+// MarkSpendPending records that a transaction in the mempool spends a utxo, such as one the wallet just broadcast, or
+// a replacement of the previous spending transaction. A spent utxo returns to pending when its spending transaction's
+// block is disconnected. The utxo is no longer spendable.
+func (m *UTXOManager) MarkSpendPending(txid string, index int, spendingTxid string) error {
+	return m.transition(txid, index, UTXOStateSpentPending, spendingTxid)
+}
+
+// MarkSpent records that a transaction in a block spends a confirmed or pending utxo.
+func (m *UTXOManager) MarkSpent(txid string, index int, spendingTxid string) error {
+	return m.transition(txid, index, UTXOStateSpent, spendingTxid)
+}
+
+// CancelSpend returns a pending utxo to the unconfirmed or confirmed state, as its `IsConfirmed` field says, when its
+// spending transaction is dropped from the mempool or replaced by one not spending it.
+func (m *UTXOManager) CancelSpend(txid string, index int) error {
+	utxo := m.utxo(utxoKey(txid, index))
+	if utxo == nil {
+		return ErrUTXONotFound
+	}
+	if m.states[utxoKey(txid, index)] != UTXOStateSpentPending {
+		return ErrInvalidUTXOStateTransition
+	}
+	if utxo.IsConfirmed {
+		return m.transition(txid, index, UTXOStateConfirmed, "")
+	}
+	return m.transition(txid, index, UTXOStateUnconfirmed, "")
+}
+
+// MarkReorgedOut records that the transaction creating a utxo left both the chain and mempool, such as when a reorg
+// replaces it with a double spend. The utxo is kept, unspendable, in case the transaction returns.
+func (m *UTXOManager) MarkReorgedOut(txid string, index int) error {
+	return m.transition(txid, index, UTXOStateReorgedOut, "")
+}
+
+/// Unexported functions
+
+// transition moves a held utxo to a state, recording the spending txid of the spent states, and keeps the utxo's
+// `IsConfirmed` field in step with the confirmation of its transaction.
+func (m *UTXOManager) transition(txid string, index int, state int, spendingTxid string) error {
+	key := utxoKey(txid, index)
+	utxo := m.utxo(key)
+	if utxo == nil {
+		return ErrUTXONotFound
+	}
+	if !isUTXOStateTransition(m.states[key], state) {
+		return ErrInvalidUTXOStateTransition
+	}
+	if state == UTXOStateSpentPending || state == UTXOStateSpent {
+		if _, err := outPointFromInfo(spendingTxid, 0); err != nil {
+			return fmt.Errorf("invalid spending txid: %s", err)
+		}
+		m.spendingTxids[key] = spendingTxid
+	} else {
+		delete(m.spendingTxids, key)
+	}
+
+	switch state {
+	case UTXOStateUnconfirmed, UTXOStateReorgedOut:
+		utxo.IsConfirmed = false
+	case UTXOStateConfirmed, UTXOStateSpent:
+		utxo.IsConfirmed = true
+	}
+	m.states[key] = state
+	return nil
+}
+
+func isUTXOStateTransition(from int, to int) bool {
+	for _, state := range utxoStateTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// isSpendableUTXOState returns true for the states of utxos not spent, or being spent, and still on chain or in the
+// mempool.
+func isSpendableUTXOState(state int) bool {
+	return state == UTXOStateUnconfirmed || state == UTXOStateConfirmed
+}
@@ -0,0 +1,115 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const utxoStateTestSpendingTxid = "1a08dafe993fdc17fdc661988c88f97a9974013291e759b9b5766b8e97c78f87"
+
+func TestUTXOManager_StateTransitions(t *testing.T) {
+	manager := NewUTXOManager()
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	utxo := NewUTXO(utxoManagerTestTxid, 0, 50000, path, nil, false)
+	assert.Nil(t, manager.AddUTXO(utxo))
+	assertUTXOState(t, manager, UTXOStateUnconfirmed)
+	assert.Equal(t, 1, manager.SpendableUTXOCount())
+
+	assert.Nil(t, manager.MarkConfirmed(utxoManagerTestTxid, 0))
+	assertUTXOState(t, manager, UTXOStateConfirmed)
+	assert.True(t, utxo.IsConfirmed)
+
+	// broadcast, replaced, then dropped
+	assert.Nil(t, manager.MarkSpendPending(utxoManagerTestTxid, 0, utxoStateTestSpendingTxid))
+	assertUTXOState(t, manager, UTXOStateSpentPending)
+	assert.Equal(t, utxoStateTestSpendingTxid, manager.SpendingTxid(utxoManagerTestTxid, 0))
+	assert.Equal(t, 0, manager.SpendableUTXOCount())
+	assert.Equal(t, 0, manager.SpendableAmount())
+	assert.Nil(t, manager.MarkSpendPending(utxoManagerTestTxid, 0, utxoManagerTestTxid))
+	assert.Equal(t, utxoManagerTestTxid, manager.SpendingTxid(utxoManagerTestTxid, 0))
+	assert.Nil(t, manager.CancelSpend(utxoManagerTestTxid, 0))
+	assertUTXOState(t, manager, UTXOStateConfirmed)
+	assert.Equal(t, "", manager.SpendingTxid(utxoManagerTestTxid, 0))
+	assert.Equal(t, 1, manager.SpendableUTXOCount())
+
+	// spent, then the spending block is disconnected
+	assert.Nil(t, manager.MarkSpendPending(utxoManagerTestTxid, 0, utxoStateTestSpendingTxid))
+	assert.Nil(t, manager.MarkSpent(utxoManagerTestTxid, 0, utxoStateTestSpendingTxid))
+	assertUTXOState(t, manager, UTXOStateSpent)
+	assert.Equal(t, ErrInvalidUTXOStateTransition, manager.MarkConfirmed(utxoManagerTestTxid, 0))
+	assert.Equal(t, ErrInvalidUTXOStateTransition, manager.CancelSpend(utxoManagerTestTxid, 0))
+	assert.Nil(t, manager.MarkSpendPending(utxoManagerTestTxid, 0, utxoStateTestSpendingTxid))
+
+	// the creating transaction is reorged out, then mined again
+	assert.Nil(t, manager.MarkReorgedOut(utxoManagerTestTxid, 0))
+	assertUTXOState(t, manager, UTXOStateReorgedOut)
+	assert.False(t, utxo.IsConfirmed)
+	assert.Equal(t, "", manager.SpendingTxid(utxoManagerTestTxid, 0))
+	assert.Equal(t, 0, manager.SpendableUTXOCount())
+	assert.Equal(t, ErrInvalidUTXOStateTransition, manager.MarkSpent(utxoManagerTestTxid, 0, utxoStateTestSpendingTxid))
+	assert.Equal(t, ErrInvalidUTXOStateTransition, manager.MarkReorgedOut(utxoManagerTestTxid, 0))
+	assert.Nil(t, manager.MarkUnconfirmed(utxoManagerTestTxid, 0))
+	assert.Nil(t, manager.MarkConfirmed(utxoManagerTestTxid, 0))
+	assertUTXOState(t, manager, UTXOStateConfirmed)
+
+	// adding the utxo again does not undo a spend
+	assert.Nil(t, manager.MarkSpendPending(utxoManagerTestTxid, 0, utxoStateTestSpendingTxid))
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 0, 50000, path, nil, true)))
+	assertUTXOState(t, manager, UTXOStateSpentPending)
+
+	assert.NotNil(t, manager.MarkSpent(utxoManagerTestTxid, 0, "not a txid"))
+	_, err := manager.UTXOState(utxoManagerTestTxid, 1)
+	assert.Equal(t, ErrUTXONotFound, err)
+	assert.Equal(t, ErrUTXONotFound, manager.MarkConfirmed(utxoManagerTestTxid, 1))
+	assert.Equal(t, ErrUTXONotFound, manager.CancelSpend(utxoManagerTestTxid, 1))
+}
+
+func TestUTXOManager_SerializedState_RestoresUTXOStates(t *testing.T) {
+	manager := NewUTXOManager()
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, i, 50000, path, nil, true)))
+	}
+	assert.Nil(t, manager.MarkSpendPending(utxoManagerTestTxid, 1, utxoStateTestSpendingTxid))
+	assert.Nil(t, manager.MarkReorgedOut(utxoManagerTestTxid, 2))
+	state, err := manager.SerializedState()
+	assert.Nil(t, err)
+
+	// restored before the utxos are added again
+	restored := NewUTXOManager()
+	assert.Nil(t, restored.RestoreSerializedState(state))
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, restored.AddUTXO(NewUTXO(utxoManagerTestTxid, i, 50000, path, nil, true)))
+	}
+	for i, expected := range []int{UTXOStateConfirmed, UTXOStateSpentPending, UTXOStateReorgedOut} {
+		state, err := restored.UTXOState(utxoManagerTestTxid, i)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, state)
+	}
+	assert.Equal(t, utxoStateTestSpendingTxid, restored.SpendingTxid(utxoManagerTestTxid, 1))
+	assert.Equal(t, 1, restored.SpendableUTXOCount())
+	again, err := restored.SerializedState()
+	assert.Nil(t, err)
+	assert.Equal(t, state, again)
+
+	// utxos without a serialized state follow their confirmation
+	unstated := NewUTXOManager()
+	assert.Nil(t, unstated.AddUTXO(NewUTXO(utxoManagerTestTxid, 0, 50000, path, nil, false)))
+	assert.Nil(t, unstated.RestoreSerializedState(`{"version":1}`))
+	unstatedState, err := unstated.UTXOState(utxoManagerTestTxid, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, UTXOStateUnconfirmed, unstatedState)
+
+	assert.NotNil(t, restored.RestoreSerializedState(`{"version":1,"states":[{"outpoint":"a:0","state":5}]}`))
+	assert.NotNil(t, restored.RestoreSerializedState(`{"version":1,"states":[{"outpoint":"a:0","state":3}]}`))
+	assert.NotNil(t, restored.RestoreSerializedState(`{"version":1,"states":[{"outpoint":"a:0","state":1,"spending_txid":"b"}]}`))
+	assert.NotNil(t, restored.RestoreSerializedState(`{"version":2}`))
+	assert.NotNil(t, restored.RestoreSerializedState("not json"))
+}
+
+func assertUTXOState(t *testing.T, manager *UTXOManager, expected int) {
+	state, err := manager.UTXOState(utxoManagerTestTxid, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, state)
+}
@@ -0,0 +1,49 @@
+package cnlib
+
+/// Type Definitions
+
+// WalletObserver receives changes to a wallet's state as they happen, so platform UIs can update reactively instead
+// of polling. Implement it in the app, and register it with the wallet's and UTXOManager's `SetObserver`. Methods are
+// called synchronously, on the goroutine making the change, so they should return quickly and dispatch to the UI
+// thread themselves.
+type WalletObserver interface {
+	// OnAddressUsed is called when the wallet's UsedIndexTracker records an address as used, raising the highest used
+	// index of its chain. Marking an index at or below the current one is not reported.
+	OnAddressUsed(address *MetaAddress)
+
+	// OnUTXOAdded is called when a UTXOManager's `AddUTXO` adds a utxo it did not hold. Replacing a held utxo is not
+	// reported.
+	OnUTXOAdded(utxo *UTXO)
+
+	// OnTxConfirmed is called when a UTXOManager's `ConfirmTransaction` records a transaction creating or spending
+	// the wallet's utxos as mined.
+	OnTxConfirmed(txid string)
+}
+
+/// Receiver functions
+
+// SetObserver registers an observer of the wallet's changes, replacing any previous one. Pass nil to stop observing.
+func (wallet *HDWallet) SetObserver(observer WalletObserver) {
+	wallet.observer = observer
+}
+
+// SetObserver registers an observer of the UTXOManager's added utxos and confirmed transactions, replacing any
+// previous one. Pass nil to stop observing.
+func (m *UTXOManager) SetObserver(observer WalletObserver) {
+	m.observer = observer
+}
+
+/// Unexported functions
+
+// notifyAddressUsed reports a newly used address at an index of a chain to the wallet's observer, if any.
+func (wallet *HDWallet) notifyAddressUsed(change int, index int) error {
+	if wallet.observer == nil {
+		return nil
+	}
+	meta, err := wallet.addressForIndex(change, index)
+	if err != nil {
+		return err
+	}
+	wallet.observer.OnAddressUsed(meta)
+	return nil
+}
@@ -0,0 +1,96 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingObserver struct {
+	used      []*MetaAddress
+	added     []*UTXO
+	confirmed []string
+}
+
+func (o *recordingObserver) OnAddressUsed(address *MetaAddress) {
+	o.used = append(o.used, address)
+}
+
+func (o *recordingObserver) OnUTXOAdded(utxo *UTXO) {
+	o.added = append(o.added, utxo)
+}
+
+func (o *recordingObserver) OnTxConfirmed(txid string) {
+	o.confirmed = append(o.confirmed, txid)
+}
+
+func TestWalletObserver_OnAddressUsed(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	observer := &recordingObserver{}
+	wallet.SetObserver(observer)
+	tracker := wallet.UsedIndexTracker()
+
+	assert.Nil(t, tracker.MarkReceiveIndexUsed(2))
+	assert.Nil(t, tracker.MarkReceiveIndexUsed(1))
+	assert.Nil(t, tracker.MarkReceiveIndexUsed(2))
+	assert.Nil(t, tracker.MarkChangeIndexUsed(0))
+	assert.Equal(t, 2, len(observer.used))
+
+	expected, err := wallet.ReceiveAddressForIndex(2)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Address, observer.used[0].Address)
+	assert.True(t, observer.used[0].IsReceiveAddress())
+	assert.Equal(t, 1, observer.used[1].DerivationPath.Change)
+	assert.Equal(t, 0, observer.used[1].DerivationPath.Index)
+
+	third, err := wallet.ReceiveAddressForIndex(3)
+	assert.Nil(t, err)
+	assert.Nil(t, tracker.MarkAddressUsed(third.Address, 5))
+	assert.Equal(t, 3, len(observer.used))
+	assert.Equal(t, third.Address, observer.used[2].Address)
+
+	wallet.SetObserver(nil)
+	assert.Nil(t, tracker.MarkReceiveIndexUsed(4))
+	assert.Equal(t, 3, len(observer.used))
+	assert.Equal(t, 4, tracker.HighestUsedReceiveIndex)
+}
+
+func TestWalletObserver_OnUTXOAddedAndOnTxConfirmed(t *testing.T) {
+	manager := NewUTXOManager()
+	observer := &recordingObserver{}
+	manager.SetObserver(observer)
+	path := NewDerivationPath(BaseCoinBip84MainNet, 0, 0)
+
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 0, 50000, path, nil, false)))
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 1, 20000, path, nil, false)))
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 1, 20000, path, nil, false)))
+	assert.Equal(t, 2, len(observer.added))
+	assert.Equal(t, 1, observer.added[1].Index)
+
+	// both outputs of the transaction confirm, reported once
+	moved, err := manager.ConfirmTransaction(utxoManagerTestTxid)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, moved)
+	assert.Equal(t, []string{utxoManagerTestTxid}, observer.confirmed)
+	moved, err = manager.ConfirmTransaction(utxoManagerTestTxid)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, moved)
+	assert.Equal(t, 1, len(observer.confirmed))
+
+	// the wallet's spend confirms
+	assert.Nil(t, manager.MarkSpendPending(utxoManagerTestTxid, 0, utxoStateTestSpendingTxid))
+	moved, err = manager.ConfirmTransaction(utxoStateTestSpendingTxid)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, moved)
+	state, err := manager.UTXOState(utxoManagerTestTxid, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, UTXOStateSpent, state)
+	assert.Equal(t, []string{utxoManagerTestTxid, utxoStateTestSpendingTxid}, observer.confirmed)
+
+	_, err = manager.ConfirmTransaction("not a txid")
+	assert.NotNil(t, err)
+
+	manager.SetObserver(nil)
+	assert.Nil(t, manager.AddUTXO(NewUTXO(utxoManagerTestTxid, 2, 20000, path, nil, false)))
+	assert.Equal(t, 2, len(observer.added))
+}
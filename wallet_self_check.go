@@ -0,0 +1,170 @@
+package cnlib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+// selfCheckIndexes are the receive and change indexes re-derived by `SelfCheck`, spanning the default gap limit.
+var selfCheckIndexes = []int{0, 1, 19}
+
+// selfCheckMessage is signed by `SelfCheck` with each sampled key.
+const selfCheckMessage = "cnlib wallet self check"
+
+// Following errors are returned by `SelfCheck`, naming the invariant which failed.
+var (
+	ErrSelfCheckNetwork         = errors.New("wallet keys or addresses do not match the basecoin's network")
+	ErrSelfCheckAddressMismatch = errors.New("addresses derived from the wallet's private and public keys differ")
+	ErrSelfCheckSignature       = errors.New("signature made by the wallet does not verify with its public key")
+)
+
+/// Receiver functions
+
+// SelfCheck re-derives a sample of the wallet's receive and change addresses from both its private and public key
+// branches, bypassing any derivation or address cache, and compares them with the addresses the wallet returns. It
+// then signs and verifies a message with each sampled key and the m/42 signing key, and checks the keys and addresses
+// are encoded for the BaseCoin's network. Run it at app start to detect corrupted storage or a bad build before the
+// wallet is used. Watch-only wallets check their public branch only.
+func (wallet *HDWallet) SelfCheck() error {
+	bc := wallet.BaseCoin
+	if bc == nil {
+		return errors.New("no basecoin provided")
+	}
+	if err := bc.validateIndices(); err != nil {
+		return err
+	}
+
+	accountKey := wallet.accountPublicKey
+	if wallet.masterPrivateKey != nil {
+		if !wallet.masterPrivateKey.IsForNet(bc.defaultNetParams()) {
+			return ErrSelfCheckNetwork
+		}
+		// an uncached key factory re-derives every key from the master key
+		kf := keyFactory{masterPrivateKey: wallet.masterPrivateKey}
+		key, _, err := kf.accountExtendedPublicKey(bc)
+		if err != nil {
+			return err
+		}
+		accountKey = key
+	}
+	if accountKey == nil {
+		return errors.New("no valid master private key or account extended public key found")
+	}
+	if err := selfCheckAccountKeyNetwork(accountKey, bc); err != nil {
+		return err
+	}
+
+	for _, change := range []int{0, 1} {
+		for _, index := range selfCheckIndexes {
+			if err := wallet.selfCheckIndex(accountKey, change, index); err != nil {
+				return err
+			}
+		}
+	}
+
+	if wallet.masterPrivateKey != nil {
+		return wallet.selfCheckSigningKey()
+	}
+	return nil
+}
+
+/// Unexported functions
+
+// selfCheckIndex compares the address at an index derived from the account public key with the address the wallet
+// returns, and, if the wallet has private keys, with the address of the independently derived private key, which
+// must also sign for the public key.
+func (wallet *HDWallet) selfCheckIndex(accountKey *hdkeychain.ExtendedKey, change int, index int) error {
+	public, err := indexMetaAddressFromExtendedPubkey(accountKey, nil, wallet.BaseCoin, uint32(change), uint32(index))
+	if err != nil {
+		return err
+	}
+	if _, err := pkScriptForNetworkAddress(public.Address, wallet.BaseCoin.defaultNetParams()); err != nil {
+		return ErrSelfCheckNetwork
+	}
+
+	returned, err := wallet.addressForIndex(change, index)
+	if err != nil {
+		return err
+	}
+	if returned.Address != public.Address {
+		return ErrSelfCheckAddressMismatch
+	}
+	if wallet.masterPrivateKey == nil {
+		return nil
+	}
+
+	kf := keyFactory{masterPrivateKey: wallet.masterPrivateKey}
+	path := NewDerivationPath(wallet.BaseCoin, change, index)
+	indexKey, err := kf.indexPrivateKey(path)
+	if err != nil {
+		return err
+	}
+	privateKey, err := indexKey.ECPrivKey()
+	if err != nil {
+		return err
+	}
+	private, err := generateAddress(path, privateKey.PubKey())
+	if err != nil {
+		return err
+	}
+	if private != public.Address {
+		return ErrSelfCheckAddressMismatch
+	}
+
+	serializedKey, err := hex.DecodeString(public.UncompressedPublicKey)
+	if err != nil {
+		return err
+	}
+	publicKey, err := btcec.ParsePubKey(serializedKey, btcec.S256())
+	if err != nil {
+		return err
+	}
+	return selfCheckSignature(privateKey, publicKey)
+}
+
+// selfCheckSigningKey verifies a signature of the m/42 signing key against the public key the wallet reports for it.
+func (wallet *HDWallet) selfCheckSigningKey() error {
+	signature, err := wallet.SignData([]byte(selfCheckMessage))
+	if err != nil {
+		return err
+	}
+	serializedKey, err := wallet.SigningPublicKey()
+	if err != nil {
+		return err
+	}
+	if err := VerifySignature([]byte(selfCheckMessage), signature, hex.EncodeToString(serializedKey)); err != nil {
+		return ErrSelfCheckSignature
+	}
+	return nil
+}
+
+// selfCheckAccountKeyNetwork returns `ErrSelfCheckNetwork` if the account key, encoded for the BaseCoin, decodes as
+// the key of another network.
+func selfCheckAccountKeyNetwork(accountKey *hdkeychain.ExtendedKey, bc *BaseCoin) error {
+	encoded, err := encodeExtendedPubkey(accountKey, bc)
+	if err != nil {
+		return err
+	}
+	decoded, err := NewBaseCoinFromAccountPubKey(encoded)
+	if err != nil || decoded.Coin != bc.Coin {
+		return ErrSelfCheckNetwork
+	}
+	return nil
+}
+
+// selfCheckSignature signs a fixed message with a private key, and verifies the signature with a public key.
+func selfCheckSignature(privateKey *btcec.PrivateKey, publicKey *btcec.PublicKey) error {
+	hash := sha256.Sum256([]byte(selfCheckMessage))
+	signature, err := privateKey.Sign(hash[:])
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(hash[:], publicKey) {
+		return ErrSelfCheckSignature
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cnlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfCheck(t *testing.T) {
+	for _, basecoin := range []*BaseCoin{BaseCoinBip49MainNet, BaseCoinBip84MainNet, BaseCoinBip86MainNet, BaseCoinBip84TestNet} {
+		wallet := NewHDWalletFromWords(w, basecoin)
+		assert.Nil(t, wallet.SelfCheck(), "purpose %d coin %d", basecoin.Purpose, basecoin.Coin)
+	}
+
+	signet, err := NewTestNetworkBaseCoin(84, 0, TestNetworkSignet)
+	assert.Nil(t, err)
+	assert.Nil(t, NewHDWalletFromWords(w, signet).SelfCheck())
+}
+
+func TestSelfCheck_Litecoin(t *testing.T) {
+	for _, basecoin := range []*BaseCoin{BaseCoinBip49Litecoin, BaseCoinBip84Litecoin} {
+		wallet := NewHDWalletFromWords(w, basecoin)
+		assert.Nil(t, wallet.SelfCheck(), "purpose %d", basecoin.Purpose)
+
+		key, err := wallet.AccountExtendedPublicKey()
+		assert.Nil(t, err)
+		watchOnly, err := NewWatchOnlyWallet(key, basecoin)
+		assert.Nil(t, err)
+		assert.Nil(t, watchOnly.SelfCheck(), "purpose %d", basecoin.Purpose)
+	}
+}
+
+func TestSelfCheck_WatchOnly(t *testing.T) {
+	wallet, err := NewHDWalletFromAccountExtendedPublicKey("zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs")
+	assert.Nil(t, err)
+	assert.Nil(t, wallet.SelfCheck())
+}
+
+func TestSelfCheck_CorruptAddressCache(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	wallet.AddressCache()
+	other, err := wallet.ReceiveAddressForIndex(5)
+	assert.Nil(t, err)
+
+	wallet.addressCache.add(&addressCacheEntry{Purpose: 84, Coin: 0, Account: 0, Change: 0, Index: 1, Address: other.Address})
+	assert.Equal(t, ErrSelfCheckAddressMismatch, wallet.SelfCheck())
+}
+
+func TestSelfCheck_CorruptDerivationCache(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	assert.Nil(t, wallet.WarmUp())
+
+	// swap the cached receive and change chain keys
+	receive := derivationCacheKey{purpose: 84, coin: 0, account: 0, change: 0}
+	change := derivationCacheKey{purpose: 84, coin: 0, account: 0, change: 1}
+	keys := wallet.keyCache.chainKeys
+	keys[receive], keys[change] = keys[change], keys[receive]
+	assert.Equal(t, ErrSelfCheckAddressMismatch, wallet.SelfCheck())
+}
+
+func TestSelfCheck_NetworkMismatch(t *testing.T) {
+	wallet := NewHDWalletFromWords(w, BaseCoinBip84MainNet)
+	wallet.UpdateCoin(NewBaseCoin(84, 1, 0))
+	assert.Equal(t, ErrSelfCheckNetwork, wallet.SelfCheck())
+}